@@ -0,0 +1,253 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// AlterAlgorithm classifies how the server executes an ALTER TABLE, in terms
+// of the cost of the operation on large tables.
+type AlterAlgorithm int
+
+// Constants enumerating AlterAlgorithm values, in order of increasing cost
+const (
+	AlterAlgorithmInPlace AlterAlgorithm = iota // instant, metadata-only, or in-place without rebuilding the table
+	AlterAlgorithmRebuild                       // in-place, but rebuilds the table; concurrent DML is generally permitted
+	AlterAlgorithmCopy                          // copies the table row-by-row; concurrent writes are blocked
+)
+
+// String returns a human-readable description of the algorithm.
+func (algo AlterAlgorithm) String() string {
+	switch algo {
+	case AlterAlgorithmRebuild:
+		return "in-place table rebuild"
+	case AlterAlgorithmCopy:
+		return "table copy"
+	default:
+		return "in-place"
+	}
+}
+
+// ClassifyAlter determines how objDiff will be executed by a server of the
+// supplied flavor, if run without an explicit ALGORITHM clause. The returned
+// reasons describe each operation requiring a table rebuild or copy, and are
+// empty if the returned algorithm is AlterAlgorithmInPlace. Diffs which are
+// not an ALTER TABLE are always classified as AlterAlgorithmInPlace.
+//
+// The classification is based on documented InnoDB online DDL behavior for
+// each flavor. When an operation's behavior depends on factors that cannot be
+// determined from the table definitions alone, the more expensive algorithm is
+// assumed.
+func ClassifyAlter(objDiff tengo.ObjectDiff, flavor tengo.Flavor) (AlterAlgorithm, []string) {
+	result := AlterAlgorithmInPlace
+	var reasons []string
+	for _, op := range alterOperations(objDiff, flavor) {
+		if op.algorithm > result {
+			result = op.algorithm
+		}
+		reasons = append(reasons, op.reason)
+	}
+	return result, reasons
+}
+
+// alterOperations returns an alterOperation for each rebuild- or copy-forcing
+// change in objDiff.
+func alterOperations(objDiff tengo.ObjectDiff, flavor tengo.Flavor) (ops []alterOperation) {
+	switch diff := objDiff.(type) {
+	case *tengo.TableDiff:
+		if diff.Type == tengo.DiffTypeAlter && diff.From != nil && diff.To != nil {
+			ops = tableAlterOperations(diff.From, diff.To, flavor)
+		}
+	case *tablespaceDiff:
+		ops = []alterOperation{{AlterAlgorithmRebuild, diff.rebuildReason()}}
+	case *encryptionDiff:
+		ops = []alterOperation{{AlterAlgorithmCopy, diff.rebuildReason()}}
+	case *conversionDiff:
+		ops = []alterOperation{{AlterAlgorithmCopy, diff.rebuildReason()}}
+	case *partitionListDiff:
+		if reason := diff.rebuildReason(); reason != "" {
+			ops = []alterOperation{{AlterAlgorithmCopy, reason}}
+		}
+	case *checkDiff:
+		if reason := diff.rebuildReason(); reason != "" {
+			ops = []alterOperation{{AlterAlgorithmCopy, reason}}
+		}
+	case *combinedTableDiff:
+		for _, subDiff := range diff.diffs {
+			ops = append(ops, alterOperations(subDiff, flavor)...)
+		}
+	}
+	return ops
+}
+
+// alterOperation describes a single operation within an ALTER TABLE which
+// requires a table rebuild or copy.
+type alterOperation struct {
+	algorithm AlterAlgorithm
+	reason    string
+}
+
+// tableAlterOperations returns an alterOperation for each rebuild- or
+// copy-forcing change between from and to.
+func tableAlterOperations(from, to *tengo.Table, flavor tengo.Flavor) (ops []alterOperation) {
+	add := func(algo AlterAlgorithm, format string, a ...interface{}) {
+		ops = append(ops, alterOperation{algorithm: algo, reason: fmt.Sprintf(format, a...)})
+	}
+
+	if !strings.EqualFold(from.Engine, to.Engine) {
+		add(AlterAlgorithmCopy, "storage engine changes from %s to %s", from.Engine, to.Engine)
+	}
+	if fromFormat, toFormat := from.RowFormatClause(), to.RowFormatClause(); fromFormat != toFormat || createOption(from, "KEY_BLOCK_SIZE") != createOption(to, "KEY_BLOCK_SIZE") {
+		add(AlterAlgorithmRebuild, "row format or key block size changes")
+	}
+
+	// Added and dropped columns. Virtual columns never require a rebuild.
+	fromCols, toCols := from.ColumnsByName(), to.ColumnsByName()
+	stored := func(col *tengo.Column) bool {
+		return col.GenerationExpr == "" || !col.Virtual
+	}
+	var dropped, added, addedNotLast []string
+	for _, col := range from.Columns {
+		if _, ok := toCols[col.Name]; !ok && stored(col) {
+			dropped = append(dropped, tengo.EscapeIdentifier(col.Name))
+		}
+	}
+	var seenExisting bool
+	for n := len(to.Columns) - 1; n >= 0; n-- {
+		col := to.Columns[n]
+		if _, ok := fromCols[col.Name]; ok {
+			seenExisting = true
+		} else if stored(col) {
+			added = append([]string{tengo.EscapeIdentifier(col.Name)}, added...)
+			if seenExisting {
+				addedNotLast = append(addedNotLast, col.Name)
+			}
+		}
+	}
+	if len(dropped) > 0 && !instantDropColumn(flavor) {
+		add(AlterAlgorithmRebuild, "stored columns are dropped: %s", strings.Join(dropped, ", "))
+	}
+	if len(added) > 0 && !instantAddColumn(flavor, to, len(addedNotLast) == 0) {
+		add(AlterAlgorithmRebuild, "stored columns are added: %s", strings.Join(added, ", "))
+	}
+	if existingColumnsReordered(from, to) && !flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 4) {
+		add(AlterAlgorithmRebuild, "existing columns are reordered")
+	}
+
+	// Modified columns
+	for _, toCol := range to.Columns {
+		fromCol := fromCols[toCol.Name]
+		if fromCol == nil {
+			continue
+		}
+		name := tengo.EscapeIdentifier(toCol.Name)
+		if fromCol.CharSet != toCol.CharSet {
+			add(AlterAlgorithmCopy, "column %s character set changes from %s to %s", name, fromCol.CharSet, toCol.CharSet)
+		} else if fromCol.TypeInDB != toCol.TypeInDB && !inPlaceVarcharExtension(fromCol, toCol, flavor) {
+			add(AlterAlgorithmCopy, "column %s type changes from %s to %s", name, fromCol.TypeInDB, toCol.TypeInDB)
+		}
+		if fromCol.Nullable != toCol.Nullable {
+			add(AlterAlgorithmRebuild, "column %s nullability changes", name)
+		}
+	}
+	for _, cc := range indexedCollationChanges(from, to) {
+		add(AlterAlgorithmRebuild, "%s", cc.String())
+	}
+
+	// Primary key and fulltext index changes. Other index changes do not
+	// rebuild the table.
+	if from.PrimaryKey != nil && to.PrimaryKey == nil {
+		add(AlterAlgorithmCopy, "primary key is dropped without adding a new one")
+	} else if to.PrimaryKey != nil && !to.PrimaryKey.Equals(from.PrimaryKey) {
+		add(AlterAlgorithmRebuild, "primary key is added or changed")
+	}
+	if !hasFullTextIndex(from) && hasFullTextIndex(to) {
+		add(AlterAlgorithmRebuild, "first FULLTEXT index is added")
+	}
+	return ops
+}
+
+// instantDropColumn returns true if flavor can drop stored columns without a
+// table rebuild.
+func instantDropColumn(flavor tengo.Flavor) bool {
+	return flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 4) || flavor.MySQLishMinVersion(8, 0, 29)
+}
+
+// instantAddColumn returns true if flavor can add stored columns to table
+// without a table rebuild. last indicates whether all new columns are
+// positioned after all existing columns.
+func instantAddColumn(flavor tengo.Flavor, table *tengo.Table, last bool) bool {
+	if table.RowFormatClause() == "COMPRESSED" {
+		return false
+	}
+	if flavor.Vendor == tengo.VendorMariaDB {
+		return flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 4) || (last && flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 3, 2))
+	}
+	if hasFullTextIndex(table) && !flavor.MySQLishMinVersion(8, 0, 29) {
+		return false
+	}
+	return flavor.MySQLishMinVersion(8, 0, 29) || (last && flavor.MySQLishMinVersion(8, 0, 12))
+}
+
+// maxCharBytes maps common character sets to their maximum bytes per
+// character, for purposes of determining VARCHAR length byte requirements.
+var maxCharBytes = map[string]int{
+	"ascii":   1,
+	"binary":  1,
+	"latin1":  1,
+	"latin2":  1,
+	"ucs2":    2,
+	"utf16":   4,
+	"utf32":   4,
+	"utf8":    3,
+	"utf8mb3": 3,
+	"utf8mb4": 4,
+}
+
+var reVarchar = regexp.MustCompile(`^varchar\((\d+)\)$`)
+
+// inPlaceVarcharExtension returns true if the only change between from and to
+// is increasing the length of a VARCHAR column, without changing the number
+// of length bytes required for its maximum size in bytes. Such changes are
+// performed in-place without a table rebuild in MySQL 5.7+ and MariaDB 10.2+.
+func inPlaceVarcharExtension(from, to *tengo.Column, flavor tengo.Flavor) bool {
+	if !flavor.MySQLishMinVersion(5, 7) && !flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2, 2) {
+		return false
+	}
+	fromMatch, toMatch := reVarchar.FindStringSubmatch(from.TypeInDB), reVarchar.FindStringSubmatch(to.TypeInDB)
+	bytesPerChar := maxCharBytes[to.CharSet]
+	if fromMatch == nil || toMatch == nil || bytesPerChar == 0 {
+		return false
+	}
+	fromLen, _ := strconv.Atoi(fromMatch[1])
+	toLen, _ := strconv.Atoi(toMatch[1])
+	if toLen < fromLen {
+		return false
+	}
+	return (fromLen*bytesPerChar < 256) == (toLen*bytesPerChar < 256)
+}
+
+// hasFullTextIndex returns true if table has at least one FULLTEXT index.
+func hasFullTextIndex(table *tengo.Table) bool {
+	for _, idx := range table.SecondaryIndexes {
+		if idx.Type == "FULLTEXT" {
+			return true
+		}
+	}
+	return false
+}
+
+// createOption returns the value of the named option in table's
+// CreateOptions, or an empty string if not present.
+func createOption(table *tengo.Table, name string) string {
+	for _, opt := range strings.Fields(table.CreateOptions) {
+		if strings.HasPrefix(strings.ToUpper(opt), name+"=") {
+			return opt[len(name)+1:]
+		}
+	}
+	return ""
+}
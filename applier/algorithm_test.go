@@ -0,0 +1,200 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/tengo"
+)
+
+func TestClassifyAlter(t *testing.T) {
+	makeTable := func() *tengo.Table {
+		return &tengo.Table{
+			Name:      "widgets",
+			Engine:    "InnoDB",
+			CharSet:   "utf8mb4",
+			Collation: "utf8mb4_general_ci",
+			Columns: []*tengo.Column{
+				{Name: "id", TypeInDB: "int(10) unsigned"},
+				{Name: "name", TypeInDB: "varchar(30)", Nullable: true, CharSet: "utf8mb4", Collation: "utf8mb4_general_ci"},
+				{Name: "qty", TypeInDB: "int(11)"},
+			},
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+		}
+	}
+	mysql57 := tengo.Flavor{Vendor: tengo.VendorMySQL, Major: 5, Minor: 7, Patch: 30}
+	mysql8012 := tengo.Flavor{Vendor: tengo.VendorMySQL, Major: 8, Minor: 0, Patch: 12}
+	mysql8029 := tengo.Flavor{Vendor: tengo.VendorMySQL, Major: 8, Minor: 0, Patch: 29}
+	maria103 := tengo.Flavor{Vendor: tengo.VendorMariaDB, Major: 10, Minor: 3, Patch: 20}
+	maria104 := tengo.Flavor{Vendor: tengo.VendorMariaDB, Major: 10, Minor: 4, Patch: 10}
+	newCol := &tengo.Column{Name: "price", TypeInDB: "int(11)"}
+	virtCol := &tengo.Column{Name: "total", TypeInDB: "int(11)", GenerationExpr: "`qty` * 2", Virtual: true, Nullable: true}
+
+	cases := []struct {
+		desc     string
+		change   func(to *tengo.Table)
+		flavor   tengo.Flavor
+		expected AlterAlgorithm
+	}{
+		{"no change", func(to *tengo.Table) {}, mysql57, AlterAlgorithmInPlace},
+		{"add secondary index", func(to *tengo.Table) {
+			to.SecondaryIndexes = []*tengo.Index{{Name: "qty", Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "qty"}}}}
+		}, mysql57, AlterAlgorithmInPlace},
+		{"add first fulltext index", func(to *tengo.Table) {
+			to.SecondaryIndexes = []*tengo.Index{{Name: "name", Type: "FULLTEXT", Parts: []tengo.IndexPart{{ColumnName: "name"}}}}
+		}, mysql8029, AlterAlgorithmRebuild},
+		{"add column last", func(to *tengo.Table) {
+			to.Columns = append(to.Columns, newCol)
+		}, mysql57, AlterAlgorithmRebuild},
+		{"add column last", func(to *tengo.Table) {
+			to.Columns = append(to.Columns, newCol)
+		}, mysql8012, AlterAlgorithmInPlace},
+		{"add column last", func(to *tengo.Table) {
+			to.Columns = append(to.Columns, newCol)
+		}, maria103, AlterAlgorithmInPlace},
+		{"add column last to compressed table", func(to *tengo.Table) {
+			to.Columns = append(to.Columns, newCol)
+			to.CreateOptions = "ROW_FORMAT=COMPRESSED"
+		}, mysql8029, AlterAlgorithmRebuild},
+		{"add column first", func(to *tengo.Table) {
+			to.Columns = append([]*tengo.Column{newCol}, to.Columns...)
+		}, mysql8012, AlterAlgorithmRebuild},
+		{"add column first", func(to *tengo.Table) {
+			to.Columns = append([]*tengo.Column{newCol}, to.Columns...)
+		}, maria103, AlterAlgorithmRebuild},
+		{"add column first", func(to *tengo.Table) {
+			to.Columns = append([]*tengo.Column{newCol}, to.Columns...)
+		}, mysql8029, AlterAlgorithmInPlace},
+		{"add column first", func(to *tengo.Table) {
+			to.Columns = append([]*tengo.Column{newCol}, to.Columns...)
+		}, maria104, AlterAlgorithmInPlace},
+		{"add virtual column", func(to *tengo.Table) {
+			to.Columns = append([]*tengo.Column{virtCol}, to.Columns...)
+		}, mysql57, AlterAlgorithmInPlace},
+		{"drop column", func(to *tengo.Table) {
+			to.Columns = to.Columns[0:2]
+		}, mysql8012, AlterAlgorithmRebuild},
+		{"drop column", func(to *tengo.Table) {
+			to.Columns = to.Columns[0:2]
+		}, mysql8029, AlterAlgorithmInPlace},
+		{"drop column", func(to *tengo.Table) {
+			to.Columns = to.Columns[0:2]
+		}, maria104, AlterAlgorithmInPlace},
+		{"reorder columns", func(to *tengo.Table) {
+			to.Columns[1], to.Columns[2] = to.Columns[2], to.Columns[1]
+		}, mysql8029, AlterAlgorithmRebuild},
+		{"reorder columns", func(to *tengo.Table) {
+			to.Columns[1], to.Columns[2] = to.Columns[2], to.Columns[1]
+		}, maria104, AlterAlgorithmInPlace},
+		{"change nullability", func(to *tengo.Table) {
+			to.Columns[1].Nullable = false
+		}, mysql57, AlterAlgorithmRebuild},
+		{"change column type", func(to *tengo.Table) {
+			to.Columns[2].TypeInDB = "bigint(20)"
+		}, mysql8029, AlterAlgorithmCopy},
+		{"extend varchar within length byte", func(to *tengo.Table) {
+			to.Columns[1].TypeInDB = "varchar(60)"
+		}, mysql57, AlterAlgorithmInPlace},
+		{"extend varchar within length byte", func(to *tengo.Table) {
+			to.Columns[1].TypeInDB = "varchar(60)"
+		}, tengo.FlavorMySQL56, AlterAlgorithmCopy},
+		{"extend varchar past length byte", func(to *tengo.Table) {
+			to.Columns[1].TypeInDB = "varchar(100)"
+		}, mysql57, AlterAlgorithmCopy},
+		{"shrink varchar", func(to *tengo.Table) {
+			to.Columns[1].TypeInDB = "varchar(20)"
+		}, mysql57, AlterAlgorithmCopy},
+		{"change column charset", func(to *tengo.Table) {
+			to.Columns[1].CharSet, to.Columns[1].Collation = "latin1", "latin1_swedish_ci"
+		}, mysql8029, AlterAlgorithmCopy},
+		{"change engine", func(to *tengo.Table) {
+			to.Engine = "MyISAM"
+		}, mysql8029, AlterAlgorithmCopy},
+		{"change row format", func(to *tengo.Table) {
+			to.CreateOptions = "ROW_FORMAT=DYNAMIC"
+		}, mysql57, AlterAlgorithmRebuild},
+		{"change key block size", func(to *tengo.Table) {
+			to.CreateOptions = "KEY_BLOCK_SIZE=4"
+		}, mysql57, AlterAlgorithmRebuild},
+		{"change other create option", func(to *tengo.Table) {
+			to.CreateOptions = "STATS_PERSISTENT=1"
+		}, mysql57, AlterAlgorithmInPlace},
+		{"change primary key", func(to *tengo.Table) {
+			to.PrimaryKey.Parts = append(to.PrimaryKey.Parts, tengo.IndexPart{ColumnName: "qty"})
+		}, mysql8029, AlterAlgorithmRebuild},
+		{"drop primary key", func(to *tengo.Table) {
+			to.PrimaryKey = nil
+		}, mysql8029, AlterAlgorithmCopy},
+		{"rebuild and copy", func(to *tengo.Table) {
+			to.Columns[1].Nullable = false
+			to.Columns[2].TypeInDB = "bigint(20)"
+		}, mysql57, AlterAlgorithmCopy},
+	}
+	for _, c := range cases {
+		to := makeTable()
+		c.change(to)
+		diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: makeTable(), To: to}
+		algo, reasons := ClassifyAlter(diff, c.flavor)
+		if algo != c.expected {
+			t.Errorf("%s on %s: expected classification %q, instead found %q (reasons: %v)", c.desc, c.flavor, c.expected, algo, reasons)
+		} else if (algo == AlterAlgorithmInPlace) != (len(reasons) == 0) {
+			t.Errorf("%s on %s: unexpected reasons %v for classification %q", c.desc, c.flavor, reasons, algo)
+		}
+	}
+
+	// Non-ALTER diffs are always in-place
+	for _, diff := range []tengo.ObjectDiff{
+		&tengo.TableDiff{Type: tengo.DiffTypeCreate, To: makeTable()},
+		&tengo.TableDiff{Type: tengo.DiffTypeDrop, From: makeTable()},
+		&tengo.DatabaseDiff{From: &tengo.Schema{Name: "foo", CharSet: "latin1"}, To: &tengo.Schema{Name: "foo", CharSet: "utf8mb4"}},
+	} {
+		if algo, reasons := ClassifyAlter(diff, mysql57); algo != AlterAlgorithmInPlace || len(reasons) > 0 {
+			t.Errorf("Unexpected classification for %+v: %s %v", diff, algo, reasons)
+		}
+	}
+}
+
+func TestCheckRequireWrapper(t *testing.T) {
+	diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: &tengo.Table{Name: "widgets"}, To: &tengo.Table{Name: "widgets"}}
+	cases := []struct {
+		requireWrapper string
+		minSize        string
+		algo           AlterAlgorithm
+		wrapper        string
+		tableSize      int64
+		expectErr      bool
+	}{
+		{"none", "", AlterAlgorithmCopy, "", 100, false},
+		{"copy", "", AlterAlgorithmInPlace, "", 100, false},
+		{"copy", "", AlterAlgorithmRebuild, "", 100, false},
+		{"copy", "", AlterAlgorithmCopy, "", 100, true},
+		{"copy", "", AlterAlgorithmCopy, "/bin/echo", 100, false},
+		{"rebuild", "", AlterAlgorithmInPlace, "", 100, false},
+		{"rebuild", "", AlterAlgorithmRebuild, "", 100, true},
+		{"rebuild", "", AlterAlgorithmCopy, "", 100, true},
+		{"rebuild", "", AlterAlgorithmRebuild, "/bin/echo", 100, false},
+		{"rebuild", "1k", AlterAlgorithmRebuild, "", 100, false},
+		{"rebuild", "1k", AlterAlgorithmRebuild, "", 2048, true},
+	}
+	for _, c := range cases {
+		configMap := map[string]string{
+			"require-wrapper":        c.requireWrapper,
+			"alter-wrapper":          "",
+			"alter-wrapper-min-size": "0",
+			"osc-tool":               "none",
+		}
+		if c.minSize != "" {
+			configMap["alter-wrapper"] = "/bin/echo"
+			configMap["alter-wrapper-min-size"] = c.minSize
+		}
+		cfg := mybase.SimpleConfig(configMap)
+		if err := checkRequireWrapper(cfg, diff, c.algo, c.wrapper, c.tableSize); (err != nil) != c.expectErr {
+			t.Errorf("Unexpected result from checkRequireWrapper for %+v: %v", c, err)
+		}
+	}
+
+	cfg := mybase.SimpleConfig(map[string]string{"require-wrapper": "invalid"})
+	if err := checkRequireWrapper(cfg, diff, AlterAlgorithmInPlace, "", 0); err == nil {
+		t.Error("Expected error from invalid require-wrapper value, but err was nil")
+	}
+}
@@ -1,5 +1,11 @@
 // Package applier handles execution of generating diffs between schemas, and
 // appropriate application of the generated DDL.
+//
+// Programs embedding Skeema can use Diff and Push to perform the same
+// operations as `skeema diff` and `skeema push`, on dirs obtained from
+// fs.ParseDir using a configuration with the options from AddCommandOptions.
+// A Printer from NewRecordingPrinter can be used to obtain the generated
+// statements, instead of having them output to STDOUT.
 package applier
 
 import (
@@ -9,72 +15,117 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
 	"github.com/skeema/skeema/linter"
 	"github.com/skeema/tengo"
 )
 
 // Result stores the overall result of all operations the worker has completed.
+// Results sent by Worker each reflect a single target, identified by Instance
+// and SchemaName; these fields are left empty by SumResults.
 type Result struct {
 	Differences      bool
 	SkipCount        int
 	UnsupportedCount int
+	HaltedCount      int // number of targets not processed due to the context being cancelled
+	Instance         string
+	SchemaName       string
 }
 
 // Summary returns a string reflecting the contents of the result.
 func (r Result) Summary() string {
-	if r.SkipCount+r.UnsupportedCount == 0 {
-		return ""
+	var summary string
+	if r.SkipCount+r.UnsupportedCount > 0 {
+		var plural, reason string
+		if r.SkipCount+r.UnsupportedCount > 1 {
+			plural = "s"
+		}
+		if r.SkipCount == 0 {
+			reason = "unsupported feature"
+		} else if r.UnsupportedCount == 0 {
+			reason = "error"
+		} else {
+			reason = "unsupported features or error"
+		}
+		summary = fmt.Sprintf("Skipped %d operation%s due to %s%s", r.SkipCount+r.UnsupportedCount, plural, reason, plural)
 	}
-	var plural, reason string
-	if r.SkipCount+r.UnsupportedCount > 1 {
-		plural = "s"
+	if r.HaltedCount > 0 {
+		schemas := countAndNoun(r.HaltedCount, "schema")
+		if summary == "" {
+			summary = "Halted before processing " + schemas
+		} else {
+			summary += "; halted before processing " + schemas
+		}
 	}
-	if r.SkipCount == 0 {
-		reason = "unsupported feature"
-	} else if r.UnsupportedCount == 0 {
-		reason = "error"
-	} else {
-		reason = "unsupported features or error"
+	return summary
+}
+
+// Status returns a single word describing the result: "failed" if any
+// operations were skipped due to errors, "halted" if any targets were not
+// processed, "unsupported" if any operations were skipped due to unsupported
+// features, or "succeeded" otherwise.
+func (r Result) Status() string {
+	switch {
+	case r.SkipCount > 0:
+		return "failed"
+	case r.HaltedCount > 0:
+		return "halted"
+	case r.UnsupportedCount > 0:
+		return "unsupported"
+	default:
+		return "succeeded"
 	}
-	return fmt.Sprintf("Skipped %d operation%s due to %s%s", r.SkipCount+r.UnsupportedCount, plural, reason, plural)
 }
 
 // Worker reads TargetGroups from the input channel and performs the appropriate
-// diff/push operation on each target per TargetGroup. When there are no more
-// TargetGroups to read, it writes its aggregate Result to the output channel.
-// If a fatal error occurs, it will be returned immediately; Worker is meant to
-// be called via an errgroup (see golang.org/x/sync/errgroup).
+// diff/push operation on each target per TargetGroup, writing a Result for each
+// target to the output channel. If ctx is cancelled, no further targets are
+// processed, but Worker continues reading TargetGroups until the input channel
+// is closed, writing a Result with HaltedCount 1 for each target not
+// processed. If a fatal error occurs, it will be returned immediately; Worker
+// is meant to be called via an errgroup (see golang.org/x/sync/errgroup).
 func Worker(ctx context.Context, targetGroups <-chan TargetGroup, results chan<- Result, printer *Printer) error {
 	for tg := range targetGroups {
 		for _, t := range tg {
+			if ctx.Err() != nil {
+				results <- Result{HaltedCount: 1, Instance: t.Instance.String(), SchemaName: t.SchemaName}
+				continue
+			}
 			result, err := applyTarget(t, printer)
 			if err != nil {
 				return err
 			}
+			result.Instance, result.SchemaName = t.Instance.String(), t.SchemaName
 			results <- result
-
-			// Exit early if context cancelled
-			select {
-			case <-ctx.Done():
-				return nil
-			default:
-			}
 		}
 	}
 	return nil
 }
 
+// applyTarget diffs and, unless in dry-run mode, pushes changes for t. Problems
+// specific to t, such as being unable to connect to or introspect its instance,
+// are logged and reflected in the returned Result's SkipCount. An error is only
+// returned if processing of all targets should stop, for example due to
+// invalid configuration.
 func applyTarget(t *Target, printer *Printer) (Result, error) {
 	var result Result
 
+	// Unreachable instances were already logged by instancesForDir
+	if t.connectErr != nil {
+		result.SkipCount++
+		return result, nil
+	}
+
 	schemaFromInstance, err := t.SchemaFromInstance()
 	if err != nil {
 		result.SkipCount++
 		log.Errorf("Skipping %s schema %s for %s: %s", t.Instance, t.SchemaName, t.Dir, err)
-		return result, err
+		return result, nil
 	}
 
-	t.logApplyStart()
+	if !t.checkOnly {
+		t.logApplyStart()
+	}
 	schemaFromDir := t.SchemaFromDir()
 
 	// Obtain StatementModifiers based on the dir's config
@@ -83,54 +134,133 @@ func applyTarget(t *Target, printer *Printer) (Result, error) {
 		return result, ConfigError(err.Error())
 	}
 	mods.Flavor = t.Instance.Flavor()
-	if mods.Partitioning == tengo.PartitioningRemove {
-		// With partitioning=remove, forcibly treat all filesystem definitions as if
-		// they didn't have a partitioning clause. This is designed to aid in the
-		// use-case of not running any partition management in a dev environment; if
-		// a table somehow manages to be partitioned there anyway by mistake, we
-		// intentionally want to de-partition it.
-		for _, table := range schemaFromDir.Tables {
-			if table.Partitioning != nil {
-				table.CreateStatement = table.UnpartitionedCreateStatement(mods.Flavor)
-				table.Partitioning = nil
-			}
+	if t.checkOnly {
+		mods.AllowUnsafe = true // all remaining differences are of interest
+	}
+	convergenceMode, err := t.Dir.Config.GetEnum("check-convergence", "off", "warn", "error")
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+	external, err := externalObjectsForDir(t.Dir)
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+
+	// With object-types, objects of any other type are excluded from both sides
+	// of the diff
+	introspectOpts, err := introspect.OptionsForDir(t.Dir)
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+	typeOpts := introspect.Options{ObjectTypes: introspectOpts.ObjectTypes}
+
+	// tengo does not support events, sequences, views, or triggers, so these are
+	// introspected separately, unless the schema does not exist yet
+	var liveObjs introspect.Objects
+	if schemaFromInstance != nil {
+		if liveObjs, err = introspect.SchemaObjects(t.Instance, t.SchemaName, typeOpts); err != nil {
+			result.SkipCount++
+			log.Errorf("Skipping %s schema %s for %s: %s", t.Instance, t.SchemaName, t.Dir, err)
+			return result, nil
 		}
 	}
 
-	diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir)
+	// Tables lacking an encryption clause in the filesystem inherit the live
+	// schema's default encryption
+	defaultEncryption, err := schemaDefaultEncryption(t, schemaFromInstance)
+	if err != nil {
+		result.SkipCount++
+		log.Errorf("Skipping %s schema %s for %s: %s", t.Instance, t.SchemaName, t.Dir, err)
+		return result, nil
+	}
+
+	diffOpts := schemaDiffOptions{
+		mods:                 mods,
+		typeOpts:             typeOpts,
+		external:             external,
+		includeSystemColumns: t.Dir.Config.GetBool("include-system-columns"),
+		reorderColumns:       t.Dir.Config.GetBool("reorder-columns") || t.Dir.Config.GetBool("exact-match"),
+		managePartitionList:  t.Dir.Config.GetBool("manage-partition-list"),
+		allowDropPartition:   t.Dir.Config.GetBool("allow-drop-partition"),
+		allowDropTrigger:     t.Dir.Config.GetBool("allow-drop-trigger"),
+		defaultEncryption:    defaultEncryption,
+		target:               t,
+	}
+	diff, objDiffs, diffResult, err := diffSchemas(schemaFromInstance, schemaFromDir, liveObjs, introspect.WorkspaceObjects(t.DesiredSchema, typeOpts), diffOpts)
+	result.SkipCount += diffResult.SkipCount
+	result.UnsupportedCount += diffResult.UnsupportedCount
+	if err != nil {
+		result.SkipCount++
+		log.Errorf("Skipping %s schema %s for %s: %s", t.Instance, t.SchemaName, t.Dir, err)
+		return result, nil
+	}
 	if err := VerifyDiff(diff, t); err != nil {
 		return result, err
 	}
+	if err := checkEventScheduler(t, objDiffs); err != nil {
+		result.SkipCount++
+		log.Errorf("Skipping %s schema %s for %s: %s", t.Instance, t.SchemaName, t.Dir, err)
+		return result, nil
+	}
+
+	// Confirm the instance supports all character sets and collations used by
+	// the desired definitions, before attempting to run any DDL
+	if err := checkCollations(objDiffs, t); err != nil {
+		result.SkipCount += len(objDiffs)
+		log.Error(err)
+		return result, nil
+	}
+	objDiffs, fkCyclic := orderDiffs(objDiffs, mods)
 
 	// Build DDLStatements for each ObjectDiff, handling pre-execution errors
 	// accordingly. Also track ObjectKeys for modified objects, for subsequent
 	// use in linting.
-	objDiffs := diff.ObjectDiffs()
 	ddls := make([]*DDLStatement, 0, len(objDiffs))
 	keys := make([]tengo.ObjectKey, 0, len(objDiffs))
-	for _, objDiff := range objDiffs {
+	for n, objDiff := range objDiffs {
 		ddl, err := NewDDLStatement(objDiff, mods, t)
 		if ddl == nil && err == nil {
 			continue // Skip entirely if mods made the statement a noop
 		}
 		result.Differences = true
+		if td, ok := objDiff.(*tengo.TableDiff); ok && err == nil {
+			ddl.disableFKChecks = fkCyclic[td]
+		}
 		if err == nil {
+			ddls = append(ddls, ddl.backfills...)
 			ddls = append(ddls, ddl)
 			keys = append(keys, objDiff.ObjectKey())
+			if ddl.algorithm != AlterAlgorithmInPlace {
+				log.Warnf("ALTER of %s requires a %s: %s", objDiff.ObjectKey(), ddl.algorithm, strings.Join(ddl.algorithmReasons, "; "))
+			}
 		} else if unsupportedErr, ok := err.(*tengo.UnsupportedDiffError); ok {
 			result.UnsupportedCount++
-			log.Warnf("Skipping %s: unable to generate DDL due to use of unsupported features. Use --debug for more information.", unsupportedErr.ObjectKey)
+			if dd, ok := objDiff.(*directoryDiff); ok {
+				log.Warnf("Skipping %s: %s. Use --debug for more information.", unsupportedErr.ObjectKey, dd.reason())
+			} else {
+				log.Warnf("Skipping %s: unable to generate DDL due to use of unsupported features. Use --debug for more information.", unsupportedErr.ObjectKey)
+			}
 			DebugLogUnsupportedDiff(unsupportedErr)
+			printer.printSkipped(t, objDiffs[n:n+1], mods, "unsupported features")
 		} else {
 			result.SkipCount += len(objDiffs)
-			log.Errorf(err.Error())
+			log.Error(err)
 			if len(objDiffs) > 1 {
 				log.Warnf("Skipping %d additional operations for %s %s due to previous error", len(objDiffs)-1, t.Instance, t.SchemaName)
 			}
+			printer.printSkipped(t, objDiffs[n:n+1], mods, err.Error())
+			printer.printSkipped(t, append(objDiffs[0:n:n], objDiffs[n+1:]...), mods, "previous error")
 			return result, nil
 		}
 	}
 
+	// When checking convergence, just track the DDL; nothing is linted, output,
+	// or executed
+	if t.checkOnly {
+		t.remainingDDL = ddls
+		return result, nil
+	}
+
 	// Lint any modified objects; output the result; skip target if any
 	// annotations are at the error level
 	if t.Dir.Config.GetBool("lint") {
@@ -147,12 +277,28 @@ func applyTarget(t *Target, printer *Printer) (Result, error) {
 		if lintResult.ErrorCount > 0 {
 			result.SkipCount += len(objDiffs)
 			log.Warnf("Skipping %s %s due to %s", t.Instance, t.SchemaName, countAndNoun(lintResult.ErrorCount, "linter error"))
+			printer.printSkipped(t, objDiffs, mods, "linter errors")
 			return result, nil
 		}
 	}
 
-	// Print DDL; if not dry-run, execute it; final logging; return result
-	result.SkipCount += t.processDDL(ddls, printer)
+	// With --group-by-safety, output is reordered to place destructive
+	// operations last, which is only permissible since nothing is executed
+	if t.dryRun() && t.Dir.Config.GetBool("group-by-safety") {
+		ddls = groupDDLBySafety(ddls)
+	}
+
+	// Print DDL; if not dry-run, execute it
+	skipCount := t.processDDL(ddls, printer)
+	result.SkipCount += skipCount
+
+	// With check-convergence, confirm that the executed DDL actually brought the
+	// schema in line with the filesystem
+	if len(ddls) > 0 && skipCount == 0 && !t.dryRun() {
+		result.SkipCount += t.logConvergence(convergenceMode)
+	}
+
+	// Final logging; return result
 	t.logApplyEnd(result)
 	return result, nil
 }
@@ -179,10 +325,28 @@ func SumResults(results []Result) Result {
 		total.Differences = total.Differences || r.Differences
 		total.SkipCount += r.SkipCount
 		total.UnsupportedCount += r.UnsupportedCount
+		total.HaltedCount += r.HaltedCount
 	}
 	return total
 }
 
+// SumResultsByInstance sums the supplied results separately for each instance,
+// returning a map keyed by instance string. Each summed Result has its Instance
+// field set, but not its SchemaName.
+func SumResultsByInstance(results []Result) map[string]Result {
+	byInst := make(map[string][]Result)
+	for _, r := range results {
+		byInst[r.Instance] = append(byInst[r.Instance], r)
+	}
+	sums := make(map[string]Result, len(byInst))
+	for inst, instResults := range byInst {
+		sum := SumResults(instResults)
+		sum.Instance = inst
+		sums[inst] = sum
+	}
+	return sums
+}
+
 // StatementModifiersForDir returns a set of DDL modifiers, based on the
 // directory's configuration.
 func StatementModifiersForDir(dir *fs.Dir) (mods tengo.StatementModifiers, err error) {
@@ -208,15 +372,18 @@ func StatementModifiersForDir(dir *fs.Dir) (mods tengo.StatementModifiers, err e
 	if partitioning, err = dir.Config.GetEnum("partitioning", "keep", "remove", "modify"); err != nil {
 		return
 	}
-	partMap := map[string]tengo.PartitioningMode{
-		"keep":   tengo.PartitioningKeep,
-		"remove": tengo.PartitioningRemove,
-		"modify": tengo.PartitioningPermissive,
-	}
-	mods.Partitioning = partMap[partitioning]
+	mods.Partitioning = partitioningModes[partitioning]
 	return
 }
 
+// partitioningModes maps values of the partitioning option to the
+// corresponding tengo.PartitioningMode.
+var partitioningModes = map[string]tengo.PartitioningMode{
+	"keep":   tengo.PartitioningKeep,
+	"remove": tengo.PartitioningRemove,
+	"modify": tengo.PartitioningPermissive,
+}
+
 // DebugLogUnsupportedDiff logs (at Debug level) the reason why an object is
 // unsupported for diff/alter operations.
 func DebugLogUnsupportedDiff(err *tengo.UnsupportedDiffError) {
@@ -234,3 +401,12 @@ type ConfigError string
 func (ce ConfigError) Error() string {
 	return string(ce)
 }
+
+// FileError represents a problem opening or writing a file requested by
+// configuration, such as a state file, audit log, or migration file.
+type FileError string
+
+// Error satisfies the builtin error interface.
+func (fe FileError) Error() string {
+	return string(fe)
+}
@@ -1,6 +1,7 @@
 package applier
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -29,18 +30,103 @@ func TestSumResults(t *testing.T) {
 			Differences:      true,
 			SkipCount:        3,
 			UnsupportedCount: 5,
+			HaltedCount:      2,
 		},
 	}
 	expectSum := Result{
 		Differences:      true,
 		SkipCount:        4,
 		UnsupportedCount: 5,
+		HaltedCount:      2,
 	}
 	if actualSum := SumResults(input); actualSum != expectSum {
 		t.Errorf("Unexpected result from SumResults: %+v", actualSum)
 	}
 }
 
+func TestSumResultsByInstance(t *testing.T) {
+	input := []Result{
+		{Instance: "a:3306", SchemaName: "one", Differences: true},
+		{Instance: "b:3306", SchemaName: "one", SkipCount: 2},
+		{Instance: "a:3306", SchemaName: "two", UnsupportedCount: 1},
+		{Instance: "c:3306", SchemaName: "one", HaltedCount: 1},
+	}
+	expected := map[string]Result{
+		"a:3306": {Instance: "a:3306", Differences: true, UnsupportedCount: 1},
+		"b:3306": {Instance: "b:3306", SkipCount: 2},
+		"c:3306": {Instance: "c:3306", HaltedCount: 1},
+	}
+	actual := SumResultsByInstance(input)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d instances, instead found %d", len(expected), len(actual))
+	}
+	for inst, r := range expected {
+		if actual[inst] != r {
+			t.Errorf("Unexpected result for %s: expected %+v, found %+v", inst, r, actual[inst])
+		}
+	}
+}
+
+func TestResultSummaryStatus(t *testing.T) {
+	cases := []struct {
+		result  Result
+		summary string
+		status  string
+	}{
+		{Result{Differences: true}, "", "succeeded"},
+		{Result{UnsupportedCount: 1}, "Skipped 1 operation due to unsupported feature", "unsupported"},
+		{Result{SkipCount: 2}, "Skipped 2 operations due to errors", "failed"},
+		{Result{SkipCount: 1, UnsupportedCount: 1, HaltedCount: 3}, "Skipped 2 operations due to unsupported features or errors; halted before processing 3 schemas", "failed"},
+		{Result{HaltedCount: 1}, "Halted before processing 1 schema", "halted"},
+	}
+	for _, c := range cases {
+		if actual := c.result.Summary(); actual != c.summary {
+			t.Errorf("Unexpected Summary() for %+v: expected %q, found %q", c.result, c.summary, actual)
+		}
+		if actual := c.result.Status(); actual != c.status {
+			t.Errorf("Unexpected Status() for %+v: expected %q, found %q", c.result, c.status, actual)
+		}
+	}
+}
+
+func TestWorkerHalted(t *testing.T) {
+	// With a cancelled context, Worker should not connect to any instance, and
+	// should instead return a halted result for each target
+	var targetGroups []TargetGroup
+	for _, host := range []string{"127.0.0.1:1", "127.0.0.1:2"} {
+		inst, err := tengo.NewInstance("mysql", "root:password@tcp("+host+")/")
+		if err != nil {
+			t.Fatalf("Unexpected error from NewInstance: %s", err)
+		}
+		targetGroups = append(targetGroups, TargetGroup{
+			{Instance: inst, SchemaName: "one"},
+			{Instance: inst, SchemaName: "two"},
+		})
+	}
+	tgchan := make(chan TargetGroup, len(targetGroups))
+	for _, tg := range targetGroups {
+		tgchan <- tg
+	}
+	close(tgchan)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := make(chan Result, 4)
+	if err := Worker(ctx, tgchan, results, NewPrinter(false)); err != nil {
+		t.Fatalf("Unexpected error from Worker: %v", err)
+	}
+	close(results)
+	var count int
+	for r := range results {
+		count++
+		if r.HaltedCount != 1 || r.SkipCount != 0 || r.Instance == "" || r.SchemaName == "" {
+			t.Errorf("Unexpected result from halted Worker: %+v", r)
+		}
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 results, instead found %d", count)
+	}
+}
+
 func TestIntegration(t *testing.T) {
 	images := tengo.SplitEnv("SKEEMA_TEST_IMAGES")
 	if len(images) == 0 {
@@ -0,0 +1,153 @@
+package applier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/skeema/tengo"
+)
+
+// AuditLog is a StatementHook which appends a line of JSON to a file for each
+// statement executed, whether or not it succeeded. Each line is written as soon
+// as its statement finishes, so the file remains accurate even if Skeema is
+// killed.
+//
+// All methods are safe to call on a nil *AuditLog, in which case nothing is
+// recorded. Methods may also be called concurrently from multiple workers.
+type AuditLog struct {
+	sync.Mutex
+	path string
+	f    *os.File
+}
+
+// auditEntry represents one line of an audit log, or one row of an audit
+// table. The shell command is recorded in the same form as it is displayed by
+// push, which means any password has already been masked.
+type auditEntry struct {
+	Time         string  `json:"time"`
+	Environment  string  `json:"environment"`
+	Instance     string  `json:"instance"`
+	Schema       string  `json:"schema"`
+	ObjectType   string  `json:"object_type"`
+	ObjectName   string  `json:"object_name"`
+	DiffType     string  `json:"diff_type"`
+	Statement    string  `json:"statement"`
+	ShellCommand string  `json:"shell_command,omitempty"`
+	Duration     float64 `json:"duration"` // seconds
+	Error        string  `json:"error,omitempty"`
+}
+
+func newAuditEntry(t *Target, event *StatementEvent) auditEntry {
+	entry := auditEntry{
+		Time:         event.Started.UTC().Format("2006-01-02 15:04:05"),
+		Environment:  t.Dir.Config.Get("environment"),
+		Instance:     event.Instance.String(),
+		Schema:       event.Schema,
+		ObjectType:   string(event.ObjectKey.Type),
+		ObjectName:   event.ObjectKey.Name,
+		DiffType:     diffTypeLabel(event.DiffType),
+		Statement:    event.Statement,
+		ShellCommand: event.ShellCommand,
+		Duration:     float64(event.Duration/time.Millisecond) / 1000,
+	}
+	if event.Err != nil {
+		entry.Error = event.Err.Error()
+	}
+	return entry
+}
+
+// OpenAuditLog opens the audit log at path for appending. If the file does not
+// exist yet, it is created.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{path: path, f: f}, nil
+}
+
+// Path returns the path to the underlying file.
+func (al *AuditLog) Path() string {
+	if al == nil {
+		return ""
+	}
+	return al.path
+}
+
+// BeforeStatement does nothing, since statements are only recorded once they
+// finish.
+func (al *AuditLog) BeforeStatement(t *Target, event *StatementEvent) error {
+	return nil
+}
+
+// AfterStatement records the finished statement described by event, writing
+// it to the audit log immediately.
+func (al *AuditLog) AfterStatement(t *Target, event *StatementEvent) error {
+	if al == nil {
+		return nil
+	}
+	line, err := json.Marshal(newAuditEntry(t, event))
+	if err != nil {
+		return err
+	}
+	al.Lock()
+	defer al.Unlock()
+	if _, err := al.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Unable to write to audit log %s: %s", al.path, err)
+	}
+	if err := al.f.Sync(); err != nil {
+		return fmt.Errorf("Unable to write to audit log %s: %s", al.path, err)
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (al *AuditLog) Close() error {
+	if al == nil || al.f == nil {
+		return nil
+	}
+	al.Lock()
+	defer al.Unlock()
+	err := al.f.Close()
+	al.f = nil
+	return err
+}
+
+// auditTableHook is a StatementHook which inserts a row into a table on the
+// target's instance for each statement executed, as configured by the
+// audit-table option. The table must already exist; see the documentation of
+// audit-table for its required columns.
+type auditTableHook struct {
+	schema string
+	table  string
+}
+
+// BeforeStatement does nothing, since statements are only recorded once they
+// finish.
+func (ath auditTableHook) BeforeStatement(t *Target, event *StatementEvent) error {
+	return nil
+}
+
+// AfterStatement inserts a row describing the finished statement.
+func (ath auditTableHook) AfterStatement(t *Target, event *StatementEvent) error {
+	db, err := event.Instance.Connect(ath.schema, "")
+	if err != nil {
+		return err
+	}
+	entry := newAuditEntry(t, event)
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		       (executed_at, environment, instance, schema_name, object_type, object_name,
+		        diff_type, statement, shell_command, duration, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tengo.EscapeIdentifier(ath.table))
+	_, err = db.Exec(query, entry.Time, entry.Environment, entry.Instance, entry.Schema, entry.ObjectType, entry.ObjectName,
+		entry.DiffType, entry.Statement, entry.ShellCommand, entry.Duration, entry.Error)
+	if err != nil {
+		return fmt.Errorf("Unable to insert into audit table %s.%s: %s", tengo.EscapeIdentifier(ath.schema), tengo.EscapeIdentifier(ath.table), err)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package applier
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// cacheFormatVersion is included in every cache checksum, so that cached
+// schemas are invalidated whenever the cache format or introspection logic
+// changes incompatibly.
+const cacheFormatVersion = "2"
+
+// cacheChecksumQueries returns queries whose results change whenever a table
+// or routine is created, altered, or dropped, but which are much cheaper to
+// run than a full introspection, since no SHOW CREATE commands are needed.
+// Columns which change due to DML alone, such as row counts and AUTO_INCREMENT
+// values, are intentionally excluded. Since instant or in-place ALTERs do not
+// necessarily affect a table's create_time, each attribute which such an ALTER
+// can modify must be covered, using whichever columns flavor supports. The
+// first query must always be the one for the schema itself.
+func cacheChecksumQueries(flavor tengo.Flavor) []string {
+	var generationExpression string
+	if flavor.GeneratedColumns() {
+		generationExpression = ", generation_expression"
+	}
+	var indexVisibility string
+	if flavor.MySQLishMinVersion(8, 0, 13) {
+		indexVisibility = ", is_visible, expression"
+	} else if flavor.MySQLishMinVersion(8, 0) {
+		indexVisibility = ", is_visible"
+	}
+	queries := []string{
+		`SELECT default_character_set_name, default_collation_name
+		 FROM   information_schema.schemata
+		 WHERE  schema_name = ?`,
+		`SELECT table_name, table_type, engine, create_time, table_collation, create_options, table_comment
+		 FROM   information_schema.tables
+		 WHERE  table_schema = ?
+		 ORDER BY table_name`,
+		`SELECT table_name, column_name, ordinal_position, column_type, is_nullable, column_default, extra, collation_name, column_comment` + generationExpression + `
+		 FROM   information_schema.columns
+		 WHERE  table_schema = ?
+		 ORDER BY table_name, ordinal_position`,
+		`SELECT table_name, index_name, seq_in_index, column_name, non_unique, sub_part, index_type, index_comment` + indexVisibility + `
+		 FROM   information_schema.statistics
+		 WHERE  table_schema = ?
+		 ORDER BY table_name, index_name, seq_in_index`,
+		`SELECT table_name, constraint_name, referenced_table_name, update_rule, delete_rule
+		 FROM   information_schema.referential_constraints
+		 WHERE  constraint_schema = ?
+		 ORDER BY table_name, constraint_name`,
+		`SELECT table_name, partition_name, subpartition_name, partition_method, subpartition_method,
+		        partition_expression, subpartition_expression, partition_description, partition_comment, tablespace_name
+		 FROM   information_schema.partitions
+		 WHERE  table_schema = ? AND partition_name IS NOT NULL
+		 ORDER BY table_name, partition_ordinal_position, subpartition_ordinal_position`,
+		`SELECT routine_type, routine_name, created, last_altered
+		 FROM   information_schema.routines
+		 WHERE  routine_schema = ?
+		 ORDER BY routine_type, routine_name`,
+	}
+	if flavor.MySQLishMinVersion(8, 0, 16) {
+		queries = append(queries,
+			`SELECT tc.table_name, cc.constraint_name, cc.check_clause, tc.enforced
+			 FROM   information_schema.check_constraints cc
+			 JOIN   information_schema.table_constraints tc
+			        ON  tc.constraint_schema = cc.constraint_schema
+			        AND tc.constraint_name = cc.constraint_name
+			        AND tc.constraint_type = 'CHECK'
+			 WHERE  cc.constraint_schema = ?
+			 ORDER BY tc.table_name, cc.constraint_name`)
+	} else if flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+		queries = append(queries,
+			`SELECT constraint_name, check_clause
+			 FROM   information_schema.check_constraints
+			 WHERE  constraint_schema = ?
+			 ORDER BY constraint_name`)
+	}
+	return queries
+}
+
+// cacheChecksum returns a hex-encoded SHA-256 hash of the results of
+// cacheChecksumQueries for the named schema on inst. If the schema does not
+// exist, sql.ErrNoRows is returned.
+func cacheChecksum(inst *tengo.Instance, schemaName string) (string, error) {
+	db, err := inst.Connect("information_schema", "")
+	if err != nil {
+		return "", err
+	}
+	flavor := inst.Flavor()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", cacheFormatVersion, flavor)
+	for n, query := range cacheChecksumQueries(flavor) {
+		rows, err := db.Query(query, schemaName)
+		if err != nil {
+			return "", err
+		}
+		cols, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return "", err
+		}
+		values := make([]sql.RawBytes, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		var rowCount int
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return "", err
+			}
+			// Each value is NUL-terminated, with NULLs represented by a lone SOH,
+			// since neither can appear in any of them
+			for _, value := range values {
+				if value == nil {
+					h.Write([]byte{1})
+				}
+				h.Write(append(value, 0))
+			}
+			rowCount++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return "", err
+		} else if n == 0 && rowCount == 0 {
+			return "", sql.ErrNoRows
+		}
+		fmt.Fprintf(h, "\x00%d\x00", rowCount)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheEntry represents one schema in an introspection cache: the checksum of
+// its metadata at the time it was introspected, along with the JSON encoding of
+// the introspected *tengo.Schema.
+type cacheEntry struct {
+	Checksum string          `json:"checksum"`
+	Schema   json.RawMessage `json:"schema"`
+}
+
+// schemaCache is implemented by types which can persist cacheEntry values.
+type schemaCache interface {
+	load(inst *tengo.Instance, schemaName string) (entry cacheEntry, ok bool, err error)
+	store(inst *tengo.Instance, schemaName string, entry cacheEntry) error
+}
+
+// CacheFile stores introspected schemas in a local JSON file, as configured by
+// the introspection-cache option. The file is read once upon opening, and only
+// rewritten upon Close, if any entries changed.
+//
+// All methods are safe to call on a nil *CacheFile, in which case nothing is
+// cached. Methods may also be called concurrently from multiple workers.
+type CacheFile struct {
+	sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// OpenCacheFile reads any cached schemas from the file at path. If the file
+// does not exist yet, it will be created upon Close.
+func OpenCacheFile(path string) (*CacheFile, error) {
+	cf := &CacheFile{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+	}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cf, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &cf.entries); err != nil {
+		return nil, fmt.Errorf("Introspection cache file %s is malformed: %s", path, err)
+	}
+	return cf, nil
+}
+
+// Path returns the path to the underlying file.
+func (cf *CacheFile) Path() string {
+	if cf == nil {
+		return ""
+	}
+	return cf.path
+}
+
+func (cf *CacheFile) load(inst *tengo.Instance, schemaName string) (cacheEntry, bool, error) {
+	if cf == nil {
+		return cacheEntry{}, false, nil
+	}
+	cf.Lock()
+	defer cf.Unlock()
+	entry, ok := cf.entries[inst.String()+"/"+schemaName]
+	return entry, ok, nil
+}
+
+func (cf *CacheFile) store(inst *tengo.Instance, schemaName string, entry cacheEntry) error {
+	if cf == nil {
+		return nil
+	}
+	cf.Lock()
+	defer cf.Unlock()
+	cf.entries[inst.String()+"/"+schemaName] = entry
+	cf.dirty = true
+	return nil
+}
+
+// Close writes the cache file if any entries have changed. The new contents
+// are written to a temporary file, which then replaces the original, so that
+// the cache is never left partially written.
+func (cf *CacheFile) Close() error {
+	if cf == nil {
+		return nil
+	}
+	cf.Lock()
+	defer cf.Unlock()
+	if !cf.dirty {
+		return nil
+	}
+	contents, err := json.Marshal(cf.entries)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(cf.path), "."+filepath.Base(cf.path)+".")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), cf.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	cf.dirty = false
+	return nil
+}
+
+// cacheTable stores introspected schemas in a table on each target's own
+// instance, as configured by the introspection-cache-table option. The table
+// must already exist; see the documentation of introspection-cache-table for
+// its required columns.
+type cacheTable struct {
+	schema string
+	table  string
+}
+
+func (ct cacheTable) String() string {
+	return tengo.EscapeIdentifier(ct.schema) + "." + tengo.EscapeIdentifier(ct.table)
+}
+
+func (ct cacheTable) load(inst *tengo.Instance, schemaName string) (entry cacheEntry, ok bool, err error) {
+	db, err := inst.Connect(ct.schema, "")
+	if err != nil {
+		return entry, false, err
+	}
+	var data string
+	query := fmt.Sprintf("SELECT checksum, schema_json FROM %s WHERE schema_name = ?", tengo.EscapeIdentifier(ct.table))
+	err = db.QueryRow(query, schemaName).Scan(&entry.Checksum, &data)
+	if err == sql.ErrNoRows {
+		return entry, false, nil
+	} else if err != nil {
+		return entry, false, fmt.Errorf("Unable to query introspection cache table %s: %s", ct, err)
+	}
+	entry.Schema = json.RawMessage(data)
+	return entry, true, nil
+}
+
+func (ct cacheTable) store(inst *tengo.Instance, schemaName string, entry cacheEntry) error {
+	db, err := inst.Connect(ct.schema, "")
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("REPLACE INTO %s (schema_name, checksum, schema_json) VALUES (?, ?, ?)", tengo.EscapeIdentifier(ct.table))
+	if _, err := db.Exec(query, schemaName, entry.Checksum, string(entry.Schema)); err != nil {
+		return fmt.Errorf("Unable to update introspection cache table %s: %s", ct, err)
+	}
+	return nil
+}
+
+// schemaCache returns the cache configured for t, or nil if none. The
+// introspection-cache-table option takes precedence over a CacheFile.
+func (t *Target) schemaCache() (schemaCache, error) {
+	if value := t.Dir.Config.Get("introspection-cache-table"); value != "" {
+		parts := strings.Split(value, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, ConfigError(fmt.Sprintf("Option introspection-cache-table must be in the format schema.table, instead found %q", value))
+		}
+		return cacheTable{schema: parts[0], table: parts[1]}, nil
+	} else if t.Cache != nil {
+		return t.Cache, nil
+	}
+	return nil, nil
+}
+
+// useCachedSchema returns true if a cached schema may be used in place of
+// introspecting t's schema. Cached schemas are only used for generating DDL
+// which will not be executed, and never when checking convergence after a push,
+// or when the no-cache option is enabled.
+func (t *Target) useCachedSchema() bool {
+	return t.Dir.Config.GetBool("dry-run") && !t.checkOnly && !t.Dir.Config.GetBool("no-cache")
+}
+
+// cachedSchemaFromInstance behaves like SchemaFromInstance, but returns the
+// schema from cache if its metadata checksum has not changed since it was
+// cached. Otherwise, the schema is introspected fully, and the cache is
+// updated. Problems with the cache itself are logged, but otherwise ignored.
+func (t *Target) cachedSchemaFromInstance(cache schemaCache) (*tengo.Schema, error) {
+	checksum, err := cacheChecksum(t.Instance, t.SchemaName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		log.Warnf("Unable to compute introspection cache checksum for %s %s: %s", t.Instance, t.SchemaName, err)
+		schema, err := t.Instance.Schema(t.SchemaName)
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+		return schema, err
+	}
+	if t.useCachedSchema() {
+		if entry, ok, err := cache.load(t.Instance, t.SchemaName); err != nil {
+			log.Warnf("Unable to read introspection cache for %s %s: %s", t.Instance, t.SchemaName, err)
+		} else if ok && entry.Checksum == checksum {
+			var schema tengo.Schema
+			if err := json.Unmarshal(entry.Schema, &schema); err == nil {
+				log.Debugf("Using cached introspection of %s %s", t.Instance, t.SchemaName)
+				return &schema, nil
+			}
+			log.Warnf("Ignoring malformed introspection cache entry for %s %s: %s", t.Instance, t.SchemaName, err)
+		}
+	}
+	schema, err := t.Instance.Schema(t.SchemaName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(schema)
+	if err == nil {
+		err = cache.store(t.Instance, t.SchemaName, cacheEntry{Checksum: checksum, Schema: data})
+	}
+	if err != nil {
+		log.Warnf("Unable to update introspection cache for %s %s: %s", t.Instance, t.SchemaName, err)
+	}
+	return schema, nil
+}
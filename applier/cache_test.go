@@ -0,0 +1,185 @@
+package applier
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestCacheFile(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	path := "testdata/.scratch/introspection.cache"
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+
+	// A nil CacheFile caches nothing, but must not panic
+	var nilCache *CacheFile
+	if err := nilCache.store(inst, "product", cacheEntry{Checksum: "abc"}); err != nil || nilCache.Close() != nil {
+		t.Error("Unexpected behavior from nil CacheFile")
+	} else if _, ok, _ := nilCache.load(inst, "product"); ok {
+		t.Error("Expected nil CacheFile to never return an entry")
+	}
+
+	// The cached schema should survive a round-trip through the file intact
+	table := &tengo.Table{
+		Name:               "widgets",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}, {Name: "name", TypeInDB: "varchar(30)", Nullable: true, Default: "NULL"}},
+	}
+	table.PrimaryKey = &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Parts: []tengo.IndexPart{{ColumnName: "id"}}}
+	table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+	schema := &tengo.Schema{Name: "product", CharSet: "latin1", Collation: "latin1_swedish_ci", Tables: []*tengo.Table{table}}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Unexpected error from Marshal: %s", err)
+	}
+	cf, err := OpenCacheFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error from OpenCacheFile: %s", err)
+	}
+	if err := cf.store(inst, "product", cacheEntry{Checksum: "abc", Schema: data}); err != nil {
+		t.Fatalf("Unexpected error from store: %s", err)
+	}
+	if err := cf.Close(); err != nil {
+		t.Fatalf("Unexpected error from Close: %s", err)
+	}
+	if cf, err = OpenCacheFile(path); err != nil {
+		t.Fatalf("Unexpected error from OpenCacheFile: %s", err)
+	}
+	if _, ok, _ := cf.load(inst, "analytics"); ok {
+		t.Error("Expected no cache entry for a different schema")
+	}
+	entry, ok, err := cf.load(inst, "product")
+	if !ok || err != nil || entry.Checksum != "abc" {
+		t.Fatalf("Unexpected result from load: %+v, %t, %v", entry, ok, err)
+	}
+	var cached tengo.Schema
+	if err := json.Unmarshal(entry.Schema, &cached); err != nil {
+		t.Fatalf("Unexpected error from Unmarshal: %s", err)
+	}
+	if !reflect.DeepEqual(schema, &cached) {
+		t.Errorf("Cached schema does not match original: %+v vs %+v", cached, schema)
+	}
+	if diff := tengo.NewSchemaDiff(schema, &cached); len(diff.ObjectDiffs()) > 0 {
+		t.Errorf("Expected no differences between cached and original schema, instead found %v", diff.ObjectDiffs())
+	}
+
+	// Malformed cache files should be rejected
+	fs.WriteTestFile(t, path, "not json\n")
+	if _, err := OpenCacheFile(path); err == nil {
+		t.Error("Expected error from malformed cache file, but err was nil")
+	}
+}
+
+func TestCacheChecksumQueries(t *testing.T) {
+	// Each flavor should only query metadata columns and tables it supports
+	cases := []struct {
+		flavor   tengo.Flavor
+		present  []string
+		excluded []string
+	}{
+		{tengo.FlavorMySQL56, []string{"index_comment", "information_schema.partitions"}, []string{"generation_expression", "is_visible", "check_constraints"}},
+		{tengo.FlavorMySQL57, []string{"generation_expression"}, []string{"is_visible", "check_constraints"}},
+		{tengo.Flavor{Vendor: tengo.VendorMySQL, Major: 8, Minor: 0, Patch: 12}, []string{"is_visible"}, []string{"is_visible, expression", "check_constraints"}},
+		{tengo.Flavor{Vendor: tengo.VendorMySQL, Major: 8, Minor: 0, Patch: 16}, []string{"is_visible, expression", "check_constraints", "enforced"}, nil},
+		{tengo.FlavorMariaDB103, []string{"generation_expression", "check_constraints"}, []string{"is_visible", "enforced"}},
+	}
+	for _, c := range cases {
+		queries := cacheChecksumQueries(c.flavor)
+		if !strings.Contains(queries[0], "information_schema.schemata") {
+			t.Errorf("Flavor %s: expected first query to be for the schema, instead found %s", c.flavor, queries[0])
+		}
+		all := strings.Join(queries, "\n")
+		for _, str := range c.present {
+			if !strings.Contains(all, str) {
+				t.Errorf("Flavor %s: expected queries to include %q, but they did not", c.flavor, str)
+			}
+		}
+		for _, str := range c.excluded {
+			if strings.Contains(all, str) {
+				t.Errorf("Flavor %s: expected queries to not include %q, but they did", c.flavor, str)
+			}
+		}
+	}
+}
+
+func (s ApplierIntegrationSuite) TestCachedSchemaFromInstance(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	if _, err := s.d[0].SourceSQL("testdata/setup.sql"); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	cache, err := OpenCacheFile("testdata/.scratch/introspection.cache")
+	if err != nil {
+		t.Fatalf("Unexpected error from OpenCacheFile: %s", err)
+	}
+	getTarget := func(flags string) *Target {
+		return &Target{
+			Instance:   s.d[0].Instance,
+			Dir:        getDir(t, "testdata/simple", flags),
+			SchemaName: "analytics",
+			Cache:      cache,
+		}
+	}
+
+	// The first introspection populates the cache, even outside of dry-run
+	target := getTarget("")
+	if schema, err := target.SchemaFromInstance(); err != nil || schema == nil {
+		t.Fatalf("Unexpected result from SchemaFromInstance: %v, %v", schema, err)
+	}
+	entry, ok, _ := cache.load(s.d[0].Instance, "analytics")
+	if !ok {
+		t.Fatal("Expected cache to be populated, but it was not")
+	}
+
+	// In dry-run mode, an unchanged schema is obtained from the cache. Tamper
+	// with the cached copy to confirm it is the one being used.
+	var tampered tengo.Schema
+	json.Unmarshal(entry.Schema, &tampered)
+	tampered.Tables = tampered.Tables[1:]
+	entry.Schema, _ = json.Marshal(tampered)
+	cache.store(s.d[0].Instance, "analytics", entry)
+	target = getTarget("--dry-run")
+	schema, err := target.SchemaFromInstance()
+	if err != nil || len(schema.Tables) != len(tampered.Tables) {
+		t.Errorf("Expected cached schema to be used, but it was not: %v", err)
+	}
+
+	// With no-cache, or once the schema changes, full introspection is used
+	target = getTarget("--dry-run --no-cache")
+	if schema, err = target.SchemaFromInstance(); err != nil || len(schema.Tables) == len(tampered.Tables) {
+		t.Errorf("Expected cached schema to be ignored with no-cache, but it was not: %v", err)
+	}
+	cache.store(s.d[0].Instance, "analytics", entry)
+	db, err := s.d[0].Connect("analytics", "")
+	if err != nil {
+		t.Fatalf("Unable to connect to DockerizedInstance: %s", err)
+	}
+	if _, err := db.Exec("ALTER TABLE pageviews ADD COLUMN cached int"); err != nil {
+		t.Fatalf("Unexpected error from ALTER TABLE: %s", err)
+	}
+	target = getTarget("--dry-run")
+	if schema, err = target.SchemaFromInstance(); err != nil || len(schema.Tables) == len(tampered.Tables) {
+		t.Errorf("Expected cached schema to be ignored after schema change, but it was not: %v", err)
+	}
+
+	// Nonexistent schemas are never cached
+	target.SchemaName = "doesnt_exist"
+	if schema, err = target.SchemaFromInstance(); schema != nil || err != nil {
+		t.Errorf("Expected nonexistent schema to return nil, nil; instead found %v, %v", schema, err)
+	}
+	if _, ok, _ := cache.load(s.d[0].Instance, "doesnt_exist"); ok {
+		t.Error("Expected nonexistent schema to not be cached")
+	}
+}
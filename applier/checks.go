@@ -0,0 +1,150 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/tengo"
+)
+
+// checkDiff represents an ALTER TABLE which drops, adds, and/or changes the
+// enforcement of CHECK constraints. A constraint with a modified expression is
+// both dropped and re-added. It satisfies the tengo.ObjectDiff interface.
+type checkDiff struct {
+	table   *tengo.Table
+	drop    []introspect.CheckConstraint
+	add     []introspect.CheckConstraint
+	enforce []introspect.CheckConstraint // desired state of constraints which only differ in enforcement
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (cd *checkDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// altered.
+func (cd *checkDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: cd.table.Name}
+}
+
+// Statement returns the full ALTER TABLE statement.
+func (cd *checkDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(cd.table.Name) {
+		return "", nil
+	}
+	clauses, _ := cd.Clauses(mods)
+	return fmt.Sprintf("%s %s", cd.table.AlterStatement(), clauses), nil
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ". MariaDB drops CHECK constraints using DROP
+// CONSTRAINT, whereas MySQL 8.0.16-8.0.18 only support DROP CHECK. Enforcement
+// changes only occur in MySQL, since MariaDB always enforces CHECK constraints.
+func (cd *checkDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	var clauses []string
+	if mods.AlgorithmClause != "" {
+		clauses = append(clauses, fmt.Sprintf("ALGORITHM=%s", strings.ToUpper(mods.AlgorithmClause)))
+	}
+	if mods.LockClause != "" {
+		clauses = append(clauses, fmt.Sprintf("LOCK=%s", strings.ToUpper(mods.LockClause)))
+	}
+	dropKeyword := "DROP CHECK"
+	if mods.Flavor.Vendor == tengo.VendorMariaDB {
+		dropKeyword = "DROP CONSTRAINT"
+	}
+	for _, check := range cd.drop {
+		clauses = append(clauses, fmt.Sprintf("%s %s", dropKeyword, tengo.EscapeIdentifier(check.Name)))
+	}
+	for _, check := range cd.add {
+		clauses = append(clauses, fmt.Sprintf("ADD CONSTRAINT %s %s", tengo.EscapeIdentifier(check.Name), check.Definition()))
+	}
+	for _, check := range cd.enforce {
+		enforcement := "NOT ENFORCED"
+		if check.Enforced {
+			enforcement = "ENFORCED"
+		}
+		clauses = append(clauses, fmt.Sprintf("ALTER CHECK %s %s", tengo.EscapeIdentifier(check.Name), enforcement))
+	}
+	return strings.Join(clauses, ", "), nil
+}
+
+// rebuildReason returns a human-readable description of why the ALTER TABLE
+// copies the table, or an empty string if it does not. Adding an enforced
+// CHECK constraint, or enforcing an existing one, requires a table copy so
+// that all existing rows are validated; other changes only modify metadata.
+func (cd *checkDiff) rebuildReason() string {
+	var names []string
+	for _, check := range append(cd.add, cd.enforce...) {
+		if check.Enforced {
+			names = append(names, tengo.EscapeIdentifier(check.Name))
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	noun := "constraint"
+	if len(names) > 1 {
+		noun = "constraints"
+	}
+	return fmt.Sprintf("CHECK %s %s enforced, which requires validating all existing rows", noun, strings.Join(names, ", "))
+}
+
+// extractChecks removes table-level CHECK constraints from the CreateStatement
+// of tables existing in both schemaFromInstance and schemaFromDir, so that
+// tengo can diff the rest of their definitions normally, even though it does
+// not support CHECK constraints directly. A checkDiff is returned for each
+// table whose constraints differ. Modified dir tables are replaced with
+// copies, since the same desired schema may be shared by other targets.
+func extractChecks(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) []tengo.ObjectDiff {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	var diffs []tengo.ObjectDiff
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		instTable := instTables[table.Name]
+		if instTable == nil {
+			continue
+		}
+		instBase, instChecks := introspect.ParseCreateChecks(instTable.CreateStatement)
+		dirBase, dirChecks := introspect.ParseCreateChecks(table.CreateStatement)
+		if instChecks == nil && dirChecks == nil {
+			continue // neither side has CHECK constraints
+		}
+		stripTableClause(instTable, instBase, flavor)
+		tableCopy := *table
+		stripTableClause(&tableCopy, dirBase, flavor)
+		dirTables[n] = &tableCopy
+
+		cd := &checkDiff{table: &tableCopy}
+		instByName := make(map[string]introspect.CheckConstraint, len(instChecks))
+		for _, check := range instChecks {
+			instByName[check.Name] = check
+		}
+		dirByName := make(map[string]introspect.CheckConstraint, len(dirChecks))
+		for _, check := range dirChecks {
+			dirByName[check.Name] = check
+		}
+		for _, check := range instChecks {
+			if other, ok := dirByName[check.Name]; !ok || other.Expression != check.Expression {
+				cd.drop = append(cd.drop, check)
+			}
+		}
+		for _, check := range dirChecks {
+			if other, ok := instByName[check.Name]; !ok || other.Expression != check.Expression {
+				cd.add = append(cd.add, check)
+			} else if other.Enforced != check.Enforced {
+				cd.enforce = append(cd.enforce, check)
+			}
+		}
+		if len(cd.drop) > 0 || len(cd.add) > 0 || len(cd.enforce) > 0 {
+			diffs = append(diffs, cd)
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	return diffs
+}
@@ -0,0 +1,130 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestExtractChecks(t *testing.T) {
+	flavor := tengo.FlavorMariaDB103
+	makeTable := func(name string, checks ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "qty", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		if len(checks) > 0 {
+			table.CreateStatement = strings.Replace(table.CreateStatement, "\n)", ",\n  "+strings.Join(checks, ",\n  ")+"\n)", 1)
+			table.UnsupportedDDL = true
+		}
+		return table
+	}
+	positive := "CONSTRAINT `positive` CHECK (`qty` > 0)"
+	small := "CONSTRAINT `small` CHECK (`qty` < 100)"
+	smaller := "CONSTRAINT `small` CHECK (`qty` < 10)"
+	schemaFromInstance := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", positive),
+			makeTable("added"),
+			makeTable("dropped", positive, small),
+			makeTable("modified", positive, small),
+			makeTable("none"),
+		},
+	}
+	desired := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", positive),
+			makeTable("added", positive, small),
+			makeTable("dropped", small),
+			makeTable("modified", positive, smaller),
+			makeTable("none"),
+			makeTable("created", positive),
+		},
+	}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: desired.Tables}
+	diffs := extractChecks(schemaFromInstance, schemaFromDir, flavor)
+	expected := map[string]string{
+		"added":    "ALTER TABLE `added` ADD CONSTRAINT `positive` CHECK (`qty` > 0), ADD CONSTRAINT `small` CHECK (`qty` < 100)",
+		"dropped":  "ALTER TABLE `dropped` DROP CONSTRAINT `positive`",
+		"modified": "ALTER TABLE `modified` DROP CONSTRAINT `small`, ADD CONSTRAINT `small` CHECK (`qty` < 10)",
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+	}
+	mods := tengo.StatementModifiers{Flavor: flavor}
+	for _, diff := range diffs {
+		key := diff.ObjectKey()
+		if stmt, err := diff.Statement(mods); stmt != expected[key.Name] || err != nil {
+			t.Errorf("Unexpected result from Statement() for %s: %q, %v", key, stmt, err)
+		}
+		if algo, _ := ClassifyAlter(diff, flavor); (algo == AlterAlgorithmCopy) != (key.Name != "dropped") {
+			t.Errorf("Unexpected algorithm %s for %s", algo, key)
+		}
+	}
+
+	// MySQL uses DROP CHECK instead of DROP CONSTRAINT
+	for _, diff := range diffs {
+		if diff.ObjectKey().Name == "dropped" {
+			mods.Flavor = tengo.FlavorMySQL80
+			if stmt, _ := diff.Statement(mods); stmt != "ALTER TABLE `dropped` DROP CHECK `positive`" {
+				t.Errorf("Unexpected statement for MySQL: %s", stmt)
+			}
+		}
+	}
+
+	// Tables existing on both sides should no longer have CHECK constraints, and
+	// should now be supported; the desired schema's original tables must not
+	// have been modified
+	for n, table := range schemaFromDir.Tables {
+		if table.Name == "created" || table.Name == "none" {
+			if table != desired.Tables[n] {
+				t.Errorf("Expected table %s to be left as-is", table.Name)
+			}
+			continue
+		}
+		if strings.Contains(table.CreateStatement, "CHECK") || table.UnsupportedDDL {
+			t.Errorf("Expected table %s to no longer have CHECK constraints, but it does: %s", table.Name, table.CreateStatement)
+		}
+	}
+	for _, table := range desired.Tables {
+		if strings.Contains(table.CreateStatement, "CHECK") != (table.Name != "none") {
+			t.Errorf("Desired schema's table %s unexpectedly modified: %s", table.Name, table.CreateStatement)
+		}
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.FilteredTableDiffs(tengo.DiffTypeAlter)) > 0 {
+		t.Errorf("Expected no other ALTER TABLEs after CHECK extraction, instead found %v", diff.FilteredTableDiffs(tengo.DiffTypeAlter))
+	}
+
+	// In MySQL, a change in enforcement alone uses ALTER CHECK
+	flavor = tengo.FlavorMySQL80
+	enforced := "CONSTRAINT `positive` CHECK ((`qty` > 0))"
+	notEnforced := enforced + " /*!80016 NOT ENFORCED */"
+	schemaFromInstance = &tengo.Schema{Name: "product", Tables: []*tengo.Table{makeTable("enable", notEnforced), makeTable("disable", enforced)}}
+	schemaFromDir = &tengo.Schema{Name: "product", Tables: []*tengo.Table{makeTable("enable", enforced), makeTable("disable", notEnforced)}}
+	diffs = extractChecks(schemaFromInstance, schemaFromDir, flavor)
+	expected = map[string]string{
+		"enable":  "ALTER TABLE `enable` ALTER CHECK `positive` ENFORCED",
+		"disable": "ALTER TABLE `disable` ALTER CHECK `positive` NOT ENFORCED",
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+	}
+	mods.Flavor = flavor
+	for _, diff := range diffs {
+		key := diff.ObjectKey()
+		if stmt, err := diff.Statement(mods); stmt != expected[key.Name] || err != nil {
+			t.Errorf("Unexpected result from Statement() for %s: %q, %v", key, stmt, err)
+		}
+		if algo, _ := ClassifyAlter(diff, flavor); (algo == AlterAlgorithmCopy) != (key.Name == "enable") {
+			t.Errorf("Unexpected algorithm %s for %s", algo, key)
+		}
+	}
+}
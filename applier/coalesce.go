@@ -0,0 +1,195 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// combinedTableDiff represents a single ALTER TABLE which combines the clauses
+// of several ALTER TABLEs on the same table, so that the server only needs to
+// rebuild the table once. It satisfies the tengo.ObjectDiff interface.
+type combinedTableDiff struct {
+	diffs []tengo.ObjectDiff // each is an ALTER TABLE of the same table, and satisfies clauser
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (cd *combinedTableDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// altered.
+func (cd *combinedTableDiff) ObjectKey() tengo.ObjectKey {
+	return cd.diffs[0].ObjectKey()
+}
+
+// Statement returns the full ALTER TABLE statement. If any of the combined
+// diffs are forbidden by mods, the returned error will be a
+// *tengo.ForbiddenDiffError describing the full statement.
+func (cd *combinedTableDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(cd.ObjectKey().Name) {
+		return "", nil
+	}
+	clauses, err := cd.Clauses(mods)
+	if clauses == "" {
+		return "", err
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s %s", tengo.EscapeIdentifier(cd.ObjectKey().Name), clauses)
+	if fde, isForbiddenDiff := err.(*tengo.ForbiddenDiffError); isForbiddenDiff {
+		fde.Statement = stmt
+	}
+	return stmt, err
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ". Any ALGORITHM or LOCK clause from mods is only
+// included once, at the beginning. The clauses of a tengo.TableDiff are always
+// placed last, since they may end with clauses that must follow all others,
+// such as WITH VALIDATION.
+func (cd *combinedTableDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	var clauses []string
+	if mods.AlgorithmClause != "" {
+		clauses = append(clauses, fmt.Sprintf("ALGORITHM=%s", strings.ToUpper(mods.AlgorithmClause)))
+	}
+	if mods.LockClause != "" {
+		clauses = append(clauses, fmt.Sprintf("LOCK=%s", strings.ToUpper(mods.LockClause)))
+	}
+	prefixLen := len(clauses)
+	innerMods := mods
+	innerMods.AlgorithmClause, innerMods.LockClause = "", ""
+
+	var tableClauses string
+	var forbiddenErr error
+	for _, diff := range cd.diffs {
+		diffClauses, err := diff.(clauser).Clauses(innerMods)
+		if _, isForbiddenDiff := err.(*tengo.ForbiddenDiffError); isForbiddenDiff {
+			forbiddenErr = err
+		} else if err != nil {
+			return "", err
+		}
+		if _, ok := diff.(*tengo.TableDiff); ok {
+			tableClauses = diffClauses
+		} else if diffClauses != "" {
+			clauses = append(clauses, diffClauses)
+		}
+	}
+	if tableClauses != "" {
+		clauses = append(clauses, tableClauses)
+	}
+	if len(clauses) == prefixLen {
+		return "", forbiddenErr
+	}
+	return strings.Join(clauses, ", "), forbiddenErr
+}
+
+// tableDiff returns the tengo.TableDiff among the combined diffs, or nil if
+// there is none.
+func (cd *combinedTableDiff) tableDiff() *tengo.TableDiff {
+	for _, diff := range cd.diffs {
+		if td, ok := diff.(*tengo.TableDiff); ok {
+			return td
+		}
+	}
+	return nil
+}
+
+// unwrapTableDiff returns objDiff as a *tengo.TableDiff, if it is one or if it
+// is a combinedTableDiff containing one. Otherwise, nil is returned.
+func unwrapTableDiff(objDiff tengo.ObjectDiff) *tengo.TableDiff {
+	switch diff := objDiff.(type) {
+	case *tengo.TableDiff:
+		return diff
+	case *combinedTableDiff:
+		return diff.tableDiff()
+	}
+	return nil
+}
+
+// coalesceAlters combines multiple ALTER TABLEs of the same table in objDiffs
+// into a single statement, so that a table requiring several rebuilds (for
+// example, a column change along with a tablespace move or encryption change)
+// is only rebuilt once. The combined statement is positioned where the table's
+// first ALTER TABLE was, and the relative order of all other diffs is
+// unchanged.
+//
+// ALTER TABLEs are not combined if doing so is unsafe or could cause the
+// statement to fail: ALTER TABLEs which only add foreign keys are kept separate
+// since they must run after any other tables are created; ALTER TABLEs of
+// partitioned tables are kept separate since partitioning clauses have special
+// placement rules; ALTER TABLEs which tengo cannot generate are kept separate
+// so that they do not prevent the other changes; and a table's ALTER TABLEs
+// generated by tengo are never combined with each other, since they were
+// intentionally split (see splitDependentAlters). Additionally, if mods
+// contain an ALGORITHM or LOCK clause, ALTER TABLEs which cannot use that
+// clause are kept separate from those that can, so that the clause is not
+// violated by the combination.
+func coalesceAlters(objDiffs []tengo.ObjectDiff, mods tengo.StatementModifiers) []tengo.ObjectDiff {
+	type groupKey struct {
+		name       string
+		compatible bool
+	}
+	groups := make(map[groupKey][]tengo.ObjectDiff)
+	keyFor := make([]*groupKey, len(objDiffs))
+	for n, objDiff := range objDiffs {
+		if !combinableAlter(objDiff, mods.Flavor) {
+			continue
+		}
+		key := groupKey{
+			name:       objDiff.ObjectKey().Name,
+			compatible: algorithmCompatible(objDiff, mods),
+		}
+		if _, ok := objDiff.(*tengo.TableDiff); ok && (&combinedTableDiff{diffs: groups[key]}).tableDiff() != nil {
+			continue // at most one tengo.TableDiff per combined statement
+		}
+		groups[key] = append(groups[key], objDiff)
+		keyFor[n] = &key
+	}
+
+	result := make([]tengo.ObjectDiff, 0, len(objDiffs))
+	emitted := make(map[groupKey]bool, len(groups))
+	for n, objDiff := range objDiffs {
+		key := keyFor[n]
+		if key == nil || len(groups[*key]) == 1 {
+			result = append(result, objDiff)
+		} else if !emitted[*key] {
+			result = append(result, &combinedTableDiff{diffs: groups[*key]})
+			emitted[*key] = true
+		}
+	}
+	return result
+}
+
+// combinableAlter returns true if objDiff is an ALTER TABLE which may be
+// combined with other ALTER TABLEs of the same table.
+func combinableAlter(objDiff tengo.ObjectDiff, flavor tengo.Flavor) bool {
+	switch diff := objDiff.(type) {
+	case *tablespaceDiff, *encryptionDiff, *checkDiff, *conversionDiff:
+		return true
+	case *tengo.TableDiff:
+		if diff.Type != tengo.DiffTypeAlter || diff.From.Partitioning != nil || diff.To.Partitioning != nil {
+			return false
+		}
+		if other, _ := diff.SplitAddForeignKeys(); other == nil {
+			return false
+		}
+		_, err := diff.Statement(tengo.StatementModifiers{AllowUnsafe: true, Flavor: flavor})
+		_, unsupported := err.(*tengo.UnsupportedDiffError)
+		return !unsupported
+	}
+	return false
+}
+
+// algorithmCompatible returns true if objDiff can be executed using the
+// ALGORITHM and LOCK clauses of mods, or if mods do not specify either clause.
+func algorithmCompatible(objDiff tengo.ObjectDiff, mods tengo.StatementModifiers) bool {
+	algo, _ := ClassifyAlter(objDiff, mods.Flavor)
+	switch {
+	case mods.AlgorithmClause == "instant":
+		return algo == AlterAlgorithmInPlace
+	case mods.AlgorithmClause == "inplace" || mods.LockClause == "none":
+		return algo != AlterAlgorithmCopy
+	}
+	return true
+}
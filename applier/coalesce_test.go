@@ -0,0 +1,133 @@
+package applier
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/tengo"
+)
+
+func TestCoalesceAlters(t *testing.T) {
+	makeTable := func(name string, colNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)"}},
+			PrimaryKey:         &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+		}
+		for _, colName := range colNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: colName, TypeInDB: "int(11)", Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	instSchema := &tengo.Schema{
+		Name:   "product",
+		Tables: []*tengo.Table{makeTable("widgets", "qty"), makeTable("gadgets", "widget_id"), makeTable("parts")},
+	}
+	dirSchema := &tengo.Schema{
+		Name:   "product",
+		Tables: []*tengo.Table{makeTable("widgets", "qty", "price"), makeTable("gadgets", "widget_id"), makeTable("parts")},
+	}
+	gadgets := dirSchema.Tables[1]
+	gadgets.ForeignKeys = []*tengo.ForeignKey{{
+		Name:                  "widget_fk",
+		ColumnNames:           []string{"widget_id"},
+		ReferencedTableName:   "widgets",
+		ReferencedColumnNames: []string{"id"},
+		UpdateRule:            "RESTRICT",
+		DeleteRule:            "CASCADE",
+	}}
+	gadgets.CreateStatement = gadgets.GeneratedCreateStatement(tengo.FlavorUnknown)
+
+	// The schema diff should contain an ALTER of widgets to add a column, and an
+	// ALTER of gadgets which only adds a foreign key. Tablespace and encryption
+	// changes are appended, similar to applyTarget.
+	objDiffs := tengo.NewSchemaDiff(instSchema, dirSchema).ObjectDiffs()
+	if len(objDiffs) != 2 {
+		t.Fatalf("Expected 2 object diffs, instead found %d", len(objDiffs))
+	}
+	objDiffs = append(objDiffs,
+		&tablespaceDiff{table: dirSchema.Tables[0], to: "ts1"},
+		&encryptionDiff{table: dirSchema.Tables[0], from: "N", to: "Y"},
+		&encryptionDiff{table: gadgets, from: "N", to: "Y"},
+		&tablespaceDiff{table: dirSchema.Tables[2], to: "ts1"},
+	)
+
+	expected := []string{
+		"ALTER TABLE `widgets` TABLESPACE `ts1`, ENCRYPTION='Y', ADD COLUMN `price` int(11) DEFAULT NULL",
+		"ALTER TABLE `gadgets` ADD CONSTRAINT `widget_fk` FOREIGN KEY (`widget_id`) REFERENCES `widgets` (`id`) ON DELETE CASCADE",
+		"ALTER TABLE `gadgets` ENCRYPTION='Y'",
+		"ALTER TABLE `parts` TABLESPACE `ts1`",
+	}
+	assertStatements := func(diffs []tengo.ObjectDiff, mods tengo.StatementModifiers, expected []string) {
+		t.Helper()
+		if len(diffs) != len(expected) {
+			t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+			return
+		}
+		for n, diff := range diffs {
+			if stmt, err := diff.Statement(mods); stmt != expected[n] || err != nil {
+				t.Errorf("Unexpected result from Statement() for diff[%d]: %q, %v", n, stmt, err)
+			}
+		}
+	}
+	assertStatements(coalesceAlters(objDiffs, tengo.StatementModifiers{}), tengo.StatementModifiers{}, expected)
+
+	// ALGORITHM and LOCK clauses should only appear once in the combined
+	// statement. With ALGORITHM=INPLACE, the encryption change cannot be combined
+	// with the other changes, since it requires a table copy.
+	mods := tengo.StatementModifiers{AlgorithmClause: "inplace", LockClause: "none"}
+	expected = []string{
+		"ALTER TABLE `widgets` ALGORITHM=INPLACE, LOCK=NONE, TABLESPACE `ts1`, ADD COLUMN `price` int(11) DEFAULT NULL",
+		"ALTER TABLE `gadgets` ALGORITHM=INPLACE, LOCK=NONE, ADD CONSTRAINT `widget_fk` FOREIGN KEY (`widget_id`) REFERENCES `widgets` (`id`) ON DELETE CASCADE",
+		"ALTER TABLE `widgets` ALGORITHM=INPLACE, LOCK=NONE, ENCRYPTION='Y'",
+		"ALTER TABLE `gadgets` ALGORITHM=INPLACE, LOCK=NONE, ENCRYPTION='Y'",
+		"ALTER TABLE `parts` ALGORITHM=INPLACE, LOCK=NONE, TABLESPACE `ts1`",
+	}
+	assertStatements(coalesceAlters(objDiffs, mods), mods, expected)
+
+	// Combined diffs should classify and describe all of their operations
+	combined := coalesceAlters(objDiffs, tengo.StatementModifiers{})[0]
+	if algo, reasons := ClassifyAlter(combined, tengo.FlavorMySQL57); algo != AlterAlgorithmCopy || len(reasons) != 3 {
+		t.Errorf("Unexpected classification of combined diff: %s %v", algo, reasons)
+	}
+	if unwrapTableDiff(combined) != objDiffs[0] {
+		t.Error("Expected combined diff to unwrap to its tengo.TableDiff")
+	}
+	if params := getConnectParams(combined, mybase.SimpleConfig(map[string]string{"foreign-key-checks": "0"})); params != "readTimeout=0" {
+		t.Errorf("Unexpected connect params for combined diff: %q", params)
+	}
+
+	// Noop and forbidden components should be handled properly
+	if stmt, err := combined.Statement(tengo.StatementModifiers{IgnoreTable: regexp.MustCompile("^widgets$")}); stmt != "" || err != nil {
+		t.Errorf("Expected combined diff to be a noop for ignored table, instead found %q, %v", stmt, err)
+	}
+	unsafe := &combinedTableDiff{diffs: []tengo.ObjectDiff{
+		tengo.NewAlterTable(dirSchema.Tables[0], instSchema.Tables[0]),
+		&tablespaceDiff{table: instSchema.Tables[0], from: "ts1"},
+	}}
+	if stmt, err := unsafe.Statement(tengo.StatementModifiers{}); !tengo.IsForbiddenDiff(err) || stmt == "" {
+		t.Errorf("Expected combined diff with unsafe component to return forbidden diff error, instead found %q, %v", stmt, err)
+	}
+
+	// Multiple tengo.TableDiffs of the same table should not be combined with
+	// each other, although other diffs may be combined with the first one
+	split := []tengo.ObjectDiff{objDiffs[0], objDiffs[0], &tablespaceDiff{table: dirSchema.Tables[0], to: "ts1"}}
+	if coalesced := coalesceAlters(split, tengo.StatementModifiers{}); len(coalesced) != 2 || coalesced[1] != objDiffs[0] {
+		t.Errorf("Unexpected result from coalescing multiple TableDiffs of the same table: %+v", coalesced)
+	}
+
+	// Partitioned tables should not be combined
+	td := tengo.NewAlterTable(instSchema.Tables[0], dirSchema.Tables[0])
+	partitioned := *td.To
+	partitioned.Partitioning = &tengo.TablePartitioning{}
+	td.To = &partitioned
+	if combinableAlter(td, tengo.FlavorUnknown) {
+		t.Error("Expected ALTER of partitioned table to not be combinable")
+	}
+}
@@ -0,0 +1,125 @@
+package applier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skeema/tengo"
+)
+
+// collationCache maps an instance's String() to its supported collations,
+// which in turn map collation names to their character set. This avoids
+// repeatedly querying the same instance when multiple dirs or schemas target
+// it.
+var collationCache struct {
+	sync.Mutex
+	instanceCollations map[string]map[string]string
+}
+
+func init() {
+	collationCache.instanceCollations = make(map[string]map[string]string)
+}
+
+// supportedCollations returns a map of collation name to character set name,
+// for all collations available on the supplied instance. Results are cached
+// per instance.
+func supportedCollations(instance *tengo.Instance) (map[string]string, error) {
+	collationCache.Lock()
+	defer collationCache.Unlock()
+	if collations, ok := collationCache.instanceCollations[instance.String()]; ok {
+		return collations, nil
+	}
+	db, err := instance.Connect("", "")
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Collation string `db:"collation_name"`
+		CharSet   string `db:"character_set_name"`
+	}
+	query := `
+		SELECT collation_name, character_set_name
+		FROM   information_schema.collations`
+	if err := db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+	collations := make(map[string]string, len(rows))
+	for _, row := range rows {
+		collations[strings.ToLower(row.Collation)] = strings.ToLower(row.CharSet)
+	}
+	collationCache.instanceCollations[instance.String()] = collations
+	return collations, nil
+}
+
+// unsupportedCollations examines the desired state of each created or altered
+// table and database among objDiffs, and returns a sorted list of
+// human-readable descriptions of any character set or collation which does not
+// exist in supported. Dropped objects are ignored, since their DDL does not
+// reference any character set or collation.
+func unsupportedCollations(objDiffs []tengo.ObjectDiff, supported map[string]string) []string {
+	charSets := make(map[string]bool, len(supported))
+	for _, charSet := range supported {
+		charSets[charSet] = true
+	}
+	problems := make(map[string]bool)
+	check := func(charSet, collation, usedBy string) {
+		if charSet != "" && !charSets[strings.ToLower(charSet)] {
+			problems[fmt.Sprintf("character set %s (used by %s)", charSet, usedBy)] = true
+		}
+		if collation != "" {
+			if _, ok := supported[strings.ToLower(collation)]; !ok {
+				problems[fmt.Sprintf("collation %s (used by %s)", collation, usedBy)] = true
+			}
+		}
+	}
+	for _, objDiff := range objDiffs {
+		if objDiff.DiffType() == tengo.DiffTypeDrop {
+			continue
+		}
+		switch od := objDiff.(type) {
+		case *tengo.DatabaseDiff:
+			if od.To != nil {
+				check(od.To.CharSet, od.To.Collation, "schema "+tengo.EscapeIdentifier(od.To.Name))
+			}
+		case *tengo.TableDiff:
+			if od.To == nil {
+				continue
+			}
+			tableKey := od.ObjectKey().String()
+			check(od.To.CharSet, od.To.Collation, tableKey)
+			for _, col := range od.To.Columns {
+				check(col.CharSet, col.Collation, fmt.Sprintf("column %s of %s", tengo.EscapeIdentifier(col.Name), tableKey))
+			}
+		case *conversionDiff:
+			check(od.charSet, od.collation, od.ObjectKey().String())
+		}
+	}
+	result := make([]string, 0, len(problems))
+	for problem := range problems {
+		result = append(result, problem)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkCollations confirms that t's instance supports every character set and
+// collation referenced by the created or altered tables and databases in
+// objDiffs. This catches a common problem when moving schemas between database
+// versions or vendors, for example utf8mb4_0900_ai_ci only exists in MySQL 8.0+.
+// An error is returned if anything is unsupported.
+func checkCollations(objDiffs []tengo.ObjectDiff, t *Target) error {
+	if len(objDiffs) == 0 {
+		return nil
+	}
+	supported, err := supportedCollations(t.Instance)
+	if err != nil {
+		return fmt.Errorf("Skipping %s %s: unable to obtain supported collations: %s", t.Instance, t.SchemaName, err)
+	}
+	problems := unsupportedCollations(objDiffs, supported)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Skipping %s %s: this database server (%s) does not support %s", t.Instance, t.SchemaName, t.Instance.Flavor(), strings.Join(problems, ", "))
+}
@@ -0,0 +1,78 @@
+package applier
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestUnsupportedCollations(t *testing.T) {
+	// Simulate a MySQL 5.7 server, which lacks MySQL 8's utf8mb4_0900 collations
+	supported := map[string]string{
+		"latin1_swedish_ci":  "latin1",
+		"utf8mb4_general_ci": "utf8mb4",
+		"utf8mb4_bin":        "utf8mb4",
+	}
+	makeTable := func(name, charSet, collation string) *tengo.Table {
+		return &tengo.Table{
+			Name:      name,
+			CharSet:   charSet,
+			Collation: collation,
+			Columns: []*tengo.Column{
+				{Name: "id", TypeInDB: "int unsigned"},
+				{Name: "code", TypeInDB: "char(3)", CharSet: "utf8mb4", Collation: "utf8mb4_bin"},
+				{Name: "name", TypeInDB: "varchar(40)", CharSet: charSet, Collation: collation},
+			},
+		}
+	}
+
+	// Supported charsets and collations, as well as dropped objects, should
+	// never be flagged
+	objDiffs := []tengo.ObjectDiff{
+		tengo.NewCreateTable(makeTable("ok", "utf8mb4", "utf8mb4_general_ci")),
+		tengo.NewDropTable(makeTable("dropped", "utf8mb4", "utf8mb4_0900_ai_ci")),
+		&tengo.DatabaseDiff{From: &tengo.Schema{Name: "foo"}, To: &tengo.Schema{Name: "foo", CharSet: "latin1", Collation: "latin1_swedish_ci"}},
+	}
+	if problems := unsupportedCollations(objDiffs, supported); len(problems) > 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+
+	// Unsupported collations and charsets should be flagged once per object
+	// using them, in sorted order
+	objDiffs = []tengo.ObjectDiff{
+		tengo.NewCreateTable(makeTable("new", "utf8mb4", "utf8mb4_0900_ai_ci")),
+		tengo.NewAlterTable(makeTable("existing", "latin1", "latin1_swedish_ci"), makeTable("existing", "utf8mb3", "utf8mb3_general_ci")),
+		&tengo.DatabaseDiff{From: &tengo.Schema{Name: "foo"}, To: &tengo.Schema{Name: "foo", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci"}},
+	}
+	expected := []string{
+		"character set utf8mb3 (used by column `name` of table `existing`)",
+		"character set utf8mb3 (used by table `existing`)",
+		"collation utf8mb3_general_ci (used by column `name` of table `existing`)",
+		"collation utf8mb3_general_ci (used by table `existing`)",
+		"collation utf8mb4_0900_ai_ci (used by column `name` of table `new`)",
+		"collation utf8mb4_0900_ai_ci (used by schema `foo`)",
+		"collation utf8mb4_0900_ai_ci (used by table `new`)",
+	}
+	if problems := unsupportedCollations(objDiffs, supported); fmt.Sprintf("%v", problems) != fmt.Sprintf("%v", expected) {
+		t.Errorf("Unexpected result from unsupportedCollations.\nExpected: %v\nActual:   %v", expected, problems)
+	}
+}
+
+func (s ApplierIntegrationSuite) TestSupportedCollations(t *testing.T) {
+	collations, err := supportedCollations(s.d[0].Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from supportedCollations: %s", err)
+	}
+	if collations["latin1_swedish_ci"] != "latin1" || collations["utf8mb4_bin"] != "utf8mb4" {
+		t.Errorf("Expected common collations to be present, but they were not: %v", collations)
+	}
+	if _, ok := collations["utf8mb4_0900_ai_ci"]; ok != s.d[0].Flavor().MySQLishMinVersion(8, 0) {
+		t.Errorf("Unexpected presence of utf8mb4_0900_ai_ci in %s: %t", s.d[0].Flavor(), ok)
+	}
+
+	// Subsequent calls should return the cached map
+	if again, err := supportedCollations(s.d[0].Instance); err != nil || len(again) != len(collations) {
+		t.Errorf("Expected cached result from second call, instead found %d collations, err=%v", len(again), err)
+	}
+}
@@ -0,0 +1,91 @@
+package applier
+
+import (
+	"github.com/skeema/tengo"
+)
+
+// ignoreColumnOrder adjusts the tables of schemaFromDir, so that columns which
+// also exist in the corresponding table of schemaFromInstance retain their
+// current relative order, and any new columns are placed at the end of the
+// table. This prevents generated ALTER TABLEs from moving existing columns via
+// MODIFY COLUMN ... AFTER, which requires a full rebuild of the table. Tables
+// that tengo does not fully support are left unchanged. Adjusted tables are
+// replaced with copies, since the tables of schemaFromDir are shared by all
+// targets of the dir, whose instances may each have a different column order.
+func ignoreColumnOrder(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		instTable := instTables[table.Name]
+		if instTable == nil || instTable.UnsupportedDDL || table.UnsupportedDDL || !columnsReordered(instTable, table) {
+			continue
+		}
+		if table.GeneratedCreateStatement(flavor) != table.CreateStatement {
+			continue
+		}
+		tableCopy := *table
+		tableCopy.Columns = alignedColumns(instTable, table)
+		tableCopy.CreateStatement = tableCopy.GeneratedCreateStatement(flavor)
+		dirTables[n] = &tableCopy
+	}
+	schemaFromDir.Tables = dirTables
+}
+
+// alignedColumns returns the columns of to, ordered such that columns with the
+// same name as a column in from appear in from's order, followed by any
+// remaining columns in their original relative order.
+func alignedColumns(from, to *tengo.Table) []*tengo.Column {
+	toCols := to.ColumnsByName()
+	result := make([]*tengo.Column, 0, len(to.Columns))
+	for _, fromCol := range from.Columns {
+		if toCol, ok := toCols[fromCol.Name]; ok {
+			result = append(result, toCol)
+		}
+	}
+	fromCols := from.ColumnsByName()
+	for _, toCol := range to.Columns {
+		if _, ok := fromCols[toCol.Name]; !ok {
+			result = append(result, toCol)
+		}
+	}
+	return result
+}
+
+// columnsReordered returns true if any columns that exist in both from and to
+// have a different relative order in to, or if any new columns in to are not
+// positioned after all of the columns that already exist in from.
+func columnsReordered(from, to *tengo.Table) bool {
+	aligned := alignedColumns(from, to)
+	for n := range aligned {
+		if aligned[n] != to.Columns[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// existingColumnsReordered returns true if any columns that exist in both from
+// and to have a different relative order in to. Unlike columnsReordered, the
+// positions of new columns are not considered.
+func existingColumnsReordered(from, to *tengo.Table) bool {
+	toCols := to.ColumnsByName()
+	fromCols := from.ColumnsByName()
+	var toPos int
+	for _, fromCol := range from.Columns {
+		if _, ok := toCols[fromCol.Name]; !ok {
+			continue
+		}
+		for toPos < len(to.Columns) {
+			if _, ok := fromCols[to.Columns[toPos].Name]; ok {
+				break
+			}
+			toPos++
+		}
+		if toPos >= len(to.Columns) || to.Columns[toPos].Name != fromCol.Name {
+			return true
+		}
+		toPos++
+	}
+	return false
+}
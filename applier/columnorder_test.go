@@ -0,0 +1,89 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestColumnOrder(t *testing.T) {
+	makeTable := func(colNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for _, name := range colNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: name, TypeInDB: "int", Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	colNames := func(table *tengo.Table) string {
+		names := make([]string, len(table.Columns))
+		for n, col := range table.Columns {
+			names[n] = col.Name
+		}
+		return strings.Join(names, ",")
+	}
+
+	cases := []struct {
+		From              []string
+		To                []string
+		Reordered         bool
+		ExistingReordered bool
+		Aligned           string
+	}{
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, false, false, "a,b,c"},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c", "d"}, false, false, "a,b,c,d"},
+		{[]string{"a", "b", "c"}, []string{"a", "d", "b", "c"}, true, false, "a,b,c,d"},
+		{[]string{"a", "b", "c"}, []string{"d", "a", "b", "c"}, true, false, "a,b,c,d"},
+		{[]string{"a", "b", "c"}, []string{"a", "c", "b"}, true, true, "a,b,c"},
+		{[]string{"a", "b", "c"}, []string{"c", "e", "a", "d"}, true, true, "a,c,e,d"},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, false, false, "a,c"},
+	}
+	for _, c := range cases {
+		from, to := makeTable(c.From...), makeTable(c.To...)
+		if actual := columnsReordered(from, to); actual != c.Reordered {
+			t.Errorf("Expected columnsReordered(%v, %v) to return %t, instead found %t", c.From, c.To, c.Reordered, actual)
+		}
+		if actual := existingColumnsReordered(from, to); actual != c.ExistingReordered {
+			t.Errorf("Expected existingColumnsReordered(%v, %v) to return %t, instead found %t", c.From, c.To, c.ExistingReordered, actual)
+		}
+
+		schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{from}}
+		schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{to}}
+		origTo := colNames(to)
+		ignoreColumnOrder(schemaFromInstance, schemaFromDir, tengo.FlavorUnknown)
+		if colNames(to) != origTo {
+			t.Errorf("Expected ignoreColumnOrder to leave the original table unchanged, instead found columns %s", colNames(to))
+		}
+		to = schemaFromDir.Tables[0]
+		if actual := colNames(to); actual != c.Aligned {
+			t.Errorf("Expected ignoreColumnOrder to result in columns %s, instead found %s", c.Aligned, actual)
+		} else if to.CreateStatement != to.GeneratedCreateStatement(tengo.FlavorUnknown) {
+			t.Errorf("Expected ignoreColumnOrder to update CREATE TABLE, but it did not:\n%s", to.CreateStatement)
+		}
+		if columnsReordered(from, to) {
+			t.Errorf("Expected no reordering after ignoreColumnOrder for %v, %v", c.From, c.To)
+		}
+		_, reasons := ClassifyAlter(&tengo.TableDiff{Type: tengo.DiffTypeAlter, From: from, To: makeTable(c.To...)}, tengo.FlavorUnknown)
+		if c.ExistingReordered != strings.Contains(strings.Join(reasons, "; "), "existing columns are reordered") {
+			t.Errorf("Unexpected rebuild reasons for %v, %v: %v", c.From, c.To, reasons)
+		}
+	}
+
+	// Tables whose CREATE doesn't match tengo's generated CREATE should be left
+	// alone
+	from, to := makeTable("a", "b"), makeTable("b", "a")
+	to.CreateStatement = strings.Replace(to.CreateStatement, "\n", " ", -1)
+	origCreate := to.CreateStatement
+	schemaFromDir := &tengo.Schema{Tables: []*tengo.Table{to}}
+	ignoreColumnOrder(&tengo.Schema{Tables: []*tengo.Table{from}}, schemaFromDir, tengo.FlavorUnknown)
+	if to = schemaFromDir.Tables[0]; colNames(to) != "b,a" || to.CreateStatement != origCreate {
+		t.Errorf("Expected unsupported table to be unchanged, instead found columns %s", colNames(to))
+	}
+}
@@ -0,0 +1,69 @@
+package applier
+
+import "github.com/skeema/mybase"
+
+// AddCommandOptions adds diff/push-related mybase options to the supplied
+// mybase.Command. Callers should typically also call AddCommandOptions in
+// package linter, as well as util.AddGlobalOptions if cmd is not a subcommand
+// of a suite that already has the global options, since Diff and Push also
+// obtain linter and connection settings from each dir's configuration.
+func AddCommandOptions(cmd *mybase.Command) {
+	cmd.AddOption(mybase.BoolOption("verify", 0, true, "Test all generated ALTER statements on temp schema to verify correctness"))
+	cmd.AddOption(mybase.BoolOption("allow-unsafe", 0, false, "Permit running ALTER or DROP operations that are potentially destructive"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-partition", 0, false, "Permit running ALTER TABLE ... DROP PARTITION from manage-partition-list without allow-unsafe"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-trigger", 0, false, "Permit running DROP TRIGGER, including to re-create modified triggers, without allow-unsafe"))
+	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Output DDL but don't run it; equivalent to `skeema diff`"))
+	cmd.AddOption(mybase.BoolOption("first-only", '1', false, "For dirs mapping to multiple instances or schemas, just run against the first per dir"))
+	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("reorder-columns", 0, true, "Move existing columns as needed to match column order in *.sql table definitions"))
+	cmd.AddOption(mybase.BoolOption("include-system-columns", 0, false, "Include hidden columns generated automatically by the server, such as invisible primary keys, in diffs"))
+	cmd.AddOption(mybase.BoolOption("foreign-key-checks", 0, false, "Force the server to check referential integrity of any new foreign key"))
+	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
+	cmd.AddOption(mybase.BoolOption("lint", 0, true, "Check modified objects for problems before proceeding"))
+	cmd.AddOption(mybase.StringOption("output-format", 0, "sql", `Format of output to STDOUT (valid values: "sql", "json")`))
+	cmd.AddOption(mybase.BoolOption("brief", 'q', false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("group-by-safety", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("migration-format", 0, "none", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("migration-dir", 0, ".", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("migration-description", 0, "skeema", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("migration-split", 0, "none", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("plan", 0, "", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("backfill-nulls", 0, false, "Before changing columns to NOT NULL, update any NULL values to the column's default"))
+	cmd.AddOption(mybase.BoolOption("drop-if-exists", 0, false, "Add IF EXISTS to generated DROP statements, tolerating objects already dropped out-of-band"))
+	cmd.AddOption(mybase.BoolOption("exact-row-counts", 0, false, "Count rows exactly, rather than estimating, when reporting rows rewritten by ALTER TABLE"))
+	cmd.AddOption(mybase.BoolOption("alter-validate-virtual", 0, false, "Apply a WITH VALIDATION clause to ALTER TABLEs affecting virtual columns"))
+	cmd.AddOption(mybase.StringOption("alter-wrapper", 'x', "", "External bin to shell out to for ALTER TABLE; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("alter-wrapper-min-size", 0, "0", "Ignore --alter-wrapper for tables smaller than this size in bytes"))
+	cmd.AddOption(mybase.StringOption("alter-lock", 0, "", `Apply a LOCK clause to all ALTER TABLEs (valid values: "none", "shared", "exclusive")`))
+	cmd.AddOption(mybase.StringOption("alter-algorithm", 0, "", `Apply an ALGORITHM clause to all ALTER TABLEs (valid values: "inplace", "copy", "instant")`))
+	cmd.AddOption(mybase.StringOption("ddl-wrapper", 'X', "", "Like --alter-wrapper, but applies to all DDL types (CREATE, DROP, ALTER)"))
+	cmd.AddOption(mybase.StringOption("osc-tool", 0, "none", `Run ALTER TABLEs which rebuild or copy the table via an online schema change tool (valid values: "none", "gh-ost", "pt-osc")`))
+	cmd.AddOption(mybase.StringOption("osc-chunk-size", 0, "0", "Number of rows copied per chunk by osc-tool; 0 uses the tool's default"))
+	cmd.AddOption(mybase.StringOption("osc-max-lag", 0, "0", "Make osc-tool throttle whenever replica lag exceeds this many seconds; 0 uses the tool's default"))
+	cmd.AddOption(mybase.StringOption("osc-cut-over", 0, "atomic", `Table swap method used by osc-tool=gh-ost (valid values: "atomic", "two-step")`))
+	cmd.AddOption(mybase.StringOption("osc-args", 0, "", "Additional command-line args for osc-tool; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("require-wrapper", 0, "none", `Forbid ALTER TABLEs that copy or rebuild the table unless using alter-wrapper (valid values: "none", "copy", "rebuild")`))
+	cmd.AddOption(mybase.StringOption("safe-below-size", 0, "0", "Always permit destructive operations for tables below this size in bytes"))
+	cmd.AddOption(mybase.StringOption("unsafe-ops", 0, "", "Permit running these comma-separated classes of destructive operations without allow-unsafe; see manual for classes"))
+	cmd.AddOption(mybase.StringOption("concurrent-instances", 'c', "1", "Perform operations on this number of instances concurrently"))
+	cmd.AddOption(mybase.StringOption("max-failures", 0, "0", "Halt operations once this number of instances have failed (0 for no limit)"))
+	cmd.AddOption(mybase.StringOption("max-replica-lag", 0, "0", "On replicas, wait before and after each DDL until replication lag is at most this many seconds"))
+	cmd.AddOption(mybase.StringOption("replica-lag-timeout", 0, "300", "Max seconds to wait for replication lag to fall to max-replica-lag"))
+	cmd.AddOption(mybase.StringOption("fatal-warnings", 0, "", `Treat DDL warnings with these comma-separated codes (or "all") as errors, halting remaining DDL; the DDL itself is already applied`))
+	cmd.AddOption(mybase.StringOption("ddl-retries", 0, "0", "Retry DDL failing due to lock wait timeout or deadlock up to this many times"))
+	cmd.AddOption(mybase.StringOption("ddl-retry-backoff", 0, "1", "Seconds to wait before the first DDL retry; doubled for each subsequent retry"))
+	cmd.AddOption(mybase.StringOption("check-convergence", 0, "off", `After pushing, re-introspect and diff again to confirm no differences remain (valid values: "off", "warn", "error")`))
+	cmd.AddOption(mybase.StringOption("resume", 0, "", "Record completed statements in this state file, and skip any already recorded there by a failed push"))
+	cmd.AddOption(mybase.StringOption("before-statement", 0, "", "Shell out to this command before each DDL statement, aborting if it fails; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("after-statement", 0, "", "Shell out to this command after each DDL statement; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("audit-log", 0, "", "Append a line of JSON describing each executed DDL statement to this file"))
+	cmd.AddOption(mybase.StringOption("audit-table", 0, "", "Insert a row describing each executed DDL statement into this schema.table on the target instance"))
+	cmd.AddOption(mybase.StringOption("introspection-cache", 0, "", "Cache introspected schemas in this file, skipping full introspection of unchanged schemas in diff"))
+	cmd.AddOption(mybase.StringOption("introspection-cache-table", 0, "", "Like introspection-cache, but cache in this schema.table on each instance"))
+	cmd.AddOption(mybase.BoolOption("no-cache", 0, false, "Ignore any cached schemas from introspection-cache or introspection-cache-table, introspecting fully"))
+	cmd.AddOption(mybase.StringOption("external-objects", 0, "", "Never create, alter, or drop these comma-separated objects, e.g. table:name,proc:name"))
+	cmd.AddOption(mybase.StringOption("external-objects-file", 0, "", "Never create, alter, or drop objects listed in this file, one per line"))
+	cmd.AddOption(mybase.StringOption("partitioning", 0, "keep", `Specify handling of partitioning status on the database side (valid values: "keep", "remove", "modify")`))
+	cmd.AddOption(mybase.BoolOption("manage-partition-list", 0, false, "Add, drop, or reorganize partitions to match the partition list of *.sql table definitions"))
+	cmd.AddOption(mybase.BoolOption("convert-character-set", 0, false, "Convert text columns via CONVERT TO CHARACTER SET when a table's default charset or collation changes, including from a changed default-character-set"))
+}
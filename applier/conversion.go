@@ -0,0 +1,428 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// charSetInfo describes a character set supported by an instance.
+type charSetInfo struct {
+	defaultCollation string
+	maxLen           int // maximum bytes per character
+}
+
+// charSetCache maps an instance's String() to its supported character sets.
+// This avoids repeatedly querying the same instance when multiple dirs or
+// schemas target it.
+var charSetCache struct {
+	sync.Mutex
+	instanceCharSets map[string]map[string]charSetInfo
+}
+
+func init() {
+	charSetCache.instanceCharSets = make(map[string]map[string]charSetInfo)
+}
+
+// supportedCharSets returns a map of character set name to charSetInfo, for
+// all character sets available on the supplied instance. Results are cached
+// per instance.
+func supportedCharSets(instance *tengo.Instance) (map[string]charSetInfo, error) {
+	charSetCache.Lock()
+	defer charSetCache.Unlock()
+	if charSets, ok := charSetCache.instanceCharSets[instance.String()]; ok {
+		return charSets, nil
+	}
+	db, err := instance.Connect("", "")
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		CharSet          string `db:"character_set_name"`
+		DefaultCollation string `db:"default_collate_name"`
+		MaxLen           int    `db:"maxlen"`
+	}
+	query := `
+		SELECT character_set_name, default_collate_name, maxlen
+		FROM   information_schema.character_sets`
+	if err := db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+	charSets := make(map[string]charSetInfo, len(rows))
+	for _, row := range rows {
+		charSets[strings.ToLower(row.CharSet)] = charSetInfo{
+			defaultCollation: strings.ToLower(row.DefaultCollation),
+			maxLen:           row.MaxLen,
+		}
+	}
+	charSetCache.instanceCharSets[instance.String()] = charSets
+	return charSets, nil
+}
+
+// conversionDiff represents an ALTER TABLE which converts a table, along with
+// all of its text columns, to a new character set and collation using CONVERT
+// TO CHARACTER SET. It satisfies the tengo.ObjectDiff interface.
+type conversionDiff struct {
+	table        *tengo.Table // desired definition, after conversion
+	fromCharSets []string     // distinct character sets of the table's existing text columns
+	charSet      string
+	collation    string
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (cd *conversionDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// converted.
+func (cd *conversionDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: cd.table.Name}
+}
+
+// Statement returns the full ALTER TABLE statement. A non-nil error will be
+// returned if the conversion may lose data, and mods do not permit unsafe
+// operations.
+func (cd *conversionDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(cd.table.Name) {
+		return "", nil
+	}
+	clauses, err := cd.Clauses(mods)
+	stmt := fmt.Sprintf("%s %s", cd.table.AlterStatement(), clauses)
+	if fde, isForbiddenDiff := err.(*tengo.ForbiddenDiffError); isForbiddenDiff {
+		fde.Statement = stmt
+	}
+	return stmt, err
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ".
+func (cd *conversionDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	var clauses []string
+	if mods.AlgorithmClause != "" {
+		clauses = append(clauses, fmt.Sprintf("ALGORITHM=%s", strings.ToUpper(mods.AlgorithmClause)))
+	}
+	if mods.LockClause != "" {
+		clauses = append(clauses, fmt.Sprintf("LOCK=%s", strings.ToUpper(mods.LockClause)))
+	}
+	clauses = append(clauses, fmt.Sprintf("CONVERT TO CHARACTER SET %s COLLATE %s", cd.charSet, cd.collation))
+	var err error
+	if cd.unsafe() && !mods.AllowUnsafe {
+		err = &tengo.ForbiddenDiffError{
+			Reason: fmt.Sprintf("Converting %s from character set %s to %s may lose data", cd.ObjectKey(), strings.Join(cd.fromCharSets, ", "), cd.charSet),
+		}
+	}
+	return strings.Join(clauses, ", "), err
+}
+
+// unsafe returns true if any existing text column uses a character set which
+// cannot be losslessly converted to the new one.
+func (cd *conversionDiff) unsafe() bool {
+	for _, from := range cd.fromCharSets {
+		if !losslessConversion(from, cd.charSet) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildReason returns a human-readable description of the conversion.
+func (cd *conversionDiff) rebuildReason() string {
+	return fmt.Sprintf("all text columns are converted to character set %s", cd.charSet)
+}
+
+// losslessConversion returns true if every character representable in
+// character set from is also representable in character set to.
+func losslessConversion(from, to string) bool {
+	if from == to {
+		return true
+	}
+	switch to {
+	case "utf8mb4":
+		return from == "ascii" || from == "latin1" || from == "utf8" || from == "utf8mb3"
+	case "utf8", "utf8mb3":
+		return from == "ascii" || from == "latin1" || from == "utf8" || from == "utf8mb3"
+	case "latin1":
+		return from == "ascii"
+	}
+	return false
+}
+
+// extractConversions examines tables existing in both schemaFromInstance and
+// schemaFromDir, and returns a conversionDiff for each table which should be
+// converted to a different character set or collation. This is the case if
+// the filesystem definition uses a different default character set or
+// collation than the live table, or if the filesystem definition still uses
+// the live schema's default character set and collation while the dir's
+// default-character-set or default-collation changes them. In either case, all
+// of the filesystem definition's text columns must use the table's default
+// character set and collation, since CONVERT TO CHARACTER SET converts them
+// all.
+//
+// Both sides of each converted table are replaced with copies reflecting the
+// conversion, so that tengo only diffs the rest of their definitions. Tables
+// are not converted if their indexes would exceed the storage engine's size
+// limits, or if CONVERT TO CHARACTER SET would change the type of any TEXT
+// column; these are logged, and, for tables which would otherwise have been
+// converted solely due to the schema default changing, counted in the
+// returned skip count. Other tables which are not converted are left to
+// tengo's normal diff.
+func extractConversions(t *Target, schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) (diffs []tengo.ObjectDiff, skipCount int, err error) {
+	if schemaFromInstance == nil {
+		return nil, 0, nil
+	}
+	var charSets map[string]charSetInfo
+	newDefaultCharSet, newDefaultCollation := schemaFromInstance.CharSet, schemaFromInstance.Collation
+	if t.Dir.Config.Get("default-character-set") != "" || t.Dir.Config.Get("default-collation") != "" {
+		if schemaFromDir.CharSet != "" {
+			newDefaultCharSet = schemaFromDir.CharSet
+		}
+		if schemaFromDir.Collation != "" {
+			newDefaultCollation = schemaFromDir.Collation
+		} else if newDefaultCharSet != schemaFromInstance.CharSet {
+			newDefaultCollation = "" // determined below once character sets are known
+		}
+	}
+
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	copy(dirTables, schemaFromDir.Tables)
+	for n, table := range schemaFromDir.Tables {
+		key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name}
+		instTable := instTables[table.Name]
+		if instTable == nil || instTable.UnsupportedDDL || table.UnsupportedDDL || !textColumnsUseTableDefault(table) {
+			continue
+		}
+		charSet, collation, isDefault := table.CharSet, table.Collation, table.CollationIsDefault
+		inherited := (table.CharSet == schemaFromInstance.CharSet && table.Collation == schemaFromInstance.Collation)
+		if inherited && (newDefaultCharSet != schemaFromInstance.CharSet || newDefaultCollation != schemaFromInstance.Collation) {
+			charSet, collation = newDefaultCharSet, newDefaultCollation
+		} else {
+			inherited = false
+		}
+		if charSet == instTable.CharSet && collation == instTable.Collation {
+			continue
+		}
+		if charSets == nil {
+			if charSets, err = supportedCharSets(t.Instance); err != nil {
+				return nil, 0, err
+			}
+		}
+		if inherited {
+			if collation == "" {
+				collation = charSets[charSet].defaultCollation
+			}
+			isDefault = (collation == charSets[charSet].defaultCollation)
+		}
+
+		converted := convertedTable(instTable, charSet, collation, isDefault, flavor)
+		if problems := oversizedIndexes(converted, charSets, flavor); len(problems) > 0 {
+			if inherited {
+				log.Warnf("Skipping conversion of %s to character set %s, since %s", key, charSet, strings.Join(problems, "; "))
+				skipCount++
+			} else {
+				log.Debugf("Not using CONVERT TO CHARACTER SET for %s, since %s", key, strings.Join(problems, "; "))
+			}
+			continue
+		}
+		if columns := promotedTextColumns(instTable, charSet, charSets); len(columns) > 0 {
+			if inherited {
+				log.Warnf("Skipping conversion of %s to character set %s, since CONVERT TO CHARACTER SET would change the type of %s", key, charSet, strings.Join(columns, ", "))
+				skipCount++
+			} else {
+				log.Debugf("Not using CONVERT TO CHARACTER SET for %s, since it would change the type of %s", key, strings.Join(columns, ", "))
+			}
+			continue
+		}
+
+		if inherited {
+			table = convertedTable(table, charSet, collation, isDefault, flavor)
+			log.Infof("Converting %s to the new schema default character set %s; afterwards, run skeema pull to update its CREATE TABLE in the filesystem", key, charSet)
+		}
+		dirTables[n] = table
+		for m, existing := range schemaFromInstance.Tables {
+			if existing == instTable {
+				schemaFromInstance.Tables[m] = converted
+			}
+		}
+		diffs = append(diffs, &conversionDiff{
+			table:        table,
+			fromCharSets: textColumnCharSets(instTable),
+			charSet:      charSet,
+			collation:    collation,
+		})
+	}
+	schemaFromDir.Tables = dirTables
+	return diffs, skipCount, nil
+}
+
+// textColumnsUseTableDefault returns true if every text column of table uses
+// the table's default character set and collation.
+func textColumnsUseTableDefault(table *tengo.Table) bool {
+	for _, col := range table.Columns {
+		if col.CharSet != "" && (col.CharSet != table.CharSet || col.Collation != table.Collation) {
+			return false
+		}
+	}
+	return true
+}
+
+// textColumnCharSets returns the distinct character sets used by table's text
+// columns, in column order.
+func textColumnCharSets(table *tengo.Table) (charSets []string) {
+	seen := make(map[string]bool)
+	for _, col := range table.Columns {
+		if col.CharSet != "" && !seen[col.CharSet] {
+			charSets = append(charSets, col.CharSet)
+			seen[col.CharSet] = true
+		}
+	}
+	return charSets
+}
+
+// convertedTable returns a copy of table, as it would be after CONVERT TO
+// CHARACTER SET charSet COLLATE collation. isDefault indicates whether
+// collation is the default collation of charSet.
+func convertedTable(table *tengo.Table, charSet, collation string, isDefault bool, flavor tengo.Flavor) *tengo.Table {
+	converted := *table
+	converted.CharSet, converted.Collation, converted.CollationIsDefault = charSet, collation, isDefault
+	converted.Columns = make([]*tengo.Column, len(table.Columns))
+	for n, col := range table.Columns {
+		if col.CharSet == "" {
+			converted.Columns[n] = col
+			continue
+		}
+		colCopy := *col
+		colCopy.CharSet, colCopy.Collation, colCopy.CollationIsDefault = charSet, collation, isDefault
+		converted.Columns[n] = &colCopy
+	}
+	converted.CreateStatement = converted.GeneratedCreateStatement(flavor)
+	return &converted
+}
+
+// promotedTextColumns returns the names of TINYTEXT, TEXT, and MEDIUMTEXT
+// columns of table which the server would change to a larger type when
+// converting them to charSet, since the new character set requires more bytes
+// per character.
+func promotedTextColumns(table *tengo.Table, charSet string, charSets map[string]charSetInfo) (columns []string) {
+	for _, col := range table.Columns {
+		if col.CharSet == "" {
+			continue
+		}
+		switch strings.ToLower(col.TypeInDB) {
+		case "tinytext", "text", "mediumtext":
+			if charSets[charSet].maxLen > charSets[col.CharSet].maxLen {
+				columns = append(columns, tengo.EscapeIdentifier(col.Name))
+			}
+		}
+	}
+	return columns
+}
+
+// reTypeLength matches a column type with a parenthesized length, such as
+// varchar(255), capturing the base type and the length.
+var reTypeLength = regexp.MustCompile(`^(\w+)\((\d+)\)`)
+
+// fixedTypeBytes maps non-text column types to the number of bytes they occupy
+// in an index.
+var fixedTypeBytes = map[string]int{
+	"tinyint":   1,
+	"smallint":  2,
+	"mediumint": 3,
+	"int":       4,
+	"bigint":    8,
+	"float":     4,
+	"double":    8,
+	"date":      3,
+	"time":      3,
+	"datetime":  8,
+	"timestamp": 4,
+	"year":      1,
+}
+
+// indexPartBytes returns the maximum number of bytes occupied by part, which
+// indexes col, or 0 if this cannot be determined. maxLen is the maximum bytes
+// per character of the column's character set.
+func indexPartBytes(col *tengo.Column, part tengo.IndexPart, maxLen int) int {
+	typ := strings.ToLower(col.TypeInDB)
+	if strings.HasPrefix(typ, "enum(") {
+		return 2
+	} else if strings.HasPrefix(typ, "set(") {
+		return 8
+	}
+	length := int(part.PrefixLength)
+	if matches := reTypeLength.FindStringSubmatch(typ); matches != nil {
+		if length == 0 {
+			length, _ = strconv.Atoi(matches[2])
+		}
+		typ = matches[1]
+	}
+	if col.CharSet != "" {
+		return length * maxLen
+	}
+	switch typ {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return length
+	}
+	if fields := strings.Fields(typ); len(fields) > 0 {
+		return fixedTypeBytes[fields[0]]
+	}
+	return 0
+}
+
+// oversizedIndexes returns human-readable descriptions of any indexes of
+// table which exceed the storage engine's limits on the size of each indexed
+// column or of the entire index. Only InnoDB and MyISAM limits are checked.
+// FULLTEXT and SPATIAL indexes, and functional index parts, are ignored.
+func oversizedIndexes(table *tengo.Table, charSets map[string]charSetInfo, flavor tengo.Flavor) (problems []string) {
+	var partLimit, totalLimit int
+	switch strings.ToLower(table.Engine) {
+	case "innodb":
+		partLimit, totalLimit = 767, 3072
+		switch strings.ToUpper(table.RowFormatClause()) {
+		case "DYNAMIC", "COMPRESSED":
+			partLimit = 3072
+		case "":
+			// DYNAMIC is the default row format in MySQL 5.7+ and MariaDB 10.2+
+			if flavor.MySQLishMinVersion(5, 7) || flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+				partLimit = 3072
+			}
+		}
+	case "myisam":
+		partLimit, totalLimit = 1000, 1000
+	default:
+		return nil
+	}
+
+	cols := table.ColumnsByName()
+	indexes := table.SecondaryIndexes
+	if table.PrimaryKey != nil {
+		indexes = append([]*tengo.Index{table.PrimaryKey}, indexes...)
+	}
+	for _, idx := range indexes {
+		if idx.Type == "FULLTEXT" || idx.Type == "SPATIAL" {
+			continue
+		}
+		var total int
+		for _, part := range idx.Parts {
+			col := cols[part.ColumnName]
+			if col == nil {
+				continue
+			}
+			bytes := indexPartBytes(col, part, charSets[col.CharSet].maxLen)
+			if col.CharSet != "" && bytes > partLimit {
+				problems = append(problems, fmt.Sprintf("column %s of index %s would be %d bytes, exceeding the limit of %d bytes", tengo.EscapeIdentifier(col.Name), tengo.EscapeIdentifier(idx.Name), bytes, partLimit))
+			}
+			total += bytes
+		}
+		if total > totalLimit {
+			problems = append(problems, fmt.Sprintf("index %s would be %d bytes, exceeding the limit of %d bytes", tengo.EscapeIdentifier(idx.Name), total, totalLimit))
+		}
+	}
+	return problems
+}
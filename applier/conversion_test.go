@@ -0,0 +1,223 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestExtractConversions(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	// Simulate the instance's character sets, to avoid querying it
+	charSetCache.Lock()
+	charSetCache.instanceCharSets[inst.String()] = map[string]charSetInfo{
+		"latin1":  {defaultCollation: "latin1_swedish_ci", maxLen: 1},
+		"utf8":    {defaultCollation: "utf8_general_ci", maxLen: 3},
+		"utf8mb4": {defaultCollation: "utf8mb4_general_ci", maxLen: 4},
+	}
+	charSetCache.Unlock()
+	defer func() {
+		charSetCache.Lock()
+		delete(charSetCache.instanceCharSets, inst.String())
+		charSetCache.Unlock()
+	}()
+
+	flavor := tengo.FlavorMySQL57
+	makeTable := func(name, charSet, collation, nameType string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            charSet,
+			Collation:          collation,
+			CollationIsDefault: true,
+			Columns: []*tengo.Column{
+				{Name: "id", TypeInDB: "int(10) unsigned"},
+				{Name: "name", TypeInDB: nameType, Nullable: true, Default: "NULL", CharSet: charSet, Collation: collation, CollationIsDefault: true},
+			},
+		}
+		table.PrimaryKey = &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Parts: []tengo.IndexPart{{ColumnName: "id"}}}
+		if strings.HasPrefix(nameType, "varchar") {
+			table.SecondaryIndexes = []*tengo.Index{{Name: "name", Parts: []tengo.IndexPart{{ColumnName: "name"}}, Type: "BTREE"}}
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+	getTarget := func(defaultCharSet string) *Target {
+		configMap := map[string]string{
+			"default-character-set": defaultCharSet,
+			"default-collation":     "",
+		}
+		return &Target{
+			Instance: inst,
+			Dir:      &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(configMap)},
+		}
+	}
+	makeSchemas := func(newCharSet string, dirTables ...*tengo.Table) (schemaFromInstance, schemaFromDir *tengo.Schema) {
+		schemaFromInstance = &tengo.Schema{
+			Name:      "product",
+			CharSet:   "utf8",
+			Collation: "utf8_general_ci",
+			Tables: []*tengo.Table{
+				makeTable("widgets", "utf8", "utf8_general_ci", "varchar(255)"),
+				makeTable("huge", "utf8", "utf8_general_ci", "varchar(1000)"),
+				makeTable("notes", "utf8", "utf8_general_ci", "text"),
+			},
+		}
+		schemaFromDir = &tengo.Schema{
+			Name:      "product",
+			CharSet:   newCharSet,
+			Collation: "",
+			Tables:    dirTables,
+		}
+		return schemaFromInstance, schemaFromDir
+	}
+
+	// Changing the dir's default-character-set converts the tables still using
+	// the old schema default, except for those whose indexes would be too large
+	// or whose TEXT columns would change type
+	schemaFromInstance, schemaFromDir := makeSchemas("utf8mb4",
+		makeTable("widgets", "utf8", "utf8_general_ci", "varchar(255)"),
+		makeTable("huge", "utf8", "utf8_general_ci", "varchar(1000)"),
+		makeTable("notes", "utf8", "utf8_general_ci", "text"),
+	)
+	diffs, skipCount, err := extractConversions(getTarget("utf8mb4"), schemaFromInstance, schemaFromDir, flavor)
+	if err != nil {
+		t.Fatalf("Unexpected error from extractConversions: %s", err)
+	} else if len(diffs) != 1 || skipCount != 2 {
+		t.Fatalf("Expected 1 diff and 2 skipped tables, instead found %d diffs and %d skipped", len(diffs), skipCount)
+	}
+	stmt, err := diffs[0].Statement(tengo.StatementModifiers{})
+	if expected := "ALTER TABLE `widgets` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"; stmt != expected || err != nil {
+		t.Errorf("Unexpected result from Statement: %q, %v", stmt, err)
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.TableDiffs) > 0 {
+		t.Errorf("Expected converted tables to have no other differences, instead found %v", diff.TableDiffs)
+	}
+	if dirTable := schemaFromDir.Tables[0]; dirTable.CharSet != "utf8mb4" || dirTable.Columns[1].Collation != "utf8mb4_general_ci" {
+		t.Errorf("Expected desired definition of converted table to be updated, instead found %s", dirTable.CreateStatement)
+	}
+	if algo, _ := ClassifyAlter(diffs[0], flavor); algo != AlterAlgorithmCopy {
+		t.Errorf("Expected conversion to be classified as %s, instead found %s", AlterAlgorithmCopy, algo)
+	}
+
+	// Without a change to the schema default, tables with a different default
+	// character set in the filesystem are converted instead
+	schemaFromInstance, schemaFromDir = makeSchemas("utf8",
+		makeTable("widgets", "latin1", "latin1_swedish_ci", "varchar(255)"),
+		makeTable("huge", "utf8", "utf8_general_ci", "varchar(1000)"),
+	)
+	diffs, skipCount, err = extractConversions(getTarget(""), schemaFromInstance, schemaFromDir, flavor)
+	if err != nil || len(diffs) != 1 || skipCount != 0 {
+		t.Fatalf("Unexpected result from extractConversions: %d diffs, %d skipped, %v", len(diffs), skipCount, err)
+	}
+
+	// Tables with a different default character set in the filesystem, whose
+	// indexes would be too large after conversion, are left to the normal diff
+	dirHuge := makeTable("huge", "utf8mb4", "utf8mb4_general_ci", "varchar(1000)")
+	_, schemaFromDir = makeSchemas("utf8", dirHuge)
+	if diffs, skipCount, err := extractConversions(getTarget(""), schemaFromInstance, schemaFromDir, flavor); err != nil || len(diffs) != 0 || skipCount != 0 {
+		t.Errorf("Unexpected result from extractConversions: %d diffs, %d skipped, %v", len(diffs), skipCount, err)
+	} else if schemaFromDir.Tables[0] != dirHuge {
+		t.Errorf("Expected desired definition of unconverted table to be left as-is, instead found %s", schemaFromDir.Tables[0].CreateStatement)
+	}
+
+	// Conversions which may lose data are unsafe
+	if _, err := diffs[0].Statement(tengo.StatementModifiers{}); !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Expected conversion from utf8 to latin1 to be forbidden, instead err=%v", err)
+	} else if classes := ClassifyUnsafe(diffs[0], tengo.StatementModifiers{}); len(classes) != 1 || classes[0] != UnsafeColumnCharSet {
+		t.Errorf("Unexpected unsafe classes: %v", classes)
+	}
+	if _, err := diffs[0].Statement(tengo.StatementModifiers{AllowUnsafe: true}); err != nil {
+		t.Errorf("Unexpected error from Statement with AllowUnsafe: %v", err)
+	}
+
+	// Tables with text columns using a non-default character set in the
+	// filesystem are left to the normal diff
+	mixed := makeTable("widgets", "utf8mb4", "utf8mb4_general_ci", "varchar(255)")
+	mixed.Columns[1].CharSet, mixed.Columns[1].Collation = "latin1", "latin1_swedish_ci"
+	schemaFromInstance, schemaFromDir = makeSchemas("utf8", mixed)
+	if diffs, _, _ := extractConversions(getTarget(""), schemaFromInstance, schemaFromDir, flavor); len(diffs) != 0 {
+		t.Errorf("Expected no conversions for table with mixed character sets, instead found %d", len(diffs))
+	}
+}
+
+func TestOversizedIndexes(t *testing.T) {
+	charSets := map[string]charSetInfo{
+		"utf8":    {maxLen: 3},
+		"utf8mb4": {maxLen: 4},
+	}
+	table := &tengo.Table{
+		Name:    "widgets",
+		Engine:  "InnoDB",
+		CharSet: "utf8mb4",
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "bigint(20) unsigned"},
+			{Name: "name", TypeInDB: "varchar(255)", CharSet: "utf8mb4"},
+			{Name: "body", TypeInDB: "text", CharSet: "utf8mb4"},
+			{Name: "status", TypeInDB: "enum('a','b')", CharSet: "utf8mb4"},
+		},
+		SecondaryIndexes: []*tengo.Index{
+			{Name: "name", Parts: []tengo.IndexPart{{ColumnName: "name"}, {ColumnName: "id"}, {ColumnName: "status"}}},
+			{Name: "body", Parts: []tengo.IndexPart{{ColumnName: "body", PrefixLength: 700}}},
+			{Name: "ft", Parts: []tengo.IndexPart{{ColumnName: "body"}}, Type: "FULLTEXT"},
+		},
+	}
+
+	// With the DYNAMIC row format, each column may be up to 3072 bytes, which
+	// a 700-character utf8mb4 prefix does not exceed
+	if problems := oversizedIndexes(table, charSets, tengo.FlavorMySQL57); len(problems) > 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+
+	// With the COMPACT row format, or an older default, the limit is 767 bytes
+	table.CreateOptions = "ROW_FORMAT=COMPACT"
+	problems := oversizedIndexes(table, charSets, tengo.FlavorMySQL57)
+	if len(problems) != 2 || !strings.Contains(problems[0], "`name` of index `name` would be 1020 bytes") || !strings.Contains(problems[1], "`body` of index `body` would be 2800 bytes") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	table.CreateOptions = ""
+	if problems := oversizedIndexes(table, charSets, tengo.FlavorMySQL56); len(problems) != 2 {
+		t.Errorf("Expected 2 problems with MySQL 5.6, instead found %v", problems)
+	}
+
+	// The entire index is limited to 3072 bytes
+	table.SecondaryIndexes[1].Parts = append(table.SecondaryIndexes[1].Parts, tengo.IndexPart{ColumnName: "name"})
+	problems = oversizedIndexes(table, charSets, tengo.FlavorMySQL57)
+	if len(problems) != 1 || !strings.Contains(problems[0], "index `body` would be 3820 bytes, exceeding the limit of 3072 bytes") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+
+	// Other storage engines are not checked
+	table.Engine = "MEMORY"
+	if problems := oversizedIndexes(table, charSets, tengo.FlavorMySQL57); len(problems) > 0 {
+		t.Errorf("Expected no problems for MEMORY table, instead found %v", problems)
+	}
+}
+
+func TestLosslessConversion(t *testing.T) {
+	cases := []struct {
+		from, to string
+		expected bool
+	}{
+		{"utf8mb4", "utf8mb4", true},
+		{"utf8", "utf8mb4", true},
+		{"utf8mb3", "utf8mb4", true},
+		{"latin1", "utf8mb4", true},
+		{"ascii", "latin1", true},
+		{"utf8mb4", "utf8", false},
+		{"utf8", "latin1", false},
+		{"latin1", "ascii", false},
+		{"sjis", "utf8mb4", false},
+	}
+	for _, c := range cases {
+		if actual := losslessConversion(c.from, c.to); actual != c.expected {
+			t.Errorf("Expected losslessConversion(%q, %q) to return %t, instead found %t", c.from, c.to, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package applier
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// objectTypeData is the object type of dataDiffs. Using a distinct type, rather
+// than tengo.ObjectTypeTable, ensures that DML is never handled as DDL: for
+// example, it is never combined with an ALTER TABLE, and never executed by an
+// alter-wrapper or osc-tool.
+const objectTypeData tengo.ObjectType = "data"
+
+// dataDiff represents a difference in one row of a table listed in the
+// manage-data option, between the filesystem and a live schema. It satisfies
+// the tengo.ObjectDiff interface. The diff type is a create for an INSERT, an
+// alter for an UPDATE, or a drop for a DELETE.
+type dataDiff struct {
+	data *workspace.TableData
+	from []sql.NullString // nil for an INSERT
+	to   []sql.NullString // nil for a DELETE
+}
+
+// DiffType returns the type of diff operation.
+func (dd *dataDiff) DiffType() tengo.DiffType {
+	if dd.from == nil {
+		return tengo.DiffTypeCreate
+	} else if dd.to == nil {
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the table whose data is modified.
+func (dd *dataDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: objectTypeData, Name: dd.data.Table.Name}
+}
+
+// Statement returns the full DML statement corresponding to the dataDiff. A
+// non-nil error will be returned if the statement is a DELETE and mods do not
+// permit unsafe operations.
+func (dd *dataDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	table := dd.data.Table
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(table.Name) {
+		return "", nil
+	}
+	switch dd.DiffType() {
+	case tengo.DiffTypeCreate:
+		cols := make([]string, len(dd.data.Columns))
+		values := make([]string, len(dd.data.Columns))
+		for n, col := range dd.data.Columns {
+			cols[n] = tengo.EscapeIdentifier(col)
+			values[n] = dd.literal(n, dd.to[n])
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tengo.EscapeIdentifier(table.Name), strings.Join(cols, ", "), strings.Join(values, ", ")), nil
+	case tengo.DiffTypeAlter:
+		var assignments []string
+		for n, col := range dd.data.Columns {
+			if dd.from[n] != dd.to[n] {
+				assignments = append(assignments, fmt.Sprintf("%s = %s", tengo.EscapeIdentifier(col), dd.literal(n, dd.to[n])))
+			}
+		}
+		return fmt.Sprintf("UPDATE %s SET %s WHERE %s", tengo.EscapeIdentifier(table.Name), strings.Join(assignments, ", "), dd.whereClause()), nil
+	default:
+		stmt := fmt.Sprintf("DELETE FROM %s WHERE %s", tengo.EscapeIdentifier(table.Name), dd.whereClause())
+		var err error
+		if !mods.AllowUnsafe {
+			err = &tengo.ForbiddenDiffError{
+				Reason:    "DELETE not permitted",
+				Statement: stmt,
+			}
+		}
+		return stmt, err
+	}
+}
+
+// whereClause returns a condition matching the modified row by its primary
+// key values.
+func (dd *dataDiff) whereClause() string {
+	positions := dd.data.KeyPositions()
+	conditions := make([]string, len(positions))
+	for n, pos := range positions {
+		conditions[n] = fmt.Sprintf("%s = %s", tengo.EscapeIdentifier(dd.data.Columns[pos]), dd.literal(pos, dd.from[pos]))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// literal returns value formatted as a SQL literal for the column at position
+// n. Numeric values are left unquoted; binary values, and any other values
+// which are not valid UTF-8, are formatted as hex literals.
+func (dd *dataDiff) literal(n int, value sql.NullString) string {
+	if !value.Valid {
+		return "NULL"
+	}
+	var colType string
+	for _, col := range dd.data.Table.Columns {
+		if col.Name == dd.data.Columns[n] {
+			colType = strings.ToLower(col.TypeInDB)
+			break
+		}
+	}
+	for _, prefix := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double"} {
+		if strings.HasPrefix(colType, prefix) {
+			return value.String
+		}
+	}
+	if strings.HasPrefix(colType, "bit") || strings.Contains(colType, "binary") || strings.Contains(colType, "blob") || !utf8.ValidString(value.String) {
+		return fmt.Sprintf("X'%X'", value.String)
+	}
+	return "'" + tengo.EscapeValueForCreateTable(value.String) + "'"
+}
+
+// diffData compares the rows of live tables to the desired rows from the
+// target's workspace, for tables listed in the manage-data option. dataDiffs
+// are returned for each row which must be inserted, updated, or deleted, with
+// the rows of each table handled in that order of DELETEs, UPDATEs, and
+// INSERTs, so that unique keys are not violated by rows which are being
+// replaced. Tables with names matching mods.IgnoreTable are ignored. If a live
+// table's columns or primary key differ from the desired table, its data
+// cannot be compared until its definition matches, so it is skipped with a
+// warning; the returned count reflects the number of such tables.
+func diffData(t *Target, schemaFromInstance *tengo.Schema, mods tengo.StatementModifiers) (diffs []tengo.ObjectDiff, skipped int, err error) {
+	if len(t.DesiredSchema.Data) == 0 {
+		return nil, 0, nil
+	}
+	var instTables map[string]*tengo.Table
+	if schemaFromInstance != nil {
+		instTables = schemaFromInstance.TablesByName()
+	}
+	for _, desired := range t.DesiredSchema.Data {
+		name := desired.Table.Name
+		if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(name) {
+			continue
+		}
+		instTable := instTables[name]
+		if instTable == nil {
+			for _, row := range desired.Rows {
+				diffs = append(diffs, &dataDiff{data: desired, to: row})
+			}
+			continue
+		}
+		if !instTable.PrimaryKey.Equals(desired.Table.PrimaryKey) {
+			log.Warnf("Skipping data changes for %s in %s schema %s: the live table's primary key differs from %s", tengo.EscapeIdentifier(name), t.Instance, t.SchemaName, t.Dir)
+			skipped++
+			continue
+		}
+		live, err := liveTableData(t, instTable)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !sameDataColumns(desired, live) {
+			log.Warnf("Skipping data changes for %s in %s schema %s: the live table's columns differ from %s", tengo.EscapeIdentifier(name), t.Instance, t.SchemaName, t.Dir)
+			skipped++
+			continue
+		}
+		desiredByKey := make(map[string][]sql.NullString, len(desired.Rows))
+		for _, row := range desired.Rows {
+			desiredByKey[desired.RowKey(row)] = row
+		}
+		liveByKey := make(map[string][]sql.NullString, len(live.Rows))
+		for _, row := range live.Rows {
+			key := live.RowKey(row)
+			liveByKey[key] = row
+			if desiredByKey[key] == nil {
+				diffs = append(diffs, &dataDiff{data: desired, from: row})
+			}
+		}
+		var inserts []tengo.ObjectDiff
+		for _, row := range desired.Rows {
+			if liveRow := liveByKey[desired.RowKey(row)]; liveRow == nil {
+				inserts = append(inserts, &dataDiff{data: desired, to: row})
+			} else if !sameRow(liveRow, row) {
+				diffs = append(diffs, &dataDiff{data: desired, from: liveRow, to: row})
+			}
+		}
+		diffs = append(diffs, inserts...)
+	}
+	return diffs, skipped, nil
+}
+
+// liveTableData introspects the rows of the live table.
+func liveTableData(t *Target, table *tengo.Table) (*workspace.TableData, error) {
+	db, err := t.Instance.Connect(t.SchemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	return workspace.IntrospectTableData(db, table)
+}
+
+// sameDataColumns returns true if a and b have the same non-generated columns,
+// meaning that their rows can be compared directly.
+func sameDataColumns(a, b *workspace.TableData) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for n := range a.Columns {
+		if a.Columns[n] != b.Columns[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameRow returns true if rows a and b have identical values.
+func sameRow(a, b []sql.NullString) bool {
+	for n := range a {
+		if a[n] != b[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// orderDataDiffs returns objDiffs reordered so that row changes occur after
+// all other changes, since they require their tables to be in their final
+// state, but before triggers are created. This mirrors the workspace, where
+// INSERTs run before any triggers exist.
+func orderDataDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	var other, data, triggerCreates []tengo.ObjectDiff
+	for _, objDiff := range objDiffs {
+		key := objDiff.ObjectKey()
+		if key.Type == objectTypeData {
+			data = append(data, objDiff)
+		} else if key.Type == fs.ObjectTypeTrigger && objDiff.DiffType() == tengo.DiffTypeCreate {
+			triggerCreates = append(triggerCreates, objDiff)
+		} else {
+			other = append(other, objDiff)
+		}
+	}
+	result := append(other, data...)
+	return append(result, triggerCreates...)
+}
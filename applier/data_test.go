@@ -0,0 +1,88 @@
+package applier
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestDataDiffStatement(t *testing.T) {
+	table := &tengo.Table{
+		Name: "statuses",
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(10) unsigned"},
+			{Name: "name", TypeInDB: "varchar(40)"},
+			{Name: "flags", TypeInDB: "varbinary(4)"},
+		},
+		PrimaryKey: &tengo.Index{PrimaryKey: true, Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+	}
+	data := &workspace.TableData{Table: table, Columns: []string{"id", "name", "flags"}}
+	value := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: true}
+	}
+	from := []sql.NullString{value("1"), value("active"), {}}
+	to := []sql.NullString{value("1"), value("it's \\active\\"), value("\x01\xff")}
+	mods := tengo.StatementModifiers{Flavor: tengo.FlavorMySQL80}
+
+	cases := []struct {
+		diff     *dataDiff
+		expected string
+	}{
+		{&dataDiff{data: data, to: to}, "INSERT INTO `statuses` (`id`, `name`, `flags`) VALUES (1, 'it''s \\\\active\\\\', X'01FF')"},
+		{&dataDiff{data: data, to: from}, "INSERT INTO `statuses` (`id`, `name`, `flags`) VALUES (1, 'active', NULL)"},
+		{&dataDiff{data: data, from: from, to: to}, "UPDATE `statuses` SET `name` = 'it''s \\\\active\\\\', `flags` = X'01FF' WHERE `id` = 1"},
+	}
+	for n, c := range cases {
+		if actual, err := c.diff.Statement(mods); err != nil || actual != c.expected {
+			t.Errorf("Unexpected return from Statement() in case %d:\nexpected %s\nfound    %s / %v", n, c.expected, actual, err)
+		}
+	}
+
+	// DELETE is only permitted with allow-unsafe
+	del := &dataDiff{data: data, from: from}
+	if del.DiffType() != tengo.DiffTypeDrop || del.ObjectKey().Type != objectTypeData {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", del.DiffType(), del.ObjectKey())
+	}
+	stmt, err := del.Statement(mods)
+	if expected := "DELETE FROM `statuses` WHERE `id` = 1"; stmt != expected || !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement(): %s / %v", stmt, err)
+	}
+	if ClassifySafety(del, mods) != SafetyDestructive {
+		t.Error("Expected DELETE to be classified as destructive")
+	}
+	unsafeMods := mods
+	unsafeMods.AllowUnsafe = true
+	if _, err := del.Statement(unsafeMods); err != nil {
+		t.Errorf("Unexpected error with allow-unsafe: %v", err)
+	}
+
+	// Tables matching ignore-table are skipped
+	mods.IgnoreTable = regexp.MustCompile("^stat")
+	if stmt, err := del.Statement(mods); stmt != "" || err != nil {
+		t.Errorf("Expected ignored table to have no statement, instead found %s / %v", stmt, err)
+	}
+}
+
+func TestOrderDataDiffs(t *testing.T) {
+	data := &workspace.TableData{Table: &tengo.Table{Name: "statuses"}}
+	objDiffs := []tengo.ObjectDiff{
+		&dataDiff{data: data, to: []sql.NullString{}},
+		&triggerDiff{from: &workspace.Trigger{Name: "old"}},
+		tengo.NewCreateTable(&tengo.Table{Name: "statuses"}),
+		&triggerDiff{to: &workspace.Trigger{Name: "new"}},
+		&sequenceDiff{to: &workspace.Sequence{Name: "seq"}},
+	}
+	var names []string
+	for _, objDiff := range orderDataDiffs(objDiffs) {
+		names = append(names, objDiff.DiffType().String()+" "+string(objDiff.ObjectKey().Type))
+	}
+	expected := "DROP " + string(fs.ObjectTypeTrigger) + ", CREATE table, CREATE sequence, CREATE data, CREATE trigger"
+	if actual := strings.Join(names, ", "); actual != expected {
+		t.Errorf("Unexpected order from orderDataDiffs:\nexpected %s\nfound    %s", expected, actual)
+	}
+}
@@ -0,0 +1,142 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reDirectoryClause matches a table-level DATA DIRECTORY or INDEX DIRECTORY
+// clause found in SHOW CREATE TABLE output. Partition-level clauses are
+// formatted with spaces around the equals sign, so they are not matched; tengo
+// handles those as part of the partitioning clause.
+var reDirectoryClause = regexp.MustCompile(` (DATA|INDEX) DIRECTORY='((?:[^'\\]|\\.|'')*)'`)
+
+// tableDirectories represents the DATA DIRECTORY and INDEX DIRECTORY of a
+// table. An empty string means the table uses the server's default location.
+type tableDirectories struct {
+	data  string
+	index string
+}
+
+// String returns a human-readable description of the directories.
+func (td tableDirectories) String() string {
+	describe := func(dir string) string {
+		if dir == "" {
+			return "default"
+		}
+		return "'" + dir + "'"
+	}
+	return fmt.Sprintf("DATA DIRECTORY %s, INDEX DIRECTORY %s", describe(td.data), describe(td.index))
+}
+
+// parseCreateDirectories splits the supplied CREATE TABLE statement into a
+// version without any table-level DATA DIRECTORY or INDEX DIRECTORY clauses,
+// and the directories specified by those clauses. Trailing slashes are removed
+// from the directories, since the server may report them differently than they
+// were originally specified.
+func parseCreateDirectories(createStmt string) (base string, dirs tableDirectories) {
+	base = createStmt
+	for _, match := range reDirectoryClause.FindAllStringSubmatch(createStmt, -1) {
+		base = strings.Replace(base, match[0], "", 1)
+		dir := strings.TrimRight(match[2], "/")
+		if dir == "" {
+			dir = "/"
+		}
+		if strings.ToUpper(match[1]) == "DATA" {
+			dirs.data = dir
+		} else {
+			dirs.index = dir
+		}
+	}
+	return base, dirs
+}
+
+// directoryDiff represents a change to the DATA DIRECTORY or INDEX DIRECTORY of
+// a table. It satisfies the tengo.ObjectDiff interface. ALTER TABLE ignores
+// these options, so the table would need to be recreated, which cannot be done
+// without losing its data. Accordingly, Statement always returns an
+// UnsupportedDiffError, causing the difference to be reported but skipped.
+type directoryDiff struct {
+	table      *tengo.Table
+	from       tableDirectories
+	to         tableDirectories
+	fromCreate string // original SHOW CREATE TABLE, including directory clauses
+	toCreate   string // ditto
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (dd *directoryDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table.
+func (dd *directoryDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: dd.table.Name}
+}
+
+// Statement returns an UnsupportedDiffError, unless mods indicate the table is
+// ignored.
+func (dd *directoryDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(dd.table.Name) {
+		return "", nil
+	}
+	return "", &tengo.UnsupportedDiffError{
+		ObjectKey:      dd.ObjectKey(),
+		ExpectedCreate: dd.toCreate,
+		ActualCreate:   dd.fromCreate,
+	}
+}
+
+// reason returns a human-readable description of the directory change, for use
+// in explaining why the difference was skipped.
+func (dd *directoryDiff) reason() string {
+	return fmt.Sprintf("directories change from %s to %s, which requires recreating the table", dd.from, dd.to)
+}
+
+// extractDirectories removes table-level DATA DIRECTORY and INDEX DIRECTORY
+// clauses from the CreateStatement of tables existing in both
+// schemaFromInstance and schemaFromDir, so that tengo can diff the rest of
+// their definitions normally, even though it does not support these clauses.
+// A directoryDiff is returned for each table whose directories differ. A table
+// lacking either clause is treated as using the default location. Modified dir
+// tables are replaced with copies, since the same desired schema may be shared
+// by other targets.
+func extractDirectories(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) []tengo.ObjectDiff {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	var diffs []tengo.ObjectDiff
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		instTable := instTables[table.Name]
+		if instTable == nil {
+			continue
+		}
+		instBase, instDirs := parseCreateDirectories(instTable.CreateStatement)
+		dirBase, dirDirs := parseCreateDirectories(table.CreateStatement)
+		if instBase == instTable.CreateStatement && dirBase == table.CreateStatement {
+			continue // neither side has a directory clause
+		}
+		instCreate, dirCreate := instTable.CreateStatement, table.CreateStatement
+		stripTableClause(instTable, instBase, flavor)
+		tableCopy := *table
+		stripTableClause(&tableCopy, dirBase, flavor)
+		dirTables[n] = &tableCopy
+		if instDirs != dirDirs {
+			diffs = append(diffs, &directoryDiff{
+				table:      &tableCopy,
+				from:       instDirs,
+				to:         dirDirs,
+				fromCreate: instCreate,
+				toCreate:   dirCreate,
+			})
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	return diffs
+}
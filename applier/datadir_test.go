@@ -0,0 +1,129 @@
+package applier
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestParseCreateDirectories(t *testing.T) {
+	base := "CREATE TABLE `widgets` (\n  `id` int(11) DEFAULT NULL\n) ENGINE=MyISAM DEFAULT CHARSET=latin1"
+	cases := []struct {
+		Clauses string
+		Data    string
+		Index   string
+	}{
+		{"", "", ""},
+		{" DATA DIRECTORY='/data/mysql/'", "/data/mysql", ""},
+		{" DATA DIRECTORY='/data/mysql/' INDEX DIRECTORY='/idx/mysql'", "/data/mysql", "/idx/mysql"},
+		{" INDEX DIRECTORY='/idx/it''s here/'", "", "/idx/it''s here"},
+		{" DATA DIRECTORY='/'", "/", ""},
+	}
+	for _, c := range cases {
+		input := base + c.Clauses
+		actualBase, dirs := parseCreateDirectories(input)
+		if actualBase != base || dirs.data != c.Data || dirs.index != c.Index {
+			t.Errorf("Unexpected result from parseCreateDirectories on %s: returned %q, %+v", input, actualBase, dirs)
+		}
+	}
+
+	// Partition-level clauses are left alone
+	partitioned := base + "\n/*!50100 PARTITION BY RANGE (`id`)\n(PARTITION p0 VALUES LESS THAN MAXVALUE DATA DIRECTORY = '/data/p0' ENGINE = MyISAM) */"
+	if actualBase, dirs := parseCreateDirectories(partitioned); actualBase != partitioned || dirs != (tableDirectories{}) {
+		t.Errorf("Unexpected result from parseCreateDirectories on partitioned table: returned %q, %+v", actualBase, dirs)
+	}
+}
+
+func TestExtractDirectories(t *testing.T) {
+	makeTable := func(name, clauses string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "MyISAM",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown) + clauses
+		table.UnsupportedDDL = (clauses != "")
+		return table
+	}
+	schemaFromInstance := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", " DATA DIRECTORY='/data/' INDEX DIRECTORY='/idx/'"),
+			makeTable("slash", " DATA DIRECTORY='/data/'"),
+			makeTable("moved", " DATA DIRECTORY='/data/'"),
+			makeTable("todefault", " INDEX DIRECTORY='/idx/'"),
+			makeTable("fromdefault", ""),
+			makeTable("none", ""),
+		},
+	}
+	desired := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", " DATA DIRECTORY='/data/' INDEX DIRECTORY='/idx/'"),
+			makeTable("slash", " DATA DIRECTORY='/data'"),
+			makeTable("moved", " DATA DIRECTORY='/data2/'"),
+			makeTable("todefault", ""),
+			makeTable("fromdefault", " DATA DIRECTORY='/data/'"),
+			makeTable("none", ""),
+			makeTable("created", " DATA DIRECTORY='/data/'"),
+		},
+	}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: desired.Tables}
+
+	diffs := extractDirectories(schemaFromInstance, schemaFromDir, tengo.FlavorUnknown)
+	expected := map[string]string{
+		"moved":       "DATA DIRECTORY '/data', INDEX DIRECTORY default to DATA DIRECTORY '/data2', INDEX DIRECTORY default",
+		"todefault":   "DATA DIRECTORY default, INDEX DIRECTORY '/idx' to DATA DIRECTORY default, INDEX DIRECTORY default",
+		"fromdefault": "DATA DIRECTORY default, INDEX DIRECTORY default to DATA DIRECTORY '/data', INDEX DIRECTORY default",
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+	}
+	for _, diff := range diffs {
+		key := diff.ObjectKey()
+		dd := diff.(*directoryDiff)
+		if !strings.Contains(dd.reason(), expected[key.Name]) {
+			t.Errorf("Unexpected reason for %s: %s", key, dd.reason())
+		}
+		stmt, err := diff.Statement(tengo.StatementModifiers{})
+		if unsupportedErr, ok := err.(*tengo.UnsupportedDiffError); stmt != "" || !ok || unsupportedErr.ObjectKey != key {
+			t.Errorf("Expected Statement() for %s to return an UnsupportedDiffError, instead found %q, %v", key, stmt, err)
+		}
+	}
+
+	// Tables existing on both sides should no longer be considered unsupported,
+	// and the desired schema's original tables must not have been modified
+	for n, table := range schemaFromDir.Tables {
+		if table.Name == "created" {
+			if table != desired.Tables[n] || !table.UnsupportedDDL {
+				t.Error("Expected table only existing in desired schema to be left as-is")
+			}
+			continue
+		}
+		if table.UnsupportedDDL || table.CreateStatement != table.GeneratedCreateStatement(tengo.FlavorUnknown) {
+			t.Errorf("Expected table %s to no longer have a directory clause, but it does: %s", table.Name, table.CreateStatement)
+		}
+		if table.Name != "none" && table == desired.Tables[n] {
+			t.Errorf("Expected table %s to be replaced with a copy, but it was not", table.Name)
+		}
+	}
+	for _, table := range desired.Tables {
+		if strings.Contains(table.CreateStatement, "DIRECTORY") != (table.Name != "none" && table.Name != "todefault") {
+			t.Errorf("Desired schema's table %s unexpectedly modified: %s", table.Name, table.CreateStatement)
+		}
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.FilteredTableDiffs(tengo.DiffTypeAlter)) > 0 {
+		t.Errorf("Expected no other ALTER TABLEs after directory extraction, instead found %v", diff.FilteredTableDiffs(tengo.DiffTypeAlter))
+	}
+
+	// Ignored tables yield a blank statement rather than an error
+	mods := tengo.StatementModifiers{IgnoreTable: regexp.MustCompile(".")}
+	if stmt, err := diffs[0].Statement(mods); stmt != "" || err != nil {
+		t.Errorf("Expected ignored table to yield blank statement, instead found %q, %v", stmt, err)
+	}
+}
@@ -1,8 +1,12 @@
 package applier
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -23,6 +27,23 @@ type DDLStatement struct {
 	instance      *tengo.Instance
 	schemaName    string
 	connectParams string
+
+	backfills     []*DDLStatement // UPDATEs which must be run prior to this statement
+	rowsNote      string          // comment describing rows rewritten by this statement, if any
+	fatalWarnings map[string]bool // warning codes (or "all") which cause Execute to return an error
+	retries       retryPolicy     // how Execute handles transient errors such as deadlocks
+
+	algorithm        AlterAlgorithm // how the server executes this statement, if an ALTER TABLE
+	algorithmReasons []string       // operations causing a table rebuild or copy, if any
+
+	safety        Safety        // whether this statement is destructive, for grouping output
+	unsafeClasses []UnsafeClass // kinds of destructive operations performed by this statement, if any
+	groupLabel    string        // header describing this statement's group in output, if grouping
+
+	key      tengo.ObjectKey // object modified by this statement, for JSON output
+	diffType tengo.DiffType  // DiffTypeNone for backfills and statements from plan files
+
+	disableFKChecks bool // if true, output must disable foreign_key_checks around this statement, due to a cycle of foreign keys
 }
 
 // NewDDLStatement creates and returns a DDLStatement. If the statement ends up
@@ -34,6 +55,8 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 	ddl = &DDLStatement{
 		instance:   target.Instance,
 		schemaName: target.SchemaName,
+		key:        diff.ObjectKey(),
+		diffType:   diff.DiffType(),
 	}
 
 	// Don't run database-level DDL in a schema; not even possible for CREATE
@@ -60,16 +83,40 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		}
 	}
 
+	// Classify whether the statement rebuilds or copies the table, which affects
+	// whether osc-tool is used, as well as enforcement of --require-wrapper below
+	ddl.algorithm, ddl.algorithmReasons = ClassifyAlter(diff, ddl.instance.Flavor())
+
 	// Options may indicate some/all DDL gets executed by shelling out to another program.
-	wrapper, err := getWrapper(target.Dir.Config, diff, tableSize, &mods)
+	wrapper, oscTool, err := getWrapper(target, diff, ddl.algorithm, tableSize, &mods)
 	if err != nil {
 		return nil, err
 	}
 
+	// If --unsafe-ops permits every class of destructive operation performed by
+	// the statement, generate it as if --allow-unsafe were enabled. This must
+	// not affect the NOT NULL check of nullBackfills below, since that is not
+	// one of the classes.
+	stmtMods := mods
+	ddl.unsafeClasses = ClassifyUnsafe(diff, mods)
+	if len(ddl.unsafeClasses) > 0 && !mods.AllowUnsafe {
+		permitted, err := unsafeOpsForConfig(target.Dir.Config)
+		if err != nil {
+			return nil, err
+		}
+		stmtMods.AllowUnsafe = true
+		for _, class := range ddl.unsafeClasses {
+			stmtMods.AllowUnsafe = stmtMods.AllowUnsafe && permitted[class]
+		}
+		if stmtMods.AllowUnsafe {
+			log.Debugf("Allowing unsafe operations for %s: unsafe-ops permits %s", diff.ObjectKey(), joinUnsafeClasses(ddl.unsafeClasses))
+		}
+	}
+
 	// Get the raw DDL statement as a string, handling errors and noops correctly
-	if ddl.stmt, err = diff.Statement(mods); tengo.IsForbiddenDiff(err) {
+	if ddl.stmt, err = diff.Statement(stmtMods); tengo.IsForbiddenDiff(err) {
 		// Intentionally avoiding fmt.Errorf here to avoid golint complaining about capitalization
-		errorText := fmt.Sprintf("Destructive statement /* %s */ is considered unsafe. Use --allow-unsafe or --safe-below-size to permit this operation; see --help for more information.", ddl.stmt)
+		errorText := fmt.Sprintf("Destructive statement /* %s */ is considered unsafe. %s; see --help for more information.", ddl.stmt, permitSuggestion(ddl.unsafeClasses))
 		return nil, errors.New(errorText)
 	} else if err != nil {
 		// Leave the error untouched/unwrapped to allow caller to handle appropriately
@@ -77,6 +124,67 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 	} else if ddl.stmt == "" {
 		// Noop statements (due to mods) must be skipped by caller
 		return nil, nil
+	} else if columns := reducedPrecisionColumns(diff); len(columns) > 0 && !stmtMods.AllowUnsafe {
+		// tengo doesn't detect all reductions in numeric precision, so these are
+		// checked separately
+		noun := "column"
+		if len(columns) > 1 {
+			noun = "columns"
+		}
+		errorText := fmt.Sprintf("Destructive statement /* %s */ is considered unsafe, since it reduces the precision of %s %s. %s; see --help for more information.", ddl.stmt, noun, strings.Join(columns, ", "), permitSuggestion(ddl.unsafeClasses))
+		return nil, errors.New(errorText)
+	}
+
+	// Enforce --require-wrapper if the statement rebuilds or copies the table
+	// without a wrapper
+	if err := checkRequireWrapper(target.Dir.Config, diff, ddl.algorithm, wrapper, tableSize); err != nil {
+		return nil, err
+	}
+
+	// Determine which warnings, if any, should be treated as errors
+	if ddl.fatalWarnings, err = parseFatalWarnings(target.Dir.Config.GetSlice("fatal-warnings", ',', true)); err != nil {
+		return nil, err
+	}
+
+	// Determine whether statements failing with transient errors are retried
+	if ddl.retries, err = retryPolicyForConfig(target.Dir.Config); err != nil {
+		return nil, err
+	}
+
+	// If requested, make DROPs tolerate objects which were already removed
+	dropExists := target.Dir.Config.GetBool("drop-if-exists")
+	if dropExists {
+		ddl.stmt = addDropIfExists(ddl.stmt, diff, ddl.instance.Flavor())
+	}
+
+	// If any columns are changing to NOT NULL, check the live table for NULL
+	// values, and backfill them if requested
+	if ddl.backfills, err = nullBackfills(diff, mods, target); err != nil {
+		return nil, err
+	}
+
+	// Classify whether the statement is destructive, for grouping of output.
+	// Backfills are grouped along with their ALTER TABLE.
+	ddl.safety = ClassifySafety(diff, mods)
+	for _, backfill := range ddl.backfills {
+		backfill.safety = ddl.safety
+		backfill.key = ddl.key
+	}
+
+	// Describe how many rows will be rewritten, for operators to gauge impact.
+	// Errors here are not fatal, since this is only informational.
+	if !target.briefOutput() {
+		if note, err := rowCountNote(diff, target); err != nil {
+			log.Warnf("Unable to determine number of rows rewritten by ALTER of %s: %s", diff.ObjectKey(), err)
+		} else {
+			ddl.rowsNote = note
+		}
+		if ddl.algorithm != AlterAlgorithmInPlace {
+			ddl.rowsNote = fmt.Sprintf("-- ALTER of %s requires a %s\n", diff.ObjectKey(), ddl.algorithm) + ddl.rowsNote
+		}
+		if len(ddl.unsafeClasses) > 0 {
+			ddl.rowsNote = fmt.Sprintf("-- Unsafe operations: %s\n", joinUnsafeClasses(ddl.unsafeClasses)) + ddl.rowsNote
+		}
 	}
 
 	if wrapper == "" {
@@ -88,16 +196,33 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		} else {
 			port = strconv.Itoa(ddl.instance.Port)
 		}
-		if connOpts, err = util.RealConnectOptions(target.Dir.Config.Get("connect-options")); err != nil {
+		if connOpts, err = util.ExpandEnvVars(target.Dir.Config.Get("connect-options")); err != nil {
+			return nil, ConfigError(err.Error())
+		}
+		if connOpts, err = util.RealConnectOptions(connOpts); err != nil {
+			return nil, ConfigError(err.Error())
+		}
+		user, err := util.ExpandedOption(target.Dir.Config, "user")
+		if err != nil {
 			return nil, ConfigError(err.Error())
 		}
+		password, err := util.PasswordForConfig(target.Dir.Config, ddl.instance.Host, ddl.instance.Port)
+		if err != nil {
+			return nil, err
+		}
+		if timeZone := target.Dir.Config.Get("time-zone"); timeZone != "" {
+			if connOpts != "" {
+				connOpts += ","
+			}
+			connOpts += "time_zone='" + timeZone + "'"
+		}
 		variables := map[string]string{
 			"HOST":        ddl.instance.Host,
 			"PORT":        port,
 			"SOCKET":      socket,
 			"SCHEMA":      ddl.schemaName,
-			"USER":        target.Dir.Config.Get("user"),
-			"PASSWORD":    target.Dir.Config.Get("password"),
+			"USER":        user,
+			"PASSWORD":    password,
 			"ENVIRONMENT": target.Dir.Config.Get("environment"),
 			"DDL":         ddl.stmt,
 			"CLAUSES":     "", // filled in below only for tables
@@ -111,8 +236,11 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 			"DIRPATH":     target.Dir.Path,
 		}
 		if diff.ObjectKey().Type == tengo.ObjectTypeTable {
-			td := diff.(*tengo.TableDiff)
+			td := diff.(clauser)
 			variables["CLAUSES"], _ = td.Clauses(mods)
+			if dropExists {
+				variables["CLAUSES"] = addDropIfExists(variables["CLAUSES"], diff, ddl.instance.Flavor())
+			}
 			variables["TABLE"] = variables["NAME"]
 		}
 
@@ -121,11 +249,26 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 			errorText := fmt.Sprintf("A fatal error occurred with pre-processing a DDL statement: %s.", err)
 			return nil, errors.New(errorText)
 		}
+
+		// Surface the progress output of online schema change tools in the log,
+		// since they may run for a long time
+		if oscTool != "" {
+			prefix := fmt.Sprintf("%s on %s %s", oscTool, ddl.instance, diff.ObjectKey())
+			ddl.shellOut.OutputFunc = func(line string) {
+				log.Infof("%s: %s", prefix, line)
+			}
+		}
 	}
 
 	return ddl, nil
 }
 
+// clauser is satisfied by table diffs, which can return the body of their
+// statement separately from the statement's prefix.
+type clauser interface {
+	Clauses(tengo.StatementModifiers) (string, error)
+}
+
 // needTableSize returns true if diff represents an ALTER TABLE or DROP TABLE,
 // and at least one size-related option is in use, meaning that it will be
 // necessary to query for the table's size.
@@ -167,15 +310,43 @@ func getTableSize(target *Target, tableName string) (int64, error) {
 
 // getWrapper returns the command-line for executing diff as a shell-out, if
 // configured to do so. Any variable placeholders in the returned string have
-// NOT been interpolated yet.
-func getWrapper(config *mybase.Config, diff tengo.ObjectDiff, tableSize int64, mods *tengo.StatementModifiers) (string, error) {
-	wrapper := config.Get("ddl-wrapper")
-	if diff.ObjectKey().Type == tengo.ObjectTypeTable && diff.DiffType() == tengo.DiffTypeAlter && config.Changed("alter-wrapper") {
+// NOT been interpolated yet. If the command-line runs an online schema change
+// tool configured by osc-tool, the tool's name is also returned.
+func getWrapper(target *Target, diff tengo.ObjectDiff, algo AlterAlgorithm, tableSize int64, mods *tengo.StatementModifiers) (wrapper, oscTool string, err error) {
+	config := target.Dir.Config
+	wrapper = config.Get("ddl-wrapper")
+	tool, err := getOSCTool(config)
+	if err != nil {
+		return "", "", err
+	}
+	if diff.ObjectKey().Type == tengo.ObjectTypeTable && diff.DiffType() == tengo.DiffTypeAlter && (config.Changed("alter-wrapper") || tool != "none") {
 		minSize, err := config.GetBytes("alter-wrapper-min-size")
 		if err != nil {
-			return "", ConfigError(err.Error())
+			return "", "", ConfigError(err.Error())
 		}
-		if tableSize >= int64(minSize) {
+		if tableSize >= int64(minSize) && tool != "none" {
+			// Instant, metadata-only, and in-place ALTERs which do not rebuild the
+			// table gain nothing from an external tool, so they are run directly.
+			// ALGORITHM and LOCK clauses are not understood by OSC tools, so they are
+			// always omitted when using one.
+			td := unwrapTableDiff(diff)
+			if algo == AlterAlgorithmInPlace || td == nil {
+				log.Debugf("Skipping osc-tool for %s: ALTER does not rebuild or copy the table", diff.ObjectKey())
+				return wrapper, "", nil
+			}
+			if reason := oscIneligibleReason(tool, td.From, target.DesiredSchema); reason != "" {
+				// Intentionally avoiding fmt.Errorf here to avoid golint complaining about capitalization
+				errorText := fmt.Sprintf("ALTER of %s requires a %s, but cannot be run via --osc-tool=%s because %s. Use --osc-tool=none to run it directly, or perform the ALTER manually; see --help for more information.", diff.ObjectKey(), algo, tool, reason)
+				return "", "", errors.New(errorText)
+			}
+			if wrapper, err = oscToolCommand(config, tool, target.Instance.SocketPath != ""); err != nil {
+				return "", "", err
+			}
+			log.Debugf("Using osc-tool=%s for %s, since ALTER requires a %s", tool, diff.ObjectKey(), algo)
+			mods.AlgorithmClause = ""
+			mods.LockClause = ""
+			return wrapper, tool, nil
+		} else if tableSize >= int64(minSize) {
 			wrapper = config.Get("alter-wrapper")
 
 			// If alter-wrapper-min-size is set, and the table is big enough to use
@@ -195,7 +366,31 @@ func getWrapper(config *mybase.Config, diff tengo.ObjectDiff, tableSize int64, m
 			log.Debugf("Skipping alter-wrapper for %s: size=%d < alter-wrapper-min-size=%d", diff.ObjectKey(), tableSize, minSize)
 		}
 	}
-	return wrapper, nil
+	return wrapper, "", nil
+}
+
+// checkRequireWrapper returns an error if the require-wrapper option forbids
+// running a statement with the supplied algorithm without a wrapper. Tables
+// smaller than alter-wrapper-min-size are exempt, since the configuration
+// already indicates they may be altered without a wrapper.
+func checkRequireWrapper(config *mybase.Config, diff tengo.ObjectDiff, algo AlterAlgorithm, wrapper string, tableSize int64) error {
+	value, err := config.GetEnum("require-wrapper", "none", "copy", "rebuild")
+	if err != nil {
+		return ConfigError(err.Error())
+	}
+	if value == "none" || wrapper != "" || algo == AlterAlgorithmInPlace {
+		return nil
+	} else if value == "copy" && algo != AlterAlgorithmCopy {
+		return nil
+	}
+	if config.Changed("alter-wrapper") || config.Get("osc-tool") != "none" {
+		if minSize, err := config.GetBytes("alter-wrapper-min-size"); err == nil && tableSize < int64(minSize) {
+			return nil
+		}
+	}
+	// Intentionally avoiding fmt.Errorf here to avoid golint complaining about capitalization
+	errorText := fmt.Sprintf("ALTER of %s requires a %s, which --require-wrapper=%s only permits via --alter-wrapper or --osc-tool. Use --require-wrapper=none to permit this operation; see --help for more information.", diff.ObjectKey(), algo, value)
+	return errors.New(errorText)
 }
 
 // getConnectParams returns the necessary connection params (session variables)
@@ -205,6 +400,13 @@ func getConnectParams(diff tengo.ObjectDiff, config *mybase.Config) string {
 	// operations can be slow on large tables.
 	// For ALTER TABLE, if requested, also use foreign_key_checks=1 if adding
 	// new foreign key constraints.
+	if cd, ok := diff.(*combinedTableDiff); ok {
+		if td := cd.tableDiff(); td != nil {
+			diff = td
+		} else {
+			return "readTimeout=0"
+		}
+	}
 	if td, ok := diff.(*tengo.TableDiff); ok && td.Type == tengo.DiffTypeAlter {
 		if config.GetBool("foreign-key-checks") {
 			_, addFKs := td.SplitAddForeignKeys()
@@ -217,10 +419,12 @@ func getConnectParams(diff tengo.ObjectDiff, config *mybase.Config) string {
 		return "readTimeout=0"
 	}
 
-	// If creating a routine, use the server's global sql_mode instead of Skeema's
-	// normal built-in override
+	// If creating a routine or trigger, or creating or altering an event, use the
+	// server's global sql_mode instead of Skeema's normal built-in override
 	otype := diff.ObjectKey().Type
-	if diff.DiffType() == tengo.DiffTypeCreate && (otype == tengo.ObjectTypeProc || otype == tengo.ObjectTypeFunc) {
+	if diff.DiffType() == tengo.DiffTypeCreate && (otype == tengo.ObjectTypeProc || otype == tengo.ObjectTypeFunc || otype == fs.ObjectTypeTrigger) {
+		return "sql_mode=@@GLOBAL.sql_mode"
+	} else if otype == fs.ObjectTypeEvent && diff.DiffType() != tengo.DiffTypeDrop {
 		return "sql_mode=@@GLOBAL.sql_mode"
 	}
 
@@ -236,16 +440,32 @@ func (ddl *DDLStatement) IsShellOut() bool {
 
 // String returns a string representation of ddl. If an external command is in
 // use, the returned string will be prefixed with "\!", the MySQL CLI command
-// shortcut for "system" shellout.
+// shortcut for "system" shellout. If the statement cannot be ordered to satisfy
+// foreign key dependencies, it is surrounded by statements which disable and
+// then restore foreign_key_checks.
 func (ddl *DDLStatement) String() string {
 	if ddl.IsShellOut() {
 		return fmt.Sprintf("\\! %s\n", ddl.shellOut)
+	} else if ddl.disableFKChecks {
+		return fkChecksOffStatement + fs.AddDelimiter(ddl.stmt) + fkChecksRestoreStatement
 	}
 	return fs.AddDelimiter(ddl.stmt)
 }
 
 // Execute runs the DDL statement, either by running a SQL query against a DB,
 // or shelling out to an external program, as appropriate.
+// Before running a SQL query, the connection pool is pinged, so that any idle
+// connections which were dropped by the server (e.g. due to wait_timeout
+// during a long push) are transparently replaced with new ones. Session
+// variables are part of the connection params, so they are automatically
+// re-applied to new connections. However, if the connection is lost while the
+// DDL itself is in-flight, the DDL is NOT retried, since it may or may not have
+// taken effect; a descriptive error is returned instead. If the DDL fails due
+// to a lock wait timeout or deadlock, it is retried as configured by the
+// ddl-retries and ddl-retry-backoff options.
+// After a SQL query succeeds, any warnings it generated are logged, or returned
+// as an error if configured by the fatal-warnings option. In the latter case,
+// the DDL has still taken effect.
 func (ddl *DDLStatement) Execute() error {
 	if ddl.IsShellOut() {
 		return ddl.shellOut.Run()
@@ -254,6 +474,147 @@ func (ddl *DDLStatement) Execute() error {
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(ddl.stmt)
-	return err
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("Unable to reconnect to %s: %s", ddl.instance, err)
+	}
+
+	// SHOW WARNINGS only applies to the connection which ran the DDL, so a single
+	// connection must be used for both
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to reconnect to %s: %s", ddl.instance, err)
+	}
+	defer conn.Close()
+	err = ddl.retries.run(fmt.Sprintf("DDL on %s %s", ddl.instance, ddl.schemaName), func() error {
+		_, err := conn.ExecContext(ctx, ddl.stmt)
+		return err
+	})
+	if isConnectionLostError(err) {
+		return fmt.Errorf("Connection to %s was lost while executing DDL, so it may or may not have been applied. Verify the current state of the object before running Skeema again. Original error: %s", ddl.instance, err)
+	} else if err != nil {
+		return err
+	}
+	return ddl.checkWarnings(ctx, conn)
+}
+
+// checkWarnings examines any warnings generated by the statement which conn
+// just executed. Warnings are only logged at debug level unless the
+// fatal-warnings option is set. Warnings with codes configured by that option
+// are returned as an error, along with the statement text. Other warnings are
+// logged. Notes, which are less severe than warnings, are only logged at the
+// debug level, unless they are also configured as fatal. If fatal-warnings is
+// not set and debug logging is disabled, no query is run at all, avoiding an
+// extra round-trip per statement.
+func (ddl *DDLStatement) checkWarnings(ctx context.Context, conn *sql.Conn) error {
+	if len(ddl.fatalWarnings) == 0 && !log.IsLevelEnabled(log.DebugLevel) {
+		return nil
+	}
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return fmt.Errorf("DDL was executed, but its warnings could not be obtained: %s", err)
+	}
+	defer rows.Close()
+	var fatal []string
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return fmt.Errorf("DDL was executed, but its warnings could not be obtained: %s", err)
+		}
+		text := fmt.Sprintf("%s %d: %s", level, code, message)
+		if ddl.fatalWarnings["all"] || ddl.fatalWarnings[strconv.Itoa(code)] {
+			fatal = append(fatal, text)
+		} else if level == "Note" || len(ddl.fatalWarnings) == 0 {
+			log.Debugf("DDL on %s %s generated %s [Full SQL: %s]", ddl.instance, ddl.schemaName, text, ddl.stmt)
+		} else {
+			log.Warnf("DDL on %s %s generated %s [Full SQL: %s]", ddl.instance, ddl.schemaName, text, ddl.stmt)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("DDL was executed, but its warnings could not be obtained: %s", err)
+	}
+	if len(fatal) > 0 {
+		return &FatalWarningError{Warnings: fatal, Statement: ddl.stmt}
+	}
+	return nil
+}
+
+// FatalWarningError is returned by DDLStatement.Execute if the DDL generated
+// warnings configured as fatal by the fatal-warnings option. The DDL itself
+// has still been executed successfully.
+type FatalWarningError struct {
+	Warnings  []string
+	Statement string
+}
+
+// Error satisfies the builtin error interface.
+func (fwe *FatalWarningError) Error() string {
+	return fmt.Sprintf("DDL was executed, but generated %s configured as fatal by the fatal-warnings option: %s [Full SQL: %s]", countAndNoun(len(fwe.Warnings), "warning"), strings.Join(fwe.Warnings, "; "), fwe.Statement)
+}
+
+// parseFatalWarnings converts the values of the fatal-warnings option into a
+// set of warning codes. The special value "all" matches all warnings and notes.
+// An error is returned if any other value is not a numeric warning code.
+func parseFatalWarnings(values []string) (map[string]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	codes := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if _, err := strconv.ParseUint(value, 10, 16); err != nil && value != "all" {
+			return nil, ConfigError(fmt.Sprintf("Option fatal-warnings has invalid value %q: must be a comma-separated list of numeric warning codes, or \"all\"", value))
+		}
+		codes[value] = true
+	}
+	return codes, nil
+}
+
+// isConnectionLostError returns true if err indicates the database connection
+// was dropped, or false otherwise. The go-sql-driver/mysql driver returns
+// driver.ErrBadConn only if it knows nothing was sent to the server; otherwise
+// it returns its own "invalid connection" error, which we must detect by
+// message since that package isn't imported directly here.
+func isConnectionLostError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return err == driver.ErrBadConn || err.Error() == "invalid connection" || strings.Contains(err.Error(), "broken pipe") || strings.Contains(err.Error(), "connection reset by peer")
+}
+
+// supportsAtomicDDL returns true if the flavor supports atomic DDL, meaning
+// that each individual DDL statement affecting InnoDB tables is either fully
+// applied or fully rolled back, even upon failure or crash. This is the case in
+// MySQL 8.0+ (including Percona Server 8.0+) due to its transactional data
+// dictionary, and in MariaDB 10.6+. Note that atomic DDL never extends to
+// groups of statements, since DDL always implicitly commits.
+func supportsAtomicDDL(flavor tengo.Flavor) bool {
+	if !flavor.Known() {
+		return false
+	}
+	return flavor.HasDataDictionary() || flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 6)
+}
+
+var (
+	reDropObject = regexp.MustCompile(`^((?:#.*\n)*)DROP (TABLE|PROCEDURE|FUNCTION|EVENT|SEQUENCE|VIEW|TRIGGER) `)
+	reDropClause = regexp.MustCompile("(^|^ALTER TABLE `(?:[^`]|``)+` |, )DROP (KEY|FOREIGN KEY) `")
+)
+
+// addDropIfExists returns stmt, generated by diff, with an IF EXISTS clause
+// added if diff is a DROP TABLE, DROP PROCEDURE, DROP FUNCTION, or DROP EVENT.
+// For flavors supporting the syntax (MariaDB 10.1+), if diff is an ALTER TABLE,
+// IF EXISTS is also added to any DROP KEY or DROP FOREIGN KEY clauses; in this
+// case, stmt may be either the full statement or just its list of clauses.
+// Other statements are returned unchanged.
+func addDropIfExists(stmt string, diff tengo.ObjectDiff, flavor tengo.Flavor) string {
+	switch diff.DiffType() {
+	case tengo.DiffTypeDrop:
+		return reDropObject.ReplaceAllString(stmt, "${1}DROP $2 IF EXISTS ")
+	case tengo.DiffTypeAlter:
+		if diff.ObjectKey().Type == tengo.ObjectTypeTable && flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 1) {
+			return reDropClause.ReplaceAllString(stmt, "${1}DROP $2 IF EXISTS `")
+		}
+	}
+	return stmt
 }
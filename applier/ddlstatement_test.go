@@ -1,6 +1,8 @@
 package applier
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -57,10 +59,21 @@ func (s ApplierIntegrationSuite) TestNewDDLStatement(t *testing.T) {
 		"ddl-wrapper":            "/bin/echo ddl-wrapper {SCHEMA}.{NAME} {TYPE} {CLASS}",
 		"alter-wrapper":          "/bin/echo alter-wrapper {SCHEMA}.{TABLE} {TYPE} {CLAUSES}",
 		"alter-wrapper-min-size": "1",
+		"osc-tool":               "none",
 		"alter-algorithm":        "inplace",
 		"alter-lock":             "none",
 		"safe-below-size":        "0",
+		"unsafe-ops":             "",
+		"backfill-nulls":         "0",
+		"drop-if-exists":         "0",
+		"exact-row-counts":       "0",
+		"fatal-warnings":         "",
+		"ddl-retries":            "0",
+		"ddl-retry-backoff":      "1",
+		"require-wrapper":        "none",
+		"brief":                  "0",
 		"connect-options":        "",
+		"time-zone":              "",
 		"environment":            "production",
 	}
 	major, minor, _ := s.d[0].Version()
@@ -160,3 +173,203 @@ func objectDiffExpected(t *testing.T, diff tengo.ObjectDiff, ddl *DDLStatement,
 	}
 	return
 }
+
+func (s ApplierIntegrationSuite) TestDDLStatementReconnect(t *testing.T) {
+	if _, err := s.d[0].SourceSQL(filepath.Join("testdata", "setup.sql")); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	ddl := &DDLStatement{
+		stmt:       "CREATE TABLE reconnect1 (id int unsigned NOT NULL PRIMARY KEY)",
+		instance:   s.d[0].Instance,
+		schemaName: "analytics",
+	}
+	if err := ddl.Execute(); err != nil {
+		t.Fatalf("Unexpected error from Execute: %s", err)
+	}
+
+	// Simulate the server dropping idle connections, by killing all connections
+	// of the pool used by ddl
+	db, err := s.d[0].Connect("", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	var ids []int64
+	query := "SELECT id FROM information_schema.processlist WHERE db = 'analytics' AND id != CONNECTION_ID()"
+	if err := db.Select(&ids, query); err != nil {
+		t.Fatalf("Unexpected error querying processlist: %s", err)
+	} else if len(ids) == 0 {
+		t.Fatal("Expected to find at least one connection in processlist, but found none")
+	}
+	for _, id := range ids {
+		if _, err := db.Exec(fmt.Sprintf("KILL %d", id)); err != nil {
+			t.Fatalf("Unexpected error killing connection %d: %s", id, err)
+		}
+	}
+
+	// Next statement should transparently use a new connection
+	ddl.stmt = "CREATE TABLE reconnect2 (id int unsigned NOT NULL PRIMARY KEY)"
+	if err := ddl.Execute(); err != nil {
+		t.Errorf("Expected Execute to transparently reconnect, but instead got error: %s", err)
+	}
+	schema, err := s.d[0].Schema("analytics")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	}
+	if !schema.HasTable("reconnect1") || !schema.HasTable("reconnect2") {
+		t.Error("Expected both tables to have been created, but at least one is missing")
+	}
+}
+
+func (s ApplierIntegrationSuite) TestDDLStatementWarnings(t *testing.T) {
+	if _, err := s.d[0].SourceSQL(filepath.Join("testdata", "setup.sql")); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	db, err := s.d[0].Connect("analytics", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	if _, err := db.Exec("CREATE TABLE truncation (name varchar(10))"); err != nil {
+		t.Fatalf("Unexpected error creating table: %s", err)
+	}
+	if _, err := db.Exec("INSERT INTO truncation (name) VALUES ('abcdefghij')"); err != nil {
+		t.Fatalf("Unexpected error inserting row: %s", err)
+	}
+
+	// Without strict mode, narrowing a column truncates data with only a warning,
+	// which is just logged by default
+	ddl := &DDLStatement{
+		stmt:          "ALTER TABLE truncation MODIFY COLUMN name varchar(8)",
+		instance:      s.d[0].Instance,
+		schemaName:    "analytics",
+		connectParams: "sql_mode=''",
+	}
+	if err := ddl.Execute(); err != nil {
+		t.Fatalf("Unexpected error from Execute: %s", err)
+	}
+
+	// If the warning's code is configured as fatal, an error is returned, even
+	// though the DDL has still taken effect
+	for n, codes := range []string{"1265", "all", "1264,1265"} {
+		ddl.fatalWarnings, _ = parseFatalWarnings(strings.Split(codes, ","))
+		ddl.stmt = fmt.Sprintf("ALTER TABLE truncation MODIFY COLUMN name varchar(%d)", 7-n)
+		if err := ddl.Execute(); !isFatalWarning(err) || !strings.Contains(err.Error(), "Data truncated") || !strings.Contains(err.Error(), ddl.stmt) {
+			t.Errorf("With fatal-warnings=%s, expected Execute to return an error about truncation, instead found: %v", codes, err)
+		}
+	}
+
+	// Warnings with other codes are not fatal
+	ddl.fatalWarnings, _ = parseFatalWarnings([]string{"1264"})
+	ddl.stmt = "ALTER TABLE truncation MODIFY COLUMN name varchar(2)"
+	if err := ddl.Execute(); err != nil {
+		t.Errorf("Unexpected error from Execute: %s", err)
+	}
+}
+
+func TestParseFatalWarnings(t *testing.T) {
+	if codes, err := parseFatalWarnings(nil); codes != nil || err != nil {
+		t.Errorf("Unexpected return from parseFatalWarnings(nil): %v, %v", codes, err)
+	}
+	codes, err := parseFatalWarnings([]string{"1265", "ALL", "1366"})
+	if err != nil || len(codes) != 3 || !codes["1265"] || !codes["all"] || !codes["1366"] {
+		t.Errorf("Unexpected return from parseFatalWarnings: %v, %v", codes, err)
+	}
+	for _, bad := range []string{"truncation", "-1", "70000", "12.5"} {
+		if _, err := parseFatalWarnings([]string{"1265", bad}); err == nil {
+			t.Errorf("Expected parseFatalWarnings to return an error for value %q, but it did not", bad)
+		}
+	}
+}
+
+func TestIsConnectionLostError(t *testing.T) {
+	cases := map[error]bool{
+		nil:                                    false,
+		driver.ErrBadConn:                      true,
+		errors.New("invalid connection"):       true,
+		errors.New("write: broken pipe"):       true,
+		errors.New("Error 1050: Table exists"): false,
+	}
+	for err, expected := range cases {
+		if actual := isConnectionLostError(err); actual != expected {
+			t.Errorf("Expected isConnectionLostError(%v) to return %t, instead found %t", err, expected, actual)
+		}
+	}
+}
+
+func TestSupportsAtomicDDL(t *testing.T) {
+	cases := map[string]bool{
+		"mysql:5.5":     false,
+		"mysql:5.7":     false,
+		"mysql:8.0":     true,
+		"percona:5.7":   false,
+		"percona:8.0":   true,
+		"mariadb:10.3":  false,
+		"mariadb:10.5":  false,
+		"mariadb:10.6":  true,
+		"mariadb:10.11": true,
+		"unknown:8.0":   false,
+		"":              false,
+	}
+	for input, expected := range cases {
+		if actual := supportsAtomicDDL(tengo.NewFlavor(input)); actual != expected {
+			t.Errorf("Expected supportsAtomicDDL(%q) to return %t, instead found %t", input, expected, actual)
+		}
+	}
+}
+
+func TestAddDropIfExists(t *testing.T) {
+	mysql, maria := tengo.FlavorMySQL80, tengo.FlavorMariaDB103
+	makeTable := func(indexNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:       "users",
+			Engine:     "InnoDB",
+			CharSet:    "latin1",
+			Collation:  "latin1_swedish_ci",
+			Columns:    []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}, {Name: "name", TypeInDB: "varchar(30)", CharSet: "latin1", Collation: "latin1_swedish_ci", CollationIsDefault: true}},
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+		}
+		for _, name := range indexNames {
+			table.SecondaryIndexes = append(table.SecondaryIndexes, &tengo.Index{Name: name, Parts: []tengo.IndexPart{{ColumnName: "name"}}})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(mysql)
+		return table
+	}
+	proc := &tengo.Routine{Name: "drop_table", Type: tengo.ObjectTypeProc, Body: "BEGIN\nDROP TABLE foo;\nEND"}
+	proc.CreateStatement = "CREATE PROCEDURE `drop_table`()\nBEGIN\nDROP TABLE foo;\nEND"
+	mods := tengo.StatementModifiers{AllowUnsafe: true}
+
+	cases := []struct {
+		diff     tengo.ObjectDiff
+		flavor   tengo.Flavor
+		expected string
+	}{
+		{tengo.NewDropTable(makeTable()), mysql, "DROP TABLE IF EXISTS `users`"},
+		{&tengo.RoutineDiff{From: proc}, mysql, "DROP PROCEDURE IF EXISTS `drop_table`"},
+		{&tengo.RoutineDiff{From: proc, ForMetadata: true}, mysql, "# Dropping and re-creating procedure `drop_table` to update metadata\nDROP PROCEDURE IF EXISTS `drop_table`"},
+		{&tengo.RoutineDiff{To: proc}, maria, proc.CreateStatement},
+		{tengo.NewCreateTable(makeTable()), maria, makeTable().CreateStatement},
+		{tengo.NewAlterTable(makeTable("a", "b"), makeTable()), mysql, "ALTER TABLE `users` DROP KEY `a`, DROP KEY `b`"},
+		{tengo.NewAlterTable(makeTable("a", "b"), makeTable()), maria, "ALTER TABLE `users` DROP KEY IF EXISTS `a`, DROP KEY IF EXISTS `b`"},
+	}
+	for _, c := range cases {
+		mods.CompareMetadata = true
+		stmt, err := c.diff.Statement(mods)
+		if err != nil {
+			t.Fatalf("Unexpected error from Statement: %s", err)
+		}
+		if actual := addDropIfExists(stmt, c.diff, c.flavor); actual != c.expected {
+			t.Errorf("Unexpected result from addDropIfExists for %s\nExpected: %s\nActual:   %s", c.flavor, c.expected, actual)
+		}
+	}
+
+	// Clause lists, as used by alter-wrapper's {CLAUSES}, are also handled
+	diff := tengo.NewAlterTable(makeTable("a"), makeTable())
+	clauses, _ := diff.Clauses(mods)
+	if actual := addDropIfExists(clauses, diff, maria); actual != "DROP KEY IF EXISTS `a`" {
+		t.Errorf("Unexpected result from addDropIfExists on clauses: %s", actual)
+	}
+}
+
+func isFatalWarning(err error) bool {
+	_, ok := err.(*FatalWarningError)
+	return ok
+}
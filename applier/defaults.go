@@ -0,0 +1,130 @@
+package applier
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reCurrentTimestamp matches CURRENT_TIMESTAMP, or any of its synonyms, as
+// found in column defaults and ON UPDATE clauses. The first subexpression is
+// the fractional precision, which may be empty.
+var reCurrentTimestamp = regexp.MustCompile(`(?i)^(?:current_timestamp|now|localtime|localtimestamp)(?:\((\d*)\))?$`)
+
+// reQuotedNumber matches a numeric column default which has been quoted.
+var reQuotedNumber = regexp.MustCompile(`^'(-?[0-9]+(?:\.[0-9]*)?(?:[eE][-+]?[0-9]+)?)'$`)
+
+// reUnquotedNumber matches a numeric column default which has not been quoted.
+var reUnquotedNumber = regexp.MustCompile(`^-?[0-9]+(?:\.[0-9]*)?(?:[eE][-+]?[0-9]+)?$`)
+
+// canonicalTimestamp returns value rewritten in the format used by flavor, if
+// value is CURRENT_TIMESTAMP or one of its synonyms. MariaDB 10.2+ displays
+// current_timestamp() in lower-case with parens, while other flavors display
+// CURRENT_TIMESTAMP in upper-case without parens, unless it has a fractional
+// precision. Other values are returned as-is.
+func canonicalTimestamp(value string, flavor tengo.Flavor) string {
+	match := reCurrentTimestamp.FindStringSubmatch(value)
+	if match == nil {
+		return value
+	}
+	if flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+		return "current_timestamp(" + match[1] + ")"
+	} else if match[1] == "" {
+		return "CURRENT_TIMESTAMP"
+	}
+	return "CURRENT_TIMESTAMP(" + match[1] + ")"
+}
+
+// canonicalDefault returns the default value of col rewritten in the format
+// used by flavor. In addition to the handling of canonicalTimestamp, numeric
+// literal defaults of numeric columns are unquoted in MariaDB 10.2+, and quoted
+// in other flavors.
+func canonicalDefault(col *tengo.Column, flavor tengo.Flavor) string {
+	if value := canonicalTimestamp(col.Default, flavor); value != col.Default {
+		return value
+	}
+	var numeric bool
+	for _, prefix := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double"} {
+		if strings.HasPrefix(col.TypeInDB, prefix) {
+			numeric = true
+			break
+		}
+	}
+	if !numeric {
+		return col.Default
+	}
+	if flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+		if match := reQuotedNumber.FindStringSubmatch(col.Default); match != nil {
+			return match[1]
+		}
+	} else if reUnquotedNumber.MatchString(col.Default) {
+		return "'" + col.Default + "'"
+	}
+	return col.Default
+}
+
+// normalizeColumnDefaults rewrites column defaults and ON UPDATE clauses in
+// both schemaFromInstance and schemaFromDir to use the format displayed by
+// flavor. Equivalent values may otherwise be displayed differently, causing
+// spurious differences: MariaDB displays defaults of tables created prior to
+// an upgrade to 10.2+ in the older format, and a workspace may run a different
+// flavor than the target instance. Modified dir tables are replaced with
+// copies, since the same desired schema may be shared by other targets.
+func normalizeColumnDefaults(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	if schemaFromInstance != nil {
+		for n, table := range schemaFromInstance.Tables {
+			if normalized := tableWithCanonicalDefaults(table, flavor); normalized != nil {
+				schemaFromInstance.Tables[n] = normalized
+			}
+		}
+	}
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if normalized := tableWithCanonicalDefaults(table, flavor); normalized != nil {
+			dirTables[n] = normalized
+		}
+	}
+	schemaFromDir.Tables = dirTables
+}
+
+// tableWithCanonicalDefaults returns a copy of table with its column defaults
+// and ON UPDATE clauses rewritten by canonicalDefault and canonicalTimestamp,
+// or nil if no changes are needed. The copy's CreateStatement is rewritten
+// accordingly, on the line defining each modified column.
+func tableWithCanonicalDefaults(table *tengo.Table, flavor tengo.Flavor) *tengo.Table {
+	normalized := *table
+	normalized.Columns = make([]*tengo.Column, len(table.Columns))
+	lines := strings.Split(table.CreateStatement, "\n")
+	var changed bool
+	for n, col := range table.Columns {
+		normalized.Columns[n] = col
+		def, onUpdate := canonicalDefault(col, flavor), canonicalTimestamp(col.OnUpdate, flavor)
+		if def == col.Default && onUpdate == col.OnUpdate {
+			continue
+		}
+		colCopy := *col
+		colCopy.Default, colCopy.OnUpdate = def, onUpdate
+		normalized.Columns[n] = &colCopy
+		changed = true
+		prefix := "  " + tengo.EscapeIdentifier(col.Name) + " "
+		for ln, line := range lines {
+			if strings.HasPrefix(line, prefix) {
+				if def != col.Default {
+					line = strings.Replace(line, " DEFAULT "+col.Default, " DEFAULT "+def, 1)
+				}
+				if onUpdate != col.OnUpdate {
+					line = strings.Replace(line, " ON UPDATE "+col.OnUpdate, " ON UPDATE "+onUpdate, 1)
+				}
+				lines[ln] = line
+				break
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	stripTableClause(&normalized, strings.Join(lines, "\n"), flavor)
+	return &normalized
+}
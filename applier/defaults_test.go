@@ -0,0 +1,87 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestCanonicalDefault(t *testing.T) {
+	cases := []struct {
+		typ      string
+		value    string
+		mariadb  string
+		mysqlish string
+	}{
+		{"timestamp", "CURRENT_TIMESTAMP", "current_timestamp()", "CURRENT_TIMESTAMP"},
+		{"timestamp", "current_timestamp()", "current_timestamp()", "CURRENT_TIMESTAMP"},
+		{"datetime(6)", "CURRENT_TIMESTAMP(6)", "current_timestamp(6)", "CURRENT_TIMESTAMP(6)"},
+		{"datetime", "now()", "current_timestamp()", "CURRENT_TIMESTAMP"},
+		{"int(11)", "'0'", "0", "'0'"},
+		{"int(11)", "-5", "-5", "'-5'"},
+		{"decimal(5,2)", "'1.50'", "1.50", "'1.50'"},
+		{"int(11)", "NULL", "NULL", "NULL"},
+		{"varchar(20)", "'0'", "'0'", "'0'"},
+		{"varchar(20)", "'now()'", "'now()'", "'now()'"},
+		{"int(11)", "(1 + 1)", "(1 + 1)", "(1 + 1)"},
+	}
+	for _, c := range cases {
+		col := &tengo.Column{TypeInDB: c.typ, Default: c.value}
+		if actual := canonicalDefault(col, tengo.FlavorMariaDB103); actual != c.mariadb {
+			t.Errorf("Expected canonicalDefault(%s %s) for MariaDB to return %s, instead found %s", c.typ, c.value, c.mariadb, actual)
+		}
+		if actual := canonicalDefault(col, tengo.FlavorMySQL57); actual != c.mysqlish {
+			t.Errorf("Expected canonicalDefault(%s %s) for MySQL to return %s, instead found %s", c.typ, c.value, c.mysqlish, actual)
+		}
+	}
+}
+
+func TestNormalizeColumnDefaults(t *testing.T) {
+	flavor := tengo.FlavorMariaDB103
+	makeTable := func(qtyDefault, tsDefault string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns: []*tengo.Column{
+				{Name: "qty", TypeInDB: "int(11)", Default: qtyDefault},
+				{Name: "updated_at", TypeInDB: "timestamp", Default: tsDefault, OnUpdate: tsDefault},
+			},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+
+	// Simulate a live table created prior to an upgrade to MariaDB 10.2+, and a
+	// workspace table created by a newer version
+	schemaFromInstance := &tengo.Schema{Tables: []*tengo.Table{makeTable("'0'", "CURRENT_TIMESTAMP")}}
+	origDirTable := makeTable("0", "current_timestamp()")
+	schemaFromDir := &tengo.Schema{Tables: []*tengo.Table{origDirTable}}
+	dirTables := schemaFromDir.Tables
+	normalizeColumnDefaults(schemaFromInstance, schemaFromDir, flavor)
+	if schemaFromDir.Tables[0] != origDirTable || dirTables[0] != origDirTable {
+		t.Error("Expected dir table already in canonical format to be left unchanged")
+	}
+	instTable := schemaFromInstance.Tables[0]
+	if instTable.CreateStatement != origDirTable.CreateStatement || instTable.UnsupportedDDL {
+		t.Errorf("Expected live table to be normalized, instead found %s", instTable.CreateStatement)
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.ObjectDiffs()) > 0 {
+		t.Errorf("Expected no differences after normalization, instead found %v", diff.ObjectDiffs())
+	}
+
+	// With a MySQL target, the dir table is normalized instead, using a copy
+	flavor = tengo.FlavorMySQL57
+	schemaFromInstance = &tengo.Schema{Tables: []*tengo.Table{makeTable("'0'", "CURRENT_TIMESTAMP")}}
+	schemaFromDir = &tengo.Schema{Tables: dirTables}
+	normalizeColumnDefaults(schemaFromInstance, schemaFromDir, flavor)
+	if schemaFromDir.Tables[0] == origDirTable || strings.Contains(origDirTable.CreateStatement, "CURRENT_TIMESTAMP") {
+		t.Error("Expected dir table to be replaced with a normalized copy")
+	}
+	if actual, expected := schemaFromDir.Tables[0].CreateStatement, schemaFromInstance.Tables[0].CreateStatement; actual != expected {
+		t.Errorf("Expected normalized dir table to match live table, instead found %s vs %s", actual, expected)
+	}
+}
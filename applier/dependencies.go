@@ -0,0 +1,217 @@
+package applier
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reIdentifier matches backtick-quoted identifiers, as used for column
+// references in generation expressions and functional index expressions.
+var reIdentifier = regexp.MustCompile("`((?:[^`]|``)+)`")
+
+// referencedColumns returns the set of column names referenced by expr.
+func referencedColumns(expr string) map[string]bool {
+	result := make(map[string]bool)
+	for _, match := range reIdentifier.FindAllStringSubmatch(expr, -1) {
+		result[strings.Replace(match[1], "``", "`", -1)] = true
+	}
+	return result
+}
+
+// tableDependencies represents the intra-table dependency graph of a table:
+// which generated columns and indexes depend on each column.
+type tableDependencies struct {
+	columns map[string][]*tengo.Column // column name -> generated columns referencing it
+	indexes map[string][]*tengo.Index  // column name -> indexes containing it, directly or in an expression
+}
+
+// newTableDependencies builds the dependency graph for table.
+func newTableDependencies(table *tengo.Table) *tableDependencies {
+	deps := &tableDependencies{
+		columns: make(map[string][]*tengo.Column),
+		indexes: make(map[string][]*tengo.Index),
+	}
+	for _, col := range table.Columns {
+		if col.GenerationExpr == "" {
+			continue
+		}
+		for name := range referencedColumns(col.GenerationExpr) {
+			deps.columns[name] = append(deps.columns[name], col)
+		}
+	}
+	indexes := table.SecondaryIndexes
+	if table.PrimaryKey != nil {
+		indexes = append([]*tengo.Index{table.PrimaryKey}, indexes...)
+	}
+	for _, idx := range indexes {
+		names := make(map[string]bool)
+		for _, part := range idx.Parts {
+			if part.ColumnName != "" {
+				names[part.ColumnName] = true
+			}
+			for name := range referencedColumns(part.Expression) {
+				names[name] = true
+			}
+		}
+		for name := range names {
+			deps.indexes[name] = append(deps.indexes[name], idx)
+		}
+	}
+	return deps
+}
+
+// dependentDrops returns the generated columns and indexes of td.From which
+// must be dropped in a separate ALTER TABLE, prior to the rest of td. These
+// are generated columns and indexes that depend on a column being dropped or
+// modified, and which are themselves being dropped or modified; along with any
+// other generated columns and indexes depending on those, transitively, since
+// they cannot remain in the table without them. If there are no such objects,
+// or td is not an ALTER TABLE, nil values are returned.
+func dependentDrops(td *tengo.TableDiff) (cols map[string]bool, indexes map[string]bool) {
+	if td.Type != tengo.DiffTypeAlter || td.From.UnsupportedDDL || td.To.UnsupportedDDL {
+		return nil, nil
+	}
+	toCols := td.To.ColumnsByName()
+	changedCol := func(col *tengo.Column) bool {
+		toCol := toCols[col.Name]
+		return toCol == nil || !col.Equals(toCol)
+	}
+	toIndexes := td.To.SecondaryIndexesByName()
+	if td.To.PrimaryKey != nil {
+		toIndexes[td.To.PrimaryKey.Name] = td.To.PrimaryKey
+	}
+	changedIndex := func(idx *tengo.Index) bool {
+		return !idx.Equals(toIndexes[idx.Name])
+	}
+
+	deps := newTableDependencies(td.From)
+	cols, indexes = make(map[string]bool), make(map[string]bool)
+	var queue []string
+	for _, col := range td.From.Columns {
+		if !changedCol(col) {
+			continue
+		}
+		for _, depCol := range deps.columns[col.Name] {
+			if changedCol(depCol) && !cols[depCol.Name] {
+				cols[depCol.Name] = true
+				queue = append(queue, depCol.Name)
+			}
+		}
+		for _, idx := range deps.indexes[col.Name] {
+			if hasExpressionPart(idx) && changedIndex(idx) {
+				indexes[idx.Name] = true
+			}
+		}
+	}
+	if len(cols) == 0 && len(indexes) == 0 {
+		return nil, nil
+	}
+
+	// Anything depending on a dropped generated column must be dropped as well
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, depCol := range deps.columns[name] {
+			if !cols[depCol.Name] {
+				cols[depCol.Name] = true
+				queue = append(queue, depCol.Name)
+			}
+		}
+		for _, idx := range deps.indexes[name] {
+			indexes[idx.Name] = true
+		}
+	}
+	return cols, indexes
+}
+
+// hasExpressionPart returns true if idx is a functional index, with at least
+// one part consisting of an expression.
+func hasExpressionPart(idx *tengo.Index) bool {
+	for _, part := range idx.Parts {
+		if part.Expression != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDependentAlters rewrites the ALTER TABLEs in diff which drop or modify
+// columns used by generated columns or functional indexes that are also being
+// dropped or modified. Each such ALTER TABLE is replaced with two: the first
+// drops the dependent generated columns and indexes, and the second performs
+// the rest of the changes, including re-adding any dependents that still exist
+// in the desired table. Otherwise, depending on the order of clauses in a
+// single ALTER TABLE, the server may reject a change to a column while a
+// dependent object still references it.
+//
+// ALTER TABLEs are left as-is if a dependent object is part of the primary key
+// or a foreign key, or if the table is partitioned, since these cannot safely
+// be dropped and re-added.
+func splitDependentAlters(diff *tengo.SchemaDiff, flavor tengo.Flavor) {
+	tableDiffs := make([]*tengo.TableDiff, 0, len(diff.TableDiffs))
+	for _, td := range diff.TableDiffs {
+		if pre, post := splitDependentAlter(td, flavor); pre != nil {
+			tableDiffs = append(tableDiffs, pre, post)
+		} else {
+			tableDiffs = append(tableDiffs, td)
+		}
+	}
+	diff.TableDiffs = tableDiffs
+}
+
+// splitDependentAlter returns the two replacement ALTER TABLEs for td, as
+// described by splitDependentAlters. If td does not require splitting, nil
+// values are returned.
+func splitDependentAlter(td *tengo.TableDiff, flavor tengo.Flavor) (pre, post *tengo.TableDiff) {
+	if td.Type != tengo.DiffTypeAlter || td.From.Partitioning != nil || td.To.Partitioning != nil {
+		return nil, nil
+	}
+	dropCols, dropIndexes := dependentDrops(td)
+	if dropCols == nil {
+		return nil, nil
+	} else if td.From.PrimaryKey != nil && dropIndexes[td.From.PrimaryKey.Name] {
+		return nil, nil
+	}
+	for _, fk := range td.From.ForeignKeys {
+		for _, name := range fk.ColumnNames {
+			if dropCols[name] {
+				return nil, nil
+			}
+		}
+	}
+
+	// Build the intermediate version of the table, lacking the dependents
+	mid := *td.From
+	mid.Columns = make([]*tengo.Column, 0, len(td.From.Columns))
+	for _, col := range td.From.Columns {
+		if !dropCols[col.Name] {
+			mid.Columns = append(mid.Columns, col)
+		}
+	}
+	mid.SecondaryIndexes = make([]*tengo.Index, 0, len(td.From.SecondaryIndexes))
+	for _, idx := range td.From.SecondaryIndexes {
+		if !dropIndexes[idx.Name] {
+			mid.SecondaryIndexes = append(mid.SecondaryIndexes, idx)
+		}
+	}
+	mid.CreateStatement = mid.GeneratedCreateStatement(flavor)
+
+	// Any foreign keys being added were already split into a separate ALTER
+	// TABLE by tengo, so they must be omitted from post
+	pre, post = tengo.NewAlterTable(td.From, &mid), tengo.NewAlterTable(&mid, td.To)
+	if pre == nil || post == nil {
+		return nil, nil
+	}
+	if post, _ = post.SplitAddForeignKeys(); post == nil {
+		return nil, nil
+	}
+	mods := tengo.StatementModifiers{AllowUnsafe: true, Flavor: flavor}
+	for _, split := range []*tengo.TableDiff{pre, post} {
+		if _, err := split.Statement(mods); err != nil {
+			return nil, nil
+		}
+	}
+	return pre, post
+}
@@ -0,0 +1,114 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestSplitDependentAlters(t *testing.T) {
+	flavor := tengo.FlavorMySQL80
+	makeTable := func(name string, cols []*tengo.Column, indexes ...*tengo.Index) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            append([]*tengo.Column{{Name: "id", TypeInDB: "int"}}, cols...),
+			PrimaryKey:         &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+			SecondaryIndexes:   indexes,
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+	price := &tengo.Column{Name: "price", TypeInDB: "int", Nullable: true, Default: "NULL"}
+	priceBig := &tengo.Column{Name: "price", TypeInDB: "bigint", Nullable: true, Default: "NULL"}
+	cents := &tengo.Column{Name: "price_cents", TypeInDB: "int", Nullable: true, GenerationExpr: "(`price` * 100)", Virtual: true}
+	centsBig := &tengo.Column{Name: "price_cents", TypeInDB: "bigint", Nullable: true, GenerationExpr: "(`price` * 100)", Virtual: true}
+	centsIdx := &tengo.Index{Name: "price_cents", Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "price_cents"}}}
+	funcIdx := &tengo.Index{Name: "price_neg", Type: "BTREE", Parts: []tengo.IndexPart{{Expression: "(-(`price`))"}}}
+	funcIdxChanged := &tengo.Index{Name: "price_neg", Type: "BTREE", Parts: []tengo.IndexPart{{Expression: "(-(`price`) * 2)"}}}
+
+	cases := []struct {
+		desc     string
+		from, to *tengo.Table
+		expected []string
+	}{
+		{
+			desc: "drop base column along with generated column feeding an index",
+			from: makeTable("a", []*tengo.Column{price, cents}, centsIdx),
+			to:   makeTable("a", nil),
+			expected: []string{
+				"ALTER TABLE `a` DROP COLUMN `price_cents`, DROP KEY `price_cents`",
+				"ALTER TABLE `a` DROP COLUMN `price`",
+			},
+		},
+		{
+			desc: "modify base column along with generated column feeding an index",
+			from: makeTable("b", []*tengo.Column{price, cents}, centsIdx),
+			to:   makeTable("b", []*tengo.Column{priceBig, centsBig}, centsIdx),
+			expected: []string{
+				"ALTER TABLE `b` DROP COLUMN `price_cents`, DROP KEY `price_cents`",
+				"ALTER TABLE `b` MODIFY COLUMN `price` bigint DEFAULT NULL, ADD COLUMN `price_cents` bigint GENERATED ALWAYS AS ((`price` * 100)) VIRTUAL, ADD KEY `price_cents` (`price_cents`)",
+			},
+		},
+		{
+			desc: "drop base column along with functional index",
+			from: makeTable("c", []*tengo.Column{price}, funcIdx),
+			to:   makeTable("c", nil),
+			expected: []string{
+				"ALTER TABLE `c` DROP KEY `price_neg`",
+				"ALTER TABLE `c` DROP COLUMN `price`",
+			},
+		},
+		{
+			desc: "modify base column and functional index",
+			from: makeTable("d", []*tengo.Column{price}, funcIdx),
+			to:   makeTable("d", []*tengo.Column{priceBig}, funcIdxChanged),
+			expected: []string{
+				"ALTER TABLE `d` DROP KEY `price_neg`",
+				"ALTER TABLE `d` MODIFY COLUMN `price` bigint DEFAULT NULL, ADD KEY `price_neg` (((-(`price`) * 2)))",
+			},
+		},
+		{
+			desc: "dependents unchanged",
+			from: makeTable("e", []*tengo.Column{price, cents}, centsIdx, funcIdx),
+			to:   makeTable("e", []*tengo.Column{priceBig, cents}, centsIdx, funcIdx),
+			expected: []string{
+				"ALTER TABLE `e` MODIFY COLUMN `price` bigint DEFAULT NULL",
+			},
+		},
+		{
+			desc: "dependent changed, base unchanged",
+			from: makeTable("f", []*tengo.Column{price, cents}, centsIdx),
+			to:   makeTable("f", []*tengo.Column{price, centsBig}, centsIdx),
+			expected: []string{
+				"ALTER TABLE `f` MODIFY COLUMN `price_cents` bigint GENERATED ALWAYS AS ((`price` * 100)) VIRTUAL",
+			},
+		},
+	}
+	mods := tengo.StatementModifiers{AllowUnsafe: true, Flavor: flavor}
+	for _, c := range cases {
+		diff := tengo.NewSchemaDiff(&tengo.Schema{Tables: []*tengo.Table{c.from}}, &tengo.Schema{Tables: []*tengo.Table{c.to}})
+		splitDependentAlters(diff, flavor)
+		if len(diff.TableDiffs) != len(c.expected) {
+			t.Errorf("%s: expected %d table diffs, instead found %d", c.desc, len(c.expected), len(diff.TableDiffs))
+			continue
+		}
+		for n, td := range diff.TableDiffs {
+			if stmt, err := td.Statement(mods); err != nil || stmt != c.expected[n] {
+				t.Errorf("%s: unexpected statement %d:\n  expected: %s\n  actual:   %s (err=%v)", c.desc, n, c.expected[n], stmt, err)
+			}
+		}
+	}
+
+	// Foreign keys on dependent columns prevent splitting
+	from := makeTable("g", []*tengo.Column{price, cents}, centsIdx)
+	from.ForeignKeys = []*tengo.ForeignKey{{Name: "fk", ColumnNames: []string{"price_cents"}, ReferencedTableName: "h", ReferencedColumnNames: []string{"id"}, UpdateRule: "RESTRICT", DeleteRule: "RESTRICT"}}
+	from.CreateStatement = from.GeneratedCreateStatement(flavor)
+	td := tengo.NewAlterTable(from, makeTable("g", nil))
+	if pre, post := splitDependentAlter(td, flavor); pre != nil || post != nil {
+		t.Error("Expected ALTER affecting a foreign key on a dependent column to not be split")
+	}
+}
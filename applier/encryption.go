@@ -0,0 +1,172 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reEncryptionClause matches the table-level InnoDB encryption clause found in
+// SHOW CREATE TABLE output in MySQL 5.7+ and Percona Server 5.7+. Some
+// versions wrap the clause in a version-gated comment.
+var reEncryptionClause = regexp.MustCompile(` (?:/\*!\d+ )?ENCRYPTION='([YyNn])'(?: \*/)?`)
+
+// reEncryptionOption matches the encryption option within a table's
+// CreateOptions, as obtained from information_schema.
+var reEncryptionOption = regexp.MustCompile(`(?i)(?:^| )ENCRYPTION=["']?([YN])["']?(?: |$)`)
+
+// parseCreateEncryption splits the supplied CREATE TABLE statement into a
+// version without any table-level ENCRYPTION clause, and the upper-case value
+// of that clause ("Y" or "N"). If the statement has no such clause, the
+// statement is returned as-is along with an empty string.
+func parseCreateEncryption(createStmt string) (base, encryption string) {
+	match := reEncryptionClause.FindStringSubmatchIndex(createStmt)
+	if match == nil {
+		return createStmt, ""
+	}
+	base = createStmt[:match[0]] + createStmt[match[1]:]
+	encryption = strings.ToUpper(createStmt[match[2]:match[3]])
+	return base, encryption
+}
+
+// stripCreateOptionsEncryption returns createOptions without any ENCRYPTION
+// option.
+func stripCreateOptionsEncryption(createOptions string) string {
+	return strings.TrimSpace(reEncryptionOption.ReplaceAllString(createOptions, " "))
+}
+
+// encryptionDiff represents an ALTER TABLE which enables or disables InnoDB
+// encryption for a table. It satisfies the tengo.ObjectDiff interface.
+type encryptionDiff struct {
+	table *tengo.Table
+	from  string // "Y" or "N"
+	to    string // ditto
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (ed *encryptionDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// altered.
+func (ed *encryptionDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: ed.table.Name}
+}
+
+// Statement returns the full ALTER TABLE statement.
+func (ed *encryptionDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(ed.table.Name) {
+		return "", nil
+	}
+	clauses, _ := ed.Clauses(mods)
+	return fmt.Sprintf("%s %s", ed.table.AlterStatement(), clauses), nil
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ".
+func (ed *encryptionDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	var clauses []string
+	if mods.AlgorithmClause != "" {
+		clauses = append(clauses, fmt.Sprintf("ALGORITHM=%s", strings.ToUpper(mods.AlgorithmClause)))
+	}
+	if mods.LockClause != "" {
+		clauses = append(clauses, fmt.Sprintf("LOCK=%s", strings.ToUpper(mods.LockClause)))
+	}
+	clauses = append(clauses, fmt.Sprintf("ENCRYPTION='%s'", ed.to))
+	return strings.Join(clauses, ", "), nil
+}
+
+// rebuildReason returns a human-readable description of the encryption change.
+func (ed *encryptionDiff) rebuildReason() string {
+	if ed.to == "Y" {
+		return "encryption is enabled, which re-writes all data in encrypted form"
+	}
+	return "encryption is disabled, which re-writes all data in unencrypted form"
+}
+
+// extractEncryption removes table-level ENCRYPTION clauses from the
+// CreateStatement and CreateOptions of tables existing in both
+// schemaFromInstance and schemaFromDir, so that tengo can diff the rest of
+// their definitions normally. An encryptionDiff is returned for each table
+// whose encryption status differs.
+//
+// A table lacking an ENCRYPTION clause in the database is unencrypted. A table
+// lacking an ENCRYPTION clause in the filesystem is treated as desiring
+// schemaDefault, which should be "Y" if the live schema has a default
+// encryption of 'Y' (MySQL 8.0.16+), or "N" otherwise. This way, tables that
+// inherited encryption from their schema are not unexpectedly decrypted.
+//
+// Modified dir tables are replaced with copies, since the same desired schema
+// may be shared by other targets.
+func extractEncryption(schemaFromInstance, schemaFromDir *tengo.Schema, schemaDefault string, flavor tengo.Flavor) []tengo.ObjectDiff {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	if schemaDefault == "" {
+		schemaDefault = "N"
+	}
+	var diffs []tengo.ObjectDiff
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		instTable := instTables[table.Name]
+		if instTable == nil {
+			continue
+		}
+		instBase, instEncryption := parseCreateEncryption(instTable.CreateStatement)
+		dirBase, dirEncryption := parseCreateEncryption(table.CreateStatement)
+		if instEncryption == "" && dirEncryption == "" {
+			continue // neither side has an encryption clause
+		}
+		instTable.CreateOptions = stripCreateOptionsEncryption(instTable.CreateOptions)
+		stripTableClause(instTable, instBase, flavor)
+		tableCopy := *table
+		tableCopy.CreateOptions = stripCreateOptionsEncryption(table.CreateOptions)
+		stripTableClause(&tableCopy, dirBase, flavor)
+		dirTables[n] = &tableCopy
+		if instEncryption == "" {
+			instEncryption = "N"
+		}
+		if dirEncryption == "" {
+			dirEncryption = schemaDefault
+		}
+		if instEncryption != dirEncryption {
+			diffs = append(diffs, &encryptionDiff{
+				table: &tableCopy,
+				from:  instEncryption,
+				to:    dirEncryption,
+			})
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	return diffs
+}
+
+// schemaDefaultEncryption returns "Y" if t's schema exists and has a default
+// encryption of 'Y', or "N" otherwise. Schema-level default encryption only
+// exists in MySQL 8.0.16+.
+func schemaDefaultEncryption(t *Target, schemaFromInstance *tengo.Schema) (string, error) {
+	if schemaFromInstance == nil || !t.Instance.Flavor().MySQLishMinVersion(8, 0, 16) {
+		return "N", nil
+	}
+	db, err := t.Instance.Connect("", "")
+	if err != nil {
+		return "", err
+	}
+	var defaultEncryption string
+	query := `
+		SELECT default_encryption
+		FROM   information_schema.schemata
+		WHERE  schema_name = ?`
+	if err := db.QueryRow(query, t.SchemaName).Scan(&defaultEncryption); err != nil {
+		return "", err
+	}
+	if strings.EqualFold(defaultEncryption, "YES") {
+		return "Y", nil
+	}
+	return "N", nil
+}
@@ -0,0 +1,149 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestParseCreateEncryption(t *testing.T) {
+	base := "CREATE TABLE `widgets` (\n  `id` int(11) DEFAULT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	cases := []struct {
+		Input      string
+		Encryption string
+	}{
+		{base, ""},
+		{base + " ENCRYPTION='Y'", "Y"},
+		{base + " ENCRYPTION='N'", "N"},
+		{base + " ENCRYPTION='y'", "Y"},
+		{base + " /*!80016 ENCRYPTION='Y' */", "Y"},
+	}
+	for _, c := range cases {
+		actualBase, actualEncryption := parseCreateEncryption(c.Input)
+		if actualBase != base || actualEncryption != c.Encryption {
+			t.Errorf("Unexpected result from parseCreateEncryption on %s: returned %q, %q", c.Input, actualBase, actualEncryption)
+		}
+	}
+
+	optCases := map[string]string{
+		"":                                      "",
+		"ENCRYPTION='Y'":                        "",
+		"ROW_FORMAT=DYNAMIC ENCRYPTION='N'":     "ROW_FORMAT=DYNAMIC",
+		"ENCRYPTION='Y' STATS_PERSISTENT=1":     "STATS_PERSISTENT=1",
+		"KEY_BLOCK_SIZE=8 ENCRYPTION=\"Y\" X=1": "KEY_BLOCK_SIZE=8 X=1",
+	}
+	for input, expected := range optCases {
+		if actual := stripCreateOptionsEncryption(input); actual != expected {
+			t.Errorf("Unexpected result from stripCreateOptionsEncryption(%q): expected %q, found %q", input, expected, actual)
+		}
+	}
+}
+
+func TestExtractEncryption(t *testing.T) {
+	makeTable := func(name, encryption string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+		}
+		if encryption != "" {
+			table.CreateOptions = "ENCRYPTION='" + encryption + "'"
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	makeSchemas := func() (schemaFromInstance, desired *tengo.Schema) {
+		schemaFromInstance = &tengo.Schema{
+			Name: "product",
+			Tables: []*tengo.Table{
+				makeTable("same", "Y"),
+				makeTable("enable", ""),
+				makeTable("enableexplicit", "N"),
+				makeTable("disable", "Y"),
+				makeTable("implicit", "Y"),
+				makeTable("none", ""),
+				makeTable("dropped", "Y"),
+			},
+		}
+		desired = &tengo.Schema{
+			Name: "product",
+			Tables: []*tengo.Table{
+				makeTable("same", "Y"),
+				makeTable("enable", "Y"),
+				makeTable("enableexplicit", "Y"),
+				makeTable("disable", "N"),
+				makeTable("implicit", ""),
+				makeTable("none", ""),
+				makeTable("created", "Y"),
+			},
+		}
+		return schemaFromInstance, desired
+	}
+
+	// With a schema default of N, an encrypted table lacking a clause in the dir
+	// gets decrypted
+	schemaFromInstance, desired := makeSchemas()
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: desired.Tables}
+	diffs := extractEncryption(schemaFromInstance, schemaFromDir, "N", tengo.FlavorUnknown)
+	expected := map[string]string{
+		"enable":         "ALTER TABLE `enable` ENCRYPTION='Y'",
+		"enableexplicit": "ALTER TABLE `enableexplicit` ENCRYPTION='Y'",
+		"disable":        "ALTER TABLE `disable` ENCRYPTION='N'",
+		"implicit":       "ALTER TABLE `implicit` ENCRYPTION='N'",
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+	}
+	for _, diff := range diffs {
+		key := diff.ObjectKey()
+		if stmt, err := diff.Statement(tengo.StatementModifiers{}); stmt != expected[key.Name] || err != nil {
+			t.Errorf("Unexpected result from Statement() for %s: %q, %v", key, stmt, err)
+		}
+		if _, reasons := ClassifyAlter(diff, tengo.FlavorMySQL80); len(reasons) != 1 {
+			t.Errorf("Expected encryption change of %s to have one rebuild reason, instead found %v", key, reasons)
+		}
+	}
+
+	// Tables existing on both sides should no longer have encryption clauses,
+	// and the desired schema's original tables must not have been modified
+	for n, table := range schemaFromDir.Tables {
+		if table.Name == "created" {
+			if table != desired.Tables[n] {
+				t.Error("Expected table only existing in desired schema to be left as-is")
+			}
+			continue
+		}
+		if strings.Contains(table.CreateStatement, "ENCRYPTION") || strings.Contains(table.CreateOptions, "ENCRYPTION") {
+			t.Errorf("Expected table %s to no longer have an encryption clause, but it does: %s", table.Name, table.CreateStatement)
+		}
+	}
+	for _, table := range desired.Tables {
+		if strings.Contains(table.CreateStatement, "ENCRYPTION") != (table.Name != "none" && table.Name != "implicit") {
+			t.Errorf("Desired schema's table %s unexpectedly modified: %s", table.Name, table.CreateStatement)
+		}
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.FilteredTableDiffs(tengo.DiffTypeAlter)) > 0 {
+		t.Errorf("Expected no other ALTER TABLEs after encryption extraction, instead found %v", diff.FilteredTableDiffs(tengo.DiffTypeAlter))
+	}
+
+	// With a schema default of Y, a table lacking a clause in the dir inherits
+	// encryption from the schema
+	schemaFromInstance, desired = makeSchemas()
+	schemaFromDir = &tengo.Schema{Name: "product", Tables: desired.Tables}
+	for _, diff := range extractEncryption(schemaFromInstance, schemaFromDir, "Y", tengo.FlavorUnknown) {
+		if diff.ObjectKey().Name == "implicit" {
+			t.Errorf("Expected table to inherit schema default encryption, but found diff %+v", diff)
+		}
+	}
+
+	// Confirm statement modifiers are handled
+	ed := diffs[0].(*encryptionDiff)
+	mods := tengo.StatementModifiers{AlgorithmClause: "copy"}
+	if stmt, _ := ed.Statement(mods); !strings.HasPrefix(stmt, "ALTER TABLE `"+ed.table.Name+"` ALGORITHM=COPY, ENCRYPTION=") {
+		t.Errorf("Unexpected statement with mods: %s", stmt)
+	}
+}
@@ -0,0 +1,114 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// eventDiff represents a difference in an event between the filesystem and a
+// live schema. tengo does not support events, so these diffs are computed
+// separately. It satisfies the tengo.ObjectDiff interface.
+type eventDiff struct {
+	from *workspace.Event // nil for a create
+	to   *workspace.Event // nil for a drop
+}
+
+// DiffType returns the type of diff operation.
+func (ed *eventDiff) DiffType() tengo.DiffType {
+	if ed.from == nil {
+		return tengo.DiffTypeCreate
+	} else if ed.to == nil {
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the event.
+func (ed *eventDiff) ObjectKey() tengo.ObjectKey {
+	if ed.to != nil {
+		return tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: ed.to.Name}
+	}
+	return tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: ed.from.Name}
+}
+
+// Statement returns the full DDL statement corresponding to the eventDiff. A
+// non-nil error will be returned if the statement is a DROP EVENT and mods do
+// not permit unsafe operations.
+func (ed *eventDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	switch ed.DiffType() {
+	case tengo.DiffTypeCreate:
+		return ed.to.CreateStatement, nil
+	case tengo.DiffTypeAlter:
+		return ed.to.AlterStatement(), nil
+	default:
+		stmt := fmt.Sprintf("DROP EVENT %s", tengo.EscapeIdentifier(ed.from.Name))
+		var err error
+		if !mods.AllowUnsafe {
+			err = &tengo.ForbiddenDiffError{
+				Reason:    "DROP EVENT not permitted",
+				Statement: stmt,
+			}
+		}
+		return stmt, err
+	}
+}
+
+// diffEvents compares liveEvents to desiredEvents, returning an eventDiff for
+// each event which must be created, altered, or dropped. If partial is true,
+// events missing from desiredEvents are not dropped.
+func diffEvents(liveEvents, desiredEvents []*workspace.Event, mods tengo.StatementModifiers, partial bool) []tengo.ObjectDiff {
+	liveByName := make(map[string]*workspace.Event, len(liveEvents))
+	for _, event := range liveEvents {
+		liveByName[strings.ToLower(event.Name)] = event
+	}
+
+	var diffs []tengo.ObjectDiff
+	for _, event := range desiredEvents {
+		live := liveByName[strings.ToLower(event.Name)]
+		delete(liveByName, strings.ToLower(event.Name))
+		if live != nil && event.Matches(live, mods.CompareMetadata) {
+			continue
+		}
+		diffs = append(diffs, &eventDiff{from: live, to: event})
+	}
+	if !partial {
+		for _, live := range liveEvents {
+			if liveByName[strings.ToLower(live.Name)] != nil {
+				diffs = append(diffs, &eventDiff{from: live})
+			}
+		}
+	}
+	return diffs
+}
+
+// checkEventScheduler logs a warning if any enabled events in objDiffs will be
+// created or altered while the event scheduler of t's instance is off, since
+// they will not execute in this situation.
+func checkEventScheduler(t *Target, objDiffs []tengo.ObjectDiff) error {
+	var enabledChanges bool
+	for _, objDiff := range objDiffs {
+		if ed, ok := objDiff.(*eventDiff); ok && ed.to != nil && ed.to.Status == "ENABLED" {
+			enabledChanges = true
+		}
+	}
+	if !enabledChanges {
+		return nil
+	}
+	db, err := t.Instance.Connect("", "")
+	if err != nil {
+		return err
+	}
+	var scheduler string
+	if err := db.QueryRow("SELECT @@global.event_scheduler").Scan(&scheduler); err != nil {
+		return err
+	}
+	if !strings.EqualFold(scheduler, "ON") {
+		log.Warnf("The event scheduler on %s is %s, so events in schema %s will not execute until it is turned ON", t.Instance, strings.ToUpper(scheduler), t.SchemaName)
+	}
+	return nil
+}
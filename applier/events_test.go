@@ -0,0 +1,56 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestEventDiffStatement(t *testing.T) {
+	from := &workspace.Event{
+		Name:            "ev1",
+		Status:          "ENABLED",
+		CreateStatement: "CREATE DEFINER=`root`@`%` EVENT `ev1` ON SCHEDULE EVERY 1 DAY STARTS '2030-01-01 00:00:00' ON COMPLETION NOT PRESERVE ENABLE DO SELECT 1",
+	}
+	to := &workspace.Event{
+		Name:            "ev1",
+		Status:          "DISABLED",
+		CreateStatement: "CREATE EVENT ev1 ON SCHEDULE EVERY 2 DAY DISABLE DO SELECT 2",
+	}
+	mods := tengo.StatementModifiers{}
+	expectKey := tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: "ev1"}
+
+	create := &eventDiff{to: to}
+	if create.DiffType() != tengo.DiffTypeCreate || create.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", create.DiffType(), create.ObjectKey())
+	}
+	if stmt, err := create.Statement(mods); stmt != to.CreateStatement || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	alter := &eventDiff{from: from, to: to}
+	expected := "ALTER EVENT ev1 ON SCHEDULE EVERY 2 DAY ON COMPLETION NOT PRESERVE DISABLE COMMENT '' DO SELECT 2"
+	if alter.DiffType() != tengo.DiffTypeAlter || alter.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", alter.DiffType(), alter.ObjectKey())
+	}
+	if stmt, err := alter.Statement(mods); stmt != expected || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	drop := &eventDiff{from: from}
+	if drop.DiffType() != tengo.DiffTypeDrop || drop.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", drop.DiffType(), drop.ObjectKey())
+	}
+	if stmt, err := drop.Statement(mods); stmt != "DROP EVENT `ev1`" || !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	mods.AllowUnsafe = true
+	if stmt, err := drop.Statement(mods); stmt != "DROP EVENT `ev1`" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	if stmt := addDropIfExists("DROP EVENT `ev1`", drop, tengo.FlavorMySQL80); stmt != "DROP EVENT IF EXISTS `ev1`" {
+		t.Errorf("Unexpected return from addDropIfExists: %q", stmt)
+	}
+}
@@ -0,0 +1,128 @@
+package applier
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// externalObjects is a set of objects which are intentionally managed outside
+// of Skeema. Diffs affecting these objects are suppressed entirely, regardless
+// of whether the objects exist in the filesystem, the live database, or both.
+type externalObjects map[tengo.ObjectKey]bool
+
+// externalObjectsForDir returns the set of objects listed in dir's
+// external-objects option, along with any listed in the file named by its
+// external-objects-file option. A relative file path is interpreted relative
+// to dir.
+func externalObjectsForDir(dir *fs.Dir) (externalObjects, error) {
+	external := make(externalObjects)
+	for _, entry := range dir.Config.GetSlice("external-objects", ',', true) {
+		if err := external.add(entry); err != nil {
+			return nil, fmt.Errorf("Option external-objects: %s", err)
+		}
+	}
+	filePath := dir.Config.Get("external-objects-file")
+	if filePath == "" {
+		return external, nil
+	} else if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(dir.Path, filePath)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Option external-objects-file: %s", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if err := external.add(line); err != nil {
+			return nil, fmt.Errorf("%s line %d: %s", filePath, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Option external-objects-file: %s", err)
+	}
+	return external, nil
+}
+
+// add parses entry, of the form [type:]name, and adds it to the set. If the
+// type is omitted, the object is assumed to be a table.
+func (external externalObjects) add(entry string) error {
+	key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: strings.TrimSpace(entry)}
+	if colon := strings.Index(entry, ":"); colon >= 0 {
+		key.Name = strings.TrimSpace(entry[colon+1:])
+		switch strings.ToLower(strings.TrimSpace(entry[:colon])) {
+		case "table":
+			key.Type = tengo.ObjectTypeTable
+		case "proc", "procedure":
+			key.Type = tengo.ObjectTypeProc
+		case "func", "function":
+			key.Type = tengo.ObjectTypeFunc
+		case "event":
+			key.Type = fs.ObjectTypeEvent
+		case "sequence":
+			key.Type = fs.ObjectTypeSequence
+		case "view":
+			key.Type = fs.ObjectTypeView
+		case "trigger":
+			key.Type = fs.ObjectTypeTrigger
+		default:
+			return fmt.Errorf("entry %q has invalid object type; must be one of table, procedure, function, event, sequence, view, trigger", entry)
+		}
+	}
+	key.Name = strings.Trim(key.Name, "`")
+	if key.Name == "" {
+		return fmt.Errorf("entry %q is missing an object name", entry)
+	}
+	external[key] = true
+	return nil
+}
+
+// filterSchemaDiff removes any table or routine diffs affecting external
+// objects from diff, in-place.
+func (external externalObjects) filterSchemaDiff(diff *tengo.SchemaDiff) {
+	if len(external) == 0 {
+		return
+	}
+	tableDiffs := diff.TableDiffs[:0]
+	for _, td := range diff.TableDiffs {
+		if !external[td.ObjectKey()] {
+			tableDiffs = append(tableDiffs, td)
+		}
+	}
+	diff.TableDiffs = tableDiffs
+	routineDiffs := diff.RoutineDiffs[:0]
+	for _, rd := range diff.RoutineDiffs {
+		if !external[rd.ObjectKey()] {
+			routineDiffs = append(routineDiffs, rd)
+		}
+	}
+	diff.RoutineDiffs = routineDiffs
+}
+
+// filterDiffs returns the subset of objDiffs which do not affect external
+// objects.
+func (external externalObjects) filterDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	if len(external) == 0 {
+		return objDiffs
+	}
+	result := make([]tengo.ObjectDiff, 0, len(objDiffs))
+	for _, objDiff := range objDiffs {
+		if !external[objDiff.ObjectKey()] {
+			result = append(result, objDiff)
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package applier
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestExternalObjectsForDir(t *testing.T) {
+	dir := getDir(t, "testdata/simple", "--external-objects='legacy_audit, func:`legacy_hash`, view:legacy_report' --external-objects-file=../external-objects.txt")
+	external, err := externalObjectsForDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error from externalObjectsForDir: %v", err)
+	}
+	expected := externalObjects{
+		{Type: tengo.ObjectTypeTable, Name: "legacy_audit"}:      true,
+		{Type: tengo.ObjectTypeFunc, Name: "legacy_hash"}:        true,
+		{Type: tengo.ObjectTypeTable, Name: "reporting_rollups"}: true,
+		{Type: tengo.ObjectTypeProc, Name: "refresh_rollups"}:    true,
+		{Type: fs.ObjectTypeEvent, Name: "purge_rollups"}:        true,
+		{Type: fs.ObjectTypeView, Name: "legacy_report"}:         true,
+	}
+	if !reflect.DeepEqual(external, expected) {
+		t.Errorf("Unexpected result from externalObjectsForDir: %v", external)
+	}
+
+	for _, badFlags := range []string{
+		"--external-objects=user:foo",
+		"--external-objects=table:",
+		"--external-objects-file=doesnt-exist.txt",
+	} {
+		if _, err := externalObjectsForDir(getDir(t, "testdata/simple", badFlags)); err == nil {
+			t.Errorf("Expected error from externalObjectsForDir with %s, but err was nil", badFlags)
+		}
+	}
+}
+
+func TestExternalObjectsFilter(t *testing.T) {
+	makeTable := func(name string, colNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for _, colName := range colNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: colName, TypeInDB: "int(11)", Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	proc := &tengo.Routine{Name: "refresh_rollups", Type: tengo.ObjectTypeProc, Body: "SELECT 1", Definer: "root@%", SQLDataAccess: "CONTAINS SQL", SecurityType: "DEFINER"}
+	live := &tengo.Schema{
+		Name:     "product",
+		Tables:   []*tengo.Table{makeTable("posts", "id"), makeTable("reporting_rollups", "id", "total"), makeTable("stray", "id"), makeTable("legacy_audit", "id")},
+		Routines: []*tengo.Routine{proc},
+	}
+	fromDir := &tengo.Schema{
+		Name:   "product",
+		Tables: []*tengo.Table{makeTable("posts", "id"), makeTable("reporting_rollups", "id"), makeTable("widgets", "id")},
+	}
+	external := externalObjects{
+		{Type: tengo.ObjectTypeTable, Name: "reporting_rollups"}: true,
+		{Type: tengo.ObjectTypeTable, Name: "legacy_audit"}:      true,
+		{Type: tengo.ObjectTypeProc, Name: "refresh_rollups"}:    true,
+		{Type: fs.ObjectTypeEvent, Name: "purge_rollups"}:        true,
+	}
+
+	// Registered objects should be neither altered nor dropped, while the
+	// unregistered unexpected table is still dropped
+	diff := tengo.NewSchemaDiff(live, fromDir)
+	external.filterSchemaDiff(diff)
+	expected := map[tengo.ObjectKey]tengo.DiffType{
+		{Type: tengo.ObjectTypeTable, Name: "stray"}:   tengo.DiffTypeDrop,
+		{Type: tengo.ObjectTypeTable, Name: "widgets"}: tengo.DiffTypeCreate,
+	}
+	objDiffs := diff.ObjectDiffs()
+	if len(objDiffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, instead found %d: %v", len(expected), len(objDiffs), objDiffs)
+	}
+	for _, objDiff := range objDiffs {
+		if diffType, ok := expected[objDiff.ObjectKey()]; !ok || diffType != objDiff.DiffType() {
+			t.Errorf("Unexpected diff %s %s", objDiff.DiffType(), objDiff.ObjectKey())
+		}
+	}
+
+	// Other types of diffs should also be filtered
+	otherDiffs := []tengo.ObjectDiff{
+		&tablespaceDiff{table: live.Tables[1], to: "ts1"},
+		&tablespaceDiff{table: live.Tables[2], to: "ts1"},
+		&eventDiff{from: &workspace.Event{Name: "purge_rollups"}},
+	}
+	if filtered := external.filterDiffs(otherDiffs); len(filtered) != 1 || filtered[0] != otherDiffs[1] {
+		t.Errorf("Unexpected result from filterDiffs: %v", filtered)
+	}
+	if filtered := (externalObjects{}).filterDiffs(otherDiffs); len(filtered) != len(otherDiffs) {
+		t.Errorf("Expected empty externalObjects to not filter anything, instead found %v", filtered)
+	}
+}
@@ -0,0 +1,104 @@
+package applier
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// fastPathSchema attempts to obtain the desired state of logicalSchema without
+// executing its statements in a workspace. This is only possible when dir maps
+// to exactly one schema on inst, and every CREATE in logicalSchema is already
+// identical to the canonical SHOW CREATE output of the corresponding object in
+// that schema. In this situation the only possible differences are objects
+// which exist in the live schema but not the filesystem, so the desired schema
+// can be derived from the live one.
+//
+// If any condition cannot be confirmed, nil is returned, and the caller should
+// use a workspace instead. The fast path is never used if compare-metadata is
+// enabled, if the dir lacks an explicit default-character-set or
+// default-collation, or if logicalSchema contains any ALTER or INSERT
+// statements.
+func fastPathSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, inst *tengo.Instance) *workspace.Schema {
+	if len(logicalSchema.Alters) > 0 || len(logicalSchema.Inserts) > 0 || logicalSchema.CharSet == "" || logicalSchema.Collation == "" || dir.Config.GetBool("compare-metadata") {
+		return nil
+	}
+	var schemaName string
+	if logicalSchema.Name == "" {
+		schemaNames, err := dir.SchemaNames(inst)
+		if err != nil || len(schemaNames) == 0 || (len(schemaNames) > 1 && !dir.Config.GetBool("first-only")) {
+			return nil
+		}
+		schemaName = schemaNames[0]
+	} else {
+		var err error
+		if schemaName, err = dir.AliasedSchemaName(logicalSchema.Name); err != nil {
+			return nil
+		}
+	}
+	instSchema, err := inst.Schema(schemaName)
+	if err != nil || instSchema.CharSet != logicalSchema.CharSet || instSchema.Collation != logicalSchema.Collation {
+		return nil
+	}
+
+	// Confirm every CREATE matches the live object exactly. For tables, the
+	// next auto-increment value is ignored if the file doesn't specify one, since
+	// this is how `skeema pull` and `skeema init` write tables by default.
+	// Any default table options are applied to the file's CREATE first, just as
+	// they would be in a workspace.
+	defaultTableOpts, err := dir.DefaultTableOptions()
+	if err != nil {
+		return nil
+	}
+	instCreates := instSchema.ObjectDefinitions()
+	fsCreates := make(map[tengo.ObjectKey]string, len(logicalSchema.Creates))
+	for key, stmt := range logicalSchema.Creates {
+		fsCreate, instCreate := stmt.Body(), instCreates[key]
+		if key.Type == tengo.ObjectTypeTable {
+			fsCreate = defaultTableOpts.ApplyToCreate(fsCreate)
+			if _, fsAutoInc := tengo.ParseCreateAutoInc(fsCreate); fsAutoInc <= 1 {
+				fsCreate, _ = tengo.ParseCreateAutoInc(fsCreate)
+				instCreate, _ = tengo.ParseCreateAutoInc(instCreate)
+			}
+		}
+		if instCreate == "" || fsCreate != instCreate {
+			return nil
+		}
+		fsCreates[key] = fsCreate
+	}
+
+	// Build the desired schema from copies of the matching live objects. Tables
+	// whose file omits the next auto-increment value are adjusted to look like
+	// freshly-created tables, just as they would in a workspace.
+	desired := *instSchema
+	desired.Tables = []*tengo.Table{}
+	for _, table := range instSchema.Tables {
+		fsCreate, ok := fsCreates[tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name}]
+		if !ok {
+			continue
+		}
+		tableCopy := *table
+		if fsCreate != table.CreateStatement {
+			tableCopy.CreateStatement = fsCreate
+			tableCopy.NextAutoIncrement = 0
+			if tableCopy.HasAutoIncrement() {
+				tableCopy.NextAutoIncrement = 1
+			}
+		}
+		desired.Tables = append(desired.Tables, &tableCopy)
+	}
+	desired.Routines = []*tengo.Routine{}
+	for _, routine := range instSchema.Routines {
+		if _, ok := fsCreates[tengo.ObjectKey{Type: routine.Type, Name: routine.Name}]; ok {
+			routineCopy := *routine
+			desired.Routines = append(desired.Routines, &routineCopy)
+		}
+	}
+	log.Debugf("%s: all CREATE statements already match %s %s exactly, so no workspace is needed", dir, inst, schemaName)
+	return &workspace.Schema{
+		Schema:        &desired,
+		LogicalSchema: logicalSchema,
+		Failures:      []*workspace.StatementError{},
+	}
+}
@@ -0,0 +1,95 @@
+package applier
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func (s ApplierIntegrationSuite) TestFastPathSchema(t *testing.T) {
+	setupHostList(t, s.d[0].Instance)
+	defer cleanupHostList(t)
+	if _, err := s.d[0].SourceSQL(filepath.Join("testdata", "setup.sql")); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	db, err := s.d[0].Connect("product", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	db.MustExec("INSERT INTO users (name) VALUES ('alice'), ('bob')")
+	instSchema, err := s.d[0].Schema("product")
+	if err != nil {
+		t.Fatalf("Unable to obtain schema: %s", err)
+	}
+
+	// Write a dir containing each table exactly as the server displays it, minus
+	// next auto-increment values, but omitting the comments table
+	fs.WriteTestFile(t, "testdata/.scratch/fastpath/.skeema", fmt.Sprintf(
+		"host=placeholder\nhost-wrapper='cat testdata/.scratch/applier-hosts'\npassword=fakepw\nschema=product\ndefault-character-set=%s\ndefault-collation=%s\n",
+		instSchema.CharSet, instSchema.Collation))
+	for _, table := range instSchema.Tables {
+		if table.Name != "comments" {
+			create, _ := tengo.ParseCreateAutoInc(table.CreateStatement)
+			fs.WriteTestFile(t, "testdata/.scratch/fastpath/"+table.Name+".sql", create+";\n")
+		}
+	}
+
+	// statements returns the sorted DDL needed to turn instSchema into the
+	// supplied desired schema
+	statements := func(desired *workspace.Schema) []string {
+		t.Helper()
+		to := *desired.Schema
+		to.Name = instSchema.Name
+		mods := tengo.StatementModifiers{AllowUnsafe: true, NextAutoInc: tengo.NextAutoIncIfIncreased}
+		var result []string
+		for _, diff := range tengo.NewSchemaDiff(instSchema, &to).ObjectDiffs() {
+			if stmt, err := diff.Statement(mods); err != nil {
+				t.Fatalf("Unexpected error from Statement: %s", err)
+			} else if stmt != "" {
+				result = append(result, stmt)
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	dir := getDir(t, "testdata/.scratch/fastpath", "")
+	fastSchema := fastPathSchema(dir.LogicalSchemas[0], dir, s.d[0].Instance)
+	if fastSchema == nil {
+		t.Fatal("Expected fastPathSchema to return a schema, but it returned nil")
+	}
+	opts, err := workspace.OptionsForDir(dir, s.d[0].Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	wsSchema, err := workspace.ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	fastStatements, wsStatements := statements(fastSchema), statements(wsSchema)
+	if len(fastStatements) != 1 || fmt.Sprintf("%v", fastStatements) != fmt.Sprintf("%v", wsStatements) {
+		t.Errorf("Fast path result does not match workspace result.\nFast path: %v\nWorkspace: %v", fastStatements, wsStatements)
+	}
+
+	// The fast path must not be used if any file differs from the live schema in
+	// any way, if compare-metadata is enabled, or if the dir does not pin down
+	// the schema's character set and collation
+	if fastPathSchema(dir.LogicalSchemas[0], getDir(t, "testdata/.scratch/fastpath", "--compare-metadata"), s.d[0].Instance) != nil {
+		t.Error("Expected fastPathSchema to return nil with compare-metadata enabled")
+	}
+	fs.WriteTestFile(t, "testdata/.scratch/fastpath/users.sql", "CREATE TABLE users (id bigint(20) unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY, name varchar(30) NOT NULL);\n")
+	dir = getDir(t, "testdata/.scratch/fastpath", "")
+	if fastPathSchema(dir.LogicalSchemas[0], dir, s.d[0].Instance) != nil {
+		t.Error("Expected fastPathSchema to return nil for non-canonical CREATE, but it did not")
+	}
+	dir.LogicalSchemas[0].Creates = map[tengo.ObjectKey]*fs.Statement{}
+	dir.LogicalSchemas[0].Collation = ""
+	if fastPathSchema(dir.LogicalSchemas[0], dir, s.d[0].Instance) != nil {
+		t.Error("Expected fastPathSchema to return nil without a default-collation, but it did not")
+	}
+}
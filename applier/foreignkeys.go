@@ -0,0 +1,121 @@
+package applier
+
+import (
+	"github.com/skeema/tengo"
+)
+
+// Statements output around DDL which cannot be ordered to satisfy foreign key
+// dependencies, so that the output may be run successfully even in a session
+// with foreign_key_checks enabled. The previous value is saved and restored,
+// in the same manner as mysqldump.
+const (
+	fkChecksOffStatement     = "SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0;\n"
+	fkChecksRestoreStatement = "SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS;\n"
+)
+
+// orderForeignKeyDiffs returns objDiffs reordered so that CREATE TABLEs of
+// tables referenced by foreign keys occur before CREATE TABLEs of the tables
+// referencing them, and DROP TABLEs occur in the opposite order. Only the
+// relative order of CREATE TABLEs, and separately of DROP TABLEs, is changed;
+// each is placed into a position previously occupied by another of the same
+// type, so their ordering relative to all other diffs is preserved. Ties are
+// broken by the original order, so the result is deterministic if objDiffs is.
+//
+// Skeema always executes table DDL with foreign_key_checks disabled, so this
+// ordering is not required for execution by Skeema itself. Rather, it permits
+// Skeema's output to be executed directly by other tools. If foreign keys form
+// a cycle, no such order exists; the returned map indicates which diffs must
+// be executed with foreign_key_checks disabled as a result.
+func orderForeignKeyDiffs(objDiffs []tengo.ObjectDiff) ([]tengo.ObjectDiff, map[*tengo.TableDiff]bool) {
+	var creates, drops []*tengo.TableDiff
+	var createSlots, dropSlots []int
+	for n, objDiff := range objDiffs {
+		if td, ok := objDiff.(*tengo.TableDiff); ok && td.Type == tengo.DiffTypeCreate {
+			creates = append(creates, td)
+			createSlots = append(createSlots, n)
+		} else if ok && td.Type == tengo.DiffTypeDrop {
+			drops = append(drops, td)
+			dropSlots = append(dropSlots, n)
+		}
+	}
+
+	result := make([]tengo.ObjectDiff, len(objDiffs))
+	copy(result, objDiffs)
+	cyclic := make(map[*tengo.TableDiff]bool)
+
+	// A created table depends on the tables it references, whereas a dropped
+	// table is depended upon by the tables referencing it
+	createDeps := func(td *tengo.TableDiff) []*tengo.ForeignKey { return td.To.ForeignKeys }
+	for n, td := range sortTableDiffsByForeignKeys(creates, createDeps, false, cyclic) {
+		result[createSlots[n]] = td
+	}
+	dropDeps := func(td *tengo.TableDiff) []*tengo.ForeignKey { return td.From.ForeignKeys }
+	for n, td := range sortTableDiffsByForeignKeys(drops, dropDeps, true, cyclic) {
+		result[dropSlots[n]] = td
+	}
+	return result, cyclic
+}
+
+// sortTableDiffsByForeignKeys topologically sorts tableDiffs, based on the
+// same-schema foreign keys of each diff's table returned by fks. Normally, a
+// diff is placed after the diffs of the tables its foreign keys reference; if
+// reverse is true, it is instead placed before them. When a cycle of foreign
+// keys prevents this, the earliest remaining diff is placed next anyway, and
+// it is added to cyclic.
+func sortTableDiffsByForeignKeys(tableDiffs []*tengo.TableDiff, fks func(*tengo.TableDiff) []*tengo.ForeignKey, reverse bool, cyclic map[*tengo.TableDiff]bool) []*tengo.TableDiff {
+	// Build a map of table name -> names of tables which must be handled first
+	byName := make(map[string]*tengo.TableDiff, len(tableDiffs))
+	for _, td := range tableDiffs {
+		byName[td.ObjectKey().Name] = td
+	}
+	waitingOn := make(map[string]map[string]bool, len(tableDiffs))
+	for _, td := range tableDiffs {
+		waitingOn[td.ObjectKey().Name] = make(map[string]bool)
+	}
+	for _, td := range tableDiffs {
+		name := td.ObjectKey().Name
+		for _, fk := range fks(td) {
+			other := fk.ReferencedTableName
+			if fk.ReferencedSchemaName != "" || other == name || byName[other] == nil {
+				continue
+			}
+			if reverse {
+				waitingOn[other][name] = true
+			} else {
+				waitingOn[name][other] = true
+			}
+		}
+	}
+
+	result := make([]*tengo.TableDiff, 0, len(tableDiffs))
+	done := make(map[string]bool, len(tableDiffs))
+	ready := func(td *tengo.TableDiff) bool {
+		for other := range waitingOn[td.ObjectKey().Name] {
+			if !done[other] {
+				return false
+			}
+		}
+		return true
+	}
+	for len(result) < len(tableDiffs) {
+		var next *tengo.TableDiff
+		for _, td := range tableDiffs {
+			if !done[td.ObjectKey().Name] && ready(td) {
+				next = td
+				break
+			}
+		}
+		if next == nil {
+			for _, td := range tableDiffs {
+				if !done[td.ObjectKey().Name] {
+					next = td
+					cyclic[td] = true
+					break
+				}
+			}
+		}
+		done[next.ObjectKey().Name] = true
+		result = append(result, next)
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestOrderForeignKeyDiffs(t *testing.T) {
+	makeTable := func(name string, parents ...string) *tengo.Table {
+		table := &tengo.Table{Name: name}
+		for _, parent := range parents {
+			table.ForeignKeys = append(table.ForeignKeys, &tengo.ForeignKey{Name: name + "_" + parent, ReferencedTableName: parent})
+		}
+		return table
+	}
+	names := func(objDiffs []tengo.ObjectDiff) string {
+		result := make([]string, len(objDiffs))
+		for n, objDiff := range objDiffs {
+			result[n] = objDiff.DiffType().String() + " " + objDiff.ObjectKey().Name
+		}
+		return strings.Join(result, ", ")
+	}
+
+	// Creates should be placed after their parents, and drops before them, with
+	// other diffs remaining in place. References to other schemas, to tables
+	// without a diff, and to the table itself are ignored.
+	other := makeTable("other")
+	otherSchemaFK := &tengo.ForeignKey{Name: "fk_ext", ReferencedSchemaName: "elsewhere", ReferencedTableName: "grandparent"}
+	orders := makeTable("orders", "customers", "notexist", "orders")
+	orders.ForeignKeys = append(orders.ForeignKeys, otherSchemaFK)
+	objDiffs := []tengo.ObjectDiff{
+		tengo.NewCreateTable(makeTable("lines", "orders", "products")),
+		tengo.NewDropTable(makeTable("oldparent")),
+		tengo.NewCreateTable(orders),
+		&tablespaceDiff{table: other, to: "ts1"},
+		tengo.NewDropTable(makeTable("oldchild", "oldparent")),
+		tengo.NewCreateTable(makeTable("customers")),
+		tengo.NewCreateTable(makeTable("products")),
+	}
+	result, cyclic := orderForeignKeyDiffs(objDiffs)
+	expected := "CREATE customers, DROP oldchild, CREATE orders, ALTER other, DROP oldparent, CREATE products, CREATE lines"
+	if actual := names(result); actual != expected {
+		t.Errorf("Unexpected order from orderForeignKeyDiffs:\nexpected %s\nfound    %s", expected, actual)
+	}
+	if len(cyclic) > 0 {
+		t.Errorf("Expected no cyclic diffs, instead found %d", len(cyclic))
+	}
+	if names(objDiffs) == names(result) {
+		t.Error("Expected orderForeignKeyDiffs to return a copy rather than modifying its input")
+	}
+
+	// With a cycle, the earliest diff in the cycle is placed first, and flagged
+	cycleDrops := []*tengo.TableDiff{
+		tengo.NewDropTable(makeTable("b", "a")),
+		tengo.NewDropTable(makeTable("a", "b")),
+	}
+	objDiffs = []tengo.ObjectDiff{
+		tengo.NewCreateTable(makeTable("c", "d")),
+		tengo.NewCreateTable(makeTable("d", "c")),
+		tengo.NewCreateTable(makeTable("e", "d")),
+		cycleDrops[0],
+		cycleDrops[1],
+	}
+	result, cyclic = orderForeignKeyDiffs(objDiffs)
+	expected = "CREATE c, CREATE d, CREATE e, DROP b, DROP a"
+	if actual := names(result); actual != expected {
+		t.Errorf("Unexpected order from orderForeignKeyDiffs:\nexpected %s\nfound    %s", expected, actual)
+	}
+	if len(cyclic) != 2 || !cyclic[objDiffs[0].(*tengo.TableDiff)] || !cyclic[cycleDrops[0]] {
+		t.Errorf("Unexpected cyclic diffs: %v", cyclic)
+	}
+
+	// Statements for cyclic diffs disable foreign_key_checks in output
+	ddl := &DDLStatement{stmt: "CREATE TABLE c (id int)", disableFKChecks: true}
+	expected = "SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0;\nCREATE TABLE c (id int);\nSET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS;\n"
+	if actual := ddl.String(); actual != expected {
+		t.Errorf("Unexpected return from String():\nexpected %q\nfound    %q", expected, actual)
+	}
+}
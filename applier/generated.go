@@ -0,0 +1,123 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// normalizeGeneratedExpressions corrects the generation expressions of
+// generated columns, and the expressions of functional index parts, in tables
+// of schemaFromInstance and schemaFromDir which tengo does not support diffing.
+// tengo obtains these expressions from information_schema, which may format
+// them differently than SHOW CREATE TABLE does, for example in how string
+// literals are escaped or prefixed with character set introducers; tengo only
+// corrects this for generated columns in flavors with a data dictionary. When
+// this is the only reason that a table is unsupported, the expressions are
+// replaced with the versions from SHOW CREATE TABLE, so that the table may be
+// diffed normally. Modified dir tables are replaced with copies, since the
+// same desired schema may be shared by other targets.
+func normalizeGeneratedExpressions(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	if schemaFromInstance != nil {
+		for n, table := range schemaFromInstance.Tables {
+			if normalized := tableWithShowCreateExpressions(table, flavor); normalized != nil {
+				schemaFromInstance.Tables[n] = normalized
+			}
+		}
+	}
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if normalized := tableWithShowCreateExpressions(table, flavor); normalized != nil {
+			dirTables[n] = normalized
+		}
+	}
+	schemaFromDir.Tables = dirTables
+}
+
+// tableWithShowCreateExpressions returns a copy of table, with expressions of
+// generated columns and functional index parts parsed from its
+// CreateStatement. If the table is already supported, has no such expressions,
+// or would still be unsupported after parsing them, nil is returned.
+func tableWithShowCreateExpressions(table *tengo.Table, flavor tengo.Flavor) *tengo.Table {
+	if !table.UnsupportedDDL {
+		return nil
+	}
+	normalized := *table
+	var changed bool
+	normalized.Columns = make([]*tengo.Column, len(table.Columns))
+	for n, col := range table.Columns {
+		normalized.Columns[n] = col
+		if col.GenerationExpr == "" {
+			continue
+		}
+		colCopy := *col
+		colCopy.GenerationExpr = expressionPlaceholder(0)
+		if exprs := parseExpressions(table.CreateStatement, colCopy.Definition(flavor, table), 1); exprs != nil {
+			colCopy.GenerationExpr = exprs[0]
+			normalized.Columns[n] = &colCopy
+			changed = true
+		}
+	}
+	normalized.SecondaryIndexes = make([]*tengo.Index, len(table.SecondaryIndexes))
+	for n, idx := range table.SecondaryIndexes {
+		normalized.SecondaryIndexes[n] = idx
+		idxCopy := *idx
+		idxCopy.Parts = make([]tengo.IndexPart, len(idx.Parts))
+		var exprCount int
+		for i, part := range idx.Parts {
+			idxCopy.Parts[i] = part
+			if part.Expression != "" {
+				idxCopy.Parts[i].Expression = expressionPlaceholder(exprCount)
+				exprCount++
+			}
+		}
+		if exprCount == 0 {
+			continue
+		}
+		if exprs := parseExpressions(table.CreateStatement, idxCopy.Definition(flavor), exprCount); exprs != nil {
+			for i := range idxCopy.Parts {
+				if idxCopy.Parts[i].Expression != "" {
+					idxCopy.Parts[i].Expression, exprs = exprs[0], exprs[1:]
+				}
+			}
+			normalized.SecondaryIndexes[n] = &idxCopy
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	stripTableClause(&normalized, table.CreateStatement, flavor)
+	if normalized.UnsupportedDDL {
+		return nil
+	}
+	return &normalized
+}
+
+// expressionPlaceholder returns a string used in place of the nth expression
+// in a definition, which cannot occur in any real expression.
+func expressionPlaceholder(n int) string {
+	return fmt.Sprintf("!!!EXPR%d!!!", n)
+}
+
+// parseExpressions finds the line of createStatement matching definition,
+// which must contain count expression placeholders, and returns the text
+// occurring in place of each placeholder. If no line matches, nil is returned.
+func parseExpressions(createStatement, definition string, count int) []string {
+	template := regexp.QuoteMeta(definition)
+	for n := 0; n < count; n++ {
+		template = strings.Replace(template, expressionPlaceholder(n), "(.+?)", 1)
+	}
+	re, err := regexp.Compile(`(?m)^  ` + template + `,?$`)
+	if err != nil {
+		return nil
+	}
+	matches := re.FindStringSubmatch(createStatement)
+	if matches == nil {
+		return nil
+	}
+	return matches[1:]
+}
@@ -0,0 +1,72 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestNormalizeGeneratedExpressions(t *testing.T) {
+	flavor := tengo.FlavorMySQL80
+	makeTable := func() *tengo.Table {
+		nameCol := &tengo.Column{Name: "name", TypeInDB: "varchar(40)", Nullable: true, Default: "NULL", CharSet: "latin1", Collation: "latin1_swedish_ci", CollationIsDefault: true}
+		labelCol := &tengo.Column{Name: "label", TypeInDB: "varchar(50)", Nullable: true, GenerationExpr: "concat(`name`,_latin1'!')", Virtual: true, CharSet: "latin1", Collation: "latin1_swedish_ci", CollationIsDefault: true}
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{nameCol, labelCol},
+			SecondaryIndexes: []*tengo.Index{
+				{Name: "idx_name", Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "name"}}},
+				{Name: "idx_expr", Type: "BTREE", Parts: []tengo.IndexPart{{Expression: "lower(`name`)"}, {ColumnName: "label"}, {Expression: "concat(`name`,_latin1'x')"}}},
+			},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+
+	// Simulate information_schema formatting the expressions differently than
+	// SHOW CREATE TABLE
+	table := makeTable()
+	table.Columns[1].GenerationExpr = "concat(`name`,'!')"
+	table.SecondaryIndexes[1].Parts[2].Expression = "concat(`name`,_latin1\\'x\\')"
+	table.UnsupportedDDL = true
+	origCol, origIdx := table.Columns[1], table.SecondaryIndexes[1]
+	schemaFromDir := &tengo.Schema{Tables: []*tengo.Table{table}}
+	normalizeGeneratedExpressions(nil, schemaFromDir, flavor)
+	normalized := schemaFromDir.Tables[0]
+	if normalized == table || normalized.UnsupportedDDL {
+		t.Fatalf("Expected table to be replaced with a supported copy, instead found %+v", normalized)
+	}
+	expected := makeTable()
+	if normalized.Columns[1].GenerationExpr != expected.Columns[1].GenerationExpr {
+		t.Errorf("Unexpected generation expression %q", normalized.Columns[1].GenerationExpr)
+	}
+	for n, part := range normalized.SecondaryIndexes[1].Parts {
+		if part != expected.SecondaryIndexes[1].Parts[n] {
+			t.Errorf("Unexpected index part %d: %+v", n, part)
+		}
+	}
+	if origCol.GenerationExpr != "concat(`name`,'!')" || origIdx.Parts[2].Expression != "concat(`name`,_latin1\\'x\\')" {
+		t.Error("Expected original table's columns and indexes to remain unmodified")
+	}
+
+	// Tables which are supported, or unsupported for another reason, are left
+	// unchanged
+	table = makeTable()
+	schema := &tengo.Schema{Tables: []*tengo.Table{table}}
+	normalizeGeneratedExpressions(schema, schema, flavor)
+	if schema.Tables[0] != table {
+		t.Error("Expected supported table to be left unchanged")
+	}
+	table.Columns[1].GenerationExpr = "concat(`name`,'!')"
+	table.CreateStatement = strings.Replace(table.CreateStatement, "ENGINE=InnoDB", "ENGINE=InnoDB STATS_AUTO_RECALC=1", 1)
+	table.UnsupportedDDL = true
+	normalizeGeneratedExpressions(schema, schema, flavor)
+	if schema.Tables[0] != table || table.Columns[1].GenerationExpr != "concat(`name`,'!')" {
+		t.Error("Expected table with other unsupported features to be left unchanged")
+	}
+}
@@ -0,0 +1,233 @@
+package applier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// StatementEvent describes a DDL statement which is about to be executed, or
+// has just been executed, on a target. Duration and Err are only populated
+// once the statement has finished.
+type StatementEvent struct {
+	Instance     *tengo.Instance
+	Schema       string
+	ObjectKey    tengo.ObjectKey // zero value for backfills of NULL values
+	DiffType     tengo.DiffType  // DiffTypeNone for backfills of NULL values
+	Statement    string          // the SQL statement, even if run through a wrapper
+	ShellCommand string          // wrapper command, with passwords masked, if one is in use
+	Started      time.Time
+	Duration     time.Duration
+	Err          error
+}
+
+func newStatementEvent(t *Target, ddl *DDLStatement) *StatementEvent {
+	event := &StatementEvent{
+		Instance:  t.Instance,
+		Schema:    t.SchemaName,
+		ObjectKey: ddl.key,
+		DiffType:  ddl.diffType,
+		Statement: ddl.stmt,
+		Started:   time.Now(),
+	}
+	if ddl.IsShellOut() {
+		event.ShellCommand = ddl.shellOut.String()
+	}
+	return event
+}
+
+// StatementHook permits programs embedding this package to take action around
+// each DDL statement executed by push or apply, for example to notify another
+// system or record statements centrally. Hooks are never called in dry-run
+// mode, or for statements skipped by resume.
+//
+// BeforeStatement is called immediately before the statement is executed.
+// Returning a non-nil error prevents the statement from running, and causes
+// the rest of the target's statements to be skipped, as if the statement had
+// failed. AfterStatement is called once the statement finishes, regardless of
+// whether it succeeded; event.Err reports its outcome. Returning a non-nil
+// error from AfterStatement causes the statement to be counted as a failure
+// even though it has taken effect, and the rest of the target's statements to
+// be skipped. Hooks may be called concurrently for targets on different
+// instances.
+type StatementHook interface {
+	BeforeStatement(t *Target, event *StatementEvent) error
+	AfterStatement(t *Target, event *StatementEvent) error
+}
+
+var statementHooks struct {
+	sync.Mutex
+	list []StatementHook
+}
+
+// RegisterStatementHook adds h to a package-level list of StatementHooks,
+// which are called in registration order for every target, prior to any hooks
+// configured by the before-statement, after-statement, audit-log, and
+// audit-table options. Supplying nil has no effect.
+func RegisterStatementHook(h StatementHook) {
+	if h == nil {
+		return
+	}
+	statementHooks.Lock()
+	defer statementHooks.Unlock()
+	statementHooks.list = append(statementHooks.list, h)
+}
+
+// hooks returns the StatementHooks to call around each of t's statements: all
+// registered hooks, followed by any configured for t's dir.
+func (t *Target) hooks() ([]StatementHook, error) {
+	statementHooks.Lock()
+	hooks := append([]StatementHook{}, statementHooks.list...)
+	statementHooks.Unlock()
+	before, after := t.Dir.Config.Get("before-statement"), t.Dir.Config.Get("after-statement")
+	if before != "" || after != "" {
+		hooks = append(hooks, commandHook{before: before, after: after})
+	}
+	if t.Audit != nil {
+		hooks = append(hooks, t.Audit)
+	}
+	if auditTable := t.Dir.Config.Get("audit-table"); auditTable != "" {
+		parts := strings.Split(auditTable, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, ConfigError(fmt.Sprintf("Option audit-table must be in the format schema.table, instead found %q", auditTable))
+		}
+		hooks = append(hooks, auditTableHook{schema: parts[0], table: parts[1]})
+	}
+	return hooks, nil
+}
+
+// runBeforeStatementHooks calls BeforeStatement on each hook in order,
+// stopping at the first one to return an error.
+func runBeforeStatementHooks(hooks []StatementHook, t *Target, event *StatementEvent) error {
+	for _, h := range hooks {
+		if err := h.BeforeStatement(t, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterStatementHooks calls AfterStatement on each hook in order, and
+// returns the first error. Subsequent hooks are still called after an error,
+// so that one failing hook cannot prevent another from auditing the statement.
+func runAfterStatementHooks(hooks []StatementHook, t *Target, event *StatementEvent) (err error) {
+	for _, h := range hooks {
+		if hookErr := h.AfterStatement(t, event); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
+
+// commandHook is a StatementHook which shells out to the commands configured
+// by the before-statement and after-statement options.
+type commandHook struct {
+	before string
+	after  string
+}
+
+// BeforeStatement runs the before-statement command, if any. A non-zero exit
+// code prevents the statement from running.
+func (ch commandHook) BeforeStatement(t *Target, event *StatementEvent) error {
+	if ch.before == "" {
+		return nil
+	}
+	if err := runHookCommand(ch.before, t, event, false); err != nil {
+		return fmt.Errorf("before-statement command failed: %s", err)
+	}
+	return nil
+}
+
+// AfterStatement runs the after-statement command, if any.
+func (ch commandHook) AfterStatement(t *Target, event *StatementEvent) error {
+	if ch.after == "" {
+		return nil
+	}
+	if err := runHookCommand(ch.after, t, event, true); err != nil {
+		return fmt.Errorf("after-statement command failed: %s", err)
+	}
+	return nil
+}
+
+// runHookCommand interpolates variables describing event into command, and
+// then runs it.
+func runHookCommand(command string, t *Target, event *StatementEvent, finished bool) error {
+	variables, err := hookVariables(t, event, finished)
+	if err != nil {
+		return err
+	}
+	s, err := util.NewInterpolatedShellOut(command, variables)
+	if err != nil {
+		return err
+	}
+	return s.Run()
+}
+
+// hookVariables returns the variables available to before-statement and
+// after-statement commands. These are a subset of those available to
+// ddl-wrapper, along with DURATION, STATUS, and ERROR, which are only
+// populated once the statement has finished.
+func hookVariables(t *Target, event *StatementEvent, finished bool) (map[string]string, error) {
+	var socket, port string
+	if event.Instance.SocketPath != "" {
+		socket = event.Instance.SocketPath
+	} else {
+		port = strconv.Itoa(event.Instance.Port)
+	}
+	user, err := util.ExpandedOption(t.Dir.Config, "user")
+	if err != nil {
+		return nil, err
+	}
+	password, err := util.PasswordForConfig(t.Dir.Config, event.Instance.Host, event.Instance.Port)
+	if err != nil {
+		return nil, err
+	}
+	variables := map[string]string{
+		"HOST":        event.Instance.Host,
+		"PORT":        port,
+		"SOCKET":      socket,
+		"SCHEMA":      event.Schema,
+		"USER":        user,
+		"PASSWORD":    password,
+		"ENVIRONMENT": t.Dir.Config.Get("environment"),
+		"DDL":         event.Statement,
+		"NAME":        event.ObjectKey.Name,
+		"TYPE":        strings.ToUpper(diffTypeLabel(event.DiffType)),
+		"CLASS":       event.ObjectKey.Type.Caps(),
+		"DIRNAME":     t.Dir.BaseName(),
+		"DIRPATH":     t.Dir.Path,
+		"DURATION":    "",
+		"STATUS":      "",
+		"ERROR":       "",
+	}
+	if finished {
+		variables["DURATION"] = formatDuration(event.Duration)
+		variables["STATUS"] = "success"
+		if event.Err != nil {
+			variables["STATUS"] = "failure"
+			variables["ERROR"] = event.Err.Error()
+		}
+	}
+	return variables, nil
+}
+
+// diffTypeLabel returns a lower-case description of diffType, as used in JSON
+// output and audit records. Backfills, which have no diff type, are described
+// as "backfill", since they modify rows of their table rather than its
+// definition.
+func diffTypeLabel(diffType tengo.DiffType) string {
+	if diffType == tengo.DiffTypeNone {
+		return "backfill"
+	}
+	return strings.ToLower(diffType.String())
+}
+
+// formatDuration returns d as a number of seconds with millisecond precision.
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,213 @@
+package applier
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// recordingHook is a StatementHook which tracks the events it receives, and
+// refuses to run statements for the object named by block.
+type recordingHook struct {
+	block  string
+	before []string
+	after  []*StatementEvent
+}
+
+func (rh *recordingHook) BeforeStatement(t *Target, event *StatementEvent) error {
+	rh.before = append(rh.before, event.ObjectKey.Name)
+	if event.ObjectKey.Name == rh.block {
+		return errors.New("blocked")
+	}
+	return nil
+}
+
+func (rh *recordingHook) AfterStatement(t *Target, event *StatementEvent) error {
+	rh.after = append(rh.after, event)
+	return nil
+}
+
+func TestProcessDDLHooks(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	defer func(orig []StatementHook) {
+		statementHooks.list = orig
+	}(statementHooks.list)
+	statementHooks.list = nil
+	hook := &recordingHook{}
+	RegisterStatementHook(hook)
+	RegisterStatementHook(nil)
+	if len(statementHooks.list) != 1 {
+		t.Fatalf("Expected 1 registered hook, instead found %d", len(statementHooks.list))
+	}
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	commandsPath := "testdata/.scratch/commands.log"
+	auditPath := "testdata/.scratch/audit.log"
+	getTarget := func(before, auditTable string) *Target {
+		t.Helper()
+		audit, err := OpenAuditLog(auditPath)
+		if err != nil {
+			t.Fatalf("Unexpected error from OpenAuditLog: %s", err)
+		}
+		configMap := map[string]string{
+			"dry-run":          "0",
+			"max-replica-lag":  "0",
+			"environment":      "production",
+			"user":             "root",
+			"password":         "",
+			"password-command": "",
+			"before-statement": before,
+			"after-statement":  "echo after {NAME} {STATUS} >>" + commandsPath,
+			"audit-table":      auditTable,
+		}
+		return &Target{
+			Instance:   inst,
+			Dir:        &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(configMap)},
+			SchemaName: "product",
+			Audit:      audit,
+		}
+	}
+	makeDDLs := func(commands ...string) []*DDLStatement {
+		ddls := make([]*DDLStatement, len(commands))
+		for n, command := range commands {
+			ddls[n] = &DDLStatement{
+				stmt:       fmt.Sprintf("CREATE TABLE t%d (id int)", n),
+				shellOut:   &util.ShellOut{Command: command},
+				instance:   inst,
+				schemaName: "product",
+				key:        tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: fmt.Sprintf("t%d", n)},
+				diffType:   tengo.DiffTypeCreate,
+			}
+		}
+		return ddls
+	}
+	printer := NewPrinter(false)
+
+	// The second statement fails, so the third is never attempted. Hooks should
+	// run after both the successful and failed statements.
+	target := getTarget("echo before {NAME} >>"+commandsPath, "")
+	if skipCount := target.processDDL(makeDDLs("true", "false", "true"), printer); skipCount != 2 {
+		t.Errorf("Expected 2 skipped statements, instead found %d", skipCount)
+	}
+	target.Audit.Close()
+	contents, err := ioutil.ReadFile(commandsPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading %s: %s", commandsPath, err)
+	}
+	if expected := "before t0\nafter t0 success\nbefore t1\nafter t1 failure\n"; string(contents) != expected {
+		t.Errorf("Unexpected commands run by hooks: %q", contents)
+	}
+	if strings.Join(hook.before, ",") != "t0,t1" || len(hook.after) != 2 || hook.after[0].Err != nil || hook.after[1].Err == nil {
+		t.Errorf("Unexpected events received by registered hook: %v, %v", hook.before, hook.after)
+	}
+	var entries []auditEntry
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatalf("Unexpected error opening %s: %s", auditPath, err)
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Unexpected error unmarshaling audit log line: %s", err)
+		}
+		entries = append(entries, entry)
+	}
+	f.Close()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit log entries, instead found %d", len(entries))
+	}
+	if entries[0].Statement != "CREATE TABLE t0 (id int)" || entries[0].ShellCommand != "true" || entries[0].DiffType != "create" || entries[0].Error != "" {
+		t.Errorf("Unexpected audit log entry for successful statement: %+v", entries[0])
+	}
+	if entries[1].ObjectName != "t1" || entries[1].Environment != "production" || entries[1].Error == "" {
+		t.Errorf("Unexpected audit log entry for failed statement: %+v", entries[1])
+	}
+
+	// A failing before-statement command, or a registered hook returning an
+	// error, should prevent the statement and all subsequent ones from running
+	hook.before, hook.after = nil, nil
+	target = getTarget("false", "")
+	if skipCount := target.processDDL(makeDDLs("true", "true"), printer); skipCount != 2 || len(hook.after) > 0 {
+		t.Errorf("Expected 2 skipped statements and no after hooks, instead found %d and %d", skipCount, len(hook.after))
+	}
+	target.Audit.Close()
+	hook.block = "t1"
+	target = getTarget("", "")
+	if skipCount := target.processDDL(makeDDLs("true", "true", "true"), printer); skipCount != 2 || len(hook.after) != 1 {
+		t.Errorf("Expected 2 skipped statements and 1 after hook, instead found %d and %d", skipCount, len(hook.after))
+	}
+	target.Audit.Close()
+
+	// An invalid audit-table prevents anything from running
+	hook.before, hook.after = nil, nil
+	target = getTarget("", "nodot")
+	if skipCount := target.processDDL(makeDDLs("true"), printer); skipCount != 1 || len(hook.before) > 0 {
+		t.Errorf("Expected 1 skipped statement and no hooks, instead found %d and %d", skipCount, len(hook.before))
+	}
+	target.Audit.Close()
+
+	// A nil AuditLog records nothing, but must not panic
+	var nilAudit *AuditLog
+	if err := nilAudit.AfterStatement(target, &StatementEvent{Instance: inst}); err != nil || nilAudit.Close() != nil || nilAudit.Path() != "" {
+		t.Error("Unexpected behavior from nil AuditLog")
+	}
+}
+
+func TestHookVariables(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	configMap := map[string]string{
+		"environment":      "production",
+		"user":             "root",
+		"password":         "",
+		"password-command": "",
+	}
+	target := &Target{
+		Instance: inst,
+		Dir:      &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(configMap)},
+	}
+	event := &StatementEvent{
+		Instance:  inst,
+		Schema:    "product",
+		ObjectKey: tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "widgets"},
+		DiffType:  tengo.DiffTypeAlter,
+		Statement: "ALTER TABLE widgets ADD COLUMN qty int",
+	}
+	variables, err := hookVariables(target, event, false)
+	if err != nil {
+		t.Fatalf("Unexpected error from hookVariables: %s", err)
+	}
+	if variables["PORT"] != "3306" || variables["CLASS"] != "TABLE" || variables["TYPE"] != "ALTER" || variables["DIRNAME"] != "fakedir" {
+		t.Errorf("Unexpected variables: %v", variables)
+	}
+	if variables["DURATION"] != "" || variables["STATUS"] != "" {
+		t.Errorf("Expected DURATION and STATUS to be empty before statement finishes, instead found %q, %q", variables["DURATION"], variables["STATUS"])
+	}
+	event.Duration, event.Err = 1500*time.Millisecond, errors.New("Duplicate column name 'qty'")
+	variables, _ = hookVariables(target, event, true)
+	if variables["DURATION"] != "1.500" || variables["STATUS"] != "failure" || variables["ERROR"] != "Duplicate column name 'qty'" {
+		t.Errorf("Unexpected variables after failed statement: %v", variables)
+	}
+	event.DiffType = tengo.DiffTypeNone
+	if variables, _ = hookVariables(target, event, true); variables["TYPE"] != "BACKFILL" {
+		t.Errorf("Expected TYPE of backfill to be BACKFILL, instead found %q", variables["TYPE"])
+	}
+}
@@ -0,0 +1,298 @@
+package applier
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/tengo"
+)
+
+// MigrationFile collects the DDL generated by a diff, so that it can be written
+// as a versioned migration file for use by an external schema migration tool,
+// or as a standalone script for manual review and execution. Three formats are
+// supported: "flyway" produces a Flyway SQL migration, "liquibase" produces a
+// Liquibase formatted SQL changelog with one changeset per statement, and "sql"
+// produces a plain SQL script. The statements may be written to a single file,
+// or split into one file per instance or per schema.
+//
+// All methods are safe to call on a nil *MigrationFile, in which case nothing is
+// collected. Add may also be called concurrently from multiple workers.
+type MigrationFile struct {
+	sync.Mutex
+	format       string
+	split        string
+	entries      []migrationEntry
+	fingerprints map[*Target]migrationFingerprints
+}
+
+// migrationEntry represents one statement collected by a MigrationFile.
+type migrationEntry struct {
+	target          *Target
+	instance        string
+	schema          string
+	stmt            string
+	disableFKChecks bool
+}
+
+// migrationFingerprints holds the schema fingerprints of a target, for use in
+// header comments. desired is empty if it could not be meaningfully computed.
+type migrationFingerprints struct {
+	live    string
+	desired string
+}
+
+// NewMigrationFile returns a pointer to a new MigrationFile using the supplied
+// format, which must be "flyway", "liquibase", or "sql". split must be "none"
+// to write all statements to a single file, or "instance" or "schema" to write
+// a separate file per instance or per schema.
+func NewMigrationFile(format, split string) *MigrationFile {
+	return &MigrationFile{format: format, split: split}
+}
+
+// Add records that ddl was generated for t. Statements which shell out to an
+// external command cannot be represented in a migration file, so an error is
+// returned for these instead of recording them.
+func (mf *MigrationFile) Add(t *Target, ddl *DDLStatement) error {
+	if mf == nil {
+		return nil
+	} else if ddl.IsShellOut() {
+		return errors.New("statements executed via alter-wrapper, ddl-wrapper, or osc-tool cannot be written to a migration file")
+	}
+	mf.Lock()
+	defer mf.Unlock()
+	mf.entries = append(mf.entries, migrationEntry{
+		target:          t,
+		instance:        t.Instance.String(),
+		schema:          t.SchemaName,
+		stmt:            ddl.stmt,
+		disableFKChecks: ddl.disableFKChecks,
+	})
+	return nil
+}
+
+// Len returns the number of statements collected so far.
+func (mf *MigrationFile) Len() int {
+	if mf == nil {
+		return 0
+	}
+	mf.Lock()
+	defer mf.Unlock()
+	return len(mf.entries)
+}
+
+// reMigrationDescriptionUnsafe matches runs of characters which are not
+// permitted in the description portion of a migration file name.
+var reMigrationDescriptionUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// FileName returns the base name to use for the migration file, consisting of
+// a version based on the UTC timestamp now, followed by two underscores and
+// description. Flyway additionally requires a "V" prefix for versioned
+// migrations. Any characters in description which are not letters, digits,
+// dashes, or underscores are replaced with underscores.
+func (mf *MigrationFile) FileName(description string, now time.Time) string {
+	description = reMigrationDescriptionUnsafe.ReplaceAllString(description, "_")
+	name := fmt.Sprintf("%s__%s.sql", migrationVersion(now), description)
+	if mf.format == "flyway" {
+		name = "V" + name
+	}
+	return name
+}
+
+// Fingerprint computes the fingerprints of the live schema of each target with
+// collected statements, as well as the fingerprint which the live schema
+// should have after the statements are applied. These are included as header
+// comments by Content. Since the statements were generated without modifying
+// the live schemas, the live fingerprints reflect the same state that the
+// statements were generated from, unless something else modified the schemas
+// in the meantime.
+func (mf *MigrationFile) Fingerprint() error {
+	mf.Lock()
+	defer mf.Unlock()
+	mf.fingerprints = make(map[*Target]migrationFingerprints)
+	for _, entry := range mf.entries {
+		if _, already := mf.fingerprints[entry.target]; already {
+			continue
+		}
+		fingerprints, err := targetFingerprints(entry.target)
+		if err != nil {
+			return fmt.Errorf("Unable to fingerprint %s %s: %s", entry.instance, entry.schema, err)
+		}
+		mf.fingerprints[entry.target] = fingerprints
+	}
+	return nil
+}
+
+// targetFingerprints returns the fingerprints of t's live schema, and of its
+// desired schema, both filtered by the ignore options of t's dir. The desired
+// fingerprint is omitted for partial targets, since their desired schema only
+// specifies some objects. If the dir does not configure the schema's default
+// character set or collation, the live values are used for the desired
+// fingerprint, since the statements will not change them.
+func targetFingerprints(t *Target) (fingerprints migrationFingerprints, err error) {
+	opts, err := introspect.OptionsForDir(t.Dir)
+	if err != nil {
+		return fingerprints, err
+	}
+	live, liveObjs, err := liveSchemaAndObjects(t, opts)
+	if err != nil {
+		return fingerprints, err
+	}
+	fingerprints.live = introspect.Fingerprint(live, liveObjs)
+	if t.Partial || t.DesiredSchema == nil || t.DesiredSchema.Schema == nil {
+		return fingerprints, nil
+	}
+	desired := *t.DesiredSchema.Schema
+	if live != nil && t.Dir.Config.Get("default-character-set") == "" {
+		desired.CharSet = live.CharSet
+	}
+	if live != nil && t.Dir.Config.Get("default-collation") == "" {
+		desired.Collation = live.Collation
+	}
+	fingerprints.desired = introspect.Fingerprint(introspect.FilterSchema(&desired, opts), introspect.WorkspaceObjects(t.DesiredSchema, opts))
+	return fingerprints, nil
+}
+
+// Content returns the full contents of the migration file, using a version
+// based on the UTC timestamp now. Statements are grouped by instance and then by
+// schema, preserving their original order within each schema. If the split
+// option was used, this returns the combined contents of all files.
+func (mf *MigrationFile) Content(now time.Time) string {
+	return mf.content(mf.sortedEntries(), now)
+}
+
+// sortedEntries returns a copy of the collected entries, sorted by instance and
+// then by schema, preserving their original order within each schema.
+func (mf *MigrationFile) sortedEntries() []migrationEntry {
+	mf.Lock()
+	entries := make([]migrationEntry, len(mf.entries))
+	copy(entries, mf.entries)
+	mf.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].instance != entries[j].instance {
+			return entries[i].instance < entries[j].instance
+		}
+		return entries[i].schema < entries[j].schema
+	})
+	return entries
+}
+
+// content returns the contents of a file containing entries, which must
+// already be sorted.
+func (mf *MigrationFile) content(entries []migrationEntry, now time.Time) string {
+	mf.Lock()
+	fingerprints := mf.fingerprints
+	mf.Unlock()
+	version := migrationVersion(now)
+	var b strings.Builder
+	var lastInstance, lastSchema string
+	if mf.format == "liquibase" {
+		b.WriteString("--liquibase formatted sql\n")
+	}
+	fmt.Fprintf(&b, "-- Generated by skeema diff at %s\n", now.UTC().Format(time.RFC3339))
+	for n, entry := range entries {
+		if entry.instance != lastInstance {
+			fmt.Fprintf(&b, "\n-- instance: %s\n", entry.instance)
+			lastInstance, lastSchema = entry.instance, ""
+		}
+		if entry.schema != lastSchema {
+			if fp, ok := fingerprints[entry.target]; ok {
+				fmt.Fprintf(&b, "-- live schema fingerprint: %s\n", fp.live)
+				if fp.desired != "" {
+					fmt.Fprintf(&b, "-- desired schema fingerprint: %s\n", fp.desired)
+				}
+			}
+			// Liquibase may skip changesets which were already applied, so the
+			// default database is set in a changeset that always runs
+			if mf.format == "liquibase" {
+				fmt.Fprintf(&b, "\n--changeset skeema:%s-use-%d runAlways:true\n", version, n+1)
+			}
+			fmt.Fprintf(&b, "USE %s;\n", tengo.EscapeIdentifier(entry.schema))
+			lastSchema = entry.schema
+		}
+		stmt := fs.AddDelimiter(entry.stmt)
+		if entry.disableFKChecks {
+			stmt = fkChecksOffStatement + stmt + fkChecksRestoreStatement
+		}
+		if mf.format == "liquibase" {
+			// Liquibase does not support DELIMITER commands, so compound statements
+			// are instead sent to the server as-is without splitting on semicolons
+			if strings.HasPrefix(stmt, "DELIMITER") {
+				fmt.Fprintf(&b, "\n--changeset skeema:%s-%d splitStatements:false\n%s\n", version, n+1, entry.stmt)
+				continue
+			}
+			fmt.Fprintf(&b, "\n--changeset skeema:%s-%d\n", version, n+1)
+		}
+		b.WriteString(stmt)
+	}
+	return b.String()
+}
+
+// Write writes the migration file to dirPath, using a file name and version
+// based on description and the UTC timestamp now; see FileName. If the split
+// option was used, one file is written per instance or per schema, with the
+// instance and schema appended to description. The paths to the new files are
+// returned. An error is returned if a file with the same name already exists,
+// in which case no files are written.
+func (mf *MigrationFile) Write(dirPath, description string, now time.Time) ([]string, error) {
+	var paths, contents []string
+	var group []migrationEntry
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		fileDescription := description
+		if mf.split == "instance" || mf.split == "schema" {
+			fileDescription += "__" + group[0].instance
+		}
+		if mf.split == "schema" {
+			fileDescription += "__" + group[0].schema
+		}
+		paths = append(paths, filepath.Join(dirPath, mf.FileName(fileDescription, now)))
+		contents = append(contents, mf.content(group, now))
+		group = nil
+	}
+	for _, entry := range mf.sortedEntries() {
+		if len(group) > 0 && ((mf.split == "instance" && entry.instance != group[0].instance) || (mf.split == "schema" && (entry.instance != group[0].instance || entry.schema != group[0].schema))) {
+			flush()
+		}
+		group = append(group, entry)
+	}
+	flush()
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("Migration file %s already exists", path)
+		}
+	}
+	for n, path := range paths {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			return paths[:n], fmt.Errorf("Migration file %s already exists", path)
+		} else if err != nil {
+			return paths[:n], err
+		}
+		if _, err := f.WriteString(contents[n]); err != nil {
+			f.Close()
+			return paths[:n], err
+		}
+		if err := f.Close(); err != nil {
+			return paths[:n], err
+		}
+	}
+	return paths, nil
+}
+
+// migrationVersion returns the version number to use for a migration file
+// generated at time now.
+func migrationVersion(now time.Time) string {
+	return now.UTC().Format("20060102150405")
+}
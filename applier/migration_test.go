@@ -0,0 +1,187 @@
+package applier
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+func TestMigrationFile(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	product := &Target{Instance: inst, SchemaName: "product"}
+	analytics := &Target{Instance: inst, SchemaName: "analytics"}
+	proc := "CREATE PROCEDURE p1() BEGIN SELECT 1; SELECT 2; END"
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	// A nil MigrationFile collects nothing, but must not panic
+	var nilMigration *MigrationFile
+	nilMigration.Add(product, &DDLStatement{stmt: "DROP TABLE foo"})
+	if nilMigration.Len() != 0 {
+		t.Error("Unexpected behavior from nil MigrationFile")
+	}
+
+	// Add statements in an interleaved order, as if from concurrent workers.
+	// Shell-outs are rejected rather than collected.
+	addAll := func(mf *MigrationFile) {
+		mf.Add(product, &DDLStatement{stmt: "CREATE TABLE foo (id int)"})
+		mf.Add(analytics, &DDLStatement{stmt: "DROP TABLE bar"})
+		mf.Add(product, &DDLStatement{stmt: proc})
+		if err := mf.Add(product, &DDLStatement{shellOut: &util.ShellOut{Command: "echo hi"}}); err == nil {
+			t.Error("Expected error adding shell-out to migration file, but err was nil")
+		}
+	}
+
+	flyway := NewMigrationFile("flyway", "none")
+	addAll(flyway)
+	if flyway.Len() != 3 {
+		t.Errorf("Expected 3 statements to be collected, instead found %d", flyway.Len())
+	}
+	if name := flyway.FileName("add foo/bar", now); name != "V20200304050607__add_foo_bar.sql" {
+		t.Errorf("Unexpected flyway file name %q", name)
+	}
+	expected := "-- Generated by skeema diff at 2020-03-04T05:06:07Z\n" +
+		"\n-- instance: 127.0.0.1:3306\n" +
+		"USE `analytics`;\n" +
+		"DROP TABLE bar;\n" +
+		"USE `product`;\n" +
+		"CREATE TABLE foo (id int);\n" +
+		"DELIMITER //\n" + proc + "//\nDELIMITER ;\n"
+	if actual := flyway.Content(now); actual != expected {
+		t.Errorf("Unexpected flyway content:\n%s\nExpected:\n%s", actual, expected)
+	}
+
+	liquibase := NewMigrationFile("liquibase", "none")
+	addAll(liquibase)
+	if name := liquibase.FileName("skeema", now); name != "20200304050607__skeema.sql" {
+		t.Errorf("Unexpected liquibase file name %q", name)
+	}
+	expected = "--liquibase formatted sql\n" +
+		"-- Generated by skeema diff at 2020-03-04T05:06:07Z\n" +
+		"\n-- instance: 127.0.0.1:3306\n" +
+		"\n--changeset skeema:20200304050607-use-1 runAlways:true\nUSE `analytics`;\n" +
+		"\n--changeset skeema:20200304050607-1\nDROP TABLE bar;\n" +
+		"\n--changeset skeema:20200304050607-use-2 runAlways:true\nUSE `product`;\n" +
+		"\n--changeset skeema:20200304050607-2\nCREATE TABLE foo (id int);\n" +
+		"\n--changeset skeema:20200304050607-3 splitStatements:false\n" + proc + "\n"
+	if actual := liquibase.Content(now); actual != expected {
+		t.Errorf("Unexpected liquibase content:\n%s\nExpected:\n%s", actual, expected)
+	}
+
+	// Writing should create the file, but refuse to overwrite an existing one
+	paths, err := liquibase.Write("testdata/.scratch", "skeema", now)
+	if err != nil {
+		t.Fatalf("Unexpected error from Write: %s", err)
+	} else if len(paths) != 1 || paths[0] != filepath.Join("testdata/.scratch", "20200304050607__skeema.sql") {
+		t.Errorf("Unexpected paths returned from Write: %v", paths)
+	}
+	if contents, err := ioutil.ReadFile(paths[0]); err != nil || string(contents) != expected {
+		t.Errorf("Unexpected result reading back migration file: contents=%q err=%v", contents, err)
+	}
+	if _, err := liquibase.Write("testdata/.scratch", "skeema", now); err == nil {
+		t.Error("Expected error writing migration file which already exists, but err was nil")
+	}
+
+	// The sql format writes a plain script. Once fingerprints are known, they are
+	// included as comments before each schema's USE statement.
+	script := NewMigrationFile("sql", "none")
+	addAll(script)
+	script.fingerprints = map[*Target]migrationFingerprints{
+		product:   {live: "aaa", desired: "bbb"},
+		analytics: {live: "ccc"},
+	}
+	if name := script.FileName("skeema", now); name != "20200304050607__skeema.sql" {
+		t.Errorf("Unexpected sql file name %q", name)
+	}
+	expected = "-- Generated by skeema diff at 2020-03-04T05:06:07Z\n" +
+		"\n-- instance: 127.0.0.1:3306\n" +
+		"-- live schema fingerprint: ccc\n" +
+		"USE `analytics`;\n" +
+		"DROP TABLE bar;\n" +
+		"-- live schema fingerprint: aaa\n" +
+		"-- desired schema fingerprint: bbb\n" +
+		"USE `product`;\n" +
+		"CREATE TABLE foo (id int);\n" +
+		"DELIMITER //\n" + proc + "//\nDELIMITER ;\n"
+	if actual := script.Content(now); actual != expected {
+		t.Errorf("Unexpected sql content:\n%s\nExpected:\n%s", actual, expected)
+	}
+}
+
+func TestMigrationFileSplit(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	inst1, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	inst2, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3307)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	targets := []*Target{
+		{Instance: inst2, SchemaName: "product"},
+		{Instance: inst1, SchemaName: "product"},
+		{Instance: inst1, SchemaName: "analytics"},
+	}
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	cases := map[string][]string{
+		"none":     {"20200304050607__skeema.sql"},
+		"instance": {"20200304050607__skeema__127_0_0_1_3306.sql", "20200304050607__skeema__127_0_0_1_3307.sql"},
+		"schema":   {"20200304050607__skeema__127_0_0_1_3306__analytics.sql", "20200304050607__skeema__127_0_0_1_3306__product.sql", "20200304050607__skeema__127_0_0_1_3307__product.sql"},
+	}
+	for split, expected := range cases {
+		dirPath := filepath.Join("testdata/.scratch", split)
+		fs.MakeTestDirectory(t, dirPath)
+		mf := NewMigrationFile("sql", split)
+		for _, target := range targets {
+			mf.Add(target, &DDLStatement{stmt: "DROP TABLE foo"})
+		}
+		paths, err := mf.Write(dirPath, "skeema", now)
+		if err != nil {
+			t.Fatalf("Unexpected error from Write with split=%s: %s", split, err)
+		} else if len(paths) != len(expected) {
+			t.Fatalf("Expected Write with split=%s to return %d paths, instead found %v", split, len(expected), paths)
+		}
+		var combined string
+		for n, path := range paths {
+			if path != filepath.Join(dirPath, expected[n]) {
+				t.Errorf("Unexpected path returned from Write with split=%s: %s", split, path)
+			}
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error reading back %s: %s", path, err)
+			}
+			combined += string(contents)
+		}
+		if split == "none" && combined != mf.Content(now) {
+			t.Errorf("Unexpected contents written with split=none: %q", combined)
+		}
+
+		// If any file already exists, no files should be written
+		if split == "none" {
+			continue
+		}
+		if err := os.Remove(paths[0]); err != nil {
+			t.Fatalf("Unexpected error from Remove: %s", err)
+		}
+		if _, err := mf.Write(dirPath, "skeema", now); err == nil {
+			t.Errorf("Expected error from Write with split=%s when some files already exist, but err was nil", split)
+		} else if _, err := os.Stat(paths[0]); err == nil {
+			t.Errorf("Expected Write with split=%s to not write any files when some already exist", split)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package applier
+
+import (
+	"sync"
+
+	"github.com/skeema/tengo"
+)
+
+// Normalizer permits programs embedding this package to adjust both sides of a
+// diff before it is computed, for example to ignore particular table options
+// or strip certain comments. Normalize receives the schema introspected from
+// the target's instance, which is nil if the schema does not exist yet, and
+// the desired schema from the target's dir.
+//
+// Normalizers run after all of the applier's built-in normalization of tables
+// and routines (such as partitioning=remove, partial targets, reorder-columns,
+// and tablespace handling), immediately before tengo diffs the two schemas.
+// They only affect that diff of tables, routines, and schema-level options.
+// Events, sequences, views, triggers, and manage-data rows are diffed earlier
+// and separately, so changes made by a normalizer have no effect on them.
+//
+// The desired schema's tables and routines, as well as the slices containing
+// them, may be shared by other targets; a normalizer should assign a new slice
+// containing copies of any objects it modifies, rather than modifying anything
+// in-place. Returning a non-nil error causes the target to be skipped.
+type Normalizer interface {
+	Normalize(schemaFromInstance, schemaFromDir *tengo.Schema, t *Target) error
+}
+
+// NormalizerFunc adapts an ordinary function into a Normalizer.
+type NormalizerFunc func(schemaFromInstance, schemaFromDir *tengo.Schema, t *Target) error
+
+// Normalize calls nf.
+func (nf NormalizerFunc) Normalize(schemaFromInstance, schemaFromDir *tengo.Schema, t *Target) error {
+	return nf(schemaFromInstance, schemaFromDir, t)
+}
+
+var normalizers struct {
+	sync.Mutex
+	list []Normalizer
+}
+
+// RegisterNormalizer adds n to a package-level list of Normalizers, which are
+// run in registration order for every target. Supplying nil has no effect. By
+// default no normalizers are registered, and the applier's behavior is
+// unchanged.
+func RegisterNormalizer(n Normalizer) {
+	if n == nil {
+		return
+	}
+	normalizers.Lock()
+	defer normalizers.Unlock()
+	normalizers.list = append(normalizers.list, n)
+}
+
+// runNormalizers calls each registered Normalizer in order, stopping at the
+// first one to return an error.
+func runNormalizers(schemaFromInstance, schemaFromDir *tengo.Schema, t *Target) error {
+	normalizers.Lock()
+	list := normalizers.list
+	normalizers.Unlock()
+	for _, n := range list {
+		if err := n.Normalize(schemaFromInstance, schemaFromDir, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
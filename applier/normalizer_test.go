@@ -0,0 +1,97 @@
+package applier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+// stripTableComments is a sample Normalizer which ignores differences in
+// table comments, by copying each instance table's comment onto the
+// corresponding dir table.
+func stripTableComments(schemaFromInstance, schemaFromDir *tengo.Schema, t *Target) error {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if instTable, ok := instTables[table.Name]; ok && instTable.Comment != table.Comment {
+			tableCopy := *table
+			tableCopy.Comment = instTable.Comment
+			tableCopy.CreateStatement = tableCopy.GeneratedCreateStatement(tengo.FlavorUnknown)
+			dirTables[n] = &tableCopy
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	return nil
+}
+
+func TestRunNormalizers(t *testing.T) {
+	defer func(orig []Normalizer) {
+		normalizers.list = orig
+	}(normalizers.list)
+	normalizers.list = nil
+
+	makeTable := func(comment string) *tengo.Table {
+		table := &tengo.Table{
+			Name:    "foo",
+			Engine:  "InnoDB",
+			CharSet: "latin1",
+			Comment: comment,
+			Columns: []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	shared := makeTable("new comment")
+	sharedTables := []*tengo.Table{shared}
+	instSchema := &tengo.Schema{Name: "bar", Tables: []*tengo.Table{makeTable("old comment")}}
+	dirSchema := &tengo.Schema{Name: "bar", Tables: sharedTables}
+
+	// With nothing registered, or only nil registered, schemas are unchanged
+	RegisterNormalizer(nil)
+	if err := runNormalizers(instSchema, dirSchema, nil); err != nil {
+		t.Errorf("Unexpected error from runNormalizers: %s", err)
+	}
+	if len(tengo.NewSchemaDiff(instSchema, dirSchema).ObjectDiffs()) != 1 {
+		t.Fatal("Expected table comment difference to be present without any normalizers")
+	}
+
+	// Normalizers run in registration order, and the first error stops further
+	// normalizers from running
+	var calls []string
+	RegisterNormalizer(NormalizerFunc(stripTableComments))
+	RegisterNormalizer(NormalizerFunc(func(_, _ *tengo.Schema, _ *Target) error {
+		calls = append(calls, "second")
+		return errors.New("boom")
+	}))
+	RegisterNormalizer(NormalizerFunc(func(_, _ *tengo.Schema, _ *Target) error {
+		calls = append(calls, "third")
+		return nil
+	}))
+	if err := runNormalizers(instSchema, dirSchema, nil); err == nil || err.Error() != "boom" {
+		t.Errorf("Expected error from second normalizer, instead found %v", err)
+	}
+	if strings.Join(calls, ",") != "second" {
+		t.Errorf("Unexpected normalizer calls: %v", calls)
+	}
+
+	// The sample normalizer should have eliminated the difference, without
+	// modifying the original table or slice, which may be shared by other targets
+	if diffs := tengo.NewSchemaDiff(instSchema, dirSchema).ObjectDiffs(); len(diffs) != 0 {
+		t.Errorf("Expected no differences after normalization, instead found %d", len(diffs))
+	}
+	if shared.Comment != "new comment" || dirSchema.Tables[0] == shared || sharedTables[0] != shared {
+		t.Error("Expected normalizer to replace the table with a modified copy")
+	}
+
+	// A nil instance schema, as seen when the schema does not exist yet, must be
+	// handled by the normalizer
+	if err := stripTableComments(nil, dirSchema, nil); err != nil {
+		t.Errorf("Unexpected error from sample normalizer with nil instance schema: %s", err)
+	}
+}
@@ -0,0 +1,80 @@
+package applier
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// newlyNotNullColumns returns the columns of an ALTER TABLE diff which are
+// nullable in the table's current version, but NOT NULL in the desired
+// version. The returned columns are from the desired version of the table.
+// Generated columns are excluded, since their values cannot be backfilled and
+// are computed by the server anyway.
+func newlyNotNullColumns(diff tengo.ObjectDiff) []*tengo.Column {
+	td := unwrapTableDiff(diff)
+	if td == nil || td.Type != tengo.DiffTypeAlter {
+		return nil
+	}
+	fromColumns := td.From.ColumnsByName()
+	var result []*tengo.Column
+	for _, col := range td.To.Columns {
+		if fromCol, ok := fromColumns[col.Name]; ok && fromCol.Nullable && !col.Nullable && col.GenerationExpr == "" && fromCol.GenerationExpr == "" {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+// nullBackfills checks whether any columns being changed to NOT NULL by diff
+// currently contain NULL values in the target's table. Such a change is
+// considered unsafe: depending on sql_mode it will either fail, or silently
+// convert the NULLs to the column type's implicit default. If backfill-nulls
+// is enabled and the column has a default value, an UPDATE statement is
+// returned for each such column, which must be run prior to the ALTER TABLE.
+// Otherwise, an error is returned unless mods permit unsafe operations.
+func nullBackfills(diff tengo.ObjectDiff, mods tengo.StatementModifiers, target *Target) (backfills []*DDLStatement, err error) {
+	columns := newlyNotNullColumns(diff)
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	db, err := target.Instance.Connect(target.SchemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	tableName := diff.ObjectKey().Name
+	for _, col := range columns {
+		var nullCount int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", tengo.EscapeIdentifier(tableName), tengo.EscapeIdentifier(col.Name))
+		if err := db.QueryRow(query).Scan(&nullCount); err != nil {
+			return nil, fmt.Errorf("Unable to check for NULL values in column %s of %s: %s", col.Name, diff.ObjectKey(), err)
+		} else if nullCount == 0 {
+			continue
+		}
+		hasDefault := col.Default != "" && col.Default != "NULL"
+		if target.Dir.Config.GetBool("backfill-nulls") && hasDefault {
+			log.Infof("Column %s of %s has %s; these will be backfilled with the column's default value prior to making it NOT NULL", col.Name, diff.ObjectKey(), countAndNoun(int(nullCount), "row with a NULL value", "rows with NULL values"))
+			backfills = append(backfills, &DDLStatement{
+				stmt:          fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", tengo.EscapeIdentifier(tableName), tengo.EscapeIdentifier(col.Name), col.Default, tengo.EscapeIdentifier(col.Name)),
+				instance:      target.Instance,
+				schemaName:    target.SchemaName,
+				connectParams: "readTimeout=0",
+			})
+		} else if mods.AllowUnsafe {
+			log.Warnf("Column %s of %s has %s; changing it to NOT NULL will fail in strict mode, or otherwise convert these values to the column type's implicit default", col.Name, diff.ObjectKey(), countAndNoun(int(nullCount), "row with a NULL value", "rows with NULL values"))
+		} else {
+			var suggestion string
+			if hasDefault {
+				suggestion = "Use --backfill-nulls to first update these rows to the column's default value, or use --allow-unsafe or --safe-below-size to permit this operation"
+			} else {
+				suggestion = "Add a default value to the column to permit use of --backfill-nulls, or use --allow-unsafe or --safe-below-size to permit this operation"
+			}
+			// Intentionally avoiding fmt.Errorf here to avoid golint complaining about capitalization
+			errorText := fmt.Sprintf("Changing column %s of %s to NOT NULL is considered unsafe, since %s. %s; see --help for more information.", col.Name, diff.ObjectKey(), countAndNoun(int(nullCount), "row has a NULL value", "rows have NULL values"), suggestion)
+			return nil, errors.New(errorText)
+		}
+	}
+	return backfills, nil
+}
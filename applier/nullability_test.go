@@ -0,0 +1,117 @@
+package applier
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func (s ApplierIntegrationSuite) TestNullBackfills(t *testing.T) {
+	if _, err := s.d[0].SourceSQL(filepath.Join("testdata", "setup.sql")); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	db, err := s.d[0].Connect("analytics", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	db.MustExec("CREATE TABLE nulltest (id int unsigned NOT NULL PRIMARY KEY, filled varchar(20), sparse varchar(20), nodefault varchar(20))")
+	db.MustExec("INSERT INTO nulltest (id, filled, sparse, nodefault) VALUES (1, 'a', 'b', 'c'), (2, 'd', NULL, NULL), (3, 'e', NULL, NULL)")
+	instSchema, err := s.d[0].Schema("analytics")
+	if err != nil {
+		t.Fatalf("Unable to obtain schema: %s", err)
+	}
+	fromTable := instSchema.Table("nulltest")
+
+	// getDiff returns an ALTER TABLE diff which changes the specified column to
+	// NOT NULL DEFAULT 'x', or to NOT NULL without a default
+	getDiff := func(colName string, withDefault bool) tengo.ObjectDiff {
+		t.Helper()
+		toTable := *fromTable
+		toTable.Columns = make([]*tengo.Column, len(fromTable.Columns))
+		for n, col := range fromTable.Columns {
+			colCopy := *col
+			if col.Name == colName {
+				colCopy.Nullable = false
+				colCopy.Default = ""
+				if withDefault {
+					colCopy.Default = "'x'"
+				}
+			}
+			toTable.Columns[n] = &colCopy
+		}
+		toTable.CreateStatement = toTable.GeneratedCreateStatement(s.d[0].Flavor())
+		toSchema := *instSchema
+		toSchema.Tables = []*tengo.Table{&toTable}
+		for _, table := range instSchema.Tables {
+			if table.Name != "nulltest" {
+				toSchema.Tables = append(toSchema.Tables, table)
+			}
+		}
+		diffs := tengo.NewSchemaDiff(instSchema, &toSchema).ObjectDiffs()
+		if len(diffs) != 1 {
+			t.Fatalf("Expected 1 diff, instead found %d", len(diffs))
+		}
+		return diffs[0]
+	}
+	getTarget := func(backfill bool) *Target {
+		configMap := map[string]string{"backfill-nulls": "0"}
+		if backfill {
+			configMap["backfill-nulls"] = "1"
+		}
+		return &Target{
+			Instance:      s.d[0].Instance,
+			Dir:           &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(configMap)},
+			SchemaName:    "analytics",
+			DesiredSchema: &workspace.Schema{Schema: instSchema},
+		}
+	}
+
+	// Column without any NULLs: always safe, no backfill needed
+	for _, backfill := range []bool{false, true} {
+		if backfills, err := nullBackfills(getDiff("filled", true), tengo.StatementModifiers{}, getTarget(backfill)); len(backfills) > 0 || err != nil {
+			t.Errorf("Unexpected return from nullBackfills for column without NULLs: %v, %v", backfills, err)
+		}
+	}
+
+	// Column with NULLs: unsafe unless backfill enabled or unsafe permitted
+	diff := getDiff("sparse", true)
+	if _, err := nullBackfills(diff, tengo.StatementModifiers{}, getTarget(false)); err == nil || !strings.Contains(err.Error(), "2 rows have NULL values") {
+		t.Errorf("Expected nullBackfills to return unsafe error, instead found %v", err)
+	}
+	if backfills, err := nullBackfills(diff, tengo.StatementModifiers{AllowUnsafe: true}, getTarget(false)); len(backfills) > 0 || err != nil {
+		t.Errorf("Unexpected return from nullBackfills with AllowUnsafe: %v, %v", backfills, err)
+	}
+	backfills, err := nullBackfills(diff, tengo.StatementModifiers{}, getTarget(true))
+	if err != nil || len(backfills) != 1 {
+		t.Fatalf("Unexpected return from nullBackfills with backfill-nulls: %v, %v", backfills, err)
+	}
+	expected := "UPDATE `nulltest` SET `sparse` = 'x' WHERE `sparse` IS NULL"
+	if backfills[0].stmt != expected {
+		t.Errorf("Unexpected backfill statement: expected %q, found %q", expected, backfills[0].stmt)
+	}
+
+	// Column with NULLs but no default cannot be backfilled
+	if _, err := nullBackfills(getDiff("nodefault", false), tengo.StatementModifiers{}, getTarget(true)); err == nil {
+		t.Error("Expected nullBackfills to return an error for column lacking a default, but it did not")
+	}
+
+	// Executing the backfill should permit the ALTER to succeed in strict mode
+	if err := backfills[0].Execute(); err != nil {
+		t.Fatalf("Unexpected error executing backfill: %s", err)
+	}
+	alter, err := diff.Statement(tengo.StatementModifiers{})
+	if err != nil {
+		t.Fatalf("Unexpected error from Statement: %s", err)
+	}
+	if _, err := db.Exec(alter); err != nil {
+		t.Errorf("Unexpected error executing ALTER after backfill: %s", err)
+	}
+	if backfills, err := nullBackfills(getDiff("nodefault", true), tengo.StatementModifiers{}, getTarget(false)); len(backfills) > 0 || err == nil {
+		t.Errorf("Expected other column to still have NULLs, instead found %v, %v", backfills, err)
+	}
+}
@@ -0,0 +1,130 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// oscToolBinaries maps each supported value of the osc-tool option to the name
+// of the executable which is run, which must be on the PATH.
+var oscToolBinaries = map[string]string{
+	"gh-ost": "gh-ost",
+	"pt-osc": "pt-online-schema-change",
+}
+
+// getOSCTool returns the value of the osc-tool option, or "none" if it is not
+// in use. An error is returned if the value is invalid, or if alter-wrapper is
+// also set, since both options control how ALTER TABLEs are executed.
+func getOSCTool(config *mybase.Config) (string, error) {
+	tool, err := config.GetEnum("osc-tool", "none", "gh-ost", "pt-osc")
+	if err != nil {
+		return "", ConfigError(err.Error())
+	} else if tool != "none" && config.Changed("alter-wrapper") {
+		return "", ConfigError("Options osc-tool and alter-wrapper cannot be used together")
+	}
+	return tool, nil
+}
+
+// oscToolCommand returns a command-line for running an ALTER TABLE through the
+// supplied online schema change tool, built from the osc-* options. The result
+// is suitable for use as an alter-wrapper: its variable placeholders have NOT
+// been interpolated yet. If socket is true, the target instance is reached via
+// a socket file rather than a host and port.
+func oscToolCommand(config *mybase.Config, tool string, socket bool) (string, error) {
+	chunkSize, err := config.GetInt("osc-chunk-size")
+	if err != nil || chunkSize < 0 {
+		return "", ConfigError(fmt.Sprintf("Option osc-chunk-size must be a non-negative integer; found %q", config.Get("osc-chunk-size")))
+	}
+	maxLag, err := config.GetInt("osc-max-lag")
+	if err != nil || maxLag < 0 {
+		return "", ConfigError(fmt.Sprintf("Option osc-max-lag must be a non-negative integer; found %q", config.Get("osc-max-lag")))
+	}
+	cutOver, err := config.GetEnum("osc-cut-over", "atomic", "two-step")
+	if err != nil {
+		return "", ConfigError(err.Error())
+	}
+
+	args := []string{oscToolBinaries[tool]}
+	switch tool {
+	case "gh-ost":
+		if socket {
+			return "", ConfigError("osc-tool=gh-ost cannot connect to an instance via a socket file")
+		}
+		args = append(args, "--host={HOST}", "--port={PORT}", "--user={USER}", "--password={PASSWORDX}", "--database={SCHEMA}", "--table={TABLE}", "--alter={CLAUSES}", "--allow-on-master", "--cut-over="+cutOver)
+		if chunkSize > 0 {
+			args = append(args, fmt.Sprintf("--chunk-size=%d", chunkSize))
+		}
+		if maxLag > 0 {
+			args = append(args, fmt.Sprintf("--max-lag-millis=%d", maxLag*1000))
+		}
+	case "pt-osc":
+		// pt-online-schema-change always swaps tables with an atomic RENAME TABLE
+		if cutOver != "atomic" {
+			return "", ConfigError(fmt.Sprintf("osc-cut-over=%s is only supported with osc-tool=gh-ost", cutOver))
+		}
+		args = append(args, "--alter={CLAUSES}")
+		if chunkSize > 0 {
+			args = append(args, fmt.Sprintf("--chunk-size=%d", chunkSize))
+		}
+		if maxLag > 0 {
+			args = append(args, fmt.Sprintf("--max-lag=%ds", maxLag))
+		}
+	}
+	args = append(args, "--execute")
+	if extra := config.Get("osc-args"); extra != "" {
+		args = append(args, extra)
+	}
+	if tool == "pt-osc" {
+		// DSN must come last
+		if socket {
+			args = append(args, "D={SCHEMA},t={TABLE},S={SOCKET},u={USER},p={PASSWORDX}")
+		} else {
+			args = append(args, "D={SCHEMA},t={TABLE},h={HOST},P={PORT},u={USER},p={PASSWORDX}")
+		}
+	}
+	return strings.Join(args, " "), nil
+}
+
+// oscIneligibleReason returns a description of why table cannot be altered by
+// the supplied online schema change tool, or an empty string if it can. Both
+// tools require a primary key or unique index, so that rows can be tracked
+// while the table is copied. gh-ost additionally does not support foreign keys
+// on either side of a relationship, nor triggers; schema is examined to detect
+// these.
+func oscIneligibleReason(tool string, table *tengo.Table, schema *workspace.Schema) string {
+	hasUnique := (table.PrimaryKey != nil)
+	for _, idx := range table.SecondaryIndexes {
+		hasUnique = hasUnique || idx.Unique
+	}
+	if !hasUnique {
+		return "the table has no primary key or unique index"
+	}
+	if tool != "gh-ost" {
+		return ""
+	}
+	if len(table.ForeignKeys) > 0 {
+		return "gh-ost does not support tables with foreign keys"
+	}
+	if schema == nil {
+		return ""
+	}
+	if schema.Schema != nil {
+		for _, other := range schema.Tables {
+			for _, fk := range other.ForeignKeys {
+				if fk.ReferencedTableName == table.Name && fk.ReferencedSchemaName == "" {
+					return fmt.Sprintf("gh-ost does not support tables referenced by foreign keys, such as %s of %s", tengo.EscapeIdentifier(fk.Name), tengo.EscapeIdentifier(other.Name))
+				}
+			}
+		}
+	}
+	for _, trigger := range schema.Triggers {
+		if trigger.Table == table.Name {
+			return fmt.Sprintf("gh-ost does not support tables with triggers, such as %s", tengo.EscapeIdentifier(trigger.Name))
+		}
+	}
+	return ""
+}
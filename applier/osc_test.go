@@ -0,0 +1,145 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestGetOSCTool(t *testing.T) {
+	if tool, err := getOSCTool(getBaseConfig(t, "")); tool != "none" || err != nil {
+		t.Errorf("Unexpected return from getOSCTool: %q / %v", tool, err)
+	}
+	if tool, err := getOSCTool(getBaseConfig(t, "--osc-tool=GH-OST")); tool != "gh-ost" || err != nil {
+		t.Errorf("Unexpected return from getOSCTool: %q / %v", tool, err)
+	}
+	for _, badFlags := range []string{"--osc-tool=lhm", "--osc-tool=pt-osc --alter-wrapper=/bin/echo"} {
+		if _, err := getOSCTool(getBaseConfig(t, badFlags)); err == nil {
+			t.Errorf("Expected error from getOSCTool with %s, but err was nil", badFlags)
+		}
+	}
+}
+
+func TestOSCToolCommand(t *testing.T) {
+	cases := []struct {
+		flags    string
+		tool     string
+		socket   bool
+		expected string
+	}{
+		{"", "gh-ost", false, "gh-ost --host={HOST} --port={PORT} --user={USER} --password={PASSWORDX} --database={SCHEMA} --table={TABLE} --alter={CLAUSES} --allow-on-master --cut-over=atomic --execute"},
+		{"--osc-chunk-size=500 --osc-max-lag=2 --osc-cut-over=two-step --osc-args='--verbose'", "gh-ost", false, "gh-ost --host={HOST} --port={PORT} --user={USER} --password={PASSWORDX} --database={SCHEMA} --table={TABLE} --alter={CLAUSES} --allow-on-master --cut-over=two-step --chunk-size=500 --max-lag-millis=2000 --execute --verbose"},
+		{"", "pt-osc", false, "pt-online-schema-change --alter={CLAUSES} --execute D={SCHEMA},t={TABLE},h={HOST},P={PORT},u={USER},p={PASSWORDX}"},
+		{"--osc-chunk-size=500 --osc-max-lag=2 --osc-args='--recursion-method=none'", "pt-osc", true, "pt-online-schema-change --alter={CLAUSES} --chunk-size=500 --max-lag=2s --execute --recursion-method=none D={SCHEMA},t={TABLE},S={SOCKET},u={USER},p={PASSWORDX}"},
+	}
+	for _, c := range cases {
+		if actual, err := oscToolCommand(getBaseConfig(t, c.flags), c.tool, c.socket); err != nil {
+			t.Errorf("Unexpected error from oscToolCommand for %+v: %v", c, err)
+		} else if actual != c.expected {
+			t.Errorf("Unexpected result from oscToolCommand for %+v:\nexpected %s\nfound    %s", c, c.expected, actual)
+		}
+	}
+
+	badCases := []struct {
+		flags  string
+		tool   string
+		socket bool
+	}{
+		{"", "gh-ost", true},
+		{"--osc-cut-over=two-step", "pt-osc", false},
+		{"--osc-cut-over=invalid", "gh-ost", false},
+		{"--osc-chunk-size=-1", "gh-ost", false},
+		{"--osc-max-lag=abc", "pt-osc", false},
+	}
+	for _, c := range badCases {
+		if _, err := oscToolCommand(getBaseConfig(t, c.flags), c.tool, c.socket); err == nil {
+			t.Errorf("Expected error from oscToolCommand for %+v, but err was nil", c)
+		}
+	}
+}
+
+func TestOSCIneligibleReason(t *testing.T) {
+	pk := &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true}
+	widgets := &tengo.Table{Name: "widgets", PrimaryKey: pk}
+	schema := &workspace.Schema{Schema: &tengo.Schema{Tables: []*tengo.Table{widgets}}}
+	for _, tool := range []string{"gh-ost", "pt-osc"} {
+		if reason := oscIneligibleReason(tool, widgets, schema); reason != "" {
+			t.Errorf("Expected %s to be eligible for %s, instead found reason %q", widgets.Name, tool, reason)
+		}
+	}
+
+	// Unique secondary index suffices in place of a primary key
+	uniq := &tengo.Table{Name: "uniq", SecondaryIndexes: []*tengo.Index{{Name: "name", Unique: true}}}
+	if reason := oscIneligibleReason("gh-ost", uniq, schema); reason != "" {
+		t.Errorf("Expected %s to be eligible, instead found reason %q", uniq.Name, reason)
+	}
+	nokey := &tengo.Table{Name: "nokey", SecondaryIndexes: []*tengo.Index{{Name: "name"}}}
+	if reason := oscIneligibleReason("pt-osc", nokey, schema); !strings.Contains(reason, "no primary key") {
+		t.Errorf("Unexpected reason for %s: %q", nokey.Name, reason)
+	}
+
+	// Foreign keys and triggers are only a problem for gh-ost
+	child := &tengo.Table{
+		Name:        "parts",
+		PrimaryKey:  pk,
+		ForeignKeys: []*tengo.ForeignKey{{Name: "widget_fk", ReferencedTableName: "widgets"}},
+	}
+	schema.Tables = append(schema.Tables, child)
+	if reason := oscIneligibleReason("gh-ost", child, schema); !strings.Contains(reason, "foreign keys") {
+		t.Errorf("Unexpected reason for %s: %q", child.Name, reason)
+	}
+	if reason := oscIneligibleReason("gh-ost", widgets, schema); !strings.Contains(reason, "widget_fk") {
+		t.Errorf("Unexpected reason for %s: %q", widgets.Name, reason)
+	}
+	if reason := oscIneligibleReason("pt-osc", widgets, schema); reason != "" {
+		t.Errorf("Expected %s to be eligible for pt-osc, instead found reason %q", widgets.Name, reason)
+	}
+	schema.Tables = schema.Tables[0:1]
+	schema.Triggers = []*workspace.Trigger{{Name: "widgets_bi", Table: "widgets"}}
+	if reason := oscIneligibleReason("gh-ost", widgets, schema); !strings.Contains(reason, "widgets_bi") {
+		t.Errorf("Unexpected reason for %s: %q", widgets.Name, reason)
+	}
+}
+
+func TestGetWrapperOSCTool(t *testing.T) {
+	table := &tengo.Table{Name: "widgets", PrimaryKey: &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true}}
+	diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: table, To: table}
+	target := &Target{
+		Instance:      &tengo.Instance{Host: "localhost", Port: 3306},
+		Dir:           getDir(t, "testdata/simple", "--osc-tool=gh-ost --alter-wrapper-min-size=1k --ddl-wrapper=/bin/echo"),
+		DesiredSchema: &workspace.Schema{Schema: &tengo.Schema{Tables: []*tengo.Table{table}}},
+	}
+
+	// ALTERs which rebuild the table use osc-tool, with ALGORITHM and LOCK
+	// clauses omitted
+	mods := tengo.StatementModifiers{AlgorithmClause: "inplace", LockClause: "none"}
+	wrapper, tool, err := getWrapper(target, diff, AlterAlgorithmRebuild, 2048, &mods)
+	if err != nil || tool != "gh-ost" || !strings.HasPrefix(wrapper, "gh-ost ") {
+		t.Errorf("Unexpected return from getWrapper: %q / %q / %v", wrapper, tool, err)
+	} else if mods.AlgorithmClause != "" || mods.LockClause != "" {
+		t.Errorf("Expected ALGORITHM and LOCK clauses to be cleared, instead found %+v", mods)
+	}
+
+	// In-place ALTERs and small tables skip osc-tool, but still use ddl-wrapper
+	for _, c := range []struct {
+		algo      AlterAlgorithm
+		tableSize int64
+	}{
+		{AlterAlgorithmInPlace, 2048},
+		{AlterAlgorithmCopy, 100},
+	} {
+		mods := tengo.StatementModifiers{AlgorithmClause: "inplace"}
+		if wrapper, tool, err := getWrapper(target, diff, c.algo, c.tableSize, &mods); err != nil || tool != "" || wrapper != "/bin/echo" || mods.AlgorithmClause != "inplace" {
+			t.Errorf("Unexpected return from getWrapper for %+v: %q / %q / %v", c, wrapper, tool, err)
+		}
+	}
+
+	// Ineligible tables result in an error, rather than silently running the
+	// ALTER directly
+	target.DesiredSchema.Triggers = []*workspace.Trigger{{Name: "widgets_bi", Table: "widgets"}}
+	if _, _, err := getWrapper(target, diff, AlterAlgorithmCopy, 2048, &mods); err == nil {
+		t.Error("Expected error from getWrapper for ineligible table, but err was nil")
+	}
+}
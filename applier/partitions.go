@@ -0,0 +1,229 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// partitionListDiff represents an ALTER TABLE which changes the partition list
+// of a table, without changing its partitioning method or expression. tengo
+// does not generate DDL for such changes, so these diffs are computed
+// separately. It satisfies the tengo.ObjectDiff interface. The server only
+// permits one partition management operation per ALTER TABLE, so each
+// partitionListDiff represents exactly one operation.
+type partitionListDiff struct {
+	table     *tengo.Table
+	method    string             // partitioning method of the table
+	drop      []*tengo.Partition // partitions to drop, or to reorganize if add is also non-empty
+	add       []*tengo.Partition // partitions to add, or the result of reorganizing
+	count     int                // for HASH or KEY partitioning, the number of partitions to add (positive) or coalesce (negative)
+	allowDrop bool               // if true, permit DROP PARTITION even if mods do not permit unsafe operations
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (pld *partitionListDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// altered.
+func (pld *partitionListDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: pld.table.Name}
+}
+
+// Statement returns the full ALTER TABLE statement. A non-nil error will be
+// returned if the statement drops partitions, and neither mods nor the
+// allow-drop-partition option permit it.
+func (pld *partitionListDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(pld.table.Name) {
+		return "", nil
+	}
+	clauses, _ := pld.Clauses(mods)
+	stmt := fmt.Sprintf("%s %s", pld.table.AlterStatement(), clauses)
+	var err error
+	if pld.unsafe() && !mods.AllowUnsafe && !pld.allowDrop {
+		err = &tengo.ForbiddenDiffError{
+			Reason:    "DROP PARTITION not permitted; use allow-drop-partition or allow-unsafe",
+			Statement: stmt,
+		}
+	}
+	return stmt, err
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ". ALGORITHM and LOCK clauses are not included, since
+// the server does not permit them in combination with partition management.
+func (pld *partitionListDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	definitions := func(partitions []*tengo.Partition) string {
+		defs := make([]string, len(partitions))
+		for n, p := range partitions {
+			defs[n] = p.Definition(mods.Flavor, pld.method)
+		}
+		return strings.Join(defs, ",\n ")
+	}
+	switch {
+	case pld.count > 0:
+		return fmt.Sprintf("ADD PARTITION PARTITIONS %d", pld.count), nil
+	case pld.count < 0:
+		return fmt.Sprintf("COALESCE PARTITION %d", -pld.count), nil
+	case len(pld.add) == 0:
+		return fmt.Sprintf("DROP PARTITION %s", partitionNames(pld.drop)), nil
+	case len(pld.drop) == 0:
+		return fmt.Sprintf("ADD PARTITION (%s)", definitions(pld.add)), nil
+	default:
+		return fmt.Sprintf("REORGANIZE PARTITION %s INTO (%s)", partitionNames(pld.drop), definitions(pld.add)), nil
+	}
+}
+
+// unsafe returns true if the diff drops partitions, destroying their data.
+// Reorganizing and coalescing partitions moves their data into other
+// partitions, so these are not considered unsafe; the server returns an error
+// if any rows would not fit the new partition definitions.
+func (pld *partitionListDiff) unsafe() bool {
+	return pld.count == 0 && len(pld.add) == 0
+}
+
+// rebuildReason returns a human-readable description of the partition
+// operation, if it copies data between partitions, or an empty string
+// otherwise.
+func (pld *partitionListDiff) rebuildReason() string {
+	if pld.count != 0 {
+		return fmt.Sprintf("rows are redistributed among %s partitions", strings.ToUpper(pld.method))
+	} else if len(pld.add) > 0 && len(pld.drop) > 0 {
+		return fmt.Sprintf("partitions %s are reorganized", partitionNames(pld.drop))
+	}
+	return ""
+}
+
+// partitionNames returns a comma-separated list of the names of partitions.
+func partitionNames(partitions []*tengo.Partition) string {
+	names := make([]string, len(partitions))
+	for n, p := range partitions {
+		names[n] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// diffPartitionLists returns partitionListDiffs transforming the partition list
+// of from into that of to, which must have the same partitioning method and
+// expression. For RANGE and LIST partitioning, partitions which only exist in
+// from are dropped; partitions added after all existing ones are added; and
+// any other changes reorganize the smallest contiguous span of partitions
+// which differs. For HASH and KEY partitioning, only changes to the number of
+// partitions are supported, and only if the partitions have default names. If
+// the changes are not supported, nil is returned.
+func diffPartitionLists(table *tengo.Table, from, to *tengo.TablePartitioning, allowDrop bool) []*partitionListDiff {
+	if !strings.HasPrefix(from.Method, "RANGE") && !strings.HasPrefix(from.Method, "LIST") {
+		for _, tp := range []*tengo.TablePartitioning{from, to} {
+			for n, p := range tp.Partitions {
+				if p.Name != fmt.Sprintf("p%d", n) || p.Comment != "" || p.DataDir != "" {
+					return nil
+				}
+			}
+		}
+		if count := len(to.Partitions) - len(from.Partitions); count != 0 {
+			return []*partitionListDiff{{table: table, method: from.Method, count: count}}
+		}
+		return nil
+	}
+
+	var diffs []*partitionListDiff
+	toNames := make(map[string]bool, len(to.Partitions))
+	for _, p := range to.Partitions {
+		toNames[p.Name] = true
+	}
+	var dropped, remaining []*tengo.Partition
+	for _, p := range from.Partitions {
+		if toNames[p.Name] {
+			remaining = append(remaining, p)
+		} else {
+			dropped = append(dropped, p)
+		}
+	}
+	if len(remaining) == 0 {
+		// The server does not permit dropping all partitions of a table
+		return []*partitionListDiff{{table: table, method: from.Method, drop: from.Partitions, add: to.Partitions}}
+	} else if len(dropped) > 0 {
+		diffs = append(diffs, &partitionListDiff{table: table, method: from.Method, drop: dropped, allowDrop: allowDrop})
+	}
+
+	// Find the span of partitions which differs, after excluding a common prefix
+	// and suffix
+	var prefix, suffix int
+	for prefix < len(remaining) && prefix < len(to.Partitions) && *remaining[prefix] == *to.Partitions[prefix] {
+		prefix++
+	}
+	for suffix < len(remaining)-prefix && suffix < len(to.Partitions)-prefix && *remaining[len(remaining)-1-suffix] == *to.Partitions[len(to.Partitions)-1-suffix] {
+		suffix++
+	}
+	fromSpan := remaining[prefix : len(remaining)-suffix]
+	toSpan := to.Partitions[prefix : len(to.Partitions)-suffix]
+	if len(toSpan) == 0 {
+		return diffs // only drops, if anything
+	}
+	if len(fromSpan) == 0 && suffix > 0 {
+		// New partitions can only be added after all existing ones, so instead
+		// reorganize the next existing partition into the new ones plus itself
+		fromSpan = remaining[prefix : prefix+1]
+		toSpan = to.Partitions[prefix : len(to.Partitions)-suffix+1]
+	}
+	diffs = append(diffs, &partitionListDiff{table: table, method: from.Method, drop: fromSpan, add: toSpan})
+	return diffs
+}
+
+// extractPartitionLists handles tables existing in both schemaFromInstance and
+// schemaFromDir which only differ in their partition list, so that tengo can
+// diff the rest of their definitions normally, even though it does not
+// generate DDL for partition list changes. For each such table, the partition
+// list of the instance table is replaced with that of the dir table, and
+// partitionListDiffs are returned which perform the change. Tables whose
+// partition list changes are not supported are left as-is, so that tengo
+// handles them as it otherwise would.
+func extractPartitionLists(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor, allowDrop bool) []tengo.ObjectDiff {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	var diffs []tengo.ObjectDiff
+	instTables := schemaFromInstance.TablesByName()
+	for _, table := range schemaFromDir.Tables {
+		instTable := instTables[table.Name]
+		if instTable == nil || instTable.UnsupportedDDL || table.UnsupportedDDL {
+			continue
+		}
+		from, to := instTable.Partitioning, table.Partitioning
+		if from == nil || to == nil || from.Method != to.Method || from.Expression != to.Expression || from.AlgoClause != to.AlgoClause || from.SubMethod != "" || to.SubMethod != "" || samePartitions(from, to) {
+			continue
+		}
+		tableDiffs := diffPartitionLists(table, from, to, allowDrop)
+		if tableDiffs == nil {
+			continue
+		}
+		fromCopy := *from
+		fromCopy.Partitions = to.Partitions
+		fromDef := from.Definition(flavor)
+		if !strings.Contains(instTable.CreateStatement, fromDef) {
+			continue
+		}
+		instTable.CreateStatement = strings.Replace(instTable.CreateStatement, fromDef, fromCopy.Definition(flavor), 1)
+		instTable.Partitioning = &fromCopy
+		for _, td := range tableDiffs {
+			diffs = append(diffs, td)
+		}
+	}
+	return diffs
+}
+
+// samePartitions returns true if a and b have identical partition lists.
+func samePartitions(a, b *tengo.TablePartitioning) bool {
+	if len(a.Partitions) != len(b.Partitions) {
+		return false
+	}
+	for n := range a.Partitions {
+		if *a.Partitions[n] != *b.Partitions[n] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestDiffPartitionLists(t *testing.T) {
+	table := &tengo.Table{Name: "events"}
+	rangePartitions := func(uppers ...string) *tengo.TablePartitioning {
+		tp := &tengo.TablePartitioning{Method: "RANGE", Expression: "`id`"}
+		for _, upper := range uppers {
+			name := "pmax"
+			if upper != "MAXVALUE" {
+				name = "p" + upper
+			}
+			tp.Partitions = append(tp.Partitions, &tengo.Partition{Name: name, Values: upper, Engine: "InnoDB"})
+		}
+		return tp
+	}
+	mods := tengo.StatementModifiers{Flavor: tengo.FlavorMySQL80}
+	statements := func(diffs []*partitionListDiff) string {
+		result := make([]string, len(diffs))
+		for n, diff := range diffs {
+			stmt, err := diff.Statement(mods)
+			if diff.unsafe() != tengo.IsForbiddenDiff(err) || (err != nil && !tengo.IsForbiddenDiff(err)) {
+				t.Errorf("Unexpected error from Statement(): %v", err)
+			}
+			result[n] = stmt
+		}
+		return strings.Join(result, "; ")
+	}
+
+	cases := []struct {
+		from     *tengo.TablePartitioning
+		to       *tengo.TablePartitioning
+		expected string
+	}{
+		// Rolling window: drop oldest partition, add newest
+		{rangePartitions("10", "20"), rangePartitions("20", "30"), "ALTER TABLE `events` DROP PARTITION p10; ALTER TABLE `events` ADD PARTITION (PARTITION p30 VALUES LESS THAN (30) ENGINE = InnoDB)"},
+		// New partition before MAXVALUE requires reorganizing it
+		{rangePartitions("10", "MAXVALUE"), rangePartitions("10", "20", "MAXVALUE"), "ALTER TABLE `events` REORGANIZE PARTITION pmax INTO (PARTITION p20 VALUES LESS THAN (20) ENGINE = InnoDB,\n PARTITION pmax VALUES LESS THAN MAXVALUE ENGINE = InnoDB)"},
+		// Splitting a partition in the middle
+		{rangePartitions("10", "30", "40"), rangePartitions("10", "20", "30", "40"), "ALTER TABLE `events` REORGANIZE PARTITION p30 INTO (PARTITION p20 VALUES LESS THAN (20) ENGINE = InnoDB,\n PARTITION p30 VALUES LESS THAN (30) ENGINE = InnoDB)"},
+		// Dropping all partitions is not permitted, so they are reorganized
+		{rangePartitions("10"), rangePartitions("20"), "ALTER TABLE `events` REORGANIZE PARTITION p10 INTO (PARTITION p20 VALUES LESS THAN (20) ENGINE = InnoDB)"},
+		// HASH and KEY partition counts
+		{&tengo.TablePartitioning{Method: "HASH", Expression: "`id`", Partitions: []*tengo.Partition{{Name: "p0"}, {Name: "p1"}}}, &tengo.TablePartitioning{Method: "HASH", Expression: "`id`", Partitions: []*tengo.Partition{{Name: "p0"}, {Name: "p1"}, {Name: "p2"}}}, "ALTER TABLE `events` ADD PARTITION PARTITIONS 1"},
+		{&tengo.TablePartitioning{Method: "KEY", Expression: "`id`", Partitions: []*tengo.Partition{{Name: "p0"}, {Name: "p1"}, {Name: "p2"}}}, &tengo.TablePartitioning{Method: "KEY", Expression: "`id`", Partitions: []*tengo.Partition{{Name: "p0"}}}, "ALTER TABLE `events` COALESCE PARTITION 2"},
+	}
+	for n, c := range cases {
+		if actual := statements(diffPartitionLists(table, c.from, c.to, false)); actual != c.expected {
+			t.Errorf("Unexpected result from case %d:\nexpected %s\nfound    %s", n, c.expected, actual)
+		}
+	}
+
+	// DROP PARTITION is only permitted with allow-unsafe or allow-drop-partition
+	diffs := diffPartitionLists(table, rangePartitions("10", "20"), rangePartitions("20"), false)
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 diff, instead found %d", len(diffs))
+	}
+	if _, err := diffs[0].Statement(mods); !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Expected forbidden diff error, instead found %v", err)
+	}
+	if ClassifySafety(diffs[0], mods) != SafetyDestructive {
+		t.Error("Expected DROP PARTITION to be classified as destructive")
+	}
+	if classes := ClassifyUnsafe(diffs[0], mods); len(classes) != 1 || classes[0] != UnsafeDropPartition {
+		t.Errorf("Expected DROP PARTITION to be classified as %s, instead found %v", UnsafeDropPartition, classes)
+	}
+	unsafeMods := mods
+	unsafeMods.AllowUnsafe = true
+	if _, err := diffs[0].Statement(unsafeMods); err != nil {
+		t.Errorf("Unexpected error with allow-unsafe: %v", err)
+	}
+	diffs = diffPartitionLists(table, rangePartitions("10", "20"), rangePartitions("20"), true)
+	if _, err := diffs[0].Statement(mods); err != nil {
+		t.Errorf("Unexpected error with allow-drop-partition: %v", err)
+	}
+
+	// Only reorganizing or redistributing partitions is considered a copy
+	reorg := diffPartitionLists(table, rangePartitions("10", "MAXVALUE"), rangePartitions("10", "20", "MAXVALUE"), false)[0]
+	if algo, _ := ClassifyAlter(reorg, tengo.FlavorMySQL80); algo != AlterAlgorithmCopy {
+		t.Errorf("Expected REORGANIZE PARTITION to be classified as a copy, instead found %s", algo)
+	}
+	add := diffPartitionLists(table, rangePartitions("10"), rangePartitions("10", "20"), false)[0]
+	if algo, _ := ClassifyAlter(add, tengo.FlavorMySQL80); algo != AlterAlgorithmInPlace {
+		t.Errorf("Expected ADD PARTITION to be classified as in-place, instead found %s", algo)
+	}
+
+	// HASH partitions with non-default names are not supported
+	named := &tengo.TablePartitioning{Method: "HASH", Expression: "`id`", Partitions: []*tengo.Partition{{Name: "a"}}}
+	if diffs := diffPartitionLists(table, named, &tengo.TablePartitioning{Method: "HASH", Expression: "`id`"}, false); diffs != nil {
+		t.Errorf("Expected nil result for HASH partitions with non-default names, instead found %d diffs", len(diffs))
+	}
+}
+
+func TestExtractPartitionLists(t *testing.T) {
+	flavor := tengo.FlavorMySQL80
+	makeTable := func(uppers ...int) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "events",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+			Partitioning:       &tengo.TablePartitioning{Method: "RANGE", Expression: "`id`"},
+		}
+		for _, upper := range uppers {
+			table.Partitioning.Partitions = append(table.Partitioning.Partitions, &tengo.Partition{Name: fmt.Sprintf("p%d", upper), Values: fmt.Sprintf("%d", upper), Engine: "InnoDB"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+	instSchema := &tengo.Schema{Tables: []*tengo.Table{makeTable(10, 20)}}
+	dirSchema := &tengo.Schema{Tables: []*tengo.Table{makeTable(10, 20, 30)}}
+	diffs := extractPartitionLists(instSchema, dirSchema, flavor, false)
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 diff, instead found %d", len(diffs))
+	}
+	if stmt, err := diffs[0].Statement(tengo.StatementModifiers{Flavor: flavor}); err != nil || !strings.Contains(stmt, "ADD PARTITION (PARTITION p30") {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	// The instance table's partition list should now match the dir table, so
+	// that tengo does not see any remaining difference
+	if instSchema.Tables[0].CreateStatement != dirSchema.Tables[0].CreateStatement {
+		t.Errorf("Expected instance table's CreateStatement to be updated, instead found:\n%s", instSchema.Tables[0].CreateStatement)
+	}
+	if td := tengo.NewAlterTable(instSchema.Tables[0], dirSchema.Tables[0]); td != nil {
+		t.Errorf("Expected no remaining table diff, instead found %+v", td)
+	}
+
+	// Tables only existing on one side, or with a different partitioning method,
+	// are left for tengo to handle
+	other := makeTable(10)
+	other.Partitioning.Method = "LIST"
+	other.CreateStatement = other.GeneratedCreateStatement(flavor)
+	instSchema = &tengo.Schema{Tables: []*tengo.Table{makeTable(10)}}
+	dirSchema = &tengo.Schema{Tables: []*tengo.Table{other}}
+	if diffs := extractPartitionLists(instSchema, dirSchema, flavor, false); len(diffs) != 0 {
+		t.Errorf("Expected no diffs, instead found %d", len(diffs))
+	}
+	if diffs := extractPartitionLists(nil, dirSchema, flavor, false); len(diffs) != 0 {
+		t.Errorf("Expected no diffs, instead found %d", len(diffs))
+	}
+}
@@ -0,0 +1,214 @@
+package applier
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/tengo"
+)
+
+// Plan represents the DDL generated by `skeema diff --plan`, so that it can be
+// reviewed and then executed later by `skeema apply`. For each target with
+// differences, the plan records the exact ordered statements, along with the
+// fingerprint of the target's live schema at the time the plan was made. A plan
+// may only be applied to a target whose fingerprint still matches, ensuring the
+// statements are not run against a schema which has drifted since the plan was
+// reviewed.
+//
+// Add is safe to call on a nil *Plan, in which case nothing is collected. It
+// may also be called concurrently from multiple workers.
+type Plan struct {
+	Created     string        `json:"created"`
+	Environment string        `json:"environment"`
+	Targets     []*PlanTarget `json:"targets"`
+
+	mu      sync.Mutex
+	targets map[*Target]*PlanTarget // only used while collecting statements
+}
+
+// PlanTarget represents the portion of a Plan for a single schema on a single
+// instance.
+type PlanTarget struct {
+	Dir         string          `json:"dir"`
+	Instance    string          `json:"instance"`
+	Schema      string          `json:"schema"`
+	Fingerprint string          `json:"fingerprint"`
+	Statements  []PlanStatement `json:"statements"`
+}
+
+// PlanStatement represents a single statement in a PlanTarget, along with any
+// session variables required to execute it.
+type PlanStatement struct {
+	SQL           string `json:"sql"`
+	ConnectParams string `json:"connect_params,omitempty"`
+}
+
+// NewPlan returns a pointer to a new empty Plan for the supplied environment
+// name.
+func NewPlan(environment string) *Plan {
+	return &Plan{
+		Environment: environment,
+		targets:     make(map[*Target]*PlanTarget),
+	}
+}
+
+// ReadPlan reads and returns the plan in the file at path.
+func ReadPlan(path string) (*Plan, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(contents, plan); err != nil {
+		return nil, fmt.Errorf("Plan file %s is malformed: %s", path, err)
+	}
+	return plan, nil
+}
+
+// Add records that ddl was generated for t. Statements which shell out to an
+// external command cannot be represented in a plan, so an error is returned for
+// these instead of recording them.
+func (p *Plan) Add(t *Target, ddl *DDLStatement) error {
+	if p == nil {
+		return nil
+	} else if ddl.IsShellOut() {
+		return errors.New("statements executed via alter-wrapper, ddl-wrapper, or osc-tool cannot be written to a plan file")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pt := p.targets[t]
+	if pt == nil {
+		pt = &PlanTarget{
+			Dir:      t.Dir.Path,
+			Instance: t.Instance.String(),
+			Schema:   t.SchemaName,
+		}
+		p.targets[t] = pt
+	}
+	pt.Statements = append(pt.Statements, PlanStatement{
+		SQL:           ddl.stmt,
+		ConnectParams: ddl.connectParams,
+	})
+	return nil
+}
+
+// Len returns the number of targets with statements collected so far.
+func (p *Plan) Len() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.targets)
+}
+
+// Write fingerprints the live schema of each target with collected statements,
+// and then writes the plan to path as JSON, replacing any existing file. Since
+// the plan's statements were generated without modifying the live schemas, the
+// fingerprints reflect the same state that the statements were generated from,
+// unless something else modified the schemas in the meantime.
+func (p *Plan) Write(path string, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Created = now.UTC().Format(time.RFC3339)
+	p.Targets = make([]*PlanTarget, 0, len(p.targets))
+	for t, pt := range p.targets {
+		var err error
+		if pt.Fingerprint, err = liveFingerprint(t); err != nil {
+			return fmt.Errorf("Unable to fingerprint %s %s: %s", t.Instance, t.SchemaName, err)
+		}
+		p.Targets = append(p.Targets, pt)
+	}
+	sort.Slice(p.Targets, func(i, j int) bool {
+		if p.Targets[i].Instance != p.Targets[j].Instance {
+			return p.Targets[i].Instance < p.Targets[j].Instance
+		}
+		return p.Targets[i].Schema < p.Targets[j].Schema
+	})
+	contents, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// CheckDrift returns an error if t's live schema no longer has the fingerprint
+// recorded in pt, indicating the schema was modified after the plan was made.
+func (pt *PlanTarget) CheckDrift(t *Target) error {
+	fingerprint, err := liveFingerprint(t)
+	if err != nil {
+		return fmt.Errorf("Unable to fingerprint %s %s: %s", t.Instance, t.SchemaName, err)
+	} else if fingerprint != pt.Fingerprint {
+		return fmt.Errorf("%s %s has been modified since the plan was made; its fingerprint is now %s, but the plan expects %s", t.Instance, t.SchemaName, fingerprint, pt.Fingerprint)
+	}
+	return nil
+}
+
+// ApplyPlan executes the statements of pt on t, in order, using t's
+// configuration for options affecting execution, such as ddl-retries,
+// fatal-warnings, and max-replica-lag. The caller should first confirm that t
+// has not drifted from the plan, using pt.CheckDrift.
+func (t *Target) ApplyPlan(pt *PlanTarget, printer *Printer) (result Result, err error) {
+	fatalWarnings, err := parseFatalWarnings(t.Dir.Config.GetSlice("fatal-warnings", ',', true))
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+	retries, err := retryPolicyForConfig(t.Dir.Config)
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+	ddls := make([]*DDLStatement, len(pt.Statements))
+	for n, ps := range pt.Statements {
+		ddls[n] = &DDLStatement{
+			stmt:          ps.SQL,
+			instance:      t.Instance,
+			schemaName:    pt.Schema,
+			connectParams: ps.ConnectParams,
+			fatalWarnings: fatalWarnings,
+			retries:       retries,
+		}
+	}
+	log.Infof("Applying plan to %s %s", t.Instance, t.SchemaName)
+	result.Differences = (len(ddls) > 0)
+	result.SkipCount = t.processDDL(ddls, printer)
+	t.logApplyEnd(result)
+	return result, nil
+}
+
+// liveFingerprint returns the fingerprint of t's live schema, including its
+// events, sequences, views, and triggers, filtered by the ignore options of t's
+// dir. If the schema does not exist, the fingerprint of a nil schema is
+// returned.
+func liveFingerprint(t *Target) (string, error) {
+	opts, err := introspect.OptionsForDir(t.Dir)
+	if err != nil {
+		return "", err
+	}
+	schema, objs, err := liveSchemaAndObjects(t, opts)
+	if err != nil {
+		return "", err
+	}
+	return introspect.Fingerprint(schema, objs), nil
+}
+
+// liveSchemaAndObjects introspects t's live schema and its other objects,
+// filtered by opts. If the schema does not exist, nil is returned for the
+// schema, without any objects or error.
+func liveSchemaAndObjects(t *Target, opts introspect.Options) (*tengo.Schema, introspect.Objects, error) {
+	schema, err := introspect.Schema(t.Instance, t.SchemaName, opts)
+	if err == sql.ErrNoRows || (err == nil && schema == nil) {
+		return nil, introspect.Objects{}, nil
+	} else if err != nil {
+		return nil, introspect.Objects{}, err
+	}
+	objs, err := introspect.SchemaObjects(t.Instance, t.SchemaName, opts)
+	return schema, objs, err
+}
@@ -0,0 +1,131 @@
+package applier
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+func TestPlanCollect(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	dir := &fs.Dir{Path: "/var/tmp/fakedir"}
+	product := &Target{Instance: inst, Dir: dir, SchemaName: "product"}
+	analytics := &Target{Instance: inst, Dir: dir, SchemaName: "analytics"}
+
+	// A nil Plan collects nothing, but must not panic
+	var nilPlan *Plan
+	nilPlan.Add(product, &DDLStatement{stmt: "DROP TABLE foo"})
+	if nilPlan.Len() != 0 {
+		t.Error("Unexpected behavior from nil Plan")
+	}
+
+	plan := NewPlan("production")
+	plan.Add(product, &DDLStatement{stmt: "CREATE TABLE foo (id int)"})
+	plan.Add(analytics, &DDLStatement{stmt: "DROP TABLE bar", connectParams: "readTimeout=0"})
+	plan.Add(product, &DDLStatement{stmt: "DROP TABLE baz", connectParams: "readTimeout=0"})
+	if err := plan.Add(product, &DDLStatement{shellOut: &util.ShellOut{Command: "echo hi"}}); err == nil {
+		t.Error("Expected error adding shell-out to plan, but err was nil")
+	}
+	if plan.Len() != 2 {
+		t.Fatalf("Expected 2 targets to be collected, instead found %d", plan.Len())
+	}
+	pt := plan.targets[product]
+	expected := []PlanStatement{{SQL: "CREATE TABLE foo (id int)"}, {SQL: "DROP TABLE baz", ConnectParams: "readTimeout=0"}}
+	if pt.Dir != dir.Path || pt.Instance != inst.String() || pt.Schema != "product" || len(pt.Statements) != len(expected) {
+		t.Fatalf("Unexpected plan target: %+v", *pt)
+	}
+	for n := range expected {
+		if pt.Statements[n] != expected[n] {
+			t.Errorf("Expected statement[%d] to be %+v, instead found %+v", n, expected[n], pt.Statements[n])
+		}
+	}
+
+	// Missing or malformed plan files should be rejected
+	if _, err := ReadPlan("testdata/.scratch/missing.plan"); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error reading missing plan file, instead found %v", err)
+	}
+	fs.WriteTestFile(t, "testdata/.scratch/bad.plan", "{\"targets\": 123}\n")
+	if _, err := ReadPlan("testdata/.scratch/bad.plan"); err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Errorf("Expected error about malformed plan file, instead found %v", err)
+	}
+}
+
+func (s ApplierIntegrationSuite) TestPlanDrift(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	path := "testdata/.scratch/test.plan"
+	if _, err := s.d[0].SourceSQL("testdata/setup.sql"); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	target := &Target{
+		Instance:   s.d[0].Instance,
+		Dir:        getDir(t, "testdata/simple", ""),
+		SchemaName: "analytics",
+	}
+	printer := NewPrinter(false)
+
+	// Write a plan and read it back; it should be applicable as long as nothing
+	// has changed
+	writePlan := func() *PlanTarget {
+		t.Helper()
+		plan := NewPlan("production")
+		plan.Add(target, &DDLStatement{stmt: "CREATE TABLE plan1 (id int)"})
+		plan.Add(target, &DDLStatement{stmt: "CREATE TABLE plan2 (id int)"})
+		if err := plan.Write(path, time.Now()); err != nil {
+			t.Fatalf("Unexpected error from Write: %s", err)
+		}
+		readPlan, err := ReadPlan(path)
+		if err != nil {
+			t.Fatalf("Unexpected error from ReadPlan: %s", err)
+		} else if readPlan.Environment != "production" || len(readPlan.Targets) != 1 || readPlan.Targets[0].Fingerprint == "" {
+			t.Fatalf("Unexpected plan read back from file: %+v", readPlan.Targets)
+		}
+		return readPlan.Targets[0]
+	}
+	pt := writePlan()
+	if err := pt.CheckDrift(target); err != nil {
+		t.Fatalf("Unexpected error from CheckDrift: %s", err)
+	}
+
+	// An out-of-band schema change should cause the stale plan to be rejected
+	db, err := s.d[0].Connect("analytics", "")
+	if err != nil {
+		t.Fatalf("Unable to connect to DockerizedInstance: %s", err)
+	}
+	if _, err := db.Exec("ALTER TABLE pageviews ADD COLUMN drift int"); err != nil {
+		t.Fatalf("Unexpected error from ALTER TABLE: %s", err)
+	}
+	if err := pt.CheckDrift(target); err == nil {
+		t.Error("Expected CheckDrift to return an error after schema changed, but it did not")
+	}
+
+	// A new plan should be applicable, and applying it should cause the plan
+	// to now be considered stale
+	pt = writePlan()
+	if err := pt.CheckDrift(target); err != nil {
+		t.Fatalf("Unexpected error from CheckDrift: %s", err)
+	}
+	if result, err := target.ApplyPlan(pt, printer); err != nil || result.SkipCount != 0 || !result.Differences {
+		t.Fatalf("Unexpected result from ApplyPlan: %+v, %v", result, err)
+	}
+	schema, err := s.d[0].Schema("analytics")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	} else if !schema.HasTable("plan1") || !schema.HasTable("plan2") {
+		t.Error("Expected ApplyPlan to create tables, but it did not")
+	}
+	if err := pt.CheckDrift(target); err == nil {
+		t.Error("Expected CheckDrift to return an error after plan was applied, but it did not")
+	}
+}
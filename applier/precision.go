@@ -0,0 +1,55 @@
+package applier
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reNumericPrecision matches a fixed-point or floating-point column type with
+// an explicit precision, capturing the type, precision, and optional scale.
+var reNumericPrecision = regexp.MustCompile(`^(decimal|float|double)\((\d+)(?:,(\d+))?\)`)
+
+// reducedPrecisionColumns returns the names of columns modified by an ALTER
+// TABLE diff in a way that reduces the number of digits a DECIMAL, FLOAT, or
+// DOUBLE column can store, on either side of the decimal point. tengo only
+// considers such a change unsafe if the total precision or the scale is
+// reduced, so for example a change from DECIMAL(10,2) to DECIMAL(10,4) would
+// otherwise be permitted, even though it reduces the digits before the decimal
+// point from 8 to 6. Floating-point columns without an explicit precision use
+// the hardware maximum, which is already handled by tengo.
+func reducedPrecisionColumns(diff tengo.ObjectDiff) []string {
+	td := unwrapTableDiff(diff)
+	if td == nil || td.Type != tengo.DiffTypeAlter {
+		return nil
+	}
+	fromColumns := td.From.ColumnsByName()
+	var result []string
+	for _, col := range td.To.Columns {
+		if fromCol, ok := fromColumns[col.Name]; ok && fromCol.TypeInDB != col.TypeInDB && !fromCol.Virtual && precisionReduced(fromCol.TypeInDB, col.TypeInDB) {
+			result = append(result, col.Name)
+		}
+	}
+	return result
+}
+
+// precisionReduced returns true if changing a column from oldType to newType
+// reduces the number of digits it can store before or after the decimal point.
+// Only changes between DECIMAL types, between FLOAT types, between DOUBLE
+// types, or from FLOAT to DOUBLE are examined; other changes return false.
+func precisionReduced(oldType, newType string) bool {
+	oldMatches := reNumericPrecision.FindStringSubmatch(strings.ToLower(oldType))
+	newMatches := reNumericPrecision.FindStringSubmatch(strings.ToLower(newType))
+	if oldMatches == nil || newMatches == nil {
+		return false
+	} else if oldMatches[1] != newMatches[1] && (oldMatches[1] != "float" || newMatches[1] != "double") {
+		return false
+	}
+	oldPrecision, _ := strconv.Atoi(oldMatches[2])
+	oldScale, _ := strconv.Atoi(oldMatches[3])
+	newPrecision, _ := strconv.Atoi(newMatches[2])
+	newScale, _ := strconv.Atoi(newMatches[3])
+	return newScale < oldScale || newPrecision-newScale < oldPrecision-oldScale
+}
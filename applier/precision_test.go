@@ -0,0 +1,78 @@
+package applier
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestPrecisionReduced(t *testing.T) {
+	cases := []struct {
+		oldType, newType string
+		expected         bool
+	}{
+		{"decimal(10,2)", "decimal(12,2)", false},
+		{"decimal(10,2)", "decimal(12,4)", false},
+		{"decimal(10,2)", "decimal(10,2) unsigned", false},
+		{"decimal(10,2)", "decimal(8,2)", true},
+		{"decimal(10,2)", "decimal(10,0)", true},
+		{"decimal(10,2)", "decimal(10,4)", true},
+		{"decimal(10,0)", "decimal(10)", false},
+		{"float(7,4)", "float(9,4)", false},
+		{"float(7,4)", "double(9,6)", false},
+		{"float(7,4)", "float(7,5)", true},
+		{"double(9,6)", "float(9,6)", false}, // different types are left to tengo
+		{"double", "double(9,6)", false},     // ditto for hardware max precision
+		{"datetime(6)", "datetime(3)", false},
+		{"int(11)", "decimal(5,2)", false},
+	}
+	for _, c := range cases {
+		if actual := precisionReduced(c.oldType, c.newType); actual != c.expected {
+			t.Errorf("Expected precisionReduced(%q, %q) to return %t, instead found %t", c.oldType, c.newType, c.expected, actual)
+		}
+	}
+}
+
+func TestReducedPrecisionColumns(t *testing.T) {
+	makeTable := func(colTypes ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "prices",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for n, colType := range colTypes {
+			table.Columns = append(table.Columns, &tengo.Column{Name: string(rune('a' + n)), TypeInDB: colType, Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	cases := []struct {
+		from, to *tengo.Table
+		columns  []string
+		safety   Safety
+	}{
+		{makeTable("decimal(10,2)"), makeTable("decimal(12,2)"), nil, SafetySafeAlter},
+		{makeTable("decimal(10,2)"), makeTable("decimal(8,2)"), []string{"a"}, SafetyDestructive},
+		{makeTable("decimal(10,2)", "decimal(5,1)"), makeTable("decimal(10,4)", "decimal(5,1)"), []string{"a"}, SafetyDestructive},
+		{makeTable("decimal(10,2)", "decimal(5,1)"), makeTable("decimal(10,4)", "decimal(5,2)"), []string{"a", "b"}, SafetyDestructive},
+		{makeTable("datetime"), makeTable("datetime(3)"), nil, SafetySafeAlter},
+		{makeTable("datetime(3)"), makeTable("datetime(6)"), nil, SafetySafeAlter},
+		{makeTable("datetime(6)"), makeTable("datetime(3)"), nil, SafetyDestructive},
+		{makeTable("datetime(6)"), makeTable("datetime"), nil, SafetyDestructive},
+	}
+	for n, c := range cases {
+		diff := tengo.NewAlterTable(c.from, c.to)
+		if actual := reducedPrecisionColumns(diff); !reflect.DeepEqual(actual, c.columns) {
+			t.Errorf("Case %d: Expected reducedPrecisionColumns to return %v, instead found %v", n, c.columns, actual)
+		}
+		if actual := ClassifySafety(diff, tengo.StatementModifiers{}); actual != c.safety {
+			t.Errorf("Case %d: Expected ClassifySafety to return %s, instead found %s", n, c.safety, actual)
+		}
+	}
+	if columns := reducedPrecisionColumns(tengo.NewDropTable(makeTable("decimal(10,2)"))); columns != nil {
+		t.Errorf("Expected reducedPrecisionColumns to return nil for DROP TABLE, instead found %v", columns)
+	}
+}
@@ -1,7 +1,9 @@
 package applier
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/skeema/tengo"
@@ -11,8 +13,12 @@ import (
 // being called from multiple pushworker goroutines.
 type Printer struct {
 	briefOutput        bool
+	jsonOutput         bool
+	recording          bool
+	operations         []Operation
 	lastStdoutInstance string
 	lastStdoutSchema   string
+	lastGroupLabel     string
 	seenInstance       map[string]bool
 	*sync.Mutex
 }
@@ -29,6 +35,37 @@ func NewPrinter(briefMode bool) *Printer {
 	}
 }
 
+// NewJSONPrinter returns a pointer to a new Printer which outputs a JSON
+// object for each operation, one per line, rather than outputting DDL as SQL.
+// Unlike other printers, it also outputs operations which are skipped.
+func NewJSONPrinter() *Printer {
+	p := NewPrinter(false)
+	p.jsonOutput = true
+	return p
+}
+
+// NewRecordingPrinter returns a pointer to a new Printer which outputs nothing
+// to STDOUT, instead recording an Operation for each statement, as well as for
+// each operation which is skipped. This is intended for programmatic use of
+// Diff and Push, which can obtain the recorded operations via Operations.
+func NewRecordingPrinter() *Printer {
+	p := NewPrinter(false)
+	p.recording = true
+	return p
+}
+
+// Operations returns a copy of the operations recorded so far by a printer
+// from NewRecordingPrinter, in the order they were generated. For other
+// printers, nil is returned.
+func (p *Printer) Operations() []Operation {
+	p.Lock()
+	defer p.Unlock()
+	if !p.recording {
+		return nil
+	}
+	return append([]Operation{}, p.operations...)
+}
+
 // printDDL outputs DDLStatement values to STDOUT in a way that prevents
 // interleaving of output from multiple workers.
 // TODO: buffer output from external commands and also prevent interleaving there
@@ -37,6 +74,11 @@ func (p *Printer) printDDL(ddl *DDLStatement) {
 	defer p.Unlock()
 	instString := ddl.instance.String()
 
+	if p.jsonOutput || p.recording {
+		p.printRecord(newOperation(ddl))
+		return
+	}
+
 	// Support diff --brief, which only outputs instances that have differences,
 	// rather than outputting the actual differences
 	if p.briefOutput {
@@ -51,10 +93,103 @@ func (p *Printer) printDDL(ddl *DDLStatement) {
 		fmt.Printf("-- instance: %s\n", instString)
 		p.lastStdoutInstance = instString
 		p.lastStdoutSchema = ""
+		p.lastGroupLabel = ""
 	}
 	if ddl.schemaName != p.lastStdoutSchema && ddl.schemaName != "" {
 		fmt.Printf("USE %s;\n", tengo.EscapeIdentifier(ddl.schemaName))
 		p.lastStdoutSchema = ddl.schemaName
+		p.lastGroupLabel = ""
+	}
+	if ddl.groupLabel != p.lastGroupLabel && ddl.groupLabel != "" {
+		fmt.Printf("-- %s:\n", ddl.groupLabel)
+		p.lastGroupLabel = ddl.groupLabel
+	}
+	fmt.Print(ddl.rowsNote + ddl.String())
+}
+
+// printSkipped outputs a JSON record for each of objDiffs, all of which were
+// skipped on t for the supplied reason. Nothing is output unless p is a JSON or
+// recording printer, since skipped operations are otherwise only logged. This
+// is safe to call on a nil Printer.
+func (p *Printer) printSkipped(t *Target, objDiffs []tengo.ObjectDiff, mods tengo.StatementModifiers, reason string) {
+	if p == nil || (!p.jsonOutput && !p.recording) {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	for _, objDiff := range objDiffs {
+		unsafeMods := mods
+		unsafeMods.AllowUnsafe = true
+		stmt, err := objDiff.Statement(unsafeMods)
+		if stmt == "" && err == nil {
+			continue // noop due to mods, so not actually skipped
+		}
+		schemaName := t.SchemaName
+		if objDiff.ObjectKey().Type == tengo.ObjectTypeDatabase {
+			schemaName = ""
+		}
+		p.printRecord(Operation{
+			Instance:    t.Instance.String(),
+			Schema:      schemaName,
+			ObjectType:  string(objDiff.ObjectKey().Type),
+			ObjectName:  objDiff.ObjectKey().Name,
+			DiffType:    strings.ToLower(objDiff.DiffType().String()),
+			Statement:   stmt,
+			Destructive: ClassifySafety(objDiff, mods) == SafetyDestructive,
+			UnsafeOps:   joinUnsafeClasses(ClassifyUnsafe(objDiff, mods)),
+			Skipped:     true,
+			SkipReason:  reason,
+		})
+	}
+}
+
+// printRecord outputs rec as a single line of JSON, or records it if p is a
+// recording printer. The caller must hold p's lock.
+func (p *Printer) printRecord(rec Operation) {
+	if p.recording {
+		p.operations = append(p.operations, rec)
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		panic(err) // not possible, since all fields are strings or bools
+	}
+	fmt.Printf("%s\n", b)
+}
+
+// Operation describes one generated statement, or one operation which was
+// skipped. It is used in the output of a JSON Printer, as well as by
+// NewRecordingPrinter.
+type Operation struct {
+	Instance     string `json:"instance"`
+	Schema       string `json:"schema,omitempty"`
+	ObjectType   string `json:"object_type"`
+	ObjectName   string `json:"object_name"`
+	DiffType     string `json:"diff_type"`
+	Statement    string `json:"statement"`
+	ShellCommand string `json:"shell_command,omitempty"`
+	Destructive  bool   `json:"destructive"`
+	UnsafeOps    string `json:"unsafe_ops,omitempty"` // comma-separated classes, in the format of the unsafe-ops option
+	Skipped      bool   `json:"skipped"`
+	SkipReason   string `json:"skip_reason,omitempty"`
+}
+
+// newOperation returns an Operation describing ddl, which is not being
+// skipped. Backfills are described as a diff type of "backfill", since they
+// modify rows of their table rather than its definition.
+func newOperation(ddl *DDLStatement) Operation {
+	rec := Operation{
+		Instance:    ddl.instance.String(),
+		Schema:      ddl.schemaName,
+		ObjectType:  string(ddl.key.Type),
+		ObjectName:  ddl.key.Name,
+		DiffType:    diffTypeLabel(ddl.diffType),
+		Statement:   ddl.stmt,
+		Destructive: ddl.safety == SafetyDestructive,
+		UnsafeOps:   joinUnsafeClasses(ddl.unsafeClasses),
+	}
+	if ddl.IsShellOut() {
+		rec.ShellCommand = ddl.shellOut.String()
 	}
-	fmt.Print(ddl.String())
+	return rec
 }
@@ -0,0 +1,114 @@
+package applier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+func TestJSONPrinter(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	target := &Target{Instance: inst, SchemaName: "product"}
+	table := &tengo.Table{
+		Name:               "widgets",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+	}
+	table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+	tableKey := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "widgets"}
+
+	realStdout := os.Stdout
+	outFile, err := os.Create("test-json-printer.out")
+	if err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	}
+	os.Stdout = outFile
+	defer func() {
+		os.Stdout = realStdout
+		os.Remove("test-json-printer.out")
+	}()
+
+	p := NewJSONPrinter()
+	p.printDDL(&DDLStatement{stmt: "CREATE TABLE foo (id int)", instance: inst, schemaName: "product", key: tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo"}, diffType: tengo.DiffTypeCreate})
+	p.printDDL(&DDLStatement{stmt: "UPDATE `widgets` SET `id` = 0 WHERE `id` IS NULL", instance: inst, schemaName: "product", key: tableKey, safety: SafetySafeAlter})
+	p.printDDL(&DDLStatement{stmt: "ALTER TABLE `widgets` ADD COLUMN `b` int", shellOut: &util.ShellOut{Command: "echo hi"}, instance: inst, schemaName: "product", key: tableKey, diffType: tengo.DiffTypeAlter, safety: SafetySafeAlter})
+	p.printSkipped(target, []tengo.ObjectDiff{tengo.NewDropTable(table)}, tengo.StatementModifiers{}, "unsafe")
+
+	// Non-JSON and nil printers should not output skipped operations
+	NewPrinter(false).printSkipped(target, []tengo.ObjectDiff{tengo.NewDropTable(table)}, tengo.StatementModifiers{}, "unsafe")
+	var nilPrinter *Printer
+	nilPrinter.printSkipped(target, []tengo.ObjectDiff{tengo.NewDropTable(table)}, tengo.StatementModifiers{}, "unsafe")
+
+	outFile.Close()
+	os.Stdout = realStdout
+	output, err := ioutil.ReadFile("test-json-printer.out")
+	if err != nil {
+		t.Fatalf("Unable to read file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	expected := []Operation{
+		{Instance: "127.0.0.1:3306", Schema: "product", ObjectType: "table", ObjectName: "foo", DiffType: "create", Statement: "CREATE TABLE foo (id int)"},
+		{Instance: "127.0.0.1:3306", Schema: "product", ObjectType: "table", ObjectName: "widgets", DiffType: "backfill", Statement: "UPDATE `widgets` SET `id` = 0 WHERE `id` IS NULL"},
+		{Instance: "127.0.0.1:3306", Schema: "product", ObjectType: "table", ObjectName: "widgets", DiffType: "alter", Statement: "ALTER TABLE `widgets` ADD COLUMN `b` int", ShellCommand: "echo hi"},
+		{Instance: "127.0.0.1:3306", Schema: "product", ObjectType: "table", ObjectName: "widgets", DiffType: "drop", Statement: "DROP TABLE `widgets`", Destructive: true, UnsafeOps: "drop-table", Skipped: true, SkipReason: "unsafe"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines of output, instead found %d: %s", len(expected), len(lines), output)
+	}
+	for n, line := range lines {
+		var actual Operation
+		if err := json.Unmarshal([]byte(line), &actual); err != nil {
+			t.Errorf("Unable to unmarshal line %d of output %q: %s", n+1, line, err)
+		} else if actual != expected[n] {
+			t.Errorf("Unexpected record on line %d:\nexpected %+v\nfound    %+v", n+1, expected[n], actual)
+		}
+	}
+}
+
+func TestRecordingPrinter(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	target := &Target{Instance: inst, SchemaName: "product"}
+	table := &tengo.Table{
+		Name:               "widgets",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+	}
+	table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+
+	p := NewRecordingPrinter()
+	p.printDDL(&DDLStatement{stmt: "CREATE TABLE foo (id int)", instance: inst, schemaName: "product", key: tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo"}, diffType: tengo.DiffTypeCreate})
+	p.printSkipped(target, []tengo.ObjectDiff{tengo.NewDropTable(table)}, tengo.StatementModifiers{}, "unsafe")
+	ops := p.Operations()
+	if len(ops) != 2 {
+		t.Fatalf("Expected 2 recorded operations, instead found %d", len(ops))
+	}
+	if ops[0].Statement != "CREATE TABLE foo (id int)" || ops[0].Skipped || ops[1].Statement != "DROP TABLE `widgets`" || !ops[1].Skipped {
+		t.Errorf("Unexpected recorded operations: %+v", ops)
+	}
+
+	// The returned slice is a copy, and other printers don't record anything
+	ops[0].Statement = "modified"
+	if p.Operations()[0].Statement == "modified" {
+		t.Error("Expected Operations to return a copy, but it did not")
+	}
+	if ops := NewJSONPrinter().Operations(); ops != nil {
+		t.Errorf("Expected non-recording printer to return nil from Operations, instead found %v", ops)
+	}
+}
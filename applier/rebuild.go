@@ -0,0 +1,84 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// collationChange describes a change to the collation of a column, along with
+// the pre-existing indexes that must be rebuilt as a result.
+type collationChange struct {
+	from, to  *tengo.Column
+	indexes   []*tengo.Index
+	clustered bool // true if one of indexes is the InnoDB clustered index
+}
+
+// String returns a human-readable description of the collation change, its
+// affected indexes, and the cost of rebuilding them.
+func (cc collationChange) String() string {
+	names := make([]string, len(cc.indexes))
+	var unique bool
+	for n, idx := range cc.indexes {
+		names[n] = tengo.EscapeIdentifier(idx.Name)
+		unique = unique || idx.Unique
+	}
+	subject, pronoun := "index "+names[0], "it"
+	if len(names) > 1 {
+		subject, pronoun = "indexes "+strings.Join(names, ", "), "them"
+	}
+	cost := "moderate cost: only index entries are re-sorted"
+	if cc.clustered {
+		cost = "high cost: all row data is re-sorted, since the clustered index is affected"
+	}
+	result := fmt.Sprintf("indexed column %s collation changes from %s to %s, so %s must be rebuilt (%s), and queries using %s may sort or compare values differently",
+		tengo.EscapeIdentifier(cc.to.Name), cc.from.Collation, cc.to.Collation, subject, cost, pronoun)
+	if unique {
+		result += ", and unique indexes may reject existing values which now compare as equal"
+	}
+	return result
+}
+
+// indexedCollationChanges returns a collationChange for each column of to
+// whose collation differs from the corresponding column of from, without a
+// change in character set, and which is part of at least one index that exists
+// in both tables. Indexes being newly added are not considered to be rebuilt.
+func indexedCollationChanges(from, to *tengo.Table) []collationChange {
+	existingIndexes := make(map[string]bool)
+	for name := range from.SecondaryIndexesByName() {
+		existingIndexes[name] = true
+	}
+	indexes := to.SecondaryIndexes
+	if to.PrimaryKey != nil {
+		indexes = append([]*tengo.Index{to.PrimaryKey}, indexes...)
+		existingIndexes[to.PrimaryKey.Name] = (from.PrimaryKey != nil)
+	}
+	clusteredIndex := to.ClusteredIndexKey()
+
+	var result []collationChange
+	fromCols := from.ColumnsByName()
+	for _, toCol := range to.Columns {
+		fromCol := fromCols[toCol.Name]
+		if fromCol == nil || fromCol.Collation == "" || toCol.Collation == "" || fromCol.CharSet != toCol.CharSet || fromCol.Collation == toCol.Collation {
+			continue
+		}
+		cc := collationChange{from: fromCol, to: toCol}
+		for _, idx := range indexes {
+			if !existingIndexes[idx.Name] {
+				continue
+			}
+			for _, part := range idx.Parts {
+				if part.ColumnName == toCol.Name {
+					cc.indexes = append(cc.indexes, idx)
+					cc.clustered = cc.clustered || idx == clusteredIndex
+					break
+				}
+			}
+		}
+		if len(cc.indexes) > 0 {
+			result = append(result, cc)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestClassifyAlterCollation(t *testing.T) {
+	// Simulate a MySQL 8 upgrade scenario, where columns' collations are being
+	// changed without changing their character set
+	makeTable := func(collation string) *tengo.Table {
+		cols := []*tengo.Column{
+			{Name: "id", TypeInDB: "int unsigned"},
+			{Name: "name", TypeInDB: "varchar(40)", CharSet: "utf8mb4", Collation: collation},
+			{Name: "bio", TypeInDB: "text", CharSet: "utf8mb4", Collation: collation},
+		}
+		return &tengo.Table{
+			Name:       "users",
+			Engine:     "InnoDB",
+			CharSet:    "utf8mb4",
+			Collation:  collation,
+			Columns:    cols,
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+			SecondaryIndexes: []*tengo.Index{
+				{Name: "idx_name", Parts: []tengo.IndexPart{{ColumnName: "name", PrefixLength: 10}}},
+			},
+		}
+	}
+	from, to := makeTable("utf8mb4_general_ci"), makeTable("utf8mb4_0900_ai_ci")
+	diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: from, To: to}
+
+	// Only the indexed column should be flagged; the non-indexed text column's
+	// collation change does not require a rebuild
+	algo, reasons := ClassifyAlter(diff, tengo.FlavorMySQL80)
+	if algo != AlterAlgorithmRebuild || len(reasons) != 1 || !strings.Contains(reasons[0], "`name`") {
+		t.Errorf("Expected exactly 1 rebuild reason for column name, instead found %s %v", algo, reasons)
+	}
+
+	// A charset change is not flagged as a collation change
+	to.Columns[1].CharSet = "latin1"
+	to.Columns[1].Collation = "latin1_swedish_ci"
+	if _, reasons := ClassifyAlter(diff, tengo.FlavorMySQL80); len(reasons) != 1 || strings.Contains(reasons[0], "must be rebuilt") {
+		t.Errorf("Expected only a character set change reason, instead found %v", reasons)
+	}
+}
+
+func TestIndexedCollationChanges(t *testing.T) {
+	makeTable := func(collation string) *tengo.Table {
+		cols := []*tengo.Column{
+			{Name: "code", TypeInDB: "varchar(10)", CharSet: "utf8mb4", Collation: collation},
+			{Name: "name", TypeInDB: "varchar(40)", CharSet: "utf8mb4", Collation: collation},
+			{Name: "email", TypeInDB: "varchar(100)", CharSet: "utf8mb4", Collation: collation},
+			{Name: "bio", TypeInDB: "text", CharSet: "utf8mb4", Collation: collation},
+		}
+		return &tengo.Table{
+			Name:       "users",
+			Engine:     "InnoDB",
+			CharSet:    "utf8mb4",
+			Collation:  collation,
+			Columns:    cols,
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Parts: []tengo.IndexPart{{ColumnName: "code"}}},
+			SecondaryIndexes: []*tengo.Index{
+				{Name: "idx_name", Parts: []tengo.IndexPart{{ColumnName: "name"}}},
+				{Name: "idx_name_email", Parts: []tengo.IndexPart{{ColumnName: "name"}, {ColumnName: "email"}}},
+			},
+		}
+	}
+	from, to := makeTable("utf8mb4_general_ci"), makeTable("utf8mb4_0900_ai_ci")
+	to.SecondaryIndexes = append(to.SecondaryIndexes, &tengo.Index{Name: "uniq_email", Unique: true, Parts: []tengo.IndexPart{{ColumnName: "email"}}})
+
+	// Each indexed column should be linked to its pre-existing indexes; the new
+	// unique index isn't rebuilt, and the non-indexed text column has no entry
+	changes := indexedCollationChanges(from, to)
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 collation changes, instead found %d: %v", len(changes), changes)
+	}
+	expected := map[string][]string{
+		"code":  {"PRIMARY"},
+		"name":  {"idx_name", "idx_name_email"},
+		"email": {"idx_name_email"},
+	}
+	for _, cc := range changes {
+		var names []string
+		for _, idx := range cc.indexes {
+			names = append(names, idx.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(expected[cc.to.Name], ",") {
+			t.Errorf("Column %s: expected affected indexes %v, instead found %v", cc.to.Name, expected[cc.to.Name], names)
+		}
+		if cc.clustered != (cc.to.Name == "code") {
+			t.Errorf("Column %s: unexpected clustered value %t", cc.to.Name, cc.clustered)
+		}
+	}
+
+	// Descriptions should name the indexes, classify cost, and mention unique
+	// index risks only when applicable
+	if desc := changes[0].String(); !strings.Contains(desc, "index `PRIMARY` must be rebuilt") || !strings.Contains(desc, "high cost") || !strings.Contains(desc, "unique indexes") {
+		t.Errorf("Unexpected description: %s", desc)
+	}
+	if desc := changes[1].String(); !strings.Contains(desc, "indexes `idx_name`, `idx_name_email` must be rebuilt") || !strings.Contains(desc, "moderate cost") || strings.Contains(desc, "unique indexes") {
+		t.Errorf("Unexpected description: %s", desc)
+	}
+
+	// Without a primary key or other suitable unique index, InnoDB uses an
+	// internal clustered index, so no secondary index change affects row data
+	from.PrimaryKey, to.PrimaryKey = nil, nil
+	for _, cc := range indexedCollationChanges(from, to) {
+		if cc.clustered || cc.to.Name == "code" {
+			t.Errorf("Unexpected collation change result without primary key: %+v", cc)
+		}
+	}
+}
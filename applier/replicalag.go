@@ -0,0 +1,127 @@
+package applier
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// replicaLagPollInterval is how long to sleep between checks of replication
+// lag, while waiting for it to fall below the threshold. It is a variable only
+// to permit tests to shorten it.
+var replicaLagPollInterval = 5 * time.Second
+
+// replicaStatusQuery returns the statement for obtaining replication status on
+// the supplied flavor. Newer versions deprecate the old SHOW SLAVE STATUS.
+func replicaStatusQuery(flavor tengo.Flavor) string {
+	if flavor.MySQLishMinVersion(8, 0, 22) || flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 5, 1) {
+		return "SHOW REPLICA STATUS"
+	}
+	return "SHOW SLAVE STATUS"
+}
+
+// replicaLag runs query, which should be a replica status statement, and
+// returns the replication lag in seconds. If the server is not a replica, the
+// query returns no rows, and isReplica will be false. For a replica with
+// multiple replication channels, the highest lag of any channel is returned.
+// If replication is not running on any channel, lag will be -1.
+func replicaLag(db *sql.DB, query string) (lag int, isReplica bool, err error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, false, err
+	}
+	lagCol := -1
+	for n, col := range cols {
+		// MySQL 8.0.22+ renamed the column, but MariaDB did not
+		if col == "Seconds_Behind_Source" || col == "Seconds_Behind_Master" {
+			lagCol = n
+		}
+	}
+	if lagCol < 0 {
+		return 0, false, fmt.Errorf("%s did not return a Seconds_Behind_Source or Seconds_Behind_Master column", query)
+	}
+
+	lag = -1
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for n := range values {
+		dest[n] = &values[n]
+	}
+	for rows.Next() {
+		isReplica = true
+		if err := rows.Scan(dest...); err != nil {
+			return 0, false, err
+		}
+		if values[lagCol] == nil { // NULL means replication is stopped on this channel
+			continue
+		}
+		channelLag, err := strconv.Atoi(string(values[lagCol]))
+		if err != nil {
+			return 0, false, fmt.Errorf("Unable to parse replication lag %q: %s", values[lagCol], err)
+		}
+		if channelLag > lag {
+			lag = channelLag
+		}
+	}
+	if !isReplica {
+		lag = 0
+	}
+	return lag, isReplica, rows.Err()
+}
+
+// waitForReplicaLag blocks until db's replication lag is at most maxLag
+// seconds, or returns an error if this does not occur within timeout. Servers
+// which are not replicas, or on which replication is stopped, are considered
+// caught up, since waiting on them would be futile.
+func waitForReplicaLag(db *sql.DB, flavor tengo.Flavor, maxLag int, timeout time.Duration) error {
+	query := replicaStatusQuery(flavor)
+	deadline := time.Now().Add(timeout)
+	for {
+		lag, isReplica, err := replicaLag(db, query)
+		if err != nil {
+			return fmt.Errorf("Unable to check replication lag: %s", err)
+		} else if !isReplica {
+			log.Debug("Not checking replication lag, since the server is not a replica")
+			return nil
+		} else if lag < 0 {
+			log.Warn("Not waiting on replication lag, since replication is not running")
+			return nil
+		} else if lag <= maxLag {
+			return nil
+		} else if time.Now().Add(replicaLagPollInterval).After(deadline) {
+			return fmt.Errorf("Replication lag of %ds did not fall to max-replica-lag of %ds within %s", lag, maxLag, timeout)
+		}
+		log.Infof("Waiting for replication lag of %ds to fall to max-replica-lag of %ds", lag, maxLag)
+		time.Sleep(replicaLagPollInterval)
+	}
+}
+
+// waitForReplicaLag waits for the target's replication lag to fall below the
+// threshold configured by the max-replica-lag option, if any, for up to the
+// duration of the replica-lag-timeout option.
+func (t *Target) waitForReplicaLag() error {
+	maxLag, err := t.Dir.Config.GetInt("max-replica-lag")
+	if err != nil {
+		return err
+	} else if maxLag <= 0 {
+		return nil
+	}
+	timeout, err := t.Dir.Config.GetInt("replica-lag-timeout")
+	if err != nil {
+		return err
+	}
+	db, err := t.Instance.Connect("", "")
+	if err != nil {
+		return err
+	}
+	return waitForReplicaLag(db.DB, t.Instance.Flavor(), maxLag, time.Duration(timeout)*time.Second)
+}
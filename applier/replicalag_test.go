@@ -0,0 +1,172 @@
+package applier
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/tengo"
+)
+
+// fakeReplicaStatuses maps DSNs of the skeematest-replica driver to the
+// results of successive replica status queries. Each result is a slice of
+// channel lag values, with nil meaning replication is stopped on that channel.
+// Once only one result remains, it is returned by all subsequent queries.
+var fakeReplicaStatuses map[string][][]driver.Value
+
+// fakeReplicaDriver is a driver with connections that return mocked replica
+// status results, as configured by fakeReplicaStatuses. DSNs beginning with
+// "mariadb" return the older column names, and DSNs containing "fail" return
+// an error.
+type fakeReplicaDriver struct{}
+type fakeReplicaConn struct{ dsn string }
+type fakeReplicaStmt struct{ dsn string }
+type fakeReplicaRows struct {
+	dsn  string
+	lags []driver.Value
+}
+
+func (fakeReplicaDriver) Open(dsn string) (driver.Conn, error) { return fakeReplicaConn{dsn: dsn}, nil }
+func (c fakeReplicaConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeReplicaStmt{dsn: c.dsn}, nil
+}
+func (fakeReplicaConn) Close() error              { return nil }
+func (fakeReplicaConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+func (fakeReplicaStmt) Close() error              { return nil }
+func (fakeReplicaStmt) NumInput() int             { return -1 }
+func (fakeReplicaStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (st fakeReplicaStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(st.dsn, "fail") {
+		return nil, errors.New("access denied")
+	}
+	results := fakeReplicaStatuses[st.dsn]
+	if len(results) == 0 {
+		return &fakeReplicaRows{dsn: st.dsn}, nil
+	}
+	if len(results) > 1 {
+		fakeReplicaStatuses[st.dsn] = results[1:]
+	}
+	return &fakeReplicaRows{dsn: st.dsn, lags: results[0]}, nil
+}
+func (r *fakeReplicaRows) Columns() []string {
+	if strings.HasPrefix(r.dsn, "mariadb") {
+		return []string{"Slave_IO_State", "Master_Host", "Seconds_Behind_Master"}
+	}
+	return []string{"Replica_IO_State", "Source_Host", "Seconds_Behind_Source"}
+}
+func (r *fakeReplicaRows) Close() error { return nil }
+func (r *fakeReplicaRows) Next(dest []driver.Value) error {
+	if len(r.lags) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1], dest[2] = []byte("Waiting for source to send event"), []byte("primary.example.com"), r.lags[0]
+	r.lags = r.lags[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("skeematest-replica", fakeReplicaDriver{})
+}
+
+func TestReplicaStatusQuery(t *testing.T) {
+	cases := map[tengo.Flavor]string{
+		tengo.FlavorMySQL57:                  "SHOW SLAVE STATUS",
+		tengo.FlavorMySQL80:                  "SHOW SLAVE STATUS",
+		tengo.NewFlavor("mysql", 8, 0, 22):   "SHOW REPLICA STATUS",
+		tengo.NewFlavor("percona", 8, 0, 23): "SHOW REPLICA STATUS",
+		tengo.FlavorMariaDB104:               "SHOW SLAVE STATUS",
+		tengo.NewFlavor("mariadb", 10, 5, 1): "SHOW REPLICA STATUS",
+		tengo.NewFlavor("mariadb", 10, 6):    "SHOW REPLICA STATUS",
+	}
+	for flavor, expected := range cases {
+		if actual := replicaStatusQuery(flavor); actual != expected {
+			t.Errorf("Expected replicaStatusQuery(%s) to return %q, instead found %q", flavor, expected, actual)
+		}
+	}
+}
+
+func TestWaitForReplicaLag(t *testing.T) {
+	origInterval := replicaLagPollInterval
+	replicaLagPollInterval = time.Millisecond
+	defer func() {
+		replicaLagPollInterval = origInterval
+		fakeReplicaStatuses = nil
+	}()
+	fakeReplicaStatuses = map[string][][]driver.Value{
+		"primary":     nil,
+		"caughtup":    {{[]byte("0")}},
+		"catchingup":  {{[]byte("50")}, {[]byte("20")}, {[]byte("2")}},
+		"stopped":     {{nil}},
+		"behind":      {{[]byte("300")}},
+		"multisource": {{[]byte("1"), nil, []byte("75")}, {[]byte("2"), []byte("3")}},
+		"mariadb":     {{[]byte("12")}, {[]byte("0")}},
+		"garbage":     {{[]byte("soon")}},
+		"fail":        nil,
+	}
+	cases := map[string]bool{ // DSN -> whether an error is expected
+		"primary":     false,
+		"caughtup":    false,
+		"catchingup":  false,
+		"stopped":     false,
+		"behind":      true,
+		"multisource": false,
+		"mariadb":     false,
+		"garbage":     true,
+		"fail":        true,
+	}
+	for dsn, expectErr := range cases {
+		db, err := sql.Open("skeematest-replica", dsn)
+		if err != nil {
+			t.Fatalf("Unexpected error from sql.Open: %s", err)
+		}
+		err = waitForReplicaLag(db, tengo.FlavorMySQL80, 5, 50*time.Millisecond)
+		if expectErr && err == nil {
+			t.Errorf("Expected waitForReplicaLag on %s to return an error, but it did not", dsn)
+		} else if !expectErr && err != nil {
+			t.Errorf("Unexpected error from waitForReplicaLag on %s: %s", dsn, err)
+		}
+		db.Close()
+	}
+	if results := fakeReplicaStatuses["catchingup"]; len(results) != 1 || string(results[0][0].([]byte)) != "2" {
+		t.Errorf("Expected waitForReplicaLag to poll until lag fell below threshold, but remaining results are %v", results)
+	}
+}
+
+func TestReplicaLag(t *testing.T) {
+	defer func() {
+		fakeReplicaStatuses = nil
+	}()
+	fakeReplicaStatuses = map[string][][]driver.Value{
+		"primary":     nil,
+		"replica":     {{[]byte("4")}},
+		"stopped":     {{nil}},
+		"multisource": {{[]byte("1"), nil, []byte("75")}},
+	}
+	cases := []struct {
+		dsn       string
+		lag       int
+		isReplica bool
+	}{
+		{"primary", 0, false},
+		{"replica", 4, true},
+		{"stopped", -1, true},
+		{"multisource", 75, true},
+	}
+	for _, c := range cases {
+		db, err := sql.Open("skeematest-replica", c.dsn)
+		if err != nil {
+			t.Fatalf("Unexpected error from sql.Open: %s", err)
+		}
+		lag, isReplica, err := replicaLag(db, "SHOW REPLICA STATUS")
+		if err != nil || lag != c.lag || isReplica != c.isReplica {
+			t.Errorf("Unexpected result from replicaLag on %s: %d, %t, %v", c.dsn, lag, isReplica, err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,150 @@
+package applier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// StateFile tracks which DDL statements have already been executed
+// successfully, persisting this information to a file so that a failed push
+// can be resumed later. Each completed statement is appended to the file as
+// a line of JSON as soon as it finishes, so the file remains accurate even if
+// Skeema is killed.
+//
+// All methods are safe to call on a nil *StateFile, in which case nothing is
+// tracked. Methods may also be called concurrently from multiple workers.
+type StateFile struct {
+	sync.Mutex
+	path      string
+	f         *os.File
+	completed map[stateEntry]bool
+}
+
+// stateEntry represents one line of a state file. The statement is recorded in
+// the same form as it is displayed by push, which means any password in a
+// shell-out command has already been masked.
+type stateEntry struct {
+	Instance  string `json:"instance"`
+	Schema    string `json:"schema"`
+	Statement string `json:"statement"`
+}
+
+// OpenStateFile reads any completed statements from the state file at path,
+// and opens it for appending additional ones. If the file does not exist yet,
+// it is created.
+func OpenStateFile(path string) (*StateFile, error) {
+	sf := &StateFile{
+		path:      path,
+		completed: make(map[stateEntry]bool),
+	}
+	if existing, err := os.Open(path); err == nil {
+		defer existing.Close()
+		scanner := bufio.NewScanner(existing)
+		scanner.Buffer(nil, 16*1024*1024)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			var entry stateEntry
+			if len(scanner.Bytes()) == 0 {
+				continue
+			} else if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				return nil, fmt.Errorf("State file %s line %d is malformed: %s", path, lineNumber, err)
+			}
+			sf.completed[entry] = true
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	sf.f = f
+	return sf, nil
+}
+
+// Path returns the path to the underlying file.
+func (sf *StateFile) Path() string {
+	if sf == nil {
+		return ""
+	}
+	return sf.path
+}
+
+// CompletedCount returns the number of statements recorded as completed.
+func (sf *StateFile) CompletedCount() int {
+	if sf == nil {
+		return 0
+	}
+	sf.Lock()
+	defer sf.Unlock()
+	return len(sf.completed)
+}
+
+// Completed returns true if ddl was already executed successfully on t according
+// to the state file.
+func (sf *StateFile) Completed(t *Target, ddl *DDLStatement) bool {
+	if sf == nil {
+		return false
+	}
+	sf.Lock()
+	defer sf.Unlock()
+	return sf.completed[newStateEntry(t, ddl)]
+}
+
+// Record notes that ddl was executed successfully on t, writing this to the
+// state file immediately.
+func (sf *StateFile) Record(t *Target, ddl *DDLStatement) error {
+	if sf == nil {
+		return nil
+	}
+	entry := newStateEntry(t, ddl)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	sf.Lock()
+	defer sf.Unlock()
+	sf.completed[entry] = true
+	if _, err := sf.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return sf.f.Sync()
+}
+
+// Close closes the underlying file, leaving it in place for a future resume.
+func (sf *StateFile) Close() error {
+	if sf == nil || sf.f == nil {
+		return nil
+	}
+	sf.Lock()
+	defer sf.Unlock()
+	err := sf.f.Close()
+	sf.f = nil
+	return err
+}
+
+// Remove closes and deletes the underlying file. This should be called once
+// all work has completed successfully, so that nothing is skipped by a future
+// push using the same state file.
+func (sf *StateFile) Remove() error {
+	if sf == nil {
+		return nil
+	}
+	if err := sf.Close(); err != nil {
+		return err
+	}
+	return os.Remove(sf.path)
+}
+
+func newStateEntry(t *Target, ddl *DDLStatement) stateEntry {
+	return stateEntry{
+		Instance:  t.Instance.String(),
+		Schema:    t.SchemaName,
+		Statement: ddl.String(),
+	}
+}
@@ -0,0 +1,142 @@
+package applier
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestStateFile(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	path := "testdata/.scratch/push.state"
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	target := &Target{Instance: inst, SchemaName: "product"}
+	otherTarget := &Target{Instance: inst, SchemaName: "analytics"}
+	ddls := []*DDLStatement{
+		{stmt: "CREATE TABLE foo (id int)", instance: inst, schemaName: "product"},
+		{stmt: "CREATE TABLE bar (id int)", instance: inst, schemaName: "product"},
+		{stmt: "DROP TABLE baz", instance: inst, schemaName: "product"},
+	}
+
+	// A nil StateFile tracks nothing, but must not panic
+	var nilState *StateFile
+	if err := nilState.Record(target, ddls[0]); err != nil || nilState.Completed(target, ddls[0]) || nilState.Remove() != nil {
+		t.Error("Unexpected behavior from nil StateFile")
+	}
+
+	// Simulate a push which fails on the third statement
+	sf, err := OpenStateFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error from OpenStateFile: %s", err)
+	}
+	for _, ddl := range ddls[0:2] {
+		if err := sf.Record(target, ddl); err != nil {
+			t.Fatalf("Unexpected error from Record: %s", err)
+		}
+	}
+	if err := sf.Close(); err != nil {
+		t.Fatalf("Unexpected error from Close: %s", err)
+	}
+
+	// Resuming should skip only the completed statements for the same target
+	if sf, err = OpenStateFile(path); err != nil {
+		t.Fatalf("Unexpected error from OpenStateFile: %s", err)
+	}
+	if sf.CompletedCount() != 2 {
+		t.Errorf("Expected 2 completed statements, instead found %d", sf.CompletedCount())
+	}
+	for n, ddl := range ddls {
+		if expected := (n < 2); sf.Completed(target, ddl) != expected {
+			t.Errorf("Expected Completed for %q to return %t, but it did not", ddl.stmt, expected)
+		}
+		if sf.Completed(otherTarget, ddl) {
+			t.Errorf("Expected Completed for %q on a different schema to return false, but it did not", ddl.stmt)
+		}
+	}
+	if err := sf.Record(target, ddls[2]); err != nil {
+		t.Fatalf("Unexpected error from Record: %s", err)
+	}
+	if err := sf.Remove(); err != nil {
+		t.Fatalf("Unexpected error from Remove: %s", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected state file to be removed, but Stat returned %v", err)
+	}
+
+	// Malformed state files should be rejected
+	fs.WriteTestFile(t, path, "{\"instance\":\"x\"}\nnot json\n")
+	if _, err := OpenStateFile(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error about line 2 of malformed state file, instead found %v", err)
+	}
+}
+
+func (s ApplierIntegrationSuite) TestProcessDDLResume(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	path := "testdata/.scratch/push.state"
+	if _, err := s.d[0].SourceSQL("testdata/setup.sql"); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+
+	getTarget := func() *Target {
+		t.Helper()
+		state, err := OpenStateFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error from OpenStateFile: %s", err)
+		}
+		return &Target{
+			Instance:   s.d[0].Instance,
+			Dir:        &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(map[string]string{"dry-run": "0", "max-replica-lag": "0", "before-statement": "", "after-statement": "", "audit-table": ""})},
+			SchemaName: "analytics",
+			State:      state,
+		}
+	}
+	makeDDLs := func(stmts ...string) []*DDLStatement {
+		ddls := make([]*DDLStatement, len(stmts))
+		for n, stmt := range stmts {
+			ddls[n] = &DDLStatement{stmt: stmt, instance: s.d[0].Instance, schemaName: "analytics"}
+		}
+		return ddls
+	}
+	printer := NewPrinter(false)
+
+	// Simulate a push which fails partway: the first statement succeeds, the
+	// second fails, and the third is never attempted
+	target := getTarget()
+	ddls := makeDDLs("CREATE TABLE resume1 (id int)", "CREATE TABLE resume2 (id int, id int)", "CREATE TABLE resume3 (id int)")
+	if skipCount := target.processDDL(ddls, printer); skipCount != 2 {
+		t.Errorf("Expected 2 skipped statements, instead found %d", skipCount)
+	}
+	target.State.Close()
+
+	// Re-running with the same statements (after fixing the broken one) should
+	// skip the first statement, which would otherwise fail since the table
+	// already exists
+	target = getTarget()
+	ddls = makeDDLs("CREATE TABLE resume1 (id int)", "CREATE TABLE resume2 (id int)", "CREATE TABLE resume3 (id int)")
+	if skipCount := target.processDDL(ddls, printer); skipCount != 0 {
+		t.Errorf("Expected 0 skipped statements, instead found %d", skipCount)
+	}
+	if count := target.State.CompletedCount(); count != 3 {
+		t.Errorf("Expected 3 completed statements in state file, instead found %d", count)
+	}
+	target.State.Close()
+	schema, err := s.d[0].Schema("analytics")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	}
+	for _, name := range []string{"resume1", "resume2", "resume3"} {
+		if !schema.HasTable(name) {
+			t.Errorf("Expected table %s to exist, but it does not", name)
+		}
+	}
+}
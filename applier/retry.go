@@ -0,0 +1,62 @@
+package applier
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/tengo"
+)
+
+// retrySleep is used to wait between retry attempts. It is a variable only to
+// permit tests to avoid actually sleeping.
+var retrySleep = time.Sleep
+
+// transientErrorCodes are the MySQL error numbers which indicate a statement
+// failed due to contention with other sessions, and may succeed if retried:
+// 1205 is lock wait timeout, and 1213 is deadlock.
+var transientErrorCodes = []uint16{1205, 1213}
+
+// retryPolicy controls how DDL is retried upon transient errors.
+type retryPolicy struct {
+	maxRetries int           // maximum number of retries after the initial attempt; 0 disables retries
+	backoff    time.Duration // wait before the first retry; doubled for each subsequent retry
+}
+
+// retryPolicyForConfig returns the retry policy configured by the ddl-retries
+// and ddl-retry-backoff options.
+func retryPolicyForConfig(config *mybase.Config) (policy retryPolicy, err error) {
+	if policy.maxRetries, err = config.GetInt("ddl-retries"); err != nil || policy.maxRetries < 0 {
+		return policy, ConfigError(fmt.Sprintf("Option ddl-retries has invalid value %q: must be a non-negative integer", config.Get("ddl-retries")))
+	}
+	backoff, err := config.GetInt("ddl-retry-backoff")
+	if err != nil || backoff < 0 {
+		return policy, ConfigError(fmt.Sprintf("Option ddl-retry-backoff has invalid value %q: must be a non-negative number of seconds", config.Get("ddl-retry-backoff")))
+	}
+	policy.backoff = time.Duration(backoff) * time.Second
+	return policy, nil
+}
+
+// run calls f, retrying it if it returns a transient error, until it succeeds,
+// returns a non-transient error, or the maximum number of retries has been
+// reached. The error from the final attempt is returned. Each retry is logged
+// as a warning, using desc to describe what is being retried.
+func (policy retryPolicy) run(desc string, f func() error) error {
+	backoff := policy.backoff
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.maxRetries || !isTransientError(err) {
+			return err
+		}
+		log.Warnf("%s failed with transient error, retrying in %s (retry %d of %d): %s", desc, backoff, attempt+1, policy.maxRetries, err)
+		retrySleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransientError returns true if err is a database error caused by lock
+// contention, such as a lock wait timeout or deadlock.
+func isTransientError(err error) bool {
+	return tengo.IsDatabaseError(err, transientErrorCodes...)
+}
@@ -0,0 +1,89 @@
+package applier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestRetryPolicyForConfig(t *testing.T) {
+	dir := getDir(t, "testdata/simple", "--ddl-retries=3 --ddl-retry-backoff=2")
+	policy, err := retryPolicyForConfig(dir.Config)
+	if err != nil {
+		t.Fatalf("Unexpected error from retryPolicyForConfig: %v", err)
+	}
+	if expected := (retryPolicy{maxRetries: 3, backoff: 2 * time.Second}); policy != expected {
+		t.Errorf("Expected policy %+v, instead found %+v", expected, policy)
+	}
+
+	for _, flags := range []string{"--ddl-retries=-1", "--ddl-retries=lots", "--ddl-retry-backoff=-5", "--ddl-retry-backoff=1s"} {
+		dir := getDir(t, "testdata/simple", flags)
+		if _, err := retryPolicyForConfig(dir.Config); err == nil {
+			t.Errorf("Expected error from retryPolicyForConfig with %s, but found none", flags)
+		} else if _, ok := err.(ConfigError); !ok {
+			t.Errorf("Expected error with %s to be a ConfigError, instead found %T", flags, err)
+		}
+	}
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	var sleeps []time.Duration
+	origSleep := retrySleep
+	retrySleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { retrySleep = origSleep }()
+
+	lockWait := &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"}
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+	dupe := &mysql.MySQLError{Number: 1050, Message: "Table 'foo' already exists"}
+
+	// fakeExec returns each of errs in turn, and then nil once exhausted
+	var calls int
+	fakeExec := func(errs ...error) func() error {
+		calls = 0
+		sleeps = nil
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}
+	}
+
+	// Transient errors followed by success
+	policy := retryPolicy{maxRetries: 3, backoff: time.Second}
+	if err := policy.run("test", fakeExec(lockWait, deadlock)); err != nil {
+		t.Errorf("Expected success after retries, instead found error %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, instead found %d", calls)
+	}
+	if len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != 2*time.Second {
+		t.Errorf("Unexpected backoff durations %v", sleeps)
+	}
+
+	// Retries exhausted: error from the final attempt is returned
+	if err := policy.run("test", fakeExec(lockWait, lockWait, deadlock, deadlock)); err != deadlock {
+		t.Errorf("Expected final deadlock error to be returned, instead found %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 attempts, instead found %d", calls)
+	}
+
+	// Non-transient errors are never retried
+	for _, nonTransient := range []error{dupe, errors.New("invalid connection")} {
+		if err := policy.run("test", fakeExec(nonTransient)); err != nonTransient {
+			t.Errorf("Expected error %v to be returned, instead found %v", nonTransient, err)
+		}
+		if calls != 1 || len(sleeps) != 0 {
+			t.Errorf("Expected non-transient error %v to not be retried, but found %d attempts", nonTransient, calls)
+		}
+	}
+
+	// Default policy doesn't retry at all
+	if err := (retryPolicy{}).run("test", fakeExec(deadlock)); err != deadlock || calls != 1 {
+		t.Errorf("Expected zero-value policy to not retry; found err=%v after %d attempts", err, calls)
+	}
+}
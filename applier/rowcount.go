@@ -0,0 +1,60 @@
+package applier
+
+import (
+	"fmt"
+
+	"github.com/skeema/tengo"
+)
+
+// rewritesRows returns true if objDiff is an ALTER TABLE which ClassifyAlter
+// expects to rebuild or copy the table on a server of the supplied flavor,
+// rewriting its existing row data.
+func rewritesRows(objDiff tengo.ObjectDiff, flavor tengo.Flavor) bool {
+	algo, _ := ClassifyAlter(objDiff, flavor)
+	return algo != AlterAlgorithmInPlace
+}
+
+// rowCountNote returns a comment describing how many rows of the target's
+// table will be rewritten by objDiff, or an empty string if objDiff does not
+// rewrite rows or the table is empty. By default the row count is an estimate
+// from information_schema, which is cheap to obtain but may be inaccurate.
+// With the exact-row-counts option, the rows are counted instead, which may
+// be expensive for large tables.
+func rowCountNote(objDiff tengo.ObjectDiff, target *Target) (string, error) {
+	if !rewritesRows(objDiff, target.Instance.Flavor()) {
+		return "", nil
+	}
+	tableName := objDiff.ObjectKey().Name
+	var rowCount int64
+	var approx string
+	if target.Dir.Config.GetBool("exact-row-counts") {
+		db, err := target.Instance.Connect(target.SchemaName, "")
+		if err != nil {
+			return "", err
+		}
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tengo.EscapeIdentifier(tableName))
+		if err := db.QueryRow(query).Scan(&rowCount); err != nil {
+			return "", err
+		}
+	} else {
+		// MySQL 8 caches information_schema table statistics by default, which
+		// would make the estimate even less accurate
+		var params string
+		if target.Instance.Flavor().HasDataDictionary() {
+			params = "information_schema_stats_expiry=0"
+		}
+		db, err := target.Instance.Connect("information_schema", params)
+		if err != nil {
+			return "", err
+		}
+		query := "SELECT IFNULL(table_rows, 0) FROM tables WHERE table_schema = ? AND table_name = ?"
+		if err := db.QueryRow(query, target.SchemaName, tableName).Scan(&rowCount); err != nil {
+			return "", err
+		}
+		approx = "approximately "
+	}
+	if rowCount == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("-- ALTER of %s rewrites %s%s\n", objDiff.ObjectKey(), approx, countAndNoun(int(rowCount), "row")), nil
+}
@@ -0,0 +1,128 @@
+package applier
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestRewritesRows(t *testing.T) {
+	makeTable := func(cols ...*tengo.Column) *tengo.Table {
+		return &tengo.Table{Name: "widgets", Engine: "InnoDB", Columns: cols}
+	}
+	id := &tengo.Column{Name: "id", TypeInDB: "int unsigned"}
+	name := &tengo.Column{Name: "name", TypeInDB: "varchar(40)", Nullable: true, CharSet: "utf8mb4", Collation: "utf8mb4_general_ci"}
+	virt := &tengo.Column{Name: "name_lower", TypeInDB: "varchar(40)", GenerationExpr: "lower(`name`)", Virtual: true, Nullable: true}
+	modified := func(col *tengo.Column, mod func(*tengo.Column)) *tengo.Column {
+		colCopy := *col
+		mod(&colCopy)
+		return &colCopy
+	}
+
+	cases := []struct {
+		from     *tengo.Table
+		to       *tengo.Table
+		expected bool
+	}{
+		{makeTable(id, name), makeTable(id, name), false},
+		{makeTable(id), makeTable(id, name), true},
+		{makeTable(id, name), makeTable(id), true},
+		{makeTable(id, name), makeTable(id, name, virt), false},
+		{makeTable(id, name, virt), makeTable(id, name), false},
+		{makeTable(id, name), makeTable(id, modified(name, func(c *tengo.Column) { c.Nullable = false; c.Default = "''" })), true},
+		{makeTable(id, name), makeTable(id, modified(name, func(c *tengo.Column) { c.TypeInDB = "varchar(80)" })), true},
+		{makeTable(id, name), makeTable(id, modified(name, func(c *tengo.Column) { c.Collation = "utf8mb4_bin" })), false},
+		{makeTable(id, name), makeTable(id, modified(name, func(c *tengo.Column) { c.Comment = "hello" })), false},
+		{makeTable(id, name), makeTable(id, modified(name, func(c *tengo.Column) { c.Default = "'x'" })), false},
+	}
+	for n, c := range cases {
+		diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: c.from, To: c.to}
+		if actual := rewritesRows(diff, tengo.FlavorMySQL57); actual != c.expected {
+			t.Errorf("Case %d: expected rewritesRows to return %t, instead found %t", n, c.expected, actual)
+		}
+	}
+
+	// Operations which are instant on newer flavors do not rewrite rows there
+	for _, flavor := range []tengo.Flavor{tengo.FlavorMariaDB104, {Vendor: tengo.VendorMySQL, Major: 8, Minor: 0, Patch: 29}} {
+		diff := &tengo.TableDiff{Type: tengo.DiffTypeAlter, From: makeTable(id, name), To: makeTable(name, id, modified(name, func(c *tengo.Column) { c.Name = "added" }))}
+		if expected := (flavor.Vendor == tengo.VendorMySQL); rewritesRows(diff, flavor) != expected {
+			t.Errorf("With flavor %s, expected rewritesRows to return %t for added and reordered columns", flavor, expected)
+		}
+	}
+
+	// Engine changes rebuild the table
+	from, to := makeTable(id), makeTable(id)
+	from.Engine = "MyISAM"
+	if !rewritesRows(&tengo.TableDiff{Type: tengo.DiffTypeAlter, From: from, To: to}, tengo.FlavorMySQL57) {
+		t.Error("Expected engine change to rewrite rows, but rewritesRows returned false")
+	}
+
+	// Other diff types never rewrite rows
+	for _, diff := range []*tengo.TableDiff{
+		{Type: tengo.DiffTypeCreate, To: to},
+		{Type: tengo.DiffTypeDrop, From: from},
+	} {
+		if rewritesRows(diff, tengo.FlavorMySQL57) {
+			t.Errorf("Expected rewritesRows to return false for %s, but it returned true", diff.DiffType())
+		}
+	}
+}
+
+func (s ApplierIntegrationSuite) TestRowCountNote(t *testing.T) {
+	if _, err := s.d[0].SourceSQL(filepath.Join("testdata", "setup.sql")); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	db, err := s.d[0].Connect("analytics", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	db.MustExec("CREATE TABLE rowtest (id int unsigned NOT NULL PRIMARY KEY, name varchar(20))")
+	db.MustExec("INSERT INTO rowtest (id, name) VALUES (1, 'a'), (2, 'b'), (3, NULL), (4, 'd'), (5, 'e')")
+	db.MustExec("ANALYZE TABLE rowtest")
+	db.MustExec("CREATE TABLE emptytest (id int unsigned NOT NULL PRIMARY KEY, name varchar(20))")
+	instSchema, err := s.d[0].Schema("analytics")
+	if err != nil {
+		t.Fatalf("Unable to obtain schema: %s", err)
+	}
+
+	// getDiff returns an ALTER TABLE diff which changes the name column of the
+	// supplied table to varchar(40)
+	getDiff := func(tableName string) tengo.ObjectDiff {
+		t.Helper()
+		fromTable := instSchema.Table(tableName)
+		toTable := *fromTable
+		toTable.Columns = []*tengo.Column{fromTable.Columns[0], {Name: "name", TypeInDB: "varchar(40)", Nullable: true, Default: "NULL", CharSet: fromTable.Columns[1].CharSet, Collation: fromTable.Columns[1].Collation, CollationIsDefault: fromTable.Columns[1].CollationIsDefault}}
+		toTable.CreateStatement = toTable.GeneratedCreateStatement(s.d[0].Flavor())
+		return tengo.NewAlterTable(fromTable, &toTable)
+	}
+	getTarget := func(exact bool) *Target {
+		configMap := map[string]string{"exact-row-counts": "0"}
+		if exact {
+			configMap["exact-row-counts"] = "1"
+		}
+		return &Target{
+			Instance:      s.d[0].Instance,
+			Dir:           &fs.Dir{Path: "/var/tmp/fakedir", Config: mybase.SimpleConfig(configMap)},
+			SchemaName:    "analytics",
+			DesiredSchema: &workspace.Schema{Schema: instSchema},
+		}
+	}
+
+	diff := getDiff("rowtest")
+	if note, err := rowCountNote(diff, getTarget(true)); err != nil || note != "-- ALTER of table `rowtest` rewrites 5 rows\n" {
+		t.Errorf("Unexpected result from rowCountNote with exact-row-counts: %q, %v", note, err)
+	}
+	if note, err := rowCountNote(diff, getTarget(false)); err != nil || !strings.HasPrefix(note, "-- ALTER of table `rowtest` rewrites approximately ") {
+		t.Errorf("Unexpected result from rowCountNote: %q, %v", note, err)
+	}
+	for _, exact := range []bool{false, true} {
+		if note, err := rowCountNote(getDiff("emptytest"), getTarget(exact)); err != nil || note != "" {
+			t.Errorf("Expected no note for empty table, instead found %q, %v", note, err)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package applier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+// Outcome describes the combined result of Run, Push, Diff, or DiffPartial.
+// The embedded Result is the sum of Results, plus any targets which were
+// skipped before they could be processed, for example due to dirs which could
+// not be parsed.
+type Outcome struct {
+	Result
+	Results        []Result // one per target processed or halted, in order of completion
+	MigrationPaths []string // files written due to the migration-format option, if any
+	PlanPath       string   // file written due to the plan option, if any
+}
+
+// Push performs diff/push operations on all targets mapped to by dirs and
+// their subdirs. Targets from all dirs are combined, so that operations on the
+// same instance are grouped together regardless of which dir they came from.
+// Configuration from the first dir controls behaviors which apply to the
+// entire operation, such as resume, audit-log, introspection-cache, and the
+// options used by Run. Statements are only executed if the dry-run option is
+// disabled; see also Diff.
+//
+// Once all targets have been processed successfully, any migration or plan file
+// requested by the configuration is written. A FileError is returned if any
+// requested file cannot be opened or written. See Run for other errors, and
+// for the handling of printer and ctx.
+func Push(ctx context.Context, dirs []*fs.Dir, printer *Printer) (outcome Outcome, err error) {
+	if len(dirs) == 0 {
+		return outcome, ConfigError("No directories supplied")
+	}
+	dir := dirs[0]
+	var targets []*Target
+	var skipCount int
+	for _, d := range dirs {
+		dirTargets, dirSkipCount := TargetsForDir(d, 5)
+		targets = append(targets, dirTargets...)
+		skipCount += dirSkipCount
+	}
+
+	// With resume, statements completed by a previous failed push are skipped,
+	// and the state file is removed once everything succeeds
+	var state *StateFile
+	if statePath := dir.Config.Get("resume"); statePath != "" && !dir.Config.GetBool("dry-run") {
+		if state, err = OpenStateFile(statePath); err != nil {
+			return outcome, FileError(fmt.Sprintf("Unable to use state file: %s", err))
+		}
+		defer state.Close()
+		if count := state.CompletedCount(); count > 0 {
+			log.Infof("Resuming push using state file %s, which lists %s", statePath, countAndNoun(count, "completed statement"))
+		}
+		for _, t := range targets {
+			t.State = state
+		}
+	}
+
+	// With audit-log, every executed statement is recorded in the audit log
+	audit, err := AuditLogForDir(dir)
+	if err != nil {
+		return outcome, err
+	}
+	defer audit.Close()
+	for _, t := range targets {
+		t.Audit = audit
+	}
+
+	// With introspection-cache, unchanged schemas are loaded from the cache file
+	// in dry-run mode, and the file is updated with any new introspections
+	cache, err := cacheFileForDir(dir)
+	if err != nil {
+		return outcome, err
+	}
+	defer closeCacheFile(cache)
+	for _, t := range targets {
+		t.Cache = cache
+	}
+
+	// With migration-format or plan in dry-run mode, generated DDL is also
+	// collected, and written to a file once all targets have been processed
+	// successfully
+	migration, plan, err := diffOutputFiles(dir, targets)
+	if err != nil {
+		return outcome, err
+	}
+	for _, t := range targets {
+		t.Migration, t.Plan = migration, plan
+	}
+
+	outcome, err = Run(ctx, dir, targets, skipCount, printer)
+	failed := err != nil || outcome.SkipCount+outcome.UnsupportedCount > 0
+	if state != nil && !failed {
+		if rmErr := state.Remove(); rmErr != nil {
+			log.Warnf("Push succeeded, but unable to remove state file: %s", rmErr)
+		}
+	}
+	if migration.Len() == 0 && plan.Len() == 0 {
+		return outcome, err
+	} else if failed {
+		log.Warnf("Not writing migration or plan file, since some operations were skipped due to errors")
+		return outcome, err
+	}
+	now := time.Now()
+	if migration.Len() > 0 {
+		if fpErr := migration.Fingerprint(); fpErr != nil {
+			return outcome, fmt.Errorf("Unable to write migration file: %s", fpErr)
+		}
+		paths, writeErr := migration.Write(dir.Config.Get("migration-dir"), dir.Config.Get("migration-description"), now)
+		for _, path := range paths {
+			log.Infof("Wrote migration file %s", path)
+		}
+		outcome.MigrationPaths = paths
+		if writeErr != nil {
+			return outcome, FileError(fmt.Sprintf("Unable to write migration file: %s", writeErr))
+		}
+	}
+	if plan.Len() > 0 {
+		path := dir.Config.Get("plan")
+		if writeErr := plan.Write(path, now); writeErr != nil {
+			return outcome, FileError(fmt.Sprintf("Unable to write plan file: %s", writeErr))
+		}
+		log.Infof("Wrote plan file %s; use `skeema apply %s` to execute it", path, path)
+		outcome.PlanPath = path
+	}
+	return outcome, nil
+}
+
+// Diff behaves like Push, but always enables the dry-run option, so that DDL
+// is generated but never executed. The option is enabled in the same manner as
+// `skeema diff`, by overriding its command-line value; since this is shared by
+// all configurations derived from the same mybase.Config, dry-run remains
+// enabled for any other dirs parsed from it.
+func Diff(ctx context.Context, dirs []*fs.Dir, printer *Printer) (Outcome, error) {
+	if len(dirs) == 0 {
+		return Outcome{}, ConfigError("No directories supplied")
+	}
+	dirs[0].Config.CLI.OptionValues["dry-run"] = "1"
+	for _, dir := range dirs {
+		dir.Config.MarkDirty()
+	}
+	return Push(ctx, dirs, printer)
+}
+
+// DiffPartial generates DDL to make the schemas mapped to by dir match the
+// objects in logicalSchema, leaving all other objects unchanged; see
+// TargetsForPartialSchema. Unlike Diff, the dry-run option must already be
+// enabled in dir's configuration, and migration and plan files are not
+// supported. See Run for the handling of printer, ctx, and errors.
+func DiffPartial(ctx context.Context, logicalSchema *fs.LogicalSchema, dir *fs.Dir, printer *Printer) (Outcome, error) {
+	if !dir.Config.GetBool("dry-run") {
+		return Outcome{}, ConfigError("DiffPartial requires the dry-run option to be enabled")
+	}
+	targets, skipCount := TargetsForPartialSchema(logicalSchema, dir)
+	cache, err := cacheFileForDir(dir)
+	if err != nil {
+		return Outcome{}, err
+	}
+	defer closeCacheFile(cache)
+	for _, t := range targets {
+		t.Cache = cache
+	}
+	return Run(ctx, dir, targets, skipCount, printer)
+}
+
+// Run performs diff/push operations on targets, using configuration from dir
+// for the concurrent-instances and max-failures options. The returned Outcome
+// includes skipCount, which should reflect any targets which were already
+// skipped due to non-fatal errors, such as those returned by TargetsForDir. If
+// printer is nil, a printer is selected based on the output-format and brief
+// options of dir.
+//
+// Cancelling ctx prevents any further targets from being processed, but does
+// not interrupt a target which is already being processed. In this situation,
+// the returned Outcome reflects all halted targets, and ctx.Err() is returned.
+// A ConfigError is returned if dir's configuration is invalid; any other error
+// is fatal, meaning that some targets may not have been processed or reported
+// in the Outcome.
+func Run(ctx context.Context, dir *fs.Dir, targets []*Target, skipCount int, printer *Printer) (outcome Outcome, err error) {
+	if printer == nil {
+		if printer, err = printerForDir(dir); err != nil {
+			return outcome, err
+		}
+	}
+	workerCount, err := dir.Config.GetInt("concurrent-instances")
+	if err == nil && workerCount < 1 {
+		err = fmt.Errorf("concurrent-instances cannot be less than 1")
+	}
+	if err != nil {
+		return outcome, ConfigError(err.Error())
+	}
+	maxFailures, err := dir.Config.GetInt("max-failures")
+	if err == nil && maxFailures < 0 {
+		err = fmt.Errorf("max-failures cannot be negative")
+	}
+	if err != nil {
+		return outcome, ConfigError(err.Error())
+	}
+
+	// Cancelling haltCtx causes workers to stop processing further targets; this
+	// occurs once max-failures instances have failed, if a worker returns a
+	// fatal error, or if the caller's ctx is cancelled
+	haltCtx, halt := context.WithCancel(ctx)
+	defer halt()
+	g, workerCtx := errgroup.WithContext(haltCtx)
+	tgchan := TargetGroupChan(targets)
+	results := make(chan Result)
+	for n := 0; n < workerCount; n++ {
+		g.Go(func() error {
+			return Worker(workerCtx, tgchan, results, printer)
+		})
+	}
+	go func() {
+		g.Wait()
+		close(results)
+	}()
+
+	outcome.Results = make([]Result, 0, len(targets))
+	failedInstances := make(map[string]bool)
+	for r := range results {
+		outcome.Results = append(outcome.Results, r)
+		if r.SkipCount > 0 && !failedInstances[r.Instance] {
+			failedInstances[r.Instance] = true
+			if len(failedInstances) == maxFailures {
+				log.Errorf("Halting remaining operations, since %d instance(s) have failed (max-failures=%d)", maxFailures, maxFailures)
+				halt()
+			}
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return outcome, err
+	}
+	logInstanceResults(outcome.Results)
+	outcome.Result = SumResults(outcome.Results)
+	outcome.SkipCount += skipCount
+	return outcome, ctx.Err()
+}
+
+// printerForDir returns a Printer reflecting the output-format and brief
+// options of dir.
+func printerForDir(dir *fs.Dir) (*Printer, error) {
+	briefMode := dir.Config.GetBool("dry-run") && dir.Config.GetBool("brief")
+	if outputFormat, err := dir.Config.GetEnum("output-format", "sql", "json"); err != nil {
+		return nil, ConfigError(err.Error())
+	} else if outputFormat == "json" && briefMode {
+		return nil, ConfigError("The output-format=json and brief options cannot be used together")
+	} else if outputFormat == "json" {
+		return NewJSONPrinter(), nil
+	}
+	return NewPrinter(briefMode), nil
+}
+
+// logInstanceResults logs the status of each instance, if results span more
+// than one instance.
+func logInstanceResults(results []Result) {
+	byInst := SumResultsByInstance(results)
+	if len(byInst) < 2 {
+		return
+	}
+	instances := make([]string, 0, len(byInst))
+	statusCounts := make(map[string]int)
+	for inst, r := range byInst {
+		instances = append(instances, inst)
+		statusCounts[r.Status()]++
+	}
+	sort.Strings(instances)
+	log.Infof("Results for %d instances: %d succeeded, %d failed, %d halted, %d with unsupported features",
+		len(instances), statusCounts["succeeded"], statusCounts["failed"], statusCounts["halted"], statusCounts["unsupported"])
+	for _, inst := range instances {
+		r := byInst[inst]
+		if summary := r.Summary(); summary != "" {
+			log.Infof("  %s: %s (%s)", inst, r.Status(), summary)
+		} else {
+			log.Infof("  %s: %s", inst, r.Status())
+		}
+	}
+}
+
+// diffOutputFiles returns a MigrationFile and/or Plan if dir's configuration
+// requests writing these files, or nil for either if not requested. These files
+// are only written in dry-run mode, without brief, and cannot be combined with
+// alter-wrapper, ddl-wrapper, or osc-tool since shell commands cannot be
+// represented in them. Since subdirectories may configure these options
+// separately, they are checked for dir and for the dir of every target.
+func diffOutputFiles(dir *fs.Dir, targets []*Target) (migration *MigrationFile, plan *Plan, err error) {
+	if !dir.Config.GetBool("dry-run") || dir.Config.GetBool("brief") {
+		return nil, nil, nil
+	}
+	format, err := dir.Config.GetEnum("migration-format", "none", "flyway", "liquibase", "sql")
+	if err != nil {
+		return nil, nil, ConfigError(err.Error())
+	}
+	split, err := dir.Config.GetEnum("migration-split", "none", "instance", "schema")
+	if err != nil {
+		return nil, nil, ConfigError(err.Error())
+	}
+	planPath := dir.Config.Get("plan")
+	if format == "none" && planPath == "" {
+		return nil, nil, nil
+	}
+	checkDirs := []*fs.Dir{dir}
+	for _, t := range targets {
+		checkDirs = append(checkDirs, t.Dir)
+	}
+	for _, d := range checkDirs {
+		if d.Config.Get("alter-wrapper") != "" || d.Config.Get("ddl-wrapper") != "" || d.Config.Get("osc-tool") != "none" {
+			return nil, nil, ConfigError(fmt.Sprintf("The migration-format and plan options cannot be combined with alter-wrapper, ddl-wrapper, or osc-tool, which are configured for %s", d))
+		}
+	}
+	if format != "none" {
+		migration = NewMigrationFile(format, split)
+	}
+	if planPath != "" {
+		plan = NewPlan(dir.Config.Get("environment"))
+	}
+	return migration, plan, nil
+}
+
+// AuditLogForDir returns the AuditLog configured by the audit-log option of
+// dir, or nil if none is configured or dir is in dry-run mode. A FileError is
+// returned if the audit log cannot be opened.
+func AuditLogForDir(dir *fs.Dir) (*AuditLog, error) {
+	auditPath := dir.Config.Get("audit-log")
+	if auditPath == "" || dir.Config.GetBool("dry-run") {
+		return nil, nil
+	}
+	audit, err := OpenAuditLog(auditPath)
+	if err != nil {
+		return nil, FileError(fmt.Sprintf("Unable to use audit log: %s", err))
+	}
+	return audit, nil
+}
+
+// cacheFileForDir returns the CacheFile configured by the introspection-cache
+// option of dir, or nil if none is configured.
+func cacheFileForDir(dir *fs.Dir) (*CacheFile, error) {
+	cachePath := dir.Config.Get("introspection-cache")
+	if cachePath == "" {
+		return nil, nil
+	}
+	cache, err := OpenCacheFile(cachePath)
+	if err != nil {
+		return nil, FileError(fmt.Sprintf("Unable to use introspection cache: %s", err))
+	}
+	return cache, nil
+}
+
+// closeCacheFile writes any changes to cache, logging a warning upon failure,
+// since the cache only affects performance.
+func closeCacheFile(cache *CacheFile) {
+	if err := cache.Close(); err != nil {
+		log.Warnf("Unable to write introspection cache %s: %s", cache.Path(), err)
+	}
+}
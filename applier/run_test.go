@@ -0,0 +1,215 @@
+package applier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/linter"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// getRunDir returns a dir for dirPath, using a configuration with the full set
+// of options from AddCommandOptions, as a program embedding Diff or Push would.
+func getRunDir(t *testing.T, dirPath, cliFlags string) *fs.Dir {
+	t.Helper()
+	cmd := mybase.NewCommand("runtest", "", "", nil)
+	util.AddGlobalOptions(cmd)
+	AddCommandOptions(cmd)
+	linter.AddCommandOptions(cmd)
+	cmd.AddArg("environment", "production", false)
+	cfg := mybase.ParseFakeCLI(t, cmd, fmt.Sprintf("runtest %s", cliFlags))
+	dir, err := fs.ParseDir(dirPath, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %s", err)
+	}
+	return dir
+}
+
+func TestDiffPushConfig(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch/empty")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+	ctx := context.Background()
+
+	// A dir without any targets has no differences, and Diff forces dry-run
+	dir := getRunDir(t, "testdata/.scratch/empty", "")
+	outcome, err := Diff(ctx, []*fs.Dir{dir}, nil)
+	if err != nil || outcome.Differences || outcome.SkipCount > 0 || len(outcome.Results) > 0 {
+		t.Errorf("Unexpected result from Diff: %+v, %v", outcome, err)
+	}
+	if !dir.Config.GetBool("dry-run") {
+		t.Error("Expected Diff to enable dry-run, but it did not")
+	}
+	if _, err := Push(ctx, nil, nil); err == nil {
+		t.Error("Expected error from Push with no dirs, but err was nil")
+	}
+
+	// Invalid configuration should be reported as ConfigError or FileError
+	cases := map[string]bool{
+		"--dry-run --migration-format=sql --osc-tool=gh-ost": true,
+		"--dry-run --migration-format=xml":                   true,
+		"--dry-run --brief --output-format=json":             true,
+		"--output-format=yaml":                               true,
+		"--concurrent-instances=0":                           true,
+		"--max-failures=-1":                                  true,
+		"--audit-log=testdata/.scratch/missing/audit.log":    false,
+		"--resume=testdata/.scratch/missing/state.json":      false,
+	}
+	for flags, expectConfigError := range cases {
+		_, err := Push(ctx, []*fs.Dir{getRunDir(t, "testdata/.scratch/empty", flags)}, nil)
+		if _, ok := err.(ConfigError); expectConfigError && !ok {
+			t.Errorf("With flags %q, expected ConfigError, instead found %T %v", flags, err, err)
+		} else if _, ok := err.(FileError); !expectConfigError && !ok {
+			t.Errorf("With flags %q, expected FileError, instead found %T %v", flags, err, err)
+		}
+	}
+	if _, err := DiffPartial(ctx, &fs.LogicalSchema{}, getRunDir(t, "testdata/.scratch/empty", ""), nil); err == nil {
+		t.Error("Expected error from DiffPartial without dry-run, but err was nil")
+	}
+}
+
+func TestDiffOutputFiles(t *testing.T) {
+	fs.WriteTestFile(t, "testdata/.scratch/wrapped/.skeema", "schema=product\n")
+	fs.WriteTestFile(t, "testdata/.scratch/wrapped/sub/.skeema", "alter-wrapper=\"/bin/echo {CLAUSES}\"\n")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	getTargets := func(flags string) (*fs.Dir, []*Target) {
+		dir := getRunDir(t, "testdata/.scratch/wrapped", flags)
+		subdirs, err := dir.Subdirs()
+		if err != nil || len(subdirs) != 1 {
+			t.Fatalf("Unexpected result from Subdirs: %v, %v", subdirs, err)
+		}
+		return dir, []*Target{
+			{Instance: inst, Dir: dir, SchemaName: "product"},
+			{Instance: inst, Dir: subdirs[0], SchemaName: "product"},
+		}
+	}
+
+	// A wrapper configured only in a subdir must still be rejected
+	for _, flags := range []string{"--dry-run --migration-format=sql", "--dry-run --plan=testdata/.scratch/out.plan"} {
+		dir, targets := getTargets(flags)
+		if _, _, err := diffOutputFiles(dir, targets); err == nil {
+			t.Errorf("With flags %q, expected error from wrapper in subdir, but err was nil", flags)
+		} else if _, ok := err.(ConfigError); !ok || !strings.Contains(err.Error(), "sub") {
+			t.Errorf("With flags %q, expected ConfigError naming subdir, instead found %T %v", flags, err, err)
+		}
+		if _, _, err := diffOutputFiles(dir, targets[0:1]); err != nil {
+			t.Errorf("With flags %q, unexpected error without subdir target: %v", flags, err)
+		}
+	}
+
+	// Without any file requested, wrappers are permitted
+	dir, targets := getTargets("--dry-run")
+	if migration, plan, err := diffOutputFiles(dir, targets); migration != nil || plan != nil || err != nil {
+		t.Errorf("Unexpected result from diffOutputFiles: %v, %v, %v", migration, plan, err)
+	}
+}
+
+func TestRunHalted(t *testing.T) {
+	// With a cancelled context, Run should not connect to any instance, and
+	// should instead report all targets as halted
+	dir := getRunDir(t, "testdata/simple", "--concurrent-instances=2")
+	var targets []*Target
+	for _, host := range []string{"127.0.0.1:1", "127.0.0.1:2"} {
+		inst, err := tengo.NewInstance("mysql", "root:password@tcp("+host+")/")
+		if err != nil {
+			t.Fatalf("Unexpected error from NewInstance: %s", err)
+		}
+		targets = append(targets, &Target{Instance: inst, Dir: dir, SchemaName: "one"}, &Target{Instance: inst, Dir: dir, SchemaName: "two"})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outcome, err := Run(ctx, dir, targets, 1, NewRecordingPrinter())
+	if err != context.Canceled {
+		t.Errorf("Expected Run to return context.Canceled, instead found %v", err)
+	}
+	if outcome.HaltedCount != 4 || outcome.SkipCount != 1 || len(outcome.Results) != 4 {
+		t.Errorf("Unexpected outcome from Run: %+v", outcome)
+	}
+}
+
+func TestRunUnreachable(t *testing.T) {
+	fs.WriteTestFile(t, "testdata/.scratch/shards/.skeema", "host=placeholder\nhost-wrapper='cat testdata/.scratch/hosts'\nschema=product\n")
+	fs.WriteTestFile(t, "testdata/.scratch/hosts", "127.0.0.1:1\n127.0.0.1:2\n")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	// Each unreachable shard should be reported as a failed instance, rather
+	// than only being counted as a skipped dir
+	dir := getRunDir(t, "testdata/.scratch/shards", "--skip-lint")
+	outcome, err := Diff(context.Background(), []*fs.Dir{dir}, NewRecordingPrinter())
+	if err != nil {
+		t.Fatalf("Unexpected error from Diff: %v", err)
+	}
+	if outcome.SkipCount != 2 || len(outcome.Results) != 2 {
+		t.Errorf("Unexpected outcome from Diff: %+v", outcome)
+	}
+	byInst := SumResultsByInstance(outcome.Results)
+	for _, inst := range []string{"127.0.0.1:1", "127.0.0.1:2"} {
+		if r, ok := byInst[inst]; !ok || r.Status() != "failed" {
+			t.Errorf("Expected instance %s to be reported as failed, instead found %+v", inst, r)
+		}
+	}
+
+	// Introspection failures should likewise be skipped, rather than halting
+	// processing of other targets
+	var targets []*Target
+	for _, host := range []string{"127.0.0.1:1", "127.0.0.1:2"} {
+		inst, err := tengo.NewInstance("mysql", "root:password@tcp("+host+")/")
+		if err != nil {
+			t.Fatalf("Unexpected error from NewInstance: %s", err)
+		}
+		targets = append(targets, &Target{Instance: inst, Dir: dir, SchemaName: "product"})
+	}
+	outcome, err = Run(context.Background(), dir, targets, 0, NewRecordingPrinter())
+	if err != nil || outcome.SkipCount != 2 || len(outcome.Results) != 2 {
+		t.Errorf("Unexpected result from Run: %+v, %v", outcome, err)
+	}
+}
+
+func (s ApplierIntegrationSuite) TestDiffPush(t *testing.T) {
+	setupHostList(t, s.d[0].Instance)
+	defer cleanupHostList(t)
+	if _, err := s.d[0].SourceSQL("testdata/setup.sql"); err != nil {
+		t.Fatalf("Unexpected error from SourceSQL: %s", err)
+	}
+	ctx := context.Background()
+
+	// Each generated statement should be recorded by the printer, but not
+	// executed by Diff
+	printer := NewRecordingPrinter()
+	outcome, err := Diff(ctx, []*fs.Dir{getRunDir(t, "testdata/simple", "--skip-lint --allow-unsafe")}, printer)
+	if err != nil || outcome.SkipCount+outcome.UnsupportedCount > 0 {
+		t.Fatalf("Unexpected result from Diff: %+v, %v", outcome, err)
+	} else if !outcome.Differences || len(printer.Operations()) == 0 {
+		t.Fatalf("Expected Diff to find differences, instead found outcome %+v with %d operations", outcome, len(printer.Operations()))
+	}
+	diffOps := printer.Operations()
+	for _, op := range diffOps {
+		if op.Instance != s.d[0].Instance.String() || op.Statement == "" || op.Skipped {
+			t.Errorf("Unexpected operation from Diff: %+v", op)
+		}
+	}
+
+	// Push should execute the same statements, after which there should be no
+	// remaining differences
+	printer = NewRecordingPrinter()
+	outcome, err = Push(ctx, []*fs.Dir{getRunDir(t, "testdata/simple", "--skip-lint --allow-unsafe")}, printer)
+	if err != nil || outcome.SkipCount+outcome.UnsupportedCount > 0 || !outcome.Differences {
+		t.Fatalf("Unexpected result from Push: %+v, %v", outcome, err)
+	} else if pushOps := printer.Operations(); len(pushOps) != len(diffOps) {
+		t.Errorf("Expected Push to run %d statements, instead found %d", len(diffOps), len(pushOps))
+	}
+	printer = NewRecordingPrinter()
+	outcome, err = Diff(ctx, []*fs.Dir{getRunDir(t, "testdata/simple", "--skip-lint")}, printer)
+	if err != nil || outcome.Differences || len(printer.Operations()) > 0 {
+		t.Errorf("Expected no differences after Push, instead found %+v, %v, %v", outcome, err, printer.Operations())
+	}
+}
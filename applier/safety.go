@@ -0,0 +1,256 @@
+package applier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// Safety classifies a DDL statement by its potential for data loss, for
+// purposes of grouping output for review.
+type Safety int
+
+// Constants enumerating Safety values, in the order that groups are output
+const (
+	SafetySafeCreate  Safety = iota // CREATE statements
+	SafetySafeAlter                 // ALTER statements, and anything else which is not destructive
+	SafetyDestructive               // statements forbidden without allow-unsafe, regardless of size
+)
+
+// String returns a human-readable label for the group of statements.
+func (s Safety) String() string {
+	switch s {
+	case SafetySafeCreate:
+		return "Safe CREATEs"
+	case SafetySafeAlter:
+		return "Safe ALTERs"
+	default:
+		return "Destructive operations"
+	}
+}
+
+// ClassifySafety determines whether diff is destructive, using the same
+// classification as the allow-unsafe option: a diff is destructive if it
+// would be forbidden without allow-unsafe, including if it reduces the
+// precision of any numeric column. The other fields of mods are used
+// as-is, and the size of the table is not considered, so for example
+// safe-below-size has no effect on the classification. Non-destructive diffs
+// are classified as SafetySafeCreate or SafetySafeAlter based on their type.
+func ClassifySafety(diff tengo.ObjectDiff, mods tengo.StatementModifiers) Safety {
+	mods.AllowUnsafe = false
+	if _, err := diff.Statement(mods); tengo.IsForbiddenDiff(err) || len(reducedPrecisionColumns(diff)) > 0 {
+		return SafetyDestructive
+	} else if diff.DiffType() == tengo.DiffTypeCreate {
+		return SafetySafeCreate
+	}
+	return SafetySafeAlter
+}
+
+// groupDDLBySafety reorders ddls by safety, for output only, and labels each
+// statement with its group so that the Printer outputs a header before each
+// group. The relative order of statements within each group is unchanged,
+// and any backfill statements remain immediately before their ALTER TABLE.
+// Since ordering between groups may not reflect dependencies between
+// statements, the returned statements must not be executed.
+func groupDDLBySafety(ddls []*DDLStatement) []*DDLStatement {
+	result := make([]*DDLStatement, len(ddls))
+	copy(result, ddls)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].safety < result[j].safety
+	})
+	for _, ddl := range result {
+		ddl.groupLabel = ddl.safety.String()
+	}
+	return result
+}
+
+// UnsafeClass identifies a kind of destructive operation, for purposes of the
+// unsafe-ops option, and for describing destructive statements in output.
+type UnsafeClass string
+
+// Constants enumerating UnsafeClass values
+const (
+	UnsafeDropTable     UnsafeClass = "drop-table"     // DROP TABLE
+	UnsafeDropColumn    UnsafeClass = "drop-column"    // ALTER TABLE dropping a stored column
+	UnsafeColumnType    UnsafeClass = "column-type"    // ALTER TABLE changing a column's type in a way that may lose data
+	UnsafeColumnCharSet UnsafeClass = "column-charset" // ALTER TABLE changing a column's character set
+	UnsafeEngine        UnsafeClass = "engine"         // ALTER TABLE changing the storage engine
+	UnsafeDropPartition UnsafeClass = "drop-partition" // ALTER TABLE dropping partitions
+	UnsafeDropView      UnsafeClass = "drop-view"      // DROP VIEW
+	UnsafeDropRoutine   UnsafeClass = "drop-routine"   // DROP PROCEDURE or DROP FUNCTION, including to re-create a modified routine
+	UnsafeDropTrigger   UnsafeClass = "drop-trigger"   // DROP TRIGGER, including to re-create a modified trigger
+	UnsafeDropEvent     UnsafeClass = "drop-event"     // DROP EVENT
+	UnsafeDropSequence  UnsafeClass = "drop-sequence"  // DROP SEQUENCE
+	UnsafeDeleteRows    UnsafeClass = "delete-rows"    // DELETE of rows from a table listed in manage-data
+	UnsafeOther         UnsafeClass = "other"          // any other destructive operation; only permitted by allow-unsafe
+)
+
+// permittableUnsafeClasses lists the UnsafeClass values which may be supplied
+// to the unsafe-ops option.
+var permittableUnsafeClasses = []UnsafeClass{
+	UnsafeDropTable,
+	UnsafeDropColumn,
+	UnsafeColumnType,
+	UnsafeColumnCharSet,
+	UnsafeEngine,
+	UnsafeDropPartition,
+	UnsafeDropView,
+	UnsafeDropRoutine,
+	UnsafeDropTrigger,
+	UnsafeDropEvent,
+	UnsafeDropSequence,
+	UnsafeDeleteRows,
+}
+
+// ClassifyUnsafe returns the classes of destructive operations performed by
+// diff, in sorted order, or nil if ClassifySafety does not consider diff to be
+// destructive. If diff is destructive for a reason which cannot be attributed
+// to a specific class, UnsafeOther is returned.
+func ClassifyUnsafe(diff tengo.ObjectDiff, mods tengo.StatementModifiers) []UnsafeClass {
+	if ClassifySafety(diff, mods) != SafetyDestructive {
+		return nil
+	}
+	seen := make(map[UnsafeClass]bool)
+	for _, class := range unsafeClasses(diff) {
+		seen[class] = true
+	}
+	if len(seen) == 0 {
+		return []UnsafeClass{UnsafeOther}
+	}
+	classes := make([]UnsafeClass, 0, len(seen))
+	for class := range seen {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i] < classes[j]
+	})
+	return classes
+}
+
+// unsafeClasses returns the classes of potentially destructive operations in
+// diff, possibly with duplicates, without regard to whether diff is actually
+// forbidden without allow-unsafe.
+func unsafeClasses(diff tengo.ObjectDiff) (classes []UnsafeClass) {
+	if diff.DiffType() == tengo.DiffTypeDrop {
+		switch diff.ObjectKey().Type {
+		case tengo.ObjectTypeTable:
+			return []UnsafeClass{UnsafeDropTable}
+		case fs.ObjectTypeView:
+			return []UnsafeClass{UnsafeDropView}
+		case tengo.ObjectTypeProc, tengo.ObjectTypeFunc:
+			return []UnsafeClass{UnsafeDropRoutine}
+		case fs.ObjectTypeTrigger:
+			return []UnsafeClass{UnsafeDropTrigger}
+		case fs.ObjectTypeEvent:
+			return []UnsafeClass{UnsafeDropEvent}
+		case fs.ObjectTypeSequence:
+			return []UnsafeClass{UnsafeDropSequence}
+		case objectTypeData:
+			return []UnsafeClass{UnsafeDeleteRows}
+		}
+		return nil
+	}
+	switch diff := diff.(type) {
+	case *tengo.TableDiff:
+		if diff.Type == tengo.DiffTypeAlter && diff.From != nil && diff.To != nil {
+			classes = tableUnsafeClasses(diff.From, diff.To)
+		}
+	case *partitionListDiff:
+		if diff.unsafe() {
+			classes = []UnsafeClass{UnsafeDropPartition}
+		}
+	case *conversionDiff:
+		if diff.unsafe() {
+			classes = []UnsafeClass{UnsafeColumnCharSet}
+		}
+	case *combinedTableDiff:
+		for _, subDiff := range diff.diffs {
+			classes = append(classes, unsafeClasses(subDiff)...)
+		}
+	}
+	return classes
+}
+
+// tableUnsafeClasses returns the classes of potentially destructive operations
+// in an ALTER TABLE changing from into to.
+func tableUnsafeClasses(from, to *tengo.Table) (classes []UnsafeClass) {
+	toCols := to.ColumnsByName()
+	for _, col := range from.Columns {
+		if _, ok := toCols[col.Name]; !ok && (tengo.DropColumn{Column: col}).Unsafe() {
+			classes = append(classes, UnsafeDropColumn)
+		}
+	}
+	fromCols := from.ColumnsByName()
+	for _, col := range to.Columns {
+		fromCol, ok := fromCols[col.Name]
+		if !ok || fromCol.Virtual {
+			continue
+		}
+		if fromCol.CharSet != col.CharSet {
+			classes = append(classes, UnsafeColumnCharSet)
+		} else if (tengo.ModifyColumn{Table: to, OldColumn: fromCol, NewColumn: col}).Unsafe() || precisionReduced(fromCol.TypeInDB, col.TypeInDB) {
+			classes = append(classes, UnsafeColumnType)
+		}
+	}
+	if !strings.EqualFold(from.Engine, to.Engine) {
+		classes = append(classes, UnsafeEngine)
+	}
+	if from.Partitioning != nil && to.Partitioning != nil {
+		toParts := make(map[string]bool, len(to.Partitioning.Partitions))
+		for _, part := range to.Partitioning.Partitions {
+			toParts[part.Name] = true
+		}
+		for _, part := range from.Partitioning.Partitions {
+			if !toParts[part.Name] {
+				classes = append(classes, UnsafeDropPartition)
+				break
+			}
+		}
+	}
+	return classes
+}
+
+// unsafeOpsForConfig returns the classes of destructive operations permitted
+// by the unsafe-ops option in config. An error is returned if any value is not
+// a recognized class.
+func unsafeOpsForConfig(config *mybase.Config) (map[UnsafeClass]bool, error) {
+	permittable := make(map[UnsafeClass]bool, len(permittableUnsafeClasses))
+	names := make([]string, len(permittableUnsafeClasses))
+	for n, class := range permittableUnsafeClasses {
+		permittable[class] = true
+		names[n] = string(class)
+	}
+	permitted := make(map[UnsafeClass]bool)
+	for _, value := range config.GetSlice("unsafe-ops", ',', true) {
+		class := UnsafeClass(strings.ToLower(value))
+		if !permittable[class] {
+			return nil, ConfigError(fmt.Sprintf("Option unsafe-ops has invalid value %q: must be a comma-separated list of these values: %s", value, strings.Join(names, ", ")))
+		}
+		permitted[class] = true
+	}
+	return permitted, nil
+}
+
+// joinUnsafeClasses returns classes as a comma-separated string.
+func joinUnsafeClasses(classes []UnsafeClass) string {
+	names := make([]string, len(classes))
+	for n, class := range classes {
+		names[n] = string(class)
+	}
+	return strings.Join(names, ",")
+}
+
+// permitSuggestion returns a sentence describing the options which would
+// permit a statement performing the supplied classes of destructive operations.
+func permitSuggestion(classes []UnsafeClass) string {
+	for _, class := range classes {
+		if class == UnsafeOther {
+			return "Use --allow-unsafe or --safe-below-size to permit this operation"
+		}
+	}
+	return fmt.Sprintf("Use --allow-unsafe, --unsafe-ops=%s, or --safe-below-size to permit this operation", joinUnsafeClasses(classes))
+}
@@ -0,0 +1,176 @@
+package applier
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestClassifySafety(t *testing.T) {
+	makeTable := func(colNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for _, name := range colNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: name, TypeInDB: "int(11)", Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	routine := &tengo.Routine{Name: "func1", Type: tengo.ObjectTypeFunc, Body: "RETURN 1", ReturnDataType: "int(11)", Definer: "root@%", SQLDataAccess: "CONTAINS SQL", SecurityType: "DEFINER"}
+	cases := []struct {
+		diff     tengo.ObjectDiff
+		expected Safety
+	}{
+		{tengo.NewCreateTable(makeTable("a")), SafetySafeCreate},
+		{tengo.NewAlterTable(makeTable("a"), makeTable("a", "b")), SafetySafeAlter},
+		{tengo.NewAlterTable(makeTable("a", "b"), makeTable("a")), SafetyDestructive},
+		{tengo.NewDropTable(makeTable("a")), SafetyDestructive},
+		{&tengo.RoutineDiff{To: routine}, SafetySafeCreate},
+		{&tengo.RoutineDiff{From: routine}, SafetyDestructive},
+		{&tengo.DatabaseDiff{From: &tengo.Schema{Name: "foo", CharSet: "latin1"}, To: &tengo.Schema{Name: "foo", CharSet: "utf8mb4"}}, SafetySafeAlter},
+		{&tablespaceDiff{table: makeTable("a"), to: "ts1"}, SafetySafeAlter},
+	}
+	for n, c := range cases {
+		// allow-unsafe should have no effect on classification
+		for _, allowUnsafe := range []bool{false, true} {
+			mods := tengo.StatementModifiers{AllowUnsafe: allowUnsafe}
+			if actual := ClassifySafety(c.diff, mods); actual != c.expected {
+				t.Errorf("cases[%d]: expected %s, instead found %s", n, c.expected, actual)
+			}
+		}
+	}
+}
+
+func TestGroupDDLBySafety(t *testing.T) {
+	makeDDL := func(stmt string, safety Safety) *DDLStatement {
+		return &DDLStatement{stmt: stmt, safety: safety}
+	}
+	ddls := []*DDLStatement{
+		makeDDL("ALTER TABLE a DROP COLUMN x", SafetyDestructive),
+		makeDDL("CREATE TABLE b (id int)", SafetySafeCreate),
+		makeDDL("UPDATE c SET y = 0 WHERE y IS NULL", SafetySafeAlter),
+		makeDDL("ALTER TABLE c MODIFY COLUMN y int NOT NULL DEFAULT 0", SafetySafeAlter),
+		makeDDL("DROP TABLE d", SafetyDestructive),
+		makeDDL("CREATE TABLE e (id int)", SafetySafeCreate),
+	}
+	expected := []string{
+		"CREATE TABLE b (id int)",
+		"CREATE TABLE e (id int)",
+		"UPDATE c SET y = 0 WHERE y IS NULL",
+		"ALTER TABLE c MODIFY COLUMN y int NOT NULL DEFAULT 0",
+		"ALTER TABLE a DROP COLUMN x",
+		"DROP TABLE d",
+	}
+	grouped := groupDDLBySafety(ddls)
+	if len(grouped) != len(expected) {
+		t.Fatalf("Expected %d statements, instead found %d", len(expected), len(grouped))
+	}
+	for n, ddl := range grouped {
+		if ddl.stmt != expected[n] {
+			t.Errorf("Expected grouped[%d] to be %q, instead found %q", n, expected[n], ddl.stmt)
+		}
+		if ddl.groupLabel != ddl.safety.String() {
+			t.Errorf("Expected grouped[%d] to have label %q, instead found %q", n, ddl.safety, ddl.groupLabel)
+		}
+	}
+	if ddls[0].stmt != "ALTER TABLE a DROP COLUMN x" {
+		t.Error("Expected original slice to be left in its original order")
+	}
+}
+
+func TestClassifyUnsafe(t *testing.T) {
+	makeTable := func(colTypes ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for n, colType := range colTypes {
+			col := &tengo.Column{Name: string('a' + rune(n)), TypeInDB: colType, Nullable: true, Default: "NULL"}
+			if colType == "varchar(20)" || colType == "varchar(10)" {
+				col.CharSet, col.Collation, col.CollationIsDefault = "latin1", "latin1_swedish_ci", true
+			}
+			table.Columns = append(table.Columns, col)
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	utf8Table := makeTable("int(11)", "varchar(20)")
+	utf8Table.Columns[1].CharSet, utf8Table.Columns[1].Collation = "utf8mb4", "utf8mb4_general_ci"
+	utf8Table.CreateStatement = utf8Table.GeneratedCreateStatement(tengo.FlavorUnknown)
+	myisamTable := makeTable("int(11)")
+	myisamTable.Engine = "MyISAM"
+	myisamTable.CreateStatement = myisamTable.GeneratedCreateStatement(tengo.FlavorUnknown)
+	routine := &tengo.Routine{Name: "func1", Type: tengo.ObjectTypeFunc, Body: "RETURN 1", ReturnDataType: "int(11)", Definer: "root@%", SQLDataAccess: "CONTAINS SQL", SecurityType: "DEFINER"}
+	dataTable := makeTable("int(11)")
+	dataTable.PrimaryKey = &tengo.Index{PrimaryKey: true, Parts: []tengo.IndexPart{{ColumnName: "a"}}}
+	data := &workspace.TableData{Table: dataTable, Columns: []string{"a"}}
+
+	cases := []struct {
+		diff     tengo.ObjectDiff
+		expected []UnsafeClass
+	}{
+		{tengo.NewCreateTable(makeTable("int(11)")), nil},
+		{tengo.NewAlterTable(makeTable("int(11)"), makeTable("int(11)", "int(11)")), nil},
+		{tengo.NewAlterTable(makeTable("int(11)", "int(11)"), makeTable("int(11)")), []UnsafeClass{UnsafeDropColumn}},
+		{tengo.NewAlterTable(makeTable("int(11)", "varchar(20)"), makeTable("int(11)", "varchar(10)")), []UnsafeClass{UnsafeColumnType}},
+		{tengo.NewAlterTable(makeTable("int(11)", "varchar(10)"), makeTable("int(11)", "varchar(20)")), nil},
+		{tengo.NewAlterTable(makeTable("decimal(10,2)"), makeTable("decimal(10,4)")), []UnsafeClass{UnsafeColumnType}},
+		{tengo.NewAlterTable(makeTable("int(11)", "varchar(20)"), utf8Table), []UnsafeClass{UnsafeColumnCharSet}},
+		{tengo.NewAlterTable(makeTable("bigint(20)", "int(11)"), makeTable("int(11)")), []UnsafeClass{UnsafeColumnType, UnsafeDropColumn}},
+		{tengo.NewAlterTable(makeTable("int(11)"), myisamTable), []UnsafeClass{UnsafeEngine}},
+		{tengo.NewDropTable(makeTable("int(11)")), []UnsafeClass{UnsafeDropTable}},
+		{&tengo.RoutineDiff{To: routine}, nil},
+		{&tengo.RoutineDiff{From: routine}, []UnsafeClass{UnsafeDropRoutine}},
+		{&dataDiff{data: data, to: []sql.NullString{{String: "1", Valid: true}}}, nil},
+		{&dataDiff{data: data, from: []sql.NullString{{String: "1", Valid: true}}}, []UnsafeClass{UnsafeDeleteRows}},
+		{&tengo.DatabaseDiff{From: &tengo.Schema{Name: "foo"}}, []UnsafeClass{UnsafeOther}},
+	}
+	for n, c := range cases {
+		// allow-unsafe should have no effect on classification
+		for _, allowUnsafe := range []bool{false, true} {
+			mods := tengo.StatementModifiers{AllowUnsafe: allowUnsafe}
+			if actual := ClassifyUnsafe(c.diff, mods); !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("cases[%d]: expected %v, instead found %v", n, c.expected, actual)
+			}
+		}
+	}
+}
+
+func TestUnsafeOpsForConfig(t *testing.T) {
+	cfg := mybase.SimpleConfig(map[string]string{"unsafe-ops": "drop-column, Column-Type"})
+	permitted, err := unsafeOpsForConfig(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from unsafeOpsForConfig: %v", err)
+	}
+	expected := map[UnsafeClass]bool{UnsafeDropColumn: true, UnsafeColumnType: true}
+	if !reflect.DeepEqual(permitted, expected) {
+		t.Errorf("Expected %v, instead found %v", expected, permitted)
+	}
+	for _, value := range []string{"drop-column,drop-database", "other"} {
+		cfg = mybase.SimpleConfig(map[string]string{"unsafe-ops": value})
+		if _, err := unsafeOpsForConfig(cfg); err == nil {
+			t.Errorf("Expected error from unsafe-ops=%q, but err was nil", value)
+		} else if _, ok := err.(ConfigError); !ok {
+			t.Errorf("Expected ConfigError from unsafe-ops=%q, instead found %T", value, err)
+		}
+	}
+
+	if actual, expected := permitSuggestion([]UnsafeClass{UnsafeDropColumn, UnsafeDropTable}), "Use --allow-unsafe, --unsafe-ops=drop-column,drop-table, or --safe-below-size to permit this operation"; actual != expected {
+		t.Errorf("Expected permitSuggestion to return %q, instead found %q", expected, actual)
+	}
+	if actual, expected := permitSuggestion([]UnsafeClass{UnsafeOther}), "Use --allow-unsafe or --safe-below-size to permit this operation"; actual != expected {
+		t.Errorf("Expected permitSuggestion to return %q, instead found %q", expected, actual)
+	}
+}
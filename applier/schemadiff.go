@@ -0,0 +1,270 @@
+package applier
+
+import (
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// schemaDiffOptions configures diffSchemas.
+type schemaDiffOptions struct {
+	mods                 tengo.StatementModifiers
+	typeOpts             introspect.Options // only ObjectTypes is used
+	external             externalObjects
+	includeSystemColumns bool
+	reorderColumns       bool
+	managePartitionList  bool
+	allowDropPartition   bool
+	allowDropTrigger     bool
+	defaultEncryption    string // "Y" or "N", the default encryption of the from side's schema
+
+	// target is nil when diffing two workspace schemas. Otherwise, normalization
+	// which depends on the target is also performed: partial targets, the
+	// convert-character-set and manage-data options, and registered Normalizers.
+	target *Target
+}
+
+// diffSchemas normalizes both sides of a diff, extracts changes which tengo
+// does not support so that they can be handled separately, and then diffs the
+// remaining tables and routines via tengo. The returned ObjectDiffs include all
+// changes, but have not been ordered yet; see orderDiffs. The tengo diff is also
+// returned separately, for use in verification. from may be nil if its schema
+// does not exist. from and to, but not the objects in them, may be modified.
+//
+// Result reflects any operations which were skipped during normalization. A
+// non-nil error indicates that the diff could not be computed at all.
+func diffSchemas(from, to *tengo.Schema, fromObjs, toObjs introspect.Objects, opts schemaDiffOptions) (diff *tengo.SchemaDiff, objDiffs []tengo.ObjectDiff, result Result, err error) {
+	t, mods := opts.target, opts.mods
+
+	// With object-types, objects of any other type are excluded from both sides
+	// of the diff
+	introspect.FilterSchema(from, opts.typeOpts)
+	introspect.FilterSchema(to, opts.typeOpts)
+	fromObjs = introspect.FilterObjects(fromObjs, opts.typeOpts)
+	toObjs = introspect.FilterObjects(toObjs, opts.typeOpts)
+	if mods.Partitioning == tengo.PartitioningRemove {
+		// With partitioning=remove, forcibly treat all filesystem definitions as if
+		// they didn't have a partitioning clause. This is designed to aid in the
+		// use-case of not running any partition management in a dev environment; if
+		// a table somehow manages to be partitioned there anyway by mistake, we
+		// intentionally want to de-partition it.
+		for _, table := range to.Tables {
+			if table.Partitioning != nil {
+				table.CreateStatement = table.UnpartitionedCreateStatement(mods.Flavor)
+				table.Partitioning = nil
+			}
+		}
+	}
+
+	// Hidden columns generated automatically by the server are excluded from both
+	// sides of the diff, unless configured otherwise
+	if !opts.includeSystemColumns {
+		excludeSystemColumns(from, to, mods.Flavor)
+	}
+
+	// The utf8 character set may be reported as either utf8 or utf8mb3 depending
+	// on server version, so both sides are converted to the target's name for it
+	normalizeUTF8Aliases(from, to, mods.Flavor)
+
+	// MariaDB qualifies sequence function calls in column defaults with the
+	// schema name, so these are unqualified on both sides
+	normalizeSequenceCalls(from, to, mods.Flavor)
+
+	// Column defaults and ON UPDATE clauses may display equivalent values in
+	// different formats, so both sides are converted to the target's format
+	normalizeColumnDefaults(from, to, mods.Flavor)
+
+	// Generated column and functional index expressions from information_schema
+	// may be formatted differently than in SHOW CREATE TABLE, which would
+	// otherwise cause their tables to be treated as unsupported
+	normalizeGeneratedExpressions(from, to, mods.Flavor)
+
+	// If the target only specifies some objects, leave all others unchanged
+	partial := t != nil && t.Partial
+	if partial {
+		to = mergePartialSchema(from, to)
+	}
+
+	// With --skip-reorder-columns, don't move existing columns to match the
+	// column order of the filesystem definitions, and place new columns at the
+	// end of the table.
+	if !opts.reorderColumns {
+		ignoreColumnOrder(from, to, mods.Flavor)
+	}
+
+	// tengo does not support tablespaces, so tablespace clauses are removed prior
+	// to diffing, and any tablespace moves are handled as separate ALTER TABLEs.
+	tablespaceDiffs := extractTablespaces(from, to, mods.Flavor)
+
+	// Likewise, encryption changes are handled as separate ALTER TABLEs, since
+	// tengo cannot generate DDL to remove an ENCRYPTION clause. Tables lacking a
+	// clause in the filesystem inherit the live schema's default encryption.
+	encryptionDiffs := extractEncryption(from, to, opts.defaultEncryption, mods.Flavor)
+
+	// CHECK constraints are likewise removed prior to diffing, and any changes
+	// to them are handled as separate ALTER TABLEs
+	checkDiffs := extractChecks(from, to, mods.Flavor)
+
+	// With convert-character-set, tables changing their default character set
+	// or collation, including those which still use a schema default that is
+	// changing, are converted along with all of their text columns. These are
+	// also handled as separate ALTER TABLEs.
+	var conversionDiffs []tengo.ObjectDiff
+	if t != nil && t.Dir.Config.GetBool("convert-character-set") {
+		var conversionSkipped int
+		if conversionDiffs, conversionSkipped, err = extractConversions(t, from, to, mods.Flavor); err != nil {
+			return nil, nil, result, err
+		}
+		result.SkipCount += conversionSkipped
+	}
+
+	// DATA DIRECTORY and INDEX DIRECTORY clauses are also removed prior to
+	// diffing. Changes to them cannot be made by ALTER TABLE, so they are
+	// reported as unsupported rather than being silently ignored.
+	directoryDiffs := extractDirectories(from, to, mods.Flavor)
+
+	// tengo does not generate DDL for changes to the partition list of a table,
+	// so if requested, these are also handled as separate ALTER TABLEs
+	var partitionDiffs []tengo.ObjectDiff
+	if opts.managePartitionList {
+		partitionDiffs = extractPartitionLists(from, to, mods.Flavor, opts.allowDropPartition)
+	}
+
+	// tengo does not support events, sequences, views, or triggers at all, so
+	// they are diffed separately. Unlike other diffs, views are tracked in the
+	// order that the desired views could be created.
+	eventDiffs := diffEvents(fromObjs.Events, toObjs.Events, mods, partial)
+	sequenceDiffs := diffSequences(fromObjs.Sequences, toObjs.Sequences, mods.Flavor, partial)
+	viewDiffs := diffViews(fromObjs.Views, toObjs.Views, mods, partial)
+	triggerDiffs := diffTriggers(fromObjs.Triggers, toObjs.Triggers, mods, partial, opts.allowDropTrigger)
+
+	// Rows of tables listed in manage-data are also diffed separately. Tables
+	// whose live definition does not yet permit comparing rows are counted as
+	// unsupported.
+	var dataDiffs []tengo.ObjectDiff
+	if t != nil && opts.typeOpts.IncludesType(tengo.ObjectTypeTable) {
+		var dataSkipped int
+		if dataDiffs, dataSkipped, err = diffData(t, from, mods); err != nil {
+			return nil, nil, result, err
+		}
+		result.UnsupportedCount += dataSkipped
+	}
+
+	// Apply any custom normalization registered by programs embedding this
+	// package, now that all built-in normalization is complete
+	if t != nil {
+		if err := runNormalizers(from, to, t); err != nil {
+			return nil, nil, result, err
+		}
+	}
+
+	// Objects managed outside of Skeema are never created, altered, or dropped
+	diff = tengo.NewSchemaDiff(from, to)
+	opts.external.filterSchemaDiff(diff)
+
+	// Generated columns and functional indexes which depend on a changed column
+	// are dropped in a separate ALTER TABLE before the rest of the changes
+	splitDependentAlters(diff, mods.Flavor)
+
+	objDiffs = append(diff.ObjectDiffs(), tablespaceDiffs...)
+	objDiffs = append(objDiffs, encryptionDiffs...)
+	objDiffs = append(objDiffs, checkDiffs...)
+	objDiffs = append(objDiffs, conversionDiffs...)
+	objDiffs = append(objDiffs, directoryDiffs...)
+	objDiffs = append(objDiffs, partitionDiffs...)
+	objDiffs = append(objDiffs, eventDiffs...)
+	objDiffs = append(objDiffs, sequenceDiffs...)
+	objDiffs = append(objDiffs, viewDiffs...)
+	objDiffs = append(objDiffs, triggerDiffs...)
+	objDiffs = append(objDiffs, dataDiffs...)
+	objDiffs = opts.external.filterDiffs(objDiffs)
+	return diff, objDiffs, result, nil
+}
+
+// orderDiffs combines multiple ALTER TABLEs of the same table where possible,
+// so that the table is only rebuilt once, and then returns objDiffs in the
+// order that their statements should be run. Tables referenced by foreign keys
+// are created before, and dropped after, the tables referencing them.
+// Sequences are created before, and dropped after, any tables which may use
+// them. Views and triggers are handled the opposite way, since they may refer
+// to any other object. Row changes occur once all tables are in their final
+// state, but before any new triggers are created. The returned map indicates
+// which table diffs must be run with foreign key checks disabled, due to
+// circular references.
+func orderDiffs(objDiffs []tengo.ObjectDiff, mods tengo.StatementModifiers) ([]tengo.ObjectDiff, map[*tengo.TableDiff]bool) {
+	objDiffs = coalesceAlters(objDiffs, mods)
+	objDiffs, fkCyclic := orderForeignKeyDiffs(objDiffs)
+	objDiffs = orderSequenceDiffs(objDiffs)
+	objDiffs = orderViewDiffs(objDiffs)
+	objDiffs = orderTriggerDiffs(objDiffs)
+	objDiffs = orderDataDiffs(objDiffs)
+	return objDiffs, fkCyclic
+}
+
+// DiffWorkspaceSchemas returns the changes needed to transform from into to,
+// in the order that their statements should be run, along with the
+// StatementModifiers to use in generating the statements. This is intended for
+// diffs between two schemas obtained from workspace.ExecLogicalSchema, rather
+// than from a live database, such as by `skeema diff-snapshot`.
+//
+// Both sides are normalized in the same manner as Diff and Push, and changes
+// which tengo does not support, including those to events, sequences, views,
+// and triggers, are handled the same way. Unsafe changes are always permitted,
+// and AUTO_INCREMENT values are ignored. Besides the global options, dir's
+// configuration must define exact-match, compare-metadata, reorder-columns,
+// include-system-columns, partitioning, manage-partition-list,
+// external-objects, and external-objects-file. Options which require access to
+// a live schema, such as convert-character-set and manage-data, are not used.
+// As with a live schema, the tables of from may be modified in place, but to is
+// left unchanged, so that it may still be linted afterwards. A ConfigError is
+// returned if dir's configuration is invalid.
+func DiffWorkspaceSchemas(dir *fs.Dir, from, to *workspace.Schema, flavor tengo.Flavor) ([]tengo.ObjectDiff, tengo.StatementModifiers, error) {
+	mods := tengo.StatementModifiers{
+		NextAutoInc:     tengo.NextAutoIncIgnore,
+		AllowUnsafe:     true,
+		CompareMetadata: dir.Config.GetBool("compare-metadata"),
+		Flavor:          flavor,
+	}
+	if dir.Config.GetBool("exact-match") {
+		mods.StrictIndexOrder = true
+		mods.StrictForeignKeyNaming = true
+	}
+	var err error
+	if mods.IgnoreTable, err = dir.Config.GetRegexp("ignore-table"); err != nil {
+		return nil, mods, ConfigError(err.Error())
+	}
+	partitioning, err := dir.Config.GetEnum("partitioning", "keep", "remove", "modify")
+	if err != nil {
+		return nil, mods, ConfigError(err.Error())
+	}
+	mods.Partitioning = partitioningModes[partitioning]
+	external, err := externalObjectsForDir(dir)
+	if err != nil {
+		return nil, mods, ConfigError(err.Error())
+	}
+	introspectOpts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return nil, mods, ConfigError(err.Error())
+	}
+	opts := schemaDiffOptions{
+		mods:                 mods,
+		typeOpts:             introspect.Options{ObjectTypes: introspectOpts.ObjectTypes},
+		external:             external,
+		includeSystemColumns: dir.Config.GetBool("include-system-columns"),
+		reorderColumns:       dir.Config.GetBool("reorder-columns") || dir.Config.GetBool("exact-match"),
+		managePartitionList:  dir.Config.GetBool("manage-partition-list"),
+		allowDropPartition:   true,
+		allowDropTrigger:     true,
+		defaultEncryption:    "N",
+	}
+
+	// Both sides are shallow copies, since normalization replaces their slices
+	fromSchema, toSchema := *from.Schema, *to.Schema
+	_, objDiffs, _, err := diffSchemas(&fromSchema, &toSchema, introspect.WorkspaceObjects(from, opts.typeOpts), introspect.WorkspaceObjects(to, opts.typeOpts), opts)
+	if err != nil {
+		return nil, mods, err
+	}
+	objDiffs, _ = orderDiffs(objDiffs, mods)
+	return objDiffs, mods, nil
+}
@@ -0,0 +1,105 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestDiffWorkspaceSchemas(t *testing.T) {
+	fs.MakeTestDirectory(t, "testdata/.scratch/wsdiff")
+	defer fs.RemoveTestDirectory(t, "testdata/.scratch")
+
+	makeTable := func(name, tablespace string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		if tablespace != "" {
+			table.CreateStatement = strings.Replace(table.CreateStatement, ") ENGINE", ") /*!50100 TABLESPACE `"+tablespace+"` */ ENGINE", 1)
+			table.UnsupportedDDL = true
+		}
+		return table
+	}
+	view := &workspace.View{
+		Name:            "v1",
+		Definer:         "root@%",
+		Security:        "DEFINER",
+		CheckOption:     "NONE",
+		Definition:      "select `users`.`id` AS `id` from `users`",
+		Algorithm:       "UNDEFINED",
+		CreateStatement: "CREATE VIEW v1 AS SELECT id FROM users",
+	}
+	trig := &workspace.Trigger{
+		Name:            "users_bi",
+		Table:           "users",
+		Timing:          "BEFORE",
+		Event:           "INSERT",
+		Body:            "SET NEW.id = NEW.id + 1",
+		CreateStatement: "CREATE TRIGGER users_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.id = NEW.id + 1",
+	}
+	from := &workspace.Schema{
+		Schema: &tengo.Schema{Name: "product", Tables: []*tengo.Table{makeTable("moved", "ts1")}},
+	}
+	to := &workspace.Schema{
+		Schema:   &tengo.Schema{Name: "product", Tables: []*tengo.Table{makeTable("moved", "ts2"), makeTable("users", "")}},
+		Views:    []*workspace.View{view},
+		Triggers: []*workspace.Trigger{trig},
+	}
+	origToStatement := to.Tables[0].CreateStatement
+
+	// The tablespace move must be handled separately from tengo, and the view and
+	// trigger must be created after the table they depend on
+	dir := getRunDir(t, "testdata/.scratch/wsdiff", "")
+	objDiffs, mods, err := DiffWorkspaceSchemas(dir, from, to, tengo.FlavorMySQL80)
+	if err != nil {
+		t.Fatalf("Unexpected error from DiffWorkspaceSchemas: %v", err)
+	}
+	if !mods.AllowUnsafe || mods.NextAutoInc != tengo.NextAutoIncIgnore || mods.Flavor != tengo.FlavorMySQL80 {
+		t.Errorf("Unexpected statement modifiers: %+v", mods)
+	}
+	expected := []string{
+		"CREATE TABLE `users`",
+		"ALTER TABLE `moved` TABLESPACE `ts2`",
+		"CREATE VIEW v1",
+		"CREATE TRIGGER users_bi",
+	}
+	if len(objDiffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, instead found %d: %v", len(expected), len(objDiffs), objDiffs)
+	}
+	for n, objDiff := range objDiffs {
+		if stmt, err := objDiff.Statement(mods); err != nil || !strings.HasPrefix(stmt, expected[n]) {
+			t.Errorf("Unexpected statement at position %d: expected prefix %q, found %q / %v", n, expected[n], stmt, err)
+		}
+	}
+
+	// The desired workspace schema must not have been modified
+	if len(to.Tables) != 2 || to.Tables[0].CreateStatement != origToStatement || !to.Tables[0].UnsupportedDDL {
+		t.Error("Expected DiffWorkspaceSchemas to leave its desired schema unmodified")
+	}
+
+	// Object types and external objects are both respected
+	dir = getRunDir(t, "testdata/.scratch/wsdiff", "--object-types=table,view --external-objects=table:moved")
+	if objDiffs, _, err = DiffWorkspaceSchemas(dir, from, to, tengo.FlavorMySQL80); err != nil {
+		t.Fatalf("Unexpected error from DiffWorkspaceSchemas: %v", err)
+	}
+	if len(objDiffs) != 2 || objDiffs[0].ObjectKey().Name != "users" || objDiffs[1].ObjectKey().Type != fs.ObjectTypeView {
+		t.Errorf("Unexpected diffs with object-types and external-objects: %v", objDiffs)
+	}
+
+	// Invalid configuration results in a ConfigError
+	dir = getRunDir(t, "testdata/.scratch/wsdiff", "--partitioning=bogus")
+	if _, _, err := DiffWorkspaceSchemas(dir, from, to, tengo.FlavorMySQL80); err == nil {
+		t.Error("Expected error from invalid partitioning, but err was nil")
+	} else if _, ok := err.(ConfigError); !ok {
+		t.Errorf("Expected ConfigError, instead found %T", err)
+	}
+}
@@ -0,0 +1,164 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// sequenceDiff represents a difference in a MariaDB sequence between the
+// filesystem and a live schema. tengo does not support sequences, so these
+// diffs are computed separately. It satisfies the tengo.ObjectDiff interface.
+type sequenceDiff struct {
+	from *workspace.Sequence // nil for a create
+	to   *workspace.Sequence // nil for a drop
+}
+
+// DiffType returns the type of diff operation.
+func (sd *sequenceDiff) DiffType() tengo.DiffType {
+	if sd.from == nil {
+		return tengo.DiffTypeCreate
+	} else if sd.to == nil {
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the sequence.
+func (sd *sequenceDiff) ObjectKey() tengo.ObjectKey {
+	if sd.to != nil {
+		return tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: sd.to.Name}
+	}
+	return tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: sd.from.Name}
+}
+
+// Statement returns the full DDL statement corresponding to the sequenceDiff.
+// A non-nil error will be returned if the statement is a DROP SEQUENCE and mods
+// do not permit unsafe operations, or if the sequence's data type differs,
+// since ALTER SEQUENCE cannot change it.
+func (sd *sequenceDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	switch sd.DiffType() {
+	case tengo.DiffTypeCreate:
+		return sd.to.CreateStatement, nil
+	case tengo.DiffTypeAlter:
+		if sd.to.DataType != sd.from.DataType {
+			return "", &tengo.UnsupportedDiffError{
+				ObjectKey:      sd.ObjectKey(),
+				ExpectedCreate: sd.to.CreateStatement,
+				ActualCreate:   sd.from.CreateStatement,
+			}
+		}
+		return sd.to.AlterStatement(sd.from), nil
+	default:
+		stmt := fmt.Sprintf("DROP SEQUENCE %s", tengo.EscapeIdentifier(sd.from.Name))
+		var err error
+		if !mods.AllowUnsafe {
+			err = &tengo.ForbiddenDiffError{
+				Reason:    "DROP SEQUENCE not permitted",
+				Statement: stmt,
+			}
+		}
+		return stmt, err
+	}
+}
+
+// diffSequences compares liveSequences to desiredSequences, returning a
+// sequenceDiff for each sequence which must be created, altered, or dropped. If
+// partial is true, sequences missing from desiredSequences are not dropped.
+// Sequences are only supported by MariaDB 10.3+, so nothing is returned for
+// other flavors.
+func diffSequences(liveSequences, desiredSequences []*workspace.Sequence, flavor tengo.Flavor, partial bool) []tengo.ObjectDiff {
+	if !workspace.SupportsSequences(flavor) {
+		return nil
+	}
+	liveByName := make(map[string]*workspace.Sequence, len(liveSequences))
+	for _, seq := range liveSequences {
+		liveByName[strings.ToLower(seq.Name)] = seq
+	}
+
+	var diffs []tengo.ObjectDiff
+	for _, seq := range desiredSequences {
+		live := liveByName[strings.ToLower(seq.Name)]
+		delete(liveByName, strings.ToLower(seq.Name))
+		if live == nil || !seq.Matches(live) {
+			diffs = append(diffs, &sequenceDiff{from: live, to: seq})
+		}
+	}
+	if !partial {
+		for _, live := range liveSequences {
+			if liveByName[strings.ToLower(live.Name)] != nil {
+				diffs = append(diffs, &sequenceDiff{from: live})
+			}
+		}
+	}
+	return diffs
+}
+
+// orderSequenceDiffs returns objDiffs reordered so that sequences are created
+// and altered before any other changes, since column defaults may call
+// NEXTVAL on them, and dropped after all other changes.
+func orderSequenceDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	var first, middle, last []tengo.ObjectDiff
+	for _, objDiff := range objDiffs {
+		if objDiff.ObjectKey().Type != fs.ObjectTypeSequence {
+			middle = append(middle, objDiff)
+		} else if objDiff.DiffType() == tengo.DiffTypeDrop {
+			last = append(last, objDiff)
+		} else {
+			first = append(first, objDiff)
+		}
+	}
+	result := append(first, middle...)
+	return append(result, last...)
+}
+
+// normalizeSequenceCalls removes the schema name from calls to sequence
+// functions in column defaults, in both schemaFromInstance and schemaFromDir.
+// MariaDB always qualifies these calls in SHOW CREATE TABLE, which would
+// otherwise cause spurious differences since the workspace schema name differs
+// from the target's. Modified dir tables are replaced with copies, since the
+// same desired schema may be shared by other targets.
+func normalizeSequenceCalls(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	if !workspace.SupportsSequences(flavor) {
+		return
+	}
+	if schemaFromInstance != nil {
+		for n, table := range schemaFromInstance.Tables {
+			if normalized := tableWithUnqualifiedSequences(table, schemaFromInstance.Name, flavor); normalized != nil {
+				schemaFromInstance.Tables[n] = normalized
+			}
+		}
+	}
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if normalized := tableWithUnqualifiedSequences(table, schemaFromDir.Name, flavor); normalized != nil {
+			dirTables[n] = normalized
+		}
+	}
+	schemaFromDir.Tables = dirTables
+}
+
+// tableWithUnqualifiedSequences returns a copy of table with calls to sequences
+// in schemaName unqualified, or nil if no changes are needed.
+func tableWithUnqualifiedSequences(table *tengo.Table, schemaName string, flavor tengo.Flavor) *tengo.Table {
+	create := fs.UnqualifySequenceCalls(table.CreateStatement, schemaName)
+	if create == table.CreateStatement {
+		return nil
+	}
+	normalized := *table
+	normalized.Columns = make([]*tengo.Column, len(table.Columns))
+	for n, col := range table.Columns {
+		normalized.Columns[n] = col
+		if def := fs.UnqualifySequenceCalls(col.Default, schemaName); def != col.Default {
+			colCopy := *col
+			colCopy.Default = def
+			normalized.Columns[n] = &colCopy
+		}
+	}
+	stripTableClause(&normalized, create, flavor)
+	return &normalized
+}
@@ -0,0 +1,116 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestSequenceDiffStatement(t *testing.T) {
+	from := &workspace.Sequence{
+		Name:            "s1",
+		Start:           "1",
+		MinValue:        "1",
+		MaxValue:        "9223372036854775806",
+		Increment:       "1",
+		Cache:           "1000",
+		CreateStatement: "CREATE SEQUENCE `s1` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB",
+	}
+	to := *from
+	to.Increment = "10"
+	to.CreateStatement = "CREATE SEQUENCE s1 INCREMENT BY 10"
+	mods := tengo.StatementModifiers{}
+	expectKey := tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: "s1"}
+
+	create := &sequenceDiff{to: &to}
+	if create.DiffType() != tengo.DiffTypeCreate || create.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", create.DiffType(), create.ObjectKey())
+	}
+	if stmt, err := create.Statement(mods); stmt != to.CreateStatement || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	alter := &sequenceDiff{from: from, to: &to}
+	if alter.DiffType() != tengo.DiffTypeAlter || alter.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", alter.DiffType(), alter.ObjectKey())
+	}
+	if stmt, err := alter.Statement(mods); stmt != "ALTER SEQUENCE `s1` INCREMENT BY 10" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	to.DataType = "int"
+	if _, err := alter.Statement(mods); !tengo.IsUnsupportedDiff(err) {
+		t.Errorf("Expected UnsupportedDiffError for data type change, instead found %v", err)
+	}
+
+	drop := &sequenceDiff{from: from}
+	if drop.DiffType() != tengo.DiffTypeDrop || drop.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", drop.DiffType(), drop.ObjectKey())
+	}
+	if stmt, err := drop.Statement(mods); stmt != "DROP SEQUENCE `s1`" || !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	mods.AllowUnsafe = true
+	if stmt, err := drop.Statement(mods); stmt != "DROP SEQUENCE `s1`" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	if stmt := addDropIfExists("DROP SEQUENCE `s1`", drop, tengo.FlavorMariaDB103); stmt != "DROP SEQUENCE IF EXISTS `s1`" {
+		t.Errorf("Unexpected return from addDropIfExists: %q", stmt)
+	}
+}
+
+func TestOrderSequenceDiffs(t *testing.T) {
+	seq := &workspace.Sequence{Name: "s1"}
+	event := &workspace.Event{Name: "ev1"}
+	objDiffs := []tengo.ObjectDiff{
+		&eventDiff{to: event},
+		&sequenceDiff{from: seq},
+		&eventDiff{from: event},
+		&sequenceDiff{to: seq},
+	}
+	ordered := orderSequenceDiffs(objDiffs)
+	expected := []tengo.ObjectDiff{objDiffs[3], objDiffs[0], objDiffs[2], objDiffs[1]}
+	for n := range expected {
+		if ordered[n] != expected[n] {
+			t.Errorf("Unexpected diff at position %d: %s %s", n, ordered[n].DiffType(), ordered[n].ObjectKey())
+		}
+	}
+}
+
+func TestNormalizeSequenceCalls(t *testing.T) {
+	flavor := tengo.FlavorMariaDB103
+	newSchema := func(name string) *tengo.Schema {
+		col := &tengo.Column{Name: "id", TypeInDB: "bigint(20)", Default: "nextval(`" + name + "`.`s1`)"}
+		table := &tengo.Table{
+			Name:               "t1",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{col},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return &tengo.Schema{Name: name, Tables: []*tengo.Table{table}}
+	}
+	schemaFromInstance := newSchema("product")
+	schemaFromDir := newSchema("_skeema_tmp")
+	origDirTable := schemaFromDir.Tables[0]
+	normalizeSequenceCalls(schemaFromInstance, schemaFromDir, flavor)
+
+	for _, schema := range []*tengo.Schema{schemaFromInstance, schemaFromDir} {
+		table := schema.Tables[0]
+		if table.Columns[0].Default != "nextval(`s1`)" {
+			t.Errorf("Unexpected default in schema %s: %s", schema.Name, table.Columns[0].Default)
+		}
+		if table.CreateStatement != table.GeneratedCreateStatement(flavor) || table.UnsupportedDDL {
+			t.Errorf("Unexpected CREATE TABLE in schema %s: %s", schema.Name, table.CreateStatement)
+		}
+	}
+	if origDirTable.Columns[0].Default != "nextval(`_skeema_tmp`.`s1`)" {
+		t.Errorf("Expected original dir table to be unmodified, instead found default %s", origDirTable.Columns[0].Default)
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.ObjectDiffs()) != 0 {
+		t.Errorf("Expected no differences after normalization, instead found %d", len(diff.ObjectDiffs()))
+	}
+}
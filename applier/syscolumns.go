@@ -0,0 +1,146 @@
+package applier
+
+import (
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// isSystemColumn returns true if col is a hidden column which the server
+// generated automatically, rather than one defined by the user. Detection
+// depends on the flavor:
+//
+//   - MySQL 8.0+ may add an invisible auto-increment primary key column called
+//     my_row_id (sql_generate_invisible_primary_key), and uses hidden columns
+//     named with a "!hidden!" prefix to implement functional indexes.
+//   - MariaDB 10.3+ adds invisible row_start and row_end columns to tables with
+//     implicit system versioning, and uses hidden columns named with a
+//     "DB_ROW_HASH_" prefix to implement long unique indexes. These columns are
+//     never displayed by SHOW CREATE TABLE.
+func isSystemColumn(col *tengo.Column, table *tengo.Table, flavor tengo.Flavor) bool {
+	if flavor.MySQLishMinVersion(8, 0) {
+		if strings.HasPrefix(col.Name, "!hidden!") {
+			return true
+		}
+		return col.Name == "my_row_id" && col.Invisible && col.AutoIncrement && col.TypeInDB == "bigint unsigned" &&
+			table.PrimaryKey != nil && len(table.PrimaryKey.Parts) == 1 && table.PrimaryKey.Parts[0].ColumnName == col.Name
+	}
+	if flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 3) {
+		if !col.Invisible || strings.Contains(table.CreateStatement, "\n  "+tengo.EscapeIdentifier(col.Name)+" ") {
+			return false
+		}
+		return strings.HasPrefix(col.Name, "DB_ROW_HASH_") || col.Name == "row_start" || col.Name == "row_end"
+	}
+	return false
+}
+
+// excludeSystemColumns removes system-generated hidden columns, as identified
+// by isSystemColumn, from the tables of both schemaFromInstance and
+// schemaFromDir. This prevents spurious differences when only one side has
+// them, for example if a workspace and a target instance have different
+// settings for generating invisible primary keys. Any index consisting solely
+// of system columns is removed as well. Tables with an index mixing system and
+// ordinary columns are left unchanged. Modified dir tables are replaced with
+// copies, since the same desired schema may be shared by other targets.
+func excludeSystemColumns(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	if schemaFromInstance != nil {
+		for n, table := range schemaFromInstance.Tables {
+			if stripped := withoutSystemColumns(table, flavor); stripped != nil {
+				schemaFromInstance.Tables[n] = stripped
+			}
+		}
+	}
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if stripped := withoutSystemColumns(table, flavor); stripped != nil {
+			dirTables[n] = stripped
+		}
+	}
+	schemaFromDir.Tables = dirTables
+}
+
+// withoutSystemColumns returns a copy of table with any system columns
+// removed, or nil if the table has no system columns or cannot be modified
+// safely. The copy's CreateStatement omits the lines defining the removed
+// columns and indexes, and its support for diffing is re-evaluated, since
+// tengo cannot otherwise handle some types of hidden columns.
+func withoutSystemColumns(table *tengo.Table, flavor tengo.Flavor) *tengo.Table {
+	systemCols := make(map[string]bool)
+	var columns []*tengo.Column
+	for _, col := range table.Columns {
+		if isSystemColumn(col, table, flavor) {
+			systemCols[col.Name] = true
+		} else {
+			columns = append(columns, col)
+		}
+	}
+	if len(systemCols) == 0 {
+		return nil
+	}
+
+	// Determine which lines of the CREATE TABLE to remove, bailing out if any
+	// index mixes system and ordinary columns
+	removePrefixes := make([]string, 0, len(systemCols))
+	for name := range systemCols {
+		removePrefixes = append(removePrefixes, "  "+tengo.EscapeIdentifier(name)+" ")
+	}
+	onlySystemColumns := func(idx *tengo.Index) (bool, bool) {
+		var system, ordinary bool
+		for _, part := range idx.Parts {
+			if systemCols[part.ColumnName] {
+				system = true
+			} else {
+				ordinary = true
+			}
+		}
+		return system && !ordinary, system && ordinary
+	}
+	stripped := *table
+	stripped.Columns = columns
+	if table.PrimaryKey != nil {
+		if remove, mixed := onlySystemColumns(table.PrimaryKey); mixed {
+			return nil
+		} else if remove {
+			stripped.PrimaryKey = nil
+			removePrefixes = append(removePrefixes, "  PRIMARY KEY (")
+		}
+	}
+	stripped.SecondaryIndexes = nil
+	for _, idx := range table.SecondaryIndexes {
+		if remove, mixed := onlySystemColumns(idx); mixed {
+			return nil
+		} else if remove {
+			removePrefixes = append(removePrefixes, "  KEY "+tengo.EscapeIdentifier(idx.Name)+" (", "  UNIQUE KEY "+tengo.EscapeIdentifier(idx.Name)+" (")
+		} else {
+			stripped.SecondaryIndexes = append(stripped.SecondaryIndexes, idx)
+		}
+	}
+
+	// Rewrite the CREATE TABLE: the body consists of one definition per line,
+	// between the first line and the line beginning with the closing paren
+	lines := strings.Split(table.CreateStatement, "\n")
+	body := make([]string, 0, len(lines))
+	var tail []string
+	for n, line := range lines[1:] {
+		if strings.HasPrefix(line, ")") {
+			tail = lines[n+1:]
+			break
+		}
+		var remove bool
+		for _, prefix := range removePrefixes {
+			if strings.HasPrefix(line, prefix) {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			body = append(body, strings.TrimSuffix(line, ","))
+		}
+	}
+	if tail == nil {
+		return nil
+	}
+	stripTableClause(&stripped, lines[0]+"\n"+strings.Join(body, ",\n")+"\n"+strings.Join(tail, "\n"), flavor)
+	return &stripped
+}
@@ -0,0 +1,123 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestExcludeSystemColumnsMySQL(t *testing.T) {
+	flavor := tengo.FlavorMySQL80
+	makeTable := func() *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int", Nullable: true, Default: "NULL"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+
+	// Simulate a generated invisible primary key on the instance side only
+	dirTable := makeTable()
+	instTable := makeTable()
+	gipk := &tengo.Column{Name: "my_row_id", TypeInDB: "bigint unsigned", AutoIncrement: true, Invisible: true}
+	instTable.Columns = append([]*tengo.Column{gipk}, instTable.Columns...)
+	instTable.PrimaryKey = &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "my_row_id"}}, PrimaryKey: true, Unique: true}
+	instTable.CreateStatement = strings.Replace(dirTable.CreateStatement, "(\n", "(\n  `my_row_id` bigint unsigned NOT NULL AUTO_INCREMENT /*!80023 INVISIBLE */,\n", 1)
+	instTable.CreateStatement = strings.Replace(instTable.CreateStatement, "\n)", ",\n  PRIMARY KEY (`my_row_id`)\n)", 1)
+	instTable.UnsupportedDDL = true
+
+	schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{instTable}}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{dirTable}}
+	excludeSystemColumns(schemaFromInstance, schemaFromDir, flavor)
+	stripped := schemaFromInstance.Tables[0]
+	if stripped.CreateStatement != dirTable.CreateStatement || stripped.UnsupportedDDL || stripped.PrimaryKey != nil || len(stripped.Columns) != 1 {
+		t.Errorf("Unexpected result from excludeSystemColumns: %+v\n%s", stripped, stripped.CreateStatement)
+	}
+	if diffs := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs(); len(diffs) != 0 {
+		t.Errorf("Expected no differences after excludeSystemColumns, instead found %d", len(diffs))
+	}
+	if schemaFromDir.Tables[0] != dirTable {
+		t.Error("Expected dir table without system columns to be left as-is")
+	}
+
+	// The same table on a flavor without invisible primary keys, or a column
+	// which does not match the generated invisible primary key's definition,
+	// should not be modified
+	for _, mod := range []func(){
+		func() { flavor = tengo.FlavorMySQL57 },
+		func() { flavor, gipk.Invisible = tengo.FlavorMySQL80, false },
+		func() { gipk.Invisible, gipk.TypeInDB = true, "int unsigned" },
+	} {
+		mod()
+		if stripped := withoutSystemColumns(instTable, flavor); stripped != nil {
+			t.Errorf("Expected table to be unchanged with flavor %s and column %+v, instead found:\n%s", flavor, *gipk, stripped.CreateStatement)
+		}
+	}
+
+	// Tables with an index mixing system and ordinary columns are not modified
+	gipk.TypeInDB = "bigint unsigned"
+	instTable.SecondaryIndexes = []*tengo.Index{{Name: "mixed", Parts: []tengo.IndexPart{{ColumnName: "id"}, {ColumnName: "my_row_id"}}}}
+	if stripped := withoutSystemColumns(instTable, tengo.FlavorMySQL80); stripped != nil {
+		t.Errorf("Expected table with mixed index to be unchanged, instead found:\n%s", stripped.CreateStatement)
+	}
+}
+
+func TestExcludeSystemColumnsMariaDB(t *testing.T) {
+	flavor := tengo.FlavorMariaDB103
+	table := &tengo.Table{
+		Name:               "widgets",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"},
+			{Name: "notes", TypeInDB: "int(11)", Nullable: true, Default: "NULL", Invisible: true},
+		},
+	}
+	table.CreateStatement = table.GeneratedCreateStatement(flavor)
+	expectedCreate := table.CreateStatement
+
+	// Simulate implicit system versioning columns, which SHOW CREATE TABLE does
+	// not display
+	table.Columns = append(table.Columns,
+		&tengo.Column{Name: "row_start", TypeInDB: "timestamp(6)", Invisible: true},
+		&tengo.Column{Name: "row_end", TypeInDB: "timestamp(6)", Invisible: true},
+	)
+	table.UnsupportedDDL = true
+	stripped := withoutSystemColumns(table, flavor)
+	if stripped == nil {
+		t.Fatal("Expected withoutSystemColumns to modify table, but it returned nil")
+	}
+	if stripped.CreateStatement != expectedCreate || stripped.UnsupportedDDL || len(stripped.Columns) != 2 {
+		t.Errorf("Unexpected result from withoutSystemColumns: %+v\n%s", stripped, stripped.CreateStatement)
+	}
+	if len(table.Columns) != 4 || !table.UnsupportedDDL {
+		t.Error("withoutSystemColumns unexpectedly modified its input table")
+	}
+
+	// Columns displayed by SHOW CREATE TABLE are user-defined, even if invisible
+	// and named like a system column
+	table.Columns = table.Columns[0:2]
+	table.Columns[1].Name = "row_start"
+	table.CreateStatement = table.GeneratedCreateStatement(flavor)
+	if stripped := withoutSystemColumns(table, flavor); stripped != nil {
+		t.Errorf("Expected table with explicit invisible column to be unchanged, instead found:\n%s", stripped.CreateStatement)
+	}
+
+	// Hidden long unique hash columns are also system columns, but only for
+	// MariaDB
+	table.Columns = append(table.Columns, &tengo.Column{Name: "DB_ROW_HASH_1", TypeInDB: "bigint(20)", Nullable: true, Invisible: true})
+	if stripped := withoutSystemColumns(table, flavor); stripped == nil || len(stripped.Columns) != 2 {
+		t.Error("Expected hash column to be removed by withoutSystemColumns")
+	}
+	if stripped := withoutSystemColumns(table, tengo.FlavorMySQL80); stripped != nil {
+		t.Error("Expected hash column to be left alone for MySQL flavor")
+	}
+}
@@ -0,0 +1,146 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// defaultTablespace is the name of the pseudo-tablespace which places a table
+// in its own file-per-table tablespace.
+const defaultTablespace = "innodb_file_per_table"
+
+// reTablespaceClause matches the table-level tablespace clause found in SHOW
+// CREATE TABLE output for tables in general tablespaces.
+var reTablespaceClause = regexp.MustCompile(" /\\*!50100 TABLESPACE `((?:[^`]|``)+)`(?: STORAGE (?:DISK|MEMORY))? \\*/")
+
+// parseCreateTablespace splits the supplied CREATE TABLE statement into a
+// version without any table-level TABLESPACE clause, and the name of the
+// tablespace. An explicit clause placing the table in innodb_file_per_table is
+// equivalent to having no clause at all, so it is normalized to an empty
+// tablespace name.
+func parseCreateTablespace(createStmt string) (base, tablespace string) {
+	match := reTablespaceClause.FindStringSubmatchIndex(createStmt)
+	if match == nil {
+		return createStmt, ""
+	}
+	base = createStmt[:match[0]] + createStmt[match[1]:]
+	tablespace = strings.Replace(createStmt[match[2]:match[3]], "``", "`", -1)
+	if strings.EqualFold(tablespace, defaultTablespace) {
+		tablespace = ""
+	}
+	return base, tablespace
+}
+
+// tablespaceDiff represents an ALTER TABLE which moves a table to a different
+// tablespace. It satisfies the tengo.ObjectDiff interface.
+type tablespaceDiff struct {
+	table *tengo.Table
+	from  string // empty string means the default file-per-table tablespace
+	to    string // ditto
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (tsd *tablespaceDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table being
+// moved.
+func (tsd *tablespaceDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: tsd.table.Name}
+}
+
+// Statement returns the full ALTER TABLE statement.
+func (tsd *tablespaceDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(tsd.table.Name) {
+		return "", nil
+	}
+	clauses, _ := tsd.Clauses(mods)
+	return fmt.Sprintf("%s %s", tsd.table.AlterStatement(), clauses), nil
+}
+
+// Clauses returns the body of the ALTER TABLE, everything after
+// "ALTER TABLE [name] ".
+func (tsd *tablespaceDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	to := tsd.to
+	if to == "" {
+		to = defaultTablespace
+	}
+	var clauses []string
+	if mods.AlgorithmClause != "" {
+		clauses = append(clauses, fmt.Sprintf("ALGORITHM=%s", strings.ToUpper(mods.AlgorithmClause)))
+	}
+	if mods.LockClause != "" {
+		clauses = append(clauses, fmt.Sprintf("LOCK=%s", strings.ToUpper(mods.LockClause)))
+	}
+	clauses = append(clauses, fmt.Sprintf("TABLESPACE %s", tengo.EscapeIdentifier(to)))
+	return strings.Join(clauses, ", "), nil
+}
+
+// rebuildReason returns a human-readable description of the tablespace move.
+func (tsd *tablespaceDiff) rebuildReason() string {
+	from, to := tsd.from, tsd.to
+	if from == "" {
+		from = defaultTablespace
+	}
+	if to == "" {
+		to = defaultTablespace
+	}
+	return fmt.Sprintf("tablespace changes from %s to %s", from, to)
+}
+
+// extractTablespaces removes table-level TABLESPACE clauses from the
+// CreateStatement of tables existing in both schemaFromInstance and
+// schemaFromDir, so that tengo can diff the rest of their definitions normally,
+// even though it does not support tablespaces directly. A tablespaceDiff is
+// returned for each table whose tablespace differs. Modified dir tables are
+// replaced with copies, since the same desired schema may be shared by other
+// targets.
+func extractTablespaces(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) []tengo.ObjectDiff {
+	if schemaFromInstance == nil {
+		return nil
+	}
+	var diffs []tengo.ObjectDiff
+	instTables := schemaFromInstance.TablesByName()
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		instTable := instTables[table.Name]
+		if instTable == nil {
+			continue
+		}
+		instBase, instTablespace := parseCreateTablespace(instTable.CreateStatement)
+		dirBase, dirTablespace := parseCreateTablespace(table.CreateStatement)
+		if instBase == instTable.CreateStatement && dirBase == table.CreateStatement {
+			continue // neither side has a tablespace clause
+		}
+		stripTableClause(instTable, instBase, flavor)
+		tableCopy := *table
+		stripTableClause(&tableCopy, dirBase, flavor)
+		dirTables[n] = &tableCopy
+		if instTablespace != dirTablespace {
+			diffs = append(diffs, &tablespaceDiff{
+				table: &tableCopy,
+				from:  instTablespace,
+				to:    dirTablespace,
+			})
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	return diffs
+}
+
+// stripTableClause sets table's CreateStatement to base, and re-evaluates
+// whether tengo supports diffing the table now that it lacks a clause which
+// tengo does not support, such as a tablespace clause.
+func stripTableClause(table *tengo.Table, base string, flavor tengo.Flavor) {
+	table.CreateStatement = base
+	if table.UnsupportedDDL {
+		actual, _ := tengo.ParseCreateAutoInc(base)
+		expected, _ := tengo.ParseCreateAutoInc(table.GeneratedCreateStatement(flavor))
+		table.UnsupportedDDL = (actual != expected)
+	}
+}
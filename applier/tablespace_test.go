@@ -0,0 +1,139 @@
+package applier
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestParseCreateTablespace(t *testing.T) {
+	base := "CREATE TABLE `widgets` (\n  `id` int(11) DEFAULT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	withClause := func(clause string) string {
+		return strings.Replace(base, ") ENGINE", ") "+clause+" ENGINE", 1)
+	}
+	cases := []struct {
+		Input      string
+		Tablespace string
+	}{
+		{base, ""},
+		{withClause("/*!50100 TABLESPACE `ts1` */"), "ts1"},
+		{withClause("/*!50100 TABLESPACE `we``ird` */"), "we`ird"},
+		{withClause("/*!50100 TABLESPACE `innodb_system` */"), "innodb_system"},
+		{withClause("/*!50100 TABLESPACE `innodb_file_per_table` */"), ""},
+		{withClause("/*!50100 TABLESPACE `ts1` STORAGE DISK */"), "ts1"},
+	}
+	for _, c := range cases {
+		actualBase, actualTablespace := parseCreateTablespace(c.Input)
+		if actualBase != base || actualTablespace != c.Tablespace {
+			t.Errorf("Unexpected result from parseCreateTablespace on %s: returned %q, %q", c.Input, actualBase, actualTablespace)
+		}
+	}
+}
+
+func TestExtractTablespaces(t *testing.T) {
+	makeTable := func(name, tablespace string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Columns:            []*tengo.Column{{Name: "id", TypeInDB: "int(11)", Nullable: true, Default: "NULL"}},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		if tablespace != "" {
+			table.CreateStatement = strings.Replace(table.CreateStatement, ") ENGINE", ") /*!50100 TABLESPACE `"+tablespace+"` */ ENGINE", 1)
+			table.UnsupportedDDL = true
+		}
+		return table
+	}
+	schemaFromInstance := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", "ts1"),
+			makeTable("moved", "ts1"),
+			makeTable("tofpt", "ts1"),
+			makeTable("fromfpt", ""),
+			makeTable("explicitfpt", ""),
+			makeTable("none", ""),
+			makeTable("dropped", "ts1"),
+		},
+	}
+	desired := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("same", "ts1"),
+			makeTable("moved", "ts2"),
+			makeTable("tofpt", ""),
+			makeTable("fromfpt", "ts2"),
+			makeTable("explicitfpt", "innodb_file_per_table"),
+			makeTable("none", ""),
+			makeTable("created", "ts1"),
+		},
+	}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: desired.Tables}
+
+	diffs := extractTablespaces(schemaFromInstance, schemaFromDir, tengo.FlavorUnknown)
+	expected := map[string]string{
+		"moved":   "ALTER TABLE `moved` TABLESPACE `ts2`",
+		"tofpt":   "ALTER TABLE `tofpt` TABLESPACE `innodb_file_per_table`",
+		"fromfpt": "ALTER TABLE `fromfpt` TABLESPACE `ts2`",
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d diffs, instead found %d", len(expected), len(diffs))
+	}
+	for _, diff := range diffs {
+		key := diff.ObjectKey()
+		if stmt, err := diff.Statement(tengo.StatementModifiers{}); stmt != expected[key.Name] || err != nil {
+			t.Errorf("Unexpected result from Statement() for %s: %q, %v", key, stmt, err)
+		}
+		if _, reasons := ClassifyAlter(diff, tengo.FlavorMySQL80); len(reasons) != 1 {
+			t.Errorf("Expected tablespace move of %s to have one rebuild reason, instead found %v", key, reasons)
+		}
+	}
+
+	// Tables existing on both sides should no longer be considered unsupported,
+	// and the desired schema's original tables must not have been modified
+	for n, table := range schemaFromDir.Tables {
+		if table.Name == "created" {
+			if table != desired.Tables[n] || !table.UnsupportedDDL {
+				t.Error("Expected table only existing in desired schema to be left as-is")
+			}
+			continue
+		}
+		if table.UnsupportedDDL || table.CreateStatement != table.GeneratedCreateStatement(tengo.FlavorUnknown) {
+			t.Errorf("Expected table %s to no longer have a tablespace clause, but it does: %s", table.Name, table.CreateStatement)
+		}
+		if table.Name != "none" && table == desired.Tables[n] {
+			t.Errorf("Expected table %s to be replaced with a copy, but it was not", table.Name)
+		}
+	}
+	for _, table := range desired.Tables {
+		if strings.Contains(table.CreateStatement, "TABLESPACE") != (table.Name != "none" && table.Name != "tofpt") {
+			t.Errorf("Desired schema's table %s unexpectedly modified: %s", table.Name, table.CreateStatement)
+		}
+	}
+	if diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir); len(diff.FilteredTableDiffs(tengo.DiffTypeAlter)) > 0 {
+		t.Errorf("Expected no other ALTER TABLEs after tablespace extraction, instead found %v", diff.FilteredTableDiffs(tengo.DiffTypeAlter))
+	}
+
+	// Confirm statement modifiers are handled
+	mods := tengo.StatementModifiers{
+		AlgorithmClause: "copy",
+		LockClause:      "shared",
+	}
+	tsd := diffs[0].(*tablespaceDiff)
+	name := tsd.table.Name
+	if stmt, _ := tsd.Statement(mods); !strings.HasPrefix(stmt, "ALTER TABLE `"+name+"` ALGORITHM=COPY, LOCK=SHARED, TABLESPACE ") {
+		t.Errorf("Unexpected statement with mods: %s", stmt)
+	}
+	if clauses, _ := tsd.Clauses(mods); strings.Contains(clauses, "ALTER TABLE") {
+		t.Errorf("Unexpected clauses with mods: %s", clauses)
+	}
+	mods.IgnoreTable = regexp.MustCompile(".")
+	if stmt, err := tsd.Statement(mods); stmt != "" || err != nil {
+		t.Errorf("Expected ignored table to yield blank statement, instead found %q, %v", stmt, err)
+	}
+}
@@ -2,7 +2,9 @@ package applier
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
@@ -19,11 +21,27 @@ type Target struct {
 	Dir           *fs.Dir
 	SchemaName    string
 	DesiredSchema *workspace.Schema
+	Partial       bool           // if true, DesiredSchema only specifies some objects; others are left as-is
+	State         *StateFile     // if non-nil, used to skip statements completed by a previous push, and record new ones
+	Migration     *MigrationFile // if non-nil, generated statements are also collected here for writing to a migration file
+	Plan          *Plan          // if non-nil, generated statements are also collected here for writing to a plan file
+	Audit         *AuditLog      // if non-nil, executed statements are recorded here
+	Cache         *CacheFile     // if non-nil, introspected schemas are cached here, unless introspection-cache-table is set
+
+	checkOnly    bool            // if true, only generate DDL, storing it in remainingDDL; see checkConvergence
+	remainingDDL []*DDLStatement // DDL generated when checkOnly is true
+	connectErr   error           // if non-nil, Instance was unreachable, so the target is reported as skipped
 }
 
 // SchemaFromInstance introspects and returns the instance's version of the
-// schema, if it exists.
+// schema, if it exists. If an introspection cache is configured, the schema
+// may be obtained from the cache instead; see cachedSchemaFromInstance.
 func (t *Target) SchemaFromInstance() (*tengo.Schema, error) {
+	if cache, err := t.schemaCache(); err != nil {
+		return nil, err
+	} else if cache != nil {
+		return t.cachedSchemaFromInstance(cache)
+	}
 	schema, err := t.Instance.Schema(t.SchemaName)
 	if err == sql.ErrNoRows {
 		err = nil
@@ -31,17 +49,51 @@ func (t *Target) SchemaFromInstance() (*tengo.Schema, error) {
 	return schema, err
 }
 
-// SchemaFromDir returns the desired schema expressed in the filesystem.
+// SchemaFromDir returns the desired schema expressed in the filesystem. If the
+// target is partial, the returned schema only contains the objects that were
+// explicitly specified; see mergePartialSchema.
 func (t *Target) SchemaFromDir() *tengo.Schema {
 	schemaCopy := *t.DesiredSchema.Schema
 	schemaCopy.Name = t.SchemaName
 	return &schemaCopy
 }
 
+// mergePartialSchema returns a copy of partial which additionally includes any
+// tables and routines from schemaFromInstance that partial does not specify,
+// and uses schemaFromInstance's default character set and collation. Diffing
+// schemaFromInstance against the result will therefore only affect objects
+// present in partial. If schemaFromInstance is nil, partial is returned as-is.
+func mergePartialSchema(schemaFromInstance, partial *tengo.Schema) *tengo.Schema {
+	if schemaFromInstance == nil {
+		return partial
+	}
+	merged := *partial
+	merged.CharSet = schemaFromInstance.CharSet
+	merged.Collation = schemaFromInstance.Collation
+	merged.Tables = append([]*tengo.Table{}, partial.Tables...)
+	partialTables := partial.TablesByName()
+	for _, table := range schemaFromInstance.Tables {
+		if _, ok := partialTables[table.Name]; !ok {
+			merged.Tables = append(merged.Tables, table)
+		}
+	}
+	merged.Routines = append([]*tengo.Routine{}, partial.Routines...)
+	partialRoutines := make(map[tengo.ObjectKey]bool, len(partial.Routines))
+	for _, routine := range partial.Routines {
+		partialRoutines[tengo.ObjectKey{Type: routine.Type, Name: routine.Name}] = true
+	}
+	for _, routine := range schemaFromInstance.Routines {
+		if !partialRoutines[tengo.ObjectKey{Type: routine.Type, Name: routine.Name}] {
+			merged.Routines = append(merged.Routines, routine)
+		}
+	}
+	return &merged
+}
+
 // dryRun returns true if this target is only being used for dry-run purposes,
 // rather than actually wanting to apply changes to this target.
 func (t *Target) dryRun() bool {
-	return t.Dir.Config.GetBool("dry-run")
+	return t.checkOnly || t.Dir.Config.GetBool("dry-run")
 }
 
 // briefOutput returns true if this target is only being evaluated for having
@@ -74,11 +126,63 @@ func (t *Target) logApplyEnd(result Result) {
 }
 
 func (t *Target) processDDL(ddls []*DDLStatement, printer *Printer) (skipCount int) {
+	var executed bool
+	var hooks []StatementHook
+	if !t.dryRun() && len(ddls) > 0 {
+		var err error
+		if hooks, err = t.hooks(); err != nil {
+			log.Errorf("Unable to proceed with DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+			log.Warnf("Skipping %s for %s %s due to previous error", countAndNoun(len(ddls), "operation"), t.Instance, t.SchemaName)
+			return len(ddls)
+		}
+	}
 	for i, ddl := range ddls {
+		if t.State.Completed(t, ddl) {
+			log.Infof("Skipping statement on %s %s, since state file %s indicates it already completed: %s", t.Instance, t.SchemaName, t.State.Path(), ddl.stmt)
+			continue
+		}
+		if err := t.collectDDL(ddl); err != nil {
+			log.Errorf("Unable to proceed with DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+			skipCount += len(ddls) - i
+			log.Warnf("Skipping %s for %s %s due to previous error", countAndNoun(len(ddls)-i, "remaining operation"), t.Instance, t.SchemaName)
+			return
+		}
 		printer.printDDL(ddl)
 		if !t.dryRun() {
-			if err := ddl.Execute(); err != nil {
+			if err := t.waitForReplicaLag(); err != nil {
+				log.Errorf("Unable to proceed with DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+				t.logPartialApply(nil, i)
+				skipCount += len(ddls) - i
+				log.Warnf("Skipping %s for %s %s due to previous error", countAndNoun(len(ddls)-i, "remaining operation"), t.Instance, t.SchemaName)
+				return
+			}
+			event := newStatementEvent(t, ddl)
+			if err := runBeforeStatementHooks(hooks, t, event); err != nil {
+				log.Errorf("Unable to proceed with DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+				t.logPartialApply(nil, i)
+				skipCount += len(ddls) - i
+				log.Warnf("Skipping %s for %s %s due to previous error", countAndNoun(len(ddls)-i, "remaining operation"), t.Instance, t.SchemaName)
+				return
+			}
+			err := ddl.Execute()
+			event.Duration, event.Err = time.Since(event.Started), err
+			if err != nil {
 				log.Errorf("Error running DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+			}
+			hookErr := runAfterStatementHooks(hooks, t, event)
+			if hookErr != nil {
+				log.Errorf("Error running statement hook after DDL on %s %s: %s", t.Instance, t.SchemaName, hookErr)
+			}
+			if err != nil || hookErr != nil {
+				if _, ok := err.(*FatalWarningError); ok || err == nil {
+					// The statement itself completed, despite being counted as a failure
+					if err := t.State.Record(t, ddl); err != nil {
+						log.Warnf("Unable to record completed statement in state file %s: %s", t.State.Path(), err)
+					}
+					t.logPartialApply(nil, i+1)
+				} else {
+					t.logPartialApply(ddl, i)
+				}
 				skipped := len(ddls) - i
 				skipCount += skipped
 				if skipped > 1 {
@@ -86,11 +190,96 @@ func (t *Target) processDDL(ddls []*DDLStatement, printer *Printer) (skipCount i
 				}
 				return
 			}
+			if err := t.State.Record(t, ddl); err != nil {
+				log.Warnf("Unable to record completed statement in state file %s: %s", t.State.Path(), err)
+			}
+			executed = true
+		}
+	}
+	if executed {
+		if err := t.waitForReplicaLag(); err != nil {
+			log.Warnf("All operations completed on %s %s, but: %s", t.Instance, t.SchemaName, err)
 		}
 	}
 	return
 }
 
+// collectDDL records ddl in the target's migration file and plan, if either is
+// being generated. An error is returned if ddl cannot be represented in them.
+func (t *Target) collectDDL(ddl *DDLStatement) error {
+	if err := t.Migration.Add(t, ddl); err != nil {
+		return err
+	}
+	return t.Plan.Add(t, ddl)
+}
+
+// logPartialApply logs information about the state of the target's schema
+// after failedDDL returned an error, following appliedCount successful
+// statements. Since DDL in MySQL and MariaDB always implicitly commits, there
+// is no way to roll back earlier statements. However, on flavors supporting
+// atomic DDL, the failed statement itself has been fully rolled back.
+// failedDDL may be nil if the target was abandoned before running a statement.
+func (t *Target) logPartialApply(failedDDL *DDLStatement, appliedCount int) {
+	if failedDDL != nil && !failedDDL.IsShellOut() {
+		if supportsAtomicDDL(t.Instance.Flavor()) {
+			log.Infof("%s supports atomic DDL, so the failed statement was fully rolled back for InnoDB tables", t.Instance)
+		} else {
+			log.Warnf("%s does not support atomic DDL, so the failed statement may have been partially applied", t.Instance)
+		}
+	}
+	if appliedCount > 0 {
+		log.Warnf("%s for %s %s were already applied; these cannot be rolled back automatically, since DDL statements implicitly commit", countAndNoun(appliedCount, "previous operation"), t.Instance, t.SchemaName)
+	}
+}
+
+// checkConvergence re-introspects the target's schema after its DDL has been
+// executed, and diffs it against the filesystem again, using the same
+// normalization as the original diff. Any DDL generated by this second diff
+// is returned. A non-empty result indicates that some executed statement did
+// not produce the intended state, for example due to a server quirk or a bug
+// in generating the DDL.
+func (t *Target) checkConvergence() ([]*DDLStatement, error) {
+	check := *t
+	check.State = nil
+	check.checkOnly = true
+	if result, err := applyTarget(&check, nil); err != nil {
+		return nil, err
+	} else if result.SkipCount > 0 {
+		return nil, errors.New("unable to generate DDL for all remaining differences")
+	}
+	return check.remainingDDL, nil
+}
+
+// logConvergence runs checkConvergence if enabled by mode, the value of the
+// check-convergence option, and logs any remaining differences. With mode
+// "error", the number of remaining statements is returned, so that they are
+// counted as skipped operations; otherwise 0 is returned.
+func (t *Target) logConvergence(mode string) int {
+	if mode == "off" {
+		return 0
+	}
+	remaining, err := t.checkConvergence()
+	if err != nil {
+		log.Warnf("Unable to confirm that %s %s matches %s after push: %s", t.Instance, t.SchemaName, t.Dir, err)
+		return 0
+	} else if len(remaining) == 0 {
+		log.Debugf("Confirmed %s %s matches %s after push", t.Instance, t.SchemaName, t.Dir)
+		return 0
+	}
+	logFunc := log.Warnf
+	if mode == "error" {
+		logFunc = log.Errorf
+	}
+	logFunc("%s %s still differs from %s after push; %s would be needed to converge:", t.Instance, t.SchemaName, t.Dir, countAndNoun(len(remaining), "further statement"))
+	for _, ddl := range remaining {
+		logFunc("  %s", ddl.stmt)
+	}
+	if mode == "error" {
+		return len(remaining)
+	}
+	return 0
+}
+
 // TargetGroup represents a group of Targets that all have the same Instance.
 type TargetGroup []*Target
 
@@ -102,7 +291,9 @@ type TargetGroup []*Target
 // and/or schemas will only use of the first of each.
 //
 // Targets are returned as a slice with no guaranteed ordering. Errors are not
-// fatal; a count of skipped dirs is returned instead.
+// fatal; a count of skipped dirs is returned instead. Unreachable instances are
+// the exception: each is returned as a Target which Run reports as skipped, so
+// that the failure is attributed to that instance.
 func TargetsForDir(dir *fs.Dir, maxDepth int) (targets []*Target, skipCount int) {
 	if dir.ParseError != nil {
 		log.Warnf("Skipping %s: %s\n", dir.Path, dir.ParseError)
@@ -110,7 +301,9 @@ func TargetsForDir(dir *fs.Dir, maxDepth int) (targets []*Target, skipCount int)
 	}
 	if dir.Config.Changed("host") && dir.HasSchema() {
 		var instances []*tengo.Instance
-		instances, skipCount = instancesForDir(dir)
+		var unreachable []*Target
+		instances, unreachable, skipCount = instancesForDir(dir)
+		targets = append(targets, unreachable...)
 
 		// For each LogicalSchema, obtain a *tengo.Schema representation and then
 		// create a Target for each instance x schema combination
@@ -172,34 +365,38 @@ func checkInstanceFlavor(instance *tengo.Instance, dir *fs.Dir) {
 	}
 }
 
-func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, skipCount int) {
+// instancesForDir returns the reachable instances that dir maps to. Each
+// unreachable instance is instead returned as a Target with no schema, which
+// Run reports as skipped for that instance, so that it counts towards
+// max-failures. skipCount reflects any other problems obtaining instances.
+func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, unreachable []*Target, skipCount int) {
 	if dir.Config.GetBool("first-only") {
 		onlyInstance, err := dir.FirstInstance()
 		if onlyInstance == nil && err == nil {
 			log.Warnf("Skipping %s: dir maps to an empty list of instances\n", dir)
-			return nil, 0
+			return nil, nil, 0
 		} else if err != nil {
 			log.Warnf("Skipping %s: %s\n", dir, err)
-			return nil, 1
+			return nil, nil, 1
 		}
 		// dir.FirstInstance already checks for connectivity, so no need to redo that here
 		checkInstanceFlavor(onlyInstance, dir)
-		return []*tengo.Instance{onlyInstance}, 0
+		return []*tengo.Instance{onlyInstance}, nil, 0
 	}
 
 	rawInstances, err := dir.Instances()
 	if err != nil {
 		log.Warnf("Skipping %s: %s\n", dir, err)
-		return nil, 1
+		return nil, nil, 1
 	} else if len(rawInstances) == 0 {
 		log.Warnf("Skipping %s: dir maps to an empty list of instances\n", dir)
-		return nil, 0
+		return nil, nil, 0
 	}
 	// dir.Instances doesn't pre-check for connectivity problems, so do that now
 	for _, inst := range rawInstances {
 		if ok, err := inst.CanConnect(); !ok {
 			log.Warnf("Skipping %s for %s: %s", inst, dir, err)
-			skipCount++
+			unreachable = append(unreachable, &Target{Instance: inst, Dir: dir, connectErr: err})
 		} else {
 			checkInstanceFlavor(inst, dir)
 			instances = append(instances, inst)
@@ -209,17 +406,23 @@ func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, skipCount int) {
 }
 
 func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, instances []*tengo.Instance) (targets []*Target, skipCount int) {
-	// Obtain a *tengo.Schema representation of the dir's *.sql files from a
-	// workspace
-	opts, err := workspace.OptionsForDir(dir, instances[0])
-	if err != nil {
-		log.Warnf("Skipping %s: %s\n", dir, err)
-		return nil, len(instances)
+	// Obtain a *tengo.Schema representation of the dir's *.sql files. If they
+	// already match a single live schema exactly, this can be derived without a
+	// workspace; otherwise, use a workspace.
+	var wsSchema *workspace.Schema
+	if len(instances) == 1 {
+		wsSchema = fastPathSchema(logicalSchema, dir, instances[0])
 	}
-	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
-	if err != nil {
-		log.Warnf("Skipping %s: %s\n", dir, err)
-		return nil, len(instances)
+	if wsSchema == nil {
+		opts, err := workspace.OptionsForDir(dir, instances[0])
+		if err != nil {
+			log.Warnf("Skipping %s: %s\n", dir, err)
+			return nil, len(instances)
+		}
+		if wsSchema, err = workspace.ExecLogicalSchema(logicalSchema, opts); err != nil {
+			log.Warnf("Skipping %s: %s\n", dir, err)
+			return nil, len(instances)
+		}
 	}
 	for _, stmtErr := range wsSchema.Failures {
 		log.Error(stmtErr.Error())
@@ -241,6 +444,7 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 	for _, inst := range instances {
 		var schemaNames []string
 		if logicalSchema.Name == "" { // blank means use the schema option from dir config
+			var err error
 			schemaNames, err = dir.SchemaNames(inst)
 			if err != nil {
 				log.Warnf("Skipping %s for %s: %s", inst, dir, err)
@@ -251,7 +455,13 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 				schemaNames = schemaNames[0:1]
 			}
 		} else {
-			schemaNames = []string{logicalSchema.Name}
+			schemaName, err := dir.AliasedSchemaName(logicalSchema.Name)
+			if err != nil {
+				log.Warnf("Skipping %s for %s: %s", inst, dir, err)
+				skipCount++
+				continue
+			}
+			schemaNames = []string{schemaName}
 		}
 		for _, schemaName := range schemaNames {
 			t := &Target{
@@ -266,11 +476,45 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 	return
 }
 
+// TargetsForPartialSchema returns Targets for the instances and schemas that
+// dir maps to, using logicalSchema as the desired state instead of the dir's
+// *.sql files. The returned Targets are partial: objects which exist in the
+// live schema but not in logicalSchema are left unchanged, rather than being
+// dropped. Subdirs of dir are not examined. Errors are not fatal; a count of
+// skipped targets is returned instead.
+func TargetsForPartialSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir) (targets []*Target, skipCount int) {
+	if dir.ParseError != nil {
+		log.Warnf("Skipping %s: %s\n", dir.Path, dir.ParseError)
+		return nil, 1
+	}
+	if !dir.Config.Changed("host") || !dir.HasSchema() {
+		log.Warnf("Skipping %s: no host or schema defined for environment \"%s\"\n", dir, dir.Config.Get("environment"))
+		return nil, 1
+	}
+	instances, unreachable, skipCount := instancesForDir(dir)
+	if len(instances) > 0 {
+		var thisSkipCount int
+		targets, thisSkipCount = targetsForLogicalSchema(logicalSchema, dir, instances)
+		skipCount += thisSkipCount
+	}
+	targets = append(targets, unreachable...)
+	for _, t := range targets {
+		t.Partial = true
+	}
+	return targets, skipCount
+}
+
 // TargetGroupChanForDir returns a channel for obtaining TargetGroups for this
 // dir and its subdirs, and count of directories that were skipped due to non-
 // fatal errors.
 func TargetGroupChanForDir(dir *fs.Dir) (<-chan TargetGroup, int) {
 	targets, skipCount := TargetsForDir(dir, 5)
+	return TargetGroupChan(targets), skipCount
+}
+
+// TargetGroupChan returns a channel for obtaining TargetGroups, grouping the
+// supplied targets by instance.
+func TargetGroupChan(targets []*Target) <-chan TargetGroup {
 	groups := make(chan TargetGroup)
 	go func() {
 		byInst := make(map[string]TargetGroup)
@@ -283,7 +527,7 @@ func TargetGroupChanForDir(dir *fs.Dir) (<-chan TargetGroup, int) {
 		}
 		close(groups)
 	}()
-	return groups, skipCount
+	return groups
 }
 
 func isStrictModeError(err error) bool {
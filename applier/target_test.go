@@ -115,24 +115,38 @@ func (s ApplierIntegrationSuite) TestTargetsForDirMulti(t *testing.T) {
 	assertTargetsForDir(dir, 1, 0, 0)
 
 	// Shut down the first DockerizedInstance and confirm behavior: without
-	// --first-only, targets from the stopped host should be skipped and influence
-	// skipCount; with --first-only, the stopped host should be ignored
+	// --first-only, the stopped host should yield a single unreachable target,
+	// so that Run reports it as a failed instance; with --first-only, the
+	// stopped host should be ignored
+	assertUnreachable := func(dir *fs.Dir, expectTargets, expectUnreachable int) {
+		t.Helper()
+		targets, skipCount := TargetsForDir(dir, 1)
+		var unreachable int
+		for _, target := range targets {
+			if target.connectErr != nil {
+				unreachable++
+			}
+		}
+		if len(targets) != expectTargets || unreachable != expectUnreachable || skipCount != 0 {
+			t.Errorf("Expected %d targets (%d unreachable), 0 skipped; instead found %d targets (%d unreachable), %d skipped", expectTargets, expectUnreachable, len(targets), unreachable, skipCount)
+		}
+	}
 	setupHostList(t, s.d[0].Instance, s.d[1].Instance)
 	if err := s.d[0].Stop(); err != nil {
 		t.Fatalf("Unexpected error from Stop(): %s", err)
 	}
 	dir = getDir(t, "testdata/multi", "")
-	assertTargetsForDir(dir, 1, 2, 1)
+	assertUnreachable(dir, 3, 1)
 	dir = getDir(t, "testdata/multi", "--first-only")
 	assertTargetsForDir(dir, 1, 1, 0)
 
 	// Shut down the second DockerizedInstance and confirm behavior: no valid
-	// targets, and skipCount depends on --first-only
+	// targets, and handling of the unreachable hosts depends on --first-only
 	if err := s.d[1].Stop(); err != nil {
 		t.Fatalf("Unexpected error from Stop(): %s", err)
 	}
 	dir = getDir(t, "testdata/multi", "")
-	assertTargetsForDir(dir, 1, 0, 2)
+	assertUnreachable(dir, 2, 2)
 	dir = getDir(t, "testdata/multi", "--first-only")
 	assertTargetsForDir(dir, 1, 0, 1)
 
@@ -245,6 +259,77 @@ func (s ApplierIntegrationSuite) TestTargetGroupChanForDir(t *testing.T) {
 	}
 }
 
+func TestProcessDDLCollectShellOut(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:password@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	target := &Target{
+		Instance:   inst,
+		Dir:        getDir(t, "testdata/simple", "--dry-run"),
+		SchemaName: "product",
+		Migration:  NewMigrationFile("sql", "none"),
+		Plan:       NewPlan("production"),
+	}
+	ddls := []*DDLStatement{
+		{stmt: "DROP TABLE foo", instance: inst},
+		{shellOut: &util.ShellOut{Command: "echo hi"}, instance: inst},
+		{stmt: "DROP TABLE bar", instance: inst},
+	}
+
+	// A shell-out cannot be collected, so it and any later statements must be
+	// counted as skipped rather than silently omitted from the files
+	printer := NewRecordingPrinter()
+	if skipCount := target.processDDL(ddls, printer); skipCount != 2 {
+		t.Errorf("Expected skip count of 2, instead found %d", skipCount)
+	}
+	if target.Migration.Len() != 1 || target.Plan.Len() != 1 || len(printer.Operations()) != 1 {
+		t.Errorf("Expected only first statement to be collected and printed, instead found %d, %d, %d", target.Migration.Len(), target.Plan.Len(), len(printer.Operations()))
+	}
+}
+
+func TestMergePartialSchema(t *testing.T) {
+	live := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{{Name: "posts"}, {Name: "users", Comment: "live"}},
+		Routines:  []*tengo.Routine{{Name: "func1", Type: tengo.ObjectTypeFunc}, {Name: "func1", Type: tengo.ObjectTypeProc}},
+	}
+	partial := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "utf8mb4",
+		Collation: "utf8mb4_general_ci",
+		Tables:    []*tengo.Table{{Name: "users", Comment: "partial"}, {Name: "comments"}},
+		Routines:  []*tengo.Routine{{Name: "func1", Type: tengo.ObjectTypeProc, Body: "partial"}},
+	}
+
+	merged := mergePartialSchema(live, partial)
+	if merged.CharSet != live.CharSet || merged.Collation != live.Collation {
+		t.Errorf("Expected merged schema to retain live charset and collation; instead found %s / %s", merged.CharSet, merged.Collation)
+	}
+	tables := merged.TablesByName()
+	if len(tables) != 3 {
+		t.Errorf("Expected merged schema to have 3 tables, instead found %d", len(tables))
+	} else if tables["users"].Comment != "partial" || tables["posts"] != live.Tables[0] {
+		t.Errorf("Unexpected tables in merged schema: %+v", tables)
+	}
+	if len(merged.Routines) != 2 {
+		t.Errorf("Expected merged schema to have 2 routines, instead found %d", len(merged.Routines))
+	} else if proc := merged.ProceduresByName()["func1"]; proc == nil || proc.Body != "partial" {
+		t.Errorf("Unexpected procedure in merged schema: %+v", proc)
+	} else if merged.FunctionsByName()["func1"] == nil {
+		t.Error("Expected merged schema to retain live function, but it was not found")
+	}
+	if len(partial.Tables) != 2 || len(partial.Routines) != 1 || partial.CharSet != "utf8mb4" {
+		t.Error("mergePartialSchema unexpectedly modified its partial schema arg")
+	}
+
+	if mergePartialSchema(nil, partial) != partial {
+		t.Error("Expected mergePartialSchema to return partial schema as-is when live schema is nil")
+	}
+}
+
 func getBaseConfig(t *testing.T, cliFlags string) *mybase.Config {
 	cmd := mybase.NewCommand("appliertest", "", "", nil)
 	cmd.AddOption(mybase.BoolOption("verify", 0, true, "Test all generated ALTER statements on temp schema to verify correctness"))
@@ -252,15 +337,42 @@ func getBaseConfig(t *testing.T, cliFlags string) *mybase.Config {
 	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Output DDL but don't run it; equivalent to `skeema diff`"))
 	cmd.AddOption(mybase.BoolOption("first-only", '1', false, "For dirs mapping to multiple instances or schemas, just run against the first per dir"))
 	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("reorder-columns", 0, true, "Move existing columns as needed to match column order in *.sql table definitions"))
+	cmd.AddOption(mybase.BoolOption("include-system-columns", 0, false, "Include hidden columns generated automatically by the server, such as invisible primary keys, in diffs"))
 	cmd.AddOption(mybase.BoolOption("foreign-key-checks", 0, false, "Force the server to check referential integrity of any new foreign key"))
+	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
 	cmd.AddOption(mybase.BoolOption("brief", 'q', false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("group-by-safety", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("backfill-nulls", 0, false, "Before changing columns to NOT NULL, update any NULL values to the column's default"))
+	cmd.AddOption(mybase.BoolOption("drop-if-exists", 0, false, "Add IF EXISTS to generated DROP statements, tolerating objects already dropped out-of-band"))
+	cmd.AddOption(mybase.BoolOption("exact-row-counts", 0, false, "Count rows exactly, rather than estimating, when reporting rows rewritten by ALTER TABLE"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper", 'x', "", "External bin to shell out to for ALTER TABLE; see manual for template vars"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper-min-size", 0, "0", "Ignore --alter-wrapper for tables smaller than this size in bytes"))
 	cmd.AddOption(mybase.StringOption("alter-lock", 0, "", `Apply a LOCK clause to all ALTER TABLEs (valid values: "none", "shared", "exclusive")`))
 	cmd.AddOption(mybase.StringOption("alter-algorithm", 0, "", `Apply an ALGORITHM clause to all ALTER TABLEs (valid values: "inplace", "copy", "instant")`))
 	cmd.AddOption(mybase.StringOption("ddl-wrapper", 'X', "", "Like --alter-wrapper, but applies to all DDL types (CREATE, DROP, ALTER)"))
+	cmd.AddOption(mybase.StringOption("osc-tool", 0, "none", `Run ALTER TABLEs which rebuild or copy the table via an online schema change tool (valid values: "none", "gh-ost", "pt-osc")`))
+	cmd.AddOption(mybase.StringOption("osc-chunk-size", 0, "0", "Number of rows copied per chunk by osc-tool; 0 uses the tool's default"))
+	cmd.AddOption(mybase.StringOption("osc-max-lag", 0, "0", "Make osc-tool throttle whenever replica lag exceeds this many seconds; 0 uses the tool's default"))
+	cmd.AddOption(mybase.StringOption("osc-cut-over", 0, "atomic", `Table swap method used by osc-tool=gh-ost (valid values: "atomic", "two-step")`))
+	cmd.AddOption(mybase.StringOption("osc-args", 0, "", "Additional command-line args for osc-tool; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("require-wrapper", 0, "none", `Forbid ALTER TABLEs that copy or rebuild the table unless using alter-wrapper (valid values: "none", "copy", "rebuild")`))
 	cmd.AddOption(mybase.StringOption("safe-below-size", 0, "0", "Always permit destructive operations for tables below this size in bytes"))
+	cmd.AddOption(mybase.StringOption("unsafe-ops", 0, "", "Permit running these comma-separated classes of destructive operations without allow-unsafe; see manual for classes"))
 	cmd.AddOption(mybase.StringOption("concurrent-instances", 'c', "1", "Perform operations on this number of instances concurrently"))
+	cmd.AddOption(mybase.StringOption("max-replica-lag", 0, "0", "On replicas, wait before and after each DDL until replication lag is at most this many seconds"))
+	cmd.AddOption(mybase.StringOption("replica-lag-timeout", 0, "300", "Max seconds to wait for replication lag to fall to max-replica-lag"))
+	cmd.AddOption(mybase.StringOption("fatal-warnings", 0, "", `Treat DDL warnings with these comma-separated codes (or "all") as errors, halting remaining DDL; the DDL itself is already applied`))
+	cmd.AddOption(mybase.StringOption("ddl-retries", 0, "0", "Retry DDL failing due to lock wait timeout or deadlock up to this many times"))
+	cmd.AddOption(mybase.StringOption("ddl-retry-backoff", 0, "1", "Seconds to wait before the first DDL retry; doubled for each subsequent retry"))
+	cmd.AddOption(mybase.StringOption("check-convergence", 0, "off", `After pushing, re-introspect and diff again to confirm no differences remain (valid values: "off", "warn", "error")`))
+	cmd.AddOption(mybase.StringOption("before-statement", 0, "", "Shell out to this command before each DDL statement, aborting if it fails; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("after-statement", 0, "", "Shell out to this command after each DDL statement; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("audit-table", 0, "", "Insert a row describing each executed DDL statement into this schema.table on the target instance"))
+	cmd.AddOption(mybase.StringOption("introspection-cache-table", 0, "", "Like introspection-cache, but cache in this schema.table on each instance"))
+	cmd.AddOption(mybase.BoolOption("no-cache", 0, false, "Ignore any cached schemas from introspection-cache or introspection-cache-table, introspecting fully"))
+	cmd.AddOption(mybase.StringOption("external-objects", 0, "", "Never create, alter, or drop these comma-separated objects, e.g. table:name,proc:name"))
+	cmd.AddOption(mybase.StringOption("external-objects-file", 0, "", "Never create, alter, or drop objects listed in this file, one per line"))
 	cmd.AddArg("environment", "production", false)
 	util.AddGlobalOptions(cmd)
 	return mybase.ParseFakeCLI(t, cmd, fmt.Sprintf("appliertest %s", cliFlags))
@@ -0,0 +1,109 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// triggerDiff represents a difference in a trigger between the filesystem and
+// a live schema. tengo does not support triggers, so these diffs are computed
+// separately. It satisfies the tengo.ObjectDiff interface. There is no ALTER
+// TRIGGER statement, so a modified trigger is represented by a pair of
+// triggerDiffs: a drop followed by a create.
+type triggerDiff struct {
+	from      *workspace.Trigger // nil for a create
+	to        *workspace.Trigger // nil for a drop
+	allowDrop bool               // if true, permit drops even if mods do not permit unsafe operations
+}
+
+// DiffType returns the type of diff operation.
+func (td *triggerDiff) DiffType() tengo.DiffType {
+	if td.from == nil {
+		return tengo.DiffTypeCreate
+	}
+	return tengo.DiffTypeDrop
+}
+
+// ObjectKey returns a value representing the type and name of the trigger.
+func (td *triggerDiff) ObjectKey() tengo.ObjectKey {
+	if td.to != nil {
+		return tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: td.to.Name}
+	}
+	return tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: td.from.Name}
+}
+
+// Statement returns the full DDL statement corresponding to the triggerDiff. A
+// non-nil error will be returned if the statement is a DROP TRIGGER, and
+// neither mods nor the allow-drop-trigger option permit it.
+func (td *triggerDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if td.DiffType() == tengo.DiffTypeCreate {
+		return td.to.CreateStatement, nil
+	}
+	stmt := fmt.Sprintf("DROP TRIGGER %s", tengo.EscapeIdentifier(td.from.Name))
+	var err error
+	if !mods.AllowUnsafe && !td.allowDrop {
+		err = &tengo.ForbiddenDiffError{
+			Reason:    "DROP TRIGGER not permitted; use allow-drop-trigger or allow-unsafe",
+			Statement: stmt,
+		}
+	}
+	return stmt, err
+}
+
+// diffTriggers compares liveTriggers to desiredTriggers, returning triggerDiffs
+// for each trigger which must be created, re-created, or dropped. Triggers on
+// tables with names matching mods.IgnoreTable are ignored. If partial is true,
+// triggers missing from desiredTriggers are not dropped. allowDrop reflects the
+// allow-drop-trigger option.
+func diffTriggers(liveTriggers, desiredTriggers []*workspace.Trigger, mods tengo.StatementModifiers, partial, allowDrop bool) []tengo.ObjectDiff {
+	ignored := func(trigger *workspace.Trigger) bool {
+		return mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(trigger.Table)
+	}
+	liveByName := make(map[string]*workspace.Trigger, len(liveTriggers))
+	for _, trigger := range liveTriggers {
+		liveByName[strings.ToLower(trigger.Name)] = trigger
+	}
+
+	var diffs []tengo.ObjectDiff
+	for _, trigger := range desiredTriggers {
+		live := liveByName[strings.ToLower(trigger.Name)]
+		delete(liveByName, strings.ToLower(trigger.Name))
+		if ignored(trigger) || (live != nil && trigger.Matches(live, mods.CompareMetadata)) {
+			continue
+		}
+		if live != nil {
+			diffs = append(diffs, &triggerDiff{from: live, allowDrop: allowDrop})
+		}
+		diffs = append(diffs, &triggerDiff{to: trigger})
+	}
+	if !partial {
+		for _, live := range liveTriggers {
+			if liveByName[strings.ToLower(live.Name)] != nil && !ignored(live) {
+				diffs = append(diffs, &triggerDiff{from: live, allowDrop: allowDrop})
+			}
+		}
+	}
+	return diffs
+}
+
+// orderTriggerDiffs returns objDiffs reordered so that triggers are dropped
+// before any other changes, since dropping a table also drops its triggers,
+// and created after all other changes, since their tables must already exist.
+func orderTriggerDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	var first, middle, last []tengo.ObjectDiff
+	for _, objDiff := range objDiffs {
+		if objDiff.ObjectKey().Type != fs.ObjectTypeTrigger {
+			middle = append(middle, objDiff)
+		} else if objDiff.DiffType() == tengo.DiffTypeDrop {
+			first = append(first, objDiff)
+		} else {
+			last = append(last, objDiff)
+		}
+	}
+	result := append(first, middle...)
+	return append(result, last...)
+}
@@ -0,0 +1,68 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestTriggerDiffStatement(t *testing.T) {
+	trig := &workspace.Trigger{
+		Name:            "trg1",
+		Table:           "users",
+		Timing:          "BEFORE",
+		Event:           "INSERT",
+		Body:            "SET NEW.name = UPPER(NEW.name)",
+		CreateStatement: "CREATE TRIGGER trg1 BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name)",
+	}
+	mods := tengo.StatementModifiers{}
+	expectKey := tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: "trg1"}
+
+	create := &triggerDiff{to: trig}
+	if create.DiffType() != tengo.DiffTypeCreate || create.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", create.DiffType(), create.ObjectKey())
+	}
+	if stmt, err := create.Statement(mods); stmt != trig.CreateStatement || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	drop := &triggerDiff{from: trig}
+	if drop.DiffType() != tengo.DiffTypeDrop || drop.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", drop.DiffType(), drop.ObjectKey())
+	}
+	if stmt, err := drop.Statement(mods); stmt != "DROP TRIGGER `trg1`" || !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	drop.allowDrop = true
+	if stmt, err := drop.Statement(mods); stmt != "DROP TRIGGER `trg1`" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	drop.allowDrop = false
+	mods.AllowUnsafe = true
+	if stmt, err := drop.Statement(mods); stmt != "DROP TRIGGER `trg1`" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	if stmt := addDropIfExists("DROP TRIGGER `trg1`", drop, tengo.FlavorMySQL80); stmt != "DROP TRIGGER IF EXISTS `trg1`" {
+		t.Errorf("Unexpected return from addDropIfExists: %q", stmt)
+	}
+}
+
+func TestOrderTriggerDiffs(t *testing.T) {
+	trig := &workspace.Trigger{Name: "trg1"}
+	view := &workspace.View{Name: "v1"}
+	objDiffs := []tengo.ObjectDiff{
+		&triggerDiff{to: trig},
+		&viewDiff{from: view},
+		&viewDiff{to: view},
+		&triggerDiff{from: trig},
+	}
+	ordered := orderTriggerDiffs(orderViewDiffs(objDiffs))
+	expected := []tengo.ObjectDiff{objDiffs[3], objDiffs[1], objDiffs[2], objDiffs[0]}
+	for n := range expected {
+		if ordered[n] != expected[n] {
+			t.Errorf("Unexpected diff at position %d: %s %s", n, ordered[n].DiffType(), ordered[n].ObjectKey())
+		}
+	}
+}
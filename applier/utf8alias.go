@@ -0,0 +1,104 @@
+package applier
+
+import (
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// utf8AliasForFlavor returns the name which flavor uses to report the utf8
+// character set: MySQL 8.0.30+ and MariaDB 10.6+ report it as utf8mb3, while
+// older versions report it as utf8.
+func utf8AliasForFlavor(flavor tengo.Flavor) string {
+	if flavor.MySQLishMinVersion(8, 0, 30) || flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 6) {
+		return "utf8mb3"
+	}
+	return "utf8"
+}
+
+// normalizeUTF8Aliases rewrites all utf8 character set and collation names in
+// both schemaFromInstance and schemaFromDir to use the name reported by flavor.
+// This prevents spurious differences when the two sides use different names
+// for the same character set, for example if a workspace and a target instance
+// run different server versions. Modified dir tables and routines are replaced
+// with copies, since the same desired schema may be shared by other targets.
+func normalizeUTF8Aliases(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) {
+	alias := utf8AliasForFlavor(flavor)
+	for _, schema := range []*tengo.Schema{schemaFromInstance, schemaFromDir} {
+		if schema == nil {
+			continue
+		}
+		schema.CharSet = fs.UTF8Alias(schema.CharSet, alias)
+		schema.Collation = fs.UTF8Alias(schema.Collation, alias)
+	}
+	if schemaFromInstance != nil {
+		for n, table := range schemaFromInstance.Tables {
+			if normalized := tableWithUTF8Alias(table, alias, flavor); normalized != nil {
+				schemaFromInstance.Tables[n] = normalized
+			}
+		}
+		for n, routine := range schemaFromInstance.Routines {
+			if normalized := routineWithUTF8Alias(routine, alias); normalized != nil {
+				schemaFromInstance.Routines[n] = normalized
+			}
+		}
+	}
+	dirTables := make([]*tengo.Table, len(schemaFromDir.Tables))
+	for n, table := range schemaFromDir.Tables {
+		dirTables[n] = table
+		if normalized := tableWithUTF8Alias(table, alias, flavor); normalized != nil {
+			dirTables[n] = normalized
+		}
+	}
+	schemaFromDir.Tables = dirTables
+	dirRoutines := make([]*tengo.Routine, len(schemaFromDir.Routines))
+	for n, routine := range schemaFromDir.Routines {
+		dirRoutines[n] = routine
+		if normalized := routineWithUTF8Alias(routine, alias); normalized != nil {
+			dirRoutines[n] = normalized
+		}
+	}
+	schemaFromDir.Routines = dirRoutines
+}
+
+// tableWithUTF8Alias returns a copy of table with all utf8 character set and
+// collation names rewritten to use alias, or nil if no changes are needed.
+func tableWithUTF8Alias(table *tengo.Table, alias string, flavor tengo.Flavor) *tengo.Table {
+	normalized := *table
+	changed := false
+	replace := func(name string) string {
+		newName := fs.UTF8Alias(name, alias)
+		changed = changed || (newName != name)
+		return newName
+	}
+	normalized.CharSet = replace(table.CharSet)
+	normalized.Collation = replace(table.Collation)
+	normalized.Columns = make([]*tengo.Column, len(table.Columns))
+	for n, col := range table.Columns {
+		normalized.Columns[n] = col
+		if charSet, collation := replace(col.CharSet), replace(col.Collation); charSet != col.CharSet || collation != col.Collation {
+			colCopy := *col
+			colCopy.CharSet, colCopy.Collation = charSet, collation
+			normalized.Columns[n] = &colCopy
+		}
+	}
+	create := fs.ReplaceUTF8Alias(table.CreateStatement, alias)
+	if !changed && create == table.CreateStatement {
+		return nil
+	}
+	stripTableClause(&normalized, create, flavor)
+	return &normalized
+}
+
+// routineWithUTF8Alias returns a copy of routine with all utf8 character set
+// and collation names rewritten to use alias, or nil if no changes are needed.
+func routineWithUTF8Alias(routine *tengo.Routine, alias string) *tengo.Routine {
+	normalized := *routine
+	normalized.ParamString = fs.ReplaceUTF8Alias(routine.ParamString, alias)
+	normalized.ReturnDataType = fs.ReplaceUTF8Alias(routine.ReturnDataType, alias)
+	normalized.DatabaseCollation = fs.UTF8Alias(routine.DatabaseCollation, alias)
+	normalized.CreateStatement = fs.ReplaceUTF8Alias(routine.CreateStatement, alias)
+	if normalized == *routine {
+		return nil
+	}
+	return &normalized
+}
@@ -0,0 +1,89 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestNormalizeUTF8Aliases(t *testing.T) {
+	makeSchema := func(alias string, flavor tengo.Flavor) *tengo.Schema {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            alias,
+			Collation:          alias + "_general_ci",
+			CollationIsDefault: true,
+			Columns: []*tengo.Column{
+				{Name: "id", TypeInDB: "int"},
+				{Name: "name", TypeInDB: "varchar(30)", CharSet: alias, Collation: alias + "_bin"},
+				{Name: "code", TypeInDB: "char(3)", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", Nullable: true, Default: "NULL"},
+			},
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "id"}}, PrimaryKey: true, Unique: true},
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		routine := &tengo.Routine{
+			Name:              "widget_name",
+			Type:              tengo.ObjectTypeFunc,
+			Body:              "RETURN 'widget'",
+			ParamString:       "id int",
+			ReturnDataType:    "varchar(30) CHARSET " + alias,
+			Definer:           "root@%",
+			DatabaseCollation: alias + "_general_ci",
+			SecurityType:      "DEFINER",
+		}
+		routine.CreateStatement = routine.Definition(flavor)
+		return &tengo.Schema{
+			Name:      "product",
+			CharSet:   alias,
+			Collation: alias + "_general_ci",
+			Tables:    []*tengo.Table{table},
+			Routines:  []*tengo.Routine{routine},
+		}
+	}
+
+	// Without normalization, the two names for the same character set are seen
+	// as differences
+	newFlavor := tengo.NewFlavor("mysql", 8, 0, 30)
+	schemaFromInstance := makeSchema("utf8mb3", newFlavor)
+	schemaFromDir := makeSchema("utf8", newFlavor)
+	if diffs := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs(); len(diffs) == 0 {
+		t.Fatal("Test setup problem: expected differences between utf8 and utf8mb3 prior to normalization")
+	}
+
+	for _, flavor := range []tengo.Flavor{newFlavor, tengo.NewFlavor("mariadb", 10, 6), tengo.FlavorMySQL57, tengo.FlavorMariaDB104} {
+		expectAlias := utf8AliasForFlavor(flavor)
+		for _, aliases := range [][2]string{{"utf8mb3", "utf8"}, {"utf8", "utf8mb3"}, {expectAlias, expectAlias}} {
+			schemaFromInstance := makeSchema(aliases[0], flavor)
+			schemaFromDir := makeSchema(aliases[1], flavor)
+			origDirTable := schemaFromDir.Tables[0]
+			origDirCreate := origDirTable.CreateStatement
+			normalizeUTF8Aliases(schemaFromInstance, schemaFromDir, flavor)
+			if diffs := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs(); len(diffs) != 0 {
+				t.Errorf("Flavor %s, instance %s vs dir %s: expected no differences after normalization, instead found %d", flavor, aliases[0], aliases[1], len(diffs))
+			}
+			for _, schema := range []*tengo.Schema{schemaFromInstance, schemaFromDir} {
+				table := schema.Tables[0]
+				if table.CharSet != expectAlias || table.Columns[1].Collation != expectAlias+"_bin" || table.UnsupportedDDL {
+					t.Errorf("Flavor %s: unexpected table after normalization: %+v", flavor, *table)
+				}
+				if !strings.Contains(table.CreateStatement, "DEFAULT CHARSET="+expectAlias) || fs.ReplaceUTF8Alias(table.CreateStatement, expectAlias) != table.CreateStatement {
+					t.Errorf("Flavor %s: unexpected CREATE TABLE after normalization:\n%s", flavor, table.CreateStatement)
+				}
+				if routine := schema.Routines[0]; routine.ReturnDataType != "varchar(30) CHARSET "+expectAlias || routine.DatabaseCollation != expectAlias+"_general_ci" {
+					t.Errorf("Flavor %s: unexpected routine after normalization: %+v", flavor, *routine)
+				}
+			}
+
+			// The dir's original table must not be modified in-place, and must be
+			// left as-is if it already uses the expected alias
+			if origDirTable.CreateStatement != origDirCreate {
+				t.Errorf("Flavor %s: expected original dir table to be unmodified", flavor)
+			} else if aliases[1] == expectAlias && schemaFromDir.Tables[0] != origDirTable {
+				t.Errorf("Flavor %s: expected dir table already using %s to be left as-is", flavor, expectAlias)
+			}
+		}
+	}
+}
@@ -108,5 +108,5 @@ func VerifyDiff(diff *tengo.SchemaDiff, t *Target) error {
 }
 
 func wantVerify(diff *tengo.SchemaDiff, t *Target) bool {
-	return t.Dir.Config.GetBool("verify") && len(diff.TableDiffs) > 0 && !t.briefOutput()
+	return t.Dir.Config.GetBool("verify") && len(diff.TableDiffs) > 0 && !t.briefOutput() && !t.checkOnly
 }
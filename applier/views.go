@@ -0,0 +1,111 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// viewDiff represents a difference in a view between the filesystem and a live
+// schema. tengo does not support views, so these diffs are computed
+// separately. It satisfies the tengo.ObjectDiff interface.
+type viewDiff struct {
+	from *workspace.View // nil for a create
+	to   *workspace.View // nil for a drop
+}
+
+// DiffType returns the type of diff operation.
+func (vd *viewDiff) DiffType() tengo.DiffType {
+	if vd.from == nil {
+		return tengo.DiffTypeCreate
+	} else if vd.to == nil {
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the view.
+func (vd *viewDiff) ObjectKey() tengo.ObjectKey {
+	if vd.to != nil {
+		return tengo.ObjectKey{Type: fs.ObjectTypeView, Name: vd.to.Name}
+	}
+	return tengo.ObjectKey{Type: fs.ObjectTypeView, Name: vd.from.Name}
+}
+
+// Statement returns the full DDL statement corresponding to the viewDiff. A
+// modified view is replaced in-place using CREATE OR REPLACE VIEW. A non-nil
+// error will be returned if the statement is a DROP VIEW and mods do not
+// permit unsafe operations.
+func (vd *viewDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	switch vd.DiffType() {
+	case tengo.DiffTypeCreate:
+		return vd.to.CreateStatement, nil
+	case tengo.DiffTypeAlter:
+		return vd.to.ReplaceStatement(), nil
+	default:
+		stmt := fmt.Sprintf("DROP VIEW %s", tengo.EscapeIdentifier(vd.from.Name))
+		var err error
+		if !mods.AllowUnsafe {
+			err = &tengo.ForbiddenDiffError{
+				Reason:    "DROP VIEW not permitted",
+				Statement: stmt,
+			}
+		}
+		return stmt, err
+	}
+}
+
+// diffViews compares liveViews to desiredViews, returning a viewDiff for each
+// view which must be created, altered, or dropped. Views share a namespace with
+// tables, so views with names matching mods.IgnoreTable are ignored. If partial
+// is true, views missing from desiredViews are not dropped. Creates and alters
+// are returned in the order of desiredViews, which accounts for views referring
+// to other views.
+func diffViews(liveViews, desiredViews []*workspace.View, mods tengo.StatementModifiers, partial bool) []tengo.ObjectDiff {
+	ignored := func(name string) bool {
+		return mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(name)
+	}
+	liveByName := make(map[string]*workspace.View, len(liveViews))
+	for _, view := range liveViews {
+		liveByName[strings.ToLower(view.Name)] = view
+	}
+
+	var diffs []tengo.ObjectDiff
+	for _, view := range desiredViews {
+		live := liveByName[strings.ToLower(view.Name)]
+		delete(liveByName, strings.ToLower(view.Name))
+		if ignored(view.Name) || (live != nil && view.Matches(live)) {
+			continue
+		}
+		diffs = append(diffs, &viewDiff{from: live, to: view})
+	}
+	if !partial {
+		for _, live := range liveViews {
+			if liveByName[strings.ToLower(live.Name)] != nil && !ignored(live.Name) {
+				diffs = append(diffs, &viewDiff{from: live})
+			}
+		}
+	}
+	return diffs
+}
+
+// orderViewDiffs returns objDiffs reordered so that views are dropped before
+// any other changes, and created or altered after all other changes, since
+// views may refer to any table, routine, or sequence.
+func orderViewDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	var first, middle, last []tengo.ObjectDiff
+	for _, objDiff := range objDiffs {
+		if objDiff.ObjectKey().Type != fs.ObjectTypeView {
+			middle = append(middle, objDiff)
+		} else if objDiff.DiffType() == tengo.DiffTypeDrop {
+			first = append(first, objDiff)
+		} else {
+			last = append(last, objDiff)
+		}
+	}
+	result := append(first, middle...)
+	return append(result, last...)
+}
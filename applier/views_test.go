@@ -0,0 +1,76 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestViewDiffStatement(t *testing.T) {
+	from := &workspace.View{
+		Name:            "v1",
+		Definer:         "root@%",
+		Security:        "DEFINER",
+		CheckOption:     "NONE",
+		Definition:      "select `users`.`id` AS `id` from `users`",
+		Algorithm:       "UNDEFINED",
+		CreateStatement: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v1` AS select `users`.`id` AS `id` from `users`",
+	}
+	to := *from
+	to.Security = "INVOKER"
+	to.CreateStatement = "CREATE SQL SECURITY INVOKER VIEW v1 AS SELECT id FROM users"
+	mods := tengo.StatementModifiers{}
+	expectKey := tengo.ObjectKey{Type: fs.ObjectTypeView, Name: "v1"}
+
+	create := &viewDiff{to: &to}
+	if create.DiffType() != tengo.DiffTypeCreate || create.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", create.DiffType(), create.ObjectKey())
+	}
+	if stmt, err := create.Statement(mods); stmt != to.CreateStatement || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	alter := &viewDiff{from: from, to: &to}
+	if alter.DiffType() != tengo.DiffTypeAlter || alter.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", alter.DiffType(), alter.ObjectKey())
+	}
+	if stmt, err := alter.Statement(mods); stmt != "CREATE OR REPLACE SQL SECURITY INVOKER VIEW v1 AS SELECT id FROM users" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+
+	drop := &viewDiff{from: from}
+	if drop.DiffType() != tengo.DiffTypeDrop || drop.ObjectKey() != expectKey {
+		t.Errorf("Unexpected DiffType %s or ObjectKey %s", drop.DiffType(), drop.ObjectKey())
+	}
+	if stmt, err := drop.Statement(mods); stmt != "DROP VIEW `v1`" || !tengo.IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	mods.AllowUnsafe = true
+	if stmt, err := drop.Statement(mods); stmt != "DROP VIEW `v1`" || err != nil {
+		t.Errorf("Unexpected return from Statement(): %q / %v", stmt, err)
+	}
+	if stmt := addDropIfExists("DROP VIEW `v1`", drop, tengo.FlavorMySQL80); stmt != "DROP VIEW IF EXISTS `v1`" {
+		t.Errorf("Unexpected return from addDropIfExists: %q", stmt)
+	}
+}
+
+func TestOrderViewDiffs(t *testing.T) {
+	view := &workspace.View{Name: "v1"}
+	seq := &workspace.Sequence{Name: "s1"}
+	objDiffs := []tengo.ObjectDiff{
+		&viewDiff{to: view},
+		&sequenceDiff{to: seq},
+		&viewDiff{from: view},
+		&sequenceDiff{from: seq},
+		&viewDiff{from: view, to: view},
+	}
+	ordered := orderViewDiffs(orderSequenceDiffs(objDiffs))
+	expected := []tengo.ObjectDiff{objDiffs[2], objDiffs[1], objDiffs[3], objDiffs[0], objDiffs[4]}
+	for n := range expected {
+		if ordered[n] != expected[n] {
+			t.Errorf("Unexpected diff at position %d: %s %s", n, ordered[n].DiffType(), ordered[n].ObjectKey())
+		}
+	}
+}
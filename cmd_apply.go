@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+)
+
+func init() {
+	summary := "Execute a plan file generated by `skeema diff --plan`"
+	desc := `Executes the DDL in a plan file previously written by ` + "`" + `skeema diff --plan` + "`" + `. This
+supports a two-step workflow, in which the output of ` + "`" + `skeema diff` + "`" + ` is reviewed,
+and then exactly the reviewed statements are run, without generating a new diff.
+
+The plan file records the fingerprint of each live schema at the time the plan
+was made. Before running anything, each schema is fingerprinted again, and if
+any of them no longer match the plan, the command refuses to proceed, since the
+planned statements may no longer be correct. In this situation, generate a new
+plan. The fingerprint covers tables and routines, ignoring AUTO_INCREMENT
+values and any tables matching the ignore-table option; see
+` + "`" + `skeema help fingerprint` + "`" + ` for details.
+
+Connection settings and options affecting DDL execution, such as ddl-retries
+and fatal-warnings, are obtained from the configuration of the directories that
+the plan was generated from. These directories must still exist, and map to the
+same instances.
+
+The first argument is the path to the plan file. You may optionally pass an
+environment name as a second argument; this must match the environment used to
+generate the plan. If no environment name is supplied, the default is
+"production".
+
+An exit code of 0 will be returned if the plan was applied successfully, or 2+
+if an error occurred, including if any schema changed since the plan was made.`
+
+	cmd := mybase.NewCommand("apply", summary, desc, ApplyHandler)
+	cmd.AddArg("plan-file", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+	clonePushOptionsToApply()
+}
+
+// ApplyHandler is the handler method for `skeema apply`
+func ApplyHandler(cfg *mybase.Config) error {
+	planPath := cfg.Get("plan-file")
+	plan, err := applier.ReadPlan(planPath)
+	if os.IsNotExist(err) {
+		return NewExitValue(CodeNoInput, "Plan file %s does not exist", planPath)
+	} else if err != nil {
+		return NewExitValue(CodeBadInput, err.Error())
+	}
+	if environment := cfg.Get("environment"); environment != plan.Environment {
+		return NewExitValue(CodeBadUsage, "Plan file %s was generated for environment %q, but environment %q was specified", planPath, plan.Environment, environment)
+	}
+
+	// Confirm no schema has drifted since the plan was made, before running
+	// anything on any of them
+	targets := make([]*applier.Target, len(plan.Targets))
+	for n, pt := range plan.Targets {
+		if targets[n], err = targetForPlan(pt, cfg); err != nil {
+			return err
+		}
+		if err := pt.CheckDrift(targets[n]); err != nil {
+			return NewExitValue(CodeFatalError, "Refusing to apply plan file %s: %s", planPath, err)
+		}
+	}
+
+	// Executed statements are recorded in any audit log configured for the
+	// first dir, consistent with push
+	if len(targets) > 0 {
+		audit, err := applier.AuditLogForDir(targets[0].Dir)
+		if err != nil {
+			return NewExitValue(CodeCantCreate, err.Error())
+		}
+		defer audit.Close()
+		for _, t := range targets {
+			t.Audit = audit
+		}
+	}
+
+	printer := applier.NewPrinter(false)
+	results := make([]applier.Result, 0, len(targets))
+	for n, pt := range plan.Targets {
+		result, err := targets[n].ApplyPlan(pt, printer)
+		if _, ok := err.(applier.ConfigError); ok {
+			return NewExitValue(CodeBadConfig, err.Error())
+		} else if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+	if sum := applier.SumResults(results); sum.SkipCount > 0 {
+		return NewExitValue(CodeFatalError, sum.Summary())
+	}
+	log.Infof("Plan file %s applied successfully", planPath)
+	return nil
+}
+
+// targetForPlan returns a Target for applying pt, using the configuration of
+// the directory that pt was generated from.
+func targetForPlan(pt *applier.PlanTarget, cfg *mybase.Config) (*applier.Target, error) {
+	dir, err := fs.ParseDir(pt.Dir, cfg)
+	if err != nil {
+		return nil, NewExitValue(CodeBadConfig, "Unable to use dir %s from plan: %s", pt.Dir, err)
+	}
+	instances, err := dir.Instances()
+	if err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	}
+	for _, inst := range instances {
+		if inst.String() == pt.Instance {
+			return &applier.Target{Instance: inst, Dir: dir, SchemaName: pt.Schema}, nil
+		}
+	}
+	return nil, NewExitValue(CodeBadConfig, "%s no longer maps to instance %s, so the plan cannot be applied", dir, pt.Instance)
+}
+
+// clonePushOptionsToApply copies options from `skeema push` into
+// `skeema apply`. Only options which affect how DDL is executed remain visible
+// on the CLI, since the DDL itself is already determined by the plan.
+func clonePushOptionsToApply() {
+	push, ok := CommandSuite.SubCommands["push"]
+	if !ok {
+		return
+	}
+	visible := map[string]bool{
+		"max-replica-lag":     true,
+		"replica-lag-timeout": true,
+		"fatal-warnings":      true,
+		"ddl-retries":         true,
+		"ddl-retry-backoff":   true,
+		"before-statement":    true,
+		"after-statement":     true,
+		"audit-log":           true,
+		"audit-table":         true,
+	}
+	hiddenRewrites := make(map[string]bool)
+	for name := range push.Options() {
+		hiddenRewrites[name] = !visible[name]
+	}
+	clonePushOptions("apply", nil, hiddenRewrites)
+}
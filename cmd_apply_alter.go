@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/dumper"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Run an ad-hoc ALTER TABLE and update the table's file to match"
+	desc := `Runs a single ALTER TABLE against the database instance and schema defined by
+the current directory, and then updates the table's corresponding .sql file to
+reflect the table's new definition. This supports a workflow of making a change
+directly in the database, and then immediately persisting that change to the
+filesystem.
+
+The first argument is the table name, and the second argument is the body of the
+ALTER TABLE, everything after "ALTER TABLE [name] ". For example:
+` + "`" + `skeema apply-alter users "ADD COLUMN last_login timestamp NULL"` + "`" + `
+
+Before running anything, the table's .sql file is compared to the table's
+current definition in the database. If they differ in any way, the command
+refuses to proceed, since the file would otherwise become out of sync in ways
+unrelated to the requested change. In this situation, use ` + "`" + `skeema push` + "`" + ` or
+` + "`" + `skeema pull` + "`" + ` first.
+
+This command must be run from a directory which defines both a host and a single
+schema.
+
+You may optionally pass an environment name as a third argument. This will
+affect which section of .skeema config files is used for processing. If no
+environment name is supplied, the default is "production".`
+
+	cmd := mybase.NewCommand("apply-alter", summary, desc, ApplyAlterHandler)
+	cmd.AddOption(mybase.BoolOption("preserve-comments", 0, false, "When updating the CREATE TABLE statement, retain comments from inside the table body"))
+	cmd.AddOption(mybase.BoolOption("explicit-collations", 0, false, "Include character set and collation clauses for every table and string column, even if they match defaults"))
+	addUTF8AliasOption(cmd)
+	cmd.AddArg("table", "", true)
+	cmd.AddArg("alter", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// ApplyAlterHandler is the handler method for `skeema apply-alter`
+func ApplyAlterHandler(cfg *mybase.Config) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+	if !dir.Config.Changed("host") || !dir.HasSchema() || len(dir.LogicalSchemas) == 0 {
+		return NewExitValue(CodeBadConfig, "`skeema apply-alter` must be run from a directory which defines both a host and schema, and contains *.sql files")
+	}
+	instance, err := dir.FirstInstance()
+	if err != nil {
+		return err
+	} else if instance == nil {
+		return NewExitValue(CodeBadConfig, "No host defined for environment %q in %s", dir.Config.Get("environment"), dir)
+	}
+	schemaNames, err := dir.SchemaNames(instance)
+	if err != nil {
+		return err
+	} else if len(schemaNames) != 1 {
+		return NewExitValue(CodeBadConfig, "%s maps to %s on %s, but `skeema apply-alter` requires exactly one", dir, countAndNoun(len(schemaNames), "schema", "schemas"), instance)
+	}
+	schemaName := schemaNames[0]
+
+	tableName := cfg.Get("table")
+	clauses := strings.TrimRight(strings.TrimSpace(cfg.Get("alter")), "; \t\n")
+	if tableName == "" || clauses == "" {
+		return NewExitValue(CodeBadUsage, "`skeema apply-alter` requires a table name and the body of an ALTER TABLE")
+	}
+	introspectOpts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	} else if introspectOpts.IgnoreTable != nil && introspectOpts.IgnoreTable.MatchString(tableName) {
+		return NewExitValue(CodeBadConfig, "Table %s matches ignore-table, so it cannot be altered by Skeema", tableName)
+	} else if !introspectOpts.IncludesType(tengo.ObjectTypeTable) {
+		return NewExitValue(CodeBadConfig, "Option object-types excludes tables, so %s cannot be altered by Skeema", tableName)
+	}
+	introspectOpts.ObjectTypes = []tengo.ObjectType{tengo.ObjectTypeTable}
+	logicalSchema := dir.LogicalSchemas[0]
+	key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: tableName}
+	if _, ok := logicalSchema.Creates[key]; !ok {
+		return NewExitValue(CodeBadInput, "No CREATE TABLE for %s found in %s", key, dir)
+	}
+	instSchema, err := introspect.Schema(instance, schemaName, introspectOpts)
+	if err == sql.ErrNoRows || (err == nil && instSchema == nil) {
+		return NewExitValue(CodeBadInput, "Schema %s does not exist on %s", schemaName, instance)
+	} else if err != nil {
+		return err
+	} else if !instSchema.HasTable(tableName) {
+		return NewExitValue(CodeBadInput, "Table %s does not exist in %s %s", key, instance, schemaName)
+	}
+
+	// Confirm the file and live table currently match, so that only the requested
+	// change will be synced back to the file. Differences in next auto-increment
+	// value are ignored, since they are never meaningful here.
+	mods := tengo.StatementModifiers{
+		AllowUnsafe: true,
+		NextAutoInc: tengo.NextAutoIncIgnore,
+		Flavor:      instance.Flavor(),
+	}
+	wsOpts, err := workspace.OptionsForDir(dir, instance)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	utf8Alias, err := utf8AliasForDir(dir)
+	if err != nil {
+		return err
+	}
+	inDiff, err := objectsInDiff(logicalSchema, instSchema, nil, nil, nil, nil, wsOpts, mods)
+	if err != nil {
+		return err
+	}
+	for _, diffKey := range inDiff {
+		if diffKey == key {
+			return NewExitValue(CodeBadInput, "The definition of %s in %s does not match %s %s. Use `skeema push` or `skeema pull` to resolve this before using `skeema apply-alter`.", key, dir, instance, schemaName)
+		}
+	}
+
+	// Run the ALTER
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return err
+	}
+	alter := fmt.Sprintf("ALTER TABLE %s %s", tengo.EscapeIdentifier(tableName), clauses)
+	log.Infof("Running on %s %s: %s", instance, schemaName, alter)
+	if _, err := db.Exec(alter); err != nil {
+		return NewExitValue(CodeFatalError, "Error running ALTER on %s %s: %s", instance, schemaName, err)
+	}
+
+	// Re-introspect the schema, and update only this table's file
+	if instSchema, err = introspect.Schema(instance, schemaName, introspectOpts); err != nil {
+		return err
+	} else if instSchema == nil || !instSchema.HasTable(tableName) {
+		return NewExitValue(CodePartialError, "ALTER completed, but %s no longer exists in %s %s, so its file was not updated. Use `skeema pull` to update the filesystem.", key, instance, schemaName)
+	}
+	dumpOpts := dumper.Options{
+		PreserveComments:   dir.Config.GetBool("preserve-comments"),
+		ExplicitCollations: dir.Config.GetBool("explicit-collations"),
+		UTF8Alias:          utf8Alias,
+		DefaultTableOpts:   wsOpts.DefaultTableOptions,
+	}
+	dumpOpts.OnlyKeys([]tengo.ObjectKey{key})
+	if count, err := dumper.DumpSchema(instSchema, dir, dumpOpts); err != nil {
+		return NewExitValue(CodeCantCreate, "ALTER completed, but unable to update %s in %s: %s", key, dir, err)
+	} else if count == 0 {
+		log.Infof("ALTER completed; the CREATE TABLE for %s in %s was already up to date", key, dir)
+	} else {
+		log.Infof("ALTER completed; updated the CREATE TABLE for %s in %s", key, dir)
+	}
+	return nil
+}
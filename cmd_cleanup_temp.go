@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Drop temporary schemas left behind by interrupted runs"
+	desc := `Finds and drops temporary workspace schemas which were left behind on database
+instances by a previous Skeema process that crashed or was killed before it
+could clean up. This is primarily useful for recovering from interrupted CI jobs.
+
+Schemas are considered to be temporary workspace schemas if their name is
+exactly the value of the temp-schema option, or begins with that value
+followed by an underscore. A schema is only dropped if no other process is
+currently holding its workspace lock, and if none of its tables contain any
+rows, or the schema's marker table shows that it was created by Skeema.
+Schemas failing either check are reported but left untouched.
+
+This command operates on all instances defined in the current directory and
+its subdirectories. You may optionally pass an environment name as a CLI arg
+to select which section of .skeema config files is used. If no environment
+name is supplied, the default is "production".
+
+An exit code of 0 will be returned if no temporary schemas were found, or all
+were dropped successfully; 1 if --dry-run found temporary schemas, or some
+could not be dropped due to a held lock or existing data; or 2+ if a fatal
+error occurred.`
+
+	cmd := mybase.NewCommand("cleanup-temp", summary, desc, CleanupTempHandler)
+	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Only report leftover temporary schemas; do not drop them"))
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// CleanupTempHandler is the handler method for `skeema cleanup-temp`
+func CleanupTempHandler(cfg *mybase.Config) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+
+	var foundCount, failCount int
+	seen := make(map[string]bool)
+	err = cleanupTempWalker(dir, 5, func(inst *tengo.Instance, dir *fs.Dir) error {
+		opts, err := cleanupTempOptions(dir, inst)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s %s", inst, opts.SchemaName)
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+		names, err := workspace.OrphanedTempSchemas(inst, opts.SchemaName)
+		if err != nil {
+			return fmt.Errorf("Unable to list schemas on %s: %s", inst, err)
+		}
+		for _, name := range names {
+			foundCount++
+			if dir.Config.GetBool("dry-run") {
+				log.Infof("Found temporary schema %s on %s", name, inst)
+			} else if err := workspace.DropOrphanedTempSchema(inst, name, opts); err != nil {
+				log.Warn(err.Error())
+				failCount++
+			} else {
+				log.Infof("Dropped temporary schema %s on %s", name, inst)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return NewExitValue(CodeFatalError, err.Error())
+	}
+
+	if failCount > 0 {
+		return NewExitValue(CodePartialError, "Unable to drop %s",
+			countAndNoun(failCount, "temporary schema", "temporary schemas"),
+		)
+	} else if foundCount > 0 && dir.Config.GetBool("dry-run") {
+		return NewExitValue(CodeDifferencesFound, "Found %s",
+			countAndNoun(foundCount, "temporary schema", "temporary schemas"),
+		)
+	} else if foundCount == 0 {
+		log.Info("No temporary schemas found")
+	}
+	return nil
+}
+
+// cleanupTempWalker calls f for each reachable instance defined by dir or its
+// subdirectories. Instances which cannot be reached are logged and skipped.
+func cleanupTempWalker(dir *fs.Dir, maxDepth int, f func(*tengo.Instance, *fs.Dir) error) error {
+	if dir.ParseError != nil {
+		return fmt.Errorf("Cannot process %s: %s", dir.RelPath(), dir.ParseError)
+	}
+	instances, err := dir.Instances()
+	if err != nil {
+		return fmt.Errorf("Cannot process %s: %s", dir.RelPath(), err)
+	}
+	for _, inst := range instances {
+		if ok, err := inst.CanConnect(); !ok {
+			log.Warnf("Skipping %s for %s: %s", inst, dir, err)
+			continue
+		}
+		if err := f(inst, dir); err != nil {
+			return err
+		}
+	}
+
+	subdirs, err := dir.Subdirs()
+	if err != nil {
+		return fmt.Errorf("Cannot list subdirs of %s: %s", dir, err)
+	} else if len(subdirs) > 0 && maxDepth <= 0 {
+		return fmt.Errorf("Not walking subdirs of %s: max depth reached", dir)
+	}
+	for _, sub := range subdirs {
+		if err := cleanupTempWalker(sub, maxDepth-1, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupTempOptions returns workspace options relevant to dropping leftover
+// temporary schemas in dir. The lock wait timeout is kept very short, since a
+// held lock means the schema is actively in use by another process.
+func cleanupTempOptions(dir *fs.Dir, inst *tengo.Instance) (workspace.Options, error) {
+	opts := workspace.Options{
+		Type:            workspace.TypeTempSchema,
+		SchemaName:      dir.Config.Get("temp-schema"),
+		LockWaitTimeout: time.Second,
+	}
+	if opts.SchemaName == "" {
+		return opts, errors.New("temp-schema option cannot be empty")
+	}
+	concurrency, err := dir.Config.GetInt("temp-schema-threads")
+	if err != nil {
+		return opts, err
+	} else if concurrency < 1 {
+		return opts, errors.New("temp-schema-threads cannot be less than 1")
+	}
+	opts.Concurrency = concurrency
+	binlogEnum, err := dir.Config.GetEnum("temp-schema-binlog", "on", "off", "auto")
+	if err != nil {
+		return opts, err
+	}
+	opts.SkipBinlog = (binlogEnum == "off" || (binlogEnum == "auto" && inst.CanSkipBinlog()))
+	return opts, nil
+}
@@ -1,7 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
 )
 
 func init() {
@@ -20,10 +30,17 @@ top of the file. If no environment name is supplied, the default is
 
 The ` + "`" + `skeema diff` + "`" + ` command is equivalent to ` + "`" + `skeema push --dry-run` + "`" + `.
 
+With --stdin, CREATE statements are read from STDIN instead of from *.sql
+files, and only the objects they define are compared. This permits quick
+ad-hoc comparisons against the host and schema configured for the current
+directory, without needing to edit any *.sql files. Subdirectories are not
+examined when --stdin is used.
+
 An exit code of 0 will be returned if no differences were found, 1 if some
 differences were found, or 2+ if an error occurred.`
 
 	cmd := mybase.NewCommand("diff", summary, desc, DiffHandler)
+	cmd.AddOption(mybase.BoolOption("stdin", 0, false, "Read CREATE statements from STDIN, and only compare the objects they define"))
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 	clonePushOptionsToDiff()
@@ -34,45 +51,136 @@ func DiffHandler(cfg *mybase.Config) error {
 	// We just delegate to PushHandler, forcing dry-run to be enabled
 	cfg.CLI.OptionValues["dry-run"] = "1"
 	cfg.MarkDirty()
+	if cfg.GetBool("stdin") {
+		return diffStdin(cfg, os.Stdin)
+	}
 	return PushHandler(cfg)
 }
 
-// clonePushOptionsToDiff copies options from `skeema push` into `skeema diff`
-func clonePushOptionsToDiff() {
-	// Logic relies on init() having been called in both cmd_push.go AND
-	// cmd_diff.go, so we call it from both places, but only one will succeed
-	diff, ok1 := CommandSuite.SubCommands["diff"]
-	push, ok2 := CommandSuite.SubCommands["push"]
-	if !ok1 || !ok2 {
-		return
+// diffStdin compares the objects defined by CREATE statements read from r to
+// the corresponding objects in the schema(s) mapped to by the current dir.
+// Objects not defined in r are ignored, rather than being treated as dropped.
+func diffStdin(cfg *mybase.Config, r io.Reader) error {
+	if cfg.Changed("dir") {
+		return NewExitValue(CodeBadConfig, "The stdin option cannot be combined with the dir option")
+	}
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
 	}
+	logicalSchema, err := logicalSchemaFromReader(r, dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	outcome, err := applier.DiffPartial(context.Background(), logicalSchema, dir, nil)
+	return exitValueForOutcome(dir, outcome, err)
+}
 
+// logicalSchemaFromReader parses SQL statements read from r into a
+// LogicalSchema, using the default character set and collation of dir. Only
+// CREATE statements are permitted, and they may not reference other schemas.
+func logicalSchemaFromReader(r io.Reader, dir *fs.Dir) (*fs.LogicalSchema, error) {
+	statements, err := fs.ParseStatementsFromReader(r, "stdin")
+	if err != nil {
+		return nil, err
+	}
+	logicalSchema := &fs.LogicalSchema{
+		CharSet:   dir.Config.Get("default-character-set"),
+		Collation: dir.Config.Get("default-collation"),
+		Creates:   make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	for _, stmt := range statements {
+		switch stmt.Type {
+		case fs.StatementTypeNoop:
+			continue
+		case fs.StatementTypeCreate:
+			if stmt.Schema() != "" {
+				return nil, fmt.Errorf("%s: Statements from STDIN may not reference a schema name", stmt.Location())
+			}
+			if err := logicalSchema.AddStatement(stmt); err != nil {
+				return nil, err
+			}
+		case fs.StatementTypeCommand:
+			if !strings.HasPrefix(strings.ToLower(stmt.Text), "delimiter") {
+				return nil, fmt.Errorf("%s: Statements from STDIN may not include USE commands", stmt.Location())
+			}
+		default:
+			return nil, fmt.Errorf("%s: Only CREATE statements may be supplied on STDIN", stmt.Location())
+		}
+	}
+	if len(logicalSchema.Creates) == 0 {
+		return nil, errors.New("No CREATE statements found on STDIN")
+	}
+	return logicalSchema, nil
+}
+
+// clonePushOptionsToDiff copies options from `skeema push` into `skeema diff`
+func clonePushOptionsToDiff() {
 	descRewrites := map[string]string{
-		"allow-unsafe":    "Permit generating ALTER or DROP operations that are potentially destructive",
-		"alter-wrapper":   "Output ALTER TABLEs as shell commands rather than just raw DDL; see manual for template vars",
-		"brief":           "Don't output DDL to STDOUT; instead output list of instances with at least one difference",
-		"safe-below-size": "Always permit generating destructive operations for tables below this size in bytes",
+		"allow-drop-partition":  "Permit generating ALTER TABLE ... DROP PARTITION from manage-partition-list without allow-unsafe",
+		"allow-drop-trigger":    "Permit generating DROP TRIGGER, including to re-create modified triggers, without allow-unsafe",
+		"allow-unsafe":          "Permit generating ALTER or DROP operations that are potentially destructive",
+		"alter-wrapper":         "Output ALTER TABLEs as shell commands rather than just raw DDL; see manual for template vars",
+		"brief":                 "Don't output DDL to STDOUT; instead output list of instances with at least one difference",
+		"group-by-safety":       "Group output DDL into labeled sections: safe CREATEs, safe ALTERs, and destructive operations",
+		"migration-format":      `Also write DDL to a versioned migration file or SQL script in this format (valid values: "none", "flyway", "liquibase", "sql")`,
+		"migration-dir":         "Directory in which to write the file generated by migration-format",
+		"migration-description": "Description to use in the name of the file generated by migration-format",
+		"migration-split":       `Write a separate migration-format file per instance or schema (valid values: "none", "instance", "schema")`,
+		"osc-tool":              `Output ALTER TABLEs which rebuild or copy the table as online schema change tool commands (valid values: "none", "gh-ost", "pt-osc")`,
+		"plan":                  "Write generated DDL and live schema fingerprints to this plan file, for later use by `skeema apply`",
+		"safe-below-size":       "Always permit generating destructive operations for tables below this size in bytes",
+		"unsafe-ops":            "Permit generating these comma-separated classes of destructive operations without allow-unsafe; see manual for classes",
 	}
 	hiddenRewrites := map[string]bool{
-		"brief":              false,
-		"dry-run":            true,
-		"foreign-key-checks": true,
+		"brief":                 false,
+		"dry-run":               true,
+		"fatal-warnings":        true,
+		"group-by-safety":       false,
+		"migration-format":      false,
+		"migration-dir":         false,
+		"migration-description": false,
+		"migration-split":       false,
+		"plan":                  false,
+		"foreign-key-checks":    true,
+		"max-replica-lag":       true,
+		"replica-lag-timeout":   true,
+		"resume":                true,
+		"before-statement":      true,
+		"after-statement":       true,
+		"audit-log":             true,
+		"audit-table":           true,
+	}
+	clonePushOptions("diff", descRewrites, hiddenRewrites)
+}
+
+// clonePushOptions copies options from `skeema push` into the named command,
+// skipping any options that the command already defines. Descriptions and
+// CLI visibility of the copies can be overridden by name.
+func clonePushOptions(cmdName string, descRewrites map[string]string, hiddenRewrites map[string]bool) {
+	// Logic relies on init() having been called in both cmd_push.go AND the
+	// other command's file, so we call it from both places, but only one will
+	// succeed
+	cmd, ok1 := CommandSuite.SubCommands[cmdName]
+	push, ok2 := CommandSuite.SubCommands["push"]
+	if !ok1 || !ok2 {
+		return
 	}
 
-	diffOptions := diff.Options()
+	cmdOptions := cmd.Options()
 	pushOptions := push.Options()
 
 	for name, pushOpt := range pushOptions {
-		if _, already := diffOptions[name]; already {
+		if _, already := cmdOptions[name]; already {
 			continue
 		}
-		diffOpt := *pushOpt
+		cmdOpt := *pushOpt
 		if newDesc, ok := descRewrites[name]; ok {
-			diffOpt.Description = newDesc
+			cmdOpt.Description = newDesc
 		}
 		if newHiddenStatus, ok := hiddenRewrites[name]; ok {
-			diffOpt.HiddenOnCLI = newHiddenStatus
+			cmdOpt.HiddenOnCLI = newHiddenStatus
 		}
-		diff.AddOption(&diffOpt)
+		cmd.AddOption(&cmdOpt)
 	}
 }
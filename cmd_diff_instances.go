@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Compare schemas on two live database instances"
+	desc := `Compares a schema on one live database instance to a schema on another live
+database instance, without reference to any *.sql files. The output is a series
+of DDL commands that, if run on the target, would cause it to match the source.
+No changes are made to either instance.
+
+This permits directly comparing environments, such as staging vs production.
+Each instance is specified in the form host[:port][/schema]. If the schema name
+is omitted, the value of the schema option is used. Connection-related options,
+such as user, password, and connect-options, are obtained from the current
+directory's configuration, and apply to both instances. With --reverse, the
+source and target are swapped.
+
+The ignore-schema and ignore-table options are respected. With --json, the
+output is a single JSON document describing each difference, instead of DDL.
+
+You may optionally pass an environment name as a third argument, to select which
+section of .skeema config files is used. If no environment name is supplied, the
+default is "production".
+
+An exit code of 0 will be returned if no differences were found, 1 if some
+differences were found, or 2+ if an error occurred.`
+
+	cmd := mybase.NewCommand("diff-instances", summary, desc, DiffInstancesHandler)
+	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow source table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
+	cmd.AddOption(mybase.BoolOption("reverse", 0, false, "Output DDL to make the source match the target, instead of vice versa"))
+	cmd.AddOption(mybase.BoolOption("json", 0, false, "Output differences as JSON instead of DDL"))
+	cmd.AddArg("source", "", true)
+	cmd.AddArg("target", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// DiffInstancesHandler is the handler method for `skeema diff-instances`
+func DiffInstancesHandler(cfg *mybase.Config) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+	if dir.ParseError != nil {
+		return NewExitValue(CodeBadConfig, "Cannot process %s: %s", dir, dir.ParseError)
+	}
+	opts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+
+	var sides [2]liveSchemaSide
+	for n, argName := range []string{"source", "target"} {
+		host, schemaName, err := parseInstanceSpec(dir.Config.Get(argName), dir.Config.Get("schema"))
+		if err != nil {
+			return NewExitValue(CodeBadInput, "Invalid %s: %s", argName, err)
+		}
+		if sides[n], err = introspectLiveSchema(dir, host, schemaName, opts); err != nil {
+			return err
+		}
+	}
+	from, to := sides[1], sides[0]
+	if dir.Config.GetBool("reverse") {
+		from, to = to, from
+	}
+
+	mods := tengo.StatementModifiers{
+		NextAutoInc:     tengo.NextAutoIncIgnore,
+		AllowUnsafe:     true,
+		CompareMetadata: dir.Config.GetBool("compare-metadata"),
+		Flavor:          from.instance.Flavor(),
+	}
+	if dir.Config.GetBool("exact-match") {
+		mods.StrictIndexOrder = true
+		mods.StrictForeignKeyNaming = true
+	}
+	result, err := newLiveSchemaDiff(from.schema, to.schema, mods)
+	if err != nil {
+		return err
+	}
+	result.From = from.String()
+	result.To = to.String()
+
+	if dir.Config.GetBool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, od := range result.Unsupported {
+			log.Warnf("Skipping %s %s: unable to generate DDL due to use of unsupported features", od.Type, tengo.EscapeIdentifier(od.Name))
+		}
+		if len(result.Differences) > 0 {
+			fmt.Printf("-- from: %s\n-- to: %s\n", result.From, result.To)
+			for _, d := range result.Differences {
+				fmt.Print(fs.AddDelimiter(d.Statement))
+			}
+		}
+	}
+
+	if len(result.Unsupported) > 0 {
+		return NewExitValue(CodePartialError, "Skipped %s due to unsupported features", countAndNoun(len(result.Unsupported), "operation", "operations"))
+	} else if len(result.Differences) > 0 {
+		return NewExitValue(CodeDifferencesFound, "")
+	}
+	log.Infof("%s matches %s", from, to)
+	return nil
+}
+
+// liveSchemaSide represents one of the two schemas compared by
+// `skeema diff-instances`.
+type liveSchemaSide struct {
+	instance *tengo.Instance
+	schema   *tengo.Schema
+}
+
+func (side liveSchemaSide) String() string {
+	return fmt.Sprintf("%s/%s", side.instance, side.schema.Name)
+}
+
+// parseInstanceSpec splits spec, in the form host[:port][/schema], into its
+// host (including any port) and schema name. If spec does not include a
+// schema name, defaultSchema is used instead. An error is returned if neither
+// is available, or if defaultSchema is needed but contains multiple names.
+func parseInstanceSpec(spec, defaultSchema string) (host, schemaName string, err error) {
+	host, schemaName = spec, defaultSchema
+	if slash := strings.LastIndex(spec, "/"); slash >= 0 {
+		host, schemaName = spec[:slash], spec[slash+1:]
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("%q does not specify a host", spec)
+	} else if schemaName == "" {
+		return "", "", fmt.Errorf("%q does not specify a schema name, and the schema option is not set", spec)
+	} else if strings.ContainsAny(schemaName, ",*`") {
+		return "", "", fmt.Errorf("schema name %q must be a single literal schema name", schemaName)
+	}
+	return host, schemaName, nil
+}
+
+// introspectLiveSchema connects to host using dir's connection options, and
+// introspects schemaName, filtered according to opts.
+func introspectLiveSchema(dir *fs.Dir, host, schemaName string, opts introspect.Options) (side liveSchemaSide, err error) {
+	instances, err := dir.InstancesForHosts([]string{host})
+	if err != nil {
+		return side, NewExitValue(CodeBadConfig, err.Error())
+	}
+	side.instance = instances[0]
+	if ok, err := side.instance.CanConnect(); !ok {
+		return side, NewExitValue(CodeFatalError, "Unable to connect to %s: %s", side.instance, err)
+	}
+	if opts.IgnoreSchema != nil && opts.IgnoreSchema.MatchString(schemaName) {
+		return side, NewExitValue(CodeBadInput, "Schema %s matches ignore-schema option", schemaName)
+	}
+	if exists, err := side.instance.HasSchema(schemaName); err != nil {
+		return side, err
+	} else if !exists {
+		return side, NewExitValue(CodeBadInput, "Schema %s does not exist on %s", schemaName, side.instance)
+	}
+	if side.schema, err = introspect.Schema(side.instance, schemaName, opts); err != nil {
+		return side, NewExitValue(CodeFatalError, "Unable to introspect %s on %s: %s", schemaName, side.instance, err)
+	}
+	return side, nil
+}
+
+// liveSchemaDiff is the result of comparing two live schemas. Its structure is
+// also used as the JSON output of `skeema diff-instances --json`.
+type liveSchemaDiff struct {
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Differences []liveObjectDiff `json:"differences"`
+	Unsupported []liveObjectDiff `json:"unsupported,omitempty"` // Statement is always empty
+}
+
+// liveObjectDiff describes a single difference in a liveSchemaDiff.
+type liveObjectDiff struct {
+	Type      tengo.ObjectType `json:"type"`
+	Name      string           `json:"name"`
+	DiffType  string           `json:"operation"`
+	Statement string           `json:"statement,omitempty"`
+}
+
+// newLiveSchemaDiff compares from and to, returning the differences as DDL
+// statements which would transform from into to. Objects whose diffs cannot
+// be expressed in DDL are tracked separately in the Unsupported field. The
+// From and To fields are left for the caller to populate.
+func newLiveSchemaDiff(from, to *tengo.Schema, mods tengo.StatementModifiers) (*liveSchemaDiff, error) {
+	result := &liveSchemaDiff{
+		Differences: []liveObjectDiff{},
+	}
+	diff := tengo.NewSchemaDiff(from, to)
+	for _, objDiff := range diff.ObjectDiffs() {
+		key := objDiff.ObjectKey()
+		od := liveObjectDiff{
+			Type:     key.Type,
+			Name:     key.Name,
+			DiffType: strings.ToLower(objDiff.DiffType().String()),
+		}
+		var err error
+		od.Statement, err = objDiff.Statement(mods)
+		if _, ok := err.(*tengo.UnsupportedDiffError); ok {
+			od.Statement = ""
+			result.Unsupported = append(result.Unsupported, od)
+		} else if err != nil {
+			return nil, err
+		} else if od.Statement != "" {
+			result.Differences = append(result.Differences, od)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestParseInstanceSpec(t *testing.T) {
+	cases := []struct {
+		spec, defaultSchema string
+		host, schemaName    string
+		expectErr           bool
+	}{
+		{"db1.example.com", "product", "db1.example.com", "product", false},
+		{"db1.example.com:3307/orders", "product", "db1.example.com:3307", "orders", false},
+		{"[::1]:3306/orders", "", "[::1]:3306", "orders", false},
+		{"localhost", "", "", "", true},
+		{"localhost/", "product", "", "", true},
+		{"/product", "", "", "", true},
+		{"localhost", "product,orders", "", "", true},
+		{"localhost", "`echo product`", "", "", true},
+	}
+	for _, c := range cases {
+		host, schemaName, err := parseInstanceSpec(c.spec, c.defaultSchema)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("Expected error from parseInstanceSpec(%q, %q), but err was nil", c.spec, c.defaultSchema)
+			}
+		} else if err != nil || host != c.host || schemaName != c.schemaName {
+			t.Errorf("Unexpected result from parseInstanceSpec(%q, %q): %q, %q, %v", c.spec, c.defaultSchema, host, schemaName, err)
+		}
+	}
+}
+
+func TestNewLiveSchemaDiff(t *testing.T) {
+	makeTable := func(name string, colNames ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               name,
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+		}
+		for _, colName := range colNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: colName, TypeInDB: "int(11)", Nullable: true, Default: "NULL"})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+	staging := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{makeTable("posts", "id", "body"), makeTable("users", "id"), makeTable("widgets", "id")},
+	}
+	production := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{makeTable("posts", "id"), makeTable("users", "id"), makeTable("legacy", "id")},
+	}
+	mods := tengo.StatementModifiers{AllowUnsafe: true, NextAutoInc: tengo.NextAutoIncIgnore}
+
+	result, err := newLiveSchemaDiff(production, staging, mods)
+	if err != nil {
+		t.Fatalf("Unexpected error from newLiveSchemaDiff: %v", err)
+	}
+	expected := map[string]string{
+		"posts":   "alter",
+		"widgets": "create",
+		"legacy":  "drop",
+	}
+	if len(result.Differences) != len(expected) || len(result.Unsupported) > 0 {
+		t.Fatalf("Unexpected result from newLiveSchemaDiff: %+v", result)
+	}
+	for _, od := range result.Differences {
+		if od.Type != tengo.ObjectTypeTable || expected[od.Name] != od.DiffType || od.Statement == "" {
+			t.Errorf("Unexpected difference %+v", od)
+		}
+	}
+
+	// JSON output should use the documented field names
+	result.From, result.To = "prod1:3306/product", "stage1:3306/product"
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Unexpected error from json.Marshal: %v", err)
+	}
+	var decoded struct {
+		From        string
+		To          string
+		Differences []map[string]string
+		Unsupported []map[string]string
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unexpected error from json.Unmarshal: %v", err)
+	}
+	if decoded.From != result.From || decoded.To != result.To || len(decoded.Differences) != 3 || decoded.Unsupported != nil {
+		t.Errorf("Unexpected JSON output: %s", encoded)
+	}
+	for _, d := range decoded.Differences {
+		if d["type"] != "table" || d["name"] == "" || d["operation"] == "" || d["statement"] == "" {
+			t.Errorf("Unexpected JSON difference: %v", d)
+		}
+	}
+
+	// Identical schemas should have no differences, and encode as an empty array
+	if result, err = newLiveSchemaDiff(production, production, mods); err != nil || len(result.Differences) > 0 {
+		t.Errorf("Expected no differences between identical schemas, instead found %+v, err=%v", result, err)
+	} else if encoded, _ := json.Marshal(result); string(encoded) != `{"from":"","to":"","differences":[]}` {
+		t.Errorf("Unexpected JSON output for identical schemas: %s", encoded)
+	}
+}
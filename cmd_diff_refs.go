@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/linter"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Compare the schema between two git commits"
+	desc := `Compares the *.sql files of the current directory as of two git commits, without
+reference to any live database. The output is a series of DDL commands that, if
+run on a schema matching the old commit, would cause it to match the new commit.
+
+The *.sql files are read directly from git history, rather than from the
+working tree, so neither commit needs to be checked out. Each side is loaded
+into a workspace, and then diffed. The workspace is cleaned up afterwards. For
+complete isolation from any live database server, use workspace=docker along
+with the flavor option. Files which only exist in one of the two commits cause
+their objects to be created or dropped. Both sides are normalized and diffed in
+the same manner as ` + "`" + `skeema diff` + "`" + `, including any views, triggers, events,
+and sequences, but options which require a live schema, such as
+convert-character-set and manage-data, have no effect.
+
+The old and new refs are required, and may be any commit reference understood
+by git, such as a branch name, tag, or commit hash. You may optionally pass an
+environment name as a third argument, to select which section of .skeema config
+files is used for workspace selection. Configuration is always read from the
+working tree. If no environment name is supplied, the default is "production".
+
+An exit code of 0 will be returned if no differences were found, 1 if some
+differences were found, or 2+ if an error occurred.`
+
+	cmd := mybase.NewCommand("diff-refs", summary, desc, DiffRefsHandler)
+	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
+	cmd.AddOption(mybase.BoolOption("lint", 0, true, "Check modified objects for problems before outputting DDL"))
+	addWorkspaceDiffOptions(cmd)
+	linter.AddCommandOptions(cmd)
+	cmd.AddArg("oldref", "", true)
+	cmd.AddArg("newref", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// DiffRefsHandler is the handler method for `skeema diff-refs`
+func DiffRefsHandler(cfg *mybase.Config) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+	if dir.ParseError != nil {
+		return NewExitValue(CodeBadConfig, "Cannot process %s: %s", dir, dir.ParseError)
+	}
+	prefix, err := gitOutput(dir.Path, "rev-parse", "--show-prefix")
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "Unable to locate git repository for %s: %s", dir, err)
+	}
+	oldRef, newRef := dir.Config.Get("oldref"), dir.Config.Get("newref")
+	fromLogical, err := refLogicalSchema(dir, oldRef, strings.TrimSpace(string(prefix)))
+	if err != nil {
+		return NewExitValue(CodeBadInput, err.Error())
+	}
+	toLogical, err := refLogicalSchema(dir, newRef, strings.TrimSpace(string(prefix)))
+	if err != nil {
+		return NewExitValue(CodeBadInput, err.Error())
+	}
+
+	wsOpts, flavor, err := diffWorkspaceOptions(dir)
+	if err != nil {
+		return err
+	}
+	fromSchema, err := execDiffSchema(fromLogical, wsOpts)
+	if err != nil {
+		return err
+	}
+	toSchema, err := execDiffSchema(toLogical, wsOpts)
+	if err != nil {
+		return err
+	}
+	if err := outputSchemaDiff(dir, fromSchema, toSchema, flavor, fmt.Sprintf("-- refs: %s..%s", oldRef, newRef)); err != nil {
+		return err
+	}
+	log.Infof("%s has no schema differences between %s and %s", dir, oldRef, newRef)
+	return nil
+}
+
+// refLogicalSchema reads the *.sql files in git for dir as of the supplied
+// ref, returning a LogicalSchema containing their CREATE statements. prefix
+// is the path of dir relative to the top level of the git repository, with a
+// trailing slash unless it is the top level. If dir did not exist as of ref,
+// an empty LogicalSchema is returned. Statements which specify a schema name
+// qualifier or USE a different schema are ignored, as are any subdirectories.
+func refLogicalSchema(dir *fs.Dir, ref, prefix string) (*fs.LogicalSchema, error) {
+	if _, err := gitOutput(dir.Path, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("%s is not a valid git commit reference", ref)
+	}
+	ls := &fs.LogicalSchema{
+		CharSet:   dir.Config.Get("default-character-set"),
+		Collation: dir.Config.Get("default-collation"),
+		Creates:   make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	treeish := ref + ":" + prefix
+	if _, err := gitOutput(dir.Path, "cat-file", "-e", treeish); err != nil {
+		log.Infof("%s does not exist as of %s; treating it as an empty schema", dir, ref)
+		return ls, nil
+	}
+	// --full-tree is needed since ls-tree otherwise filters its output relative to
+	// the working directory, even when given an explicit tree
+	listing, err := gitOutput(dir.Path, "ls-tree", "-z", "--full-tree", treeish)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignoredCount int
+	managedData := dir.ManagedDataTables()
+	for _, entry := range strings.Split(string(listing), "\x00") {
+		// Each entry is in format "<mode> <type> <object>\t<file name>"
+		tab := strings.IndexByte(entry, '\t')
+		if tab < 0 || !strings.HasSuffix(entry, ".sql") {
+			continue
+		}
+		fields, fileName := strings.Fields(entry[:tab]), entry[tab+1:]
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		} else if fields[0] == "120000" {
+			log.Warnf("Ignoring symlink %s%s as of %s", prefix, fileName, ref)
+			continue
+		}
+		contents, err := gitOutput(dir.Path, "cat-file", "blob", fields[2])
+		if err != nil {
+			return nil, err
+		}
+		statements, err := fs.ParseStatementsFromReader(bytes.NewReader(contents), treeish+fileName)
+		if err != nil {
+			return nil, err
+		}
+		for _, stmt := range statements {
+			if stmt.Type == fs.StatementTypeUnknown || (stmt.Type == fs.StatementTypeInsert && !managedData[stmt.ObjectName]) || (stmt.Schema() != "" && stmt.Type != fs.StatementTypeNoop && stmt.Type != fs.StatementTypeCommand) {
+				ignoredCount++
+			} else if err := ls.AddStatement(stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ignoredCount > 0 {
+		log.Debugf("Ignored %s in %s as of %s", countAndNoun(ignoredCount, "statement", "statements"), dir, ref)
+	}
+	return ls, nil
+}
+
+// gitOutput runs git with the supplied args in dirPath, returning its standard
+// output. If git exits non-zero, the returned error includes its standard error
+// output.
+func gitOutput(dirPath string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return output, err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+// gitTestCommit stages all files in the git repo at dirPath and commits them,
+// initializing the repo first if necessary. Any error is fatal to the test.
+func gitTestCommit(t *testing.T, dirPath, message string) {
+	t.Helper()
+	run := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=skeema", "-c", "user.email=skeema@example.com", "-c", "commit.gpgsign=false"}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Unexpected error from git %s: %s\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dirPath, ".git")); os.IsNotExist(err) {
+		run("init", "-q")
+	}
+	run("add", "-A")
+	run("commit", "-q", "--allow-empty", "-m", message)
+}
+
+func TestRefLogicalSchema(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "skeema-diff-refs")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(repoPath)
+	productPath := filepath.Join(repoPath, "product")
+	writeFile := func(name, contents string) {
+		t.Helper()
+		fs.WriteTestFile(t, filepath.Join(productPath, name), contents)
+	}
+	removeFile := func(name string) {
+		t.Helper()
+		if err := os.Remove(filepath.Join(productPath, name)); err != nil {
+			t.Fatalf("Unable to remove %s: %s", name, err)
+		}
+	}
+
+	// First commit: repo only contains a README, so product does not exist yet
+	fs.WriteTestFile(t, filepath.Join(repoPath, "README"), "hello\n")
+	gitTestCommit(t, repoPath, "initial")
+
+	// Second commit: add some files
+	writeFile("foo.sql", "CREATE TABLE foo (id int);\n")
+	writeFile("bar.sql", "CREATE TABLE bar (id int);\nINSERT INTO bar VALUES (1);\n")
+	writeFile("notes.txt", "CREATE TABLE ignored (id int);\n")
+	gitTestCommit(t, repoPath, "add tables")
+
+	// Third commit: modify foo, remove bar, add routine and subdir, add a
+	// statement for another schema
+	writeFile("foo.sql", "CREATE TABLE foo (id int, name varchar(20));\nCREATE TABLE other.foo (id int);\n")
+	removeFile("bar.sql")
+	writeFile("func1.sql", "CREATE FUNCTION func1() RETURNS int DETERMINISTIC RETURN 1;\n")
+	writeFile("sub/baz.sql", "CREATE TABLE baz (id int);\n")
+	gitTestCommit(t, repoPath, "modify tables")
+
+	// Working tree changes should have no effect, since contents are read from
+	// git history
+	writeFile("uncommitted.sql", "CREATE TABLE uncommitted (id int);\n")
+
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema diff-refs HEAD~1 HEAD")
+	dir, err := fs.ParseDir(productPath, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %s", err)
+	}
+	prefix, err := gitOutput(dir.Path, "rev-parse", "--show-prefix")
+	if err != nil || strings.TrimSpace(string(prefix)) != "product/" {
+		t.Fatalf("Unexpected result from git rev-parse: %q, %v", prefix, err)
+	}
+
+	assertKeys := func(ref string, expected ...string) *fs.LogicalSchema {
+		t.Helper()
+		ls, err := refLogicalSchema(dir, ref, "product/")
+		if err != nil {
+			t.Fatalf("Unexpected error from refLogicalSchema(%s): %s", ref, err)
+		}
+		var actual []string
+		for key := range ls.Creates {
+			actual = append(actual, key.String())
+		}
+		sort.Strings(actual)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected refLogicalSchema(%s) to return %v, instead found %v", ref, expected, actual)
+		}
+		return ls
+	}
+	assertKeys("HEAD~2")
+	assertKeys("HEAD~1", "table `foo`", "table `bar`")
+	ls := assertKeys("HEAD", "table `foo`", "function `func1`")
+	for key, stmt := range ls.Creates {
+		if !strings.HasPrefix(stmt.Location(), "HEAD:product/") {
+			t.Errorf("Unexpected location for %s: %s", key, stmt.Location())
+		}
+		if key.Name == "foo" && !strings.Contains(stmt.Text, "name varchar(20)") {
+			t.Errorf("Unexpected statement text for %s: %s", key, stmt.Text)
+		}
+	}
+
+	if _, err := refLogicalSchema(dir, "nonexistent-branch", "product/"); err == nil {
+		t.Error("Expected error from refLogicalSchema with invalid ref, but it was nil")
+	}
+}
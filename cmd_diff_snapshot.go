@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/linter"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Compare a schema snapshot file to the filesystem"
+	desc := `Compares a point-in-time snapshot of a schema, such as the output of
+` + "`" + `mysqldump --no-data` + "`" + `, to the filesystem representation of the schema in the
+current directory. The output is a series of DDL commands that, if run on a
+schema matching the snapshot, would cause it to match the filesystem.
+
+This permits validating changes against a captured copy of a production schema,
+without needing to connect to production. The snapshot file and the *.sql files
+are each loaded into a workspace, and then diffed. The workspace is cleaned up
+afterwards. For complete isolation from any live database server, use
+workspace=docker along with the flavor option.
+
+Only CREATE statements in the snapshot file are used; other statements are
+ignored. If the snapshot contains multiple schemas (via USE commands), the one
+matching the current directory's schema option is used. Both sides are
+normalized and diffed in the same manner as ` + "`" + `skeema diff` + "`" + `, including any views,
+triggers, events, and sequences, but options which require a live schema, such
+as convert-character-set and manage-data, have no effect.
+
+The snapshot file path is required. You may optionally pass an environment name
+as a second argument, to select which section of .skeema config files is used
+for workspace selection. If no environment name is supplied, the default is
+"production".
+
+An exit code of 0 will be returned if no differences were found, 1 if some
+differences were found, or 2+ if an error occurred.`
+
+	cmd := mybase.NewCommand("diff-snapshot", summary, desc, DiffSnapshotHandler)
+	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
+	cmd.AddOption(mybase.BoolOption("lint", 0, true, "Check modified objects for problems before outputting DDL"))
+	addWorkspaceDiffOptions(cmd)
+	linter.AddCommandOptions(cmd)
+	cmd.AddArg("snapshot", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// DiffSnapshotHandler is the handler method for `skeema diff-snapshot`
+func DiffSnapshotHandler(cfg *mybase.Config) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+	if dir.ParseError != nil {
+		return NewExitValue(CodeBadConfig, "Cannot process %s: %s", dir, dir.ParseError)
+	} else if len(dir.LogicalSchemas) == 0 {
+		return NewExitValue(CodeBadConfig, "No *.sql files found in %s", dir)
+	}
+	snapshot, err := snapshotLogicalSchema(dir.Config.Get("snapshot"), dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+
+	wsOpts, flavor, err := diffWorkspaceOptions(dir)
+	if err != nil {
+		return err
+	}
+	fromSchema, err := execDiffSchema(snapshot, wsOpts)
+	if err != nil {
+		return err
+	}
+	toSchema, err := execDiffSchema(dir.LogicalSchemas[0], wsOpts)
+	if err != nil {
+		return err
+	}
+	if err := outputSchemaDiff(dir, fromSchema, toSchema, flavor, "-- snapshot: "+dir.Config.Get("snapshot")); err != nil {
+		return err
+	}
+	log.Infof("%s matches snapshot %s", dir, dir.Config.Get("snapshot"))
+	return nil
+}
+
+// addWorkspaceDiffOptions adds the options which affect
+// applier.DiffWorkspaceSchemas, other than global options and those which
+// diff-snapshot and diff-refs already define, to cmd.
+func addWorkspaceDiffOptions(cmd *mybase.Command) {
+	cmd.AddOption(mybase.BoolOption("reorder-columns", 0, true, "Move existing columns as needed to match column order in *.sql table definitions"))
+	cmd.AddOption(mybase.BoolOption("include-system-columns", 0, false, "Include hidden columns generated automatically by the server, such as invisible primary keys, in diffs"))
+	cmd.AddOption(mybase.StringOption("partitioning", 0, "keep", `Specify handling of partitioning status on the database side (valid values: "keep", "remove", "modify")`))
+	cmd.AddOption(mybase.BoolOption("manage-partition-list", 0, false, "Add, drop, or reorganize partitions to match the partition list of *.sql table definitions"))
+	cmd.AddOption(mybase.StringOption("external-objects", 0, "", "Never create, alter, or drop these comma-separated objects, e.g. table:name,proc:name"))
+	cmd.AddOption(mybase.StringOption("external-objects-file", 0, "", "Never create, alter, or drop objects listed in this file, one per line"))
+}
+
+// diffWorkspaceOptions returns workspace options for dir, along with the
+// flavor to use in generating DDL. This involves connecting to the first
+// defined instance, unless configured to use local Docker with an explicit
+// flavor.
+func diffWorkspaceOptions(dir *fs.Dir) (workspace.Options, tengo.Flavor, error) {
+	var inst *tengo.Instance
+	var err error
+	if wsType, _ := dir.Config.GetEnum("workspace", "temp-schema", "docker"); wsType != "docker" || !dir.Config.Changed("flavor") {
+		if inst, err = dir.FirstInstance(); err != nil {
+			return workspace.Options{}, tengo.FlavorUnknown, NewExitValue(CodeBadConfig, err.Error())
+		}
+	}
+	wsOpts, err := workspace.OptionsForDir(dir, inst)
+	if err != nil {
+		return workspace.Options{}, tengo.FlavorUnknown, NewExitValue(CodeBadConfig, err.Error())
+	}
+	flavor := wsOpts.Flavor
+	if inst != nil {
+		flavor = inst.Flavor()
+	}
+	return wsOpts, flavor, nil
+}
+
+// outputSchemaDiff prints the DDL needed to transform fromSchema into
+// toSchema, preceded by the supplied header line, using dir's configuration
+// for normalization and linting. The diff is computed by
+// applier.DiffWorkspaceSchemas, so its output matches that of `skeema diff`
+// against a live schema matching fromSchema. The returned error is nil only if
+// the schemas have no differences; otherwise it is an ExitValue reflecting
+// differences found, linter errors, or unsupported features.
+func outputSchemaDiff(dir *fs.Dir, fromSchema, toSchema *workspace.Schema, flavor tengo.Flavor, header string) (err error) {
+	objDiffs, mods, err := applier.DiffWorkspaceSchemas(dir, fromSchema, toSchema, flavor)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	var stmts []string
+	var keys []tengo.ObjectKey
+	var unsupportedCount int
+	for _, objDiff := range objDiffs {
+		stmt, err := objDiff.Statement(mods)
+		if unsupportedErr, ok := err.(*tengo.UnsupportedDiffError); ok {
+			unsupportedCount++
+			log.Warnf("Skipping %s: unable to generate DDL due to use of unsupported features", unsupportedErr.ObjectKey)
+		} else if err != nil {
+			return err
+		} else if stmt != "" {
+			stmts = append(stmts, fs.AddDelimiter(stmt))
+			keys = append(keys, objDiff.ObjectKey())
+		}
+	}
+
+	// Lint any modified objects, and don't output anything if any annotations
+	// are at the error level
+	if dir.Config.GetBool("lint") && len(keys) > 0 {
+		lintOpts, err := linter.OptionsForDir(dir)
+		if err != nil {
+			return NewExitValue(CodeBadConfig, err.Error())
+		}
+		lintOpts.OnlyKeys(keys)
+		lintResult := linter.CheckSchema(toSchema, lintOpts)
+		lintResult.SortByFile()
+		for _, annotation := range lintResult.Annotations {
+			annotation.Log()
+		}
+		if lintResult.ErrorCount > 0 {
+			return NewExitValue(CodeFatalError, "Skipping %s due to %s", dir, countAndNoun(lintResult.ErrorCount, "linter error", "linter errors"))
+		}
+	}
+
+	if len(stmts) > 0 {
+		fmt.Printf("%s\n%s", header, strings.Join(stmts, ""))
+	}
+	if unsupportedCount > 0 {
+		return NewExitValue(CodePartialError, "Skipped %s due to unsupported features", countAndNoun(unsupportedCount, "operation", "operations"))
+	} else if len(stmts) > 0 {
+		return NewExitValue(CodeDifferencesFound, "")
+	}
+	return nil
+}
+
+// snapshotLogicalSchema parses the snapshot file at filePath, returning a
+// LogicalSchema containing its CREATE statements. If the file contains CREATE
+// statements for multiple schemas, only the ones for dir's schema are returned.
+// Other types of statements are ignored.
+func snapshotLogicalSchema(filePath string, dir *fs.Dir) (*fs.LogicalSchema, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	sqlFile := fs.SQLFile{
+		Dir:      filepath.Dir(absPath),
+		FileName: filepath.Base(absPath),
+	}
+	tokenizedFile, err := sqlFile.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	bySchema := make(map[string]*fs.LogicalSchema)
+	var ignoredCount int
+	for _, stmt := range tokenizedFile.Statements {
+		if stmt.Type != fs.StatementTypeCreate {
+			if stmt.Type == fs.StatementTypeUnknown {
+				ignoredCount++
+			}
+			continue
+		} else if stmt.ObjectQualifier != "" {
+			return nil, fmt.Errorf("%s: Snapshot statements may not use schema name qualifiers", stmt.Location())
+		}
+		ls := bySchema[stmt.DefaultDatabase]
+		if ls == nil {
+			ls = &fs.LogicalSchema{
+				Name:      stmt.DefaultDatabase,
+				CharSet:   dir.Config.Get("default-character-set"),
+				Collation: dir.Config.Get("default-collation"),
+				Creates:   make(map[tengo.ObjectKey]*fs.Statement),
+			}
+			bySchema[stmt.DefaultDatabase] = ls
+		}
+		if err := ls.AddStatement(stmt); err != nil {
+			return nil, err
+		}
+	}
+	if ignoredCount > 0 {
+		log.Debugf("Ignored %s in %s", countAndNoun(ignoredCount, "non-CREATE statement", "non-CREATE statements"), sqlFile)
+	}
+
+	if len(bySchema) == 0 {
+		return nil, fmt.Errorf("No CREATE statements found in %s", sqlFile)
+	} else if len(bySchema) == 1 {
+		for _, ls := range bySchema {
+			return ls, nil
+		}
+	}
+	schemaName := dir.Config.Get("schema")
+	if ls := bySchema[schemaName]; ls != nil && schemaName != "" {
+		return ls, nil
+	}
+	return nil, fmt.Errorf("%s contains multiple schemas, but none match schema %q for %s", sqlFile, schemaName, dir)
+}
+
+// execDiffSchema runs the CREATE statements of logicalSchema in a workspace,
+// returning the resulting introspected schema. Since a diff cannot accurately
+// be computed if any statement failed, statement errors are logged and then
+// treated as fatal.
+func execDiffSchema(logicalSchema *fs.LogicalSchema, wsOpts workspace.Options) (*workspace.Schema, error) {
+	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, wsOpts)
+	if err != nil {
+		return nil, NewExitValue(CodeFatalError, err.Error())
+	}
+	for _, stmtErr := range wsSchema.Failures {
+		message := strings.Replace(stmtErr.Err.Error(), "Error executing DDL in workspace: ", "", 1)
+		log.Errorf("%s: %s", stmtErr.Location(), message)
+	}
+	if len(wsSchema.Failures) > 0 {
+		return nil, NewExitValue(CodeFatalError, "Unable to compute diff due to %s", countAndNoun(len(wsSchema.Failures), "statement error", "statement errors"))
+	}
+	return wsSchema, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Output a hash of each schema's definition"
+	desc := `Outputs a fingerprint for the schema represented by each directory: a stable
+hash of its table, routine, event, sequence, view, and trigger definitions,
+after normalization to canonical SHOW CREATE format. The fingerprint does not depend on the order or formatting
+of statements in *.sql files, so it can be used as a quick check of whether a
+schema has changed, or to compare schemas across environments without
+performing a full diff.
+
+By default, the *.sql files in each directory are fingerprinted. This relies on
+accessing database instances to test the SQL DDL in a temporary location; see
+the workspace option for more information. With --live, the schemas on each
+directory's live database instances are fingerprinted instead. Since tables
+ignored by the ignore-table option are omitted, as are AUTO_INCREMENT values,
+definers, and event schedule times, a directory and its live schema will
+typically have the same fingerprint if ` + "`skeema diff`" + ` finds no differences
+between them. Directories whose *.sql files contain statements for multiple
+schemas cannot be fingerprinted.
+
+Each line of output consists of a fingerprint and a directory path, separated
+by a tab. With --live, each line additionally contains the instance and schema
+name, each preceded by a tab.
+
+You may optionally pass an environment name as a CLI option. This will affect
+which section of .skeema config files is used for instance and workspace
+selection. If no environment name is supplied, the default is "production".
+
+An exit code of 0 will be returned if all fingerprints were output successfully,
+or 2+ if any errors occurred.`
+
+	cmd := mybase.NewCommand("fingerprint", summary, desc, FingerprintHandler)
+	cmd.AddOption(mybase.BoolOption("live", 0, false, "Fingerprint schemas on each dir's live database instances, instead of *.sql files"))
+	addDirOption(cmd)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// FingerprintHandler is the handler method for `skeema fingerprint`
+func FingerprintHandler(cfg *mybase.Config) error {
+	dirs, err := parseDirs(cfg)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if dirErr := fingerprintWalker(dir, 5); ExitCode(dirErr) > ExitCode(err) {
+			err = dirErr
+		}
+	}
+	return NewExitValue(ExitCode(err), "")
+}
+
+func fingerprintWalker(dir *fs.Dir, maxDepth int) error {
+	if dir.ParseError != nil {
+		log.Warnf("Skipping %s: %s", dir.Path, dir.ParseError)
+		return NewExitValue(CodeBadConfig, "")
+	}
+	var result error
+	if dir.Config.GetBool("live") {
+		result = fingerprintLiveDir(dir)
+	} else if fingerprint, err := fingerprintDir(dir); err != nil {
+		result = err
+	} else if fingerprint != "" {
+		fmt.Printf("%s\t%s\n", fingerprint, dir.RelPath())
+	}
+	if result != nil {
+		log.Errorf("Skipping %s: %s", dir, result)
+		return result // don't walk subdirs if something fatal happened here
+	}
+
+	subdirs, err := dir.Subdirs()
+	if err != nil {
+		log.Errorf("Cannot list subdirs of %s: %s", dir, err)
+		return err
+	} else if len(subdirs) > 0 && maxDepth <= 0 {
+		log.Errorf("Not walking subdirs of %s: max depth reached", dir)
+		return result
+	}
+	for _, sub := range subdirs {
+		err := fingerprintWalker(sub, maxDepth-1)
+		if ExitCode(err) > ExitCode(result) {
+			result = err
+		}
+	}
+	return result
+}
+
+// fingerprintDir returns the fingerprint of the logical schema in dir's *.sql
+// files, by executing them in a workspace. Statements which fail to execute
+// result in an error, since the fingerprint would not reflect them. If dir has
+// no logical schema, an empty string is returned. This function does not
+// recurse into subdirs.
+func fingerprintDir(dir *fs.Dir) (string, error) {
+	if len(dir.LogicalSchemas) == 0 {
+		return "", nil
+	}
+	opts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return "", NewExitValue(CodeBadConfig, err.Error())
+	}
+
+	// Get workspace options for dir. This involves connecting to the first
+	// defined instance, unless configured to use local Docker.
+	var inst *tengo.Instance
+	if wsType, _ := dir.Config.GetEnum("workspace", "temp-schema", "docker"); wsType != "docker" || !dir.Config.Changed("flavor") {
+		if inst, err = dir.FirstInstance(); err != nil {
+			return "", NewExitValue(CodeBadConfig, err.Error())
+		}
+	}
+	wsOpts, err := workspace.OptionsForDir(dir, inst)
+	if err != nil {
+		return "", NewExitValue(CodeBadConfig, err.Error())
+	}
+
+	// Each line of output represents a single schema, so a dir whose *.sql files
+	// refer to multiple schemas by name cannot be fingerprinted
+	if len(dir.LogicalSchemas) > 1 {
+		return "", NewExitValue(CodeBadConfig, "Unable to fingerprint %s: its *.sql files contain statements for %d different schemas", dir, len(dir.LogicalSchemas))
+	}
+	wsSchema, err := workspace.ExecLogicalSchema(dir.LogicalSchemas[0], wsOpts)
+	if err != nil {
+		return "", err
+	}
+	for _, stmtErr := range wsSchema.Failures {
+		log.Errorf("%s: %s", stmtErr.Location(), stmtErr.Err)
+	}
+	if len(wsSchema.Failures) > 0 {
+		return "", NewExitValue(CodeFatalError, "%s could not be executed", countAndNoun(len(wsSchema.Failures), "statement", "statements"))
+	}
+	schema := introspect.FilterSchema(wsSchema.Schema, opts)
+	return introspect.Fingerprint(schema, introspect.WorkspaceObjects(wsSchema, opts)), nil
+}
+
+// fingerprintLiveDir outputs the fingerprint of each live schema that dir maps
+// to, on each of dir's instances. Schemas which do not exist yet are skipped.
+// This function does not recurse into subdirs.
+func fingerprintLiveDir(dir *fs.Dir) error {
+	if !dir.HasSchema() {
+		return nil
+	}
+	opts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	instances, err := dir.Instances()
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	for _, inst := range instances {
+		schemaNames, err := dir.SchemaNames(inst)
+		if err != nil {
+			return NewExitValue(CodeBadConfig, err.Error())
+		}
+		for _, name := range schemaNames {
+			if exists, err := inst.HasSchema(name); err != nil {
+				return err
+			} else if !exists {
+				log.Warnf("Skipping %s %s: schema does not exist yet", inst, name)
+				continue
+			}
+			schema, err := introspect.Schema(inst, name, opts)
+			if err != nil {
+				return err
+			} else if schema == nil { // ignored by ignore-schema
+				continue
+			}
+			objs, err := introspect.SchemaObjects(inst, name, opts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", introspect.Fingerprint(schema, objs), dir.RelPath(), inst, name)
+		}
+	}
+	return nil
+}
@@ -26,19 +26,28 @@ apply config directives from the [staging] section of config files, as well as
 any sectionless directives at the top of the file. If no environment name is
 supplied, the default is "production".
 
+With --check, files are not rewritten; each file requiring changes is listed
+instead. This is useful in CI pipelines to reject commits containing files
+which are not in the canonical format.
+
 An exit code of 0 will be returned if all files were already formatted properly;
 1 if some files were not already in the correct format; or 2+ if any errors
 occurred.`
 
 	cmd := mybase.NewCommand("format", summary, desc, FormatHandler)
 	cmd.AddOption(mybase.BoolOption("write", 0, true, "Update files to correct format"))
+	cmd.AddOption(mybase.BoolOption("check", 0, false, "Only list files which are not in the correct format, without rewriting them; equivalent to --skip-write"))
+	cmd.AddOption(mybase.BoolOption("preserve-comments", 0, false, "When reformatting CREATE TABLE statements, retain comments from inside the table body"))
+	cmd.AddOption(mybase.BoolOption("explicit-collations", 0, false, "Include character set and collation clauses for every table and string column, even if they match defaults"))
+	addUTF8AliasOption(cmd)
+	addDirOption(cmd)
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 }
 
 // FormatHandler is the handler method for `skeema format`
 func FormatHandler(cfg *mybase.Config) error {
-	dir, err := fs.ParseDir(".", cfg)
+	dirs, err := parseDirs(cfg)
 	if err != nil {
 		return err
 	}
@@ -48,7 +57,11 @@ func FormatHandler(cfg *mybase.Config) error {
 	// have been logged. (Multiple errors may have been encountered along the way,
 	// and it's simpler to log them when they occur, rather than needlessly
 	// collecting them.)
-	err = formatWalker(dir, 5)
+	for _, dir := range dirs {
+		if dirErr := formatWalker(dir, 5); ExitCode(dirErr) > ExitCode(err) {
+			err = dirErr
+		}
+	}
 	return NewExitValue(ExitCode(err), "")
 }
 
@@ -58,7 +71,7 @@ func formatWalker(dir *fs.Dir, maxDepth int) error {
 		return NewExitValue(CodeBadConfig, "")
 	}
 
-	if dir.Config.GetBool("write") {
+	if formatWrite(dir) {
 		log.Infof("Reformatting %s", dir)
 	} else {
 		log.Infof("Checking format of %s", dir)
@@ -86,6 +99,12 @@ func formatWalker(dir *fs.Dir, maxDepth int) error {
 	return result
 }
 
+// formatWrite returns true if `skeema format` should rewrite files in dir, or
+// false if it should only report which files require changes.
+func formatWrite(dir *fs.Dir) bool {
+	return dir.Config.GetBool("write") && !dir.Config.GetBool("check")
+}
+
 // formatDir reformats SQL statements in all logical schemas in dir. This
 // function does not recurse into subdirs.
 func formatDir(dir *fs.Dir) error {
@@ -93,6 +112,10 @@ func formatDir(dir *fs.Dir) error {
 	if err != nil {
 		return NewExitValue(CodeBadConfig, err.Error())
 	}
+	utf8Alias, err := utf8AliasForDir(dir)
+	if err != nil {
+		return err
+	}
 
 	// Get workspace options for dir. This involves connecting to the first
 	// defined instance, unless configured to use local Docker.
@@ -122,9 +145,13 @@ func formatDir(dir *fs.Dir) error {
 		}
 
 		dumpOpts := dumper.Options{
-			IncludeAutoInc: true,
-			IgnoreTable:    ignoreTable,
-			CountOnly:      !dir.Config.GetBool("write"),
+			IncludeAutoInc:     true,
+			IgnoreTable:        ignoreTable,
+			CountOnly:          !formatWrite(dir),
+			PreserveComments:   dir.Config.GetBool("preserve-comments"),
+			ExplicitCollations: dir.Config.GetBool("explicit-collations"),
+			UTF8Alias:          utf8Alias,
+			DefaultTableOpts:   wsOpts.DefaultTableOptions,
 		}
 		dumpOpts.IgnoreKeys(wsSchema.FailedKeys())
 		reformatCount, err := dumper.DumpSchema(wsSchema.Schema, dir, dumpOpts)
@@ -11,6 +11,8 @@ import (
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
 
@@ -36,8 +38,13 @@ section of the file.`
 	cmd.AddOption(mybase.StringOption("dir", 'd', "<hostname>", "Subdir name to use for this host's schemas"))
 	cmd.AddOption(mybase.StringOption("schema", 0, "", "Only import the one specified schema; skip creation of subdirs for each schema"))
 	cmd.AddOption(mybase.BoolOption("include-auto-inc", 0, false, "Include starting auto-inc values in table files"))
+	cmd.AddOption(mybase.BoolOption("explicit-collations", 0, false, "Include character set and collation clauses for every table and string column, even if they match defaults"))
+	addUTF8AliasOption(cmd)
 	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex"))
+	cmd.AddOption(mybase.StringOption("include-schemas", 0, "", "Only populate dirs for schemas matching these comma-separated wildcard patterns"))
+	cmd.AddOption(mybase.StringOption("exclude-schemas", 0, "", "Never populate dirs for schemas matching these comma-separated wildcard patterns"))
 	cmd.AddOption(mybase.StringOption("ignore-table", 0, "", "Ignore tables that match regex"))
+	cmd.AddOption(mybase.StringOption("filename-template", 0, "", "Template for naming .sql files of new objects, e.g. {TYPE}_{NAME}.sql"))
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 }
@@ -58,6 +65,11 @@ func InitHandler(cfg *mybase.Config) error {
 		return NewExitValue(CodeBadConfig, "Environment name \"%s\" is invalid", environment)
 	}
 
+	filter, err := newSchemaFilter(cfg.GetSlice("include-schemas", ',', true), cfg.GetSlice("exclude-schemas", ',', true))
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+
 	hostDir, err := createHostDir(cfg)
 	if err != nil {
 		return err
@@ -86,6 +98,18 @@ func InitHandler(cfg *mybase.Config) error {
 		return NewExitValue(CodeBadConfig, "Schema %s does not exist on instance %s", onlySchema, inst)
 	}
 
+	// Without --schema, restrict the schemas using include-schemas and
+	// exclude-schemas, in the same manner as pull's detection of new schemas
+	if onlySchema == "" {
+		filtered := make([]*tengo.Schema, 0, len(schemas))
+		for _, s := range schemas {
+			if filter.match(s.Name) {
+				filtered = append(filtered, s)
+			}
+		}
+		schemas = filtered
+	}
+
 	// Write host option file
 	err = createHostOptionFile(cfg, hostDir, inst, schemas)
 	if err != nil {
@@ -94,7 +118,7 @@ func InitHandler(cfg *mybase.Config) error {
 
 	// Iterate over the schemas. For each one, create a dir with .skeema and *.sql files
 	for _, s := range schemas {
-		if err := PopulateSchemaDir(s, hostDir, separateSchemaSubdir); err != nil {
+		if err := PopulateSchemaDir(s, inst, hostDir, separateSchemaSubdir); err != nil {
 			return err
 		}
 	}
@@ -151,7 +175,7 @@ func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Insta
 	} else {
 		hostOptionFile.SetOptionValue(environment, "flavor", flavor.Family().String())
 	}
-	for _, persistOpt := range []string{"user", "ignore-schema", "ignore-table", "connect-options"} {
+	for _, persistOpt := range []string{"user", "ignore-schema", "ignore-table", "object-types", "connect-options", "filename-template"} {
 		if cfg.OnCLI(persistOpt) {
 			hostOptionFile.SetOptionValue(environment, persistOpt, cfg.Get(persistOpt))
 		}
@@ -162,9 +186,17 @@ func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Insta
 	// section/environment since the default assumption is that schema names match
 	// between environments.
 	if cfg.Changed("schema") {
+		utf8Alias, err := utf8AliasForDir(hostDir)
+		if err != nil {
+			return err
+		}
+		charSet, collation := schemas[0].CharSet, schemas[0].Collation
+		if utf8Alias != "" {
+			charSet, collation = fs.UTF8Alias(charSet, utf8Alias), fs.UTF8Alias(collation, utf8Alias)
+		}
 		hostOptionFile.SetOptionValue("", "schema", cfg.Get("schema"))
-		hostOptionFile.SetOptionValue("", "default-character-set", schemas[0].CharSet)
-		hostOptionFile.SetOptionValue("", "default-collation", schemas[0].Collation)
+		hostOptionFile.SetOptionValue("", "default-character-set", charSet)
+		hostOptionFile.SetOptionValue("", "default-collation", collation)
 	}
 
 	// By default, Skeema normally connects using strict sql_mode as well as
@@ -197,13 +229,13 @@ func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Insta
 	return nil
 }
 
-// PopulateSchemaDir writes out *.sql files for all tables in the specified
-// schema. If makeSubdir==true, a subdir with name matching the schema name
-// will be created, and a .skeema option file will be created. Otherwise, the
-// *.sql files will be put in parentDir, and it will be the caller's
+// PopulateSchemaDir writes out *.sql files for all tables, routines, events,
+// sequences, views, and triggers in the specified schema, which must be from inst. If makeSubdir==true, a
+// subdir with name matching the schema name will be created, and a .skeema
+// option file will be created. Otherwise, the *.sql files will be put in parentDir, and it will be the caller's
 // responsibility to ensure its .skeema option file exists and maps to the
 // correct schema name.
-func PopulateSchemaDir(s *tengo.Schema, parentDir *fs.Dir, makeSubdir bool) error {
+func PopulateSchemaDir(s *tengo.Schema, inst *tengo.Instance, parentDir *fs.Dir, makeSubdir bool) error {
 	// Ignore any attempt to populate a dir for the temp schema
 	if s.Name == parentDir.Config.Get("temp-schema") {
 		return nil
@@ -216,13 +248,21 @@ func PopulateSchemaDir(s *tengo.Schema, parentDir *fs.Dir, makeSubdir bool) erro
 		return nil
 	}
 
+	utf8Alias, err := utf8AliasForDir(parentDir)
+	if err != nil {
+		return err
+	}
+	charSet, collation := s.CharSet, s.Collation
+	if utf8Alias != "" {
+		charSet, collation = fs.UTF8Alias(charSet, utf8Alias), fs.UTF8Alias(collation, utf8Alias)
+	}
+
 	var dir *fs.Dir
-	var err error
 	if makeSubdir {
 		optionFile := mybase.NewFile(path.Join(parentDir.Path, s.Name), ".skeema")
 		optionFile.SetOptionValue("", "schema", s.Name)
-		optionFile.SetOptionValue("", "default-character-set", s.CharSet)
-		optionFile.SetOptionValue("", "default-collation", s.Collation)
+		optionFile.SetOptionValue("", "default-character-set", charSet)
+		optionFile.SetOptionValue("", "default-collation", collation)
 		dir, err = parentDir.CreateSubdir(s.Name, optionFile)
 		if err != nil {
 			return NewExitValue(CodeCantCreate, "Unable to create subdirectory for schema %s: %s", s.Name, err)
@@ -233,12 +273,51 @@ func PopulateSchemaDir(s *tengo.Schema, parentDir *fs.Dir, makeSubdir bool) erro
 	log.Infof("Populating %s", dir)
 
 	dumpOpts := dumper.Options{
-		IncludeAutoInc: dir.Config.GetBool("include-auto-inc"),
+		IncludeAutoInc:     dir.Config.GetBool("include-auto-inc"),
+		FileNameTemplate:   dir.Config.Get("filename-template"),
+		ExplicitCollations: dir.Config.GetBool("explicit-collations"),
+		UTF8Alias:          utf8Alias,
 	}
 	dumpOpts.IgnoreTable, err = dir.Config.GetRegexp("ignore-table")
 	if err != nil {
 		return NewExitValue(CodeBadConfig, err.Error())
 	}
+	introspectOpts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	dumpOpts.ObjectTypes = introspectOpts.ObjectTypes
+	if err := fs.ValidateFileNameTemplate(dumpOpts.FileNameTemplate); err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	if introspectOpts.IncludesType(fs.ObjectTypeEvent) {
+		events, err := introspectSchemaEvents(inst, s.Name)
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Unable to fetch events of schema %s from %s: %s", s.Name, inst, err)
+		}
+		dumpOpts.EventCreates = eventCreates(events)
+	}
+	if introspectOpts.IncludesType(fs.ObjectTypeSequence) && workspace.SupportsSequences(inst.Flavor()) {
+		sequences, err := introspectSchemaSequences(inst, s.Name)
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Unable to fetch sequences of schema %s from %s: %s", s.Name, inst, err)
+		}
+		dumpOpts.SequenceCreates = sequenceCreates(sequences)
+	}
+	if introspectOpts.IncludesType(fs.ObjectTypeView) {
+		views, err := introspectSchemaViews(inst, s.Name)
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Unable to fetch views of schema %s from %s: %s", s.Name, inst, err)
+		}
+		dumpOpts.ViewCreates = viewCreates(views)
+	}
+	if introspectOpts.IncludesType(fs.ObjectTypeTrigger) {
+		triggers, err := introspectSchemaTriggers(inst, s.Name)
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Unable to fetch triggers of schema %s from %s: %s", s.Name, inst, err)
+		}
+		setTriggerCreates(&dumpOpts, triggers)
+	}
 
 	if _, err = dumper.DumpSchema(s, dir, dumpOpts); err != nil {
 		return NewExitValue(CodeCantCreate, "Unable to write in %s: %s", dir, err)
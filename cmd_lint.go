@@ -40,18 +40,25 @@ some files were reformatted; or 2+ if any errors were emitted for any reason.`
 	cmd := mybase.NewCommand("lint", summary, desc, LintHandler)
 	linter.AddCommandOptions(cmd)
 	cmd.AddOption(mybase.BoolOption("format", 0, true, "Reformat SQL statements to match canonical SHOW CREATE"))
+	cmd.AddOption(mybase.BoolOption("preserve-comments", 0, false, "When reformatting CREATE TABLE statements, retain comments from inside the table body"))
+	cmd.AddOption(mybase.BoolOption("explicit-collations", 0, false, "Include character set and collation clauses for every table and string column, even if they match defaults"))
+	addUTF8AliasOption(cmd)
+	addDirOption(cmd)
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 }
 
 // LintHandler is the handler method for `skeema lint`
 func LintHandler(cfg *mybase.Config) error {
-	dir, err := fs.ParseDir(".", cfg)
+	dirs, err := parseDirs(cfg)
 	if err != nil {
 		return err
 	}
 
-	result := lintWalker(dir, 5)
+	result := lintWalker(dirs[0], 5)
+	for _, dir := range dirs[1:] {
+		result.Merge(lintWalker(dir, 5))
+	}
 	switch {
 	case len(result.Exceptions) > 0:
 		exitCode := CodeFatalError
@@ -129,6 +136,10 @@ func lintDir(dir *fs.Dir) *linter.Result {
 	if err != nil && len(dir.LogicalSchemas) > 0 {
 		return linter.BadConfigResult(dir, err)
 	}
+	utf8Alias, err := utf8AliasForDir(dir)
+	if err != nil && len(dir.LogicalSchemas) > 0 {
+		return linter.BadConfigResult(dir, err)
+	}
 
 	// Get workspace options for dir. This involves connecting to the first
 	// defined instance, unless configured to use local Docker.
@@ -160,8 +171,12 @@ func lintDir(dir *fs.Dir) *linter.Result {
 		// problems. Otherwise, the line offsets in annotations can be wrong.
 		if dir.Config.GetBool("format") {
 			dumpOpts := dumper.Options{
-				IncludeAutoInc: true,
-				IgnoreTable:    opts.IgnoreTable,
+				IncludeAutoInc:     true,
+				IgnoreTable:        opts.IgnoreTable,
+				PreserveComments:   dir.Config.GetBool("preserve-comments"),
+				ExplicitCollations: dir.Config.GetBool("explicit-collations"),
+				UTF8Alias:          utf8Alias,
+				DefaultTableOpts:   wsOpts.DefaultTableOptions,
 			}
 			dumpOpts.IgnoreKeys(wsSchema.FailedKeys())
 			result.ReformatCount, err = dumper.DumpSchema(wsSchema.Schema, dir, dumpOpts)
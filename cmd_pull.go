@@ -4,12 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path"
 	"regexp"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
 	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
@@ -31,8 +33,15 @@ top of the file. If no environment name is supplied, the default is
 	cmd := mybase.NewCommand("pull", summary, desc, PullHandler)
 	cmd.AddOption(mybase.BoolOption("include-auto-inc", 0, false, "Include starting auto-inc values in new table files, and update in existing files"))
 	cmd.AddOption(mybase.BoolOption("format", 0, true, "Reformat SQL statements to match canonical SHOW CREATE"))
+	cmd.AddOption(mybase.BoolOption("preserve-comments", 0, false, "When reformatting CREATE TABLE statements, retain comments from inside the table body"))
+	cmd.AddOption(mybase.BoolOption("explicit-collations", 0, false, "Include character set and collation clauses for every table and string column, even if they match defaults"))
+	addUTF8AliasOption(cmd)
+	cmd.AddOption(mybase.StringOption("target-flavor", 0, "", "Convert table definitions to the syntax of this flavor where possible, e.g. mariadb:10.4"))
 	cmd.AddOption(mybase.BoolOption("normalize", 0, true, "(deprecated alias for format)").Hidden())
 	cmd.AddOption(mybase.BoolOption("new-schemas", 0, true, "Detect any new schemas and populate new dirs for them"))
+	cmd.AddOption(mybase.StringOption("include-schemas", 0, "", "With new-schemas, only populate new dirs for schemas matching these comma-separated wildcard patterns"))
+	cmd.AddOption(mybase.StringOption("exclude-schemas", 0, "", "With new-schemas, never populate new dirs for schemas matching these comma-separated wildcard patterns"))
+	cmd.AddOption(mybase.StringOption("filename-template", 0, "", "Template for naming .sql files of new objects, e.g. {TYPE}_{NAME}.sql"))
 	cmd.AddOption(mybase.StringOption("partitioning", 0, "keep", "(slight pull impact of having partitioning=remove in .skeema file for diff/push)").Hidden())
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
@@ -150,8 +159,12 @@ func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.
 		// TODO: support pull for case where multiple explicitly-named schemas per
 		// dir. For example, ability to convert a multi-schema single-file mysqldump
 		// into Skeema's usual multi-dir layout.
-		log.Warnf("Ignoring schema %s from directory %s -- multiple schemas per dir not supported yet", logicalSchema.Name, dir)
-		return []string{logicalSchema.Name}, nil
+		schemaName, err := dir.AliasedSchemaName(logicalSchema.Name)
+		if err != nil {
+			return nil, err
+		}
+		log.Warnf("Ignoring schema %s from directory %s -- multiple schemas per dir not supported yet", schemaName, dir)
+		return []string{schemaName}, nil
 	}
 	if schemaNames, err = dir.SchemaNames(instance); err != nil {
 		return nil, fmt.Errorf("%s: Unable to fetch schema names mapped by this dir: %s", dir, err)
@@ -170,11 +183,38 @@ func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.
 
 	log.Infof("Updating %s to reflect %s %s", dir, instance, instSchema.Name)
 
+	// With target-flavor, definitions are converted from the instance's flavor
+	// to the target flavor's syntax, including the schema's default collation.
+	var sourceFlavor, targetFlavor tengo.Flavor
+	collation := instSchema.Collation
+	if dir.Config.Changed("target-flavor") {
+		targetFlavor = tengo.NewFlavor(dir.Config.Get("target-flavor"))
+		if !targetFlavor.Known() {
+			return nil, NewExitValue(CodeBadConfig, "Option target-flavor has invalid value %q", dir.Config.Get("target-flavor"))
+		}
+		sourceFlavor = statementModifiersForPull(dir.Config, instance, nil).Flavor
+		var exact bool
+		if collation, exact = dumper.ConvertCollation(collation, sourceFlavor, targetFlavor); !exact {
+			log.Warnf("%s: Collation %s does not exist in %s; substituting %s, which may compare or sort differently", dir, instSchema.Collation, targetFlavor, collation)
+		}
+	}
+
+	// With utf8-alias, the schema's default character set and collation use the
+	// configured name for utf8, just like the *.sql files
+	utf8Alias, err := utf8AliasForDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	charSet := instSchema.CharSet
+	if utf8Alias != "" {
+		charSet, collation = fs.UTF8Alias(charSet, utf8Alias), fs.UTF8Alias(collation, utf8Alias)
+	}
+
 	// Handle changes in schema's default character set and/or collation by
 	// persisting changes to the dir's option file.
-	if dir.Config.Get("default-character-set") != instSchema.CharSet || dir.Config.Get("default-collation") != instSchema.Collation {
-		dir.OptionFile.SetOptionValue("", "default-character-set", instSchema.CharSet)
-		dir.OptionFile.SetOptionValue("", "default-collation", instSchema.Collation)
+	if dir.Config.Get("default-character-set") != charSet || dir.Config.Get("default-collation") != collation {
+		dir.OptionFile.SetOptionValue("", "default-character-set", charSet)
+		dir.OptionFile.SetOptionValue("", "default-collation", collation)
 		if err := dir.OptionFile.Write(true); err != nil {
 			return nil, fmt.Errorf("Unable to update character set and collation for %s: %s", dir.OptionFile.Path(), err)
 		}
@@ -182,14 +222,59 @@ func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.
 	}
 
 	dumpOpts := dumper.Options{
-		IncludeAutoInc: dir.Config.GetBool("include-auto-inc"),
+		IncludeAutoInc:     dir.Config.GetBool("include-auto-inc"),
+		FileNameTemplate:   dir.Config.Get("filename-template"),
+		PreserveComments:   dir.Config.GetBool("preserve-comments"),
+		ExplicitCollations: dir.Config.GetBool("explicit-collations"),
+		UTF8Alias:          utf8Alias,
+		SourceFlavor:       sourceFlavor,
+		TargetFlavor:       targetFlavor,
 	}
 	if dumpOpts.IgnoreTable, err = dir.Config.GetRegexp("ignore-table"); err != nil {
 		return nil, NewExitValue(CodeBadConfig, err.Error())
 	}
+	introspectOpts, err := introspect.OptionsForDir(dir)
+	if err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	}
+	dumpOpts.ObjectTypes = introspectOpts.ObjectTypes
+	if dumpOpts.DefaultTableOpts, err = dir.DefaultTableOptions(); err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	}
+	if err := fs.ValidateFileNameTemplate(dumpOpts.FileNameTemplate); err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	}
 	if partitioning, _ := dir.Config.GetEnum("partitioning", "keep", "remove", "modify"); partitioning == "remove" {
 		dumpOpts.RetainPartitioning = true
 	}
+	var liveEvents []*workspace.Event
+	if introspectOpts.IncludesType(fs.ObjectTypeEvent) {
+		if liveEvents, err = introspectSchemaEvents(instance, instSchema.Name); err != nil {
+			return nil, fmt.Errorf("%s: Unable to fetch events of schema %s from %s: %s", dir, instSchema.Name, instance, err)
+		}
+		dumpOpts.EventCreates = eventCreates(liveEvents)
+	}
+	var liveSequences []*workspace.Sequence
+	if introspectOpts.IncludesType(fs.ObjectTypeSequence) && workspace.SupportsSequences(instance.Flavor()) {
+		if liveSequences, err = introspectSchemaSequences(instance, instSchema.Name); err != nil {
+			return nil, fmt.Errorf("%s: Unable to fetch sequences of schema %s from %s: %s", dir, instSchema.Name, instance, err)
+		}
+		dumpOpts.SequenceCreates = sequenceCreates(liveSequences)
+	}
+	var liveViews []*workspace.View
+	if introspectOpts.IncludesType(fs.ObjectTypeView) {
+		if liveViews, err = introspectSchemaViews(instance, instSchema.Name); err != nil {
+			return nil, fmt.Errorf("%s: Unable to fetch views of schema %s from %s: %s", dir, instSchema.Name, instance, err)
+		}
+		dumpOpts.ViewCreates = viewCreates(liveViews)
+	}
+	var liveTriggers []*workspace.Trigger
+	if introspectOpts.IncludesType(fs.ObjectTypeTrigger) {
+		if liveTriggers, err = introspectSchemaTriggers(instance, instSchema.Name); err != nil {
+			return nil, fmt.Errorf("%s: Unable to fetch triggers of schema %s from %s: %s", dir, instSchema.Name, instance, err)
+		}
+		setTriggerCreates(&dumpOpts, liveTriggers)
+	}
 
 	// When --skip-format is in use, we only want to update objects that have
 	// actual functional modifications, NOT just cosmetic/formatting differences.
@@ -201,7 +286,7 @@ func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.
 		if err != nil {
 			return nil, NewExitValue(CodeBadConfig, err.Error())
 		}
-		inDiff, err := objectsInDiff(logicalSchema, instSchema, opts, mods)
+		inDiff, err := objectsInDiff(logicalSchema, instSchema, liveEvents, liveSequences, liveViews, liveTriggers, opts, mods)
 		if err != nil {
 			return nil, err
 		}
@@ -247,8 +332,9 @@ func statementModifiersForPull(config *mybase.Config, instance *tengo.Instance,
 // representation yet. This also includes objects whose filesystem Statement has
 // a SQL syntax error. The return value does not include tables whose
 // differences are cosmetic / formatting-related, or are otherwise ignored by
-// mods.
-func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, opts workspace.Options, mods tengo.StatementModifiers) ([]tengo.ObjectKey, error) {
+// mods. Since tengo does not support events, sequences, views, or triggers,
+// these objects of instSchema must be supplied separately.
+func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, liveEvents []*workspace.Event, liveSequences []*workspace.Sequence, liveViews []*workspace.View, liveTriggers []*workspace.Trigger, opts workspace.Options, mods tengo.StatementModifiers) ([]tengo.ObjectKey, error) {
 	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
 	if err != nil {
 		return nil, fmt.Errorf("Error introspecting filesystem version of schema %s: %s", instSchema.Name, err)
@@ -272,6 +358,67 @@ func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, op
 		}
 	}
 
+	// Compare events separately, ignoring metadata just like tengo does for
+	// routines by default
+	fsEvents := make(map[string]*workspace.Event, len(wsSchema.Events))
+	for _, event := range wsSchema.Events {
+		fsEvents[event.Name] = event
+	}
+	for _, live := range liveEvents {
+		if event := fsEvents[live.Name]; event == nil || !event.Matches(live, false) {
+			inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: live.Name})
+		}
+		delete(fsEvents, live.Name)
+	}
+	for name := range fsEvents {
+		inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: name})
+	}
+
+	// Likewise for sequences
+	fsSequences := make(map[string]*workspace.Sequence, len(wsSchema.Sequences))
+	for _, seq := range wsSchema.Sequences {
+		fsSequences[seq.Name] = seq
+	}
+	for _, live := range liveSequences {
+		if seq := fsSequences[live.Name]; seq == nil || !seq.Matches(live) {
+			inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: live.Name})
+		}
+		delete(fsSequences, live.Name)
+	}
+	for name := range fsSequences {
+		inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: name})
+	}
+
+	// Likewise for views
+	fsViews := make(map[string]*workspace.View, len(wsSchema.Views))
+	for _, view := range wsSchema.Views {
+		fsViews[view.Name] = view
+	}
+	for _, live := range liveViews {
+		if view := fsViews[live.Name]; view == nil || !view.Matches(live) {
+			inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeView, Name: live.Name})
+		}
+		delete(fsViews, live.Name)
+	}
+	for name := range fsViews {
+		inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeView, Name: name})
+	}
+
+	// Likewise for triggers
+	fsTriggers := make(map[string]*workspace.Trigger, len(wsSchema.Triggers))
+	for _, trigger := range wsSchema.Triggers {
+		fsTriggers[trigger.Name] = trigger
+	}
+	for _, live := range liveTriggers {
+		if trigger := fsTriggers[live.Name]; trigger == nil || !trigger.Matches(live, mods.CompareMetadata) {
+			inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: live.Name})
+		}
+		delete(fsTriggers, live.Name)
+	}
+	for name := range fsTriggers {
+		inDiff = append(inDiff, tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: name})
+	}
+
 	// Treat objects with syntax errors as modified, since it isn't possible for
 	// the filesystem definition to match the live definition in this case.
 	inDiff = append(inDiff, wsSchema.FailedKeys()...)
@@ -279,6 +426,89 @@ func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, op
 	return inDiff, nil
 }
 
+// introspectSchemaEvents returns the events in the named schema on instance.
+func introspectSchemaEvents(instance *tengo.Instance, schemaName string) ([]*workspace.Event, error) {
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	return workspace.IntrospectEvents(db)
+}
+
+// eventCreates returns a map of event name to CREATE EVENT statement, suitable
+// for use in dumper.Options.
+func eventCreates(events []*workspace.Event) map[string]string {
+	creates := make(map[string]string, len(events))
+	for _, event := range events {
+		creates[event.Name] = event.CreateStatement
+	}
+	return creates
+}
+
+// introspectSchemaSequences returns the sequences in the named schema on
+// instance.
+func introspectSchemaSequences(instance *tengo.Instance, schemaName string) ([]*workspace.Sequence, error) {
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	return workspace.IntrospectSequences(db)
+}
+
+// sequenceCreates returns a map of sequence name to CREATE SEQUENCE statement,
+// suitable for use in dumper.Options.
+func sequenceCreates(sequences []*workspace.Sequence) map[string]string {
+	creates := make(map[string]string, len(sequences))
+	for _, seq := range sequences {
+		creates[seq.Name] = seq.CreateStatement
+	}
+	return creates
+}
+
+// introspectSchemaViews returns the views in the named schema on instance.
+func introspectSchemaViews(instance *tengo.Instance, schemaName string) ([]*workspace.View, error) {
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	return workspace.IntrospectViews(db)
+}
+
+// viewCreates returns a map of view name to CREATE VIEW statement, suitable for
+// use in dumper.Options.
+func viewCreates(views []*workspace.View) map[string]string {
+	creates := make(map[string]string, len(views))
+	for _, view := range views {
+		creates[view.Name] = view.CreateStatement
+	}
+	return creates
+}
+
+// introspectSchemaTriggers returns the triggers in the named schema on
+// instance.
+func introspectSchemaTriggers(instance *tengo.Instance, schemaName string) ([]*workspace.Trigger, error) {
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	return workspace.IntrospectTriggers(db)
+}
+
+// setTriggerCreates populates dumpOpts.TriggerCreates from the supplied live
+// triggers. Triggers on tables matching dumpOpts.IgnoreTable are ignored by the
+// dump, so that their files are neither written nor removed.
+func setTriggerCreates(dumpOpts *dumper.Options, triggers []*workspace.Trigger) {
+	dumpOpts.TriggerCreates = make(map[string]string, len(triggers))
+	var ignoreKeys []tengo.ObjectKey
+	for _, trigger := range triggers {
+		dumpOpts.TriggerCreates[trigger.Name] = trigger.CreateStatement
+		if dumpOpts.IgnoreTable != nil && dumpOpts.IgnoreTable.MatchString(trigger.Table) {
+			ignoreKeys = append(ignoreKeys, tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: trigger.Name})
+		}
+	}
+	dumpOpts.IgnoreKeys(ignoreKeys)
+}
+
 // updateFlavor updates the dir's .skeema option file if the instance's current
 // flavor does not match what's in the file. However, it leaves the value in the
 // file alone if it's specified and we're unable to detect the instance's
@@ -303,23 +533,96 @@ func findNewSchemas(dir *fs.Dir, instance *tengo.Instance, seenNames []string) e
 		subdirHasSchema[name] = true
 	}
 
+	filter, err := newSchemaFilter(dir.Config.GetSlice("include-schemas", ',', true), dir.Config.GetSlice("exclude-schemas", ',', true))
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
 	schemaNames, err := instance.SchemaNames()
 	if err != nil {
 		return err
 	}
 	for _, name := range schemaNames {
-		// If no existing subdir maps to the schema, we need to create and populate new dir
-		if !subdirHasSchema[name] {
+		// If no existing subdir maps to the schema, we need to create and populate
+		// new dir, unless filtered out by include-schemas or exclude-schemas
+		if !subdirHasSchema[name] && filter.match(name) {
 			s, err := instance.Schema(name)
 			if err != nil {
 				return err
 			}
 			// use same logic from init command
-			if err := PopulateSchemaDir(s, dir, true); err != nil {
+			if err := PopulateSchemaDir(s, instance, dir, true); err != nil {
 				return err
 			}
+			// PopulateSchemaDir writes definitions in the instance's own syntax, so
+			// with target-flavor, pull into the new dir again to convert them
+			if dir.Config.Changed("target-flavor") {
+				if err := repullSubdir(dir, instance, name); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
 	return nil
 }
+
+// schemaFilter determines which schemas are populated into new dirs by init,
+// or by pull's detection of new schemas, based on the include-schemas and
+// exclude-schemas options. Each pattern may contain
+// shell-style wildcards: * matches any sequence of characters, and ? matches
+// any single character.
+type schemaFilter struct {
+	include []string
+	exclude []string
+}
+
+// newSchemaFilter returns a schemaFilter using the supplied include and
+// exclude patterns. An error is returned if any pattern is malformed.
+func newSchemaFilter(include, exclude []string) (schemaFilter, error) {
+	for n, patterns := range [][]string{include, exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				optionName := []string{"include-schemas", "exclude-schemas"}[n]
+				return schemaFilter{}, fmt.Errorf("Option %s has invalid pattern %q: %s", optionName, pattern, err)
+			}
+		}
+	}
+	return schemaFilter{include: include, exclude: exclude}, nil
+}
+
+// match returns true if name matches at least one include pattern (or there
+// are no include patterns), and does not match any exclude pattern.
+func (filter schemaFilter) match(name string) bool {
+	matchAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+	if len(filter.include) > 0 && !matchAny(filter.include) {
+		log.Debugf("Skipping schema %s because it does not match include-schemas", name)
+		return false
+	} else if matchAny(filter.exclude) {
+		log.Debugf("Skipping schema %s because it matches exclude-schemas", name)
+		return false
+	}
+	return true
+}
+
+// repullSubdir re-parses the subdir of dir with the supplied name, and then
+// pulls into it from instance.
+func repullSubdir(dir *fs.Dir, instance *tengo.Instance, name string) error {
+	subdirs, err := dir.Subdirs()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subdirs {
+		if path.Base(sub.Path) == name && sub.ParseError == nil {
+			_, err = pullSchemaDir(sub, instance)
+			return err
+		}
+	}
+	return nil
+}
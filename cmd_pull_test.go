@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestSchemaFilter(t *testing.T) {
+	cases := []struct {
+		include, exclude []string
+		expected         map[string]bool
+	}{
+		{nil, nil, map[string]bool{"app": true, "app_staging": true, "tmp_1": true}},
+		{[]string{"app*"}, nil, map[string]bool{"app": true, "app_staging": true, "tmp_1": false}},
+		{nil, []string{"tmp_?", "*_staging"}, map[string]bool{"app": true, "app_staging": false, "tmp_1": false, "tmp_12": true}},
+		{[]string{"app*", "tmp_*"}, []string{"*staging"}, map[string]bool{"app": true, "app_staging": false, "tmp_1": true, "other": false}},
+		{[]string{"app"}, []string{"app"}, map[string]bool{"app": false, "application": false}},
+	}
+	for _, c := range cases {
+		filter, err := newSchemaFilter(c.include, c.exclude)
+		if err != nil {
+			t.Fatalf("Unexpected error from newSchemaFilter(%v, %v): %v", c.include, c.exclude, err)
+		}
+		for name, expected := range c.expected {
+			if actual := filter.match(name); actual != expected {
+				t.Errorf("With include=%v exclude=%v, expected match(%q) to return %t, instead found %t", c.include, c.exclude, name, expected, actual)
+			}
+		}
+	}
+
+	// Malformed patterns cause an error
+	if _, err := newSchemaFilter([]string{"app[", "foo"}, nil); err == nil {
+		t.Error("Expected error from malformed include pattern, but err was nil")
+	}
+	if _, err := newSchemaFilter(nil, []string{"[]"}); err == nil {
+		t.Error("Expected error from malformed exclude pattern, but err was nil")
+	}
+}
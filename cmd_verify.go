@@ -0,0 +1,78 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+)
+
+func init() {
+	summary := "Check whether DB instances' schemas exactly match the filesystem"
+	desc := `Checks whether the schemas on database instance(s) match the corresponding
+filesystem representation of them, without generating any DDL. This is designed
+for use in CI pipelines or monitoring, as a simple guard against out-of-band
+schema changes ("drift").
+
+The list of instances (host:port) with at least one difference is output to
+STDOUT. This command is equivalent to ` + "`" + `skeema diff --brief --skip-lint` + "`" + `,
+but with a clearer pass/fail result. Since no DDL is being generated, the
+linter and the workspace-based verification of ALTER statements are always
+skipped. The ignore-schema and ignore-table options are honored.
+
+You may optionally pass an environment name as a CLI option. This will affect
+which section of .skeema config files is used for processing. For example,
+running ` + "`" + `skeema verify staging` + "`" + ` will apply config directives from the
+[staging] section of config files, as well as any sectionless directives at the
+top of the file. If no environment name is supplied, the default is
+"production".
+
+An exit code of 0 will be returned if all schemas match the filesystem, 1 if
+some differences were found, or 2+ if an error occurred.`
+
+	cmd := mybase.NewCommand("verify", summary, desc, VerifyHandler)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+	clonePushOptionsToVerify()
+}
+
+// VerifyHandler is the handler method for `skeema verify`
+func VerifyHandler(cfg *mybase.Config) error {
+	// We just delegate to PushHandler in diff --brief mode, with linting disabled
+	cfg.CLI.OptionValues["dry-run"] = "1"
+	cfg.CLI.OptionValues["brief"] = "1"
+	cfg.CLI.OptionValues["lint"] = "0"
+	cfg.MarkDirty()
+	err := PushHandler(cfg)
+	if err == nil {
+		log.Info("Verification passed: all schemas match the filesystem")
+	} else if ExitCode(err) == CodeDifferencesFound && err.Error() == "" {
+		err = NewExitValue(CodeDifferencesFound, "Verification failed: some schemas differ from the filesystem")
+	}
+	return err
+}
+
+// clonePushOptionsToVerify copies options from `skeema push` into
+// `skeema verify`. Only options which affect whether a difference is detected
+// remain visible on the CLI; all others are irrelevant or forcibly overridden.
+func clonePushOptionsToVerify() {
+	push, ok := CommandSuite.SubCommands["push"]
+	if !ok {
+		return
+	}
+	visible := map[string]bool{
+		"first-only":             true,
+		"exact-match":            true,
+		"reorder-columns":        true,
+		"include-system-columns": true,
+		"compare-metadata":       true,
+		"partitioning":           true,
+		"manage-partition-list":  true,
+		"concurrent-instances":   true,
+		"max-failures":           true,
+		"dir":                    true,
+	}
+	hiddenRewrites := make(map[string]bool)
+	for name := range push.Options() {
+		hiddenRewrites[name] = !visible[name]
+	}
+	clonePushOptions("verify", nil, hiddenRewrites)
+}
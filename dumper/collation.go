@@ -0,0 +1,66 @@
+package dumper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+var reTableOptionsLine = regexp.MustCompile(`\n\)[^\n]*`)
+
+// explicitCollations rewrites the supplied CREATE TABLE statement for table so
+// that every string column, as well as the table itself, has an explicit
+// character set and collation. Ordinarily SHOW CREATE TABLE omits these
+// clauses when they match the table default or the character set's default
+// collation, which makes the statement's meaning depend on the defaults of the
+// server it is executed on. If to is known, collations are converted to to's
+// equivalents, matching any flavor conversion already performed on create.
+func explicitCollations(create string, table *tengo.Table, from, to tengo.Flavor) string {
+	convert := func(collation string) string {
+		if to.Known() {
+			collation, _ = ConvertCollation(collation, from, to)
+		}
+		return collation
+	}
+
+	for _, col := range table.Columns {
+		if col.CharSet == "" || col.Collation == "" {
+			continue
+		}
+		prefix := fmt.Sprintf("\n  %s %s", tengo.EscapeIdentifier(col.Name), col.TypeInDB)
+		pos := strings.Index(create, prefix)
+		if pos < 0 {
+			continue
+		}
+		pos += len(prefix)
+		var clauses string
+		if rest := create[pos:]; strings.HasPrefix(rest, " CHARACTER SET ") {
+			end := strings.IndexAny(rest[len(" CHARACTER SET "):], " ,\n")
+			if end < 0 {
+				continue
+			}
+			pos += len(" CHARACTER SET ") + end
+		} else {
+			clauses = " CHARACTER SET " + col.CharSet
+		}
+		if !strings.HasPrefix(create[pos:], " COLLATE ") {
+			clauses += " COLLATE " + convert(col.Collation)
+		}
+		create = create[:pos] + clauses + create[pos:]
+	}
+
+	if table.CharSet == "" || table.Collation == "" {
+		return create
+	}
+	if m := reTableCollation.FindStringSubmatchIndex(create); m != nil {
+		if m[6] < 0 {
+			create = create[:m[1]] + " COLLATE=" + convert(table.Collation) + create[m[1]:]
+		}
+	} else if loc := reTableOptionsLine.FindStringIndex(create); loc != nil {
+		clause := fmt.Sprintf(" DEFAULT CHARSET=%s COLLATE=%s", table.CharSet, convert(table.Collation))
+		create = create[:loc[1]] + clause + create[loc[1]:]
+	}
+	return create
+}
@@ -0,0 +1,66 @@
+package dumper
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestExplicitCollations(t *testing.T) {
+	table := &tengo.Table{
+		Name:               "posts",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(10) unsigned"},
+			{Name: "title", TypeInDB: "varchar(100)", CharSet: "latin1", Collation: "latin1_swedish_ci", CollationIsDefault: true},
+			{Name: "slug", TypeInDB: "varchar(100)", CharSet: "latin1", Collation: "latin1_bin", Nullable: true, Default: "NULL"},
+			{Name: "body", TypeInDB: "text", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", CollationIsDefault: true, Nullable: true},
+			{Name: "tags", TypeInDB: "set('a','b')", CharSet: "utf8mb4", Collation: "utf8mb4_unicode_ci", Nullable: true, Default: "NULL"},
+		},
+	}
+	create := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  `title` varchar(100) NOT NULL,\n" +
+		"  `slug` varchar(100) COLLATE latin1_bin DEFAULT NULL,\n" +
+		"  `body` text CHARACTER SET utf8mb4,\n" +
+		"  `tags` set('a','b') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	expected := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  `title` varchar(100) CHARACTER SET latin1 COLLATE latin1_swedish_ci NOT NULL,\n" +
+		"  `slug` varchar(100) CHARACTER SET latin1 COLLATE latin1_bin DEFAULT NULL,\n" +
+		"  `body` text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci,\n" +
+		"  `tags` set('a','b') CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1 COLLATE=latin1_swedish_ci"
+	if actual := explicitCollations(create, table, tengo.FlavorUnknown, tengo.FlavorUnknown); actual != expected {
+		t.Errorf("Unexpected result from explicitCollations.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	// Already-explicit statements are unchanged
+	if actual := explicitCollations(expected, table, tengo.FlavorUnknown, tengo.FlavorUnknown); actual != expected {
+		t.Errorf("Expected explicitCollations to be idempotent, instead found:\n%s", actual)
+	}
+
+	// Table options lacking DEFAULT CHARSET entirely, e.g. after stripping
+	// default table options, have it added back; partitioning clauses are
+	// unaffected
+	table.Columns = table.Columns[:1]
+	create = "CREATE TABLE `posts` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB\n/*!50100 PARTITION BY KEY (id) PARTITIONS 2 */"
+	expected = "CREATE TABLE `posts` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1 COLLATE=latin1_swedish_ci\n/*!50100 PARTITION BY KEY (id) PARTITIONS 2 */"
+	if actual := explicitCollations(create, table, tengo.FlavorUnknown, tengo.FlavorUnknown); actual != expected {
+		t.Errorf("Unexpected result from explicitCollations.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	// With a target flavor, implied collations are converted
+	table.CharSet, table.Collation = "utf8mb4", "utf8mb4_0900_ai_ci"
+	create = "CREATE TABLE `posts` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	expected = create + " COLLATE=utf8mb4_general_ci"
+	if actual := explicitCollations(create, table, tengo.FlavorMySQL80, tengo.FlavorMariaDB104); actual != expected {
+		t.Errorf("Unexpected result from explicitCollations.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
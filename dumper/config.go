@@ -3,6 +3,7 @@ package dumper
 import (
 	"regexp"
 
+	"github.com/skeema/skeema/fs"
 	"github.com/skeema/tengo"
 )
 
@@ -12,6 +13,18 @@ type Options struct {
 	RetainPartitioning bool                     // if true, and fs stmt has partitioning, but db doesn't, retain fs partitioning clause
 	CountOnly          bool                     // if true, skip writing files, just report count of rewrites
 	IgnoreTable        *regexp.Regexp           // skip tables with names matching this regex
+	ObjectTypes        []tengo.ObjectType       // if non-empty, skip objects of all other types
+	FileNameTemplate   string                   // template for naming files of new objects; see fs.PathForObjectTemplate
+	PreserveComments   bool                     // if true, retain comments from inside the body of fs CREATE TABLE statements
+	SourceFlavor       tengo.Flavor             // flavor of the live db schema; only used with TargetFlavor
+	TargetFlavor       tengo.Flavor             // if known, convert CREATE TABLEs to this flavor's syntax where possible
+	DefaultTableOpts   fs.TableOptions          // omit these table options from CREATE TABLEs, if the fs stmt also omits them
+	ExplicitCollations bool                     // if true, include charset and collation of every string column and table
+	UTF8Alias          string                   // if "utf8" or "utf8mb3", use this name for the utf8 charset and its collations
+	EventCreates       map[string]string        // live CREATE EVENTs by event name; if nil, fs events are left as-is
+	SequenceCreates    map[string]string        // live CREATE SEQUENCEs by sequence name; if nil, fs sequences are left as-is
+	ViewCreates        map[string]string        // live CREATE VIEWs by view name; if nil, fs views are left as-is
+	TriggerCreates     map[string]string        // live CREATE TRIGGERs by trigger name; if nil, fs triggers are left as-is
 	skipKeys           map[tengo.ObjectKey]bool // skip objects with true values
 	onlyKeys           map[tengo.ObjectKey]bool // if map is non-nil, only format objects with true values
 }
@@ -47,9 +60,18 @@ func (opts *Options) shouldIgnore(key tengo.ObjectKey) bool {
 	if opts.skipKeys[key] {
 		return true
 	}
-	if key.Type == tengo.ObjectTypeTable && opts.IgnoreTable != nil && opts.IgnoreTable.MatchString(key.Name) {
+	if (key.Type == tengo.ObjectTypeTable || key.Type == fs.ObjectTypeView) && opts.IgnoreTable != nil && opts.IgnoreTable.MatchString(key.Name) {
 		return true
 	}
+	if len(opts.ObjectTypes) > 0 {
+		var wanted bool
+		for _, ot := range opts.ObjectTypes {
+			wanted = wanted || key.Type == ot
+		}
+		if !wanted {
+			return true
+		}
+	}
 	if opts.onlyKeys != nil && !opts.onlyKeys[key] {
 		return true
 	}
@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/skeema/skeema/fs"
 	"github.com/skeema/tengo"
 )
 
@@ -44,6 +45,13 @@ func TestOptionsIgnore(t *testing.T) {
 	assertIgnore(tengo.ObjectTypeTable, "cats", true)
 	assertIgnore(tengo.ObjectTypeFunc, "pounce", false)
 
+	// Confirm behavior of ObjectTypes
+	opts = Options{ObjectTypes: []tengo.ObjectType{tengo.ObjectTypeTable, tengo.ObjectTypeProc}}
+	assertIgnore(tengo.ObjectTypeTable, "cats", false)
+	assertIgnore(tengo.ObjectTypeProc, "pounce", false)
+	assertIgnore(tengo.ObjectTypeFunc, "pounce", true)
+	assertIgnore(fs.ObjectTypeEvent, "nightly", true)
+
 	// Confirm behavior of combination of these settings
 	opts = Options{
 		IgnoreTable: regexp.MustCompile("^multi"),
@@ -57,4 +65,9 @@ func TestOptionsIgnore(t *testing.T) {
 	assertIgnore(tengo.ObjectTypeTable, "cats", true)
 	assertIgnore(tengo.ObjectTypeTable, "horses", true)
 	assertIgnore(tengo.ObjectTypeTable, "dogs", false)
+
+	// Views share a namespace with tables, so IgnoreTable applies to them too
+	opts = Options{IgnoreTable: regexp.MustCompile("^multi")}
+	assertIgnore(fs.ObjectTypeView, "multi1", true)
+	assertIgnore(fs.ObjectTypeView, "cats", false)
 }
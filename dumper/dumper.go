@@ -6,6 +6,7 @@ package dumper
 
 import (
 	"fmt"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
@@ -26,8 +27,14 @@ type statement struct {
 // statements is returned, along with any fatal write error. If opts.CountOnly
 // is true, no actual filesystem writes occur, but a count is still returned.
 func DumpSchema(schema *tengo.Schema, dir *fs.Dir, opts Options) (count int, err error) {
+	statementMap := getStatementMap(schema, dir, opts)
+	newFilePaths, err := newObjectPaths(schema, dir, statementMap, opts)
+	if err != nil {
+		return 0, err
+	}
+
 	filesToRewrite := make(map[*fs.TokenizedSQLFile]bool)
-	for key, s := range getStatementMap(schema, dir, opts) {
+	for key, s := range statementMap {
 		if opts.shouldIgnore(key) || s.canonicalCreate == s.filesystemCreate {
 			continue
 		}
@@ -42,8 +49,7 @@ func DumpSchema(schema *tengo.Schema, dir *fs.Dir, opts Options) (count int, err
 
 		if s.fsStatement == nil { // exists in live db schema but not yet in filesystem
 			contents := fs.AddDelimiter(s.canonicalCreate)
-			filePath := fs.PathForObject(dir.Path, key.Name)
-			if err := appendToFile(filePath, contents); err != nil {
+			if err := appendToFile(newFilePaths[key], contents); err != nil {
 				return count, err
 			}
 		} else if s.canonicalCreate == "" { // already exists in filesystem, but does not exist in live db schema
@@ -79,6 +85,17 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 		logicalSchema = &fs.LogicalSchema{}
 	}
 	for key, stmt := range logicalSchema.Creates {
+		// tengo schemas never contain events, sequences, views, or triggers, so they
+		// are only handled if supplied separately
+		if key.Type == fs.ObjectTypeEvent && opts.EventCreates == nil {
+			continue
+		} else if key.Type == fs.ObjectTypeSequence && opts.SequenceCreates == nil {
+			continue
+		} else if key.Type == fs.ObjectTypeView && opts.ViewCreates == nil {
+			continue
+		} else if key.Type == fs.ObjectTypeTrigger && opts.TriggerCreates == nil {
+			continue
+		}
 		fsCreate, fsDelimiter := stmt.SplitTextBody()
 		statementMap[key] = statement{
 			filesystemCreate: fsCreate,
@@ -88,10 +105,28 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 	}
 
 	schemaObjects := schema.ObjectDefinitions()
+	for name, create := range opts.EventCreates {
+		schemaObjects[tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: name}] = create
+	}
+	for name, create := range opts.SequenceCreates {
+		schemaObjects[tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: name}] = create
+	}
+	for name, create := range opts.ViewCreates {
+		schemaObjects[tengo.ObjectKey{Type: fs.ObjectTypeView, Name: name}] = create
+	}
+	for name, create := range opts.TriggerCreates {
+		schemaObjects[tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: name}] = create
+	}
 	for key, canonicalCreate := range schemaObjects {
 		s := statementMap[key] // not a pointer, zero value fine
 		s.canonicalCreate = canonicalCreate
 
+		// MariaDB qualifies sequence function calls in column defaults with the
+		// schema name, which should not be persisted to the filesystem
+		if key.Type == tengo.ObjectTypeTable {
+			s.canonicalCreate = fs.UnqualifySequenceCalls(s.canonicalCreate, schema.Name)
+		}
+
 		// Include or strip auto_increment clause. (Note that if fs representation
 		// already exists and explicitly had an autoinc value > 1, we keep and update
 		// it regardless.)
@@ -101,6 +136,36 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 			}
 		}
 
+		// If the filesystem create omits any default table options, omit them from
+		// the canonical create as well, so that the file isn't rewritten to add them
+		if len(opts.DefaultTableOpts) > 0 && key.Type == tengo.ObjectTypeTable && s.fsStatement != nil {
+			s.canonicalCreate = opts.DefaultTableOpts.StripFromCreate(s.canonicalCreate, s.filesystemCreate)
+		}
+
+		// If requested, convert the canonical create to another flavor's syntax,
+		// warning about anything that cannot be converted exactly
+		if opts.TargetFlavor.Known() && key.Type == tengo.ObjectTypeTable {
+			var warnings []string
+			s.canonicalCreate, warnings = convertCreateFlavor(s.canonicalCreate, opts.SourceFlavor, opts.TargetFlavor)
+			for _, warning := range warnings {
+				log.Warnf("%s: %s", key, warning)
+			}
+		}
+
+		// If requested, make all column and table character sets and collations
+		// explicit, rather than relying on table or server defaults
+		if opts.ExplicitCollations && key.Type == tengo.ObjectTypeTable {
+			if table := schema.Table(key.Name); table != nil {
+				s.canonicalCreate = explicitCollations(s.canonicalCreate, table, opts.SourceFlavor, opts.TargetFlavor)
+			}
+		}
+
+		// If requested, consistently use one name for the utf8 character set, since
+		// servers report it as either utf8 or utf8mb3 depending on version
+		if opts.UTF8Alias != "" {
+			s.canonicalCreate = fs.ReplaceUTF8Alias(s.canonicalCreate, opts.UTF8Alias)
+		}
+
 		// If requested, adjust the canonical create to add the partitioning clause
 		// from the filesystem create.
 		if opts.RetainPartitioning && key.Type == tengo.ObjectTypeTable && s.fsStatement != nil {
@@ -111,6 +176,12 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 			}
 		}
 
+		// If requested, carry over any comments from inside the body of the
+		// filesystem create, re-attaching them to the same columns or indexes.
+		if opts.PreserveComments && key.Type == tengo.ObjectTypeTable && s.fsStatement != nil {
+			s.canonicalCreate = fs.ExtractBodyComments(s.filesystemCreate).AttachBodyComments(s.canonicalCreate)
+		}
+
 		if ok, err := fs.CanParse(s.canonicalCreate); ok {
 			statementMap[key] = s
 		} else {
@@ -123,6 +194,53 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 	return statementMap
 }
 
+// newObjectPaths returns a mapping of object keys to file paths, for objects
+// which exist in the live db schema but not yet in the filesystem. If
+// opts.FileNameTemplate is set and places each object in a separate file, an
+// error is returned if the template maps multiple objects to the same file.
+func newObjectPaths(schema *tengo.Schema, dir *fs.Dir, statementMap map[tengo.ObjectKey]statement, opts Options) (map[tengo.ObjectKey]string, error) {
+	paths := make(map[tengo.ObjectKey]string)
+	checkCollisions := opts.FileNameTemplate != "" && fs.FileNameTemplateHasName(opts.FileNameTemplate)
+	seen := make(map[string]tengo.ObjectKey)
+	for key, s := range statementMap {
+		if s.fsStatement != nil || s.canonicalCreate == "" || opts.shouldIgnore(key) {
+			continue
+		}
+		filePath, err := fs.PathForObjectTemplate(dir.Path, opts.FileNameTemplate, schema.Name, key)
+		if err != nil {
+			return nil, err
+		}
+		if checkCollisions {
+			// Compare case-insensitively, since some filesystems are case-insensitive
+			lowerPath := strings.ToLower(filePath)
+			if other, already := seen[lowerPath]; already {
+				return nil, fileNameCollisionError(opts.FileNameTemplate, filePath, other, key)
+			}
+			seen[lowerPath] = key
+		}
+		paths[key] = filePath
+	}
+	return paths, nil
+}
+
+// fileNameCollisionError returns an error describing two new objects which
+// a file name template maps to the same file, along with a suggested fix.
+func fileNameCollisionError(template, filePath string, a, b tengo.ObjectKey) error {
+	if b.String() < a.String() {
+		a, b = b, a
+	}
+	var reason, suggestion string
+	if a.Name != b.Name {
+		reason = ", since special characters and letter case are ignored in file names"
+	}
+	if a.Type != b.Type {
+		suggestion = "To place objects of different types in separate files, include {TYPE} in the template, for example \"{TYPE}.{NAME}.sql\""
+	} else {
+		suggestion = "Rename one of the objects, or use a template which does not include {NAME}, such as \"{SCHEMA}.sql\", to place both in the same file"
+	}
+	return fmt.Errorf("File name template %q maps both %s and %s to %s%s. %s", template, a, b, filePath, reason, suggestion)
+}
+
 // appendToFile appends contents to filePath.
 func appendToFile(filePath, contents string) error {
 	if bytesWritten, wasNew, err := fs.AppendToFile(filePath, contents); err != nil {
@@ -81,6 +81,71 @@ func (s IntegrationSuite) TestFormatSimple(t *testing.T) {
 	s.verifyFormat(t)
 }
 
+func TestNewObjectPaths(t *testing.T) {
+	schema := &tengo.Schema{Name: "product"}
+	dir := &fs.Dir{Path: "/var/schemas/product"}
+	statementMap := map[tengo.ObjectKey]statement{
+		{Type: tengo.ObjectTypeTable, Name: "foo_bar"}: {canonicalCreate: "CREATE TABLE foo_bar (id int)"},
+		{Type: tengo.ObjectTypeTable, Name: "foo-bar"}: {canonicalCreate: "CREATE TABLE `foo-bar` (id int)"},
+		{Type: tengo.ObjectTypeProc, Name: "foo_bar"}:  {canonicalCreate: "CREATE PROCEDURE foo_bar() SELECT 1"},
+		{Type: tengo.ObjectTypeTable, Name: "gone"}:    {filesystemCreate: "CREATE TABLE gone (id int)"},
+	}
+
+	// Default template: all objects with the name foo_bar or foo-bar share a file
+	paths, err := newObjectPaths(schema, dir, statementMap, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error from newObjectPaths: %v", err)
+	} else if len(paths) != 3 {
+		t.Errorf("Expected 3 paths, instead found %d: %v", len(paths), paths)
+	}
+	for key, p := range paths {
+		if p != "/var/schemas/product/foo_bar.sql" && p != "/var/schemas/product/foobar.sql" {
+			t.Errorf("Unexpected path for %s: %s", key, p)
+		}
+	}
+
+	// Templates including {NAME} must not map multiple objects to the same file.
+	// The error should name both objects and suggest a fix.
+	if _, err := newObjectPaths(schema, dir, statementMap, Options{FileNameTemplate: "{NAME}.sql"}); err == nil {
+		t.Error("Expected collision error from newObjectPaths, but err was nil")
+	}
+	delete(statementMap, tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo-bar"})
+	if _, err := newObjectPaths(schema, dir, statementMap, Options{FileNameTemplate: "{NAME}.sql"}); err == nil {
+		t.Error("Expected collision error from newObjectPaths, but err was nil")
+	} else if msg := err.Error(); !strings.Contains(msg, "table `foo_bar`") || !strings.Contains(msg, "procedure `foo_bar`") || !strings.Contains(msg, "include {TYPE}") {
+		t.Errorf("Collision error does not name both objects or suggest {TYPE}: %s", msg)
+	}
+	sameType := map[tengo.ObjectKey]statement{
+		{Type: tengo.ObjectTypeTable, Name: "foo_bar"}: {canonicalCreate: "CREATE TABLE foo_bar (id int)"},
+		{Type: tengo.ObjectTypeTable, Name: "Foo_Bar"}: {canonicalCreate: "CREATE TABLE Foo_Bar (id int)"},
+	}
+	if _, err := newObjectPaths(schema, dir, sameType, Options{FileNameTemplate: "{NAME}.sql"}); err == nil {
+		t.Error("Expected collision error from newObjectPaths, but err was nil")
+	} else if msg := err.Error(); !strings.Contains(msg, "table `Foo_Bar` and table `foo_bar`") || !strings.Contains(msg, "Rename one of the objects") {
+		t.Errorf("Unexpected collision error: %s", msg)
+	}
+	if _, err := newObjectPaths(schema, dir, statementMap, Options{FileNameTemplate: "{TYPE}/{NAME}.sql"}); err == nil {
+		t.Error("Expected invalid template error from newObjectPaths, but err was nil")
+	}
+	paths, err = newObjectPaths(schema, dir, statementMap, Options{FileNameTemplate: "{TYPE}.{NAME}.sql"})
+	if err != nil {
+		t.Fatalf("Unexpected error from newObjectPaths: %v", err)
+	} else if p := paths[tengo.ObjectKey{Type: tengo.ObjectTypeProc, Name: "foo_bar"}]; p != "/var/schemas/product/procedure.foo_bar.sql" {
+		t.Errorf("Unexpected path for procedure: %s", p)
+	}
+
+	// Templates without {NAME} intentionally group objects
+	paths, err = newObjectPaths(schema, dir, statementMap, Options{FileNameTemplate: "{SCHEMA}.sql"})
+	if err != nil {
+		t.Fatalf("Unexpected error from newObjectPaths: %v", err)
+	}
+	for key, p := range paths {
+		if p != "/var/schemas/product/product.sql" {
+			t.Errorf("Unexpected path for %s: %s", key, p)
+		}
+	}
+}
+
 // TestFormatPull tests a use-case closer to `skeema pull`, where in addition
 // to files being reformatted, there are also objects that only exist in the
 // filesystem or only exist in the database.
@@ -123,6 +188,158 @@ func (s IntegrationSuite) TestFormatPull(t *testing.T) {
 	s.verifyFormat(t)
 }
 
+// TestDumpSchemaUnchangedFiles confirms that when only one object changes in
+// the live schema, only that object's file is rewritten; all other files are
+// preserved byte-for-byte and are not written to at all.
+// TestFormatPreserveComments confirms that comments inside the body of a
+// CREATE TABLE survive a format round-trip when opts.PreserveComments is used.
+func (s IntegrationSuite) TestFormatPreserveComments(t *testing.T) {
+	opts := Options{
+		IncludeAutoInc:   true,
+		PreserveComments: true,
+	}
+	opts.IgnoreKeys([]tengo.ObjectKey{s.statementErrors[0].ObjectKey()})
+
+	contents := fs.ReadTestFile(t, s.testdata(".scratch", "posts.sql"))
+	contents = strings.Replace(contents, "  user_id bigint", "  -- author of the post\n  user_id bigint", 1)
+	contents = strings.Replace(contents, "body varchar(150),", "body varchar(150), # may contain emoji", 1)
+	contents = strings.Replace(contents, "DEFAULT CHARSET=latin1;", "DEFAULT CHARSET=latin1 -- blog posts\n;", 1)
+	fs.WriteTestFile(t, s.testdata(".scratch", "posts.sql"), contents)
+	s.reparseScratchDir(t)
+
+	if _, err := DumpSchema(s.schema, s.scratchDir, opts); err != nil {
+		t.Fatalf("Unexpected error from DumpSchema: %v", err)
+	}
+	contents = fs.ReadTestFile(t, s.testdata(".scratch", "posts.sql"))
+	for _, expected := range []string{"  -- author of the post\n  `user_id` bigint", "`body` varchar(150) DEFAULT NULL, # may contain emoji\n", "/* pre comment preserved   */", "  -- blog posts\n) ENGINE=InnoDB DEFAULT CHARSET=latin1;\n"} {
+		if !strings.Contains(contents, expected) {
+			t.Errorf("Expected reformatted posts.sql to contain %q, but it did not. Full contents:\n%s", expected, contents)
+		}
+	}
+
+	// Subsequent run should not need to reformat anything
+	s.reparseScratchDir(t)
+	if count, err := DumpSchema(s.schema, s.scratchDir, opts); count != 0 || err != nil {
+		t.Errorf("Expected DumpSchema() to return (0, nil); instead found (%d, %v)", count, err)
+	}
+
+	// Without opts.PreserveComments, the comments should be stripped
+	opts.PreserveComments = false
+	if count, err := DumpSchema(s.schema, s.scratchDir, opts); count != 1 || err != nil {
+		t.Errorf("Expected DumpSchema() to return (1, nil); instead found (%d, %v)", count, err)
+	}
+	s.verifyFormat(t)
+}
+
+func (s IntegrationSuite) TestDumpSchemaUnchangedFiles(t *testing.T) {
+	opts := Options{
+		IncludeAutoInc: true,
+	}
+	opts.IgnoreKeys([]tengo.ObjectKey{s.statementErrors[0].ObjectKey()})
+	if _, err := DumpSchema(s.schema, s.scratchDir, opts); err != nil {
+		t.Fatalf("Unexpected error from DumpSchema: %s", err)
+	}
+	s.reparseScratchDir(t)
+
+	// Record contents and modification times of all files
+	type fileState struct {
+		contents string
+		modTime  time.Time
+	}
+	before := make(map[string]fileState)
+	for _, sqlFile := range s.scratchDir.SQLFiles {
+		fi, err := os.Stat(sqlFile.Path())
+		if err != nil {
+			t.Fatalf("Unexpected error from Stat: %s", err)
+		}
+		before[sqlFile.Path()] = fileState{
+			contents: fs.ReadTestFile(t, sqlFile.Path()),
+			modTime:  fi.ModTime(),
+		}
+	}
+
+	// Modify one table in the schema, and ensure enough time passes that a
+	// rewrite would be detectable via modification time
+	posts := s.schema.Table("posts")
+	if posts == nil {
+		t.Fatal("Unable to find table posts in test schema")
+	}
+	posts.CreateStatement = fmt.Sprintf("%s COMMENT='hello world'", posts.CreateStatement)
+	time.Sleep(1100 * time.Millisecond)
+	if count, err := DumpSchema(s.schema, s.scratchDir, opts); count != 1 || err != nil {
+		t.Fatalf("Expected DumpSchema to return (1, nil); instead found (%d, %v)", count, err)
+	}
+
+	postsPath := s.testdata(".scratch", "posts.sql")
+	for path, state := range before {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Unexpected error from Stat: %s", err)
+		}
+		contents := fs.ReadTestFile(t, path)
+		if path == postsPath {
+			if contents == state.contents {
+				t.Errorf("Expected %s to be rewritten, but contents are unchanged", path)
+			}
+		} else if contents != state.contents || !fi.ModTime().Equal(state.modTime) {
+			t.Errorf("Expected %s to be untouched, but it was rewritten", path)
+		}
+	}
+}
+
+func (s IntegrationSuite) TestDumpSchemaFileNameTemplate(t *testing.T) {
+	// Remove all files from the scratch dir, so that all objects are new
+	for _, sqlFile := range s.scratchDir.SQLFiles {
+		if err := sqlFile.Delete(); err != nil {
+			t.Fatalf("Unexpected error from Delete: %s", err)
+		}
+	}
+	dir, err := getDir(s.scratchPath())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing scratch dir: %v", err)
+	}
+	opts := Options{
+		FileNameTemplate: "{type}_{NAME}.sql",
+	}
+	opts.IgnoreKeys([]tengo.ObjectKey{s.statementErrors[0].ObjectKey()})
+	count, err := DumpSchema(s.schema, dir, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from DumpSchema: %s", err)
+	}
+	if dir, err = getDir(s.scratchPath()); err != nil {
+		t.Fatalf("Unexpected error parsing scratch dir: %v", err)
+	} else if len(dir.SQLFiles) != count {
+		t.Errorf("Expected %d files to be created, instead found %d", count, len(dir.SQLFiles))
+	}
+	for _, sqlFile := range dir.SQLFiles {
+		if !strings.HasPrefix(sqlFile.FileName, "table_") && !strings.HasPrefix(sqlFile.FileName, "procedure_") && !strings.HasPrefix(sqlFile.FileName, "function_") {
+			t.Errorf("Unexpected file name %s", sqlFile.FileName)
+		}
+	}
+	if _, err := os.Stat(s.testdata(".scratch", "table_posts.sql")); err != nil {
+		t.Errorf("Expected table_posts.sql to exist, but Stat returned %v", err)
+	}
+
+	// A template without {NAME} should group all objects into one file
+	for _, sqlFile := range dir.SQLFiles {
+		if err := sqlFile.Delete(); err != nil {
+			t.Fatalf("Unexpected error from Delete: %s", err)
+		}
+	}
+	if dir, err = getDir(s.scratchPath()); err != nil {
+		t.Fatalf("Unexpected error parsing scratch dir: %v", err)
+	}
+	opts.FileNameTemplate = "{SCHEMA}.sql"
+	if _, err := DumpSchema(s.schema, dir, opts); err != nil {
+		t.Fatalf("Unexpected error from DumpSchema: %s", err)
+	}
+	if dir, err = getDir(s.scratchPath()); err != nil {
+		t.Fatalf("Unexpected error parsing scratch dir: %v", err)
+	} else if len(dir.SQLFiles) != 1 || dir.SQLFiles[0].FileName != s.schema.Name+".sql" {
+		t.Errorf("Expected a single file %s.sql, instead found %v", s.schema.Name, dir.SQLFiles)
+	}
+}
+
 func (s *IntegrationSuite) Setup(backend string) (err error) {
 	s.d, err = s.manager.GetOrCreateInstance(tengo.DockerizedInstanceOptions{
 		Name:         fmt.Sprintf("skeema-test-%s", strings.Replace(backend, ":", "-", -1)),
@@ -0,0 +1,124 @@
+package dumper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/tengo"
+)
+
+// ConvertCollation returns the name of the collation in flavor to which most
+// closely matches the supplied collation from flavor from. The default utf8mb4
+// collation of from is always mapped to the default utf8mb4 collation of to.
+// If exact is false, the returned collation is a substitute with different
+// comparison or sorting behavior, and the caller should warn the user
+// accordingly.
+func ConvertCollation(collation string, from, to tengo.Flavor) (converted string, exact bool) {
+	if collation == from.DefaultUtf8mb4Collation() {
+		return to.DefaultUtf8mb4Collation(), true
+	}
+	charSet := strings.SplitN(collation, "_", 2)[0]
+
+	// MySQL 8.0's UCA 9.0.0 collations don't exist in other flavors
+	if strings.Contains(collation, "_0900_") && (to.Vendor == tengo.VendorMariaDB || !to.MySQLishMinVersion(8, 0)) {
+		if strings.HasSuffix(collation, "_0900_bin") {
+			return charSet + "_bin", true
+		}
+		return charSet + "_unicode_520_ci", false
+	}
+
+	if to.Vendor != tengo.VendorMariaDB {
+		// MariaDB 10.10's UCA 14.0.0 collations don't exist in MySQL
+		if strings.Contains(collation, "_uca1400_") {
+			if charSet == "utf8mb4" && to.MySQLishMinVersion(8, 0) {
+				return "utf8mb4_0900_ai_ci", false
+			}
+			return charSet + "_unicode_520_ci", false
+		}
+		// MariaDB's NO PAD collations don't exist in MySQL
+		if strings.Contains(collation, "nopad") {
+			converted = strings.Replace(collation, "_nopad", "", 1)
+			converted = strings.Replace(converted, "nopad_", "", 1)
+			return converted, false
+		}
+	}
+
+	return collation, true
+}
+
+var (
+	reTableCollation    = regexp.MustCompile(`\n\)(.*) DEFAULT CHARSET=(\w+)(?: COLLATE=(\w+))?`)
+	reColumnCollation   = regexp.MustCompile(` COLLATE (\w+)`)
+	reFunctionalKeyPart = regexp.MustCompile("(?m)^\\s+(?:UNIQUE |FULLTEXT |SPATIAL )?KEY (`(?:[^`]|``)+`) \\((?:\\(|.*,\\()")
+)
+
+// convertCreateFlavor rewrites the supplied CREATE TABLE statement, obtained
+// from a database of flavor from, to use syntax appropriate for flavor to
+// where possible. The rewritten statement is returned, along with a list of
+// human-readable warnings about features that could not be converted exactly.
+func convertCreateFlavor(create string, from, to tengo.Flavor) (string, []string) {
+	var warnings []string
+	warned := make(map[string]bool)
+	convert := func(collation string) string {
+		converted, exact := ConvertCollation(collation, from, to)
+		if !exact && !warned[collation] {
+			warned[collation] = true
+			warnings = append(warnings, fmt.Sprintf("Collation %s does not exist in %s; substituting %s, which may compare or sort differently", collation, to, converted))
+		}
+		return converted
+	}
+
+	// Column-level collations: always explicit when present
+	create = reColumnCollation.ReplaceAllStringFunc(create, func(clause string) string {
+		return " COLLATE " + convert(reColumnCollation.FindStringSubmatch(clause)[1])
+	})
+
+	// Table-level collation: the COLLATE clause is omitted in some flavors when
+	// it is the character set's default, in which case the default of from is
+	// implied. Determine the target collation and whether to display it.
+	if m := reTableCollation.FindStringSubmatchIndex(create); m != nil {
+		charSet := create[m[4]:m[5]]
+		var collation string
+		if m[6] >= 0 {
+			collation = create[m[6]:m[7]]
+		} else if charSet == "utf8mb4" {
+			collation = from.DefaultUtf8mb4Collation()
+		}
+		if collation != "" {
+			collation = convert(collation)
+			clause := fmt.Sprintf("\n)%s DEFAULT CHARSET=%s", create[m[2]:m[3]], charSet)
+			if charSet != "utf8mb4" || collation != to.DefaultUtf8mb4Collation() || to.AlwaysShowTableCollation(charSet) {
+				clause += " COLLATE=" + collation
+			}
+			create = create[:m[0]] + clause + create[m[1]:]
+		}
+	}
+
+	// Functional key parts require MySQL 8.0.13+, and have no direct equivalent
+	// in other flavors
+	if to.Vendor == tengo.VendorMariaDB || !to.MySQLishMinVersion(8, 0, 13) {
+		for _, m := range reFunctionalKeyPart.FindAllStringSubmatch(create, -1) {
+			warnings = append(warnings, fmt.Sprintf("Index %s uses a functional key part, which is not supported in %s; consider indexing a generated column instead", m[1], to))
+		}
+	}
+
+	// CHECK constraints require MySQL 8.0.16+ or MariaDB 10.2+; older versions
+	// parse but discard them. MariaDB always enforces CHECK constraints, and
+	// would reject MySQL's NOT ENFORCED clause.
+	if _, checks := introspect.ParseCreateChecks(create); len(checks) > 0 {
+		if !to.MySQLishMinVersion(8, 0, 16) && !to.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+			warnings = append(warnings, fmt.Sprintf("CHECK constraints are not supported in %s, and will be silently discarded by the server", to))
+		} else if to.Vendor == tengo.VendorMariaDB {
+			for _, check := range checks {
+				if !check.Enforced {
+					create = strings.Replace(create, " CHECK "+check.Expression+" /*!80016 NOT ENFORCED */", " CHECK "+check.Expression, 1)
+					warnings = append(warnings, fmt.Sprintf("CHECK constraint %s is not enforced, but %s always enforces CHECK constraints", tengo.EscapeIdentifier(check.Name), to))
+				}
+			}
+		}
+	}
+
+	return create, warnings
+}
@@ -0,0 +1,142 @@
+package dumper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestConvertCollation(t *testing.T) {
+	mysql57 := tengo.FlavorMySQL57
+	mysql80 := tengo.FlavorMySQL80
+	maria104 := tengo.FlavorMariaDB104
+	maria1010 := tengo.NewFlavor("mariadb:10.10")
+	cases := []struct {
+		Collation string
+		From      tengo.Flavor
+		To        tengo.Flavor
+		Expected  string
+		Exact     bool
+	}{
+		{"utf8mb4_0900_ai_ci", mysql80, maria104, "utf8mb4_general_ci", true},
+		{"utf8mb4_0900_ai_ci", mysql80, mysql57, "utf8mb4_general_ci", true},
+		{"utf8mb4_0900_bin", mysql80, maria104, "utf8mb4_bin", true},
+		{"utf8mb4_0900_as_cs", mysql80, maria104, "utf8mb4_unicode_520_ci", false},
+		{"utf8mb4_de_pb_0900_ai_ci", mysql80, mysql57, "utf8mb4_unicode_520_ci", false},
+		{"utf8mb4_unicode_ci", mysql80, maria104, "utf8mb4_unicode_ci", true},
+		{"latin1_swedish_ci", mysql80, maria104, "latin1_swedish_ci", true},
+		{"utf8mb4_general_ci", maria104, mysql80, "utf8mb4_0900_ai_ci", true},
+		{"utf8mb4_uca1400_ai_ci", maria1010, mysql80, "utf8mb4_0900_ai_ci", false},
+		{"utf8mb4_uca1400_ai_ci", maria1010, mysql57, "utf8mb4_unicode_520_ci", false},
+		{"utf8mb4_uca1400_ai_ci", maria1010, maria104, "utf8mb4_uca1400_ai_ci", true},
+		{"utf8mb4_general_nopad_ci", maria104, mysql80, "utf8mb4_general_ci", false},
+		{"utf8mb4_nopad_bin", maria104, mysql57, "utf8mb4_bin", false},
+		{"utf8mb4_nopad_bin", maria104, maria1010, "utf8mb4_nopad_bin", true},
+	}
+	for _, c := range cases {
+		actual, exact := ConvertCollation(c.Collation, c.From, c.To)
+		if actual != c.Expected || exact != c.Exact {
+			t.Errorf("Expected ConvertCollation(%q, %s, %s) to return %q, %t; instead found %q, %t", c.Collation, c.From, c.To, c.Expected, c.Exact, actual, exact)
+		}
+	}
+}
+
+func TestConvertCreateFlavorCollations(t *testing.T) {
+	// MySQL 8 to MariaDB: default collation maps to default, so the table-level
+	// COLLATE clause is removed; column-level collations are converted with a
+	// single warning each
+	mysqlCreate := "CREATE TABLE `posts` (\n" +
+		"  `id` int unsigned NOT NULL,\n" +
+		"  `title` varchar(100) COLLATE utf8mb4_0900_as_cs NOT NULL,\n" +
+		"  `subtitle` varchar(100) COLLATE utf8mb4_0900_as_cs DEFAULT NULL,\n" +
+		"  `slug` varchar(100) COLLATE utf8mb4_0900_bin NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci"
+	mariaCreate := "CREATE TABLE `posts` (\n" +
+		"  `id` int unsigned NOT NULL,\n" +
+		"  `title` varchar(100) COLLATE utf8mb4_unicode_520_ci NOT NULL,\n" +
+		"  `subtitle` varchar(100) COLLATE utf8mb4_unicode_520_ci DEFAULT NULL,\n" +
+		"  `slug` varchar(100) COLLATE utf8mb4_bin NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	actual, warnings := convertCreateFlavor(mysqlCreate, tengo.FlavorMySQL80, tengo.FlavorMariaDB104)
+	if actual != mariaCreate {
+		t.Errorf("Unexpected result from convertCreateFlavor.\nExpected:\n%s\nActual:\n%s", mariaCreate, actual)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "utf8mb4_0900_as_cs") {
+		t.Errorf("Expected exactly 1 warning about utf8mb4_0900_as_cs, instead found %v", warnings)
+	}
+
+	// MariaDB to MySQL 8: implied default collation must become explicit
+	mariaCreate = strings.Replace(mariaCreate, "utf8mb4_unicode_520_ci", "utf8mb4_general_nopad_ci", -1)
+	expected := strings.Replace(mariaCreate, "utf8mb4_general_nopad_ci", "utf8mb4_general_ci", -1) + " COLLATE=utf8mb4_0900_ai_ci"
+	actual, warnings = convertCreateFlavor(mariaCreate, tengo.FlavorMariaDB104, tengo.FlavorMySQL80)
+	if actual != expected {
+		t.Errorf("Unexpected result from convertCreateFlavor.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "utf8mb4_general_nopad_ci") {
+		t.Errorf("Expected exactly 1 warning about utf8mb4_general_nopad_ci, instead found %v", warnings)
+	}
+
+	// Non-default table collations are retained, and other character sets are
+	// left alone
+	for _, tableOpts := range []string{"DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci", "DEFAULT CHARSET=latin1"} {
+		create := "CREATE TABLE `foo` (\n  `id` int NOT NULL\n) ENGINE=InnoDB " + tableOpts
+		if actual, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, tengo.FlavorMariaDB104); actual != create || len(warnings) > 0 {
+			t.Errorf("Expected statement to be unchanged without warnings, instead found %v:\n%s", warnings, actual)
+		}
+	}
+}
+
+func TestConvertCreateFlavorFunctionalIndex(t *testing.T) {
+	create := "CREATE TABLE `users` (\n" +
+		"  `id` int unsigned NOT NULL,\n" +
+		"  `email` varchar(100) NOT NULL,\n" +
+		"  `name` varchar(100) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_email_lower` ((lower(`email`))),\n" +
+		"  KEY `idx_mixed` (`name`,(left(`email`,3))),\n" +
+		"  KEY `idx_prefix` (`name`(10),`email`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	for _, to := range []tengo.Flavor{tengo.FlavorMariaDB104, tengo.FlavorMySQL57, tengo.NewFlavor("mysql:8.0.12")} {
+		actual, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, to)
+		if actual != create {
+			t.Errorf("Expected functional indexes to be left as-is for %s, instead found:\n%s", to, actual)
+		}
+		if len(warnings) != 2 || !strings.Contains(warnings[0], "idx_email_lower") || !strings.Contains(warnings[1], "idx_mixed") {
+			t.Errorf("Unexpected warnings for %s: %v", to, warnings)
+		}
+	}
+	if _, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, tengo.NewFlavor("mysql:8.0.13")); len(warnings) > 0 {
+		t.Errorf("Expected no warnings for flavor supporting functional indexes, instead found %v", warnings)
+	}
+}
+
+func TestConvertCreateFlavorChecks(t *testing.T) {
+	create := "CREATE TABLE `widgets` (\n" +
+		"  `qty` int NOT NULL,\n" +
+		"  CONSTRAINT `positive` CHECK ((`qty` > 0)),\n" +
+		"  CONSTRAINT `small` CHECK ((`qty` < 100)) /*!80016 NOT ENFORCED */\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	if actual, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, tengo.NewFlavor("mysql:8.0.16")); actual != create || len(warnings) > 0 {
+		t.Errorf("Expected statement to be unchanged without warnings, instead found %v:\n%s", warnings, actual)
+	}
+
+	// MariaDB lacks NOT ENFORCED
+	expected := strings.Replace(create, " /*!80016 NOT ENFORCED */", "", 1)
+	actual, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, tengo.FlavorMariaDB104)
+	if actual != expected {
+		t.Errorf("Unexpected result from convertCreateFlavor.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "`small`") {
+		t.Errorf("Expected exactly 1 warning about `small`, instead found %v", warnings)
+	}
+
+	// Older flavors discard CHECK constraints entirely
+	for _, to := range []tengo.Flavor{tengo.FlavorMySQL57, tengo.NewFlavor("mysql:8.0.15"), tengo.FlavorMariaDB101} {
+		if actual, warnings := convertCreateFlavor(create, tengo.FlavorMySQL80, to); actual != create || len(warnings) != 1 || !strings.Contains(warnings[0], "discarded") {
+			t.Errorf("Unexpected result for %s: %v\n%s", to, warnings, actual)
+		}
+	}
+}
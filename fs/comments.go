@@ -0,0 +1,230 @@
+package fs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BodyComments represents SQL comments found inside the body of a CREATE TABLE
+// statement, associated positionally with the lines of the statement that they
+// precede or trail. Each line is identified by an anchor, which is derived from
+// the start of the line: the CREATE line itself, a column name, an index or
+// constraint name, or the closing parenthesis line.
+type BodyComments struct {
+	leading  map[string][]string // full-line comments preceding an anchored line
+	trailing map[string]string   // comment following the code on an anchored line
+}
+
+// Empty returns true if no comments were found.
+func (bc *BodyComments) Empty() bool {
+	return bc == nil || (len(bc.leading) == 0 && len(bc.trailing) == 0)
+}
+
+// commentedLine represents one logical line of a CREATE statement, with its
+// code and comments separated.
+type commentedLine struct {
+	code     string
+	comments []string
+}
+
+// ExtractBodyComments scans the supplied CREATE TABLE statement text (without
+// a trailing delimiter) and returns any comments found inside it. Text inside
+// quoted strings and identifiers is never treated as a comment, and neither
+// are version-gated executable comments of the form /*!NNNNN ... */. Comments
+// which precede or trail a line lacking a recognized anchor are discarded.
+func ExtractBodyComments(create string) *BodyComments {
+	bc := &BodyComments{
+		leading:  make(map[string][]string),
+		trailing: make(map[string]string),
+	}
+	var pending []string
+	for _, line := range splitCommentedLines(create) {
+		code := strings.TrimSpace(line.code)
+		if code == "" {
+			pending = append(pending, line.comments...)
+			continue
+		}
+		anchor := lineAnchor(code)
+		if anchor == "" {
+			pending = nil
+			continue
+		}
+		if len(pending) > 0 {
+			bc.leading[anchor] = pending
+			pending = nil
+		}
+		if len(line.comments) > 0 {
+			bc.trailing[anchor] = strings.Join(line.comments, " ")
+		}
+	}
+	return bc
+}
+
+// AttachBodyComments returns a copy of the supplied CREATE TABLE statement text
+// (typically in canonical SHOW CREATE TABLE format) with bc's comments
+// re-inserted adjacent to the lines having matching anchors. Comments whose
+// anchors no longer exist in create are discarded. If multiple lines have the
+// same anchor, only the first one receives comments.
+//
+// Callers typically append a delimiter to the result, so a trailing comment
+// ending in a single-line comment is never placed on the final line of the
+// statement, since it would comment out the delimiter. Instead it is placed on
+// its own line, immediately preceding the final line.
+func (bc *BodyComments) AttachBodyComments(create string) string {
+	if bc.Empty() {
+		return create
+	}
+	lines := strings.Split(create, "\n")
+	result := make([]string, 0, len(lines)+1)
+	seen := make(map[string]bool, len(lines))
+	for n, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		anchor := lineAnchor(trimmed)
+		if anchor == "" || seen[anchor] {
+			result = append(result, line)
+			continue
+		}
+		seen[anchor] = true
+		indent := line[:len(line)-len(trimmed)]
+		if anchor == "end" && indent == "" {
+			// Comments preceding the closing paren were inside the column list, so
+			// indent them like the column definitions
+			indent = "  "
+		}
+		for _, comment := range bc.leading[anchor] {
+			result = append(result, indent+comment)
+		}
+		if comment, ok := bc.trailing[anchor]; ok && n == len(lines)-1 && endsWithLineComment(comment) {
+			result = append(result, indent+comment)
+		} else if ok {
+			line += " " + comment
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// endsWithLineComment returns true if the supplied comment text, which must not
+// contain any code, ends with a single-line comment beginning with # or --.
+func endsWithLineComment(comment string) bool {
+	comments := splitCommentedLines(comment)[0].comments
+	if len(comments) == 0 {
+		return false
+	}
+	last := comments[len(comments)-1]
+	return strings.HasPrefix(last, "#") || strings.HasPrefix(last, "--")
+}
+
+// splitCommentedLines splits text into lines, separating comments from code on
+// each line. A block comment spanning multiple lines is attributed to the line
+// on which it starts.
+func splitCommentedLines(text string) []commentedLine {
+	var lines []commentedLine
+	var cur commentedLine
+	var code strings.Builder
+	flush := func() {
+		cur.code = code.String()
+		lines = append(lines, cur)
+		cur = commentedLine{}
+		code.Reset()
+	}
+	for pos := 0; pos < len(text); {
+		c := text[pos]
+		switch {
+		case c == '\n':
+			flush()
+			pos++
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(text, pos)
+			code.WriteString(text[pos:end])
+			pos = end
+		case c == '#' || (c == '-' && strings.HasPrefix(text[pos:], "--") && (pos+2 == len(text) || isSpaceByte(text[pos+2]))):
+			end := strings.IndexByte(text[pos:], '\n')
+			if end == -1 {
+				end = len(text)
+			} else {
+				end += pos
+			}
+			cur.comments = append(cur.comments, strings.TrimRight(text[pos:end], " \t\r"))
+			pos = end
+		case c == '/' && strings.HasPrefix(text[pos:], "/*") && !strings.HasPrefix(text[pos:], "/*!"):
+			end := strings.Index(text[pos+2:], "*/")
+			if end == -1 {
+				end = len(text)
+			} else {
+				end += pos + 4
+			}
+			cur.comments = append(cur.comments, text[pos:end])
+			pos = end
+		default:
+			code.WriteByte(c)
+			pos++
+		}
+	}
+	flush()
+	return lines
+}
+
+// quotedEnd returns the position just past the end of the quoted string or
+// identifier beginning at text[start]. Backslash escapes are honored in
+// strings, but not in backtick-quoted identifiers. Doubled quote characters
+// are handled implicitly, as two adjacent quoted sections.
+func quotedEnd(text string, start int) int {
+	quote := text[start]
+	for pos := start + 1; pos < len(text); pos++ {
+		if text[pos] == '\\' && quote != '`' {
+			pos++
+		} else if text[pos] == quote {
+			return pos + 1
+		}
+	}
+	return len(text)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// identPattern matches an identifier, either backtick-quoted or bare
+const identPattern = "(`(?:[^`]|``)+`|[\\w$]+)"
+
+var (
+	reAnchorCreate     = regexp.MustCompile(`(?i)^create\s`)
+	reAnchorPrimary    = regexp.MustCompile(`(?i)^primary\s+key\b`)
+	reAnchorKey        = regexp.MustCompile(`(?i)^(?:(?:unique|fulltext|spatial)\s+)?(?:key|index)\s+` + identPattern)
+	reAnchorConstraint = regexp.MustCompile(`(?i)^constraint\s+` + identPattern)
+	reAnchorColumn     = regexp.MustCompile(`^` + identPattern)
+)
+
+// lineAnchor returns a string identifying the supplied line of a CREATE TABLE
+// statement, which should already have leading whitespace removed. An empty
+// string is returned if the line is not recognized.
+func lineAnchor(line string) string {
+	if strings.HasPrefix(line, ")") {
+		return "end"
+	} else if reAnchorCreate.MatchString(line) {
+		return "create"
+	} else if reAnchorPrimary.MatchString(line) {
+		return "primary"
+	} else if m := reAnchorKey.FindStringSubmatch(line); m != nil {
+		return "key:" + normalizeAnchorIdent(m[1])
+	} else if m := reAnchorConstraint.FindStringSubmatch(line); m != nil {
+		return "constraint:" + normalizeAnchorIdent(m[1])
+	} else if m := reAnchorColumn.FindStringSubmatch(line); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "key", "index", "unique", "fulltext", "spatial", "foreign", "check", "constraint", "primary", "partition", "subpartition":
+			return ""
+		}
+		return "column:" + normalizeAnchorIdent(m[1])
+	}
+	return ""
+}
+
+// normalizeAnchorIdent strips quoting from an identifier, and lowercases it
+// since column and index names are case-insensitive.
+func normalizeAnchorIdent(ident string) string {
+	if len(ident) > 1 && ident[0] == '`' {
+		ident = strings.Replace(ident[1:len(ident)-1], "``", "`", -1)
+	}
+	return strings.ToLower(ident)
+}
@@ -0,0 +1,176 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBodyCommentsRoundTrip(t *testing.T) {
+	// Hand-written statement, in non-canonical format, with a variety of comments
+	fsCreate := "create table posts ( -- blog posts\n" +
+		"  # Surrogate key\n" +
+		"  id int unsigned not null,\n" +
+		"  -- Author of the post;\n" +
+		"  -- FK to users.id\n" +
+		"  `author_id` int unsigned,\n" +
+		"  title varchar(100) default '-- not a comment', /* inline block */\n" +
+		"  body text, # note: can be huge\n" +
+		"  /* multi-line block\n" +
+		"     comment */\n" +
+		"  primary key (id),\n" +
+		"  KEY author (`author_id`) -- for user page\n" +
+		"  -- trailing comment inside parens\n" +
+		") /*!50100 partition-like executable comment */ DEFAULT CHARSET=latin1"
+	canonical := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  `author_id` int(10) unsigned DEFAULT NULL,\n" +
+		"  `title` varchar(100) DEFAULT '-- not a comment',\n" +
+		"  `body` text,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `author` (`author_id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	expected := "CREATE TABLE `posts` ( -- blog posts\n" +
+		"  # Surrogate key\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  -- Author of the post;\n" +
+		"  -- FK to users.id\n" +
+		"  `author_id` int(10) unsigned DEFAULT NULL,\n" +
+		"  `title` varchar(100) DEFAULT '-- not a comment', /* inline block */\n" +
+		"  `body` text, # note: can be huge\n" +
+		"  /* multi-line block\n" +
+		"     comment */\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `author` (`author_id`) -- for user page\n" +
+		"  -- trailing comment inside parens\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+
+	bc := ExtractBodyComments(fsCreate)
+	if bc.Empty() {
+		t.Fatal("Expected comments to be found, but none were")
+	}
+	actual := bc.AttachBodyComments(canonical)
+	if actual != expected {
+		t.Errorf("Unexpected result from AttachBodyComments.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if ok, err := CanParse(actual); !ok {
+		t.Errorf("Statement with re-attached comments unexpectedly cannot be parsed: %v", err)
+	}
+
+	// Round-trip: extracting from the output and re-attaching to the same
+	// canonical statement should yield the same result
+	if again := ExtractBodyComments(actual).AttachBodyComments(canonical); again != actual {
+		t.Errorf("Re-attaching comments is not idempotent.\nExpected:\n%s\nActual:\n%s", actual, again)
+	}
+
+	// Comments for a column which no longer exists should be discarded
+	withoutAuthor := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	expected = "CREATE TABLE `posts` ( -- blog posts\n" +
+		"  # Surrogate key\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  /* multi-line block\n" +
+		"     comment */\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		"  -- trailing comment inside parens\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	if actual := bc.AttachBodyComments(withoutAuthor); actual != expected {
+		t.Errorf("Unexpected result from AttachBodyComments.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	// Statements without comments should be unaffected
+	bc = ExtractBodyComments(canonical)
+	if !bc.Empty() {
+		t.Errorf("Expected no comments to be found, instead found %+v", *bc)
+	}
+	if actual := bc.AttachBodyComments(withoutAuthor); actual != withoutAuthor {
+		t.Errorf("Expected statement to be unchanged, instead found:\n%s", actual)
+	}
+}
+
+func TestBodyCommentsFinalLine(t *testing.T) {
+	canonical := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  `body` text\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+
+	// A single-line comment trailing the final line must be moved to its own
+	// line, so that it does not swallow a subsequently-added delimiter
+	fsCreate := "create table posts (\n  id int unsigned not null,\n  body text\n) ENGINE=InnoDB -- blog posts"
+	expected := "CREATE TABLE `posts` (\n" +
+		"  `id` int(10) unsigned NOT NULL,\n" +
+		"  `body` text\n" +
+		"  -- blog posts\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	actual := ExtractBodyComments(fsCreate).AttachBodyComments(canonical)
+	if actual != expected {
+		t.Errorf("Unexpected result from AttachBodyComments.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if again := ExtractBodyComments(actual).AttachBodyComments(canonical); again != actual {
+		t.Errorf("Re-attaching comments is not idempotent.\nExpected:\n%s\nActual:\n%s", actual, again)
+	}
+
+	// Once a delimiter is added, the file must still tokenize into the expected
+	// statements, with line numbers (and therefore linter annotation locations)
+	// matching the rewritten text
+	contents := AddDelimiter(actual) + "CREATE TABLE users (id int);\n"
+	stmts, err := ParseStatementsFromReader(strings.NewReader(contents), "posts.sql")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsFromReader: %v", err)
+	}
+	var creates []*Statement
+	for _, stmt := range stmts {
+		if stmt.Type == StatementTypeCreate {
+			creates = append(creates, stmt)
+		}
+	}
+	if len(creates) != 2 {
+		t.Fatalf("Expected 2 CREATE statements, instead found %d", len(creates))
+	}
+	if creates[0].ObjectName != "posts" || creates[0].Body() != actual {
+		t.Errorf("Unexpected first statement: %q", creates[0].Text)
+	}
+	if creates[1].ObjectName != "users" || creates[1].LineNo != 6 {
+		t.Errorf("Expected second statement to be users at line 6, instead found %s at line %d", creates[1].ObjectName, creates[1].LineNo)
+	}
+	if offset := strings.Count(creates[0].Text[:strings.Index(creates[0].Text, "`body`")], "\n"); creates[0].LineNo+offset != 3 {
+		t.Errorf("Expected body column at line 3, instead found line %d", creates[0].LineNo+offset)
+	}
+
+	// Block comments may remain on the final line, as may single-line comments on
+	// a closing line which is followed by other lines
+	fsCreate = "create table posts (\n  id int unsigned not null,\n  body text\n) ENGINE=InnoDB /* blog posts */"
+	expected = strings.Replace(canonical, "latin1", "latin1 /* blog posts */", 1)
+	if actual := ExtractBodyComments(fsCreate).AttachBodyComments(canonical); actual != expected {
+		t.Errorf("Unexpected result from AttachBodyComments.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	partitioned := canonical + "\n/*!50100 PARTITION BY HASH (`id`) PARTITIONS 4 */"
+	fsCreate = "create table posts (\n  id int unsigned not null,\n  body text\n) ENGINE=InnoDB -- blog posts\nPARTITION BY HASH (id) PARTITIONS 4"
+	expected = strings.Replace(partitioned, "latin1\n", "latin1 -- blog posts\n", 1)
+	if actual := ExtractBodyComments(fsCreate).AttachBodyComments(partitioned); actual != expected {
+		t.Errorf("Unexpected result from AttachBodyComments.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
+
+func TestLineAnchor(t *testing.T) {
+	cases := map[string]string{
+		"CREATE TABLE `foo` (":                          "create",
+		"`id` int NOT NULL,":                            "column:id",
+		"ID int NOT NULL,":                              "column:id",
+		"`we``ird` int,":                                "column:we`ird",
+		"PRIMARY KEY (`id`),":                           "primary",
+		"UNIQUE KEY `Name` (`name`),":                   "key:name",
+		"index idx_foo (foo)":                           "key:idx_foo",
+		"CONSTRAINT `fk1` FOREIGN KEY (`a`) REFERENCES": "constraint:fk1",
+		"FOREIGN KEY (`a`) REFERENCES `b` (`a`)":        "",
+		") ENGINE=InnoDB":                               "end",
+		"PARTITION p1 VALUES LESS THAN (10)":            "",
+		"(PARTITION p0 VALUES LESS THAN (5)":            "",
+	}
+	for input, expected := range cases {
+		if actual := lineAnchor(input); actual != expected {
+			t.Errorf("Expected lineAnchor(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
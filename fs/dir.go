@@ -42,6 +42,7 @@ type LogicalSchema struct {
 	Collation string
 	Creates   map[tengo.ObjectKey]*Statement
 	Alters    []*Statement // Alterations that are run after the Creates
+	Inserts   []*Statement // Rows of tables listed in the manage-data option
 }
 
 // AddStatement adds the supplied statement into the appropriate data structure
@@ -65,6 +66,9 @@ func (logicalSchema *LogicalSchema) AddStatement(stmt *Statement) error {
 	case StatementTypeAlter:
 		logicalSchema.Alters = append(logicalSchema.Alters, stmt)
 		return nil
+	case StatementTypeInsert:
+		logicalSchema.Inserts = append(logicalSchema.Inserts, stmt)
+		return nil
 	default:
 		return nil
 	}
@@ -102,6 +106,50 @@ func ParseDir(dirPath string, globalConfig *mybase.Config) (*Dir, error) {
 	return dir, dir.ParseError
 }
 
+// ExpandDirPatterns converts a list of directory paths and/or glob patterns
+// into a list of directory paths. Glob patterns use the syntax of
+// filepath.Match, and are expanded without relying on the shell, so that they
+// behave the same way on every platform. Only directories are returned from
+// glob matches, excluding hidden ones unless the pattern explicitly begins
+// with a dot. Duplicate paths are removed. An error is returned if a pattern
+// is malformed or does not match any directories. Plain paths are returned
+// as-is, without checking whether they exist.
+func ExpandDirPatterns(patterns []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(dirPath string) {
+		if cleaned := filepath.Clean(dirPath); !seen[cleaned] {
+			seen[cleaned] = true
+			result = append(result, cleaned)
+		}
+	}
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			add(pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid directory pattern %q: %s", pattern, err)
+		}
+		allowHidden := strings.HasPrefix(filepath.Base(pattern), ".")
+		var matchCount int
+		for _, match := range matches {
+			if strings.HasPrefix(filepath.Base(match), ".") && !allowHidden {
+				continue
+			}
+			if fi, err := os.Stat(match); err == nil && fi.IsDir() {
+				add(match)
+				matchCount++
+			}
+		}
+		if matchCount == 0 {
+			return nil, fmt.Errorf("Directory pattern %q does not match any directories", pattern)
+		}
+	}
+	return result, nil
+}
+
 func (dir *Dir) String() string {
 	return dir.Path
 }
@@ -258,12 +306,24 @@ func (dir *Dir) Hostnames() ([]string, error) {
 // directory's configuration. The Instances will NOT be checked for
 // connectivity. However, if the configuration is invalid (for example, illegal
 // hostname or invalid connect-options), an error will be returned instead of
-// any instances.
+// any instances. If the connect-schema option is set, the Instances establish
+// each connection using it as the default database, and then switch to the
+// requested schema.
 func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 	hosts, err := dir.Hostnames()
 	if err != nil {
 		return nil, err
-	} else if len(hosts) == 0 {
+	}
+	return dir.InstancesForHosts(hosts)
+}
+
+// InstancesForHosts is like Instances, but uses the supplied hostnames instead
+// of the dir's host and host-wrapper options. Each hostname may optionally
+// include a port, in the form host:port. All other connection-related options,
+// such as user, password, and connect-options, are obtained from the dir's
+// configuration.
+func (dir *Dir) InstancesForHosts(hosts []string) ([]*tengo.Instance, error) {
+	if len(hosts) == 0 {
 		// If no host defined in this dir (meaning this dir's .skeema, as well as
 		// parent dirs' .skeema, global option files, or command-line) then nothing
 		// to do
@@ -272,16 +332,62 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 
 	// Before looping over hostnames, do a single lookup of user, password,
 	// connect-options, port, socket.
+	user, err := util.ExpandedOption(dir.Config, "user")
+	if err != nil {
+		return nil, err
+	}
 	var userAndPass string
 	if !dir.Config.Changed("password") {
-		userAndPass = dir.Config.Get("user")
+		userAndPass = user
 	} else {
-		userAndPass = fmt.Sprintf("%s:%s", dir.Config.Get("user"), dir.Config.Get("password"))
+		password, err := util.ExpandedOption(dir.Config, "password")
+		if err != nil {
+			return nil, err
+		}
+		userAndPass = fmt.Sprintf("%s:%s", user, password)
 	}
 	params, err := dir.InstanceDefaultParams()
 	if err != nil {
 		return nil, fmt.Errorf("Invalid connection options: %s", err)
 	}
+	if connectSchema := dir.Config.Get("connect-schema"); connectSchema != "" {
+		params = fmt.Sprintf("%s&%s=%s", params, util.ConnectSchemaParam, url.QueryEscape(connectSchema))
+	}
+	if tlsParam, err := util.TLSParamForConfig(dir.Config); err != nil {
+		return nil, fmt.Errorf("Invalid TLS options: %s", err)
+	} else if tlsParam != "" {
+		if values, _ := url.ParseQuery(params); values.Get("tls") != "" {
+			return nil, fmt.Errorf("connect-options is not allowed to contain tls when ssl-mode, ssl-ca, ssl-cert, or ssl-key are set")
+		}
+		params = fmt.Sprintf("%s&tls=%s", params, url.QueryEscape(tlsParam))
+	}
+	if dir.Config.GetBool("enable-cleartext-plugin") {
+		params += "&allowCleartextPasswords=true"
+	}
+	useToken := (dir.Config.Get("token-provider") != "")
+	usePasswordCommand := (dir.Config.Get("password-command") != "")
+	if usePasswordCommand && useToken {
+		return nil, fmt.Errorf("Options password-command and token-provider cannot be used together")
+	}
+	if dir.Config.Get("vault-role") != "" {
+		if useToken {
+			return nil, fmt.Errorf("Options vault-role and token-provider cannot be used together")
+		} else if usePasswordCommand {
+			return nil, fmt.Errorf("Options vault-role and password-command cannot be used together")
+		}
+		// The user and password are obtained from Vault upon each new connection,
+		// so omit them from the DSN entirely
+		name, err := util.VaultCredentialsForConfig(dir.Config)
+		if err != nil {
+			return nil, err
+		}
+		params = fmt.Sprintf("%s&%s=%s", params, util.CredentialsParam, url.QueryEscape(name))
+		userAndPass = ""
+	} else if useToken || usePasswordCommand {
+		// The password is obtained from token-provider or password-command
+		// separately for each host, upon connecting
+		userAndPass = ""
+	}
 	portValue := dir.Config.GetIntOrDefault("port")
 	portWasSupplied := dir.Config.Supplied("port")
 	portIsntDefault := dir.Config.Changed("port")
@@ -293,8 +399,14 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 	for _, host := range hosts {
 		var dsn string
 		thisPortValue := portValue
+		thisParams := params
 		if host == "localhost" && (socketWasSupplied || !portWasSupplied) {
-			dsn = fmt.Sprintf("%s@unix(%s)/?%s", userAndPass, socketValue, params)
+			if useToken || usePasswordCommand {
+				if thisParams, err = tokenParams(dir, params, host, thisPortValue); err != nil {
+					return nil, err
+				}
+			}
+			dsn = fmt.Sprintf("%s@unix(%s)/?%s", userAndPass, socketValue, thisParams)
 		} else {
 			splitHost, splitPort, err := tengo.SplitHostOptionalPort(host)
 			if err != nil {
@@ -307,12 +419,17 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 				host = splitHost
 				thisPortValue = splitPort
 			}
-			dsn = fmt.Sprintf("%s@tcp(%s:%d)/?%s", userAndPass, host, thisPortValue, params)
+			if useToken || usePasswordCommand {
+				if thisParams, err = tokenParams(dir, params, host, thisPortValue); err != nil {
+					return nil, err
+				}
+			}
+			dsn = fmt.Sprintf("%s@tcp(%s:%d)/?%s", userAndPass, host, thisPortValue, thisParams)
 		}
 		instance, err := util.NewInstance("mysql", dsn)
 		if err != nil {
-			if dir.Config.Changed("password") {
-				safeUserPass := fmt.Sprintf("%s:*****", dir.Config.Get("user"))
+			if userAndPass != "" && dir.Config.Changed("password") {
+				safeUserPass := fmt.Sprintf("%s:*****", user)
 				dsn = strings.Replace(dsn, userAndPass, safeUserPass, 1)
 			}
 			return nil, fmt.Errorf("Invalid connection information for %s (DSN=%s): %s", dir, dsn, err)
@@ -322,6 +439,23 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 	return instances, nil
 }
 
+// tokenParams returns params with the addition of a credentials param, which
+// obtains the password for connecting to host and port from the dir's
+// password-command or token-provider option.
+func tokenParams(dir *Dir, params, host string, port int) (string, error) {
+	var name string
+	var err error
+	if dir.Config.Get("password-command") != "" {
+		name, err = util.PasswordCommandCredentialsForConfig(dir.Config, host, port)
+	} else {
+		name, err = util.TokenCredentialsForConfig(dir.Config, host, port)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s&%s=%s", params, util.CredentialsParam, url.QueryEscape(name)), nil
+}
+
 // FirstInstance returns at most one tengo.Instance based on the directory's
 // configuration. If the config maps to multiple instances, only the first will
 // be returned. If the config maps to no instances, nil will be returned. The
@@ -351,7 +485,10 @@ func (dir *Dir) FirstInstance() (*tengo.Instance, error) {
 // or more schema names that the statements in dir's *.sql files will be applied
 // to, in cases where no schema name is explicitly specified in SQL statements.
 // If the ignore-schema option is set, it will filter out matching results from
-// the returned slice.
+// the returned slice. Explicitly-listed schema names are mapped through the
+// schema-alias option, if set; names obtained from the instance itself, via
+// "*", a regex, or a shellout, are not. The workspace temp-schema is never
+// returned.
 // An instance must be supplied since the value may be instance-specific.
 func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error) {
 	// If no schema defined in this dir (meaning this dir's .skeema, as well as
@@ -364,11 +501,19 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 	schemaValue := dir.Config.Get("schema")                        // Get strips quotes (including backticks) from fully quoted-wrapped values
 	rawSchemaValue := dir.Config.GetRaw("schema")                  // GetRaw does not strip quotes
 	if rawSchemaValue != schemaValue && rawSchemaValue[0] == '`' { // no need to check len, the Changed check above already tells us schema != ""
+		user, err := util.ExpandedOption(dir.Config, "user")
+		if err != nil {
+			return nil, err
+		}
+		password, err := util.PasswordForConfig(dir.Config, instance.Host, instance.Port)
+		if err != nil {
+			return nil, err
+		}
 		variables := map[string]string{
 			"HOST":        instance.Host,
 			"PORT":        strconv.Itoa(instance.Port),
-			"USER":        dir.Config.Get("user"),
-			"PASSWORD":    dir.Config.Get("password"),
+			"USER":        user,
+			"PASSWORD":    password,
 			"ENVIRONMENT": dir.Config.Get("environment"),
 			"DIRNAME":     dir.BaseName(),
 			"DIRPATH":     dir.Path,
@@ -406,6 +551,11 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 		}
 	} else {
 		names = dir.Config.GetSlice("schema", ',', true)
+		for n, name := range names {
+			if names[n], err = dir.AliasedSchemaName(name); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Remove ignored schemas and system schemas. (tengo removes the latter from
@@ -421,10 +571,13 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 		"sys":                true,
 		"mysql":              true,
 	}
+	tempSchema := dir.Config.Get("temp-schema")
 	keepNames := make([]string, 0, len(names))
 	for _, name := range names {
 		if ignoreSchema != nil && ignoreSchema.MatchString(name) {
 			log.Debugf("Skipping schema %s because ignore-schema='%s'", name, ignoreSchema)
+		} else if name == tempSchema {
+			log.Debugf("Skipping schema %s because it is the workspace temp-schema", name)
 		} else if !systemSchemas[name] {
 			keepNames = append(keepNames, name)
 		}
@@ -432,6 +585,46 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 	return keepNames, nil
 }
 
+// SchemaAliases returns the parsed value of the dir's schema-alias option: a
+// map of schema names, as specified by the schema option or by explicit schema
+// names in *.sql files, to the names actually used on database instances. This
+// permits one directory to map to differently-named schemas in each
+// environment. If schema-alias is not set, a nil map is returned.
+func (dir *Dir) SchemaAliases() (map[string]string, error) {
+	pairs := dir.Config.GetSlice("schema-alias", ',', true)
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	tempSchema := dir.Config.Get("temp-schema")
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) < 2 || strings.TrimSpace(tokens[0]) == "" || strings.TrimSpace(tokens[1]) == "" {
+			return nil, fmt.Errorf("Invalid value for schema-alias in %s: %q is not in NAME=ALIAS format", dir, pair)
+		}
+		name, alias := strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1])
+		if alias == tempSchema {
+			return nil, fmt.Errorf("Invalid value for schema-alias in %s: schema %s cannot be mapped to the workspace temp-schema %s", dir, name, alias)
+		}
+		aliases[name] = alias
+	}
+	return aliases, nil
+}
+
+// AliasedSchemaName returns the name to use on database instances for the
+// schema called name in this dir's configuration or *.sql files. This is name
+// itself, unless the schema-alias option maps it to a different name.
+func (dir *Dir) AliasedSchemaName(name string) (string, error) {
+	aliases, err := dir.SchemaAliases()
+	if err != nil {
+		return "", err
+	}
+	if alias, ok := aliases[name]; ok {
+		return alias, nil
+	}
+	return name, nil
+}
+
 func looksLikeRegex(input string) bool {
 	return len(input) > 2 && input[0] == '/' && input[len(input)-1] == '/'
 }
@@ -458,11 +651,98 @@ func (dir *Dir) HasSchema() bool {
 	return false
 }
 
+// DefaultTableOptions returns the parsed value of the dir's
+// default-table-options option, or nil if not set.
+func (dir *Dir) DefaultTableOptions() (TableOptions, error) {
+	opts, err := ParseTableOptions(dir.Config.Get("default-table-options"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value for default-table-options in %s: %s", dir, err)
+	}
+	return opts, nil
+}
+
+var reTemplateVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TemplateVars returns the variables used for substituting template
+// placeholders in the dir's *.sql files. Variables are read from the file
+// specified by template-vars-file, if any, followed by the NAME=VALUE pairs in
+// template-vars, which take precedence. A relative template-vars-file path is
+// interpreted relative to the directory of the option file that set it. If
+// neither option is set, a nil map is returned, meaning that *.sql files are
+// used as-is, without any substitution.
+func (dir *Dir) TemplateVars() (map[string]string, error) {
+	if dir.Config.Get("template-vars-file") == "" && dir.Config.Get("template-vars") == "" {
+		return nil, nil
+	}
+	vars := make(map[string]string)
+	if filePath := dir.Config.Get("template-vars-file"); filePath != "" {
+		if !filepath.IsAbs(filePath) {
+			baseDir := dir.Path
+			if f, ok := dir.Config.Source("template-vars-file").(*mybase.File); ok {
+				baseDir = f.Dir
+			}
+			filePath = filepath.Join(baseDir, filePath)
+		}
+		contents, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read template-vars-file for %s: %s", dir, err)
+		}
+		for n, line := range strings.Split(string(contents), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || line[0] == '#' {
+				continue
+			}
+			if err := addTemplateVar(vars, line); err != nil {
+				return nil, fmt.Errorf("Invalid line %d in %s: %s", n+1, filePath, err)
+			}
+		}
+	}
+	for _, pair := range dir.Config.GetSlice("template-vars", ',', true) {
+		if err := addTemplateVar(vars, pair); err != nil {
+			return nil, fmt.Errorf("Invalid value for template-vars in %s: %s", dir, err)
+		}
+	}
+	return vars, nil
+}
+
+// addTemplateVar parses a NAME=VALUE pair and adds it to vars.
+func addTemplateVar(vars map[string]string, pair string) error {
+	tokens := strings.SplitN(pair, "=", 2)
+	name := strings.TrimSpace(tokens[0])
+	if len(tokens) < 2 {
+		return fmt.Errorf("%q is not in NAME=VALUE format", pair)
+	} else if !reTemplateVarName.MatchString(name) {
+		return fmt.Errorf("%q is not a valid variable name", name)
+	}
+	vars[name] = strings.TrimSpace(tokens[1])
+	return nil
+}
+
+// ManagedDataTables returns a set of names of tables whose rows are managed by
+// INSERT statements in the dir's *.sql files, as configured by the manage-data
+// option. If the option is not set, nil is returned.
+func (dir *Dir) ManagedDataTables() map[string]bool {
+	names := dir.Config.GetSlice("manage-data", ',', true)
+	if len(names) == 0 {
+		return nil
+	}
+	tables := make(map[string]bool, len(names))
+	for _, name := range names {
+		tables[strings.Trim(name, "`")] = true
+	}
+	return tables
+}
+
 // InstanceDefaultParams returns a param string for use in constructing a
 // DSN. Any overrides specified in the config for this dir will be taken into
 // account. The returned string will already be in the correct format (HTTP
-// query string). An error will be returned if the configuration tried
-// manipulating params that should not be user-specified.
+// query string). Any ${NAME} environment variable placeholders in
+// connect-options are expanded first. An error will be returned if the
+// configuration tried manipulating params that should not be user-specified,
+// or referenced an environment variable that is not set. If the time-zone
+// option is set, the session time_zone is set accordingly, so that TIMESTAMP
+// values are converted consistently regardless of each server's system time
+// zone.
 func (dir *Dir) InstanceDefaultParams() (string, error) {
 	banned := map[string]bool{
 		// go-sql-driver/mysql special params that should not be overridden
@@ -476,6 +756,9 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 		"parsetime":         true,
 		"strict":            true,
 
+		// handled by Dir.Instances via the connect-schema option
+		"skeemaconnectschema": true,
+
 		// mysql session options that should not be overridden
 		"autocommit":                      true, // always enabled by default in MySQL
 		"foreign_key_checks":              true, // always disabled explicitly later in this method
@@ -484,7 +767,16 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 		"sql_quote_show_create":           true, // always enabled later in this method
 	}
 
-	options, err := util.SplitConnectOptions(dir.Config.Get("connect-options"))
+	timeZone := dir.Config.Get("time-zone")
+	if strings.ContainsAny(timeZone, `'"\`) {
+		return "", fmt.Errorf("Option time-zone may not contain quote characters or backslashes: %s", timeZone)
+	}
+
+	connectOpts, err := util.ExpandEnvVars(dir.Config.Get("connect-options"))
+	if err != nil {
+		return "", err
+	}
+	options, err := util.SplitConnectOptions(connectOpts)
 	if err != nil {
 		return "", err
 	}
@@ -507,6 +799,11 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 		if strings.ToLower(name) == "sql_mode" && strings.Contains(strings.ToLower(value), "ansi") {
 			return "", fmt.Errorf("Skeema does not support use of the ANSI_QUOTES sql_mode")
 		}
+		// Special case: time-zone and connect-options may not both set time_zone,
+		// since it would be ambiguous which one should take precedence
+		if timeZone != "" && strings.ToLower(name) == "time_zone" {
+			return "", fmt.Errorf("connect-options is not allowed to contain %s when the time-zone option is also set", name)
+		}
 
 		v.Set(name, value)
 	}
@@ -516,6 +813,9 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 	v.Set("foreign_key_checks", "0")
 	v.Set("default_storage_engine", "'InnoDB'")
 	v.Set("sql_quote_show_create", "1")
+	if timeZone != "" {
+		v.Set("time_zone", "'"+timeZone+"'")
+	}
 
 	flavorFromConfig := tengo.NewFlavor(dir.Config.Get("flavor"))
 	if flavorFromConfig.HasDataDictionary() {
@@ -544,15 +844,36 @@ func (dir *Dir) parseContents() {
 	if dir.SQLFiles, dir.ParseError = sqlFiles(dir.Path, dir.repoBase); dir.ParseError != nil {
 		return
 	}
+	var vars map[string]string
+	if vars, dir.ParseError = dir.TemplateVars(); dir.ParseError != nil {
+		return
+	}
+	managedData := dir.ManagedDataTables()
 	logicalSchemasByName := make(map[string]*LogicalSchema)
 	for _, sf := range dir.SQLFiles {
-		tokenizedFile, err := sf.Tokenize()
+		var tokenizedFile *TokenizedSQLFile
+		var err error
+		if vars != nil {
+			tokenizedFile, err = sf.TokenizeTemplate(vars)
+			if _, ok := err.(TemplateError); ok {
+				dir.ParseError = err
+				return
+			}
+		} else {
+			tokenizedFile, err = sf.Tokenize()
+		}
 		if err != nil {
 			log.Warnf(err.Error())
 			dir.IgnoredStatements = append(dir.IgnoredStatements, tokenizedFile.Statements...)
 			continue
 		}
 		for _, stmt := range tokenizedFile.Statements {
+			// INSERTs are only supported for tables listed in manage-data, and are
+			// otherwise ignored just like any other unsupported statement
+			if stmt.Type == StatementTypeInsert && !managedData[stmt.ObjectName] {
+				dir.IgnoredStatements = append(dir.IgnoredStatements, stmt)
+				continue
+			}
 			if _, ok := logicalSchemasByName[stmt.Schema()]; !ok {
 				logicalSchemasByName[stmt.Schema()] = &LogicalSchema{
 					Creates: make(map[tengo.ObjectKey]*Statement),
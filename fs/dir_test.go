@@ -168,6 +168,45 @@ func TestDirSubdirs(t *testing.T) {
 	}
 }
 
+func TestExpandDirPatterns(t *testing.T) {
+	golden := filepath.Join("..", "testdata", "golden", "init", "mydb")
+	analytics, product := filepath.Join(golden, "analytics"), filepath.Join(golden, "product")
+
+	// Globs only match dirs, and duplicates are removed; plain paths are left as-is
+	patterns := []string{filepath.Join(golden, "*"), product, "does-not-exist", filepath.Join(golden, "prod*/")}
+	expected := []string{analytics, product, "does-not-exist"}
+	if actual, err := ExpandDirPatterns(patterns); err != nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from ExpandDirPatterns: %v, %v", actual, err)
+	}
+
+	// Patterns matching only files, or only hidden entries, match nothing; so do
+	// patterns matching nothing at all
+	nonMatching := []string{
+		filepath.Join(golden, "*", "*.sql"),
+		filepath.Join(golden, "*skeema"),
+		filepath.Join("testdata", "nope*"),
+		"[",
+	}
+	for _, pattern := range nonMatching {
+		if actual, err := ExpandDirPatterns([]string{".", pattern}); err == nil {
+			t.Errorf("Expected ExpandDirPatterns to return an error for pattern %q, but it did not; result %v", pattern, actual)
+		}
+	}
+
+	// Hidden dirs are matched if the pattern explicitly begins with a dot
+	WriteTestFile(t, "testdata/.scratch/.hidden/.skeema", "")
+	WriteTestFile(t, "testdata/.scratch/visible/.skeema", "")
+	defer RemoveTestDirectory(t, "testdata/.scratch")
+	expected = []string{filepath.Join("testdata", ".scratch", "visible")}
+	if actual, err := ExpandDirPatterns([]string{filepath.Join("testdata", ".scratch", "*")}); err != nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from ExpandDirPatterns: %v, %v", actual, err)
+	}
+	expected = []string{filepath.Join("testdata", ".scratch", ".hidden")}
+	if actual, err := ExpandDirPatterns([]string{filepath.Join("testdata", ".scratch", ".h*")}); err != nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from ExpandDirPatterns: %v, %v", actual, err)
+	}
+}
+
 func TestDirInstances(t *testing.T) {
 	assertInstances := func(optionValues map[string]string, expectError bool, expectedInstances ...string) []*tengo.Instance {
 		cmd := mybase.NewCommand("test", "1.0", "this is for testing", nil)
@@ -217,8 +256,69 @@ func TestDirInstances(t *testing.T) {
 	assertInstances(map[string]string{"host": `"some.db.host, other.db.host"`, "port": "3307"}, false, "some.db.host:3307", "other.db.host:3307")
 	assertInstances(map[string]string{"host": "'some.db.host:3308', 'other.db.host'"}, false, "some.db.host:3308", "other.db.host:3306")
 
+	// connect-schema switches the instance's driver; routing of connections is
+	// tested separately in package util
+	for _, inst := range assertInstances(map[string]string{"host": "some.db.host", "connect-schema": "gateway"}, false, "some.db.host:3306") {
+		if inst.Driver != util.ConnectSchemaDriverName {
+			t.Errorf("Expected instance with connect-schema to use driver %s, instead found %s", util.ConnectSchemaDriverName, inst.Driver)
+		}
+	}
+
+	// vault-role switches the instance's driver, and omits the user and password
+	// from the DSN; obtaining credentials is tested separately in package util
+	vaultOpts := map[string]string{"host": "some.db.host", "user": "ignored", "password": "ignored", "vault-role": "app", "vault-address": "https://vault.example.com", "vault-token": "s.token"}
+	for _, inst := range assertInstances(vaultOpts, false, "some.db.host:3306") {
+		if inst.Driver != util.CredentialsDriverName || inst.User != "" || inst.Password != "" {
+			t.Errorf("Expected instance with vault-role to use driver %s without user or password, instead found %s with user %q", util.CredentialsDriverName, inst.Driver, inst.User)
+		}
+	}
+
+	// token-provider likewise switches the instance's driver, and omits the
+	// password from the DSN
+	tokenOpts := map[string]string{"host": "some.db.host,other.db.host", "password": "ignored", "token-provider": "/bin/echo {HOST}-token"}
+	for _, inst := range assertInstances(tokenOpts, false, "some.db.host:3306", "other.db.host:3306") {
+		if inst.Driver != util.CredentialsDriverName || inst.Password != "" {
+			t.Errorf("Expected instance with token-provider to use driver %s without password, instead found %s", util.CredentialsDriverName, inst.Driver)
+		}
+	}
+
+	// password-command does the same, but its command is only run once per host
+	passwordCommandOpts := map[string]string{"host": "some.db.host", "password": "ignored", "password-command": "/bin/echo {HOST}-password"}
+	for _, inst := range assertInstances(passwordCommandOpts, false, "some.db.host:3306") {
+		if inst.Driver != util.CredentialsDriverName || inst.Password != "" {
+			t.Errorf("Expected instance with password-command to use driver %s without password, instead found %s", util.CredentialsDriverName, inst.Driver)
+		}
+	}
+
+	// user and password may reference environment variables
+	os.Setenv("SKEEMA_TEST_USER", "app")
+	os.Setenv("SKEEMA_TEST_PASSWORD", "s3cret")
+	defer func() {
+		os.Unsetenv("SKEEMA_TEST_USER")
+		os.Unsetenv("SKEEMA_TEST_PASSWORD")
+	}()
+	envOpts := map[string]string{"host": "some.db.host", "user": "${SKEEMA_TEST_USER}", "password": "${SKEEMA_TEST_PASSWORD}"}
+	for _, inst := range assertInstances(envOpts, false, "some.db.host:3306") {
+		if inst.User != "app" || inst.Password != "s3cret" {
+			t.Errorf("Expected environment variables to be expanded in user and password, instead found user %q", inst.User)
+		}
+	}
+
+	// TLS and cleartext options are permitted with any host; their effect on
+	// connections is tested separately in package util
+	assertInstances(map[string]string{"host": "some.db.host", "ssl-mode": "required", "enable-cleartext-plugin": "1"}, false, "some.db.host:3306")
+
 	// invalid option values or combinations
+	assertInstances(map[string]string{"host": "some.db.host", "ssl-mode": "required", "connect-options": "tls=true"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "ssl-mode": "bogus"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "token-provider": "/bin/echo {BOGUS}"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "vault-role": "app", "vault-address": "https://vault.example.com", "vault-token": "s.token", "token-provider": "/bin/echo token"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "password-command": "/bin/echo pw", "token-provider": "/bin/echo token"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "vault-role": "app", "vault-address": "https://vault.example.com", "vault-token": "s.token", "password-command": "/bin/echo pw"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "password": "${SKEEMA_TEST_UNDEFINED}"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "user": "${SKEEMA_TEST_UNDEFINED}"}, true)
 	assertInstances(map[string]string{"host": "some.db.host", "connect-options": ","}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "connect-options": "skeemaConnectSchema=gateway"}, true)
 	assertInstances(map[string]string{"host": "some.db.host:3306", "port": "3307"}, true)
 	assertInstances(map[string]string{"host": "@@@@@"}, true)
 	assertInstances(map[string]string{"host-wrapper": "`echo {INVALID_VAR}`", "host": "irrelevant"}, true)
@@ -236,7 +336,7 @@ func TestDirInstanceDefaultParams(t *testing.T) {
 	getDir := func(connectOptions, flavor string) *Dir {
 		return &Dir{
 			Path:   "/tmp/dummydir",
-			Config: mybase.SimpleConfig(map[string]string{"connect-options": connectOptions, "flavor": flavor}),
+			Config: mybase.SimpleConfig(map[string]string{"connect-options": connectOptions, "flavor": flavor, "time-zone": ""}),
 		}
 	}
 
@@ -272,7 +372,13 @@ func TestDirInstanceDefaultParams(t *testing.T) {
 	baseDefaults += "&information_schema_stats_expiry=0"
 	assertDefaultParams("", "mysql:8.0", baseDefaults)
 
+	// Test environment variable expansion
+	os.Setenv("SKEEMA_TEST_WAIT", "60")
+	defer os.Unsetenv("SKEEMA_TEST_WAIT")
+	assertDefaultParams("lock_wait_timeout=${SKEEMA_TEST_WAIT}", "mysql:8.0", baseDefaults+"&lock_wait_timeout=60")
+
 	expectError := []string{
+		"lock_wait_timeout=${SKEEMA_TEST_UNDEFINED}",
 		"totally_benign=1,allowAllFiles=true",
 		"FOREIGN_key_CHECKS='on'",
 		"bad_parse",
@@ -285,16 +391,186 @@ func TestDirInstanceDefaultParams(t *testing.T) {
 			t.Errorf("Did not get expected error from connect-options=\"%s\"", connOpts)
 		}
 	}
+
+	// Test time-zone, which is permitted in connect-options only if time-zone is
+	// not also set
+	assertDefaultParams("time_zone='+05:00'", "mysql:8.0", baseDefaults+"&time_zone=%27%2B05%3A00%27")
+	dir := &Dir{
+		Path:   "/tmp/dummydir",
+		Config: mybase.SimpleConfig(map[string]string{"connect-options": "", "flavor": "", "time-zone": "+00:00"}),
+	}
+	expected, _ := url.ParseQuery(strings.Replace(baseDefaults, "&information_schema_stats_expiry=0", "", 1) + "&time_zone=%27%2B00%3A00%27")
+	if actual, err := dir.InstanceDefaultParams(); err != nil || actual != expected.Encode() {
+		t.Errorf("Unexpected result from InstanceDefaultParams with time-zone: %s, %v", actual, err)
+	}
+	for _, connOpts := range []string{"time_zone='+05:00'", "TIME_ZONE='SYSTEM'"} {
+		dir.Config = mybase.SimpleConfig(map[string]string{"connect-options": connOpts, "flavor": "", "time-zone": "+00:00"})
+		if _, err := dir.InstanceDefaultParams(); err == nil {
+			t.Errorf("Did not get expected error from connect-options=\"%s\" combined with time-zone", connOpts)
+		}
+	}
+	for _, timeZone := range []string{"+00'00", `UTC\`} {
+		dir.Config = mybase.SimpleConfig(map[string]string{"connect-options": "", "flavor": "", "time-zone": timeZone})
+		if _, err := dir.InstanceDefaultParams(); err == nil {
+			t.Errorf("Did not get expected error from time-zone=%s", timeZone)
+		}
+	}
+}
+
+func TestDirTemplateVars(t *testing.T) {
+	os.RemoveAll("testdata/.scratch")
+	defer os.RemoveAll("testdata/.scratch")
+	if err := os.MkdirAll("testdata/.scratch/tenant", 0777); err != nil {
+		t.Fatalf("Unable to create scratch dir: %s", err)
+	}
+	WriteTestFile(t, "testdata/.scratch/tenant/.skeema", "schema=app\ntemplate-vars-file=../tenant.vars\n")
+	WriteTestFile(t, "testdata/.scratch/tenant.vars", "# comment\nTenantPrefix = acme\n\nRegion=us-east\n")
+	WriteTestFile(t, "testdata/.scratch/tenant/users.sql", "CREATE TABLE {{.TenantPrefix}}_users (\n  id int, region varchar(20) DEFAULT '{{.Region}}'\n);\n")
+
+	dir := getDir(t, "testdata/.scratch/tenant")
+	expected := map[string]string{"TenantPrefix": "acme", "Region": "us-east"}
+	if vars, err := dir.TemplateVars(); err != nil || !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Unexpected return from TemplateVars: %v / %v", vars, err)
+	}
+	key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "acme_users"}
+	if len(dir.LogicalSchemas) != 1 || dir.LogicalSchemas[0].Creates[key] == nil {
+		t.Fatalf("Expected template placeholders to be substituted before parsing, but did not find %s", key)
+	}
+	stmt := dir.LogicalSchemas[0].Creates[key]
+	if !strings.Contains(stmt.Text, "DEFAULT 'us-east'") || !stmt.FromFile.Templated {
+		t.Errorf("Unexpected statement after substitution: %+v", *stmt)
+	}
+	if _, err := stmt.FromFile.Rewrite(); err == nil {
+		t.Error("Expected Rewrite of templated file to return an error, but it did not")
+	}
+
+	// template-vars takes precedence over template-vars-file
+	cfg := getValidConfig(t)
+	cfg.AddSource(mybase.SimpleSource(map[string]string{"template-vars": "TenantPrefix=globex"}))
+	dir, err := ParseDir("testdata/.scratch/tenant", cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %s", err)
+	}
+	key.Name = "globex_users"
+	if dir.LogicalSchemas[0].Creates[key] == nil {
+		t.Errorf("Expected to find %s, but did not", key)
+	}
+
+	// Referencing a missing variable, or supplying malformed vars, is an error
+	WriteTestFile(t, "testdata/.scratch/tenant/posts.sql", "CREATE TABLE {{.TenantPrefx}}_posts (id int);\n")
+	if _, err := ParseDir("testdata/.scratch/tenant", getValidConfig(t)); err == nil || !strings.Contains(err.Error(), "TenantPrefx") {
+		t.Errorf("Expected error about missing variable, instead found %v", err)
+	}
+	os.Remove("testdata/.scratch/tenant/posts.sql")
+	for _, value := range []string{"TenantPrefix", "Tenant-Prefix=x", "=x"} {
+		cfg := getValidConfig(t)
+		cfg.AddSource(mybase.SimpleSource(map[string]string{"template-vars": value}))
+		if _, err := ParseDir("testdata/.scratch/tenant", cfg); err == nil {
+			t.Errorf("Expected error from template-vars=%q, but err was nil", value)
+		}
+	}
+
+	// Without either option set, files are used as-is
+	WriteTestFile(t, "testdata/.scratch/tenant/.skeema", "schema=app\n")
+	dir = getDir(t, "testdata/.scratch/tenant")
+	if vars, err := dir.TemplateVars(); vars != nil || err != nil {
+		t.Errorf("Unexpected return from TemplateVars: %v / %v", vars, err)
+	}
+	if dir.LogicalSchemas[0].Creates[key] != nil {
+		t.Error("Expected no substitution without template-vars or template-vars-file")
+	}
+}
+
+func TestDirSchemaAliases(t *testing.T) {
+	os.RemoveAll("testdata/.scratch")
+	defer os.RemoveAll("testdata/.scratch")
+	if err := os.MkdirAll("testdata/.scratch/app", 0777); err != nil {
+		t.Fatalf("Unable to create scratch dir: %s", err)
+	}
+
+	// Aliases only apply in the environment section that defines them
+	WriteTestFile(t, "testdata/.scratch/app/.skeema", "schema=app,app_logs\n[staging]\nschema-alias=app=app_other\n[production]\nschema-alias=app=app_prod, other = other_prod\n")
+	dir := getDir(t, "testdata/.scratch/app")
+	if names, err := dir.SchemaNames(nil); err != nil || !reflect.DeepEqual(names, []string{"app_prod", "app_logs"}) {
+		t.Errorf("Unexpected return from SchemaNames: %v / %v", names, err)
+	}
+	for name, expected := range map[string]string{"other": "other_prod", "app_logs": "app_logs"} {
+		if actual, err := dir.AliasedSchemaName(name); err != nil || actual != expected {
+			t.Errorf("Unexpected return from AliasedSchemaName(%q): %q / %v", name, actual, err)
+		}
+	}
+
+	// Without the option, there are no aliases; the temp-schema is never
+	// returned by SchemaNames
+	WriteTestFile(t, "testdata/.scratch/app/.skeema", "schema=app,_skeema_tmp\n")
+	dir = getDir(t, "testdata/.scratch/app")
+	if aliases, err := dir.SchemaAliases(); aliases != nil || err != nil {
+		t.Errorf("Unexpected return from SchemaAliases: %v / %v", aliases, err)
+	}
+	if names, err := dir.SchemaNames(nil); err != nil || !reflect.DeepEqual(names, []string{"app"}) {
+		t.Errorf("Unexpected return from SchemaNames: %v / %v", names, err)
+	}
+
+	// Malformed values, or aliasing a schema to the temp-schema, are errors
+	for _, value := range []string{"app", "app=", "=app", "app=_skeema_tmp"} {
+		WriteTestFile(t, "testdata/.scratch/app/.skeema", "schema=app\nschema-alias="+value+"\n")
+		dir = getDir(t, "testdata/.scratch/app")
+		if _, err := dir.SchemaNames(nil); err == nil {
+			t.Errorf("Expected error from schema-alias=%q, but err was nil", value)
+		}
+	}
+}
+
+func TestDirManagedData(t *testing.T) {
+	os.RemoveAll("testdata/.scratch")
+	defer os.RemoveAll("testdata/.scratch")
+	if err := os.MkdirAll("testdata/.scratch/app", 0777); err != nil {
+		t.Fatalf("Unable to create scratch dir: %s", err)
+	}
+	WriteTestFile(t, "testdata/.scratch/app/.skeema", "schema=app\nmanage-data=statuses, `colors`\n")
+	WriteTestFile(t, "testdata/.scratch/app/statuses.sql", "CREATE TABLE statuses (id int PRIMARY KEY, name varchar(20));\n")
+	WriteTestFile(t, "testdata/.scratch/app/statuses.data.sql", "INSERT INTO statuses VALUES (1, 'active'), (2, 'closed');\nINSERT IGNORE INTO `statuses` (id, name) VALUES (3, 'pending');\nINSERT INTO posts VALUES (1);\n")
+
+	dir := getDir(t, "testdata/.scratch/app")
+	expected := map[string]bool{"statuses": true, "colors": true}
+	if tables := dir.ManagedDataTables(); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Unexpected return from ManagedDataTables: %v", tables)
+	}
+	inserts := dir.LogicalSchemas[0].Inserts
+	if len(inserts) != 2 {
+		t.Fatalf("Expected 2 INSERTs, instead found %d", len(inserts))
+	}
+	for _, stmt := range inserts {
+		if stmt.Type != StatementTypeInsert || stmt.ObjectType != tengo.ObjectTypeTable || stmt.ObjectName != "statuses" {
+			t.Errorf("Unexpected INSERT statement: %+v", *stmt)
+		}
+	}
+	if len(dir.IgnoredStatements) != 1 || dir.IgnoredStatements[0].ObjectName != "posts" {
+		t.Errorf("Expected INSERT for table not in manage-data to be ignored, instead found IgnoredStatements %v", dir.IgnoredStatements)
+	}
+
+	// Without manage-data, all INSERTs are ignored
+	WriteTestFile(t, "testdata/.scratch/app/.skeema", "schema=app\n")
+	dir = getDir(t, "testdata/.scratch/app")
+	if dir.ManagedDataTables() != nil || len(dir.LogicalSchemas[0].Inserts) > 0 || len(dir.IgnoredStatements) != 3 {
+		t.Errorf("Expected all INSERTs to be ignored without manage-data, instead found %d inserts and %d ignored", len(dir.LogicalSchemas[0].Inserts), len(dir.IgnoredStatements))
+	}
 }
 
 func getValidConfig(t *testing.T) *mybase.Config {
 	cmd := mybase.NewCommand("fstest", "", "", nil)
 	cmd.AddOption(mybase.StringOption("schema", 0, "", "Database schema name").Hidden())
+	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex").Hidden())
+	cmd.AddOption(mybase.StringOption("schema-alias", 0, "", "Comma-separated NAME=ALIAS pairs mapping schema names to the names used on database instances").Hidden())
+	cmd.AddOption(mybase.StringOption("temp-schema", 't', "_skeema_tmp", "Name of temporary schema for intermediate operations, created and dropped each run"))
 	cmd.AddOption(mybase.StringOption("default-character-set", 0, "", "Schema-level default character set").Hidden())
 	cmd.AddOption(mybase.StringOption("default-collation", 0, "", "Schema-level default collation").Hidden())
 	cmd.AddOption(mybase.StringOption("host", 0, "", "Database hostname or IP address").Hidden())
 	cmd.AddOption(mybase.StringOption("port", 0, "3306", "Port to use for database host").Hidden())
 	cmd.AddOption(mybase.StringOption("flavor", 0, "", "Database server expressed in format vendor:major.minor, for use in vendor/version specific syntax").Hidden())
+	cmd.AddOption(mybase.StringOption("template-vars", 0, "", "Comma-separated NAME=VALUE pairs to substitute for {{.NAME}} placeholders in *.sql files"))
+	cmd.AddOption(mybase.StringOption("template-vars-file", 0, "", "Path to file of NAME=VALUE lines to substitute for {{.NAME}} placeholders in *.sql files"))
+	cmd.AddOption(mybase.StringOption("manage-data", 0, "", "Comma-separated names of tables whose rows are managed by INSERT statements in *.sql files"))
 	cmd.AddArg("environment", "production", false)
 	return mybase.ParseFakeCLI(t, cmd, "fstest")
 }
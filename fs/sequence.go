@@ -0,0 +1,26 @@
+package fs
+
+import (
+	"regexp"
+)
+
+// reSequenceCall matches the start of a call to a MariaDB sequence function,
+// with a schema-qualified sequence name. This is the form that MariaDB uses in
+// SHOW CREATE TABLE for column defaults such as DEFAULT NEXTVAL(seq1).
+var reSequenceCall = regexp.MustCompile("(?i)\\b(nextval|lastval|setval)\\((`(?:[^`]|``)+`)\\.`")
+
+// UnqualifySequenceCalls returns a copy of statement with the schema name
+// removed from all sequence function calls referring to sequences in
+// schemaName. For example, with schemaName "product", a default of
+// nextval(`product`.`seq1`) becomes nextval(`seq1`). Calls referring to
+// sequences in other schemas are left as-is. This permits statements to be
+// compared across schemas with different names, such as a workspace.
+func UnqualifySequenceCalls(statement, schemaName string) string {
+	return reSequenceCall.ReplaceAllStringFunc(statement, func(match string) string {
+		groups := reSequenceCall.FindStringSubmatch(match)
+		if stripBackticks(groups[2]) != schemaName {
+			return match
+		}
+		return groups[1] + "(`"
+	})
+}
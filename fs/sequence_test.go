@@ -0,0 +1,20 @@
+package fs
+
+import (
+	"testing"
+)
+
+func TestUnqualifySequenceCalls(t *testing.T) {
+	cases := map[string]string{
+		"`id` int(11) NOT NULL DEFAULT nextval(`product`.`seq1`),":            "`id` int(11) NOT NULL DEFAULT nextval(`seq1`),",
+		"`id` int(11) NOT NULL DEFAULT NEXTVAL(`product`.`seq1`),":            "`id` int(11) NOT NULL DEFAULT NEXTVAL(`seq1`),",
+		"DEFAULT lastval(`product`.`a``b`), DEFAULT setval(`product`.`c`, 5)": "DEFAULT lastval(`a``b`), DEFAULT setval(`c`, 5)",
+		"`id` int(11) NOT NULL DEFAULT nextval(`other`.`seq1`),":              "`id` int(11) NOT NULL DEFAULT nextval(`other`.`seq1`),",
+		"`id` int(11) NOT NULL DEFAULT nextval(`seq1`),":                      "`id` int(11) NOT NULL DEFAULT nextval(`seq1`),",
+	}
+	for input, expected := range cases {
+		if actual := UnqualifySequenceCalls(input, "product"); actual != expected {
+			t.Errorf("Unexpected result from UnqualifySequenceCalls(%q): %q", input, actual)
+		}
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"text/template"
 	"unicode"
+
+	"github.com/skeema/tengo"
 )
 
 // SQLFile represents a file containing zero or more SQL statements.
@@ -21,6 +24,7 @@ type SQLFile struct {
 type TokenizedSQLFile struct {
 	SQLFile
 	Statements []*Statement
+	Templated  bool // true if template placeholders in the file were substituted
 }
 
 // Path returns the full absolute path to a SQLFile.
@@ -65,8 +69,44 @@ func (sf SQLFile) Delete() error {
 // whitespace, since any comments and/or whitespace between SQL statements gets
 // split into separate Statement values.
 func (sf SQLFile) Tokenize() (*TokenizedSQLFile, error) {
+	contents, err := ioutil.ReadFile(sf.Path())
+	if err != nil {
+		return NewTokenizedSQLFile(sf, nil), err
+	}
+	return sf.tokenize(string(contents))
+}
+
+// TokenizeTemplate behaves like Tokenize, but first substitutes any template
+// placeholders in the file, such as {{.TenantPrefix}}, using the supplied vars.
+// Placeholders use the syntax of the text/template package. If substitution
+// fails, for example due to a placeholder referencing a variable missing from
+// vars, a TemplateError is returned and the file is not tokenized.
+func (sf SQLFile) TokenizeTemplate(vars map[string]string) (*TokenizedSQLFile, error) {
+	raw, err := ioutil.ReadFile(sf.Path())
+	if err != nil {
+		return NewTokenizedSQLFile(sf, nil), err
+	}
+	contents := string(raw)
+	if !strings.Contains(contents, "{{") {
+		return sf.tokenize(contents)
+	}
+	tmpl, err := template.New(sf.FileName).Option("missingkey=error").Parse(contents)
+	if err != nil {
+		return nil, TemplateError{File: sf.Path(), Err: err}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return nil, TemplateError{File: sf.Path(), Err: err}
+	}
+	tokenizedFile, err := sf.tokenize(b.String())
+	tokenizedFile.Templated = true
+	return tokenizedFile, err
+}
+
+// tokenize splits the supplied file contents into statements.
+func (sf SQLFile) tokenize(contents string) (*TokenizedSQLFile, error) {
 	tokenizer := newStatementTokenizer(sf.Path(), ";")
-	statements, err := tokenizer.statements()
+	statements, err := tokenizer.statementsFromReader(strings.NewReader(contents))
 
 	// As a special case, if a file contains a single routine but no DELIMITER
 	// command, re-parse it as a single statement. This avoids user error from
@@ -83,7 +123,9 @@ func (sf SQLFile) Tokenize() (*TokenizedSQLFile, error) {
 			} else {
 				tryReparse = false
 			}
-		case StatementTypeUnknown:
+		case StatementTypeUnknown, StatementTypeInsert:
+			// Statements within a routine body, such as INSERTs, are split out
+			// separately if the file lacks a DELIMITER command
 			if seenRoutine {
 				unknownAfterRoutine = true
 			}
@@ -96,7 +138,7 @@ func (sf SQLFile) Tokenize() (*TokenizedSQLFile, error) {
 	}
 	if seenRoutine && unknownAfterRoutine && tryReparse {
 		tokenizer := newStatementTokenizer(sf.Path(), "\000")
-		if statements2, err2 := tokenizer.statements(); err2 == nil {
+		if statements2, err2 := tokenizer.statementsFromReader(strings.NewReader(contents)); err2 == nil {
 			statements = statements2
 			err = nil
 		}
@@ -119,6 +161,18 @@ func (sf SQLFile) WriteStatements(statements []*Statement) (int, error) {
 	return len(value), nil
 }
 
+// TemplateError is returned by SQLFile.TokenizeTemplate if the file's template
+// placeholders cannot be substituted.
+type TemplateError struct {
+	File string
+	Err  error
+}
+
+// Error satisfies the builtin error interface.
+func (te TemplateError) Error() string {
+	return fmt.Sprintf("Unable to substitute template placeholders in %s: %s", te.File, te.Err)
+}
+
 // NewTokenizedSQLFile creates a TokenizedSQLFile whose statements have a
 // FromFile pointer linking back to the TokenizedSQLFile. This permits easy
 // mutation of the statements and rewriting of the file.
@@ -137,7 +191,13 @@ func NewTokenizedSQLFile(sf SQLFile, statements []*Statement) *TokenizedSQLFile
 // number of bytes written. If the file's statements now only consist of
 // comments, whitespace, and commands (e.g. USE, DELIMITER) then the file will
 // be deleted instead, and a length of 0 will be returned.
+//
+// An error is returned if the file's contents were produced by substituting
+// template placeholders, since rewriting it would lose the placeholders.
 func (tsf *TokenizedSQLFile) Rewrite() (int, error) {
+	if tsf.Templated {
+		return 0, fmt.Errorf("Cannot rewrite %s: file contains template placeholders, which would be lost", tsf.SQLFile)
+	}
 	var keepFile bool
 	for _, stmt := range tsf.Statements {
 		if stmt.Type != StatementTypeNoop && stmt.Type != StatementTypeCommand {
@@ -163,6 +223,80 @@ func PathForObject(dirPath, objectName string) string {
 	return path.Join(dirPath, fmt.Sprintf("%s.sql", objectName))
 }
 
+var reFileNameTemplateVar = regexp.MustCompile(`{([^{}]*)}`)
+
+// ValidateFileNameTemplate returns an error if the supplied template cannot be
+// used with PathForObjectTemplate. Templates must end in ".sql", may not
+// contain path separators, and may only reference the variables {NAME},
+// {TYPE}, and {SCHEMA}. An empty template is always valid.
+func ValidateFileNameTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("File name template %q may not contain path separators", template)
+	}
+	if !strings.HasSuffix(strings.ToLower(template), ".sql") {
+		return fmt.Errorf("File name template %q must end in .sql", template)
+	}
+	for _, match := range reFileNameTemplateVar.FindAllStringSubmatch(template, -1) {
+		switch strings.ToUpper(match[1]) {
+		case "NAME", "TYPE", "SCHEMA":
+		default:
+			return fmt.Errorf("File name template %q references unknown variable %s", template, match[0])
+		}
+	}
+	return nil
+}
+
+// FileNameTemplateHasName returns true if the supplied template references
+// the {NAME} variable, meaning that each object is intended to be placed in a
+// file of its own. An empty template is treated as the default of
+// "{NAME}.sql".
+func FileNameTemplateHasName(template string) bool {
+	if template == "" {
+		return true
+	}
+	for _, match := range reFileNameTemplateVar.FindAllStringSubmatch(template, -1) {
+		if strings.ToUpper(match[1]) == "NAME" {
+			return true
+		}
+	}
+	return false
+}
+
+// PathForObjectTemplate returns a string containing a path to use for the
+// SQLFile representing the supplied object, with the file name generated from
+// template. Variables in the template are case-insensitive: {NAME} is replaced
+// with the object name, {TYPE} with the object type (e.g. "table"), and
+// {SCHEMA} with schemaName. Special characters in values are removed, in the
+// same manner as PathForObject. An empty template is equivalent to calling
+// PathForObject.
+func PathForObjectTemplate(dirPath, template, schemaName string, key tengo.ObjectKey) (string, error) {
+	if template == "" {
+		return PathForObject(dirPath, key.Name), nil
+	}
+	if err := ValidateFileNameTemplate(template); err != nil {
+		return "", err
+	}
+	fileName := reFileNameTemplateVar.ReplaceAllStringFunc(template, func(variable string) string {
+		var value string
+		switch strings.ToUpper(variable[1 : len(variable)-1]) {
+		case "NAME":
+			value = key.Name
+		case "TYPE":
+			value = string(key.Type)
+		case "SCHEMA":
+			value = schemaName
+		}
+		return strings.Map(removeSpecialChars, value)
+	})
+	if len(fileName) == len(".sql") {
+		fileName = "symbols.sql"
+	}
+	return path.Join(dirPath, fileName), nil
+}
+
 func removeSpecialChars(r rune) rune {
 	if unicode.IsSpace(r) {
 		return -1
@@ -209,11 +343,31 @@ var reIsMultiStatement = regexp.MustCompile(`(?is)begin.*;.*end`)
 
 // AddDelimiter takes the supplied string and appends a delimiter to the end.
 // If the supplied string is a multi-statement routine, delimiter commands will
-// be prepended and appended to the string appropriately.
+// be prepended and appended to the string appropriately, using a delimiter
+// which does not occur anywhere in the routine; see routineDelimiter.
 // TODO devise a way to avoid using special delimiter for single-routine files
 func AddDelimiter(stmt string) string {
 	if reIsMultiStatement.MatchString(stmt) {
-		return fmt.Sprintf("DELIMITER //\n%s//\nDELIMITER ;\n", stmt)
+		delim := routineDelimiter(stmt)
+		return fmt.Sprintf("DELIMITER %s\n%s%s\nDELIMITER ;\n", delim, stmt, delim)
 	}
 	return fmt.Sprintf("%s;\n", stmt)
 }
+
+// routineDelimiter returns a delimiter suitable for use with the DELIMITER
+// command around stmt. This is normally "//", but if stmt contains that string
+// anywhere (even in a quoted string or comment, where it would be harmless),
+// alternatives are tried instead, so that output remains parseable by any
+// client.
+func routineDelimiter(stmt string) string {
+	for _, delim := range []string{"//", "$$", ";;"} {
+		if !strings.Contains(stmt, delim) {
+			return delim
+		}
+	}
+	delim := "///"
+	for strings.Contains(stmt, delim) {
+		delim += "/"
+	}
+	return delim
+}
@@ -211,6 +211,52 @@ func TestTokenizedSQLFileRewrite(t *testing.T) {
 	}
 }
 
+func TestSQLFileTokenizeTemplate(t *testing.T) {
+	sf := SQLFile{
+		Dir:      "testdata",
+		FileName: "statements.sql",
+	}
+	vars := map[string]string{"Name": "posts"}
+
+	// Files without placeholders are tokenized normally
+	tokenizedFile, err := sf.TokenizeTemplate(vars)
+	if err != nil || tokenizedFile.Templated || len(tokenizedFile.Statements) != len(expectedStatements(sf.Path())) {
+		t.Errorf("Unexpected return from TokenizeTemplate: %+v / %v", tokenizedFile, err)
+	}
+
+	sf2 := SQLFile{
+		Dir:      "testdata",
+		FileName: "template.sql",
+	}
+	defer sf2.Delete()
+	WriteTestFile(t, sf2.Path(), "CREATE TABLE `{{.Name}}` (id int);\nCREATE TABLE {{.Name}}_archive (id int);\n")
+	if tokenizedFile, err = sf2.TokenizeTemplate(vars); err != nil {
+		t.Fatalf("Unexpected error from TokenizeTemplate: %s", err)
+	} else if !tokenizedFile.Templated || len(tokenizedFile.Statements) != 2 {
+		t.Fatalf("Unexpected result from TokenizeTemplate: %+v", tokenizedFile)
+	}
+	for n, expected := range []string{"posts", "posts_archive"} {
+		if stmt := tokenizedFile.Statements[n]; stmt.ObjectName != expected || stmt.Type != StatementTypeCreate {
+			t.Errorf("Expected statement %d to create %s, instead found %+v", n, expected, *stmt)
+		}
+	}
+
+	// The raw contents are tokenized by Tokenize, without any substitution
+	if tokenizedFile, err = sf2.Tokenize(); err != nil || tokenizedFile.Templated {
+		t.Errorf("Unexpected return from Tokenize: %+v / %v", tokenizedFile, err)
+	}
+
+	// Missing variables and malformed placeholders result in a TemplateError
+	for _, contents := range []string{"CREATE TABLE {{.Nmae}} (id int);\n", "CREATE TABLE {{.Name (id int);\n"} {
+		WriteTestFile(t, sf2.Path(), contents)
+		if _, err := sf2.TokenizeTemplate(vars); err == nil {
+			t.Errorf("Expected error from TokenizeTemplate with contents %q, but err was nil", contents)
+		} else if _, ok := err.(TemplateError); !ok {
+			t.Errorf("Expected error to be a TemplateError, instead found %T: %s", err, err)
+		}
+	}
+}
+
 // expectedStatements returns the expected contents of testdata/statements.sql
 // in the form of a slice of statement pointers
 func expectedStatements(filePath string) []*Statement {
@@ -263,6 +309,49 @@ func TestPathForObject(t *testing.T) {
 	}
 }
 
+func TestPathForObjectTemplate(t *testing.T) {
+	table := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo-bar"}
+	proc := tengo.ObjectKey{Type: tengo.ObjectTypeProc, Name: "baz"}
+	cases := []struct {
+		Template string
+		Key      tengo.ObjectKey
+		Expected string
+	}{
+		{"", table, "/var/schemas/foobar.sql"},
+		{"{NAME}.sql", proc, "/var/schemas/baz.sql"},
+		{"{type}_{name}.sql", table, "/var/schemas/table_foobar.sql"},
+		{"{TYPE}_{NAME}.sql", proc, "/var/schemas/procedure_baz.sql"},
+		{"{SCHEMA}.sql", table, "/var/schemas/mydb.sql"},
+		{"{Schema}.SQL", proc, "/var/schemas/mydb.SQL"},
+		{"all.sql", proc, "/var/schemas/all.sql"},
+	}
+	for _, c := range cases {
+		actual, err := PathForObjectTemplate("/var/schemas", c.Template, "my-db", c.Key)
+		if err != nil {
+			t.Errorf("Unexpected error from PathForObjectTemplate(%q): %v", c.Template, err)
+		} else if actual != c.Expected {
+			t.Errorf("Expected PathForObjectTemplate(%q) to return %q, instead found %q", c.Template, c.Expected, actual)
+		}
+	}
+
+	if actual, err := PathForObjectTemplate("/var/schemas", "{NAME}.sql", "", tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "[*]"}); err != nil || actual != "/var/schemas/symbols.sql" {
+		t.Errorf("Unexpected return from PathForObjectTemplate: %q, %v", actual, err)
+	}
+
+	for _, template := range []string{"{NAME}", "{SCHEMA}/{NAME}.sql", `{SCHEMA}\{NAME}.sql`, "{OBJECT}.sql", "{NAME}.txt"} {
+		if err := ValidateFileNameTemplate(template); err == nil {
+			t.Errorf("Expected template %q to be invalid, but no error returned", template)
+		}
+		if _, err := PathForObjectTemplate("/var/schemas", template, "foo", table); err == nil {
+			t.Errorf("Expected PathForObjectTemplate(%q) to return an error, but it did not", template)
+		}
+	}
+
+	if !FileNameTemplateHasName("") || !FileNameTemplateHasName("{type}_{name}.sql") || FileNameTemplateHasName("{SCHEMA}.sql") {
+		t.Error("Unexpected result from FileNameTemplateHasName")
+	}
+}
+
 func TestAppendToFile(t *testing.T) {
 	assertAppend := func(filePath, contents string, expectBytes int, expectCreated bool) {
 		t.Helper()
@@ -311,3 +400,64 @@ END;`
 		t.Errorf("Unexpected result from AddDelimiter: %s", result)
 	}
 }
+
+func TestAddDelimiterRoundTrip(t *testing.T) {
+	procs := []string{
+		// Embedded semicolons in the body, as well as in quotes and comments
+		"CREATE PROCEDURE semis()\nBEGIN\n  DECLARE v1 varchar(10) DEFAULT 'a;b';\n  SELECT v1; -- trailing; comment\n  SELECT \"c;d\";\nEND",
+		// Default delimiter appears in the body, outside of quotes and comments
+		"CREATE PROCEDURE slashes()\nBEGIN\n  SELECT 1 AS `a//b`;\n  SELECT '//';\nEND",
+		// Default and first alternate delimiters both appear in the body
+		"CREATE PROCEDURE dollars()\nBEGIN\n  SELECT '//', '$$';\n  SELECT 2;\nEND",
+	}
+	expectedDelims := []string{"//", "$$", ";;"}
+	for n, proc := range procs {
+		contents := AddDelimiter(proc) + "CREATE TABLE foo (id int);\n"
+		if expected := "DELIMITER " + expectedDelims[n] + "\n"; !strings.HasPrefix(contents, expected) {
+			t.Errorf("Expected AddDelimiter output to begin with %q, instead found:\n%s", expected, contents)
+		}
+		statements, err := ParseStatementsFromReader(strings.NewReader(contents), "roundtrip.sql")
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseStatementsFromReader: %s", err)
+		}
+		var creates []*Statement
+		for _, stmt := range statements {
+			if stmt.Type == StatementTypeCreate {
+				creates = append(creates, stmt)
+			} else if stmt.Type != StatementTypeCommand && stmt.Type != StatementTypeNoop {
+				t.Errorf("Unexpected statement type %v: %q", stmt.Type, stmt.Text)
+			}
+		}
+		if len(creates) != 2 {
+			t.Errorf("Expected 2 CREATE statements, instead found %d in:\n%s", len(creates), contents)
+			continue
+		}
+		if body := creates[0].Body(); body != proc {
+			t.Errorf("Round trip of procedure did not preserve body: expected %q, found %q", proc, body)
+		}
+		if body := creates[1].Body(); body != "CREATE TABLE foo (id int)" {
+			t.Errorf("Statement after procedure was not parsed as expected: found %q", body)
+		}
+	}
+
+	if delim := routineDelimiter("SELECT '//', '$$', ';;', '///'"); delim != "////" {
+		t.Errorf("Unexpected result from routineDelimiter: %q", delim)
+	}
+
+	// The lowercase form of DELIMITER, and delimiters with quotes, should be
+	// handled the same way
+	contents := "delimiter ;;\n" + procs[0] + ";;\ndelimiter ;\nDELIMITER '$$'\n" + procs[1] + "$$\nDELIMITER ;\n"
+	statements, err := ParseStatementsFromReader(strings.NewReader(contents), "roundtrip.sql")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsFromReader: %s", err)
+	}
+	var bodies []string
+	for _, stmt := range statements {
+		if stmt.Type == StatementTypeCreate {
+			bodies = append(bodies, stmt.Body())
+		}
+	}
+	if len(bodies) != 2 || bodies[0] != procs[0] || bodies[1] != procs[1] {
+		t.Errorf("Unexpected result parsing procedures with custom delimiters: %q", bodies)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -27,9 +26,28 @@ const (
 	StatementTypeCommand               // currently just USE or DELIMITER
 	StatementTypeCreate
 	StatementTypeAlter
+	StatementTypeInsert // only used for tables listed in the manage-data option
 	// Other types will be added once they are supported by the package
 )
 
+// ObjectTypeEvent is the object type for scheduled events. tengo does not
+// support events, so unlike other object types, this one is defined here; the
+// workspace and applier packages introspect and diff events separately.
+const ObjectTypeEvent tengo.ObjectType = "event"
+
+// ObjectTypeSequence is the object type for MariaDB sequences. Like events,
+// sequences are not supported by tengo, so they are introspected and diffed
+// separately.
+const ObjectTypeSequence tengo.ObjectType = "sequence"
+
+// ObjectTypeView is the object type for views. tengo does not support views
+// either, so they are also introspected and diffed separately.
+const ObjectTypeView tengo.ObjectType = "view"
+
+// ObjectTypeTrigger is the object type for triggers, which tengo also does not
+// support.
+const ObjectTypeTrigger tengo.ObjectType = "trigger"
+
 // Statement represents a logical instruction in a file, consisting of either
 // an SQL statement, a command (e.g. "USE some_database"), or whitespace and/or
 // comments between two separate statements or commands.
@@ -110,7 +128,7 @@ func (stmt *Statement) Remove() {
 // have been mis-parsed (for example, due to lack of DELIMITER commands)
 func (stmt *Statement) isCreateWithBegin() bool {
 	return stmt.Type == StatementTypeCreate &&
-		(stmt.ObjectType == tengo.ObjectTypeProc || stmt.ObjectType == tengo.ObjectTypeFunc) &&
+		(stmt.ObjectType == tengo.ObjectTypeProc || stmt.ObjectType == tengo.ObjectTypeFunc || stmt.ObjectType == ObjectTypeEvent || stmt.ObjectType == ObjectTypeTrigger) &&
 		strings.Contains(strings.ToLower(stmt.Text), "begin")
 }
 
@@ -163,14 +181,9 @@ func newStatementTokenizer(filePath, delimiter string) *statementTokenizer {
 	}
 }
 
-func (st *statementTokenizer) statements() ([]*Statement, error) {
-	file, err := os.Open(st.filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	reader := bufio.NewReader(file)
-
+func (st *statementTokenizer) statementsFromReader(r io.Reader) ([]*Statement, error) {
+	var err error
+	reader := bufio.NewReader(r)
 	for err != io.EOF {
 		var line string
 		line, err = reader.ReadString('\n')
@@ -189,6 +202,16 @@ func (st *statementTokenizer) statements() ([]*Statement, error) {
 	return st.result, err
 }
 
+// ParseStatementsFromReader splits the SQL read from r into statements, in
+// the same manner as SQLFile.Tokenize. The supplied name is used in place of a
+// file path for purposes of statement locations and error messages; for
+// example, "stdin". Routines containing multiple statements in a BEGIN...END
+// block must be surrounded by DELIMITER commands.
+func ParseStatementsFromReader(r io.Reader, name string) ([]*Statement, error) {
+	tokenizer := newStatementTokenizer(name, ";")
+	return tokenizer.statementsFromReader(r)
+}
+
 func (st *statementTokenizer) processLine(line string, eof bool) {
 	st.lineNo++
 	ls := &lineState{
@@ -263,6 +286,11 @@ func (st *statementTokenizer) processLine(line string, eof bool) {
 		case '"', '`', '\'':
 			ls.inQuote = c
 		case delimFirstRune:
+			// The DELIMITER command always extends to the end of the line, since its
+			// argument may begin with the current delimiter, e.g. "DELIMITER ;;"
+			if strings.HasPrefix(strings.ToLower(ls.buf.String()), "delimiter ") {
+				break
+			}
 			// Multi-rune delimiter: peek ahead to see if we've matched the full
 			// delimiter. If so, slurp up the rest of the delimiter's runes.
 			if delimRuneCount > 1 {
@@ -355,6 +383,11 @@ func (ls *lineState) doneStatement(omitEndBytes int) {
 }
 
 func (ls *lineState) parseStatement() {
+	// DELIMITER commands are terminated by a newline rather than the current
+	// delimiter, so the delimiter must not be trimmed from their argument
+	if strings.HasPrefix(strings.ToLower(ls.stmt.Text), "delimiter ") {
+		ls.stmt.delimiter = ""
+	}
 	txt, _ := ls.stmt.SplitTextBody()
 	if !ls.inRelevant || txt == "" {
 		ls.stmt.Type = StatementTypeNoop
@@ -380,6 +413,26 @@ func (ls *lineState) parseStatement() {
 			ls.stmt.Type = StatementTypeCreate
 			ls.stmt.ObjectType = tengo.ObjectTypeFunc
 			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateFunc.Name.schemaAndTable()
+		} else if sqlStmt.CreateEvent != nil {
+			ls.stmt.Type = StatementTypeCreate
+			ls.stmt.ObjectType = ObjectTypeEvent
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateEvent.Name.schemaAndTable()
+		} else if sqlStmt.CreateSequence != nil {
+			ls.stmt.Type = StatementTypeCreate
+			ls.stmt.ObjectType = ObjectTypeSequence
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateSequence.Name.schemaAndTable()
+		} else if sqlStmt.CreateView != nil {
+			ls.stmt.Type = StatementTypeCreate
+			ls.stmt.ObjectType = ObjectTypeView
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateView.Name.schemaAndTable()
+		} else if sqlStmt.CreateTrigger != nil {
+			ls.stmt.Type = StatementTypeCreate
+			ls.stmt.ObjectType = ObjectTypeTrigger
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateTrigger.Name.schemaAndTable()
+		} else if sqlStmt.InsertInto != nil {
+			ls.stmt.Type = StatementTypeInsert
+			ls.stmt.ObjectType = tengo.ObjectTypeTable
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.InsertInto.Name.schemaAndTable()
 		}
 	}
 }
@@ -435,6 +488,11 @@ type sqlStatement struct {
 	CreateTable      *createTable      `parser:"@@"`
 	CreateProc       *createProc       `parser:"| @@"`
 	CreateFunc       *createFunc       `parser:"| @@"`
+	CreateEvent      *createEvent      `parser:"| @@"`
+	CreateSequence   *createSequence   `parser:"| @@"`
+	CreateView       *createView       `parser:"| @@"`
+	CreateTrigger    *createTrigger    `parser:"| @@"`
+	InsertInto       *insertInto       `parser:"| @@"`
 	UseCommand       *useCommand       `parser:"| @@"`
 	DelimiterCommand *delimiterCommand `parser:"| @@"`
 }
@@ -481,7 +539,7 @@ type body struct {
 	Contents []string `parser:"(@Word | @String | @Number | @Operator)*"`
 }
 
-// definer represents a user who is the definer of a routine or view.
+// definer represents a user who is the definer of a routine, event, or view.
 type definer struct {
 	User string `parser:"((@String | @Word) '@'"`
 	Host string `parser:"(@String | @Word))"`
@@ -508,6 +566,42 @@ type createFunc struct {
 	Body    body       `parser:"@@"`
 }
 
+// createEvent represents a CREATE EVENT statement.
+type createEvent struct {
+	Definer *definer   `parser:"'CREATE' ('DEFINER' '=' @@)?"`
+	Name    objectName `parser:"'EVENT' ('IF' 'NOT' 'EXISTS')? @@"`
+	Body    body       `parser:"@@"`
+}
+
+// createSequence represents a CREATE SEQUENCE statement.
+type createSequence struct {
+	Name objectName `parser:"'CREATE' 'SEQUENCE' ('IF' 'NOT' 'EXISTS')? @@"`
+	Body body       `parser:"@@"`
+}
+
+// createView represents a CREATE VIEW statement.
+type createView struct {
+	Algorithm string     `parser:"'CREATE' ('OR' 'REPLACE')? ('ALGORITHM' '=' @Word)?"`
+	Definer   *definer   `parser:"('DEFINER' '=' @@)?"`
+	Security  string     `parser:"('SQL' 'SECURITY' @Word)?"`
+	Name      objectName `parser:"'VIEW' ('IF' 'NOT' 'EXISTS')? @@"`
+	Body      body       `parser:"@@"`
+}
+
+// createTrigger represents a CREATE TRIGGER statement.
+type createTrigger struct {
+	Definer *definer   `parser:"'CREATE' ('DEFINER' '=' @@)?"`
+	Name    objectName `parser:"'TRIGGER' ('IF' 'NOT' 'EXISTS')? @@"`
+	Body    body       `parser:"@@"`
+}
+
+// insertInto represents an INSERT statement. These are only used for populating
+// tables whose rows are managed by the manage-data option.
+type insertInto struct {
+	Name objectName `parser:"'INSERT' ('IGNORE')? ('INTO')? @@"`
+	Body body       `parser:"@@"`
+}
+
 // useCommand represents a USE command.
 type useCommand struct {
 	DefaultDatabase string `parser:"'USE' @Word"`
@@ -1,7 +1,10 @@
 package fs
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/skeema/tengo"
 )
 
 func TestStatementLocation(t *testing.T) {
@@ -94,7 +97,7 @@ func TestCanParse(t *testing.T) {
 		"CREATE TABLE foo (\n\t`id` int unsigned DEFAULT '0'\n) ;\n": true,
 		"CREATE TABLE   IF  not EXISTS  foo (\n\tid int\n) ;\n":      true,
 		"USE some_db\n\n":              true,
-		"INSERT INTO foo VALUES (';')": false,
+		"INSERT INTO foo VALUES (';')": true,
 		"bork bork bork":               false,
 		"# hello":                      false,
 		"CREATE TEMPORARY TABLE foo (\n\tid int\n) ;\n":   false,
@@ -109,3 +112,74 @@ func TestCanParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseStatementsFromReader(t *testing.T) {
+	input := `CREATE TABLE foo (id int);
+-- comment
+CREATE TABLE bar (
+  id int
+);
+DELIMITER //
+CREATE PROCEDURE whatever() BEGIN SELECT 1; SELECT 2; END//
+DELIMITER ;
+CREATE EVENT ev1 ON SCHEDULE EVERY 1 DAY DO DELETE FROM foo;
+DELIMITER $$
+CREATE DEFINER=root@localhost EVENT IF NOT EXISTS ` + "`ev2`" + ` ON SCHEDULE EVERY 1 HOUR DO BEGIN DELETE FROM foo; DELETE FROM bar; END$$
+DELIMITER ;
+CREATE SEQUENCE IF NOT EXISTS seq1 START WITH 100 INCREMENT BY 10;
+CREATE VIEW v1 AS SELECT id FROM foo;
+CREATE OR REPLACE ALGORITHM=MERGE DEFINER=` + "`root`@`%`" + ` SQL SECURITY INVOKER VIEW product.v2 AS SELECT id FROM bar WITH CHECK OPTION;
+DELIMITER //
+CREATE DEFINER=CURRENT_USER TRIGGER trg1 BEFORE INSERT ON foo FOR EACH ROW BEGIN SET NEW.id = 1; END//
+DELIMITER ;
+`
+	statements, err := ParseStatementsFromReader(strings.NewReader(input), "stdin")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsFromReader: %v", err)
+	}
+	var creates []*Statement
+	for _, stmt := range statements {
+		if stmt.Type == StatementTypeCreate {
+			creates = append(creates, stmt)
+		}
+	}
+	if len(creates) != 9 {
+		t.Fatalf("Expected 9 CREATE statements, instead found %d", len(creates))
+	}
+	if creates[1].ObjectName != "bar" || creates[1].Location() != "stdin:3:1" {
+		t.Errorf("Unexpected name or location for second statement: %s at %s", creates[1].ObjectName, creates[1].Location())
+	}
+	if creates[2].ObjectType != tengo.ObjectTypeProc || creates[2].ObjectName != "whatever" {
+		t.Errorf("Unexpected object for third statement: %s", creates[2].ObjectKey())
+	}
+	for n, expectedSuffix := range map[string]string{"ev1": "DELETE FROM foo", "ev2": "DELETE FROM bar; END"} {
+		var found bool
+		for _, stmt := range creates[3:] {
+			if stmt.ObjectType == ObjectTypeEvent && stmt.ObjectName == n {
+				found = true
+				if !strings.HasSuffix(stmt.Body(), expectedSuffix) {
+					t.Errorf("Unexpected body for event %s: %q", n, stmt.Body())
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Event %s not found in parsed statements", n)
+		}
+	}
+	if creates[5].ObjectType != ObjectTypeSequence || creates[5].ObjectName != "seq1" {
+		t.Errorf("Unexpected object for sixth statement: %s", creates[5].ObjectKey())
+	}
+	if creates[6].ObjectType != ObjectTypeView || creates[6].ObjectName != "v1" {
+		t.Errorf("Unexpected object for seventh statement: %s", creates[6].ObjectKey())
+	}
+	if creates[7].ObjectType != ObjectTypeView || creates[7].ObjectName != "v2" || creates[7].ObjectQualifier != "product" {
+		t.Errorf("Unexpected object for eighth statement: %s", creates[7].ObjectKey())
+	}
+	if creates[8].ObjectType != ObjectTypeTrigger || creates[8].ObjectName != "trg1" || !strings.HasSuffix(creates[8].Body(), "END") {
+		t.Errorf("Unexpected object for ninth statement: %s", creates[8].ObjectKey())
+	}
+
+	if _, err := ParseStatementsFromReader(strings.NewReader("CREATE TABLE `foo (id int);\n"), "stdin"); err == nil || !strings.Contains(err.Error(), "stdin") {
+		t.Errorf("Expected error mentioning stdin for unterminated quote, instead found %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package fs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TableOption represents a single table-level option clause, such as
+// ENGINE=InnoDB. Name is always upper-case, with CHARACTER SET normalized to
+// CHARSET.
+type TableOption struct {
+	Name  string
+	Value string
+}
+
+// String returns the option in the same format used by SHOW CREATE TABLE.
+func (opt TableOption) String() string {
+	if opt.Name == "CHARSET" {
+		return "DEFAULT CHARSET=" + opt.Value
+	}
+	return opt.Name + "=" + opt.Value
+}
+
+// isCharSetRelated returns true if the option is CHARSET or COLLATE. These are
+// treated as a unit, since supplying either one affects the other.
+func (opt TableOption) isCharSetRelated() bool {
+	return opt.Name == "CHARSET" || opt.Name == "COLLATE"
+}
+
+// TableOptions represents an ordered list of default table options, which
+// are applied to CREATE TABLE statements that do not specify them explicitly.
+type TableOptions []TableOption
+
+var reTableOption = regexp.MustCompile(`^[\s,]*(?i:DEFAULT\s+)?([A-Za-z_]+(?:\s+SET)?)\s*=?\s*('(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"|[^\s,='"]+)`)
+
+// ParseTableOptions parses a string of table options, in the same format as
+// the end of a CREATE TABLE statement, for example
+// "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC". Options may be
+// separated by spaces or commas. An error is returned if the input cannot be
+// parsed, an option is repeated, or an option is not permitted as a default.
+func ParseTableOptions(input string) (TableOptions, error) {
+	var opts TableOptions
+	seen := make(map[string]bool)
+	remaining := input
+	for strings.Trim(remaining, " \t\n,") != "" {
+		match := reTableOption.FindStringSubmatch(remaining)
+		if match == nil {
+			return nil, fmt.Errorf("Unable to parse table options %q near %q", input, strings.TrimSpace(remaining))
+		}
+		remaining = remaining[len(match[0]):]
+		name := strings.ToUpper(strings.Join(strings.Fields(match[1]), " "))
+		if name == "CHARACTER SET" {
+			name = "CHARSET"
+		}
+		switch name {
+		case "AUTO_INCREMENT", "PARTITION", "TABLESPACE":
+			return nil, fmt.Errorf("Table option %s cannot be used as a default", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("Table option %s is specified more than once in %q", name, input)
+		}
+		seen[name] = true
+		value := match[2]
+		if value[0] == '"' && !strings.ContainsAny(value[1:len(value)-1], `'"\\`) {
+			value = "'" + value[1:len(value)-1] + "'" // match SHOW CREATE TABLE's quoting
+		}
+		opts = append(opts, TableOption{Name: name, Value: value})
+	}
+	return opts, nil
+}
+
+// ApplyToCreate returns a version of the supplied CREATE TABLE statement with
+// any of the options in opts added, if the statement did not already specify
+// them. CHARSET and COLLATE are treated as a unit: if the statement specifies
+// either one, neither is added. Statements which do not have a parenthesized
+// body, such as CREATE TABLE ... LIKE, are returned unchanged.
+func (opts TableOptions) ApplyToCreate(create string) string {
+	start, end := tableOptionsBounds(create)
+	if start < 0 || len(opts) == 0 {
+		return create
+	}
+	existing := newTableOptionsRegion(create[start:end])
+	var missing []string
+	for _, opt := range opts {
+		if !existing.has(opt) {
+			missing = append(missing, opt.String())
+		}
+	}
+	if len(missing) == 0 {
+		return create
+	}
+	addition := " " + strings.Join(missing, " ")
+	if rest := create[start:]; rest != "" && !strings.ContainsAny(rest[0:1], " \t\n,;") {
+		addition += " "
+	}
+	return create[:start] + addition + create[start:]
+}
+
+// StripFromCreate is the inverse of ApplyToCreate, for use when writing a
+// canonical CREATE TABLE from the database to a file. It returns a version of
+// canonical without any clauses which exactly match one of the options in
+// opts, but only for options which are not explicitly specified in fsCreate.
+// This way, files which deliberately omit default options are not rewritten to
+// include them.
+func (opts TableOptions) StripFromCreate(canonical, fsCreate string) string {
+	fsStart, fsEnd := tableOptionsBounds(fsCreate)
+	start, end := tableOptionsBounds(canonical)
+	if fsStart < 0 || start < 0 || len(opts) == 0 {
+		return canonical
+	}
+	existing := newTableOptionsRegion(fsCreate[fsStart:fsEnd])
+	region := canonical[start:end]
+	for _, opt := range opts {
+		if existing.has(opt) {
+			continue
+		}
+		re := regexp.MustCompile(`(?i) (?:DEFAULT )?` + optionNamePattern(opt.Name) + `=` + regexp.QuoteMeta(opt.Value) + `(\s|$)`)
+		if loc := re.FindStringSubmatchIndex(region); loc != nil {
+			region = region[:loc[0]] + region[loc[2]:]
+		}
+	}
+	return canonical[:start] + region + canonical[end:]
+}
+
+// tableOptionsRegion represents the portion of a CREATE TABLE statement after
+// the closing parenthesis of its body, with any quoted strings removed.
+type tableOptionsRegion string
+
+var reQuotedString = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"|` + "`(?:[^`]|``)*`")
+
+func newTableOptionsRegion(text string) tableOptionsRegion {
+	return tableOptionsRegion(reQuotedString.ReplaceAllString(text, "''"))
+}
+
+// has returns true if the region contains the option's name, or for CHARSET
+// and COLLATE, either of those names.
+func (region tableOptionsRegion) has(opt TableOption) bool {
+	names := []string{opt.Name}
+	if opt.isCharSetRelated() {
+		names = []string{"CHARSET", "COLLATE"}
+	}
+	for _, name := range names {
+		re := regexp.MustCompile(`(?i)(?:^|[\s,)])` + optionNamePattern(name) + `\s*(?:=|\s)`)
+		if re.MatchString(string(region)) {
+			return true
+		}
+	}
+	return false
+}
+
+func optionNamePattern(name string) string {
+	if name == "CHARSET" {
+		return `(?:CHARSET|CHARACTER\s+SET)`
+	}
+	return regexp.QuoteMeta(name)
+}
+
+var rePartitionClause = regexp.MustCompile(`(?i)(?:/\*!\d+ )?\s*PARTITION\s+BY\s`)
+
+// tableOptionsBounds returns the start and end offsets of the table options
+// portion of a CREATE TABLE statement: everything after the parenthesis
+// closing the table body, up to any partitioning clause. If the statement has
+// no parenthesized body, -1 is returned for both offsets.
+func tableOptionsBounds(create string) (start, end int) {
+	depth := 0
+	var quote byte
+	for n := 0; n < len(create); n++ {
+		c := create[n]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				n++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				start = n + 1
+				end = len(create)
+				if loc := rePartitionClause.FindStringIndex(create[start:]); loc != nil {
+					end = start + loc[0]
+				}
+				return start, end
+			} else if depth < 0 {
+				return -1, -1
+			}
+		}
+	}
+	return -1, -1
+}
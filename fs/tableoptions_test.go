@@ -0,0 +1,132 @@
+package fs
+
+import (
+	"testing"
+)
+
+func TestParseTableOptions(t *testing.T) {
+	opts, err := ParseTableOptions("ENGINE=InnoDB DEFAULT CHARSET=utf8mb4, collate utf8mb4_unicode_ci ROW_FORMAT = DYNAMIC COMMENT='hello world'")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseTableOptions: %s", err)
+	}
+	expected := []string{"ENGINE=InnoDB", "DEFAULT CHARSET=utf8mb4", "COLLATE=utf8mb4_unicode_ci", "ROW_FORMAT=DYNAMIC", "COMMENT='hello world'"}
+	if len(opts) != len(expected) {
+		t.Fatalf("Expected %d options, instead found %d: %+v", len(expected), len(opts), opts)
+	}
+	for n, opt := range opts {
+		if opt.String() != expected[n] {
+			t.Errorf("Expected option %d to be %s, instead found %s", n, expected[n], opt)
+		}
+	}
+	if opts, err := ParseTableOptions("CHARACTER SET latin1"); err != nil || len(opts) != 1 || opts[0].Name != "CHARSET" {
+		t.Errorf("Unexpected result from ParseTableOptions with CHARACTER SET: %+v, %v", opts, err)
+	}
+	if opts, err := ParseTableOptions(`COMMENT="hi"`); err != nil || len(opts) != 1 || opts[0].Value != "'hi'" {
+		t.Errorf("Unexpected result from ParseTableOptions with double-quoted value: %+v, %v", opts, err)
+	}
+	if opts, err := ParseTableOptions(""); err != nil || len(opts) != 0 {
+		t.Errorf("Unexpected result from ParseTableOptions with empty input: %+v, %v", opts, err)
+	}
+
+	for _, input := range []string{"ENGINE=InnoDB ENGINE=MyISAM", "AUTO_INCREMENT=5", "ROW_FORMAT=", "ENGINE=InnoDB 'oops'", "CHARSET=utf8mb4 CHARACTER SET latin1"} {
+		if _, err := ParseTableOptions(input); err == nil {
+			t.Errorf("Expected error from ParseTableOptions(%q), but it was nil", input)
+		}
+	}
+}
+
+func TestTableOptionsApplyToCreate(t *testing.T) {
+	opts, err := ParseTableOptions("ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseTableOptions: %s", err)
+	}
+	cases := map[string]string{
+		// Injection of everything
+		"CREATE TABLE foo (id int)":   "CREATE TABLE foo (id int) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC",
+		"CREATE TABLE foo (id int)\n": "CREATE TABLE foo (id int) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC\n",
+
+		// Explicit options in the statement take precedence
+		"CREATE TABLE foo (id int) ENGINE=MyISAM":                                    "CREATE TABLE foo (id int) DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC ENGINE=MyISAM",
+		"CREATE TABLE foo (id int)engine MyISAM":                                     "CREATE TABLE foo (id int) DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC engine MyISAM",
+		"CREATE TABLE foo (id int) COLLATE=latin1_bin":                               "CREATE TABLE foo (id int) ENGINE=InnoDB ROW_FORMAT=DYNAMIC COLLATE=latin1_bin",
+		"CREATE TABLE foo (id int) CHARACTER SET latin1":                             "CREATE TABLE foo (id int) ENGINE=InnoDB ROW_FORMAT=DYNAMIC CHARACTER SET latin1",
+		"CREATE TABLE foo (id int) ENGINE=InnoDB CHARSET=utf8mb4 ROW_FORMAT=COMPACT": "CREATE TABLE foo (id int) ENGINE=InnoDB CHARSET=utf8mb4 ROW_FORMAT=COMPACT",
+
+		// Option names inside the body or inside strings don't count as explicit
+		"CREATE TABLE foo (engine varchar(10) CHARACTER SET latin1) COMMENT='ENGINE=x'":            "CREATE TABLE foo (engine varchar(10) CHARACTER SET latin1) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC COMMENT='ENGINE=x'",
+		"CREATE TABLE `a)b` (`c(` int DEFAULT ')') ROW_FORMAT=COMPRESSED":                          "CREATE TABLE `a)b` (`c(` int DEFAULT ')') ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=COMPRESSED",
+		"CREATE TABLE foo (id int) PARTITION BY HASH (id) PARTITIONS 4":                            "CREATE TABLE foo (id int) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC PARTITION BY HASH (id) PARTITIONS 4",
+		"CREATE TABLE foo (id int) ENGINE=InnoDB\n/*!50100 PARTITION BY HASH (id) PARTITIONS 4 */": "CREATE TABLE foo (id int) DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC ENGINE=InnoDB\n/*!50100 PARTITION BY HASH (id) PARTITIONS 4 */",
+
+		// Statements without a body are left alone
+		"CREATE TABLE foo LIKE bar": "CREATE TABLE foo LIKE bar",
+	}
+	for input, expected := range cases {
+		if actual := opts.ApplyToCreate(input); actual != expected {
+			t.Errorf("Unexpected result from ApplyToCreate:\ninput:    %s\nexpected: %s\nactual:   %s", input, expected, actual)
+		}
+	}
+
+	var noOpts TableOptions
+	if actual := noOpts.ApplyToCreate("CREATE TABLE foo (id int)"); actual != "CREATE TABLE foo (id int)" {
+		t.Errorf("Expected nil TableOptions to leave statement unchanged, instead found %s", actual)
+	}
+}
+
+func TestTableOptionsStripFromCreate(t *testing.T) {
+	opts, err := ParseTableOptions("ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseTableOptions: %s", err)
+	}
+	body := "CREATE TABLE `foo` (\n  `id` int(11) DEFAULT NULL\n)"
+	cases := []struct {
+		canonical string
+		fsCreate  string
+		expected  string
+	}{
+		// File omitting all options should stay that way
+		{
+			body + " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC",
+			"CREATE TABLE foo (id int)",
+			body,
+		},
+		// Options explicitly in the file are retained
+		{
+			body + " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC",
+			"CREATE TABLE foo (id int) ENGINE=InnoDB ROW_FORMAT=DYNAMIC",
+			body + " ENGINE=InnoDB ROW_FORMAT=DYNAMIC",
+		},
+		// Charset and collation are retained together if the file has either
+		{
+			body + " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC",
+			"CREATE TABLE foo (id int) CHARSET=utf8mb4",
+			body + " DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
+		},
+		// Values which differ from the defaults are never stripped
+		{
+			body + " ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=COMPACT COMMENT='hi'",
+			"CREATE TABLE foo (id int)",
+			body + " ENGINE=MyISAM COLLATE=utf8mb4_bin ROW_FORMAT=COMPACT COMMENT='hi'",
+		},
+		// Partitioning clause is unaffected
+		{
+			body + " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci\n/*!50100 PARTITION BY HASH (id) PARTITIONS 4 */",
+			"CREATE TABLE foo (id int) PARTITION BY HASH (id) PARTITIONS 4",
+			body + "\n/*!50100 PARTITION BY HASH (id) PARTITIONS 4 */",
+		},
+	}
+	for _, c := range cases {
+		if actual := opts.StripFromCreate(c.canonical, c.fsCreate); actual != c.expected {
+			t.Errorf("Unexpected result from StripFromCreate:\ncanonical: %s\nfs:        %s\nexpected:  %s\nactual:    %s", c.canonical, c.fsCreate, c.expected, actual)
+		}
+	}
+
+	// Round-trip: applying the defaults to a stripped statement should restore
+	// an equivalent statement
+	for _, c := range cases {
+		stripped := opts.StripFromCreate(c.canonical, c.fsCreate)
+		if opts.StripFromCreate(opts.ApplyToCreate(stripped), stripped) != stripped {
+			t.Errorf("Round-trip of ApplyToCreate and StripFromCreate unexpectedly changed statement: %s", stripped)
+		}
+	}
+}
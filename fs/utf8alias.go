@@ -0,0 +1,33 @@
+package fs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reUTF8Alias matches a CHARSET, CHARACTER SET, or COLLATE clause naming the
+// utf8 character set, or one of its collations, by either of its names. It
+// does not match utf8mb4.
+var reUTF8Alias = regexp.MustCompile(`(?i)\b((?:CHARSET|CHARACTER SET|COLLATE)\s*=?\s*)utf8(?:mb3)?(_[a-z0-9_]+)?\b`)
+
+// UTF8Alias returns name, a character set or collation name, rewritten to use
+// alias, which must be "utf8" or "utf8mb3". The utf8 character set is reported
+// as utf8mb3 by MySQL 8.0.30+ and MariaDB 10.6+, but as utf8 by older versions;
+// the two names are equivalent. Names of other character sets and collations
+// are returned unchanged.
+func UTF8Alias(name, alias string) string {
+	lower := strings.ToLower(name)
+	for _, prefix := range []string{"utf8mb3", "utf8"} {
+		if lower == prefix || strings.HasPrefix(lower, prefix+"_") {
+			return alias + lower[len(prefix):]
+		}
+	}
+	return name
+}
+
+// ReplaceUTF8Alias returns a copy of statement with each utf8 character set
+// or collation name, in any CHARSET, CHARACTER SET, or COLLATE clause,
+// rewritten to use alias, as per UTF8Alias.
+func ReplaceUTF8Alias(statement, alias string) string {
+	return reUTF8Alias.ReplaceAllString(statement, "${1}"+alias+"${2}")
+}
@@ -0,0 +1,51 @@
+package fs
+
+import "testing"
+
+func TestUTF8Alias(t *testing.T) {
+	cases := map[string]string{
+		"utf8":               "utf8mb3",
+		"utf8mb3":            "utf8mb3",
+		"utf8_general_ci":    "utf8mb3_general_ci",
+		"utf8mb3_unicode_ci": "utf8mb3_unicode_ci",
+		"UTF8_BIN":           "utf8mb3_bin",
+		"utf8mb4":            "utf8mb4",
+		"utf8mb4_general_ci": "utf8mb4_general_ci",
+		"latin1":             "latin1",
+		"":                   "",
+	}
+	for input, expected := range cases {
+		if actual := UTF8Alias(input, "utf8mb3"); actual != expected {
+			t.Errorf("Expected UTF8Alias(%q, \"utf8mb3\") to return %q, instead found %q", input, expected, actual)
+		}
+	}
+	if actual := UTF8Alias("utf8mb3_general_ci", "utf8"); actual != "utf8_general_ci" {
+		t.Errorf("Unexpected result from UTF8Alias: %q", actual)
+	}
+}
+
+func TestReplaceUTF8Alias(t *testing.T) {
+	input := "CREATE TABLE `utf8mb3_names` (\n" +
+		"  `a` varchar(10) CHARACTER SET utf8mb3 COLLATE utf8mb3_bin DEFAULT 'utf8mb3',\n" +
+		"  `b` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci,\n" +
+		"  `c` char(1) COLLATE utf8mb3_unicode_ci\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb3 COLLATE=utf8mb3_general_ci"
+	expected := "CREATE TABLE `utf8mb3_names` (\n" +
+		"  `a` varchar(10) CHARACTER SET utf8 COLLATE utf8_bin DEFAULT 'utf8mb3',\n" +
+		"  `b` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci,\n" +
+		"  `c` char(1) COLLATE utf8_unicode_ci\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_general_ci"
+	if actual := ReplaceUTF8Alias(input, "utf8"); actual != expected {
+		t.Errorf("Unexpected result from ReplaceUTF8Alias.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if actual := ReplaceUTF8Alias(expected, "utf8mb3"); actual != input {
+		t.Errorf("Unexpected result from ReplaceUTF8Alias.\nExpected:\n%s\nActual:\n%s", input, actual)
+	} else if ReplaceUTF8Alias(input, "utf8mb3") != input {
+		t.Error("Expected ReplaceUTF8Alias to be a no-op when statement already uses alias")
+	}
+
+	// Routine params use CHARSET without an equals sign
+	if actual := ReplaceUTF8Alias("`name` varchar(20) CHARSET utf8", "utf8mb3"); actual != "`name` varchar(20) CHARSET utf8mb3" {
+		t.Errorf("Unexpected result from ReplaceUTF8Alias: %s", actual)
+	}
+}
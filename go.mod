@@ -5,6 +5,7 @@ go 1.12
 require (
 	github.com/VividCortex/mysqlerr v0.0.0-20170204212430-6c6b55f8796f
 	github.com/alecthomas/participle v0.3.0
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/mattn/goveralls v0.0.3-0.20190605103025-4d9899298d21
 	github.com/mitchellh/go-wordwrap v1.0.0
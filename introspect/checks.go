@@ -0,0 +1,68 @@
+package introspect
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reCheckConstraint matches a line of SHOW CREATE TABLE output defining a
+// table-level CHECK constraint. The subexpressions are the constraint name, its
+// parenthesized expression, and MySQL's NOT ENFORCED clause if present.
+var reCheckConstraint = regexp.MustCompile("^  CONSTRAINT `((?:[^`]|``)+)` CHECK (\\(.*\\))( /\\*!80016 NOT ENFORCED \\*/)?,?$")
+
+// CheckConstraint represents a table-level CHECK constraint, as displayed by
+// SHOW CREATE TABLE in MySQL 8.0.16+, Percona Server 8.0.16+, or MariaDB
+// 10.2+. Older versions parse CHECK constraints but discard them.
+type CheckConstraint struct {
+	Name       string
+	Expression string // including outer parens, as displayed by SHOW CREATE TABLE
+	Enforced   bool   // always true in MariaDB, which lacks NOT ENFORCED
+}
+
+// Definition returns the constraint's definition, everything after
+// "CONSTRAINT [name] " in SHOW CREATE TABLE.
+func (cc CheckConstraint) Definition() string {
+	if cc.Enforced {
+		return "CHECK " + cc.Expression
+	}
+	return "CHECK " + cc.Expression + " /*!80016 NOT ENFORCED */"
+}
+
+// CheckConstraints returns the table-level CHECK constraints of table, in the
+// order they appear in its CreateStatement. Column-level CHECK constraints,
+// which MariaDB displays inline in the column definition, are not included.
+func CheckConstraints(table *tengo.Table) []CheckConstraint {
+	_, checks := ParseCreateChecks(table.CreateStatement)
+	return checks
+}
+
+// ParseCreateChecks splits the supplied CREATE TABLE statement into a version
+// without any table-level CHECK constraints, and the constraints themselves in
+// their original order. If the statement has no CHECK constraints, it is
+// returned as-is along with a nil slice.
+func ParseCreateChecks(createStmt string) (base string, checks []CheckConstraint) {
+	lines := strings.Split(createStmt, "\n")
+	body := make([]string, 0, len(lines))
+	var tail []string
+	for n, line := range lines[1:] {
+		if strings.HasPrefix(line, ")") {
+			tail = lines[n+1:]
+			break
+		}
+		if match := reCheckConstraint.FindStringSubmatch(line); match != nil {
+			checks = append(checks, CheckConstraint{
+				Name:       strings.Replace(match[1], "``", "`", -1),
+				Expression: match[2],
+				Enforced:   match[3] == "",
+			})
+		} else {
+			body = append(body, strings.TrimSuffix(line, ","))
+		}
+	}
+	if len(checks) == 0 || tail == nil {
+		return createStmt, nil
+	}
+	return lines[0] + "\n" + strings.Join(body, ",\n") + "\n" + strings.Join(tail, "\n"), checks
+}
@@ -0,0 +1,50 @@
+package introspect
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestParseCreateChecks(t *testing.T) {
+	base := "CREATE TABLE `widgets` (\n  `id` int(11) NOT NULL,\n  `qty` int(11) DEFAULT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	if actualBase, checks := ParseCreateChecks(base); actualBase != base || checks != nil {
+		t.Errorf("Unexpected result from ParseCreateChecks on statement without checks: %q, %+v", actualBase, checks)
+	}
+
+	cases := []struct {
+		input    string
+		expected []CheckConstraint
+	}{
+		{
+			strings.Replace(base, "(`id`)\n", "(`id`),\n  CONSTRAINT `CONSTRAINT_1` CHECK (`qty` > 0)\n", 1),
+			[]CheckConstraint{{"CONSTRAINT_1", "(`qty` > 0)", true}},
+		},
+		{
+			strings.Replace(base, "(`id`)\n", "(`id`),\n  CONSTRAINT `widgets_chk_1` CHECK ((`qty` > 0)),\n  CONSTRAINT `odd``name` CHECK ((`qty` < 100)) /*!80016 NOT ENFORCED */\n", 1),
+			[]CheckConstraint{{"widgets_chk_1", "((`qty` > 0))", true}, {"odd`name", "((`qty` < 100))", false}},
+		},
+	}
+	for _, c := range cases {
+		actualBase, checks := ParseCreateChecks(c.input)
+		if actualBase != base || !reflect.DeepEqual(checks, c.expected) {
+			t.Errorf("Unexpected result from ParseCreateChecks on %s: returned %q, %+v", c.input, actualBase, checks)
+		}
+	}
+
+	// Foreign keys are not CHECK constraints
+	fk := strings.Replace(base, "(`id`)\n", "(`id`),\n  CONSTRAINT `fk` FOREIGN KEY (`qty`) REFERENCES `other` (`id`)\n", 1)
+	if actualBase, checks := ParseCreateChecks(fk); actualBase != fk || checks != nil {
+		t.Errorf("Unexpected result from ParseCreateChecks on statement with foreign key: %q, %+v", actualBase, checks)
+	}
+
+	// Definition reconstructs the text following the constraint name
+	for _, line := range []string{"CHECK (`qty` > 0)", "CHECK ((`qty` < 100)) /*!80016 NOT ENFORCED */"} {
+		create := strings.Replace(base, "(`id`)\n", "(`id`),\n  CONSTRAINT `c` "+line+"\n", 1)
+		if checks := CheckConstraints(&tengo.Table{CreateStatement: create}); len(checks) != 1 || checks[0].Definition() != line {
+			t.Errorf("Unexpected result from CheckConstraints on %s: %+v", create, checks)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package introspect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// Fingerprint returns a stable hash of the contents of schema and objs, as a
+// hex-encoded SHA-256 string. Two schemas have the same fingerprint if they
+// have the same default character set and collation, and equivalent
+// definitions of tables, routines, events, sequences, views, and triggers.
+// Table definitions are compared using canonical SHOW CREATE output, so the
+// fingerprint does not depend on the order of objects, or on the formatting of
+// the statements that originally created them. The schema's name is excluded,
+// as are table AUTO_INCREMENT values, the definers and creation-time sql_mode
+// of all objects, the time zone and schedule times of events, and the database
+// collation of routines, since these typically vary between environments
+// without affecting the schema's structure.
+//
+// A nil schema with no objs has the same fingerprint as an empty schema with
+// no default character set or collation.
+func Fingerprint(schema *tengo.Schema, objs Objects) string {
+	if schema == nil {
+		schema = &tengo.Schema{}
+	}
+	defs := make(map[tengo.ObjectKey]string, len(schema.Tables)+len(schema.Routines))
+	for _, table := range schema.Tables {
+		defs[tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name}], _ = tengo.ParseCreateAutoInc(table.CreateStatement)
+	}
+	for _, routine := range schema.Routines {
+		r := *routine
+		r.Definer = "" // sql_mode and db collation are not part of Definition either
+		defs[tengo.ObjectKey{Type: r.Type, Name: r.Name}] = r.Definition(tengo.FlavorUnknown)
+	}
+	for _, e := range objs.Events {
+		defs[tengo.ObjectKey{Type: fs.ObjectTypeEvent, Name: e.Name}] = strings.Join([]string{e.Type, e.IntervalValue, e.IntervalField, e.OnCompletion, e.Status, e.Comment, e.Body}, "\x00")
+	}
+	for _, seq := range objs.Sequences {
+		defs[tengo.ObjectKey{Type: fs.ObjectTypeSequence, Name: seq.Name}] = strings.Join([]string{seq.DataType, seq.Start, seq.MinValue, seq.MaxValue, seq.Increment, seq.Cache, strconv.FormatBool(seq.Cycle)}, "\x00")
+	}
+	for _, v := range objs.Views {
+		defs[tengo.ObjectKey{Type: fs.ObjectTypeView, Name: v.Name}] = strings.Join([]string{v.Algorithm, v.Security, v.CheckOption, v.Definition}, "\x00")
+	}
+	for _, trig := range objs.Triggers {
+		defs[tengo.ObjectKey{Type: fs.ObjectTypeTrigger, Name: trig.Name}] = strings.Join([]string{strings.ToLower(trig.Table), trig.Timing, trig.Event, trig.Body}, "\x00")
+	}
+	keys := make([]tengo.ObjectKey, 0, len(defs))
+	for key := range defs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].Name < keys[j].Name
+	})
+
+	// Each value is NUL-terminated, since NUL cannot appear in any of them
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", schema.CharSet, schema.Collation)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", key.Type, key.Name, defs[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,178 @@
+package introspect
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestFingerprint(t *testing.T) {
+	makeSchema := func() (*tengo.Schema, *Objects) {
+		objs := &Objects{
+			Events: []*workspace.Event{
+				{Name: "cleanup", Type: "RECURRING", IntervalValue: "1", IntervalField: "DAY", Starts: "2020-01-01 00:00:00", OnCompletion: "NOT PRESERVE", Status: "ENABLED", Body: "DELETE FROM posts WHERE id < 10", Definer: "root@%"},
+			},
+			Sequences: []*workspace.Sequence{
+				{Name: "seq1", Start: "1", MinValue: "1", MaxValue: "9223372036854775806", Increment: "1", Cache: "1000"},
+			},
+			Views: []*workspace.View{
+				{Name: "user_ids", Definer: "root@%", Security: "DEFINER", CheckOption: "NONE", Algorithm: "UNDEFINED", Definition: "select `product`.`users`.`id` AS `id` from `product`.`users`"},
+			},
+			Triggers: []*workspace.Trigger{
+				{Name: "posts_bi", Table: "posts", Timing: "BEFORE", Event: "INSERT", Body: "SET NEW.id = NEW.id + 1", Definer: "root@%"},
+			},
+		}
+		return &tengo.Schema{
+			Name:      "product",
+			CharSet:   "utf8mb4",
+			Collation: "utf8mb4_general_ci",
+			Tables: []*tengo.Table{
+				{Name: "users", CreateStatement: "CREATE TABLE `users` (\n  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=123 DEFAULT CHARSET=utf8mb4"},
+				{Name: "posts", CreateStatement: "CREATE TABLE `posts` (\n  `id` int(10) unsigned NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"},
+			},
+			Routines: []*tengo.Routine{
+				{Name: "func1", Type: tengo.ObjectTypeFunc, Body: "RETURN 1", ReturnDataType: "int(11)", Definer: "root@%", SQLMode: "STRICT_TRANS_TABLES", SQLDataAccess: "CONTAINS SQL", SecurityType: "DEFINER"},
+				{Name: "proc1", Type: tengo.ObjectTypeProc, Body: "SELECT 1", Definer: "root@%", SQLMode: "STRICT_TRANS_TABLES", SQLDataAccess: "CONTAINS SQL", SecurityType: "DEFINER"},
+			},
+		}, objs
+	}
+	schema, objs := makeSchema()
+	expected := Fingerprint(schema, *objs)
+	if len(expected) != 64 {
+		t.Errorf("Expected fingerprint to be a hex-encoded SHA-256, instead found %q", expected)
+	}
+
+	// Changes which should not affect the fingerprint
+	equivalents := map[string]func(*tengo.Schema, *Objects){
+		"name": func(s *tengo.Schema, o *Objects) {
+			s.Name = "product_staging"
+		},
+		"object order": func(s *tengo.Schema, o *Objects) {
+			s.Tables[0], s.Tables[1] = s.Tables[1], s.Tables[0]
+			s.Routines[0], s.Routines[1] = s.Routines[1], s.Routines[0]
+		},
+		"auto_increment": func(s *tengo.Schema, o *Objects) {
+			s.Tables[0].CreateStatement = "CREATE TABLE `users` (\n  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=456 DEFAULT CHARSET=utf8mb4"
+		},
+		"routine metadata": func(s *tengo.Schema, o *Objects) {
+			s.Routines[0].Definer = "app@localhost"
+			s.Routines[1].SQLMode = ""
+			s.Routines[1].DatabaseCollation = "latin1_swedish_ci"
+		},
+		"object metadata": func(s *tengo.Schema, o *Objects) {
+			o.Events[0].Definer = "app@localhost"
+			o.Events[0].Starts = "2021-06-01 12:00:00"
+			o.Events[0].SQLMode = "STRICT_TRANS_TABLES"
+			o.Views[0].Definer = "app@localhost"
+			o.Triggers[0].Definer = "app@localhost"
+			o.Triggers[0].Table = "Posts"
+		},
+	}
+	for desc, change := range equivalents {
+		schema, objs := makeSchema()
+		change(schema, objs)
+		if actual := Fingerprint(schema, *objs); actual != expected {
+			t.Errorf("Expected fingerprint to be unaffected by %s change, but it changed from %s to %s", desc, expected, actual)
+		}
+	}
+
+	// Changes which should affect the fingerprint
+	differences := map[string]func(*tengo.Schema, *Objects){
+		"collation": func(s *tengo.Schema, o *Objects) {
+			s.Collation = "utf8mb4_unicode_ci"
+		},
+		"table": func(s *tengo.Schema, o *Objects) {
+			s.Tables[1].CreateStatement = "CREATE TABLE `posts` (\n  `id` bigint(20) unsigned NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+		},
+		"dropped table": func(s *tengo.Schema, o *Objects) {
+			s.Tables = s.Tables[0:1]
+		},
+		"routine body": func(s *tengo.Schema, o *Objects) {
+			s.Routines[1].Body = "SELECT 2"
+		},
+		"routine type": func(s *tengo.Schema, o *Objects) {
+			s.Routines[0].Name = "proc2"
+			s.Routines[0].Type = tengo.ObjectTypeProc
+			s.Routines[0].ReturnDataType = ""
+			s.Routines[0].Body = "SELECT 1"
+			s.Routines[1].Name = "func2"
+		},
+		"event body": func(s *tengo.Schema, o *Objects) {
+			o.Events[0].Body = "DELETE FROM posts WHERE id < 20"
+		},
+		"event interval": func(s *tengo.Schema, o *Objects) {
+			o.Events[0].IntervalField = "HOUR"
+		},
+		"sequence": func(s *tengo.Schema, o *Objects) {
+			o.Sequences[0].Increment = "2"
+		},
+		"view definition": func(s *tengo.Schema, o *Objects) {
+			o.Views[0].Definition = "select 1 AS `id`"
+		},
+		"view security": func(s *tengo.Schema, o *Objects) {
+			o.Views[0].Security = "INVOKER"
+		},
+		"trigger body": func(s *tengo.Schema, o *Objects) {
+			o.Triggers[0].Body = "SET NEW.id = NEW.id + 2"
+		},
+		"trigger timing": func(s *tengo.Schema, o *Objects) {
+			o.Triggers[0].Timing = "AFTER"
+		},
+		"dropped view": func(s *tengo.Schema, o *Objects) {
+			o.Views = nil
+		},
+		"view replaced by table": func(s *tengo.Schema, o *Objects) {
+			o.Views = nil
+			s.Tables = append(s.Tables, &tengo.Table{Name: "user_ids", CreateStatement: "CREATE TABLE `user_ids` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"})
+		},
+	}
+	for desc, change := range differences {
+		schema, objs := makeSchema()
+		change(schema, objs)
+		if actual := Fingerprint(schema, *objs); actual == expected {
+			t.Errorf("Expected fingerprint to be affected by %s change, but it remained %s", desc, actual)
+		}
+	}
+
+	if Fingerprint(nil, Objects{}) != Fingerprint(&tengo.Schema{}, Objects{}) {
+		t.Error("Expected nil schema to have same fingerprint as empty schema")
+	}
+	if Fingerprint(&tengo.Schema{}, Objects{}) == expected {
+		t.Error("Expected empty schema to have different fingerprint than non-empty schema")
+	}
+	if schema, _ := makeSchema(); Fingerprint(schema, Objects{}) == expected {
+		t.Error("Expected fingerprint to be affected by presence of events, sequences, views, and triggers")
+	}
+}
+
+func TestFilterObjects(t *testing.T) {
+	objs := Objects{
+		Events:    []*workspace.Event{{Name: "cleanup"}},
+		Sequences: []*workspace.Sequence{{Name: "seq1"}},
+		Views:     []*workspace.View{{Name: "user_ids"}, {Name: "_tmp_view"}},
+		Triggers:  []*workspace.Trigger{{Name: "users_bi", Table: "users"}, {Name: "tmp_bi", Table: "_tmp"}},
+	}
+	opts := Options{IgnoreTable: regexp.MustCompile("^_")}
+	result := FilterObjects(objs, opts)
+	if len(result.Events) != 1 || len(result.Sequences) != 1 {
+		t.Errorf("Expected events and sequences to be unaffected by ignore-table, instead found %d events, %d sequences", len(result.Events), len(result.Sequences))
+	}
+	if len(result.Views) != 1 || result.Views[0].Name != "user_ids" {
+		t.Errorf("Expected view matching ignore-table to be omitted, instead found %+v", result.Views)
+	}
+	if len(result.Triggers) != 1 || result.Triggers[0].Name != "users_bi" {
+		t.Errorf("Expected trigger on table matching ignore-table to be omitted, instead found %+v", result.Triggers)
+	}
+	if len(objs.Views) != 2 || len(objs.Triggers) != 2 {
+		t.Error("FilterObjects unexpectedly modified its input")
+	}
+
+	opts.ObjectTypes = []tengo.ObjectType{tengo.ObjectTypeTable, fs.ObjectTypeView}
+	result = FilterObjects(objs, opts)
+	if len(result.Events) != 0 || len(result.Sequences) != 0 || len(result.Triggers) != 0 || len(result.Views) != 1 {
+		t.Errorf("Expected only views to be kept based on object types, instead found %+v", result)
+	}
+}
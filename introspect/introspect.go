@@ -0,0 +1,189 @@
+// Package introspect provides a library entry point for obtaining tengo.Schema
+// values from live database instances, applying the same filtering options
+// that Skeema's commands use. It allows other Go programs to introspect
+// schemas without needing to interact with workspaces or directories.
+package introspect
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options controls introspection behavior. The zero value is usable, and
+// results in all objects in all requested schemas being returned, with one
+// schema introspected at a time.
+type Options struct {
+	IgnoreSchema *regexp.Regexp     // skip schemas with names matching this regex
+	IgnoreTable  *regexp.Regexp     // omit tables with names matching this regex
+	ObjectTypes  []tengo.ObjectType // if non-empty, only include objects of these types
+	Concurrency  int                // max schemas to introspect at once; values below 1 are treated as 1
+}
+
+// objectTypeValues lists the object types which may be used in the
+// object-types option.
+var objectTypeValues = []string{"table", "procedure", "function", "event", "sequence", "view", "trigger"}
+
+// OptionsForDir returns Options based on the configuration in an fs.Dir,
+// using its "ignore-schema", "ignore-table", and "object-types" options.
+// Concurrency is set to 1.
+func OptionsForDir(dir *fs.Dir) (Options, error) {
+	var opts Options
+	var err error
+	if opts.IgnoreSchema, err = dir.Config.GetRegexp("ignore-schema"); err != nil {
+		return Options{}, err
+	}
+	if opts.IgnoreTable, err = dir.Config.GetRegexp("ignore-table"); err != nil {
+		return Options{}, err
+	}
+	for _, value := range dir.Config.GetSlice("object-types", ',', true) {
+		var valid bool
+		for _, allowed := range objectTypeValues {
+			valid = valid || strings.ToLower(value) == allowed
+		}
+		if !valid {
+			return Options{}, fmt.Errorf("Option object-types has invalid value %q: must be one of %s", value, strings.Join(objectTypeValues, ", "))
+		}
+		opts.ObjectTypes = append(opts.ObjectTypes, tengo.ObjectType(strings.ToLower(value)))
+	}
+	opts.Concurrency = 1
+	return opts, nil
+}
+
+// IncludesType returns true if objects of type ot should be included, based on
+// opts.ObjectTypes.
+func (opts Options) IncludesType(ot tengo.ObjectType) bool {
+	if len(opts.ObjectTypes) == 0 {
+		return true
+	}
+	for _, allowed := range opts.ObjectTypes {
+		if ot == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema introspects and returns a single schema from inst, filtered according
+// to opts. If the schema does not exist, nil is returned along with a
+// sql.ErrNoRows error, matching the behavior of tengo.Instance.Schema. If the
+// schema name matches opts.IgnoreSchema, nil is returned with a nil error.
+//
+// The caller remains responsible for the lifecycle of inst, including calling
+// its CloseAll method if desired. Schema may safely be called concurrently from
+// multiple goroutines, including with the same inst. The returned
+// *tengo.Schema is not shared with other callers, and may be freely modified.
+func Schema(inst *tengo.Instance, schemaName string, opts Options) (*tengo.Schema, error) {
+	if inst == nil {
+		return nil, errors.New("No instance supplied")
+	}
+	if opts.IgnoreSchema != nil && opts.IgnoreSchema.MatchString(schemaName) {
+		return nil, nil
+	}
+	schema, err := inst.Schema(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	return FilterSchema(schema, opts), nil
+}
+
+// Schemas introspects and returns multiple schemas from inst, filtered
+// according to opts. If no schema names are supplied, all non-system schemas
+// on inst are returned. Schemas matching opts.IgnoreSchema are omitted. Up to
+// opts.Concurrency schemas are introspected at once, and the result is ordered
+// to match the order of schema names, or alphabetically by name if none were
+// supplied. If a requested schema does not exist, an error is returned.
+//
+// Like Schema, this function does not close inst's connection pools, and may
+// safely be called concurrently.
+func Schemas(inst *tengo.Instance, opts Options, schemaNames ...string) ([]*tengo.Schema, error) {
+	if inst == nil {
+		return nil, errors.New("No instance supplied")
+	}
+	if len(schemaNames) == 0 {
+		var err error
+		if schemaNames, err = inst.SchemaNames(); err != nil {
+			return nil, err
+		}
+		sort.Strings(schemaNames)
+	}
+	var names []string
+	for _, name := range schemaNames {
+		if opts.IgnoreSchema == nil || !opts.IgnoreSchema.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*tengo.Schema, len(names))
+	sem := make(chan struct{}, concurrency)
+	var g errgroup.Group
+	for n := range names {
+		n := n
+		g.Go(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			schema, err := inst.Schema(names[n])
+			if err != nil {
+				return fmt.Errorf("Unable to introspect schema %s on %s: %s", names[n], inst, err)
+			}
+			results[n] = FilterSchema(schema, opts)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SchemaFromDSN is a convenience function which connects to the database
+// server at dsn (formatted for the MySQL driver), introspects a single schema,
+// and closes all connections before returning. It is suitable for one-off use;
+// callers introspecting multiple schemas should instead manage a
+// *tengo.Instance and use Schemas.
+func SchemaFromDSN(dsn, schemaName string, opts Options) (*tengo.Schema, error) {
+	inst, err := tengo.NewInstance("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer inst.CloseAll()
+	return Schema(inst, schemaName, opts)
+}
+
+// FilterSchema removes any tables or routines from schema which should be
+// omitted based on opts. schema is modified in-place and returned. This is
+// performed automatically by Schema and Schemas, but may also be used on
+// schemas obtained from other sources, such as a workspace.
+func FilterSchema(schema *tengo.Schema, opts Options) *tengo.Schema {
+	if schema == nil {
+		return nil
+	}
+	tables := make([]*tengo.Table, 0, len(schema.Tables))
+	if opts.IncludesType(tengo.ObjectTypeTable) {
+		for _, table := range schema.Tables {
+			if opts.IgnoreTable == nil || !opts.IgnoreTable.MatchString(table.Name) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	schema.Tables = tables
+
+	routines := make([]*tengo.Routine, 0, len(schema.Routines))
+	for _, routine := range schema.Routines {
+		if opts.IncludesType(routine.Type) {
+			routines = append(routines, routine)
+		}
+	}
+	schema.Routines = routines
+	return schema
+}
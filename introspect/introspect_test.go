@@ -0,0 +1,112 @@
+package introspect
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+func TestFilterSchema(t *testing.T) {
+	makeSchema := func() *tengo.Schema {
+		return &tengo.Schema{
+			Name: "product",
+			Tables: []*tengo.Table{
+				{Name: "users"},
+				{Name: "posts"},
+				{Name: "_users_new"},
+			},
+			Routines: []*tengo.Routine{
+				{Name: "func1", Type: tengo.ObjectTypeFunc},
+				{Name: "proc1", Type: tengo.ObjectTypeProc},
+			},
+		}
+	}
+
+	if schema := FilterSchema(makeSchema(), Options{}); len(schema.Tables) != 3 || len(schema.Routines) != 2 {
+		t.Errorf("Expected zero-value Options to keep all objects, instead found %d tables, %d routines", len(schema.Tables), len(schema.Routines))
+	}
+
+	opts := Options{IgnoreTable: regexp.MustCompile("^_")}
+	if schema := FilterSchema(makeSchema(), opts); len(schema.Tables) != 2 || schema.HasTable("_users_new") || len(schema.Routines) != 2 {
+		t.Errorf("Unexpected result from FilterSchema with IgnoreTable: %+v", schema)
+	}
+
+	opts.ObjectTypes = []tengo.ObjectType{tengo.ObjectTypeProc}
+	if schema := FilterSchema(makeSchema(), opts); len(schema.Tables) != 0 || len(schema.Routines) != 1 || schema.Routines[0].Name != "proc1" {
+		t.Errorf("Unexpected result from FilterSchema with ObjectTypes: %+v", schema)
+	}
+
+	opts.ObjectTypes = []tengo.ObjectType{tengo.ObjectTypeTable, tengo.ObjectTypeFunc}
+	if schema := FilterSchema(makeSchema(), opts); len(schema.Tables) != 2 || len(schema.Routines) != 1 || schema.Routines[0].Name != "func1" {
+		t.Errorf("Unexpected result from FilterSchema with ObjectTypes: %+v", schema)
+	}
+
+	if FilterSchema(nil, opts) != nil {
+		t.Error("Expected FilterSchema(nil) to return nil")
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	if _, err := Schema(nil, "product", Options{}); err == nil {
+		t.Error("Expected error from Schema with nil instance, but err was nil")
+	}
+	if _, err := Schemas(nil, Options{}); err == nil {
+		t.Error("Expected error from Schemas with nil instance, but err was nil")
+	}
+
+	// Ignored schemas should not require any connection
+	inst, err := tengo.NewInstance("mysql", "root:fakepw@tcp(127.0.0.1:1)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %v", err)
+	}
+	opts := Options{IgnoreSchema: regexp.MustCompile("^prod")}
+	if schema, err := Schema(inst, "product", opts); schema != nil || err != nil {
+		t.Errorf("Expected Schema to return nil, nil for ignored schema; instead found %v, %v", schema, err)
+	}
+	if schemas, err := Schemas(inst, opts, "product", "production"); len(schemas) != 0 || err != nil {
+		t.Errorf("Expected Schemas to return no schemas for ignored schemas; instead found %v, %v", schemas, err)
+	}
+}
+
+func TestOptionsForDir(t *testing.T) {
+	cmd := mybase.NewCommand("introspecttest", "", "", nil)
+	cmd.AddArg("environment", "production", false)
+	util.AddGlobalOptions(cmd)
+	getDir := func(cliArgs string) *fs.Dir {
+		t.Helper()
+		cfg := mybase.ParseFakeCLI(t, cmd, "introspecttest "+cliArgs)
+		dir, err := fs.ParseDir("../testdata/golden/init/mydb/product", cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseDir: %v", err)
+		}
+		return dir
+	}
+
+	opts, err := OptionsForDir(getDir("--ignore-table='^_' --ignore-schema=^test"))
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %v", err)
+	}
+	if opts.IgnoreTable == nil || !opts.IgnoreTable.MatchString("_foo") || opts.IgnoreSchema == nil || !opts.IgnoreSchema.MatchString("test1") || opts.Concurrency != 1 {
+		t.Errorf("Unexpected result from OptionsForDir: %+v", opts)
+	}
+	if _, err := OptionsForDir(getDir("--ignore-table='+'")); err == nil {
+		t.Error("Expected error from OptionsForDir with invalid regex, but err was nil")
+	}
+
+	if opts, err = OptionsForDir(getDir("--object-types=Table,procedure")); err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %v", err)
+	}
+	if !opts.IncludesType(tengo.ObjectTypeTable) || !opts.IncludesType(tengo.ObjectTypeProc) || opts.IncludesType(tengo.ObjectTypeFunc) || opts.IncludesType(fs.ObjectTypeEvent) {
+		t.Errorf("Unexpected ObjectTypes from OptionsForDir: %v", opts.ObjectTypes)
+	}
+	if _, err := OptionsForDir(getDir("--object-types=table,user")); err == nil {
+		t.Error("Expected error from OptionsForDir with invalid object type, but err was nil")
+	}
+	if opts, err = OptionsForDir(getDir("")); err != nil || !opts.IncludesType(fs.ObjectTypeEvent) {
+		t.Errorf("Expected all object types to be included by default, instead found %v, %v", opts.ObjectTypes, err)
+	}
+}
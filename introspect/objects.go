@@ -0,0 +1,99 @@
+package introspect
+
+import (
+	"errors"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// Objects holds the events, sequences, views, and triggers of a schema. tengo
+// does not support these object types, so they are introspected separately
+// from the tengo.Schema.
+type Objects struct {
+	Events    []*workspace.Event
+	Sequences []*workspace.Sequence
+	Views     []*workspace.View
+	Triggers  []*workspace.Trigger
+}
+
+// SchemaObjects introspects and returns the events, sequences, views, and
+// triggers of a single schema on inst, filtered according to opts. Sequences
+// are only introspected on flavors which support them. The schema must already
+// exist.
+func SchemaObjects(inst *tengo.Instance, schemaName string, opts Options) (objs Objects, err error) {
+	if inst == nil {
+		return objs, errors.New("No instance supplied")
+	}
+	db, err := inst.Connect(schemaName, "")
+	if err != nil {
+		return objs, err
+	}
+	if opts.IncludesType(fs.ObjectTypeEvent) {
+		if objs.Events, err = workspace.IntrospectEvents(db); err != nil {
+			return Objects{}, err
+		}
+	}
+	if opts.IncludesType(fs.ObjectTypeSequence) && workspace.SupportsSequences(inst.Flavor()) {
+		if objs.Sequences, err = workspace.IntrospectSequences(db); err != nil {
+			return Objects{}, err
+		}
+	}
+	if opts.IncludesType(fs.ObjectTypeView) {
+		if objs.Views, err = workspace.IntrospectViews(db); err != nil {
+			return Objects{}, err
+		}
+	}
+	if opts.IncludesType(fs.ObjectTypeTrigger) {
+		if objs.Triggers, err = workspace.IntrospectTriggers(db); err != nil {
+			return Objects{}, err
+		}
+	}
+	return FilterObjects(objs, opts), nil
+}
+
+// WorkspaceObjects returns the events, sequences, views, and triggers of
+// wsSchema, filtered according to opts. A nil wsSchema results in no objects.
+func WorkspaceObjects(wsSchema *workspace.Schema, opts Options) Objects {
+	if wsSchema == nil {
+		return Objects{}
+	}
+	return FilterObjects(Objects{
+		Events:    wsSchema.Events,
+		Sequences: wsSchema.Sequences,
+		Views:     wsSchema.Views,
+		Triggers:  wsSchema.Triggers,
+	}, opts)
+}
+
+// FilterObjects returns a copy of objs, omitting any objects which should be
+// excluded based on opts. Views share a namespace with tables, so views with
+// names matching opts.IgnoreTable are omitted, as are triggers on tables with
+// matching names. The slices of objs are not modified.
+func FilterObjects(objs Objects, opts Options) (result Objects) {
+	ignored := func(name string) bool {
+		return opts.IgnoreTable != nil && opts.IgnoreTable.MatchString(name)
+	}
+	if opts.IncludesType(fs.ObjectTypeEvent) {
+		result.Events = objs.Events
+	}
+	if opts.IncludesType(fs.ObjectTypeSequence) {
+		result.Sequences = objs.Sequences
+	}
+	if opts.IncludesType(fs.ObjectTypeView) {
+		for _, view := range objs.Views {
+			if !ignored(view.Name) {
+				result.Views = append(result.Views, view)
+			}
+		}
+	}
+	if opts.IncludesType(fs.ObjectTypeTrigger) {
+		for _, trig := range objs.Triggers {
+			if !ignored(trig.Table) {
+				result.Triggers = append(result.Triggers, trig)
+			}
+		}
+	}
+	return result
+}
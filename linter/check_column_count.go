@@ -0,0 +1,41 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	rule := Rule{
+		CheckerFunc:     TableBinaryChecker(columnCountChecker),
+		Name:            "column-count",
+		Description:     "Flag tables with more columns than permitted by --max-columns",
+		DefaultSeverity: SeverityIgnore,
+	}
+	rule.RelatedLimitOption(
+		"max-columns",
+		"100",
+		"Maximum number of columns per table for --lint-column-count, optionally followed by table=limit overrides",
+	)
+	RegisterRule(rule)
+}
+
+func columnCountChecker(table *tengo.Table, createStatement string, _ *tengo.Schema, opts Options) *Note {
+	limit := opts.Limit("column-count", table.Name)
+	if limit == 0 || len(table.Columns) <= limit {
+		return nil
+	}
+	// Point at the first column beyond the limit
+	re := regexp.MustCompile(fmt.Sprintf("(?m)^\\s*`?%s(?:`|\\s)", regexp.QuoteMeta(table.Columns[limit].Name)))
+	message := fmt.Sprintf(
+		"Table %s has %d columns, which exceeds the limit of %d configured in option max-columns. Very wide tables are often a sign that some columns should be moved to a separate table, and may approach MySQL's row size limits.",
+		table.Name, len(table.Columns), limit,
+	)
+	return &Note{
+		LineOffset: FindFirstLineOffset(re, createStatement),
+		Summary:    "Too many columns",
+		Message:    message,
+	}
+}
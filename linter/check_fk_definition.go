@@ -0,0 +1,103 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	RegisterRule(Rule{
+		CheckerFunc:     TableChecker(fkDefinitionChecker),
+		Name:            "fk-definition",
+		Description:     "Flag foreign keys referencing unindexed parent columns, or using reference actions incompatible with their columns",
+		DefaultSeverity: SeverityError,
+	})
+}
+
+// fkDefinitionChecker examines each foreign key of table for problems which
+// the database server would otherwise only report opaquely, if at all. Parent
+// tables are only examined if they exist in the same schema.
+func fkDefinitionChecker(table *tengo.Table, createStatement string, schema *tengo.Schema, _ Options) []Note {
+	results := make([]Note, 0)
+	columns := table.ColumnsByName()
+	for _, fk := range table.ForeignKeys {
+		var reasons []string
+		for _, rule := range []struct{ event, action string }{{"DELETE", fk.DeleteRule}, {"UPDATE", fk.UpdateRule}} {
+			if rule.action == "SET NULL" {
+				for _, colName := range fk.ColumnNames {
+					if col := columns[colName]; col != nil && !col.Nullable {
+						reasons = append(reasons, fmt.Sprintf("uses ON %s SET NULL, but column %s is NOT NULL", rule.event, colName))
+					}
+				}
+			} else if rule.action == "SET DEFAULT" && strings.EqualFold(table.Engine, "InnoDB") {
+				reasons = append(reasons, fmt.Sprintf("uses ON %s SET DEFAULT, which is not supported by InnoDB", rule.event))
+			}
+		}
+		if fk.ReferencedSchemaName == "" || fk.ReferencedSchemaName == schema.Name {
+			if parent := schema.Table(fk.ReferencedTableName); parent != nil {
+				reasons = append(reasons, fkParentProblems(fk, parent)...)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		re := regexp.MustCompile(fmt.Sprintf("(?i)constraint\\s+`?%s(?:`|\\s)", regexp.QuoteMeta(fk.Name)))
+		results = append(results, Note{
+			LineOffset: FindFirstLineOffset(re, createStatement),
+			Summary:    "Invalid foreign key definition",
+			Message:    fmt.Sprintf("Foreign key %s of table %s %s.", fk.Name, table.Name, strings.Join(reasons, ", and ")),
+		})
+	}
+	return results
+}
+
+// fkParentProblems returns descriptions of any problems with the parent side
+// of fk: referenced columns must exist, and must be the leftmost columns (in
+// the same order, without prefix lengths) of some index in parent.
+func fkParentProblems(fk *tengo.ForeignKey, parent *tengo.Table) []string {
+	parentColumns := parent.ColumnsByName()
+	var missing []string
+	for _, colName := range fk.ReferencedColumnNames {
+		if parentColumns[colName] == nil {
+			missing = append(missing, colName)
+		}
+	}
+	if len(missing) > 0 {
+		return []string{fmt.Sprintf("references nonexistent column%s %s in parent table %s", pluralSuffix(len(missing)), strings.Join(missing, ", "), parent.Name)}
+	}
+
+	indexes := parent.SecondaryIndexes
+	if parent.PrimaryKey != nil {
+		indexes = append([]*tengo.Index{parent.PrimaryKey}, indexes...)
+	}
+	for _, idx := range indexes {
+		if fkUsableIndex(fk.ReferencedColumnNames, idx) {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("references (%s) in parent table %s, but the parent table has no index beginning with these columns", strings.Join(fk.ReferencedColumnNames, ", "), parent.Name)}
+}
+
+// fkUsableIndex returns true if idx can be used by the parent side of a foreign
+// key referencing colNames.
+func fkUsableIndex(colNames []string, idx *tengo.Index) bool {
+	if len(idx.Parts) < len(colNames) {
+		return false
+	}
+	for n, colName := range colNames {
+		if idx.Parts[n].ColumnName != colName || idx.Parts[n].PrefixLength > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func pluralSuffix(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
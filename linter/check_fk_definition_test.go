@@ -0,0 +1,93 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestFKDefinitionChecker(t *testing.T) {
+	parent := &tengo.Table{
+		Name:   "parent",
+		Engine: "InnoDB",
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(10) unsigned"},
+			{Name: "code", TypeInDB: "varchar(20)", Nullable: true},
+			{Name: "name", TypeInDB: "varchar(40)", Nullable: true},
+		},
+		PrimaryKey: &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "id"}}, PrimaryKey: true, Unique: true},
+		SecondaryIndexes: []*tengo.Index{
+			{Name: "code_name", Parts: []tengo.IndexPart{{ColumnName: "code"}, {ColumnName: "name"}}},
+			{Name: "name_prefix", Parts: []tengo.IndexPart{{ColumnName: "name", PrefixLength: 10}}},
+		},
+	}
+	child := &tengo.Table{
+		Name:   "child",
+		Engine: "InnoDB",
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(10) unsigned"},
+			{Name: "parent_id", TypeInDB: "int(10) unsigned"},
+			{Name: "parent_code", TypeInDB: "varchar(20)", Nullable: true},
+			{Name: "parent_name", TypeInDB: "varchar(40)", Nullable: true},
+		},
+	}
+	schema := &tengo.Schema{Name: "product", Tables: []*tengo.Table{parent, child}}
+	makeFK := func(name string, cols, refCols []string, updateRule, deleteRule string) *tengo.ForeignKey {
+		return &tengo.ForeignKey{
+			Name:                  name,
+			ColumnNames:           cols,
+			ReferencedTableName:   "parent",
+			ReferencedColumnNames: refCols,
+			UpdateRule:            updateRule,
+			DeleteRule:            deleteRule,
+		}
+	}
+
+	cases := []struct {
+		fk       *tengo.ForeignKey
+		expected string // substring of expected message, or empty if no note expected
+	}{
+		{makeFK("valid_pk", []string{"parent_id"}, []string{"id"}, "RESTRICT", "CASCADE"), ""},
+		{makeFK("valid_prefix", []string{"parent_code"}, []string{"code"}, "CASCADE", "SET NULL"), ""},
+		{makeFK("valid_multi", []string{"parent_code", "parent_name"}, []string{"code", "name"}, "RESTRICT", "RESTRICT"), ""},
+		{makeFK("setnull_delete", []string{"parent_id"}, []string{"id"}, "RESTRICT", "SET NULL"), "uses ON DELETE SET NULL, but column parent_id is NOT NULL"},
+		{makeFK("setnull_update", []string{"parent_id"}, []string{"id"}, "SET NULL", "RESTRICT"), "uses ON UPDATE SET NULL, but column parent_id is NOT NULL"},
+		{makeFK("setdefault", []string{"parent_code"}, []string{"code"}, "RESTRICT", "SET DEFAULT"), "uses ON DELETE SET DEFAULT, which is not supported by InnoDB"},
+		{makeFK("unindexed", []string{"parent_name"}, []string{"name"}, "RESTRICT", "RESTRICT"), "has no index beginning with these columns"},
+		{makeFK("wrongorder", []string{"parent_name", "parent_code"}, []string{"name", "code"}, "RESTRICT", "RESTRICT"), "has no index beginning with these columns"},
+		{makeFK("nonexistent", []string{"parent_name"}, []string{"nope"}, "RESTRICT", "RESTRICT"), "references nonexistent column nope in parent table parent"},
+		{makeFK("multiple", []string{"parent_id"}, []string{"nope"}, "RESTRICT", "SET NULL"), "is NOT NULL, and references nonexistent column"},
+	}
+	for _, c := range cases {
+		child.ForeignKeys = []*tengo.ForeignKey{c.fk}
+		createStatement := "CREATE TABLE child (\n  id int unsigned NOT NULL,\n  CONSTRAINT " + c.fk.Name + " FOREIGN KEY (x) REFERENCES parent (y)\n)"
+		notes := fkDefinitionChecker(child, createStatement, schema, Options{})
+		if c.expected == "" {
+			if len(notes) > 0 {
+				t.Errorf("Expected no notes for foreign key %s, instead found %+v", c.fk.Name, notes)
+			}
+			continue
+		}
+		if len(notes) != 1 {
+			t.Errorf("Expected 1 note for foreign key %s, instead found %d", c.fk.Name, len(notes))
+		} else if !strings.Contains(notes[0].Message, c.expected) || !strings.Contains(notes[0].Message, c.fk.Name) {
+			t.Errorf("Note for foreign key %s does not contain expected text %q: %s", c.fk.Name, c.expected, notes[0].Message)
+		} else if notes[0].LineOffset != 2 {
+			t.Errorf("Expected note for foreign key %s to have line offset 2, instead found %d", c.fk.Name, notes[0].LineOffset)
+		}
+	}
+
+	// Parent tables in other schemas, or not present in this one, cannot be
+	// checked
+	child.ForeignKeys = []*tengo.ForeignKey{makeFK("otherschema", []string{"parent_name"}, []string{"name"}, "RESTRICT", "RESTRICT")}
+	child.ForeignKeys[0].ReferencedSchemaName = "other"
+	if notes := fkDefinitionChecker(child, "", schema, Options{}); len(notes) > 0 {
+		t.Errorf("Expected no notes for foreign key to another schema, instead found %+v", notes)
+	}
+	child.ForeignKeys[0].ReferencedSchemaName = ""
+	child.ForeignKeys[0].ReferencedTableName = "missing"
+	if notes := fkDefinitionChecker(child, "", schema, Options{}); len(notes) > 0 {
+		t.Errorf("Expected no notes for foreign key to missing table, instead found %+v", notes)
+	}
+}
@@ -0,0 +1,39 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	RegisterRule(Rule{
+		CheckerFunc:     TableChecker(fkParentChecker),
+		Name:            "fk-parent",
+		Description:     "Flag foreign keys referencing parent tables which do not exist in the same schema",
+		DefaultSeverity: SeverityWarning,
+	})
+}
+
+// fkParentChecker flags each foreign key of table whose parent table should be
+// in the same schema, but is not defined there. Since the workspace disables
+// foreign_key_checks, such foreign keys are otherwise created without error.
+// Foreign keys referencing other schemas are not checked.
+func fkParentChecker(table *tengo.Table, createStatement string, schema *tengo.Schema, _ Options) []Note {
+	results := make([]Note, 0)
+	for _, fk := range table.ForeignKeys {
+		if fk.ReferencedSchemaName != "" && fk.ReferencedSchemaName != schema.Name {
+			continue
+		} else if schema.Table(fk.ReferencedTableName) != nil {
+			continue
+		}
+		re := regexp.MustCompile(fmt.Sprintf("(?i)references\\s+(?:`?\\w+`?\\.)?`?%s(?:`|\\s|\\()", regexp.QuoteMeta(fk.ReferencedTableName)))
+		results = append(results, Note{
+			LineOffset: FindFirstLineOffset(re, createStatement),
+			Summary:    "Foreign key references nonexistent table",
+			Message:    fmt.Sprintf("Foreign key %s of table %s references parent table %s, which does not exist in this schema.", fk.Name, table.Name, fk.ReferencedTableName),
+		})
+	}
+	return results
+}
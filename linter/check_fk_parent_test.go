@@ -0,0 +1,43 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestFKParentChecker(t *testing.T) {
+	parent := &tengo.Table{Name: "parent"}
+	child := &tengo.Table{
+		Name: "child",
+		ForeignKeys: []*tengo.ForeignKey{
+			{Name: "parent_fk", ReferencedTableName: "parent"},
+			{Name: "same_schema_fk", ReferencedSchemaName: "product", ReferencedTableName: "parent"},
+			{Name: "other_schema_fk", ReferencedSchemaName: "other", ReferencedTableName: "elsewhere"},
+			{Name: "missing_fk", ReferencedTableName: "missing"},
+			{Name: "missing_same_schema_fk", ReferencedSchemaName: "product", ReferencedTableName: "also_missing"},
+		},
+	}
+	schema := &tengo.Schema{Name: "product", Tables: []*tengo.Table{parent, child}}
+	createStatement := strings.Join([]string{
+		"CREATE TABLE child (",
+		"  id int unsigned NOT NULL,",
+		"  CONSTRAINT parent_fk FOREIGN KEY (x) REFERENCES parent (y),",
+		"  CONSTRAINT same_schema_fk FOREIGN KEY (x) REFERENCES product.parent (y),",
+		"  CONSTRAINT other_schema_fk FOREIGN KEY (x) REFERENCES other.elsewhere (y),",
+		"  CONSTRAINT missing_fk FOREIGN KEY (x) REFERENCES `missing` (`y`),",
+		"  CONSTRAINT missing_same_schema_fk FOREIGN KEY (x) REFERENCES product.also_missing(y)",
+		")",
+	}, "\n")
+	notes := fkParentChecker(child, createStatement, schema, Options{})
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notes, instead found %d: %+v", len(notes), notes)
+	}
+	if !strings.Contains(notes[0].Message, "missing_fk") || notes[0].LineOffset != 5 {
+		t.Errorf("Unexpected note for missing_fk: %+v", notes[0])
+	}
+	if !strings.Contains(notes[1].Message, "missing_same_schema_fk") || notes[1].LineOffset != 6 {
+		t.Errorf("Unexpected note for missing_same_schema_fk: %+v", notes[1])
+	}
+}
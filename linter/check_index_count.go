@@ -0,0 +1,41 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	rule := Rule{
+		CheckerFunc:     TableBinaryChecker(indexCountChecker),
+		Name:            "index-count",
+		Description:     "Flag tables with more secondary indexes than permitted by --max-indexes",
+		DefaultSeverity: SeverityIgnore,
+	}
+	rule.RelatedLimitOption(
+		"max-indexes",
+		"20",
+		"Maximum number of secondary indexes per table for --lint-index-count, optionally followed by table=limit overrides",
+	)
+	RegisterRule(rule)
+}
+
+func indexCountChecker(table *tengo.Table, createStatement string, _ *tengo.Schema, opts Options) *Note {
+	limit := opts.Limit("index-count", table.Name)
+	if limit == 0 || len(table.SecondaryIndexes) <= limit {
+		return nil
+	}
+	// Point at the first index beyond the limit
+	re := regexp.MustCompile(fmt.Sprintf("(?i)(key|index)\\s+`?%s(?:`|\\s)", regexp.QuoteMeta(table.SecondaryIndexes[limit].Name)))
+	message := fmt.Sprintf(
+		"Table %s has %d secondary indexes, which exceeds the limit of %d configured in option max-indexes. Each index adds overhead to every write, and consumes additional disk space and memory.",
+		table.Name, len(table.SecondaryIndexes), limit,
+	)
+	return &Note{
+		LineOffset: FindFirstLineOffset(re, createStatement),
+		Summary:    "Too many indexes",
+		Message:    message,
+	}
+}
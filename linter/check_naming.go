@@ -0,0 +1,124 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	// This rule uses a customized RelatedOption and ConfigFunc, since each
+	// pattern is associated with an object type and an optional severity
+	RegisterRule(Rule{
+		CheckerFunc:     namingChecker{},
+		Name:            "naming",
+		Description:     "Flag object names which do not match the patterns configured in --naming-conventions",
+		DefaultSeverity: SeverityIgnore,
+		RelatedOption:   mybase.StringOption("naming-conventions", 0, "", "List of object-type=regex naming patterns for --lint-naming, e.g. table=^[a-z0-9_]+$,index=^idx_"),
+		ConfigFunc:      RuleConfigFunc(namingConfiger),
+	})
+}
+
+// namingObjectTypes lists the object types which may be used in the
+// naming-conventions option, in the order that they are checked.
+var namingObjectTypes = []string{"table", "column", "index", "foreign-key", "procedure", "function"}
+
+// namingPattern is a single entry of the naming-conventions option.
+type namingPattern struct {
+	re       *regexp.Regexp
+	severity Severity // if non-empty, overrides the rule's severity
+}
+
+// namingConfig maps object types to the patterns that object names of that
+// type must match.
+type namingConfig map[string][]namingPattern
+
+// namingConfiger parses the naming-conventions option. Each entry is of the
+// form type=regex or type:severity=regex.
+func namingConfiger(config *mybase.Config) interface{} {
+	values := config.GetSlice("naming-conventions", ',', true)
+	if len(values) == 0 {
+		return fmt.Errorf("With option lint-naming=%s, corresponding option naming-conventions must be non-empty", config.Get("lint-naming"))
+	}
+	nc := make(namingConfig)
+	for _, value := range values {
+		eq := strings.Index(value, "=")
+		if eq < 0 {
+			return fmt.Errorf("Option naming-conventions has invalid entry %q: must be of the form type=regex or type:severity=regex", value)
+		}
+		objType, expr := strings.ToLower(strings.TrimSpace(value[:eq])), value[eq+1:]
+		var severity Severity
+		if colon := strings.Index(objType, ":"); colon >= 0 {
+			objType, severity = strings.TrimSpace(objType[:colon]), Severity(strings.TrimSpace(objType[colon+1:]))
+			if severity != SeverityWarning && severity != SeverityError {
+				return fmt.Errorf("Option naming-conventions has invalid severity %q in entry %q: must be \"warning\" or \"error\"", severity, value)
+			}
+		}
+		var validType bool
+		for _, t := range namingObjectTypes {
+			validType = validType || t == objType
+		}
+		if !validType {
+			return fmt.Errorf("Option naming-conventions has invalid object type %q in entry %q: must be one of %s", objType, value, strings.Join(namingObjectTypes, ", "))
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("Option naming-conventions has invalid regular expression in entry %q: %s", value, err)
+		}
+		nc[objType] = append(nc[objType], namingPattern{re: re, severity: severity})
+	}
+	return nc
+}
+
+// namingChecker satisfies the ObjectChecker interface directly, since it
+// checks both tables and routines.
+type namingChecker struct{}
+
+// CheckObject returns a note for each name in object, or any of its columns,
+// indexes, or foreign keys, which does not match a configured pattern for its
+// object type.
+func (namingChecker) CheckObject(object interface{}, createStatement string, _ *tengo.Schema, opts Options) []Note {
+	nc := opts.RuleConfig["naming"].(namingConfig)
+	results := make([]Note, 0)
+	check := func(objType, name, description string, re *regexp.Regexp) {
+		for _, pattern := range nc[objType] {
+			if pattern.re.MatchString(name) {
+				continue
+			}
+			lineOffset := 0
+			if re != nil {
+				lineOffset = FindFirstLineOffset(re, createStatement)
+			}
+			results = append(results, Note{
+				LineOffset: lineOffset,
+				Summary:    "Name does not match naming convention",
+				Message:    fmt.Sprintf("%s does not match pattern %s for %s names, as configured in option naming-conventions.", description, pattern.re, objType),
+				Severity:   pattern.severity,
+			})
+		}
+	}
+
+	switch object := object.(type) {
+	case *tengo.Table:
+		check("table", object.Name, fmt.Sprintf("Table %s", object.Name), nil)
+		for _, col := range object.Columns {
+			re := regexp.MustCompile(fmt.Sprintf("`%s`", regexp.QuoteMeta(col.Name)))
+			check("column", col.Name, fmt.Sprintf("Column %s of table %s", col.Name, object.Name), re)
+		}
+		for _, idx := range object.SecondaryIndexes {
+			re := regexp.MustCompile(fmt.Sprintf("(?i)(key|index)\\s+`?%s(?:`|\\s)", regexp.QuoteMeta(idx.Name)))
+			check("index", idx.Name, fmt.Sprintf("Index %s of table %s", idx.Name, object.Name), re)
+		}
+		for _, fk := range object.ForeignKeys {
+			re := regexp.MustCompile(fmt.Sprintf("(?i)constraint\\s+`?%s(?:`|\\s)", regexp.QuoteMeta(fk.Name)))
+			check("foreign-key", fk.Name, fmt.Sprintf("Foreign key %s of table %s", fk.Name, object.Name), re)
+		}
+	case *tengo.Routine:
+		objType := string(object.Type)
+		check(objType, object.Name, fmt.Sprintf("%s %s", strings.Title(objType), object.Name), nil)
+	}
+	return results
+}
@@ -0,0 +1,96 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestNamingChecker(t *testing.T) {
+	dir := getDir(t, "testdata/validcfg", "--lint-naming=warning", `--naming-conventions='table=^[a-z0-9_]+$,index:error=^idx_,foreign-key=^fk_,column=^[a-z0-9_]+$,procedure=^sp_'`)
+	opts, err := OptionsForDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %v", err)
+	}
+
+	table := &tengo.Table{
+		Name: "Order_Items",
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(10) unsigned"},
+			{Name: "orderId", TypeInDB: "int(10) unsigned"},
+			{Name: "product_id", TypeInDB: "int(10) unsigned"},
+		},
+		SecondaryIndexes: []*tengo.Index{
+			{Name: "idx_product", Parts: []tengo.IndexPart{{ColumnName: "product_id"}}},
+			{Name: "order", Parts: []tengo.IndexPart{{ColumnName: "orderId"}}},
+		},
+		ForeignKeys: []*tengo.ForeignKey{
+			{Name: "fk_product", ColumnNames: []string{"product_id"}, ReferencedTableName: "products", ReferencedColumnNames: []string{"id"}},
+			{Name: "order_items_ibfk_1", ColumnNames: []string{"orderId"}, ReferencedTableName: "orders", ReferencedColumnNames: []string{"id"}},
+		},
+	}
+	createStatement := strings.Join([]string{
+		"CREATE TABLE `Order_Items` (",
+		"  `id` int unsigned NOT NULL,",
+		"  `orderId` int unsigned NOT NULL,",
+		"  `product_id` int unsigned NOT NULL,",
+		"  KEY `idx_product` (`product_id`),",
+		"  KEY `order` (`orderId`),",
+		"  CONSTRAINT `fk_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`),",
+		"  CONSTRAINT `order_items_ibfk_1` FOREIGN KEY (`orderId`) REFERENCES `orders` (`id`)",
+		")",
+	}, "\n")
+	expected := []struct {
+		lineOffset int
+		severity   Severity
+		message    string
+	}{
+		{0, "", "Table Order_Items does not match pattern ^[a-z0-9_]+$ for table names"},
+		{2, "", "Column orderId of table Order_Items does not match pattern ^[a-z0-9_]+$ for column names"},
+		{5, SeverityError, "Index order of table Order_Items does not match pattern ^idx_ for index names"},
+		{7, "", "Foreign key order_items_ibfk_1 of table Order_Items does not match pattern ^fk_ for foreign-key names"},
+	}
+	notes := namingChecker{}.CheckObject(table, createStatement, nil, opts)
+	if len(notes) != len(expected) {
+		t.Fatalf("Expected %d notes, instead found %d: %+v", len(expected), len(notes), notes)
+	}
+	for n, note := range notes {
+		if !strings.Contains(note.Message, expected[n].message) {
+			t.Errorf("Note %d message %q does not contain expected text %q", n, note.Message, expected[n].message)
+		}
+		if note.LineOffset != expected[n].lineOffset {
+			t.Errorf("Note %d: expected line offset %d, instead found %d", n, expected[n].lineOffset, note.LineOffset)
+		}
+		if note.Severity != expected[n].severity {
+			t.Errorf("Note %d: expected severity %q, instead found %q", n, expected[n].severity, note.Severity)
+		}
+	}
+
+	// Routines: procedures configured with a pattern, functions without
+	proc := &tengo.Routine{Name: "refresh_totals", Type: tengo.ObjectTypeProc}
+	if notes := (namingChecker{}).CheckObject(proc, "CREATE PROCEDURE refresh_totals() SELECT 1", nil, opts); len(notes) != 1 {
+		t.Errorf("Expected 1 note for procedure, instead found %d", len(notes))
+	} else if !strings.HasPrefix(notes[0].Message, "Procedure refresh_totals does not match pattern ^sp_") {
+		t.Errorf("Unexpected message for procedure: %s", notes[0].Message)
+	}
+	proc.Name = "sp_refresh_totals"
+	if notes := (namingChecker{}).CheckObject(proc, "CREATE PROCEDURE sp_refresh_totals() SELECT 1", nil, opts); len(notes) != 0 {
+		t.Errorf("Expected no notes for procedure, instead found %+v", notes)
+	}
+	fn := &tengo.Routine{Name: "AnyName", Type: tengo.ObjectTypeFunc}
+	if notes := (namingChecker{}).CheckObject(fn, "CREATE FUNCTION AnyName() RETURNS int RETURN 1", nil, opts); len(notes) != 0 {
+		t.Errorf("Expected no notes for function, instead found %+v", notes)
+	}
+
+	// Conforming table
+	table = &tengo.Table{
+		Name:             "order_items",
+		Columns:          []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}, {Name: "order_id", TypeInDB: "int(10) unsigned"}},
+		SecondaryIndexes: []*tengo.Index{{Name: "idx_order", Parts: []tengo.IndexPart{{ColumnName: "order_id"}}}},
+		ForeignKeys:      []*tengo.ForeignKey{{Name: "fk_order", ColumnNames: []string{"order_id"}, ReferencedTableName: "orders", ReferencedColumnNames: []string{"id"}}},
+	}
+	if notes := (namingChecker{}).CheckObject(table, "CREATE TABLE order_items (...)", nil, opts); len(notes) != 0 {
+		t.Errorf("Expected no notes for conforming table, instead found %+v", notes)
+	}
+}
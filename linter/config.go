@@ -63,6 +63,24 @@ func (opts *Options) IsAllowed(ruleName, value string) bool {
 	return false
 }
 
+// tableLimits stores the configuration created by Rule.RelatedLimitOption.
+type tableLimits struct {
+	defaultLimit int
+	perTable     map[string]int
+}
+
+// Limit returns the configured limit for the given rule and table, or 0 if the
+// rule should not limit the table at all. This method can only be used by
+// rules that use RelatedLimitOption to configure their related option and
+// config func.
+func (opts *Options) Limit(ruleName, tableName string) int {
+	limits := opts.RuleConfig[ruleName].(tableLimits)
+	if limit, ok := limits.perTable[tableName]; ok {
+		return limit
+	}
+	return limits.defaultLimit
+}
+
 // OnlyKeys specifies a list of tengo.ObjectKeys that the linter should
 // operate on. (Objects with keys NOT in this list will be skipped.)
 // Repeated calls to this method add to the existing whitelist.
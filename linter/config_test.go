@@ -20,6 +20,8 @@ func TestOptionsForDir(t *testing.T) {
 		}
 		expectedSeverity["pk"] = SeverityError             // see testdata/validcfg/.skeema
 		expectedSeverity["display-width"] = SeverityIgnore // ditto
+		expectedSeverity["column-count"] = SeverityWarning // ditto
+		expectedSeverity["index-count"] = SeverityWarning  // ditto
 		if !reflect.DeepEqual(opts.RuleSeverity, expectedSeverity) {
 			t.Errorf("RuleSeverity is %v, does not match expectation %v", opts.RuleSeverity, expectedSeverity)
 		}
@@ -52,6 +54,21 @@ func TestOptionsForDir(t *testing.T) {
 		if !reflect.DeepEqual(expectedDefinerConfig, actualDefinerConfig) {
 			t.Errorf("definerConfig did not match expectation")
 		}
+
+		expectedLimits := []struct {
+			ruleName  string
+			tableName string
+			expected  int
+		}{
+			{"column-count", "dupeidx", 10},
+			{"index-count", "widetable", 6},
+			{"index-count", "dupeidx", 0},
+		}
+		for _, el := range expectedLimits {
+			if actual := opts.Limit(el.ruleName, el.tableName); actual != el.expected {
+				t.Errorf("Limit(%q, %q) returned %d, expected %d", el.ruleName, el.tableName, actual, el.expected)
+			}
+		}
 	}
 
 	// Coverage for error conditions
@@ -64,6 +81,15 @@ func TestOptionsForDir(t *testing.T) {
 		"--allow-engine=''",
 		"--lint-engine=gentle-nudge",
 		"--allow-definer=''",
+		"--max-columns=lots",
+		"--max-columns=-1",
+		"--max-indexes='dupeidx=0'",
+		"--max-indexes='10, dupeidx'",
+		"--lint-naming=warning",
+		"--lint-naming=warning --naming-conventions='^idx_'",
+		"--lint-naming=warning --naming-conventions='view=^v_'",
+		"--lint-naming=warning --naming-conventions='index:ignore=^idx_'",
+		"--lint-naming=warning --naming-conventions='table=+'",
 	}
 	confirmError := func(cliArgs string) {
 		t.Helper()
@@ -0,0 +1,50 @@
+package linter_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/linter"
+	"github.com/skeema/tengo"
+)
+
+// This example registers a custom rule, which flags tables whose names only
+// differ by case. Such tables cannot coexist on servers using
+// lower_case_table_names=1, such as MySQL on Windows or macOS. Since the rule
+// compares several tables to each other, it uses a SchemaChecker. The rule is
+// configured by a supplemental option listing table names to permit anyway.
+func ExampleRegisterRule() {
+	rule := linter.Rule{
+		CheckerFunc: linter.SchemaChecker(func(schema *tengo.Schema, opts linter.Options) []linter.SchemaNote {
+			var notes []linter.SchemaNote
+			seen := make(map[string]string)
+			for _, table := range schema.Tables {
+				lower := strings.ToLower(table.Name)
+				if other, ok := seen[lower]; ok && !opts.IsAllowed("table-name-case", table.Name) {
+					notes = append(notes, linter.SchemaNote{
+						Key: tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name},
+						Note: linter.Note{
+							Summary: "Table name differs only by case",
+							Message: fmt.Sprintf("Table %s has the same name as table %s, except for case.", table.Name, other),
+						},
+					})
+				}
+				seen[lower] = table.Name
+			}
+			return notes
+		}),
+		Name:            "table-name-case",
+		Description:     "Flag tables with names differing only by case",
+		DefaultSeverity: linter.SeverityWarning,
+	}
+	rule.RelatedListOption(
+		"allow-table-name-case",
+		"",
+		"List of table names which may differ from others only by case",
+		false,
+	)
+
+	// Typically this is done in an init function, before calling
+	// linter.AddCommandOptions
+	linter.RegisterRule(rule)
+}
@@ -3,6 +3,8 @@ package linter
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/workspace"
@@ -12,7 +14,8 @@ import (
 // CheckSchema runs all registered lint rules on objects in a workspace.Schema.
 // (This function does not operate directly on a tengo.Schema alone, because the
 // original fs.LogicalSchema is also needed, in order to generate annotations
-// corresponding to SQL statements / files / line numbers.)
+// corresponding to SQL statements / files / line numbers.) Rules using a
+// SchemaChecker are run once for the entire schema, after all other rules.
 func CheckSchema(wsSchema *workspace.Schema, opts Options) *Result {
 	result := &Result{}
 	tables := wsSchema.TablesByName()
@@ -43,7 +46,30 @@ func CheckSchema(wsSchema *workspace.Schema, opts Options) *Result {
 			r := rulesByName[ruleName]
 			output := r.CheckerFunc.CheckObject(object, stmt.Text, wsSchema.Schema, opts)
 			for _, lo := range output {
-				result.Annotate(stmt, severity, ruleName, lo)
+				if lo.Severity != "" {
+					result.Annotate(stmt, lo.Severity, ruleName, lo)
+				} else {
+					result.Annotate(stmt, severity, ruleName, lo)
+				}
+			}
+		}
+	}
+
+	// Schema-wide rules are run once, annotating the objects they identify
+	for ruleName, severity := range opts.RuleSeverity {
+		checker, ok := rulesByName[ruleName].CheckerFunc.(SchemaChecker)
+		if !ok || severity == SeverityIgnore {
+			continue
+		}
+		for _, sn := range checker(wsSchema.Schema, opts) {
+			stmt := wsSchema.LogicalSchema.Creates[sn.Key]
+			if stmt == nil || opts.shouldIgnore(sn.Key) {
+				continue
+			}
+			if sn.Severity != "" {
+				result.Annotate(stmt, sn.Severity, ruleName, sn.Note)
+			} else {
+				result.Annotate(stmt, severity, ruleName, sn.Note)
 			}
 		}
 	}
@@ -100,6 +126,28 @@ func (rc RoutineChecker) CheckObject(object interface{}, createStatement string,
 	return nil
 }
 
+// SchemaNote is a Note found by a SchemaChecker, along with the key of the
+// object that it pertains to.
+type SchemaNote struct {
+	Key tengo.ObjectKey
+	Note
+}
+
+// SchemaChecker is a function that looks for problems across an entire
+// schema, for example involving relationships between several objects. Unlike
+// other checkers, it is run only once per schema. Each returned SchemaNote is
+// annotated on the CREATE statement of the object identified by its Key; notes
+// for objects lacking a CREATE statement, or which are ignored by opts, are
+// discarded.
+type SchemaChecker func(schema *tengo.Schema, opts Options) []SchemaNote
+
+// CheckObject satisfies the ObjectChecker interface, in order for
+// SchemaChecker functions to be used in a Rule. It always returns nil, since
+// CheckSchema runs SchemaChecker functions separately.
+func (sc SchemaChecker) CheckObject(object interface{}, createStatement string, schema *tengo.Schema, opts Options) []Note {
+	return nil
+}
+
 // RuleConfigFunc is a function that performs supplemental configuration for
 // a Rule. The function can return any arbitrary value. If the return value
 // isn't an error or an untyped nil, it will be indexed in Config.
@@ -155,6 +203,60 @@ var rulesByName = map[string]*Rule{}
 // RegisterRule indexes a single Rule by name in a package-level registry.
 // Registered rules are automatically converted to Options in config.go's
 // AddCommandOptions, and are automatically tested by integration tests.
+//
+// Programs embedding this package may register their own rules in addition to
+// the built-in ones, as long as this is done before calling AddCommandOptions,
+// typically in an init function. A rule's CheckerFunc may be a TableChecker,
+// TableBinaryChecker, RoutineChecker, SchemaChecker, or any other type
+// satisfying ObjectChecker. Rule configuration is accessible to checkers via
+// Options.RuleConfig, populated by the rule's ConfigFunc.
+// This function panics if rule has no name or checker, or if another rule with
+// the same name is already registered, since this is indicative of programmer
+// error.
 func RegisterRule(rule Rule) {
+	if rule.Name == "" || rule.CheckerFunc == nil {
+		panic("Cannot register a lint rule without a Name and CheckerFunc")
+	} else if _, already := rulesByName[rule.Name]; already {
+		panic(fmt.Sprintf("Cannot register lint rule %s: another rule with this name is already registered", rule.Name))
+	}
 	rulesByName[rule.Name] = &rule
 }
+
+// RelatedLimitOption populates RelatedOption and ConfigFunc by creating a
+// supplemental option which configures a numeric limit. The option's value is
+// a comma-separated list, consisting of an integer default limit, optionally
+// followed by any number of table=limit entries to override the limit for
+// specific tables. A limit of 0 means unlimited, which permits suppressing the
+// rule for individual tables. The supplied name, defaultValue, and description
+// are used in the supplemental option.
+// This method panics if called on a Rule that already has a RelatedOption or
+// ConfigFunc, since this is indicative of programmer error.
+func (r *Rule) RelatedLimitOption(name, defaultValue, description string) {
+	if r.RelatedOption != nil || r.ConfigFunc != nil {
+		panic("Cannot call RelatedLimitOption on a rule that already has a RelatedOption or ConfigFunc")
+	}
+	r.RelatedOption = mybase.StringOption(name, 0, defaultValue, description)
+	fn := func(config *mybase.Config) interface{} {
+		limits := tableLimits{perTable: make(map[string]int)}
+		for n, value := range config.GetSlice(name, ',', true) {
+			tableName, limitStr := "", value
+			if eq := strings.LastIndex(value, "="); eq >= 0 {
+				tableName, limitStr = strings.TrimSpace(value[:eq]), strings.TrimSpace(value[eq+1:])
+			}
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil || limit < 0 || (tableName == "") != (n == 0) {
+				return fmt.Errorf(
+					"Option %s has invalid value %q: must be a non-negative integer, optionally followed by table=limit overrides, e.g. %s=%s,tablename=0",
+					name, config.Get(name), name, defaultValue,
+				)
+			}
+			if tableName == "" {
+				limits.defaultLimit = limit
+			} else {
+				limits.perTable[tableName] = limit
+			}
+		}
+		return limits
+	}
+	r.ConfigFunc = RuleConfigFunc(fn)
+}
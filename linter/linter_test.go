@@ -182,3 +182,73 @@ func forceRulesWarning(opts Options) {
 		opts.RuleSeverity[key] = SeverityWarning
 	}
 }
+
+func TestSchemaChecker(t *testing.T) {
+	// Register a rule which flags every table in the schema, apart from the
+	// first one, and use a non-default severity for one of them
+	RegisterRule(Rule{
+		CheckerFunc: SchemaChecker(func(schema *tengo.Schema, opts Options) []SchemaNote {
+			var notes []SchemaNote
+			for n, table := range schema.Tables[1:] {
+				sn := SchemaNote{
+					Key:  tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name},
+					Note: Note{Summary: "Not first", Message: table.Name + " is not the first table"},
+				}
+				if n == 0 {
+					sn.Severity = SeverityError
+				}
+				notes = append(notes, sn)
+			}
+			return notes
+		}),
+		Name:            "test-not-first",
+		Description:     "Flag all tables except the first",
+		DefaultSeverity: SeverityWarning,
+	})
+	defer delete(rulesByName, "test-not-first")
+
+	// Registering a duplicate name should panic
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected RegisterRule to panic with a duplicate name, but it did not")
+			}
+		}()
+		RegisterRule(Rule{CheckerFunc: TableChecker(hasFloatChecker), Name: "test-not-first"})
+	}()
+
+	logicalSchema := &fs.LogicalSchema{Creates: make(map[tengo.ObjectKey]*fs.Statement)}
+	schema := &tengo.Schema{Name: "product"}
+	for n, name := range []string{"a", "b", "c", "_d"} {
+		stmt := &fs.Statement{
+			File:       name + ".sql",
+			LineNo:     1,
+			Text:       "CREATE TABLE " + name + " (id int);\n",
+			Type:       fs.StatementTypeCreate,
+			ObjectType: tengo.ObjectTypeTable,
+			ObjectName: name,
+		}
+		if n < 3 { // _d has no CREATE statement, and c is ignored by IgnoreTable below
+			logicalSchema.Creates[stmt.ObjectKey()] = stmt
+		}
+		schema.Tables = append(schema.Tables, &tengo.Table{Name: name})
+	}
+	wsSchema := &workspace.Schema{Schema: schema, LogicalSchema: logicalSchema}
+	opts := Options{
+		RuleSeverity: map[string]Severity{"test-not-first": SeverityWarning},
+		IgnoreTable:  regexp.MustCompile("^c$"),
+	}
+	result := CheckSchema(wsSchema, opts)
+	if len(result.Annotations) != 1 {
+		t.Fatalf("Expected 1 annotation, instead found %d", len(result.Annotations))
+	}
+	if a := result.Annotations[0]; a.RuleName != "test-not-first" || a.Statement.ObjectName != "b" || a.Severity != SeverityError || a.Summary != "Not first" {
+		t.Errorf("Unexpected annotation: %+v", *a)
+	}
+
+	// Disabling the rule should prevent the checker from running
+	opts.RuleSeverity["test-not-first"] = SeverityIgnore
+	if result := CheckSchema(wsSchema, opts); len(result.Annotations) != 0 {
+		t.Errorf("Expected no annotations from ignored rule, instead found %d", len(result.Annotations))
+	}
+}
@@ -19,6 +19,7 @@ type Note struct {
 	LineOffset int
 	Summary    string
 	Message    string
+	Severity   Severity // if non-empty, overrides the rule's configured severity
 }
 
 // Annotation is an error, warning, or notice from linting a single SQL
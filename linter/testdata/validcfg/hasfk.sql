@@ -3,7 +3,7 @@ CREATE TABLE hasfk (
   customer_id int(10) unsigned DEFAULT NULL,
   PRIMARY KEY (id),
   KEY customer (customer_id),
-  CONSTRAINT customer_fk FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL /* annotations: has-fk */
+  CONSTRAINT customer_fk FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL /* annotations: has-fk, fk-parent */
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 CREATE TABLE hasfks (
@@ -13,6 +13,6 @@ CREATE TABLE hasfks (
   PRIMARY KEY (id),
   KEY customer (customer_id),
   KEY product (product_id),
-  FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL, /* annotations: has-fk */
-  FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+  FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL, /* annotations: has-fk, fk-parent */
+  FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE /* annotations: fk-parent */
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,23 @@
+CREATE TABLE widetable (
+  id int unsigned NOT NULL,
+  c1 int,
+  c2 int,
+  c3 int,
+  c4 int,
+  c5 int,
+  c6 int,
+  c7 int,
+  c8 int,
+  c9 int,
+  c10 int, /* annotations: column-count */
+  c11 int,
+  PRIMARY KEY (id),
+  KEY k1 (c1),
+  KEY k2 (c2),
+  KEY k3 (c3),
+  KEY k4 (c4),
+  KEY k5 (c5),
+  KEY k6 (c6),
+  KEY k7 (c7), /* annotations: index-count */
+  KEY k8 (c8)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
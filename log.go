@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/mitchellh/go-wordwrap"
@@ -64,6 +65,9 @@ func (f *customFormatter) Format(entry *log.Entry) ([]byte, error) {
 	}
 	levelText := fmt.Sprintf("[%s%s%s]%s ", startColor, levelName, endColor, spacing)
 	message := entry.Message
+	if len(entry.Data) > 0 {
+		message = fmt.Sprintf("%s %s", message, formatFields(entry.Data))
+	}
 	if f.isTerminal && f.width > 0 {
 		headerLen := 28 // length of line header, e.g. "2019-08-20 16:53:57 [INFO]  "
 		message = wordwrap.WrapString(message, uint(f.width-headerLen))
@@ -75,6 +79,21 @@ func (f *customFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// formatFields returns structured log fields as a string of space-separated
+// key=value pairs, sorted by key.
+func formatFields(fields log.Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for n, k := range keys {
+		pairs[n] = fmt.Sprintf("%s=%v", k, fields[k])
+	}
+	return strings.Join(pairs, " ")
+}
+
 func countAndNoun(n int, singular, plural string) string {
 	if n == 1 {
 		return fmt.Sprintf("1 %s", singular)
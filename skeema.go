@@ -3,10 +3,14 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"strings"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/util"
 	"github.com/skeema/skeema/workspace"
 )
@@ -50,14 +54,89 @@ func main() {
 		Exit(NewExitValue(CodeBadConfig, err.Error()))
 	}
 
+	go handleSignals()
 	err = cfg.HandleCommand()
 	workspace.Shutdown()
 	Exit(err)
 }
 
+// handleSignals waits for SIGINT or SIGTERM, and then cleans up any workspaces
+// still in use before exiting, so that temporary schemas and their locks are
+// not left behind by an interrupted command. A second signal exits immediately,
+// without waiting for the cleanup to finish.
+func handleSignals() {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	log.Warnf("Received %s signal; cleaning up workspaces before exiting", sig)
+	go func() {
+		sig := <-sigs
+		Exit(NewExitValue(CodeFatalError, "Received %s signal; exiting without completing cleanup", sig))
+	}()
+	workspace.CleanupAll()
+	workspace.Shutdown()
+	Exit(NewExitValue(CodeFatalError, "Interrupted by %s signal", sig))
+}
+
 func versionString() string {
 	if commit == "unknown" {
 		return fmt.Sprintf("%s (snapshot build from source)", version)
 	}
 	return fmt.Sprintf("%s, commit %s, released %s", version, commit, date)
 }
+
+// parseDirs returns the directories that a command should operate on, as
+// specified by the dir option: a comma-separated list of paths and/or glob
+// patterns, defaulting to the current working directory. An error is returned
+// if any pattern does not match any directories, or if any directory cannot be
+// parsed.
+func parseDirs(cfg *mybase.Config) ([]*fs.Dir, error) {
+	// Split manually rather than using cfg.GetSlice, since backslashes must be
+	// preserved for Windows paths
+	var patterns []string
+	for _, pattern := range strings.Split(cfg.Get("dir"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	dirPaths, err := fs.ExpandDirPatterns(patterns)
+	if err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	} else if len(dirPaths) == 0 {
+		dirPaths = []string{"."}
+	}
+	dirs := make([]*fs.Dir, len(dirPaths))
+	for n, dirPath := range dirPaths {
+		if dirs[n], err = fs.ParseDir(dirPath, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if len(dirs) > 1 {
+		log.Debugf("Processing %s: %s", countAndNoun(len(dirs), "directory", "directories"), strings.Join(dirPaths, ", "))
+	}
+	return dirs, nil
+}
+
+// addDirOption adds the dir option to cmd, for commands which support
+// operating on multiple directories via parseDirs.
+func addDirOption(cmd *mybase.Command) {
+	cmd.AddOption(mybase.StringOption("dir", 'd', ".", "Operate on these dirs (comma-separated paths or glob patterns) instead of the current dir"))
+}
+
+// addUTF8AliasOption adds the utf8-alias option to cmd, for commands which
+// write CREATE statements to *.sql files.
+func addUTF8AliasOption(cmd *mybase.Command) {
+	cmd.AddOption(mybase.StringOption("utf8-alias", 0, "keep", `Name to use for the utf8 character set in *.sql files (valid values: "keep", "utf8", "utf8mb3")`))
+}
+
+// utf8AliasForDir returns the value of dir's utf8-alias option, in the form
+// used by dumper.Options.UTF8Alias. An empty string is returned for "keep".
+func utf8AliasForDir(dir *fs.Dir) (string, error) {
+	value, err := dir.Config.GetEnum("utf8-alias", "keep", "utf8", "utf8mb3")
+	if err != nil {
+		return "", NewExitValue(CodeBadConfig, err.Error())
+	} else if value == "keep" {
+		return "", nil
+	}
+	return value, nil
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/introspect"
+	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
 
@@ -31,6 +35,16 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 		t.Error("Did not expect user to be persisted to .skeema, but it was")
 	}
 
+	// include-schemas and exclude-schemas should restrict which schemas get dirs
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir filtered -h %s -P %d --include-schemas=prod[", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir filtered -h %s -P %d --include-schemas=prod*,analytics --exclude-schemas=analytics", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("filtered/product/.skeema"); err != nil {
+		t.Errorf("Expected os.Stat to return nil error for filtered/product/.skeema; instead err=%v", err)
+	}
+	if _, err := os.Stat("filtered/analytics"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for filtered/analytics; instead err=%v", err)
+	}
+
 	// Specifying an unreachable host should fail with fatal error
 	s.handleCommand(t, CodeFatalError, ".", "skeema init --dir baddb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port-100)
 
@@ -243,6 +257,18 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 		t.Errorf("Expected os.Stat to return nil error for mydb/analytics/widget_counts.sql; instead err=%v", err)
 	}
 
+	// Test behavior with --include-schemas and --exclude-schemas: new schema
+	// should only have a dir created if it matches the patterns
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --exclude-schemas=foo,arch*")
+	if _, err := os.Stat("mydb/archives"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/archives; instead err=%v", err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --include-schemas=analytics,product")
+	if _, err := os.Stat("mydb/archives"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/archives; instead err=%v", err)
+	}
+	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --include-schemas=arch[")
+
 	// If a dir has a bad option file, new schema detection should also be skipped,
 	// since we don't know what schemas the bad subdir maps to
 	fs.WriteTestFile(t, "mydb/analytics/.skeema", "this won't parse anymore")
@@ -265,6 +291,58 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 	}
 }
 
+func (s SkeemaIntegrationSuite) TestApplyAlterHandler(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Must be run from a dir defining a schema, and with a table that has a file
+	s.handleCommand(t, CodeBadConfig, "mydb", "skeema apply-alter posts 'ADD COLUMN foo int'")
+	s.handleCommand(t, CodeBadInput, "mydb/product", "skeema apply-alter doesntexist 'ADD COLUMN foo int'")
+	s.handleCommand(t, CodeBadUsage, "mydb/product", "skeema apply-alter posts ';'")
+
+	// Successful ALTER should update the table's file, and leave other files and
+	// the db in sync
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema apply-alter posts 'ADD COLUMN foo int unsigned NOT NULL DEFAULT 3;'")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.sql"); !strings.Contains(contents, "`foo` int(10) unsigned NOT NULL DEFAULT '3'") && !strings.Contains(contents, "`foo` int unsigned NOT NULL DEFAULT '3'") {
+		t.Errorf("Expected mydb/product/posts.sql to contain new column, but it does not:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Invalid ALTER should fail without modifying the file
+	before := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema apply-alter posts 'DROP COLUMN doesntexist'")
+	if after := fs.ReadTestFile(t, "mydb/product/posts.sql"); after != before {
+		t.Error("Expected mydb/product/posts.sql to be unchanged after failed ALTER, but it was modified")
+	}
+
+	// If the file and db have diverged, the command should refuse to run, and
+	// the db should not be modified
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(before, "`foo`", "`bar`", 1))
+	s.handleCommand(t, CodeBadInput, "mydb/product", "skeema apply-alter posts 'ADD COLUMN baz int'")
+	schema, err := s.d.Schema("product")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	}
+	table := schema.Table("posts")
+	for _, col := range table.Columns {
+		if col.Name == "baz" {
+			t.Error("Expected posts.baz to not be added, but it was")
+		}
+	}
+
+	// Changes to other tables, or auto-increment changes to this one, do not
+	// block the command
+	fs.WriteTestFile(t, "mydb/product/posts.sql", before)
+	s.dbExec(t, "product", "INSERT INTO posts (user_id) VALUES (123)")
+	s.dbExec(t, "product", "ALTER TABLE users ADD COLUMN nickname varchar(20)")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema apply-alter posts 'DROP COLUMN foo'")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.sql"); strings.Contains(contents, "`foo`") {
+		t.Errorf("Expected mydb/product/posts.sql to no longer contain column foo, but it does:\n%s", contents)
+	}
+	if contents := fs.ReadTestFile(t, "mydb/product/users.sql"); strings.Contains(contents, "nickname") {
+		t.Error("Expected mydb/product/users.sql to be left as-is, but it was updated")
+	}
+}
+
 func (s SkeemaIntegrationSuite) TestLintHandler(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -346,9 +424,9 @@ func (s SkeemaIntegrationSuite) TestFormatHandler(t *testing.T) {
 	s.handleCommand(t, CodeBadConfig, "mydb/product", "skeema format --password=wrong")
 
 	// Alter a few files in a way that is still valid SQL, but doesn't match
-	// the database's native format. Format with --skip-write should return exit
-	// CodeDifferencesFound repeatedly; format with default (--write) should return
-	// CodeDifferencesFound followed by CodeSuccess.
+	// the database's native format. Format with --skip-write or --check should
+	// return exit CodeDifferencesFound repeatedly; format with default (--write)
+	// should return CodeDifferencesFound followed by CodeSuccess.
 	productDir, err := fs.ParseDir("mydb/product", cfg)
 	if err != nil {
 		t.Fatalf("Unable to obtain dir for mydb/product: %s", err)
@@ -377,6 +455,8 @@ func (s SkeemaIntegrationSuite) TestFormatHandler(t *testing.T) {
 	rewriteFiles(false)
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format --skip-write")
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format --skip-write")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format --check")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format --check")
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format")
 	s.handleCommand(t, CodeSuccess, ".", "skeema format")
 	s.verifyFiles(t, cfg, "../golden/init")
@@ -423,6 +503,14 @@ func (s SkeemaIntegrationSuite) TestDiffHandler(t *testing.T) {
 	s.dbExec(t, "analytics", "ALTER TABLE pageviews DROP COLUMN domain")
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
 
+	// Confirm --dir supports multiple dirs and glob patterns
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --dir=mydb/*")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --dir=mydb/prod*,mydb/analytics")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --dir=mydb/prod*")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --dir=mydb/product")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema diff --dir=mydb/nope*")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema diff --dir=mydb/*/*.sql")
+
 	// Confirm --brief works as expected
 	oldStdout := os.Stdout
 	if outFile, err := os.Create("diff-brief.out"); err != nil {
@@ -443,6 +531,202 @@ func (s SkeemaIntegrationSuite) TestDiffHandler(t *testing.T) {
 	}
 }
 
+func (s SkeemaIntegrationSuite) TestVerifyHandler(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeSuccess, ".", "skeema verify")
+
+	// It isn't possible to disable brief mode or enable linting with verify
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema verify --skip-brief --lint")
+	if !cfg.GetBool("brief") || !cfg.GetBool("dry-run") || cfg.GetBool("lint") {
+		t.Error("Expected verify to force brief mode and dry-run, and disable linting")
+	}
+
+	// Out-of-band changes should be detected, even ones which would be unsafe
+	// to apply, unless the changed object is ignored
+	s.dbExec(t, "analytics", "ALTER TABLE pageviews ADD COLUMN oob int")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema verify")
+	s.handleCommand(t, CodeSuccess, ".", "skeema verify --ignore-table=pageviews")
+	s.handleCommand(t, CodeSuccess, ".", "skeema verify --ignore-schema=analytics")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.handleCommand(t, CodeSuccess, ".", "skeema verify")
+
+	// Linter problems should not affect verify
+	s.dbExec(t, "analytics", "CREATE TABLE nopk (id int)")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema verify")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.handleCommand(t, CodeSuccess, ".", "skeema verify --lint-pk=error")
+}
+
+func (s SkeemaIntegrationSuite) TestDiffStdin(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	diffWithStdin := func(expectedExitCode int, pwd, contents string) {
+		t.Helper()
+		fs.WriteTestFile(t, "mydb/stdin.in", contents)
+		inFile, err := os.Open("mydb/stdin.in")
+		if err != nil {
+			t.Fatalf("Unable to open stdin.in: %s", err)
+		}
+		oldStdin := os.Stdin
+		os.Stdin = inFile
+		s.handleCommand(t, expectedExitCode, pwd, "skeema diff --stdin")
+		os.Stdin = oldStdin
+		inFile.Close()
+		if err := os.Chdir(s.scratchPath()); err != nil {
+			t.Fatalf("Unable to cd to %s: %s", s.scratchPath(), err)
+		}
+		if err := os.Remove("mydb/stdin.in"); err != nil {
+			t.Fatalf("Unable to delete stdin.in: %s", err)
+		}
+	}
+
+	// A single unchanged table should not yield differences, even though other
+	// tables in the schema are not mentioned
+	pageviews := fs.ReadTestFile(t, "mydb/analytics/pageviews.sql")
+	diffWithStdin(CodeSuccess, "mydb/analytics", pageviews)
+
+	// Modified or new objects should yield differences
+	diffWithStdin(CodeDifferencesFound, "mydb/analytics", strings.Replace(pageviews, "`domain` varchar(40)", "`domain` varchar(50)", 1))
+	diffWithStdin(CodeDifferencesFound, "mydb/analytics", pageviews+"CREATE TABLE newtable (id int);\n")
+
+	// Non-CREATE statements, schema-qualified names, duplicates, and empty input
+	// should all be rejected
+	diffWithStdin(CodeBadConfig, "mydb/analytics", "INSERT INTO pageviews (url) VALUES ('foo');\n")
+	diffWithStdin(CodeBadConfig, "mydb/analytics", "CREATE TABLE product.newtable (id int);\n")
+	diffWithStdin(CodeBadConfig, "mydb/analytics", pageviews+"\n"+pageviews)
+	diffWithStdin(CodeBadConfig, "mydb/analytics", "-- nothing here\n")
+
+	// The top-level dir has no schema, so nothing can be compared
+	diffWithStdin(CodeFatalError, "mydb", pageviews)
+}
+
+func (s SkeemaIntegrationSuite) TestDiffSnapshot(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	scratchFile := func(parts ...string) string {
+		return filepath.Join(append([]string{s.scratchPath()}, parts...)...)
+	}
+
+	// Build a snapshot file resembling the output of mysqldump, containing both
+	// schemas along with various statements that should be ignored
+	var snapshot strings.Builder
+	snapshot.WriteString("/*!40101 SET NAMES utf8 */;\n")
+	for _, schemaName := range []string{"analytics", "product"} {
+		snapshot.WriteString(fmt.Sprintf("CREATE DATABASE /*!32312 IF NOT EXISTS*/ `%s`;\nUSE `%s`;\n", schemaName, schemaName))
+		files, err := filepath.Glob(scratchFile("mydb", schemaName, "*.sql"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("Unable to list *.sql files for %s: %v", schemaName, err)
+		}
+		for _, file := range files {
+			tableName := strings.TrimSuffix(filepath.Base(file), ".sql")
+			snapshot.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", tableName))
+			snapshot.WriteString(fs.ReadTestFile(t, file))
+		}
+	}
+	snapshotPath := scratchFile("snapshot.sql")
+	fs.WriteTestFile(t, snapshotPath, snapshot.String())
+
+	// Multi-schema snapshot should be compared using the dir's schema
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-snapshot %s", snapshotPath)
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff-snapshot %s", snapshotPath)
+
+	// Changes in the filesystem should be detected, without affecting the db
+	contents := fs.ReadTestFile(t, scratchFile("mydb", "product", "posts.sql"))
+	fs.WriteTestFile(t, scratchFile("mydb", "product", "posts.sql"), strings.Replace(contents, "PRIMARY KEY", "KEY `idx_snap` (`created_at`),\n  PRIMARY KEY", 1))
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-snapshot %s", snapshotPath)
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff")
+	fs.WriteTestFile(t, scratchFile("mydb", "product", "posts.sql"), contents)
+
+	// A dir without *.sql files, or a snapshot without any CREATEs, should result
+	// in a config error
+	s.handleCommand(t, CodeBadConfig, "mydb", "skeema diff-snapshot %s", snapshotPath)
+	fs.WriteTestFile(t, snapshotPath, "/*!40101 SET NAMES utf8 */;\n")
+	s.handleCommand(t, CodeBadConfig, "mydb/product", "skeema diff-snapshot %s", snapshotPath)
+}
+
+func (s SkeemaIntegrationSuite) TestDiffInstances(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	host := fmt.Sprintf("%s:%d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// A schema compared to itself has no differences. The schema name may come
+	// from the dir's configuration.
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-instances %s %s/product", host, host)
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-instances %s %s", host, host)
+
+	// A second fixture schema with a subset of tables, one of which differs
+	s.dbExec(t, "", "CREATE DATABASE product_copy")
+	s.dbExec(t, "product_copy", "CREATE TABLE posts LIKE product.posts")
+	s.dbExec(t, "product_copy", "ALTER TABLE posts ADD COLUMN extra int")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff-instances %s/product %s/product_copy", host, host)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff-instances --reverse %s/product %s/product_copy", host, host)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff-instances --json %s/product %s/product_copy", host, host)
+
+	// Neither instance should be modified by the command
+	copySchema, err := s.d.Schema("product_copy")
+	if err != nil {
+		t.Fatalf("Unexpected error introspecting schema: %s", err)
+	} else if len(copySchema.Tables) != 1 || copySchema.Tables[0].ColumnsByName()["extra"] == nil {
+		t.Errorf("Expected schema product_copy to be unchanged by diff-instances")
+	}
+
+	// Invalid or nonexistent schemas should be errors
+	s.handleCommand(t, CodeBadInput, ".", "skeema diff-instances %s/product %s", host, host)
+	s.handleCommand(t, CodeBadInput, ".", "skeema diff-instances %s/product %s/doesnt_exist", host, host)
+	s.handleCommand(t, CodeBadInput, ".", "skeema diff-instances --ignore-schema=copy %s/product %s/product_copy", host, host)
+}
+
+func (s SkeemaIntegrationSuite) TestDiffRefs(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	gitTestCommit(t, s.scratchPath(), "init")
+
+	// Commit some changes: modify a table, add a table, remove a table
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "PRIMARY KEY", "KEY `idx_refs` (`created_at`),\n  PRIMARY KEY", 1))
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", "CREATE TABLE widgets (id int);\n")
+	if err := os.Remove("mydb/product/comments.sql"); err != nil {
+		t.Fatalf("Unable to remove comments.sql: %s", err)
+	}
+	gitTestCommit(t, s.scratchPath(), "changes")
+
+	// Differences between the two commits should be detected, without affecting
+	// the db. Comparing a commit to itself should yield no differences.
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-refs HEAD~1 HEAD")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-refs HEAD HEAD~1")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-refs HEAD~1 HEAD~1")
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff-refs HEAD~1 HEAD")
+	s.assertTableMissing(t, "product", "widgets", "")
+	s.assertTableExists(t, "product", "comments", "")
+
+	// Uncommitted changes in the working tree should have no effect
+	fs.WriteTestFile(t, "mydb/analytics/uncommitted.sql", "CREATE TABLE uncommitted (id int);\n")
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff-refs HEAD~1 HEAD")
+	if err := os.Remove("mydb/analytics/uncommitted.sql"); err != nil {
+		t.Fatalf("Unable to remove uncommitted.sql: %s", err)
+	}
+
+	// Views and triggers are diffed along with tables, subject to object-types
+	fs.WriteTestFile(t, "mydb/product/active_users.sql", "CREATE VIEW active_users AS SELECT id, name FROM users WHERE credits > 0;\n")
+	fs.WriteTestFile(t, "mydb/product/users_bi.sql", "CREATE TRIGGER users_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name);\n")
+	gitTestCommit(t, s.scratchPath(), "view and trigger")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-refs HEAD~1 HEAD")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-refs HEAD HEAD~1")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-refs --object-types=table HEAD~1 HEAD")
+	s.assertTableMissing(t, "product", "active_users", "")
+
+	// Tables are normalized the same way as by diff: with skip-reorder-columns,
+	// a change in column order alone is not a difference
+	contents = fs.ReadTestFile(t, "mydb/product/posts.sql")
+	bodyLine := "  `body` text,\n"
+	contents = strings.Replace(contents, bodyLine, "", 1)
+	contents = strings.Replace(contents, "ON UPDATE CURRENT_TIMESTAMP,\n", "ON UPDATE CURRENT_TIMESTAMP,\n"+bodyLine, 1)
+	fs.WriteTestFile(t, "mydb/product/posts.sql", contents)
+	gitTestCommit(t, s.scratchPath(), "column order")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff-refs HEAD~1 HEAD")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff-refs --skip-reorder-columns HEAD~1 HEAD")
+
+	// Invalid refs are an input error
+	s.handleCommand(t, CodeBadInput, "mydb/product", "skeema diff-refs HEAD~1 no-such-branch")
+}
+
 func (s SkeemaIntegrationSuite) TestPushHandler(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -455,6 +739,7 @@ func (s SkeemaIntegrationSuite) TestPushHandler(t *testing.T) {
 
 	// Test bad option values
 	s.handleCommand(t, CodeBadConfig, ".", "skeema push --concurrent-instances=0")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema push --max-failures=-1")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema push --alter-algorithm=invalid")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema push --alter-lock=invalid")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema push --ignore-table='+'")
@@ -553,6 +838,26 @@ func (s SkeemaIntegrationSuite) TestPushHandler(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema diff --lint-pk=error")
 }
 
+func (s SkeemaIntegrationSuite) TestCheckConvergence(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// An alter-wrapper which doesn't actually run the ALTER leaves the schema
+	// unchanged, which should be caught by check-convergence
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	contents = strings.Replace(contents, "  `body` text,\n", "  `body` text,\n  `junk` int,\n", 1)
+	fs.WriteTestFile(t, "mydb/product/posts.sql", contents)
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --alter-wrapper='/bin/echo {TABLE}'")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --alter-wrapper='/bin/echo {TABLE}' --check-convergence=warn")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push --alter-wrapper='/bin/echo {TABLE}' --check-convergence=error")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema push --check-convergence=sometimes")
+	s.assertTableMissing(t, "product", "posts", "junk")
+
+	// Without the wrapper, the push converges
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --check-convergence=error")
+	s.assertTableExists(t, "product", "posts", "junk")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
 func (s SkeemaIntegrationSuite) TestHelpHandler(t *testing.T) {
 	// Simple tests just to confirm the commands don't error
 	fs.WriteTestFile(t, "fake-etc/skeema", "# hello world")
@@ -566,6 +871,80 @@ func (s SkeemaIntegrationSuite) TestHelpHandler(t *testing.T) {
 	s.handleCommand(t, CodeFatalError, ".", "skeema --help=doesntexist")
 }
 
+func (s SkeemaIntegrationSuite) TestDefaultTableOptions(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	fs.WriteTestFile(t, "mydb/product/.skeema", fs.ReadTestFile(t, "mydb/product/.skeema")+"default-table-options=ROW_FORMAT=COMPACT COMMENT='standard'\n")
+
+	// Existing tables lack the default options, so they should be altered to
+	// have them, and new tables should be created with them
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", "CREATE TABLE widgets (id int);\n")
+	fs.WriteTestFile(t, "mydb/product/gadgets.sql", "CREATE TABLE gadgets (id int) COMMENT='explicit';\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	schema, err := s.d.Schema("product")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	}
+	for _, table := range schema.Tables {
+		expectComment := "standard"
+		if table.Name == "gadgets" {
+			expectComment = "explicit"
+		}
+		if table.Comment != expectComment || !strings.Contains(table.CreateStatement, "ROW_FORMAT=COMPACT") {
+			t.Errorf("Table %s does not have expected options: %s", table.Name, table.CreateStatement)
+		}
+	}
+
+	// Formatting or pulling should not add the default options to files which
+	// omit them, but should still add other canonical formatting
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema format")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	contents := fs.ReadTestFile(t, "mydb/product/widgets.sql")
+	if strings.Contains(contents, "ROW_FORMAT") || strings.Contains(contents, "standard") || !strings.Contains(contents, "`id`") {
+		t.Errorf("Unexpected contents of widgets.sql after format and pull: %s", contents)
+	}
+	if contents := fs.ReadTestFile(t, "mydb/product/gadgets.sql"); !strings.Contains(contents, "COMMENT='explicit'") {
+		t.Errorf("Expected gadgets.sql to retain its explicit comment, instead found: %s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema format")
+}
+
+func (s SkeemaIntegrationSuite) TestTimeZone(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := "CREATE TABLE tz (\n  `id` int(11) DEFAULT NULL,\n  `ts` timestamp NOT NULL DEFAULT '2020-01-01 00:00:00'\n) ENGINE=InnoDB DEFAULT CHARSET=latin1;\n"
+	fs.WriteTestFile(t, "mydb/product/tz.sql", contents)
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema push --time-zone=+05:00")
+
+	// Connections from the dir should use the configured session time zone
+	dir, err := fs.ParseDir("mydb/product", cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %s", err)
+	}
+	inst, err := dir.FirstInstance()
+	if inst == nil || err != nil {
+		t.Fatalf("No instances returned for %s: %s", dir, err)
+	}
+	db, err := inst.Connect("", "")
+	if err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	var timeZone string
+	if err := db.QueryRow("SELECT @@session.time_zone").Scan(&timeZone); err != nil || timeZone != "+05:00" {
+		t.Errorf("Expected session time_zone to be +05:00, instead found %q (err=%v)", timeZone, err)
+	}
+
+	// The TIMESTAMP default should be interpreted consistently between the
+	// workspace and the live table, regardless of which time zone is used, as
+	// long as it's the same for both
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --time-zone=+05:00")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --time-zone=+00:00")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --time-zone=+05:00")
+	if actual := fs.ReadTestFile(t, "mydb/product/tz.sql"); !strings.Contains(actual, "DEFAULT '2020-01-01 00:00:00'") {
+		t.Errorf("Unexpected TIMESTAMP default after pull with same time-zone as push: %s", actual)
+	}
+}
+
 func (s SkeemaIntegrationSuite) TestIndexOrdering(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -741,6 +1120,38 @@ func (s SkeemaIntegrationSuite) TestAutoInc(t *testing.T) {
 
 }
 
+// TestAutoIncExplicit confirms that an AUTO_INCREMENT=N clause which was
+// explicitly declared in a *.sql file is respected by push, and is not
+// stripped by pull, lint, or format, even without --include-auto-inc.
+func (s SkeemaIntegrationSuite) TestAutoIncExplicit(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+	contents := "CREATE TABLE widgets (id int unsigned NOT NULL AUTO_INCREMENT, PRIMARY KEY (id)) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=latin1;\n"
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", contents)
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	product, err := s.d.Schema("product")
+	if err != nil || product == nil || !product.HasTable("widgets") {
+		t.Fatalf("Unable to obtain table widgets: %v", err)
+	} else if nextAutoInc := product.Table("widgets").NextAutoIncrement; nextAutoInc != 1000 {
+		t.Errorf("Expected table widgets to have next auto-inc value of 1000, instead found %d", nextAutoInc)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	for _, command := range []string{"skeema pull", "skeema lint", "skeema format"} {
+		s.handleCommand(t, CodeSuccess, ".", command)
+		if !strings.Contains(fs.ReadTestFile(t, "mydb/product/widgets.sql"), "AUTO_INCREMENT=1000") {
+			t.Errorf("Expected mydb/product/widgets.sql to still contain AUTO_INCREMENT=1000 after %s, but it did not", command)
+		}
+	}
+
+	// Lowering the declared value should not be treated as a difference, since
+	// the table's next auto-inc value cannot be decreased below existing data
+	// and is typically not tracked; raising it should be.
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", strings.Replace(contents, "AUTO_INCREMENT=1000", "AUTO_INCREMENT=500", 1))
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", strings.Replace(contents, "AUTO_INCREMENT=1000", "AUTO_INCREMENT=2000", 1))
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+}
+
 func (s SkeemaIntegrationSuite) TestUnsupportedAlter(t *testing.T) {
 	s.sourceSQL(t, "unsupported1.sql")
 
@@ -989,6 +1400,86 @@ func (s SkeemaIntegrationSuite) TestNonInnoClauses(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema push%s", connectOpts)
 }
 
+func (s SkeemaIntegrationSuite) TestStorageEngineChange(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Changing the engine in the db should result in an unsafe diff, since the
+	// file still specifies InnoDB
+	s.dbExec(t, "product", "ALTER TABLE posts ENGINE=MyISAM")
+	s.handleCommand(t, CodeFatalError, ".", "skeema diff")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe")
+
+	// Omitting ENGINE from the file means the workspace default of InnoDB is
+	// used, so the diff is unchanged
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "ENGINE=InnoDB ", "", 1))
+	s.handleCommand(t, CodeFatalError, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Engine name casing in the file should not cause any differences
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "ENGINE=InnoDB", "ENGINE=innodb", 1))
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestColumnCollationChange(t *testing.T) {
+	s.dbExec(t, "product", "CREATE TABLE collations (id int unsigned NOT NULL PRIMARY KEY, name varchar(30) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci, bio varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci, KEY idx_name (name)) DEFAULT CHARSET=latin1")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Changing only the collation of columns, without changing their charset,
+	// should be detected by diff, and is not considered unsafe
+	contents := fs.ReadTestFile(t, "mydb/product/collations.sql")
+	if !strings.Contains(contents, "utf8mb4_general_ci") {
+		t.Fatalf("Unexpected file contents for collations.sql:\n%s", contents)
+	}
+	contents = strings.Replace(contents, "utf8mb4_general_ci", "utf8mb4_unicode_ci", -1)
+	fs.WriteTestFile(t, "mydb/product/collations.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	table, err := s.d.Schema("product")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %s", err)
+	}
+	for _, col := range table.Table("collations").Columns {
+		if col.CharSet != "" && col.Collation != "utf8mb4_unicode_ci" {
+			t.Errorf("Expected column %s to have collation utf8mb4_unicode_ci, instead found %s", col.Name, col.Collation)
+		}
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestColumnOrder(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "mydb/product/users.sql")
+
+	// Adding a column in the middle of the table should position it with AFTER
+	// by default, or at the end of the table with --skip-reorder-columns
+	addMiddle := strings.Replace(contents, "  `credits`", "  `nickname` varchar(30) DEFAULT NULL,\n  `credits`", 1)
+	fs.WriteTestFile(t, "mydb/product/users.sql", addMiddle)
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --skip-reorder-columns")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --skip-reorder-columns")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	users, err := s.d.Schema("product")
+	if err != nil {
+		t.Fatalf("Unexpected error obtaining schema: %v", err)
+	} else if cols := users.Table("users").Columns; cols[2].Name != "nickname" {
+		t.Errorf("Expected column nickname to be in position 2 after push, instead found %s", cols[2].Name)
+	}
+
+	// Reordering existing columns should be detected by default, and ignored
+	// with --skip-reorder-columns unless --exact-match is also used
+	reordered := strings.Replace(addMiddle, "  `name` varchar(30) NOT NULL,\n", "", 1)
+	reordered = strings.Replace(reordered, "  `last_modified`", "  `name` varchar(30) NOT NULL,\n  `last_modified`", 1)
+	fs.WriteTestFile(t, "mydb/product/users.sql", reordered)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --skip-reorder-columns")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --skip-reorder-columns --exact-match")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
 func (s SkeemaIntegrationSuite) TestReuseTempSchema(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -1335,6 +1826,375 @@ END`
 	}
 }
 
+func (s SkeemaIntegrationSuite) TestObjectTypes(t *testing.T) {
+	s.dbExec(t, "product", "CREATE FUNCTION routine1() returns int DETERMINISTIC return 42")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Dropping the function, or altering a table, should only be considered a
+	// difference if the corresponding type is included
+	s.dbExec(t, "product", "DROP FUNCTION routine1")
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN junk int")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe --object-types=function")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe --object-types=table")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --allow-unsafe --object-types=procedure,event")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema diff --object-types=user")
+
+	// Pushing only tables should leave the function missing, and pulling only
+	// tables should leave its file in place
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe --object-types=table")
+	if exists, phrase, err := s.objectExists("product", tengo.ObjectTypeFunc, "routine1", ""); exists || err != nil {
+		t.Errorf("Expected %s to not exist, instead found %t, err=%v", phrase, exists, err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --object-types=table")
+	if contents := fs.ReadTestFile(t, "mydb/product/routine1.sql"); !strings.Contains(contents, "return 42") {
+		t.Errorf("Expected pull with object-types=table to leave routine1.sql as-is, instead found %q", contents)
+	}
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestEvents(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	eventStatus := func(name string) string {
+		t.Helper()
+		db, err := s.d.Connect("product", "")
+		if err != nil {
+			t.Fatalf("Unable to connect to product schema: %s", err)
+		}
+		var status string
+		query := "SELECT status FROM information_schema.events WHERE event_schema = DATABASE() AND event_name = ?"
+		if err := db.QueryRow(query, name).Scan(&status); err != nil && err != sql.ErrNoRows {
+			t.Fatalf("Unexpected error querying event status: %s", err)
+		}
+		return status
+	}
+
+	// Add a file creating an event, and push it. The event should be enabled on
+	// the instance, even though it is created disabled in the workspace. Its
+	// implicit start time should not cause a diff afterwards.
+	contents := "CREATE EVENT ev1 ON SCHEDULE EVERY 1 DAY DO SET @skeema_test = 1;\n"
+	fs.WriteTestFile(t, "mydb/product/ev1.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	if status := eventStatus("ev1"); status != "ENABLED" {
+		t.Errorf("Expected ev1 to have status ENABLED, instead found %q", status)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Disabling the event in its file should alter it
+	fs.WriteTestFile(t, "mydb/product/ev1.sql", strings.Replace(contents, " DO ", " DISABLE DO ", 1))
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	if status := eventStatus("ev1"); status != "DISABLED" {
+		t.Errorf("Expected ev1 to have status DISABLED, instead found %q", status)
+	}
+
+	// pull should rewrite the file to the canonical format, after which diff
+	// and lint should be no-ops
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/ev1.sql"); !strings.HasPrefix(contents, "CREATE DEFINER=") || !strings.Contains(contents, "DISABLE") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema lint")
+
+	// Modifying the body on the instance should cause a diff, and pull with
+	// --skip-format should update the file
+	s.dbExec(t, "product", "ALTER EVENT ev1 DO SET @skeema_test = 2")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --skip-format")
+	if contents := fs.ReadTestFile(t, "mydb/product/ev1.sql"); !strings.Contains(contents, "@skeema_test = 2") {
+		t.Errorf("Unexpected contents after pull --skip-format:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Removing the file should only drop the event with --allow-unsafe
+	fs.RemoveTestFile(t, "mydb/product/ev1.sql")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	if status := eventStatus("ev1"); status != "" {
+		t.Errorf("Expected ev1 to be dropped, but it still exists with status %q", status)
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestSequences(t *testing.T) {
+	if !workspace.SupportsSequences(s.d.Flavor()) {
+		t.Skip("Test only relevant for flavors that support sequences")
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Add a sequence, along with a table using it as a column default. push
+	// must create the sequence before the table, and afterwards the schema
+	// qualifier reported in the live table's default should not cause a diff.
+	fs.WriteTestFile(t, "mydb/product/seq1.sql", "CREATE SEQUENCE seq1 START WITH 100 INCREMENT BY 10;\n")
+	fs.WriteTestFile(t, "mydb/product/seqtest.sql", "CREATE TABLE seqtest (id bigint NOT NULL DEFAULT nextval(seq1), PRIMARY KEY (id));\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Changing options should alter the sequence
+	fs.WriteTestFile(t, "mydb/product/seq1.sql", "CREATE SEQUENCE seq1 START WITH 100 INCREMENT BY 5 NOCACHE;\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// pull should rewrite the files to the canonical format, without qualifying
+	// the column default, after which diff and lint should be no-ops
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/seq1.sql"); !strings.Contains(contents, "increment by 5 nocache") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	if contents := fs.ReadTestFile(t, "mydb/product/seqtest.sql"); !strings.Contains(contents, "nextval(`seq1`)") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema lint")
+
+	// Removing the files should only drop the sequence with --allow-unsafe
+	fs.RemoveTestFile(t, "mydb/product/seq1.sql")
+	fs.RemoveTestFile(t, "mydb/product/seqtest.sql")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestCheckConstraints(t *testing.T) {
+	if flavor := s.d.Flavor(); !flavor.MySQLishMinVersion(8, 0, 16) && !flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 2) {
+		t.Skip("Test only relevant for flavors that support CHECK constraints")
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// A new table with CHECK constraints is created as-is, after which diff
+	// should be a no-op
+	fs.WriteTestFile(t, "mydb/product/checktest.sql", "CREATE TABLE checktest (id int NOT NULL, qty int, PRIMARY KEY (id), CONSTRAINT positive CHECK (qty > 0), CONSTRAINT small CHECK (qty < 100));\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Modifying, removing, or adding constraints, along with a column change,
+	// should alter the table
+	fs.WriteTestFile(t, "mydb/product/checktest.sql", "CREATE TABLE checktest (id int NOT NULL, qty int, name varchar(20), PRIMARY KEY (id), CONSTRAINT small CHECK (qty < 10), CONSTRAINT named CHECK (name <> ''));\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// pull should retain the constraints in the canonical format
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	contents := fs.ReadTestFile(t, "mydb/product/checktest.sql")
+	if !strings.Contains(contents, "CONSTRAINT `small` CHECK") || !strings.Contains(contents, "CONSTRAINT `named` CHECK") || strings.Contains(contents, "`positive`") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestViews(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Add two views, one of which refers to the other. push must create them in
+	// a working order, after which diff should be a no-op.
+	fs.WriteTestFile(t, "mydb/product/user_names.sql", "CREATE VIEW user_names AS SELECT name FROM active_users;\n")
+	fs.WriteTestFile(t, "mydb/product/active_users.sql", "CREATE VIEW active_users AS SELECT id, name FROM users WHERE credits > 0;\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Changing the SQL security characteristic or the definition should replace
+	// the view in-place, without requiring --allow-unsafe
+	fs.WriteTestFile(t, "mydb/product/active_users.sql", "CREATE SQL SECURITY INVOKER VIEW active_users AS SELECT id, name FROM users WHERE credits > 1;\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// An out-of-band change to the algorithm should be detected
+	s.dbExec(t, "product", "ALTER ALGORITHM=TEMPTABLE SQL SECURITY INVOKER VIEW active_users AS SELECT id, name FROM users WHERE credits > 1")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+
+	// pull should rewrite the files to the canonical format, without qualifying
+	// references to the schema, after which diff should be a no-op
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	contents := fs.ReadTestFile(t, "mydb/product/active_users.sql")
+	if !strings.Contains(contents, "ALGORITHM=TEMPTABLE") || !strings.Contains(contents, "SQL SECURITY INVOKER") || strings.Contains(contents, "`product`.") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// ignore-table also applies to views
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --ignore-table=user_names --allow-unsafe")
+	fs.RemoveTestFile(t, "mydb/product/user_names.sql")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --ignore-table=user_names --allow-unsafe")
+
+	// Removing the file should only drop the view with --allow-unsafe
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestTriggers(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Adding a trigger should create it, after which diff should be a no-op
+	fs.WriteTestFile(t, "mydb/product/users_bi.sql", "CREATE TRIGGER users_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name);\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Modifying the trigger requires dropping and re-creating it, which is only
+	// permitted with --allow-drop-trigger or --allow-unsafe
+	fs.WriteTestFile(t, "mydb/product/users_bi.sql", "CREATE TRIGGER users_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.name = LOWER(NEW.name);\n")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-drop-trigger")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// An out-of-band change should be detected, and pull should rewrite the file
+	// to match, after which diff should be a no-op
+	s.dbExec(t, "product", "DROP TRIGGER users_bi")
+	s.dbExec(t, "product", "CREATE TRIGGER users_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.credits = 10")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-drop-trigger")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/users_bi.sql"); !strings.Contains(contents, "NEW.credits = 10") {
+		t.Errorf("Unexpected contents after pull:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Removing the file should only drop the trigger with --allow-drop-trigger
+	// or --allow-unsafe
+	fs.RemoveTestFile(t, "mydb/product/users_bi.sql")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-drop-trigger")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestFingerprint(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema fingerprint")
+	s.handleCommand(t, CodeSuccess, ".", "skeema fingerprint --live")
+
+	getFingerprints := func() (fromDir, fromLive string) {
+		t.Helper()
+		dir, err := fs.ParseDir("mydb/product", cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseDir: %s", err)
+		}
+		if fromDir, err = fingerprintDir(dir); err != nil {
+			t.Fatalf("Unexpected error from fingerprintDir: %s", err)
+		}
+		schema, err := s.d.Schema("product")
+		if err != nil {
+			t.Fatalf("Unexpected error introspecting schema: %s", err)
+		}
+		objs, err := introspect.SchemaObjects(s.d.Instance, "product", introspect.Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error introspecting objects: %s", err)
+		}
+		return fromDir, introspect.Fingerprint(schema, objs)
+	}
+
+	// The dir and live schema should match, and this should not be affected by
+	// formatting changes or auto-increment values
+	fromDir, fromLive := getFingerprints()
+	if fromDir != fromLive {
+		t.Errorf("Expected dir and live fingerprints to match; instead found %s vs %s", fromDir, fromLive)
+	}
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(strings.ToLower(contents), "\n", " ", -1))
+	s.dbExec(t, "product", "INSERT INTO users (name) VALUES ('fingerprint-test')")
+	if fromDir2, fromLive2 := getFingerprints(); fromDir2 != fromDir || fromLive2 != fromLive {
+		t.Errorf("Expected fingerprints to be unaffected by formatting and auto-increment, but they changed from %s vs %s to %s vs %s", fromDir, fromLive, fromDir2, fromLive2)
+	}
+
+	// An actual change to the schema should change the fingerprint
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN foo int")
+	if fromDir2, fromLive2 := getFingerprints(); fromDir2 != fromDir || fromLive2 == fromLive {
+		t.Errorf("Expected only live fingerprint to change, but found %s vs %s changed to %s vs %s", fromDir, fromLive, fromDir2, fromLive2)
+	}
+
+	// Views and triggers are also included in the fingerprint
+	fromDir, fromLive = getFingerprints()
+	s.dbExec(t, "product", "CREATE TRIGGER fingerprint_bi BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name)")
+	if fromDir2, fromLive2 := getFingerprints(); fromDir2 != fromDir || fromLive2 == fromLive {
+		t.Errorf("Expected only live fingerprint to change after adding trigger, but found %s vs %s changed to %s vs %s", fromDir, fromLive, fromDir2, fromLive2)
+	}
+	fs.WriteTestFile(t, "mydb/product/user_names.sql", "CREATE VIEW user_names AS SELECT name FROM users;\n")
+	if fromDir2, _ := getFingerprints(); fromDir2 == fromDir {
+		t.Errorf("Expected dir fingerprint to change after adding view, but it remained %s", fromDir)
+	}
+
+	// A dir with statements for multiple schemas cannot be fingerprinted
+	fs.WriteTestFile(t, "mydb/product/other.sql", "USE analytics;\nCREATE TABLE other (id int);\n")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema fingerprint")
+	fs.RemoveTestFile(t, "mydb/product/other.sql")
+
+	// Invalid SQL should cause an error
+	fs.WriteTestFile(t, "mydb/product/posts.sql", "CREATE TABLE posts (id int unsigned PRIMARY KEY, foo badtype);\n")
+	s.handleCommand(t, CodeFatalError, ".", "skeema fingerprint")
+}
+
+func (s SkeemaIntegrationSuite) TestPlanApply(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// No plan file should be written if there are no differences
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --plan=test.plan")
+	if _, err := os.Stat("test.plan"); !os.IsNotExist(err) {
+		t.Errorf("Expected no plan file to be written, but stat returned %v", err)
+	}
+	s.handleCommand(t, CodeNoInput, ".", "skeema apply test.plan")
+
+	// Applying a plan should run exactly the planned statements, even if the
+	// *.sql files were changed after the plan was made
+	contents := "CREATE TABLE widgets (id int unsigned NOT NULL, name varchar(30), PRIMARY KEY (id));\n"
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --plan=test.plan")
+	s.assertTableMissing(t, "product", "widgets", "")
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", strings.Replace(contents, "varchar(30)", "varchar(30), description text", 1))
+	s.handleCommand(t, CodeBadUsage, ".", "skeema apply test.plan staging")
+	s.handleCommand(t, CodeSuccess, ".", "skeema apply test.plan")
+	s.assertTableExists(t, "product", "widgets", "name")
+	s.assertTableMissing(t, "product", "widgets", "description")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+
+	// Reapplying the same plan should be refused, since the schema has changed
+	// since the plan was made
+	s.handleCommand(t, CodeFatalError, ".", "skeema apply test.plan")
+
+	// A drifted target should reject a stale plan, without running anything
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --plan=test.plan")
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN drift int")
+	s.handleCommand(t, CodeFatalError, ".", "skeema apply test.plan")
+	s.assertTableMissing(t, "product", "widgets", "description")
+
+	// Plans cannot be combined with a wrapper
+	s.handleCommand(t, CodeBadConfig, ".", "skeema diff --plan=test.plan --alter-wrapper='echo {DDL}'")
+}
+
+func (s SkeemaIntegrationSuite) TestConnectSchema(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Operations should still target each dir's schema, even though connections
+	// are established with a different default database
+	contents := "CREATE TABLE widgets (id int unsigned NOT NULL, name varchar(30), PRIMARY KEY (id));\n"
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --connect-schema=analytics")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --connect-schema=analytics")
+	s.assertTableExists(t, "product", "widgets", "name")
+	s.assertTableMissing(t, "analytics", "widgets", "")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --connect-schema=analytics")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Same for modifications, as well as lint and pull
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", strings.Replace(contents, "varchar(30)", "varchar(40)", 1))
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --connect-schema=analytics")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema lint --connect-schema=analytics")
+	s.dbExec(t, "product", "ALTER TABLE widgets ADD COLUMN foo int")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --connect-schema=analytics")
+	if contents := fs.ReadTestFile(t, "mydb/product/widgets.sql"); !strings.Contains(contents, "`foo`") {
+		t.Errorf("Expected pull to update widgets.sql, but it did not; contents:\n%s", contents)
+	}
+	s.assertTableMissing(t, "analytics", "widgets", "")
+}
+
 func (s SkeemaIntegrationSuite) TestTempSchemaBinlog(t *testing.T) {
 	if !s.d.Flavor().MySQLishMinVersion(8, 0) {
 		t.Skip("Test only relevant for flavors that default to having binlog enabled")
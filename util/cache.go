@@ -18,7 +18,9 @@ func init() {
 
 // NewInstance wraps tengo.NewInstance such that two identical requests will
 // return the same *tengo.Instance. This helps reduce excessive creation of
-// redundant connections.
+// redundant connections. If the DSN contains ConnectSchemaParam, the returned
+// Instance uses the connect-schema driver. If SQL echo is enabled, the returned
+// Instance uses the echoing driver, which also handles ConnectSchemaParam.
 func NewInstance(driver, dsn string) (*tengo.Instance, error) {
 	key := fmt.Sprintf("%s:%s", driver, dsn)
 	instanceCache.Lock()
@@ -31,6 +33,9 @@ func NewInstance(driver, dsn string) (*tengo.Instance, error) {
 	if err != nil {
 		return nil, err
 	}
+	ApplyConnectSchema(instance, dsn)
+	ApplyCredentials(instance, dsn)
+	ApplySQLEcho(instance)
 	instanceCache.instanceMap[key] = instance
 	return instance, nil
 }
@@ -3,10 +3,12 @@ package util
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -23,9 +25,11 @@ func AddGlobalOptions(cmd *mybase.Command) {
 	cmd.AddOption(mybase.StringOption("socket", 'S', "/tmp/mysql.sock", "Absolute path to Unix socket file used if host is localhost").Hidden())
 	cmd.AddOption(mybase.StringOption("schema", 0, "", "Database schema name").Hidden())
 	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex").Hidden())
+	cmd.AddOption(mybase.StringOption("schema-alias", 0, "", "Comma-separated NAME=ALIAS pairs mapping schema names to the names used on database instances").Hidden())
 	cmd.AddOption(mybase.StringOption("ignore-table", 0, "", "Ignore tables that match regex").Hidden())
 	cmd.AddOption(mybase.StringOption("default-character-set", 0, "", "Schema-level default character set").Hidden())
 	cmd.AddOption(mybase.StringOption("default-collation", 0, "", "Schema-level default collation").Hidden())
+	cmd.AddOption(mybase.StringOption("default-table-options", 0, "", "Table options to apply to any CREATE TABLE which does not specify them").Hidden())
 	cmd.AddOption(mybase.StringOption("flavor", 0, "", "Database server expressed in format vendor:major.minor, for use in vendor/version specific syntax").Hidden())
 
 	// Deprecated options or deprecated aliases -- all hidden
@@ -36,12 +40,35 @@ func AddGlobalOptions(cmd *mybase.Command) {
 	cmd.AddOption(mybase.StringOption("password", 'p', "", "Password for database user; omit value to prompt from TTY (default no password)").ValueOptional())
 	cmd.AddOption(mybase.StringOption("host-wrapper", 'H', "", "External bin to shell out to for host lookup; see manual for template vars"))
 	cmd.AddOption(mybase.StringOption("temp-schema", 't', "_skeema_tmp", "Name of temporary schema for intermediate operations, created and dropped each run"))
+	cmd.AddOption(mybase.StringOption("temp-schema-mismatch", 0, "alter", `How to reuse an existing temp schema with different charset or collation (valid values: "alter", "recreate")`))
 	cmd.AddOption(mybase.StringOption("temp-schema-binlog", 0, "auto", `Controls whether temp schema DDL operations are replicated (valid values: "on", "off", "auto")`))
 	cmd.AddOption(mybase.StringOption("temp-schema-threads", 0, "5", "Max number of concurrent CREATE/DROP with workspace=temp-schema"))
+	cmd.AddOption(mybase.StringOption("max-rows", 0, "0", "Max rows permitted in any workspace table when cleaning up the workspace"))
+	cmd.AddOption(mybase.BoolOption("no-lock", 0, false, "Skip obtaining a workspace lock; only safe if each run uses a dedicated database instance"))
+	cmd.AddOption(mybase.StringOption("lock-wait-timeout", 0, "30", "Max seconds to wait for a workspace lock held by another process; 0 fails immediately"))
 	cmd.AddOption(mybase.StringOption("connect-options", 'o', "", "Comma-separated session options to set upon connecting to each database instance"))
+	cmd.AddOption(mybase.StringOption("connect-schema", 0, "", "Default database to use upon connecting to each database instance, before switching to the schema being operated on"))
+	cmd.AddOption(mybase.StringOption("ssl-mode", 0, "", `TLS mode for connecting to each database instance (valid values: "disabled", "preferred", "required", "verify_ca", "verify_identity")`))
+	cmd.AddOption(mybase.StringOption("ssl-ca", 0, "", "Path to PEM file of certificate authorities for verifying the server's TLS certificate"))
+	cmd.AddOption(mybase.StringOption("ssl-cert", 0, "", "Path to PEM file of TLS client certificate, for use with ssl-key"))
+	cmd.AddOption(mybase.StringOption("ssl-key", 0, "", "Path to PEM file of TLS client private key, for use with ssl-cert"))
+	cmd.AddOption(mybase.BoolOption("enable-cleartext-plugin", 0, false, "Permit sending the password in cleartext, as required by some authentication plugins"))
+	cmd.AddOption(mybase.StringOption("password-command", 0, "", "External bin to shell out to for the password, run once per host upon first connection; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("token-provider", 0, "", "External bin to shell out to for a password or auth token upon each new connection; see manual for template vars"))
+	cmd.AddOption(mybase.StringOption("vault-role", 0, "", "Obtain short-lived database credentials for this role from Vault's database secrets engine"))
+	cmd.AddOption(mybase.StringOption("vault-mount", 0, "database", "Path where Vault's database secrets engine is mounted, for use with vault-role"))
+	cmd.AddOption(mybase.StringOption("vault-address", 0, "", "Vault server address, for use with vault-role (default $VAULT_ADDR)"))
+	cmd.AddOption(mybase.StringOption("vault-token", 0, "", "Vault token, for use with vault-role (default $VAULT_TOKEN)"))
+	cmd.AddOption(mybase.StringOption("time-zone", 0, "", "Session time_zone to set upon connecting to each database instance, e.g. '+00:00'"))
+	cmd.AddOption(mybase.StringOption("object-types", 0, "", `Comma-separated object types to manage, skipping all others (valid values: "table", "procedure", "function", "event", "sequence", "view", "trigger"; default all)`))
+	cmd.AddOption(mybase.StringOption("template-vars", 0, "", "Comma-separated NAME=VALUE pairs to substitute for {{.NAME}} placeholders in *.sql files"))
+	cmd.AddOption(mybase.StringOption("template-vars-file", 0, "", "Path to file of NAME=VALUE lines to substitute for {{.NAME}} placeholders in *.sql files"))
+	cmd.AddOption(mybase.StringOption("manage-data", 0, "", "Comma-separated names of tables whose rows are managed by INSERT statements in *.sql files"))
 	cmd.AddOption(mybase.StringOption("workspace", 'w', "temp-schema", `Specifies where to run intermediate operations (valid values: "temp-schema", "docker")`))
 	cmd.AddOption(mybase.StringOption("docker-cleanup", 0, "none", `With --workspace=docker, specifies how to clean up containers (valid values: "none", "stop", "destroy")`))
 	cmd.AddOption(mybase.BoolOption("debug", 0, false, "Enable debug logging"))
+	cmd.AddOption(mybase.BoolOption("show-sql", 0, false, "Echo every SQL statement executed, with timing, to STDERR"))
+	cmd.AddOption(mybase.StringOption("log-format", 0, "text", `Format of log output (valid values: "text", "json")`))
 	cmd.AddOption(mybase.BoolOption("my-cnf", 0, true, "Parse ~/.my.cnf for configuration"))
 }
 
@@ -68,24 +95,21 @@ func AddGlobalConfigFiles(cfg *mybase.Config) {
 		if !f.Exists() {
 			continue
 		}
+		if strings.HasSuffix(path, ".my.cnf") {
+			if cfg.GetBool("my-cnf") {
+				addMyCnfFile(cfg, f, make(map[string]bool))
+			}
+			continue
+		}
 		if err := f.Read(); err != nil {
 			log.Warnf("Ignoring global option file %s due to read error: %s", f.Path(), err)
 			continue
 		}
-		if strings.HasSuffix(path, ".my.cnf") {
-			f.IgnoreUnknownOptions = true
-			f.IgnoreOptions("host")
-			if !cfg.GetBool("my-cnf") {
-				continue
-			}
-		}
 		if err := f.Parse(cfg); err != nil {
 			log.Warnf("Ignoring global option file %s due to parse error: %s", f.Path(), err)
 			continue
 		}
-		if strings.HasSuffix(path, ".my.cnf") {
-			_ = f.UseSection("skeema", "client", "mysql") // safe to ignore error (doesn't matter if section doesn't exist)
-		} else if cfg.CLI.Command.HasArg("environment") { // avoid panic on command without environment arg, such as help command!
+		if cfg.CLI.Command.HasArg("environment") { // avoid panic on command without environment arg, such as help command!
 			_ = f.UseSection(cfg.Get("environment")) // safe to ignore error (doesn't matter if section doesn't exist)
 		}
 
@@ -93,9 +117,89 @@ func AddGlobalConfigFiles(cfg *mybase.Config) {
 	}
 }
 
+// addMyCnfFile adds a MySQL client option file as a source for cfg, followed
+// by any files referenced by its !include and !includedir directives,
+// recursively, in the order the directives appear. Since each included file is
+// a separate source added after the file that includes it, its values take
+// precedence regardless of where the directive appears. Files which cannot be
+// read or parsed, including missing includes, are skipped with a warning.
+// Paths already present in seen are skipped, to avoid include cycles.
+func addMyCnfFile(cfg *mybase.Config, f *mybase.File, seen map[string]bool) {
+	if seen[f.Path()] {
+		return
+	}
+	seen[f.Path()] = true
+	if err := f.Read(); err != nil {
+		log.Warnf("Ignoring global option file %s due to read error: %s", f.Path(), err)
+		return
+	}
+	f.IgnoreUnknownOptions = true
+	f.IgnoreOptions("host")
+	if err := f.Parse(cfg); err != nil {
+		log.Warnf("Ignoring global option file %s due to parse error: %s", f.Path(), err)
+		return
+	}
+	_ = f.UseSection("skeema", "client", "mysql") // safe to ignore error (doesn't matter if section doesn't exist)
+	cfg.AddSource(f)
+
+	for _, includePath := range myCnfIncludes(f.Path()) {
+		includeFile := mybase.NewFile(includePath)
+		if !includeFile.Exists() {
+			log.Warnf("Ignoring missing option file %s, included by %s", includeFile.Path(), f.Path())
+			continue
+		}
+		addMyCnfFile(cfg, includeFile, seen)
+	}
+}
+
+// myCnfIncludes returns the paths of files referenced by !include and
+// !includedir directives in the MySQL client option file at filePath. Relative
+// paths are interpreted relative to the directory containing filePath. As with
+// the mysql client, !includedir only includes files ending in .cnf (or also
+// .ini on Windows), in this case sorted by name. Problems listing an included
+// directory are logged as warnings.
+func myCnfIncludes(filePath string) (paths []string) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	resolve := func(includePath string) string {
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(filePath), includePath)
+		}
+		return includePath
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		includePath := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		switch fields[0] {
+		case "!include":
+			paths = append(paths, resolve(includePath))
+		case "!includedir":
+			dirPath := resolve(includePath)
+			entries, err := ioutil.ReadDir(dirPath)
+			if err != nil {
+				log.Warnf("Ignoring option file directory %s, included by %s: %s", dirPath, filePath, err)
+				continue
+			}
+			for _, entry := range entries {
+				ext := filepath.Ext(entry.Name())
+				if !entry.IsDir() && (ext == ".cnf" || (ext == ".ini" && runtime.GOOS == "windows")) {
+					paths = append(paths, filepath.Join(dirPath, entry.Name()))
+				}
+			}
+		}
+	}
+	return paths
+}
+
 // ProcessSpecialGlobalOptions performs special handling of global options with
 // unusual semantics -- handling restricted placement of host and schema;
-// obtaining a password from MYSQL_PWD or STDIN; enable debug logging.
+// obtaining a password from MYSQL_PWD or STDIN; enable debug logging; set
+// the log output format.
 func ProcessSpecialGlobalOptions(cfg *mybase.Config) error {
 	// The host and schema options are special -- most commands only expect
 	// to find them when recursively crawling directory configs. So if these
@@ -130,6 +234,15 @@ func ProcessSpecialGlobalOptions(cfg *mybase.Config) error {
 	if cfg.GetBool("debug") {
 		log.SetLevel(log.DebugLevel)
 	}
+	logFormat, err := cfg.GetEnum("log-format", "text", "json")
+	if err != nil {
+		return err
+	} else if err := SetLogFormat(logFormat); err != nil {
+		return err
+	}
+	if cfg.GetBool("show-sql") {
+		EnableSQLEcho(os.Stderr)
+	}
 
 	return nil
 }
@@ -224,6 +337,37 @@ func parseConnectOptions(input string) (map[string]string, error) {
 	return result, err
 }
 
+var reEnvVarTemplate = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnvVars replaces ${NAME} placeholders in the supplied string with the
+// value of the NAME environment variable. An error is returned if any
+// placeholder refers to an environment variable that is not set. Variables
+// which are set to an empty string are permitted.
+func ExpandEnvVars(input string) (string, error) {
+	var err error
+	result := reEnvVarTemplate.ReplaceAllStringFunc(input, func(match string) string {
+		name := match[2 : len(match)-1]
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if err == nil {
+			err = fmt.Errorf("Environment variable %s is not set, but is referenced by %s", name, match)
+		}
+		return match
+	})
+	return result, err
+}
+
+// ExpandedOption returns the value of the named option in cfg, with any ${NAME}
+// placeholders replaced by environment variable values, as per ExpandEnvVars.
+func ExpandedOption(cfg *mybase.Config, name string) (string, error) {
+	value, err := ExpandEnvVars(cfg.Get(name))
+	if err != nil {
+		return "", fmt.Errorf("Invalid value for option %s: %s", name, err)
+	}
+	return value, nil
+}
+
 // RealConnectOptions takes a comma-separated string of connection options,
 // strips any Go driver-specific ones, and then returns the new string which
 // is now suitable for passing to an external tool.
@@ -70,6 +70,44 @@ func TestAddGlobalConfigFiles(t *testing.T) {
 	}
 }
 
+func TestAddGlobalConfigFilesIncludes(t *testing.T) {
+	cmdSuite := mybase.NewCommandSuite("skeematest", "", "")
+	AddGlobalOptions(cmdSuite)
+	cmd := mybase.NewCommand("diff", "", "", nil)
+	cmd.AddArg("environment", "production", false)
+	cmdSuite.AddSubCommand(cmd)
+
+	os.MkdirAll("fake-home/conf.d/nested", 0777)
+	ioutil.WriteFile("fake-home/.my.cnf", []byte("[client]\nuser=one\npassword=foo\n!includedir conf.d\n!include doesnt-exist.cnf\n"), 0777)
+	ioutil.WriteFile("fake-home/conf.d/a.cnf", []byte("[client]\nuser=two\n!include nested/creds.txt\n"), 0777)
+	ioutil.WriteFile("fake-home/conf.d/b.txt", []byte("[client]\nuser=ignored\n"), 0777)
+	ioutil.WriteFile("fake-home/conf.d/nested/creds.txt", []byte("[mysql]\npassword=bar\nhost=uhoh\n!include ../../.my.cnf\n"), 0777)
+	defer os.RemoveAll("fake-home")
+
+	// Expectation: includedir only includes *.cnf; included files take
+	// precedence over the including file; nested includes are followed relative
+	// to the including file; include cycles and missing includes are not fatal;
+	// host is still ignored in included files
+	cfg := mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
+	AddGlobalConfigFiles(cfg)
+	if actualUser := cfg.Get("user"); actualUser != "two" {
+		t.Errorf("Expected user from fake-home/conf.d/a.cnf; instead found %s", actualUser)
+	}
+	if actualPassword := cfg.Get("password"); actualPassword != "bar" {
+		t.Errorf("Expected password from fake-home/conf.d/nested/creds.txt; instead found %s", actualPassword)
+	}
+	if cfg.Supplied("host") {
+		t.Error("Expected host to be ignored in included file, but it was parsed anyway")
+	}
+
+	// Expectation: --skip-my-cnf also skips includes
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --skip-my-cnf")
+	AddGlobalConfigFiles(cfg)
+	if cfg.Supplied("user") || cfg.Supplied("password") {
+		t.Errorf("Expected user and password to be unsupplied with --skip-my-cnf; instead found %q, %q", cfg.GetRaw("user"), cfg.GetRaw("password"))
+	}
+}
+
 func TestPasswordOption(t *testing.T) {
 	assertPassword := func(cfg *mybase.Config, expected string) {
 		t.Helper()
@@ -162,6 +200,12 @@ func TestPasswordOption(t *testing.T) {
 	if err := ProcessSpecialGlobalOptions(cfg); err != nil {
 		t.Errorf("Unexpected error from ProcessSpecialGlobalOptions: %v", err)
 	}
+
+	// Invalid log-format values should return the standard enum error
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --password='' --log-format=xml")
+	if err := ProcessSpecialGlobalOptions(cfg); err == nil || !strings.Contains(err.Error(), "can only be set to one of these values") {
+		t.Errorf("Expected enum error from ProcessSpecialGlobalOptions with invalid log-format, instead found %v", err)
+	}
 }
 
 func TestSplitConnectOptions(t *testing.T) {
@@ -206,6 +250,33 @@ func TestSplitConnectOptions(t *testing.T) {
 	}
 }
 
+func TestExpandEnvVars(t *testing.T) {
+	os.Setenv("SKEEMA_TEST_TZ", "'+00:00'")
+	os.Setenv("SKEEMA_TEST_EMPTY", "")
+	defer func() {
+		os.Unsetenv("SKEEMA_TEST_TZ")
+		os.Unsetenv("SKEEMA_TEST_EMPTY")
+	}()
+	cases := map[string]string{
+		"":                            "",
+		"time_zone=${SKEEMA_TEST_TZ}": "time_zone='+00:00'",
+		"a=${SKEEMA_TEST_TZ},b=2":     "a='+00:00',b=2",
+		"x='${SKEEMA_TEST_EMPTY}'":    "x=''",
+		"no_braces=$SKEEMA_TEST_TZ":   "no_braces=$SKEEMA_TEST_TZ",
+		"two=${SKEEMA_TEST_TZ}${SKEEMA_TEST_EMPTY}": "two='+00:00'",
+	}
+	for input, expected := range cases {
+		if actual, err := ExpandEnvVars(input); err != nil {
+			t.Errorf("Unexpected error from ExpandEnvVars(%q): %s", input, err)
+		} else if actual != expected {
+			t.Errorf("Expected ExpandEnvVars(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+	if _, err := ExpandEnvVars("a=${SKEEMA_TEST_TZ},b=${SKEEMA_TEST_UNDEFINED}"); err == nil {
+		t.Error("Expected error from ExpandEnvVars with undefined variable, but err was nil")
+	}
+}
+
 func TestRealConnectOptions(t *testing.T) {
 	assertResult := func(input, expected string) {
 		actual, err := RealConnectOptions(input)
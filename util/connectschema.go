@@ -0,0 +1,80 @@
+package util
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/tengo"
+)
+
+// ConnectSchemaDriverName is the name of the database/sql driver which wraps
+// the mysql driver, permitting connections to be established with a different
+// default database than the one requested. Instances are only switched to use
+// this driver if their DSN contains ConnectSchemaParam.
+const ConnectSchemaDriverName = "mysql-skeema-connect-schema"
+
+// ConnectSchemaParam is a DSN param which is handled by the driver named by
+// ConnectSchemaDriverName, rather than being passed through to the server as a
+// session variable. Its value is the default database to use when establishing
+// each new connection. If the connection pool's requested default database
+// differs, each new connection then switches to it via USE.
+const ConnectSchemaParam = "skeemaConnectSchema"
+
+func init() {
+	// The connect-schema driver also handles CredentialsParam, so that it may be
+	// used with instances that would otherwise use the credentials driver.
+	if inner := mysqlDriver(); inner != nil {
+		sql.Register(ConnectSchemaDriverName, connectSchemaDriver{inner: credentialsDriver{inner: inner}})
+	}
+}
+
+// ApplyConnectSchema switches inst to use the connect-schema driver, if
+// dsn contains ConnectSchemaParam. Otherwise, inst is left as-is. Only
+// connection pools created after this call are affected.
+func ApplyConnectSchema(inst *tengo.Instance, dsn string) {
+	if inst == nil || inst.Driver != "mysql" {
+		return
+	}
+	if cfg, err := mysql.ParseDSN(dsn); err == nil && cfg.Params[ConnectSchemaParam] != "" {
+		inst.Driver = ConnectSchemaDriverName
+	}
+}
+
+// connectSchemaDriver wraps another driver. For DSNs containing
+// ConnectSchemaParam, each connection is established using the param's value
+// as the default database, and then switched to the DSN's original default
+// database, if any. Other DSNs are passed through to the inner driver as-is.
+type connectSchemaDriver struct {
+	inner driver.Driver
+}
+
+func (csd connectSchemaDriver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connectSchema, ok := cfg.Params[ConnectSchemaParam]
+	if !ok {
+		return csd.inner.Open(dsn)
+	}
+	delete(cfg.Params, ConnectSchemaParam)
+	targetSchema := cfg.DBName
+	cfg.DBName = connectSchema
+	conn, err := csd.inner.Open(cfg.FormatDSN())
+	if err != nil || targetSchema == "" || targetSchema == connectSchema {
+		return conn, err
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("Driver does not support switching default database")
+	}
+	if _, err := execer.ExecContext(context.Background(), "USE "+tengo.EscapeIdentifier(targetSchema), nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
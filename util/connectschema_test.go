@@ -0,0 +1,100 @@
+package util
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/tengo"
+)
+
+// recordingDriver is a driver whose connections record the DSN they were
+// opened with, as well as each statement executed directly on them.
+type recordingDriver struct {
+	conns *[]*recordingConn
+}
+type recordingConn struct {
+	fakeConn
+	dsn   string
+	execs []string
+}
+
+func (rd recordingDriver) Open(dsn string) (driver.Conn, error) {
+	conn := &recordingConn{dsn: dsn}
+	*rd.conns = append(*rd.conns, conn)
+	return conn, nil
+}
+func (rc *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rc.execs = append(rc.execs, query)
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestConnectSchemaDriver(t *testing.T) {
+	var conns []*recordingConn
+	csd := connectSchemaDriver{inner: recordingDriver{conns: &conns}}
+	assertOpen := func(dsn, expectDBName string, expectExecs ...string) {
+		t.Helper()
+		conns = nil
+		if _, err := csd.Open(dsn); err != nil {
+			t.Fatalf("Unexpected error from Open(%q): %s", dsn, err)
+		} else if len(conns) != 1 {
+			t.Fatalf("Expected Open(%q) to open 1 inner connection, instead found %d", dsn, len(conns))
+		}
+		cfg, err := mysql.ParseDSN(conns[0].dsn)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing inner DSN %q: %s", conns[0].dsn, err)
+		}
+		if cfg.DBName != expectDBName {
+			t.Errorf("Expected Open(%q) to connect with default database %q, instead found %q", dsn, expectDBName, cfg.DBName)
+		}
+		if _, ok := cfg.Params[ConnectSchemaParam]; ok {
+			t.Errorf("Expected Open(%q) to strip %s from inner DSN, but it was retained: %s", dsn, ConnectSchemaParam, conns[0].dsn)
+		}
+		if strings.Join(conns[0].execs, "; ") != strings.Join(expectExecs, "; ") {
+			t.Errorf("Expected Open(%q) to execute %v, instead found %v", dsn, expectExecs, conns[0].execs)
+		}
+	}
+
+	// DSNs without the param are passed through as-is
+	dsn := "root:secret@tcp(1.2.3.4:3306)/product?foreign_key_checks=0"
+	assertOpen(dsn, "product")
+	if conns[0].dsn != dsn {
+		t.Errorf("Expected DSN to be passed through as-is, instead found %q", conns[0].dsn)
+	}
+
+	// With the param, the connection should be established using the param's
+	// value, and then switched to the requested database if one was requested
+	assertOpen("root:secret@tcp(1.2.3.4:3306)/product?foreign_key_checks=0&"+ConnectSchemaParam+"=gateway", "gateway", "USE `product`")
+	assertOpen("root:secret@tcp(1.2.3.4:3306)/?"+ConnectSchemaParam+"=gateway", "gateway")
+	assertOpen("root:secret@tcp(1.2.3.4:3306)/gateway?"+ConnectSchemaParam+"=gateway", "gateway")
+	assertOpen("root:secret@unix(/tmp/mysql.sock)/we`ird?"+ConnectSchemaParam+"=my%20db", "my db", "USE `we``ird`")
+
+	// Failure to switch databases should result in an error
+	if _, err := csd.Open("root:secret@tcp(1.2.3.4:3306)/failure?" + ConnectSchemaParam + "=gateway"); err == nil {
+		t.Error("Expected error from Open when USE fails, but err was nil")
+	}
+}
+
+func TestApplyConnectSchema(t *testing.T) {
+	dsn := "root:secret@tcp(1.2.3.4:3306)/?foreign_key_checks=0"
+	inst, err := tengo.NewInstance("mysql", dsn)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if ApplyConnectSchema(inst, dsn); inst.Driver != "mysql" {
+		t.Errorf("Expected instance driver to be unchanged without %s, instead found %q", ConnectSchemaParam, inst.Driver)
+	}
+	dsn += "&" + ConnectSchemaParam + "=gateway"
+	if inst, err = tengo.NewInstance("mysql", dsn); err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if ApplyConnectSchema(inst, dsn); inst.Driver != ConnectSchemaDriverName {
+		t.Errorf("Expected instance driver to be switched with %s, instead found %q", ConnectSchemaParam, inst.Driver)
+	}
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/tengo"
+)
+
+// CredentialsDriverName is the name of the database/sql driver which wraps the
+// mysql driver, obtaining the user and password for each new connection from a
+// CredentialProvider. Instances are only switched to use this driver if their
+// DSN contains CredentialsParam.
+const CredentialsDriverName = "mysql-skeema-credentials"
+
+// CredentialsParam is a DSN param which is handled by the driver named by
+// CredentialsDriverName, rather than being passed through to the server as a
+// session variable. Its value is the name of a registered CredentialProvider.
+const CredentialsParam = "skeemaCredentials"
+
+// CredentialProvider supplies database credentials which may change over
+// time, such as short-lived credentials issued by a secrets manager.
+// Credentials is called each time a new connection is established, so
+// implementations should cache credentials until they are close to expiring.
+type CredentialProvider interface {
+	Credentials() (user, password string, err error)
+}
+
+var credentialProviders struct {
+	sync.Mutex
+	m map[string]CredentialProvider
+}
+
+func init() {
+	if inner := mysqlDriver(); inner != nil {
+		sql.Register(CredentialsDriverName, credentialsDriver{inner: inner})
+	}
+}
+
+// mysqlDriver returns the mysql driver, as registered by tengo's import of it,
+// for wrapping by this package's drivers. It does not connect to anything,
+// since sql.Open never establishes a connection by itself. The return value is
+// nil if the driver is somehow not registered.
+func mysqlDriver() driver.Driver {
+	db, err := sql.Open("mysql", "")
+	if err != nil {
+		return nil
+	}
+	return db.Driver()
+}
+
+// RegisterCredentialProvider makes provider available to connections whose
+// DSN sets CredentialsParam to name. Registering a provider with an existing
+// name replaces the previous provider.
+func RegisterCredentialProvider(name string, provider CredentialProvider) {
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	if credentialProviders.m == nil {
+		credentialProviders.m = make(map[string]CredentialProvider)
+	}
+	credentialProviders.m[name] = provider
+}
+
+// LookupCredentialProvider returns the provider registered with name, or nil
+// if there is no such provider.
+func LookupCredentialProvider(name string) CredentialProvider {
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	return credentialProviders.m[name]
+}
+
+// ApplyCredentials switches inst to use the credentials driver, if dsn
+// contains CredentialsParam and inst is not already using a driver which
+// handles it. Otherwise, inst is left as-is. Only connection pools created
+// after this call are affected.
+func ApplyCredentials(inst *tengo.Instance, dsn string) {
+	if inst == nil || inst.Driver != "mysql" {
+		return
+	}
+	if cfg, err := mysql.ParseDSN(dsn); err == nil && cfg.Params[CredentialsParam] != "" {
+		inst.Driver = CredentialsDriverName
+	}
+}
+
+// credentialsDriver wraps another driver. For DSNs containing
+// CredentialsParam, each connection is established using the user and password
+// currently supplied by the named CredentialProvider. Other DSNs are passed
+// through to the inner driver as-is.
+type credentialsDriver struct {
+	inner driver.Driver
+}
+
+func (cd credentialsDriver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := cfg.Params[CredentialsParam]
+	if !ok {
+		return cd.inner.Open(dsn)
+	}
+	delete(cfg.Params, CredentialsParam)
+	provider := LookupCredentialProvider(name)
+	if provider == nil {
+		return nil, fmt.Errorf("No credential provider registered with name %q", name)
+	}
+	if cfg.User, cfg.Passwd, err = provider.Credentials(); err != nil {
+		return nil, fmt.Errorf("Unable to obtain database credentials: %s", err)
+	}
+	return cd.inner.Open(cfg.FormatDSN())
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/tengo"
+)
+
+// staticCredentials is a CredentialProvider returning fixed values.
+type staticCredentials struct {
+	user, password string
+	err            error
+}
+
+func (sc *staticCredentials) Credentials() (string, string, error) {
+	return sc.user, sc.password, sc.err
+}
+
+func TestCredentialsDriver(t *testing.T) {
+	var conns []*recordingConn
+	cd := credentialsDriver{inner: recordingDriver{conns: &conns}}
+	provider := &staticCredentials{user: "v-token-app-abc123", password: "s3cr3t"}
+	RegisterCredentialProvider("test-static", provider)
+
+	// DSNs without the param are passed through as-is
+	dsn := "root:secret@tcp(1.2.3.4:3306)/product?foreign_key_checks=0"
+	if _, err := cd.Open(dsn); err != nil {
+		t.Fatalf("Unexpected error from Open(%q): %s", dsn, err)
+	} else if conns[0].dsn != dsn {
+		t.Errorf("Expected DSN to be passed through as-is, instead found %q", conns[0].dsn)
+	}
+
+	// With the param, credentials come from the provider each time
+	dsn = "@tcp(1.2.3.4:3306)/product?foreign_key_checks=0&" + CredentialsParam + "=test-static"
+	for _, expectUser := range []string{"v-token-app-abc123", "v-token-app-def456"} {
+		provider.user = expectUser
+		conns = nil
+		if _, err := cd.Open(dsn); err != nil {
+			t.Fatalf("Unexpected error from Open(%q): %s", dsn, err)
+		}
+		cfg, err := mysql.ParseDSN(conns[0].dsn)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing inner DSN %q: %s", conns[0].dsn, err)
+		}
+		if cfg.User != expectUser || cfg.Passwd != "s3cr3t" || cfg.DBName != "product" {
+			t.Errorf("Unexpected inner DSN %q", conns[0].dsn)
+		}
+		if _, ok := cfg.Params[CredentialsParam]; ok {
+			t.Errorf("Expected Open to strip %s from inner DSN, but it was retained: %s", CredentialsParam, conns[0].dsn)
+		}
+	}
+
+	// Provider errors, and unknown provider names, result in errors
+	provider.err = errors.New("lease revoked")
+	if _, err := cd.Open(dsn); err == nil {
+		t.Error("Expected error from Open when provider fails, but err was nil")
+	}
+	if _, err := cd.Open("@tcp(1.2.3.4:3306)/?" + CredentialsParam + "=doesnt-exist"); err == nil {
+		t.Error("Expected error from Open with unknown provider, but err was nil")
+	}
+
+	// Connect-schema driver passes credentials param through to inner driver
+	provider.err = nil
+	csd := connectSchemaDriver{inner: cd}
+	conns = nil
+	if _, err := csd.Open(dsn + "&" + ConnectSchemaParam + "=gateway"); err != nil {
+		t.Fatalf("Unexpected error from Open: %s", err)
+	} else if cfg, _ := mysql.ParseDSN(conns[0].dsn); cfg.User != provider.user || cfg.DBName != "gateway" {
+		t.Errorf("Unexpected inner DSN %q", conns[0].dsn)
+	}
+}
+
+func TestApplyCredentials(t *testing.T) {
+	dsn := "root:secret@tcp(1.2.3.4:3306)/?foreign_key_checks=0"
+	inst, err := tengo.NewInstance("mysql", dsn)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if ApplyCredentials(inst, dsn); inst.Driver != "mysql" {
+		t.Errorf("Expected instance driver to be unchanged without %s, instead found %q", CredentialsParam, inst.Driver)
+	}
+	dsn = "@tcp(1.2.3.4:3306)/?" + CredentialsParam + "=test-static"
+	if inst, err = tengo.NewInstance("mysql", dsn); err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if ApplyCredentials(inst, dsn); inst.Driver != CredentialsDriverName {
+		t.Errorf("Expected instance driver to be switched with %s, instead found %q", CredentialsParam, inst.Driver)
+	}
+
+	// Instances already using the connect-schema driver are left as-is, since
+	// that driver handles credentials too
+	inst.Driver = ConnectSchemaDriverName
+	if ApplyCredentials(inst, dsn); inst.Driver != ConnectSchemaDriverName {
+		t.Errorf("Expected instance driver to be unchanged, instead found %q", inst.Driver)
+	}
+}
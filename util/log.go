@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	log.AddHook(redactionHook{})
+}
+
+// SetLogFormat configures the global logger to use the supplied format, which
+// must be either "text" or "json". With "text", the logger's existing
+// formatter is left alone.
+func SetLogFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return nil
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{
+			FieldMap: log.FieldMap{
+				log.FieldKeyTime: "time",
+				log.FieldKeyMsg:  "message",
+			},
+		})
+		return nil
+	}
+	return fmt.Errorf("Invalid log-format value %q: must be either \"text\" or \"json\"", format)
+}
+
+// redactionHook is a logrus hook which ensures that passwords never appear in
+// log output, regardless of log level or formatter. Structured fields with
+// secret-sounding names have their values masked entirely, and any DSN-style
+// credentials embedded in messages or string fields are masked as well.
+type redactionHook struct{}
+
+// Levels returns all log levels, since redaction must occur regardless of
+// level.
+func (redactionHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire masks secrets in the entry. The entry's Data map is replaced rather
+// than modified in-place, since it may be shared with the caller.
+func (redactionHook) Fire(entry *log.Entry) error {
+	entry.Message = RedactSecrets(entry.Message)
+	if len(entry.Data) == 0 {
+		return nil
+	}
+	data := make(log.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if isSecretFieldName(k) {
+			data[k] = redactedValue
+		} else if s, ok := v.(string); ok {
+			data[k] = RedactSecrets(s)
+		} else {
+			data[k] = v
+		}
+	}
+	entry.Data = data
+	return nil
+}
+
+const redactedValue = "*****"
+
+var reDSNPassword = regexp.MustCompile(`([^\s:/@]+):\S*?@(tcp|unix)\(`)
+
+// RedactSecrets returns a copy of s with any passwords in DSN-style strings
+// (user:pass@tcp(host:port)/ or user:pass@unix(/path)/) masked.
+func RedactSecrets(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return reDSNPassword.ReplaceAllString(s, "$1:"+redactedValue+"@$2(")
+}
+
+func isSecretFieldName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"password", "passwd", "secret", "token", "dsn"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	cases := map[string]string{
+		"no secrets here":                                   "no secrets here",
+		"root:hunter2@tcp(127.0.0.1:3306)/":                 "root:*****@tcp(127.0.0.1:3306)/",
+		"dsn root:p@ss:w0rd@unix(/tmp/mysql.sock)/?foo=bar": "dsn root:*****@unix(/tmp/mysql.sock)/?foo=bar",
+		"root:@tcp(localhost:3306)/":                        "root:*****@tcp(localhost:3306)/",
+		"user@example.com":                                  "user@example.com",
+	}
+	for input, expected := range cases {
+		if actual := RedactSecrets(input); actual != expected {
+			t.Errorf("Expected RedactSecrets(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
+
+func TestRedactionHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New()
+	logger.Out = &buf
+	logger.Formatter = &log.JSONFormatter{}
+	logger.AddHook(redactionHook{})
+
+	fields := log.Fields{
+		"password": "hunter2",
+		"instance": "localhost:3306",
+		"conn":     "root:hunter2@tcp(localhost:3306)/",
+		"count":    3,
+	}
+	logger.WithFields(fields).Warn("Connecting via root:hunter2@tcp(localhost:3306)/")
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Fatalf("Secret present in log output: %s", buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Unable to parse JSON log output %q: %s", buf.String(), err)
+	}
+	if entry["password"] != redactedValue || entry["instance"] != "localhost:3306" || entry["count"] != float64(3) {
+		t.Errorf("Unexpected field values in log output: %+v", entry)
+	}
+
+	// Caller's fields must not be modified in-place
+	if fields["password"] != "hunter2" {
+		t.Error("redactionHook unexpectedly modified caller's log.Fields")
+	}
+}
+
+func TestSetLogFormat(t *testing.T) {
+	origFormatter := log.StandardLogger().Formatter
+	defer log.SetFormatter(origFormatter)
+
+	if err := SetLogFormat("text"); err != nil {
+		t.Errorf("Unexpected error from SetLogFormat: %s", err)
+	} else if log.StandardLogger().Formatter != origFormatter {
+		t.Error("Expected log-format=text to leave formatter unchanged")
+	}
+	if err := SetLogFormat("json"); err != nil {
+		t.Errorf("Unexpected error from SetLogFormat: %s", err)
+	} else if _, ok := log.StandardLogger().Formatter.(*log.JSONFormatter); !ok {
+		t.Errorf("Expected log-format=json to use JSONFormatter, instead found %T", log.StandardLogger().Formatter)
+	}
+	if err := SetLogFormat("xml"); err == nil {
+		t.Error("Expected error from SetLogFormat with invalid value, but err was nil")
+	}
+}
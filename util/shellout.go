@@ -1,9 +1,12 @@
 package util
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
@@ -18,6 +21,7 @@ type ShellOut struct {
 	Dir              string        // Initial working dir for the command if non-empty
 	Timeout          time.Duration // If > 0, kill process after this amount of time
 	CombineOutput    bool          // If true, combine stdout and stderr into a single stream
+	OutputFunc       func(string)  // If non-nil, Run passes each line of combined stdout and stderr to this func
 	cancelFunc       context.CancelFunc
 }
 
@@ -39,7 +43,8 @@ func (s *ShellOut) cmd() *exec.Cmd {
 
 // Run shells out to the external command and blocks until it completes. It
 // returns an error if one occurred. STDIN, STDOUT, and STDERR will be
-// redirected to those of the parent process.
+// redirected to those of the parent process, unless OutputFunc is set, in
+// which case each line of output is passed to it instead.
 func (s *ShellOut) Run() error {
 	if s.Command == "" {
 		return errors.New("Attempted to shell out to an empty command string")
@@ -50,6 +55,9 @@ func (s *ShellOut) Run() error {
 	}
 	cmd.Dir = s.Dir
 	cmd.Stdin = os.Stdin
+	if s.OutputFunc != nil {
+		return s.runWithOutputFunc(cmd)
+	}
 	cmd.Stdout = os.Stdout
 	if s.CombineOutput {
 		cmd.Stderr = os.Stdout
@@ -59,6 +67,28 @@ func (s *ShellOut) Run() error {
 	return cmd.Run()
 }
 
+// runWithOutputFunc runs cmd, passing each line of its combined STDOUT and
+// STDERR to s.OutputFunc as soon as the line is complete.
+func (s *ShellOut) runWithOutputFunc(cmd *exec.Cmd) error {
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			s.OutputFunc(scanner.Text())
+		}
+		io.Copy(ioutil.Discard, pr) // in case of an overly long line, avoid blocking the command
+		close(done)
+	}()
+	err := cmd.Run()
+	pw.Close()
+	<-done
+	return err
+}
+
 // RunCapture shells out to the external command and blocks until it completes.
 // It returns the command's STDOUT output as a single string, optionally with
 // STDERR if CombineOutput is true; otherwise STDERR is redirected to that of
@@ -26,6 +26,28 @@ func TestShellOutRun(t *testing.T) {
 	assertResult("true", "/invalid/dir", false)
 }
 
+func TestShellOutOutputFunc(t *testing.T) {
+	var lines []string
+	s := &ShellOut{
+		Command:    "echo hello; echo world >&2; printf 'no newline'",
+		OutputFunc: func(line string) { lines = append(lines, line) },
+	}
+	if err := s.Run(); err != nil {
+		t.Fatalf("Unexpected error from Run: %s", err)
+	}
+	if expected := []string{"hello", "world", "no newline"}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unexpected lines passed to OutputFunc: expected %v, found %v", expected, lines)
+	}
+
+	lines = nil
+	s.Command = "echo failing; false"
+	if err := s.Run(); err == nil {
+		t.Error("Expected command to return an error, but it did not")
+	} else if len(lines) != 1 || lines[0] != "failing" {
+		t.Errorf("Unexpected lines passed to OutputFunc: %v", lines)
+	}
+}
+
 func TestRunCaptureSplit(t *testing.T) {
 	assertResult := func(command string, expectedTokens ...string) {
 		s := &ShellOut{Command: command}
@@ -0,0 +1,253 @@
+package util
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skeema/tengo"
+)
+
+// EchoDriverName is the name of the database/sql driver which wraps the mysql
+// driver, echoing each statement executed. Instances are only switched to use
+// this driver if SQL echo has been enabled via EnableSQLEcho.
+const EchoDriverName = "mysql-skeema-echo"
+
+var sqlEcho struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func init() {
+	// The echo driver also handles ConnectSchemaParam and CredentialsParam, so
+	// that it may be used with instances that would otherwise use the
+	// connect-schema or credentials drivers.
+	if inner := mysqlDriver(); inner != nil {
+		sql.Register(EchoDriverName, echoDriver{inner: connectSchemaDriver{inner: credentialsDriver{inner: inner}}})
+	}
+}
+
+// EnableSQLEcho causes all subsequent statements executed via Instances passed
+// to ApplySQLEcho to be written to w, along with their execution time. This
+// includes both DDL and introspection queries. Supplying a nil w disables echo.
+// String literals are redacted from the output, and query arg values are never
+// written.
+func EnableSQLEcho(w io.Writer) {
+	sqlEcho.Lock()
+	defer sqlEcho.Unlock()
+	sqlEcho.w = w
+}
+
+// SQLEchoEnabled returns true if EnableSQLEcho was called with a non-nil
+// writer.
+func SQLEchoEnabled() bool {
+	sqlEcho.Lock()
+	defer sqlEcho.Unlock()
+	return sqlEcho.w != nil
+}
+
+// ApplySQLEcho switches inst to use the echoing driver, if SQL echo is enabled.
+// Otherwise, inst is left as-is. Only connection pools created after this call
+// are affected.
+func ApplySQLEcho(inst *tengo.Instance) {
+	if inst != nil && SQLEchoEnabled() && (inst.Driver == "mysql" || inst.Driver == ConnectSchemaDriverName || inst.Driver == CredentialsDriverName) {
+		inst.Driver = EchoDriverName
+	}
+}
+
+// reSQLStringLiteral matches single- or double-quoted string literals,
+// including any backslash-escaped or doubled quote characters inside them. It
+// also matches backtick-quoted identifiers, so that quote characters inside
+// identifiers are not mistaken for the start of a literal.
+var reSQLStringLiteral = regexp.MustCompile("`(?:[^`]|``)*`" + `|'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+
+// RedactSQL returns a version of statement with all string literals replaced
+// by '?', and runs of whitespace collapsed into single spaces. This strips
+// values which could be sensitive, such as column defaults or comments, while
+// keeping the statement's structure readable.
+func RedactSQL(statement string) string {
+	statement = reSQLStringLiteral.ReplaceAllStringFunc(statement, func(match string) string {
+		if match[0] == '`' {
+			return match
+		}
+		return "'?'"
+	})
+	return strings.Join(strings.Fields(statement), " ")
+}
+
+// reDSNAddress extracts the address and default schema from a DSN.
+var reDSNAddress = regexp.MustCompile(`@(?:tcp|unix)\(([^)]*)\)/([^?]*)`)
+
+// echoSQL writes a line describing one executed statement, if echo is enabled.
+func echoSQL(dsn, statement string, numArgs int, elapsed time.Duration, err error) {
+	sqlEcho.Lock()
+	defer sqlEcho.Unlock()
+	if sqlEcho.w == nil {
+		return
+	}
+	var location string
+	if matches := reDSNAddress.FindStringSubmatch(dsn); matches != nil {
+		location = matches[1] + "/" + matches[2] + " "
+	}
+	line := fmt.Sprintf("-- [sql] %s%.3fms: %s", location, elapsed.Seconds()*1000, RedactSQL(statement))
+	if numArgs > 0 {
+		line = fmt.Sprintf("%s (%d args)", line, numArgs)
+	}
+	if err != nil {
+		line = fmt.Sprintf("%s (error: %s)", line, err)
+	}
+	fmt.Fprintln(sqlEcho.w, line)
+}
+
+// echoDriver wraps another driver, echoing statements executed through its
+// connections. Statements are echoed when they complete, so that timing can
+// be included.
+type echoDriver struct {
+	inner driver.Driver
+}
+
+func (ed echoDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := ed.inner.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &echoConn{Conn: conn, dsn: dsn}, nil
+}
+
+// echoConn wraps a driver.Conn. It passes through the optional context-aware
+// interfaces to the inner connection when supported, since database/sql
+// behaves differently based on which interfaces a connection implements.
+type echoConn struct {
+	driver.Conn
+	dsn string
+}
+
+func (ec *echoConn) Prepare(query string) (driver.Stmt, error) {
+	return ec.PrepareContext(context.Background(), query)
+}
+
+func (ec *echoConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if cpc, ok := ec.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cpc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = ec.Conn.Prepare(query)
+	}
+	if err != nil {
+		echoSQL(ec.dsn, query, 0, 0, err)
+		return nil, err
+	}
+	return &echoStmt{Stmt: stmt, conn: ec, query: query}, nil
+}
+
+func (ec *echoConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := ec.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		echoSQL(ec.dsn, query, len(args), time.Since(start), err)
+	}
+	return result, err
+}
+
+func (ec *echoConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := ec.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		echoSQL(ec.dsn, query, len(args), time.Since(start), err)
+	}
+	return rows, err
+}
+
+func (ec *echoConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if cbt, ok := ec.Conn.(driver.ConnBeginTx); ok {
+		return cbt.BeginTx(ctx, opts)
+	}
+	return ec.Conn.Begin()
+}
+
+func (ec *echoConn) Ping(ctx context.Context) error {
+	if pinger, ok := ec.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (ec *echoConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := ec.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (ec *echoConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := ec.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// echoStmt wraps a prepared driver.Stmt.
+type echoStmt struct {
+	driver.Stmt
+	conn  *echoConn
+	query string
+}
+
+func (es *echoStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	result, err := es.Stmt.Exec(args)
+	echoSQL(es.conn.dsn, es.query, len(args), time.Since(start), err)
+	return result, err
+}
+
+func (es *echoStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := es.Stmt.Query(args)
+	echoSQL(es.conn.dsn, es.query, len(args), time.Since(start), err)
+	return rows, err
+}
+
+func (es *echoStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	sec, ok := es.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return es.Exec(namedValuesToValues(args))
+	}
+	start := time.Now()
+	result, err := sec.ExecContext(ctx, args)
+	echoSQL(es.conn.dsn, es.query, len(args), time.Since(start), err)
+	return result, err
+}
+
+func (es *echoStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	sqc, ok := es.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return es.Query(namedValuesToValues(args))
+	}
+	start := time.Now()
+	rows, err := sqc.QueryContext(ctx, args)
+	echoSQL(es.conn.dsn, es.query, len(args), time.Since(start), err)
+	return rows, err
+}
+
+func namedValuesToValues(named []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(named))
+	for n, nv := range named {
+		values[n] = nv.Value
+	}
+	return values
+}
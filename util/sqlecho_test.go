@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/tengo"
+)
+
+func TestRedactSQL(t *testing.T) {
+	cases := map[string]string{
+		"SELECT 1": "SELECT 1",
+		"SELECT  table_name\n\tFROM t WHERE x = ?":                "SELECT table_name FROM t WHERE x = ?",
+		"ALTER TABLE t ADD COLUMN c int DEFAULT '5' COMMENT 'hi'": "ALTER TABLE t ADD COLUMN c int DEFAULT '?' COMMENT '?'",
+		`SET @x = "it\"s", @y = 'it''s', @z = 'a\\'`:              `SET @x = '?', @y = '?', @z = '?'`,
+		"SELECT `weird'name` FROM t WHERE y = 'z'":                "SELECT `weird'name` FROM t WHERE y = '?'",
+	}
+	for input, expected := range cases {
+		if actual := RedactSQL(input); actual != expected {
+			t.Errorf("Unexpected result from RedactSQL(%q): expected %q, found %q", input, expected, actual)
+		}
+	}
+}
+
+// fakeDriver is a driver with connections that succeed at executing any
+// statement, except those containing "fail".
+type fakeDriver struct{}
+type fakeConn struct{}
+type fakeStmt struct{ query string }
+type fakeRows struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{query: query}, nil
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+func (st fakeStmt) Close() error           { return nil }
+func (st fakeStmt) NumInput() int          { return -1 }
+func (st fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(st.query, "fail") {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(0), nil
+}
+func (st fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+func (fakeRows) Columns() []string                                 { return []string{"x"} }
+func (fakeRows) Close() error                                      { return nil }
+func (fakeRows) Next(dest []driver.Value) error                    { return io.EOF }
+
+func init() {
+	sql.Register("skeematest-echo", echoDriver{inner: fakeDriver{}})
+}
+
+func TestSQLEcho(t *testing.T) {
+	defer EnableSQLEcho(nil)
+	db, err := sql.Open("skeematest-echo", "root:secret@tcp(1.2.3.4:3306)/product")
+	if err != nil {
+		t.Fatalf("Unexpected error from sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	// Nothing should be written while echo is disabled, and instances should not
+	// be switched to the echo driver
+	var buf bytes.Buffer
+	EnableSQLEcho(nil)
+	if _, err := db.Exec("CREATE TABLE foo (id int)"); err != nil {
+		t.Fatalf("Unexpected error from Exec: %s", err)
+	}
+	inst, err := tengo.NewInstance("mysql", "root:secret@tcp(1.2.3.4:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if ApplySQLEcho(inst); inst.Driver != "mysql" || SQLEchoEnabled() {
+		t.Errorf("Expected instance driver to be unchanged with echo disabled, instead found %q", inst.Driver)
+	}
+
+	// Once enabled, each statement should be written along with its timing, but
+	// without literals, arg values, or passwords
+	EnableSQLEcho(&buf)
+	if ApplySQLEcho(inst); inst.Driver != EchoDriverName {
+		t.Errorf("Expected instance driver to be switched with echo enabled, instead found %q", inst.Driver)
+	}
+	if _, err := db.Exec("ALTER TABLE foo COMMENT 'topsecret'"); err != nil {
+		t.Fatalf("Unexpected error from Exec: %s", err)
+	}
+	if rows, err := db.Query("SELECT x FROM foo WHERE y = ? AND z = ?", "hidden", 123); err != nil {
+		t.Fatalf("Unexpected error from Query: %s", err)
+	} else {
+		rows.Close()
+	}
+	if _, err := db.Exec("DROP TABLE fail"); err == nil {
+		t.Fatal("Expected error from Exec, but returned nil")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines of output, instead found %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "-- [sql] 1.2.3.4:3306/product ") || !strings.Contains(line, "ms: ") {
+			t.Errorf("Line of output missing expected location or timing: %s", line)
+		}
+		for _, forbidden := range []string{"secret", "hidden", "123"} {
+			if strings.Contains(line, forbidden) {
+				t.Errorf("Line of output unexpectedly contains %q: %s", forbidden, line)
+			}
+		}
+	}
+	if !strings.HasSuffix(lines[1], "SELECT x FROM foo WHERE y = ? AND z = ? (2 args)") {
+		t.Errorf("Unexpected output for query: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "DROP TABLE fail (error: failed)") {
+		t.Errorf("Unexpected output for failed statement: %s", lines[2])
+	}
+
+	// ProcessSpecialGlobalOptions should only enable echo with show-sql
+	cmdSuite := mybase.NewCommandSuite("skeematest", "", "")
+	AddGlobalOptions(cmdSuite)
+	cmdSuite.AddSubCommand(mybase.NewCommand("diff", "", "", nil))
+	EnableSQLEcho(nil)
+	cfg := mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
+	if err := ProcessSpecialGlobalOptions(cfg); err != nil || SQLEchoEnabled() {
+		t.Errorf("Unexpected result from ProcessSpecialGlobalOptions without show-sql: err=%v, enabled=%t", err, SQLEchoEnabled())
+	}
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --show-sql")
+	if err := ProcessSpecialGlobalOptions(cfg); err != nil || !SQLEchoEnabled() {
+		t.Errorf("Unexpected result from ProcessSpecialGlobalOptions with show-sql: err=%v, enabled=%t", err, SQLEchoEnabled())
+	}
+}
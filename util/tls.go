@@ -0,0 +1,162 @@
+package util
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/mybase"
+)
+
+var registeredTLSConfigs struct {
+	sync.Mutex
+	m map[string]bool
+}
+
+// TLSParamForConfig returns the value to use for the mysql driver's tls DSN
+// param, based on the ssl-mode, ssl-ca, ssl-cert, and ssl-key options in cfg.
+// If none of these options are set, an empty string is returned, leaving the
+// driver's default behavior (or any tls param in connect-options) in place.
+// The modes mirror those of the MySQL client:
+//
+//   - "disabled" never uses TLS
+//   - "preferred" uses TLS if the server supports it, without verifying the
+//     server's certificate
+//   - "required" always uses TLS, without verifying the server's certificate
+//   - "verify_ca" also verifies the server's certificate against ssl-ca
+//   - "verify_identity" also verifies that the certificate matches the host
+//
+// If ssl-mode is not set, it defaults to "verify_ca" if ssl-ca is set, or
+// "required" otherwise. A client certificate may be supplied in any mode other
+// than "disabled" by setting both ssl-cert and ssl-key; this forces use of TLS
+// even with "preferred". When a custom TLS configuration is needed, it is
+// registered with the mysql driver under a name derived from the options, so
+// that equivalent configurations are shared. Relative paths in ssl-ca,
+// ssl-cert, and ssl-key are interpreted relative to the option file which set
+// them.
+func TLSParamForConfig(cfg *mybase.Config) (string, error) {
+	caPath, certPath, keyPath := optionFilePath(cfg, "ssl-ca"), optionFilePath(cfg, "ssl-cert"), optionFilePath(cfg, "ssl-key")
+	if !cfg.Changed("ssl-mode") && caPath == "" && certPath == "" && keyPath == "" {
+		return "", nil
+	}
+	mode, err := cfg.GetEnum("ssl-mode", "disabled", "preferred", "required", "verify_ca", "verify_identity")
+	if err != nil {
+		return "", err
+	}
+	if !cfg.Changed("ssl-mode") {
+		if caPath != "" {
+			mode = "verify_ca"
+		} else {
+			mode = "required"
+		}
+	}
+	if (certPath == "") != (keyPath == "") {
+		return "", errors.New("Options ssl-cert and ssl-key must be used together")
+	}
+	if mode == "disabled" {
+		if caPath != "" || certPath != "" {
+			return "", errors.New("Options ssl-ca, ssl-cert, and ssl-key cannot be used with ssl-mode=disabled")
+		}
+		return "false", nil
+	} else if mode == "verify_ca" && caPath == "" {
+		return "", errors.New("Option ssl-mode=verify_ca requires ssl-ca to be set")
+	} else if certPath == "" && caPath == "" {
+		if mode == "verify_identity" {
+			return "true", nil
+		} else if mode == "preferred" {
+			return "preferred", nil
+		}
+		return "skip-verify", nil
+	}
+
+	name := fmt.Sprintf("skeema-%x", sha1.Sum([]byte(mode+"\x00"+caPath+"\x00"+certPath+"\x00"+keyPath)))
+	registeredTLSConfigs.Lock()
+	defer registeredTLSConfigs.Unlock()
+	if registeredTLSConfigs.m[name] {
+		return name, nil
+	}
+	tlsConfig := &tls.Config{}
+	if caPath != "" {
+		pem, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return "", fmt.Errorf("Unable to read ssl-ca file: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("No PEM-encoded certificates found in ssl-ca file %s", caPath)
+		}
+	}
+	if certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return "", fmt.Errorf("Unable to load ssl-cert and ssl-key: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	switch mode {
+	case "preferred", "required":
+		tlsConfig.InsecureSkipVerify = true
+	case "verify_ca":
+		// Go's TLS library always verifies the host name along with the CA, so
+		// instead the chain is verified separately without a host name
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifyChainFunc(tlsConfig.RootCAs)
+	}
+	if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+		return "", err
+	}
+	if registeredTLSConfigs.m == nil {
+		registeredTLSConfigs.m = make(map[string]bool)
+	}
+	registeredTLSConfigs.m[name] = true
+	return name, nil
+}
+
+// optionFilePath returns the value of the option name in cfg, which should be
+// a file path. Relative paths set in an option file are interpreted relative
+// to that file's directory.
+func optionFilePath(cfg *mybase.Config, name string) string {
+	value := cfg.Get(name)
+	if value == "" || filepath.IsAbs(value) {
+		return value
+	}
+	if f, ok := cfg.Source(name).(*mybase.File); ok {
+		return filepath.Join(f.Dir, value)
+	}
+	return value
+}
+
+// verifyChainFunc returns a function suitable for use as
+// tls.Config.VerifyPeerCertificate, which verifies that the server's
+// certificate chain is signed by one of roots, without checking the server's
+// host name.
+func verifyChainFunc(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("Server did not supply a TLS certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for n, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[n] = cert
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
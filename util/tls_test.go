@@ -0,0 +1,136 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/skeema/mybase"
+)
+
+// writeTestCertificate writes a self-signed certificate and its private key to
+// PEM files in dir, returning the certificate's DER bytes.
+func writeTestCertificate(t *testing.T, dir string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "skeema test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unable to marshal key: %s", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600); err != nil {
+		t.Fatalf("Unable to write certificate: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		t.Fatalf("Unable to write key: %s", err)
+	}
+	return der
+}
+
+func TestTLSParamForConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skeematls")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	der := writeTestCertificate(t, dir)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	tlsParam := func(mode, ca, cert, key string) (string, error) {
+		return TLSParamForConfig(mybase.SimpleConfig(map[string]string{
+			"ssl-mode": mode,
+			"ssl-ca":   ca,
+			"ssl-cert": cert,
+			"ssl-key":  key,
+		}))
+	}
+
+	// Modes without any files map to the driver's built-in values
+	cases := map[string]string{
+		"":                "",
+		"disabled":        "false",
+		"preferred":       "preferred",
+		"REQUIRED":        "skip-verify",
+		"verify_identity": "true",
+	}
+	for mode, expected := range cases {
+		if actual, err := tlsParam(mode, "", "", ""); err != nil || actual != expected {
+			t.Errorf("Unexpected return from TLSParamForConfig with ssl-mode=%q: %q / %v", mode, actual, err)
+		}
+	}
+
+	// Invalid values or combinations
+	invalid := [][]string{
+		{"bogus", "", "", ""},
+		{"verify_ca", "", "", ""},
+		{"disabled", certPath, "", ""},
+		{"required", "", certPath, ""},
+		{"", filepath.Join(dir, "missing.pem"), "", ""},
+		{"", keyPath, "", ""},
+		{"", "", keyPath, certPath},
+	}
+	for _, values := range invalid {
+		if _, err := tlsParam(values[0], values[1], values[2], values[3]); err == nil {
+			t.Errorf("Expected error from TLSParamForConfig with values %v, but err was nil", values)
+		}
+	}
+
+	// With files, a custom config is registered with the driver, and reused for
+	// equivalent options; ssl-ca without ssl-mode implies verify_ca
+	name, err := tlsParam("", certPath, certPath, keyPath)
+	if err != nil || !strings.HasPrefix(name, "skeema-") {
+		t.Fatalf("Unexpected return from TLSParamForConfig: %q / %v", name, err)
+	}
+	if name2, _ := tlsParam("verify_ca", certPath, certPath, keyPath); name2 != name {
+		t.Errorf("Expected equivalent options to reuse TLS config %q, instead found %q", name, name2)
+	}
+	if name3, _ := tlsParam("verify_identity", certPath, "", ""); name3 == name || name3 == "" {
+		t.Errorf("Expected different options to use a new TLS config, instead found %q", name3)
+	}
+	if _, err := mysql.ParseDSN("root@tcp(127.0.0.1:3306)/?tls=" + name); err != nil {
+		t.Errorf("Expected driver to recognize TLS config %q, instead found error %s", name, err)
+	}
+
+	// Chain verification for verify_ca does not depend on the host name
+	roots := x509.NewCertPool()
+	cert, _ := x509.ParseCertificate(der)
+	roots.AddCert(cert)
+	if err := verifyChainFunc(roots)([][]byte{der}, nil); err != nil {
+		t.Errorf("Unexpected error verifying certificate: %s", err)
+	}
+	if err := verifyChainFunc(x509.NewCertPool())([][]byte{der}, nil); err == nil {
+		t.Error("Expected error verifying certificate against empty pool, but err was nil")
+	}
+	if err := verifyChainFunc(roots)(nil, nil); err == nil {
+		t.Error("Expected error verifying missing certificate, but err was nil")
+	}
+}
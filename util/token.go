@@ -0,0 +1,154 @@
+package util
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+)
+
+// tokenNow returns the current time. It is a variable only to permit tests to
+// simulate token expiration.
+var tokenNow = time.Now
+
+// tokenLifetime is how long a token obtained from token-provider is reused
+// for new connections. This is shorter than the lifetime of typical
+// authentication tokens, such as the 15 minutes of Amazon RDS IAM tokens.
+const tokenLifetime = 5 * time.Minute
+
+// TokenCredentialProvider is a CredentialProvider which uses a fixed user,
+// and obtains the password by running an external command, such as one which
+// generates a short-lived authentication token for a cloud provider's IAM
+// authentication. The output of the command is reused for subsequent
+// connections until it is close to expiring.
+type TokenCredentialProvider struct {
+	user     string
+	command  *ShellOut
+	option   string        // name of the option supplying command, for use in errors
+	lifetime time.Duration // 0 means the output never expires
+	mu       sync.Mutex
+	token    string
+	expires  time.Time
+}
+
+// NewTokenCredentialProvider returns a provider which runs command to obtain a
+// token for user.
+func NewTokenCredentialProvider(user string, command *ShellOut) *TokenCredentialProvider {
+	return &TokenCredentialProvider{
+		user:     user,
+		command:  command,
+		option:   "token-provider",
+		lifetime: tokenLifetime,
+	}
+}
+
+// NewPasswordCommandCredentialProvider returns a provider which runs command
+// to obtain the password for user. Unlike a token, the password is assumed to
+// remain valid, so the command is only run once.
+func NewPasswordCommandCredentialProvider(user string, command *ShellOut) *TokenCredentialProvider {
+	return &TokenCredentialProvider{
+		user:    user,
+		command: command,
+		option:  "password-command",
+	}
+}
+
+// Credentials returns the user and current token, running the command first if
+// no token has been obtained yet or the previous one is close to expiring.
+func (tcp *TokenCredentialProvider) Credentials() (user, password string, err error) {
+	tcp.mu.Lock()
+	defer tcp.mu.Unlock()
+	now := tokenNow()
+	if tcp.token != "" && (tcp.lifetime == 0 || now.Before(tcp.expires)) {
+		return tcp.user, tcp.token, nil
+	}
+	output, err := tcp.command.RunCapture()
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to obtain password from %s: %s", tcp.option, err)
+	}
+	token := strings.TrimSpace(output)
+	if token == "" {
+		return "", "", fmt.Errorf("Unable to obtain password from %s: command returned no output", tcp.option)
+	}
+	log.Debugf("Obtained new password from %s for user %s", tcp.option, tcp.user)
+	tcp.token = token
+	tcp.expires = now.Add(tcp.lifetime)
+	return tcp.user, tcp.token, nil
+}
+
+// TokenCredentialsForConfig registers a TokenCredentialProvider based on the
+// token-provider and user options in cfg, for connecting to the supplied host
+// and port, and returns the name it was registered with, for use as the value
+// of CredentialsParam. The token-provider command may use the variables {HOST},
+// {PORT}, and {USER}. If an equivalent provider was already registered, it is
+// reused, so that its token may be shared by all connections to the host.
+func TokenCredentialsForConfig(cfg *mybase.Config, host string, port int) (name string, err error) {
+	return commandCredentialsForConfig(cfg, "token-provider", host, port, NewTokenCredentialProvider)
+}
+
+// PasswordCommandCredentialsForConfig behaves like TokenCredentialsForConfig,
+// but for the password-command option. The command is run at most once per
+// host and port, upon the first connection, and its output is then reused for
+// the rest of the process.
+func PasswordCommandCredentialsForConfig(cfg *mybase.Config, host string, port int) (name string, err error) {
+	return commandCredentialsForConfig(cfg, "password-command", host, port, NewPasswordCommandCredentialProvider)
+}
+
+func commandCredentialsForConfig(cfg *mybase.Config, option, host string, port int, newProvider func(string, *ShellOut) *TokenCredentialProvider) (name string, err error) {
+	command := cfg.Get(option)
+	if command == "" {
+		return "", fmt.Errorf("Option %s is not set", option)
+	}
+	user, err := ExpandedOption(cfg, "user")
+	if err != nil {
+		return "", err
+	}
+	variables := map[string]string{
+		"HOST": host,
+		"PORT": strconv.Itoa(port),
+		"USER": user,
+	}
+	shellOut, err := NewInterpolatedShellOut(command, variables)
+	if err != nil {
+		return "", fmt.Errorf("Invalid %s: %s", option, err)
+	}
+
+	prefix := "token"
+	if option != "token-provider" {
+		prefix = option
+	}
+	name = fmt.Sprintf("%s:%s@%s:%d/%x", prefix, user, host, port, sha1.Sum([]byte(shellOut.Command)))
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	if credentialProviders.m == nil {
+		credentialProviders.m = make(map[string]CredentialProvider)
+	}
+	if _, already := credentialProviders.m[name]; !already {
+		credentialProviders.m[name] = newProvider(user, shellOut)
+	}
+	return name, nil
+}
+
+// PasswordForConfig returns the password for connecting to host and port, for
+// use in {PASSWORD} variables of external commands. If the password-command
+// option is set, the password is obtained from its provider, running the
+// command if this has not already been done for the host. Otherwise, the value
+// of the password option is returned, with any ${NAME} environment variable
+// placeholders expanded. Passwords obtained via token-provider or vault-role
+// are not available, since they are only valid for a short time.
+func PasswordForConfig(cfg *mybase.Config, host string, port int) (string, error) {
+	if cfg.Get("password-command") == "" {
+		return ExpandedOption(cfg, "password")
+	}
+	name, err := PasswordCommandCredentialsForConfig(cfg, host, port)
+	if err != nil {
+		return "", err
+	}
+	_, password, err := LookupCredentialProvider(name).Credentials()
+	return password, err
+}
@@ -0,0 +1,144 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/mybase"
+)
+
+func TestTokenCredentialsForConfig(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tokenNow = func() time.Time { return now }
+	defer func() {
+		tokenNow = time.Now
+	}()
+
+	cfg := mybase.SimpleConfig(map[string]string{
+		"user":           "app",
+		"token-provider": "/bin/echo {USER}-{HOST}-{PORT}",
+	})
+	name, err := TokenCredentialsForConfig(cfg, "db1.example.com", 3306)
+	if err != nil {
+		t.Fatalf("Unexpected error from TokenCredentialsForConfig: %s", err)
+	} else if !strings.HasPrefix(name, "token:app@db1.example.com:3306/") {
+		t.Errorf("Unexpected provider name %q", name)
+	}
+	provider, ok := LookupCredentialProvider(name).(*TokenCredentialProvider)
+	if !ok {
+		t.Fatalf("Expected provider of type *TokenCredentialProvider, instead found %T", LookupCredentialProvider(name))
+	}
+	if user, password, err := provider.Credentials(); err != nil || user != "app" || password != "app-db1.example.com-3306" {
+		t.Errorf("Unexpected return from Credentials: %q, %q, %v", user, password, err)
+	}
+
+	// Equivalent config reuses the same provider, while another host gets its own
+	if name2, err := TokenCredentialsForConfig(cfg, "db1.example.com", 3306); err != nil || name2 != name {
+		t.Errorf("Expected equivalent config to reuse registered provider")
+	}
+	if name3, err := TokenCredentialsForConfig(cfg, "db2.example.com", 3306); err != nil || name3 == name {
+		t.Errorf("Expected different host to use a different provider")
+	}
+
+	// Tokens are reused until they expire
+	provider.command = &ShellOut{Command: "/bin/echo changed"}
+	if _, password, _ := provider.Credentials(); password != "app-db1.example.com-3306" {
+		t.Errorf("Expected token to be reused, instead found %q", password)
+	}
+	now = now.Add(tokenLifetime)
+	if _, password, _ := provider.Credentials(); password != "changed" {
+		t.Errorf("Expected new token after expiration, instead found %q", password)
+	}
+
+	// Commands that fail or return nothing are errors
+	provider.token = ""
+	provider.command = &ShellOut{Command: "/bin/echo -n"}
+	if _, _, err := provider.Credentials(); err == nil {
+		t.Error("Expected error from command with no output, but err was nil")
+	}
+	provider.command = &ShellOut{Command: "false"}
+	if _, _, err := provider.Credentials(); err == nil {
+		t.Error("Expected error from failing command, but err was nil")
+	}
+
+	// Invalid variables are errors
+	cfg = mybase.SimpleConfig(map[string]string{
+		"user":           "app",
+		"token-provider": "/bin/echo {PASSWORD}",
+	})
+	if _, err := TokenCredentialsForConfig(cfg, "db1.example.com", 3306); err == nil {
+		t.Error("Expected error from invalid variable, but err was nil")
+	}
+}
+
+func TestPasswordCommandCredentialsForConfig(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tokenNow = func() time.Time { return now }
+	defer func() {
+		tokenNow = time.Now
+	}()
+
+	cfg := mybase.SimpleConfig(map[string]string{
+		"user":             "app",
+		"password-command": "/bin/echo {USER}-{HOST}-{PORT}",
+	})
+	name, err := PasswordCommandCredentialsForConfig(cfg, "db1.example.com", 3306)
+	if err != nil {
+		t.Fatalf("Unexpected error from PasswordCommandCredentialsForConfig: %s", err)
+	} else if !strings.HasPrefix(name, "password-command:app@db1.example.com:3306/") {
+		t.Errorf("Unexpected provider name %q", name)
+	}
+	provider, ok := LookupCredentialProvider(name).(*TokenCredentialProvider)
+	if !ok {
+		t.Fatalf("Expected provider of type *TokenCredentialProvider, instead found %T", LookupCredentialProvider(name))
+	}
+	if user, password, err := provider.Credentials(); err != nil || user != "app" || password != "app-db1.example.com-3306" {
+		t.Errorf("Unexpected return from Credentials: %q, %q, %v", user, password, err)
+	}
+
+	// The password is reused indefinitely, unlike a token
+	provider.command = &ShellOut{Command: "/bin/echo changed"}
+	now = now.Add(24 * time.Hour)
+	if _, password, _ := provider.Credentials(); password != "app-db1.example.com-3306" {
+		t.Errorf("Expected password to be reused, instead found %q", password)
+	}
+
+	// Errors mention the correct option
+	provider.token = ""
+	provider.command = &ShellOut{Command: "false"}
+	if _, _, err := provider.Credentials(); err == nil || !strings.Contains(err.Error(), "password-command") {
+		t.Errorf("Expected error mentioning password-command, instead found %v", err)
+	}
+}
+
+func TestPasswordForConfig(t *testing.T) {
+	os.Setenv("SKEEMA_TEST_PASSWORD", "s3cret")
+	defer os.Unsetenv("SKEEMA_TEST_PASSWORD")
+
+	cfg := mybase.SimpleConfig(map[string]string{
+		"user":             "app",
+		"password":         "${SKEEMA_TEST_PASSWORD}",
+		"password-command": "",
+	})
+	if password, err := PasswordForConfig(cfg, "db1.example.com", 3306); err != nil || password != "s3cret" {
+		t.Errorf("Unexpected return from PasswordForConfig: %q, %v", password, err)
+	}
+	cfg = mybase.SimpleConfig(map[string]string{
+		"user":             "app",
+		"password":         "${SKEEMA_TEST_UNDEFINED}",
+		"password-command": "",
+	})
+	if _, err := PasswordForConfig(cfg, "db1.example.com", 3306); err == nil {
+		t.Error("Expected error from undefined environment variable, but err was nil")
+	}
+	cfg = mybase.SimpleConfig(map[string]string{
+		"user":             "app",
+		"password":         "ignored",
+		"password-command": "/bin/echo {HOST}-pw",
+	})
+	if password, err := PasswordForConfig(cfg, "db2.example.com", 3306); err != nil || password != "db2.example.com-pw" {
+		t.Errorf("Unexpected return from PasswordForConfig: %q, %v", password, err)
+	}
+}
@@ -0,0 +1,228 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+)
+
+// vaultNow returns the current time. It is a variable only to permit tests to
+// simulate lease expiration.
+var vaultNow = time.Now
+
+// VaultSecret represents database credentials issued by Vault's database
+// secrets engine, along with their lease.
+type VaultSecret struct {
+	Username      string
+	Password      string
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+// VaultClient obtains and renews database credentials from Vault.
+type VaultClient interface {
+	// ReadCredentials obtains new credentials for role from the database secrets
+	// engine mounted at mount.
+	ReadCredentials(mount, role string) (*VaultSecret, error)
+
+	// RenewLease extends the lease with the supplied ID, returning its new
+	// duration.
+	RenewLease(leaseID string) (time.Duration, error)
+}
+
+// NewVaultClient returns a VaultClient which uses Vault's HTTP API at address,
+// authenticating with token.
+func NewVaultClient(address, token string) VaultClient {
+	return &vaultHTTPClient{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type vaultHTTPClient struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// vaultResponse is the subset of Vault API response fields used by
+// vaultHTTPClient.
+type vaultResponse struct {
+	LeaseID       string   `json:"lease_id"`
+	LeaseDuration int      `json:"lease_duration"`
+	Renewable     bool     `json:"renewable"`
+	Errors        []string `json:"errors"`
+	Data          struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"data"`
+}
+
+func (vc *vaultHTTPClient) ReadCredentials(mount, role string) (*VaultSecret, error) {
+	path := fmt.Sprintf("%s/creds/%s", strings.Trim(mount, "/"), url.PathEscape(role))
+	resp, err := vc.request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.Username == "" {
+		return nil, fmt.Errorf("Vault response for %s did not include a username", path)
+	}
+	return &VaultSecret{
+		Username:      resp.Data.Username,
+		Password:      resp.Data.Password,
+		LeaseID:       resp.LeaseID,
+		LeaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable:     resp.Renewable,
+	}, nil
+}
+
+func (vc *vaultHTTPClient) RenewLease(leaseID string) (time.Duration, error) {
+	body := map[string]string{"lease_id": leaseID}
+	resp, err := vc.request("PUT", "sys/leases/renew", body)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+// request performs an API request to path, which should omit the leading
+// "/v1/". If body is non-nil, it is encoded as JSON.
+func (vc *vaultHTTPClient) request(method, path string, body interface{}) (*vaultResponse, error) {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, vc.address+"/v1/"+path, &reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", vc.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpResp, err := vc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	var resp vaultResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil && httpResp.StatusCode < 300 {
+		return nil, fmt.Errorf("Unable to parse Vault response from %s: %s", path, err)
+	}
+	if httpResp.StatusCode >= 300 {
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("Vault returned HTTP %d for %s: %s", httpResp.StatusCode, path, strings.Join(resp.Errors, "; "))
+		}
+		return nil, fmt.Errorf("Vault returned HTTP %d for %s", httpResp.StatusCode, path)
+	}
+	return &resp, nil
+}
+
+// VaultCredentialProvider is a CredentialProvider which obtains short-lived
+// credentials from Vault's database secrets engine. Credentials are cached
+// until less than a third of their lease remains. At that point, the lease is
+// renewed if possible; otherwise, or if renewal fails or is capped by the
+// lease's max TTL, fresh credentials are obtained. Connections established
+// with older credentials are unaffected, so long operations may use several
+// sets of credentials over time.
+type VaultCredentialProvider struct {
+	client  VaultClient
+	mount   string
+	role    string
+	mu      sync.Mutex
+	secret  *VaultSecret
+	expires time.Time
+}
+
+// NewVaultCredentialProvider returns a provider which obtains credentials for
+// role, using the database secrets engine mounted at mount.
+func NewVaultCredentialProvider(client VaultClient, mount, role string) *VaultCredentialProvider {
+	return &VaultCredentialProvider{
+		client: client,
+		mount:  mount,
+		role:   role,
+	}
+}
+
+// Credentials returns the current credentials, renewing or replacing them
+// first if they are close to expiring.
+func (vcp *VaultCredentialProvider) Credentials() (user, password string, err error) {
+	vcp.mu.Lock()
+	defer vcp.mu.Unlock()
+	now := vaultNow()
+	if vcp.secret != nil {
+		threshold := vcp.secret.LeaseDuration / 3
+		if vcp.secret.LeaseDuration == 0 || vcp.expires.Sub(now) > threshold {
+			return vcp.secret.Username, vcp.secret.Password, nil
+		}
+		if vcp.secret.Renewable {
+			if duration, err := vcp.client.RenewLease(vcp.secret.LeaseID); err != nil {
+				log.Debugf("Unable to renew Vault lease for role %s, obtaining new credentials instead: %s", vcp.role, err)
+			} else if duration > threshold {
+				vcp.expires = now.Add(duration)
+				return vcp.secret.Username, vcp.secret.Password, nil
+			}
+		}
+	}
+	secret, err := vcp.client.ReadCredentials(vcp.mount, vcp.role)
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to obtain credentials from Vault for role %s: %s", vcp.role, err)
+	}
+	log.Debugf("Obtained new credentials from Vault for role %s, with lease duration %s", vcp.role, secret.LeaseDuration)
+	vcp.secret = secret
+	vcp.expires = now.Add(secret.LeaseDuration)
+	return secret.Username, secret.Password, nil
+}
+
+// VaultCredentialsForConfig registers a VaultCredentialProvider based on the
+// vault-role, vault-mount, vault-address, and vault-token options in cfg, and
+// returns the name it was registered with, for use as the value of
+// CredentialsParam. If the address or token options are not set, the VAULT_ADDR
+// or VAULT_TOKEN environment variables are used instead. If an equivalent
+// provider was already registered, it is reused, so that its credentials may
+// be shared by all instances.
+func VaultCredentialsForConfig(cfg *mybase.Config) (name string, err error) {
+	role := cfg.Get("vault-role")
+	if role == "" {
+		return "", errors.New("Option vault-role is not set")
+	}
+	mount := cfg.Get("vault-mount")
+	address := cfg.Get("vault-address")
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := cfg.Get("vault-token")
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" {
+		return "", errors.New("Option vault-role requires vault-address option or VAULT_ADDR environment variable to be set")
+	} else if token == "" {
+		return "", errors.New("Option vault-role requires vault-token option or VAULT_TOKEN environment variable to be set")
+	}
+
+	name = fmt.Sprintf("vault:%s/%s/%s", strings.TrimRight(address, "/"), strings.Trim(mount, "/"), role)
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	if credentialProviders.m == nil {
+		credentialProviders.m = make(map[string]CredentialProvider)
+	}
+	if _, already := credentialProviders.m[name]; !already {
+		credentialProviders.m[name] = NewVaultCredentialProvider(NewVaultClient(address, token), mount, role)
+	}
+	return name, nil
+}
@@ -0,0 +1,193 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/mybase"
+)
+
+// mockVaultClient is a VaultClient which issues sequentially-numbered
+// credentials, and renews leases as configured.
+type mockVaultClient struct {
+	leaseDuration time.Duration
+	renewable     bool
+	renewDuration time.Duration
+	renewErr      error
+	readErr       error
+	reads         int
+	renewals      int
+}
+
+func (mvc *mockVaultClient) ReadCredentials(mount, role string) (*VaultSecret, error) {
+	if mvc.readErr != nil {
+		return nil, mvc.readErr
+	}
+	mvc.reads++
+	return &VaultSecret{
+		Username:      fmt.Sprintf("v-%s-%s-%d", mount, role, mvc.reads),
+		Password:      "pw",
+		LeaseID:       fmt.Sprintf("%s/creds/%s/%d", mount, role, mvc.reads),
+		LeaseDuration: mvc.leaseDuration,
+		Renewable:     mvc.renewable,
+	}, nil
+}
+
+func (mvc *mockVaultClient) RenewLease(leaseID string) (time.Duration, error) {
+	mvc.renewals++
+	return mvc.renewDuration, mvc.renewErr
+}
+
+func TestVaultCredentialProvider(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	vaultNow = func() time.Time { return now }
+	defer func() { vaultNow = time.Now }()
+
+	client := &mockVaultClient{leaseDuration: 30 * time.Minute}
+	vcp := NewVaultCredentialProvider(client, "database", "app")
+	assertUser := func(expected string) {
+		t.Helper()
+		if user, password, err := vcp.Credentials(); err != nil {
+			t.Fatalf("Unexpected error from Credentials: %s", err)
+		} else if user != expected || password != "pw" {
+			t.Errorf("Expected user %q, instead found %q", expected, user)
+		}
+	}
+
+	// Credentials are cached while more than a third of the lease remains
+	assertUser("v-database-app-1")
+	now = now.Add(19 * time.Minute)
+	assertUser("v-database-app-1")
+
+	// Once lease is close to expiring, non-renewable creds are replaced
+	now = now.Add(2 * time.Minute)
+	assertUser("v-database-app-2")
+	if client.renewals != 0 {
+		t.Errorf("Expected no renewals of non-renewable lease, instead found %d", client.renewals)
+	}
+
+	// Renewable creds are renewed instead, as long as the renewal extends the
+	// lease sufficiently
+	client.renewable, client.renewDuration = true, 30*time.Minute
+	now = now.Add(25 * time.Minute)
+	assertUser("v-database-app-3")
+	now = now.Add(25 * time.Minute)
+	assertUser("v-database-app-3")
+	if client.renewals != 1 {
+		t.Errorf("Expected 1 renewal, instead found %d", client.renewals)
+	}
+	now = now.Add(25 * time.Minute)
+	assertUser("v-database-app-3")
+
+	// Renewal capped by max TTL, or failing, results in new creds
+	client.renewDuration = time.Minute
+	now = now.Add(25 * time.Minute)
+	assertUser("v-database-app-4")
+	client.renewErr = errors.New("lease not found")
+	now = now.Add(25 * time.Minute)
+	assertUser("v-database-app-5")
+
+	// Errors obtaining creds are returned
+	client.readErr = errors.New("permission denied")
+	now = now.Add(time.Hour)
+	if _, _, err := vcp.Credentials(); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected error from Credentials to contain Vault error, instead found %v", err)
+	}
+}
+
+func TestVaultHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.goodtoken" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v1/database/creds/app":
+			w.Write([]byte(`{"lease_id":"database/creds/app/xyz","lease_duration":3600,"renewable":true,"data":{"username":"v-app-xyz","password":"pw"}}`))
+		case r.Method == "PUT" && r.URL.Path == "/v1/sys/leases/renew":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["lease_id"] != "database/creds/app/xyz" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors":["lease not found or lease is not renewable"]}`))
+				return
+			}
+			w.Write([]byte(`{"lease_id":"database/creds/app/xyz","lease_duration":1800,"renewable":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	vc := NewVaultClient(server.URL+"/", "s.goodtoken")
+	secret, err := vc.ReadCredentials("/database/", "app")
+	if err != nil {
+		t.Fatalf("Unexpected error from ReadCredentials: %s", err)
+	}
+	expected := VaultSecret{
+		Username:      "v-app-xyz",
+		Password:      "pw",
+		LeaseID:       "database/creds/app/xyz",
+		LeaseDuration: time.Hour,
+		Renewable:     true,
+	}
+	if *secret != expected {
+		t.Errorf("Expected secret %+v, instead found %+v", expected, *secret)
+	}
+	if duration, err := vc.RenewLease(secret.LeaseID); err != nil || duration != 30*time.Minute {
+		t.Errorf("Unexpected return from RenewLease: %s, %v", duration, err)
+	}
+	if _, err := vc.RenewLease("bogus"); err == nil || !strings.Contains(err.Error(), "not renewable") {
+		t.Errorf("Expected error from RenewLease to contain Vault error, instead found %v", err)
+	}
+	if _, err := vc.ReadCredentials("database", "nonexistent"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected error from ReadCredentials with bad role, instead found %v", err)
+	}
+	vc = NewVaultClient(server.URL, "s.badtoken")
+	if _, err := vc.ReadCredentials("database", "app"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected error from ReadCredentials with bad token, instead found %v", err)
+	}
+}
+
+func TestVaultCredentialsForConfig(t *testing.T) {
+	origAddr, origToken := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	defer func() {
+		os.Setenv("VAULT_ADDR", origAddr)
+		os.Setenv("VAULT_TOKEN", origToken)
+	}()
+	os.Setenv("VAULT_ADDR", "")
+	os.Setenv("VAULT_TOKEN", "")
+	cfg := mybase.SimpleConfig(map[string]string{
+		"vault-role":    "app",
+		"vault-mount":   "database",
+		"vault-address": "https://vault.example.com:8200/",
+		"vault-token":   "",
+	})
+	if _, err := VaultCredentialsForConfig(cfg); err == nil {
+		t.Error("Expected error from VaultCredentialsForConfig without token, but err was nil")
+	}
+	os.Setenv("VAULT_TOKEN", "s.goodtoken")
+	name, err := VaultCredentialsForConfig(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from VaultCredentialsForConfig: %s", err)
+	} else if name != "vault:https://vault.example.com:8200/database/app" {
+		t.Errorf("Unexpected provider name %q", name)
+	}
+	provider := LookupCredentialProvider(name)
+	if _, ok := provider.(*VaultCredentialProvider); !ok {
+		t.Fatalf("Expected provider of type *VaultCredentialProvider, instead found %T", provider)
+	}
+
+	// Equivalent config reuses the same provider
+	if name2, err := VaultCredentialsForConfig(cfg); err != nil || name2 != name || LookupCredentialProvider(name2) != provider {
+		t.Errorf("Expected equivalent config to reuse registered provider")
+	}
+}
@@ -0,0 +1,127 @@
+package workspace
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// TableData represents the rows of a table whose data is managed by INSERT
+// statements, as configured by the manage-data option. Values are represented
+// as strings, in the format returned by the server's text protocol.
+type TableData struct {
+	Table   *tengo.Table
+	Columns []string           // all non-generated columns, in table order
+	Rows    [][]sql.NullString // ordered by primary key
+}
+
+// KeyPositions returns the positions within Columns of the table's primary key
+// columns.
+func (td *TableData) KeyPositions() []int {
+	positions := make([]int, 0, len(td.Table.PrimaryKey.Parts))
+	for _, part := range td.Table.PrimaryKey.Parts {
+		for n, col := range td.Columns {
+			if col == part.ColumnName {
+				positions = append(positions, n)
+				break
+			}
+		}
+	}
+	return positions
+}
+
+// RowKey returns a string uniquely identifying row by its primary key values.
+func (td *TableData) RowKey(row []sql.NullString) string {
+	positions := td.KeyPositions()
+	values := make([]string, len(positions))
+	for n, pos := range positions {
+		values[n] = row[pos].String
+	}
+	return strings.Join(values, "\x00")
+}
+
+// IntrospectTableData returns the rows of table in the default database of db.
+// The table must have a primary key, since rows are identified by it when
+// comparing the data of two tables. Generated columns are omitted, since their
+// values cannot be inserted or updated directly.
+func IntrospectTableData(db *sqlx.DB, table *tengo.Table) (*TableData, error) {
+	if table.PrimaryKey == nil {
+		return nil, fmt.Errorf("Table %s has no primary key, which is required by manage-data", tengo.EscapeIdentifier(table.Name))
+	}
+	td := &TableData{Table: table}
+	escaped := make([]string, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		if col.GenerationExpr == "" {
+			td.Columns = append(td.Columns, col.Name)
+			escaped = append(escaped, tengo.EscapeIdentifier(col.Name))
+		}
+	}
+	order := make([]string, len(table.PrimaryKey.Parts))
+	for n, part := range table.PrimaryKey.Parts {
+		order[n] = tengo.EscapeIdentifier(part.ColumnName)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(escaped, ", "), tengo.EscapeIdentifier(table.Name), strings.Join(order, ", "))
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := make([]sql.NullString, len(td.Columns))
+		dest := make([]interface{}, len(row))
+		for n := range row {
+			dest[n] = &row[n]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		td.Rows = append(td.Rows, row)
+	}
+	return td, rows.Err()
+}
+
+// introspectTableData is used by ExecLogicalSchema to introspect the rows
+// inserted in a workspace by the supplied INSERT statements. Afterwards, the
+// rows are deleted from the workspace, so that they do not count towards the
+// max-rows limit upon cleanup. Tables which do not exist in the workspace are
+// skipped; the corresponding INSERTs will already have failed. Tables lacking
+// a primary key are returned as failures of their first INSERT statement.
+func introspectTableData(ws Workspace, schema *tengo.Schema, statements []*fs.Statement, opts Options) (data []*TableData, failures []*StatementError, err error) {
+	statementsByTable := make(map[string]*fs.Statement)
+	for _, stmt := range statements {
+		if statementsByTable[stmt.ObjectName] == nil {
+			statementsByTable[stmt.ObjectName] = stmt
+		}
+	}
+	for _, table := range schema.Tables {
+		stmt := statementsByTable[table.Name]
+		if stmt == nil {
+			continue
+		}
+		db, err := ws.ConnectionPool(paramsForStatement(stmt, opts))
+		if err != nil {
+			return nil, nil, err
+		}
+		if table.PrimaryKey == nil {
+			failures = append(failures, &StatementError{
+				Statement: stmt,
+				Err:       errors.New("manage-data requires the table to have a primary key"),
+			})
+		} else {
+			td, err := IntrospectTableData(db, table)
+			if err != nil {
+				return nil, nil, err
+			}
+			data = append(data, td)
+		}
+		if _, err := db.Exec("DELETE FROM " + tengo.EscapeIdentifier(table.Name)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, failures, nil
+}
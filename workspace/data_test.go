@@ -0,0 +1,41 @@
+package workspace
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestTableDataRowKey(t *testing.T) {
+	table := &tengo.Table{
+		Name: "prices",
+		Columns: []*tengo.Column{
+			{Name: "amount"},
+			{Name: "region"},
+			{Name: "sku"},
+		},
+		PrimaryKey: &tengo.Index{
+			PrimaryKey: true,
+			Parts:      []tengo.IndexPart{{ColumnName: "sku"}, {ColumnName: "region"}},
+		},
+	}
+	td := &TableData{Table: table, Columns: []string{"amount", "region", "sku"}}
+	if positions := td.KeyPositions(); len(positions) != 2 || positions[0] != 2 || positions[1] != 1 {
+		t.Errorf("Unexpected return from KeyPositions: %v", positions)
+	}
+	row := func(values ...string) []sql.NullString {
+		result := make([]sql.NullString, len(values))
+		for n, v := range values {
+			result[n] = sql.NullString{String: v, Valid: true}
+		}
+		return result
+	}
+	a, b, c := row("10.00", "us", "abc"), row("12.50", "us", "abc"), row("10.00", "u", "sabc")
+	if td.RowKey(a) != td.RowKey(b) {
+		t.Error("Expected rows with same primary key values to have the same RowKey")
+	}
+	if td.RowKey(a) == td.RowKey(c) {
+		t.Error("Expected rows with different primary key values to have different RowKeys")
+	}
+}
@@ -0,0 +1,336 @@
+package workspace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// Event represents a scheduled event. tengo does not support events, so they
+// are introspected directly from information_schema and SHOW CREATE EVENT.
+type Event struct {
+	Name            string `db:"event_name"`
+	Definer         string `db:"definer"`
+	Body            string `db:"event_definition"`
+	Type            string `db:"event_type"`     // "ONE TIME" or "RECURRING"
+	ExecuteAt       string `db:"execute_at"`     // only for one-time events
+	IntervalValue   string `db:"interval_value"` // only for recurring events
+	IntervalField   string `db:"interval_field"` // only for recurring events
+	Starts          string `db:"starts"`         // only for recurring events
+	Ends            string `db:"ends"`           // only for recurring events with an end time
+	OnCompletion    string `db:"on_completion"`  // "PRESERVE" or "NOT PRESERVE"
+	Status          string `db:"status"`         // "ENABLED", "DISABLED", or "SLAVESIDE_DISABLED"
+	Comment         string `db:"event_comment"`
+	SQLMode         string `db:"sql_mode"`
+	TimeZone        string `db:"time_zone"`
+	CreateStatement string `db:"-"`
+
+	// relativeTimes tracks which schedule times ("AT", "STARTS", "ENDS") of a
+	// desired event were omitted or expressed relative to creation time in its
+	// filesystem definition. These cannot be meaningfully compared to a live
+	// event's times.
+	relativeTimes map[string]bool
+}
+
+// IntrospectEvents returns the events in the default database of db, sorted
+// by name.
+func IntrospectEvents(db *sqlx.DB) ([]*Event, error) {
+	var events []*Event
+	query := `
+		SELECT   event_name AS event_name, definer AS definer,
+		         IFNULL(event_definition, '') AS event_definition,
+		         event_type AS event_type,
+		         IFNULL(CAST(execute_at AS char), '') AS execute_at,
+		         IFNULL(interval_value, '') AS interval_value,
+		         IFNULL(interval_field, '') AS interval_field,
+		         IFNULL(CAST(starts AS char), '') AS starts,
+		         IFNULL(CAST(ends AS char), '') AS ends,
+		         on_completion AS on_completion, status AS status,
+		         event_comment AS event_comment, sql_mode AS sql_mode,
+		         time_zone AS time_zone
+		FROM     information_schema.events
+		WHERE    event_schema = DATABASE()
+		ORDER BY event_name`
+	if err := db.Select(&events, query); err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		var name, sqlMode, timeZone, charSetClient, collationConnection, dbCollation string
+		query := fmt.Sprintf("SHOW CREATE EVENT %s", tengo.EscapeIdentifier(event.Name))
+		if err := db.QueryRow(query).Scan(&name, &sqlMode, &timeZone, &event.CreateStatement, &charSetClient, &collationConnection, &dbCollation); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Matches returns true if live is functionally equivalent to the receiver,
+// which must be a desired event obtained by ExecLogicalSchema. Schedule times
+// which the filesystem definition omitted or expressed relative to creation
+// time are not compared. Metadata (definer, and creation-time sql_mode and
+// time zone) is only compared if compareMetadata is true.
+func (e *Event) Matches(live *Event, compareMetadata bool) bool {
+	if e.Name != live.Name || e.Body != live.Body || e.Type != live.Type || e.IntervalValue != live.IntervalValue || e.IntervalField != live.IntervalField || e.OnCompletion != live.OnCompletion || e.Status != live.Status || e.Comment != live.Comment {
+		return false
+	}
+	times := map[string][2]string{
+		"AT":     {e.ExecuteAt, live.ExecuteAt},
+		"STARTS": {e.Starts, live.Starts},
+		"ENDS":   {e.Ends, live.Ends},
+	}
+	for clause, values := range times {
+		if !e.relativeTimes[clause] && values[0] != values[1] {
+			return false
+		}
+	}
+	if compareMetadata && (e.Definer != live.Definer || e.SQLMode != live.SQLMode || e.TimeZone != live.TimeZone) {
+		return false
+	}
+	return true
+}
+
+// eventToken is a word, quoted string, or punctuation character from the
+// portion of a CREATE EVENT statement preceding the event body.
+type eventToken struct {
+	text       string
+	start, end int // byte offsets in the statement
+}
+
+// upper returns the token's text in uppercase, or an empty string if the
+// token is quoted, so that quoted strings can never match a keyword.
+func (tok eventToken) upper() string {
+	if c := tok.text[0]; c == '\'' || c == '"' || c == '`' {
+		return ""
+	}
+	return strings.ToUpper(tok.text)
+}
+
+// isString returns true if the token is a single- or double-quoted string.
+func (tok eventToken) isString() bool {
+	return tok.text[0] == '\'' || tok.text[0] == '"'
+}
+
+// eventHeadTokens splits the portion of a CREATE EVENT statement preceding
+// its DO keyword into tokens, skipping whitespace and comments, and
+// returns the tokens following the SCHEDULE keyword. The byte offset of the DO
+// keyword is also returned, or -1 if it could not be found.
+func eventHeadTokens(create string) (tokens []eventToken, doPos int) {
+	var afterSchedule bool
+	for pos := 0; pos < len(create); {
+		c := create[pos]
+		start := pos
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case c == '#' || strings.HasPrefix(create[pos:], "-- "):
+			if end := strings.IndexByte(create[pos:], '\n'); end >= 0 {
+				pos += end + 1
+			} else {
+				pos = len(create)
+			}
+			continue
+		case strings.HasPrefix(create[pos:], "/*"):
+			if end := strings.Index(create[pos+2:], "*/"); end >= 0 {
+				pos += end + 4
+			} else {
+				pos = len(create)
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			for pos++; pos < len(create); pos++ {
+				if create[pos] == '\\' && c != '`' {
+					pos++
+				} else if create[pos] == c {
+					if pos+1 < len(create) && create[pos+1] == c {
+						pos++
+					} else {
+						break
+					}
+				}
+			}
+			pos++
+		case c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80:
+			for pos < len(create) {
+				c := create[pos]
+				if c != '_' && c != '$' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c < 0x80 {
+					break
+				}
+				pos++
+			}
+		default:
+			pos++
+		}
+		if pos > len(create) {
+			pos = len(create)
+		}
+		tok := eventToken{text: create[start:pos], start: start, end: pos}
+		switch word := tok.upper(); {
+		case word == "DO" && afterSchedule:
+			return tokens, start
+		case word == "SCHEDULE" && !afterSchedule:
+			afterSchedule = true
+		case afterSchedule:
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens, -1
+}
+
+// eventStatusSpan returns the desired status of an event based on the tokens
+// from eventHeadTokens, along with the indexes of the first and last tokens of
+// the status clause. If there is no status clause, the status is "ENABLED"
+// and the indexes are both -1.
+func eventStatusSpan(tokens []eventToken) (status string, first, last int) {
+	for n, tok := range tokens {
+		switch tok.upper() {
+		case "ENABLE":
+			return "ENABLED", n, n
+		case "DISABLE":
+			if n+2 < len(tokens) && tokens[n+1].upper() == "ON" && (tokens[n+2].upper() == "SLAVE" || tokens[n+2].upper() == "REPLICA") {
+				return "SLAVESIDE_DISABLED", n, n + 2
+			}
+			return "DISABLED", n, n
+		}
+	}
+	return "ENABLED", -1, -1
+}
+
+// desiredEventProperties returns the status requested by a CREATE EVENT
+// statement, as well as which of its schedule times are not expressed as
+// literal values; see Event.relativeTimes.
+func desiredEventProperties(create string) (status string, relativeTimes map[string]bool) {
+	tokens, _ := eventHeadTokens(create)
+	status, _, _ = eventStatusSpan(tokens)
+	relativeTimes = map[string]bool{"AT": true, "STARTS": true}
+	clauseKeywords := map[string]bool{"STARTS": true, "ENDS": true, "ON": true, "ENABLE": true, "DISABLE": true, "COMMENT": true}
+	for n, tok := range tokens {
+		clause := tok.upper()
+		if clause != "AT" && clause != "STARTS" && clause != "ENDS" {
+			continue
+		}
+		literal := n+1 < len(tokens) && tokens[n+1].isString() && (n+2 == len(tokens) || clauseKeywords[tokens[n+2].upper()])
+		if literal {
+			delete(relativeTimes, clause)
+		} else {
+			relativeTimes[clause] = true
+		}
+	}
+	return status, relativeTimes
+}
+
+// disabledEventCreate returns a version of the supplied CREATE EVENT statement
+// which creates the event in a disabled state. This permits safely creating
+// events in a workspace, without any risk of them executing there.
+func disabledEventCreate(create string) string {
+	tokens, doPos := eventHeadTokens(create)
+	if doPos < 0 {
+		return create
+	}
+	if _, first, last := eventStatusSpan(tokens); first >= 0 {
+		return create[:tokens[first].start] + "DISABLE" + create[tokens[last].end:]
+	}
+	insertPos := doPos
+	for _, tok := range tokens {
+		if tok.upper() == "COMMENT" {
+			insertPos = tok.start
+			break
+		}
+	}
+	return create[:insertPos] + "DISABLE " + create[insertPos:]
+}
+
+// introspectEvents is used by ExecLogicalSchema to introspect the events
+// created in a workspace from the supplied CREATE EVENT statements, which were
+// executed with their status forced to DISABLE. The returned events are
+// adjusted to reflect the desired status and schedule of the original
+// statements. Afterwards, the events are dropped from the workspace, since
+// workspace cleanup does not otherwise handle events.
+//
+// The server silently drops a one-time event upon creation if its execution
+// time has already passed and it uses ON COMPLETION NOT PRESERVE. Statements
+// whose event does not exist after execution are returned as failures, since
+// otherwise the event would appear to be absent from the filesystem.
+func introspectEvents(ws Workspace, statements []*fs.Statement) ([]*Event, []*StatementError, error) {
+	db, err := ws.ConnectionPool("")
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := IntrospectEvents(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	statementsByName := make(map[string]*fs.Statement, len(statements))
+	for _, stmt := range statements {
+		statementsByName[strings.ToLower(stmt.ObjectName)] = stmt
+	}
+	for _, event := range events {
+		if stmt := statementsByName[strings.ToLower(event.Name)]; stmt != nil {
+			event.CreateStatement = stmt.Body()
+			event.Status, event.relativeTimes = desiredEventProperties(event.CreateStatement)
+			delete(statementsByName, strings.ToLower(event.Name))
+		}
+		if _, err := db.Exec("DROP EVENT IF EXISTS " + tengo.EscapeIdentifier(event.Name)); err != nil {
+			return nil, nil, err
+		}
+	}
+	var failures []*StatementError
+	for _, stmt := range statements {
+		if statementsByName[strings.ToLower(stmt.ObjectName)] == stmt {
+			failures = append(failures, &StatementError{
+				Statement: stmt,
+				Err:       fmt.Errorf("Event %s was dropped by the server immediately upon creation, which occurs for one-time events with an execution time in the past and ON COMPLETION NOT PRESERVE", tengo.EscapeIdentifier(stmt.ObjectName)),
+			})
+		}
+	}
+	return events, failures, nil
+}
+
+// AlterStatement returns an ALTER EVENT statement which modifies an existing
+// event to match the receiver, which must be a desired event obtained by
+// ExecLogicalSchema. ALTER EVENT leaves the status, completion behavior, and
+// comment of the event unchanged if their clauses are omitted, so these
+// clauses are always included explicitly.
+func (e *Event) AlterStatement() string {
+	create := e.CreateStatement
+	tokens, doPos := eventHeadTokens(create)
+	if doPos < 0 {
+		return ""
+	}
+	if _, first, last := eventStatusSpan(tokens); first >= 0 {
+		create = create[:tokens[first].start] + strings.TrimLeft(create[tokens[last].end:], " ")
+		tokens, doPos = eventHeadTokens(create)
+	}
+	clauses := map[string]string{
+		"ENABLED":            "ENABLE",
+		"DISABLED":           "DISABLE",
+		"SLAVESIDE_DISABLED": "DISABLE ON SLAVE",
+	}[e.Status] + " "
+	insertPos, hasCompletion := doPos, false
+	for _, tok := range tokens {
+		if word := tok.upper(); word == "COMPLETION" {
+			hasCompletion = true
+		} else if word == "COMMENT" && insertPos == doPos {
+			insertPos = tok.start
+		}
+	}
+	if !hasCompletion {
+		clauses = "ON COMPLETION NOT PRESERVE " + clauses
+	}
+	if insertPos == doPos {
+		clauses += "COMMENT '' "
+	}
+	head := create[:insertPos] + clauses
+	head = reCreateEventHead.ReplaceAllString(head, "${1}ALTER ")
+	head = reEventIfNotExists.ReplaceAllString(head, "${1}")
+	return head + create[insertPos:]
+}
+
+var (
+	reCreateEventHead  = regexp.MustCompile(`^(\s*)(?i:CREATE)\s+`)
+	reEventIfNotExists = regexp.MustCompile(`(?i)(\bEVENT\s+)IF\s+NOT\s+EXISTS\s+`)
+)
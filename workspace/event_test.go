@@ -0,0 +1,149 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+)
+
+func TestDesiredEventProperties(t *testing.T) {
+	cases := []struct {
+		create   string
+		status   string
+		relative []string
+	}{
+		{"CREATE EVENT e ON SCHEDULE EVERY 1 DAY DO SELECT 1", "ENABLED", []string{"AT", "STARTS"}},
+		{"CREATE EVENT e ON SCHEDULE EVERY 1 DAY STARTS '2030-01-01 00:00:00' DISABLE DO SELECT 1", "DISABLED", []string{"AT"}},
+		{"CREATE EVENT e ON SCHEDULE EVERY 1 DAY STARTS '2030-01-01' + INTERVAL 1 HOUR ENDS '2031-01-01' DO SELECT 1", "ENABLED", []string{"AT", "STARTS"}},
+		{"CREATE EVENT e ON SCHEDULE EVERY 1 DAY ENDS CURRENT_TIMESTAMP + INTERVAL 1 WEEK DISABLE ON SLAVE COMMENT 'enable' DO SELECT 1", "SLAVESIDE_DISABLED", []string{"AT", "STARTS", "ENDS"}},
+		{"CREATE EVENT e ON SCHEDULE AT '2030-01-01 00:00:00' ON COMPLETION PRESERVE ENABLE DO SELECT 1", "ENABLED", []string{"STARTS"}},
+		{"CREATE EVENT e ON SCHEDULE AT CURRENT_TIMESTAMP DO SELECT 'DISABLE'", "ENABLED", []string{"AT", "STARTS"}},
+		{"CREATE DEFINER=`disable`@`%` EVENT `starts` ON SCHEDULE /* DISABLE */ EVERY 1 DAY DISABLE ON REPLICA DO BEGIN SELECT 1; END", "SLAVESIDE_DISABLED", []string{"AT", "STARTS"}},
+	}
+	for n, c := range cases {
+		status, relative := desiredEventProperties(c.create)
+		if status != c.status {
+			t.Errorf("case %d: expected status %s, instead found %s", n, c.status, status)
+		}
+		if len(relative) != len(c.relative) {
+			t.Errorf("case %d: expected relative times %v, instead found %v", n, c.relative, relative)
+			continue
+		}
+		for _, clause := range c.relative {
+			if !relative[clause] {
+				t.Errorf("case %d: expected relative times %v, instead found %v", n, c.relative, relative)
+			}
+		}
+	}
+}
+
+func TestDisabledEventCreate(t *testing.T) {
+	cases := map[string]string{
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY DO SELECT 1":                               "CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE DO SELECT 1",
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY ENABLE DO SELECT 1":                        "CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE DO SELECT 1",
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE ON SLAVE DO SELECT 1":              "CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE DO SELECT 1",
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY COMMENT 'x' DO SELECT 1":                   "CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE COMMENT 'x' DO SELECT 1",
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY\nDO\nBEGIN\n  SELECT 'ENABLE';\nEND":       "CREATE EVENT e ON SCHEDULE EVERY 1 DAY\nDISABLE DO\nBEGIN\n  SELECT 'ENABLE';\nEND",
+		"CREATE EVENT `do` ON SCHEDULE AT '2030-01-01' -- DO\n ENABLE DO SELECT 1":         "CREATE EVENT `do` ON SCHEDULE AT '2030-01-01' -- DO\n DISABLE DO SELECT 1",
+		"CREATE EVENT e ON SCHEDULE EVERY 1 DAY ENABLE COMMENT 'it''s DO \\'' DO SET @a=1": "CREATE EVENT e ON SCHEDULE EVERY 1 DAY DISABLE COMMENT 'it''s DO \\'' DO SET @a=1",
+		"CREATE EVENT e": "CREATE EVENT e",
+	}
+	for input, expected := range cases {
+		if actual := disabledEventCreate(input); actual != expected {
+			t.Errorf("Unexpected result from disabledEventCreate(%q):\nexpected %q\nfound    %q", input, expected, actual)
+		}
+	}
+}
+
+func TestEventAlterStatement(t *testing.T) {
+	event := &Event{
+		Status:          "ENABLED",
+		CreateStatement: "CREATE DEFINER=`root`@`%` EVENT IF NOT EXISTS `e` ON SCHEDULE EVERY 1 DAY DISABLE DO SELECT 1",
+	}
+	expected := "ALTER DEFINER=`root`@`%` EVENT `e` ON SCHEDULE EVERY 1 DAY ON COMPLETION NOT PRESERVE ENABLE COMMENT '' DO SELECT 1"
+	if actual := event.AlterStatement(); actual != expected {
+		t.Errorf("Unexpected result from AlterStatement:\nexpected %q\nfound    %q", expected, actual)
+	}
+
+	event.Status = "SLAVESIDE_DISABLED"
+	event.CreateStatement = "CREATE EVENT e ON SCHEDULE AT '2030-01-01' ON COMPLETION PRESERVE COMMENT 'hi' DO SELECT 1"
+	expected = "ALTER EVENT e ON SCHEDULE AT '2030-01-01' ON COMPLETION PRESERVE DISABLE ON SLAVE COMMENT 'hi' DO SELECT 1"
+	if actual := event.AlterStatement(); actual != expected {
+		t.Errorf("Unexpected result from AlterStatement:\nexpected %q\nfound    %q", expected, actual)
+	}
+}
+
+func TestEventMatches(t *testing.T) {
+	live := &Event{
+		Name:          "e",
+		Definer:       "root@%",
+		Body:          "SELECT 1",
+		Type:          "RECURRING",
+		IntervalValue: "1",
+		IntervalField: "DAY",
+		Starts:        "2030-01-01 00:00:00",
+		OnCompletion:  "NOT PRESERVE",
+		Status:        "ENABLED",
+		SQLMode:       "STRICT_TRANS_TABLES",
+		TimeZone:      "SYSTEM",
+	}
+	desired := *live
+	desired.Starts = "2029-06-01 12:34:56"
+	desired.Definer = "other@%"
+	desired.relativeTimes = map[string]bool{"AT": true, "STARTS": true}
+	if !desired.Matches(live, false) {
+		t.Error("Expected events with differing relative start time to match")
+	}
+	if desired.Matches(live, true) {
+		t.Error("Expected events with differing definer to not match with compareMetadata")
+	}
+	delete(desired.relativeTimes, "STARTS")
+	if desired.Matches(live, false) {
+		t.Error("Expected events with differing literal start time to not match")
+	}
+	desired.Starts = live.Starts
+	desired.Status = "DISABLED"
+	if desired.Matches(live, false) {
+		t.Error("Expected events with differing status to not match")
+	}
+	desired.Status = live.Status
+	desired.Ends = "2031-01-01 00:00:00"
+	if desired.Matches(live, false) {
+		t.Error("Expected events with differing end time to not match")
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaEvents(t *testing.T) {
+	dirPath := "../testdata/golden/init/mydb/product"
+	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {
+		dirPath = strings.Replace(dirPath, "golden", "golden-mysql55", 1)
+	}
+	dir := s.getParsedDir(t, dirPath, "")
+	opts, err := OptionsForDir(dir, s.d.Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	opts.LockWaitTimeout = 100 * time.Millisecond
+
+	// evt_expired is a one-time event in the past which does not preserve
+	// itself, so the server drops it immediately after creation
+	for _, stmt := range []*fs.Statement{
+		{ObjectName: "evt_cleanup", Text: "CREATE EVENT evt_cleanup ON SCHEDULE EVERY 1 DAY DO DELETE FROM users WHERE id = 0"},
+		{ObjectName: "evt_expired", Text: "CREATE EVENT evt_expired ON SCHEDULE AT '2001-01-01 00:00:00' ON COMPLETION NOT PRESERVE DO DELETE FROM users WHERE id = 0"},
+	} {
+		stmt.Type, stmt.ObjectType = fs.StatementTypeCreate, fs.ObjectTypeEvent
+		dir.LogicalSchemas[0].AddStatement(stmt)
+	}
+	wsSchema, err := ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	if len(wsSchema.Failures) != 1 || wsSchema.Failures[0].Statement.ObjectName != "evt_expired" {
+		t.Errorf("Expected only evt_expired to fail, instead found failures %v", wsSchema.Failures)
+	}
+	if len(wsSchema.Events) != 1 || wsSchema.Events[0].Name != "evt_cleanup" || wsSchema.Events[0].Status != "ENABLED" {
+		t.Errorf("Unexpected events returned by ExecLogicalSchema: %+v", wsSchema.Events)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/tengo"
 )
 
@@ -22,6 +23,8 @@ type LocalDocker struct {
 	releaseLock       releaseFunc
 	cleanupAction     CleanupAction
 	defaultConnParams string
+	maxRows           int
+	skipLock          bool
 }
 
 var cstore struct {
@@ -51,6 +54,8 @@ func NewLocalDocker(opts Options) (ld *LocalDocker, err error) {
 		schemaName:        opts.SchemaName,
 		cleanupAction:     opts.CleanupAction,
 		defaultConnParams: opts.DefaultConnParams,
+		maxRows:           opts.MaxRows,
+		skipLock:          opts.SkipLock,
 	}
 	image := opts.Flavor.String()
 	if opts.ContainerName == "" {
@@ -67,6 +72,7 @@ func NewLocalDocker(opts Options) (ld *LocalDocker, err error) {
 			DefaultConnParams: "", // intentionally not set here; see important comment in ConnectionPool()
 		})
 		if ld.d != nil {
+			util.ApplySQLEcho(ld.d.Instance)
 			cstore.containers[opts.ContainerName] = ld.d
 			RegisterShutdownFunc(ld.shutdown)
 		}
@@ -75,9 +81,15 @@ func NewLocalDocker(opts Options) (ld *LocalDocker, err error) {
 		}
 	}
 
-	lockName := fmt.Sprintf("skeema.%s", ld.schemaName)
-	if ld.releaseLock, err = getLock(ld.d.Instance, lockName, opts.LockWaitTimeout); err != nil {
-		return nil, fmt.Errorf("Unable to obtain lock on %s: %s", ld.d.Instance, err)
+	if ld.skipLock {
+		ld.releaseLock = skippedLock
+		logEvent(TypeLocalDocker, ld.d.Instance, ld.schemaName, "Skipped workspace lock")
+	} else {
+		lockName := fmt.Sprintf("skeema.%s", ld.schemaName)
+		if ld.releaseLock, err = getLock(ld.d.Instance, lockName, opts.LockWaitTimeout); err != nil {
+			return nil, fmt.Errorf("Unable to obtain lock on %s: %s", ld.d.Instance, err)
+		}
+		logEvent(TypeLocalDocker, ld.d.Instance, ld.schemaName, "Obtained workspace lock")
 	}
 	// If this function errors, don't continue to hold the lock
 	defer func() {
@@ -92,11 +104,10 @@ func NewLocalDocker(opts Options) (ld *LocalDocker, err error) {
 	} else if has {
 		// Attempt to drop the schema, so we can recreate it below. (This is safer
 		// than attempting to re-use the schema.) Fail if any tables actually have
-		// 1 or more rows.
-		dropOpts := tengo.BulkDropOptions{
-			MaxConcurrency: 10,
-			OnlyIfEmpty:    true,
-			SkipBinlog:     true,
+		// more than max-rows rows.
+		dropOpts, err := bulkDropOptions(ld.d.Instance, ld.schemaName, ld.maxRows, 10, true)
+		if err != nil {
+			return ld, fmt.Errorf("Cannot drop existing temporary schema on %s: %s", ld.d.Instance, err)
 		}
 		if err := ld.d.DropSchema(ld.schemaName, dropOpts); err != nil {
 			return ld, fmt.Errorf("Cannot drop existing temporary schema on %s: %s", ld.d.Instance, err)
@@ -112,6 +123,8 @@ func NewLocalDocker(opts Options) (ld *LocalDocker, err error) {
 	if err != nil {
 		return ld, fmt.Errorf("Cannot create temporary schema on %s: %s", ld.d.Instance, err)
 	}
+	logEvent(TypeLocalDocker, ld.d.Instance, ld.schemaName, "Created workspace schema")
+	trackWorkspace(ld)
 	return ld, nil
 }
 
@@ -149,28 +162,31 @@ func (ld *LocalDocker) IntrospectSchema() (*tengo.Schema, error) {
 }
 
 // Cleanup drops the temporary schema from the Dockerized instance. If any
-// tables have any rows in the temp schema, the cleanup aborts and an error is
-// returned.
+// tables in the temp schema have more rows than permitted by Options.MaxRows
+// (default 0), the cleanup aborts and an error is returned.
 // Cleanup does not handle stopping or destroying the container. If requested,
 // that is handled by Shutdown() instead, so that containers aren't needlessly
 // created and stopped/destroyed multiple times during a program's execution.
 func (ld *LocalDocker) Cleanup() error {
-	if ld.releaseLock == nil {
+	if !untrackWorkspace(ld) {
 		return errors.New("Cleanup() called multiple times on same LocalDocker")
 	}
 	defer func() {
 		ld.releaseLock()
 		ld.releaseLock = nil
+		if !ld.skipLock {
+			logEvent(TypeLocalDocker, ld.d.Instance, ld.schemaName, "Released workspace lock")
+		}
 	}()
 
-	dropOpts := tengo.BulkDropOptions{
-		MaxConcurrency: 10,
-		OnlyIfEmpty:    true,
-		SkipBinlog:     true,
+	dropOpts, err := bulkDropOptions(ld.d.Instance, ld.schemaName, ld.maxRows, 10, true)
+	if err != nil {
+		return fmt.Errorf("Cannot drop temporary schema on %s: %s", ld.d.Instance, err)
 	}
 	if err := ld.d.DropSchema(ld.schemaName, dropOpts); err != nil {
 		return fmt.Errorf("Cannot drop temporary schema on %s: %s", ld.d.Instance, err)
 	}
+	logEvent(TypeLocalDocker, ld.d.Instance, ld.schemaName, "Dropped workspace schema")
 	return nil
 }
 
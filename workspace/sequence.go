@@ -0,0 +1,153 @@
+package workspace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// Sequence represents a MariaDB sequence. tengo does not support sequences, so
+// they are introspected directly from information_schema and SHOW CREATE
+// SEQUENCE.
+type Sequence struct {
+	Name            string
+	DataType        string // only reported by MariaDB 11.5+ for non-default types
+	Start           string
+	MinValue        string
+	MaxValue        string
+	Increment       string
+	Cache           string // "0" if NOCACHE
+	Cycle           bool
+	CreateStatement string
+}
+
+// SupportsSequences returns true if flavor supports CREATE SEQUENCE.
+func SupportsSequences(flavor tengo.Flavor) bool {
+	return flavor.VendorMinVersion(tengo.VendorMariaDB, 10, 3)
+}
+
+// reSequenceOptions matches the options of a sequence, as formatted by SHOW
+// CREATE SEQUENCE.
+var reSequenceOptions = regexp.MustCompile(`(?i)\s(?:as\s+(\w+(?:\s+unsigned)?)\s+)?start\s+with\s+(-?\d+)\s+minvalue\s+(-?\d+)\s+maxvalue\s+(-?\d+)\s+increment\s+by\s+(-?\d+)\s+(?:cache\s+(\d+)|(nocache))\s+(cycle|nocycle)\b`)
+
+// IntrospectSequences returns the sequences in the default database of db,
+// sorted by name.
+func IntrospectSequences(db *sqlx.DB) ([]*Sequence, error) {
+	var names []string
+	query := `
+		SELECT   table_name AS table_name
+		FROM     information_schema.tables
+		WHERE    table_schema = DATABASE() AND table_type = 'SEQUENCE'
+		ORDER BY table_name`
+	if err := db.Select(&names, query); err != nil {
+		return nil, err
+	}
+	sequences := make([]*Sequence, 0, len(names))
+	for _, name := range names {
+		seq := &Sequence{Name: name}
+		query := fmt.Sprintf("SHOW CREATE SEQUENCE %s", tengo.EscapeIdentifier(name))
+		if err := db.QueryRow(query).Scan(&name, &seq.CreateStatement); err != nil {
+			return nil, err
+		}
+		if err := seq.parseOptions(); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences, nil
+}
+
+// parseOptions populates the sequence's options from its CreateStatement, which
+// must be in the format used by SHOW CREATE SEQUENCE.
+func (seq *Sequence) parseOptions() error {
+	m := reSequenceOptions.FindStringSubmatch(seq.CreateStatement)
+	if m == nil {
+		return fmt.Errorf("Unable to parse options of sequence %s from SHOW CREATE SEQUENCE", tengo.EscapeIdentifier(seq.Name))
+	}
+	seq.DataType = strings.ToLower(m[1])
+	seq.Start, seq.MinValue, seq.MaxValue, seq.Increment = m[2], m[3], m[4], m[5]
+	if seq.Cache = m[6]; m[7] != "" {
+		seq.Cache = "0"
+	}
+	seq.Cycle = strings.EqualFold(m[8], "cycle")
+	return nil
+}
+
+// Matches returns true if live is functionally equivalent to the receiver.
+func (seq *Sequence) Matches(live *Sequence) bool {
+	return seq.Name == live.Name && seq.DataType == live.DataType && seq.Start == live.Start && seq.MinValue == live.MinValue && seq.MaxValue == live.MaxValue && seq.Increment == live.Increment && seq.Cache == live.Cache && seq.Cycle == live.Cycle
+}
+
+// AlterStatement returns an ALTER SEQUENCE statement which modifies live to
+// match the receiver, including only the clauses for options which differ. An
+// empty string is returned if the sequences match, or if the sequences differ
+// in data type, which ALTER SEQUENCE cannot change.
+func (seq *Sequence) AlterStatement(live *Sequence) string {
+	if seq.DataType != live.DataType {
+		return ""
+	}
+	var clauses []string
+	if seq.Increment != live.Increment {
+		clauses = append(clauses, "INCREMENT BY "+seq.Increment)
+	}
+	if seq.MinValue != live.MinValue {
+		clauses = append(clauses, "MINVALUE "+seq.MinValue)
+	}
+	if seq.MaxValue != live.MaxValue {
+		clauses = append(clauses, "MAXVALUE "+seq.MaxValue)
+	}
+	if seq.Start != live.Start {
+		clauses = append(clauses, "START WITH "+seq.Start)
+	}
+	if seq.Cache != live.Cache {
+		if seq.Cache == "0" {
+			clauses = append(clauses, "NOCACHE")
+		} else {
+			clauses = append(clauses, "CACHE "+seq.Cache)
+		}
+	}
+	if seq.Cycle != live.Cycle {
+		if seq.Cycle {
+			clauses = append(clauses, "CYCLE")
+		} else {
+			clauses = append(clauses, "NOCYCLE")
+		}
+	}
+	if len(clauses) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ALTER SEQUENCE %s %s", tengo.EscapeIdentifier(seq.Name), strings.Join(clauses, " "))
+}
+
+// introspectSequences is used by ExecLogicalSchema to introspect the sequences
+// created in a workspace from the supplied CREATE SEQUENCE statements. The
+// returned sequences use the original statements as their CreateStatement.
+// Afterwards, the sequences are dropped from the workspace, since workspace
+// cleanup does not otherwise handle sequences.
+func introspectSequences(ws Workspace, statements []*fs.Statement) ([]*Sequence, error) {
+	db, err := ws.ConnectionPool("")
+	if err != nil {
+		return nil, err
+	}
+	sequences, err := IntrospectSequences(db)
+	if err != nil {
+		return nil, err
+	}
+	statementsByName := make(map[string]*fs.Statement, len(statements))
+	for _, stmt := range statements {
+		statementsByName[strings.ToLower(stmt.ObjectName)] = stmt
+	}
+	for _, seq := range sequences {
+		if stmt := statementsByName[strings.ToLower(seq.Name)]; stmt != nil {
+			seq.CreateStatement = stmt.Body()
+		}
+		if _, err := db.Exec("DROP SEQUENCE IF EXISTS " + tengo.EscapeIdentifier(seq.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return sequences, nil
+}
@@ -0,0 +1,83 @@
+package workspace
+
+import (
+	"testing"
+)
+
+func TestSequenceParseOptions(t *testing.T) {
+	seq := &Sequence{
+		Name:            "s1",
+		CreateStatement: "CREATE SEQUENCE `s1` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB",
+	}
+	if err := seq.parseOptions(); err != nil {
+		t.Fatalf("Unexpected error from parseOptions: %s", err)
+	}
+	expected := Sequence{
+		Name:            "s1",
+		Start:           "1",
+		MinValue:        "1",
+		MaxValue:        "9223372036854775806",
+		Increment:       "1",
+		Cache:           "1000",
+		CreateStatement: seq.CreateStatement,
+	}
+	if *seq != expected {
+		t.Errorf("Unexpected result from parseOptions: %+v", *seq)
+	}
+
+	seq.CreateStatement = "CREATE SEQUENCE `s1` as tinyint unsigned start with 10 minvalue 1 maxvalue 254 increment by -2 nocache cycle ENGINE=InnoDB"
+	if err := seq.parseOptions(); err != nil {
+		t.Fatalf("Unexpected error from parseOptions: %s", err)
+	}
+	if seq.DataType != "tinyint unsigned" || seq.Start != "10" || seq.Increment != "-2" || seq.Cache != "0" || !seq.Cycle {
+		t.Errorf("Unexpected result from parseOptions: %+v", *seq)
+	}
+
+	seq.CreateStatement = "CREATE SEQUENCE s1"
+	if err := seq.parseOptions(); err == nil {
+		t.Error("Expected error from parseOptions on statement lacking options, but err was nil")
+	}
+}
+
+func TestSequenceAlterStatement(t *testing.T) {
+	live := &Sequence{
+		Name:      "s1",
+		Start:     "1",
+		MinValue:  "1",
+		MaxValue:  "9223372036854775806",
+		Increment: "1",
+		Cache:     "1000",
+	}
+	desired := *live
+	if !desired.Matches(live) {
+		t.Error("Expected identical sequences to match")
+	}
+	if actual := desired.AlterStatement(live); actual != "" {
+		t.Errorf("Expected identical sequences to have no ALTER, instead found %q", actual)
+	}
+
+	desired.Increment, desired.Cache = "5", "0"
+	expected := "ALTER SEQUENCE `s1` INCREMENT BY 5 NOCACHE"
+	if desired.Matches(live) {
+		t.Error("Expected sequences with different options to not match")
+	}
+	if actual := desired.AlterStatement(live); actual != expected {
+		t.Errorf("Unexpected result from AlterStatement:\nexpected %q\nfound    %q", expected, actual)
+	}
+
+	desired = *live
+	desired.MaxValue, desired.Start, desired.Cycle = "1000", "100", true
+	expected = "ALTER SEQUENCE `s1` MAXVALUE 1000 START WITH 100 CYCLE"
+	if actual := desired.AlterStatement(live); actual != expected {
+		t.Errorf("Unexpected result from AlterStatement:\nexpected %q\nfound    %q", expected, actual)
+	}
+	if actual := live.AlterStatement(&desired); actual != "ALTER SEQUENCE `s1` MAXVALUE 9223372036854775806 START WITH 1 NOCYCLE" {
+		t.Errorf("Unexpected result from AlterStatement: %q", actual)
+	}
+
+	desired = *live
+	desired.DataType = "int"
+	if desired.Matches(live) || desired.AlterStatement(live) != "" {
+		t.Error("Expected sequences with different data types to not match, and have no ALTER")
+	}
+}
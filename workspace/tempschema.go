@@ -1,10 +1,17 @@
 package workspace
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unicode/utf8"
 
+	"github.com/VividCortex/mysqlerr"
 	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
 	"github.com/skeema/tengo"
 )
 
@@ -16,6 +23,8 @@ type TempSchema struct {
 	keepSchema  bool
 	concurrency int
 	skipBinlog  bool
+	maxRows     int
+	skipLock    bool
 	inst        *tengo.Instance
 	releaseLock releaseFunc
 }
@@ -26,17 +35,29 @@ func NewTempSchema(opts Options) (ts *TempSchema, err error) {
 	if opts.Instance == nil {
 		return nil, errors.New("No instance defined in options")
 	}
+	schemaName, err := tempSchemaName(opts)
+	if err != nil {
+		return nil, err
+	}
 	ts = &TempSchema{
-		schemaName:  opts.SchemaName,
+		schemaName:  schemaName,
 		keepSchema:  opts.CleanupAction == CleanupActionNone,
 		inst:        opts.Instance,
 		concurrency: opts.Concurrency,
 		skipBinlog:  opts.SkipBinlog,
+		maxRows:     opts.MaxRows,
+		skipLock:    opts.SkipLock,
 	}
 
-	lockName := fmt.Sprintf("skeema.%s", ts.schemaName)
-	if ts.releaseLock, err = getLock(ts.inst, lockName, opts.LockWaitTimeout); err != nil {
-		return nil, fmt.Errorf("Unable to lock temporary schema on %s: %s", ts.inst, err)
+	if ts.skipLock {
+		ts.releaseLock = skippedLock
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Skipped workspace lock")
+	} else {
+		lockName := fmt.Sprintf("skeema.%s", ts.schemaName)
+		if ts.releaseLock, err = getLock(ts.inst, lockName, opts.LockWaitTimeout); err != nil {
+			return nil, fmt.Errorf("Unable to lock temporary schema on %s: %s", ts.inst, err)
+		}
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Obtained workspace lock")
 	}
 
 	// If NewTempSchema errors, don't continue to hold the lock
@@ -55,12 +76,15 @@ func NewTempSchema(opts Options) (ts *TempSchema, err error) {
 	if has, err := ts.inst.HasSchema(ts.schemaName); err != nil {
 		return ts, fmt.Errorf("Unable to check for existence of temp schema on %s: %s", ts.inst, err)
 	} else if has {
-		// Attempt to drop any tables already present in tempSchema, but fail if
-		// any of them actually have 1 or more rows
-		dropOpts := tengo.BulkDropOptions{
-			MaxConcurrency: ts.concurrency,
-			OnlyIfEmpty:    true,
-			SkipBinlog:     opts.SkipBinlog,
+		// If the schema has a marker table, it was left behind by a previous
+		// process which was interrupted before it could clean up. The lock is now
+		// held, so nothing else is using the schema, and any rows in its tables
+		// were inserted by that process; they are dropped without a max-rows check.
+		// Otherwise, attempt to drop any tables already present in tempSchema, but
+		// fail if any of them actually have more than max-rows rows
+		dropOpts, err := ts.leftoverDropOptions()
+		if err != nil {
+			return ts, fmt.Errorf("Cannot drop existing temp schema tables on %s: %s", ts.inst, err)
 		}
 		if err := ts.inst.DropTablesInSchema(ts.schemaName, dropOpts); err != nil {
 			return ts, fmt.Errorf("Cannot drop existing temp schema tables on %s: %s", ts.inst, err)
@@ -68,46 +92,138 @@ func NewTempSchema(opts Options) (ts *TempSchema, err error) {
 		if err := ts.inst.DropRoutinesInSchema(ts.schemaName, dropOpts); err != nil {
 			return ts, fmt.Errorf("Cannot drop existing temp schema routines on %s: %s", ts.inst, err)
 		}
+		// If the existing schema's default charset or collation don't match, either
+		// alter it in-place, or drop and recreate it if requested
+		existing, err := ts.inst.Schema(ts.schemaName)
+		if err != nil {
+			return ts, fmt.Errorf("Cannot introspect existing temp schema on %s: %s", ts.inst, err)
+		}
+		if opts.RecreateOnMismatch && existing.AlterStatement(createOpts.DefaultCharSet, createOpts.DefaultCollation) != "" {
+			if err := ts.inst.DropSchema(ts.schemaName, dropOpts); err != nil {
+				return ts, fmt.Errorf("Cannot drop existing temp schema on %s: %s", ts.inst, err)
+			}
+			if _, err := ts.inst.CreateSchema(ts.schemaName, createOpts); err != nil {
+				return ts, fmt.Errorf("Cannot recreate temporary schema on %s: %s", ts.inst, err)
+			}
+			logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Recreated existing workspace schema due to character set or collation mismatch")
+			ts.activate()
+			return ts, nil
+		}
 		if err := ts.inst.AlterSchema(ts.schemaName, createOpts); err != nil {
 			return ts, fmt.Errorf("Cannot alter existing temp schema charset and collation on %s: %s", ts.inst, err)
 		}
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Re-using existing workspace schema")
 	} else {
 		_, err = ts.inst.CreateSchema(ts.schemaName, createOpts)
 		if err != nil {
 			return ts, fmt.Errorf("Cannot create temporary schema on %s: %s", ts.inst, err)
 		}
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Created workspace schema")
 	}
+	ts.activate()
 	return ts, nil
 }
 
+// activate records that ts is in use: in its marker table, so that a later
+// process may clean up the schema if this one is interrupted; and in the set of
+// active workspaces, so that CleanupAll can clean it up upon a signal.
+func (ts *TempSchema) activate() {
+	ts.writeMarker()
+	trackWorkspace(ts)
+}
+
+// leftoverDropOptions returns options for dropping the objects already present
+// in an existing temporary schema. If the schema has a marker table, the marker
+// is dropped, and the returned options permit dropping tables which have rows.
+// Otherwise, the options are subject to the max-rows check of bulkDropOptions.
+// With Options.SkipLock, an error is returned if the marker indicates the
+// schema is still in use by another process on this host.
+func (ts *TempSchema) leftoverDropOptions() (tengo.BulkDropOptions, error) {
+	marker, err := readMarker(ts.inst, ts.schemaName)
+	if err != nil {
+		return tengo.BulkDropOptions{}, err
+	} else if marker == nil {
+		return bulkDropOptions(ts.inst, ts.schemaName, ts.maxRows, ts.concurrency, ts.skipBinlog)
+	} else if ts.skipLock && marker.inUse() {
+		return tengo.BulkDropOptions{}, fmt.Errorf("schema %s is in use by %s", ts.schemaName, marker)
+	}
+	log.Infof("Cleaning up temporary schema %s on %s, left behind by %s", ts.schemaName, ts.inst, marker)
+	if err := dropMarker(ts.inst, ts.schemaName, ts.skipBinlog); err != nil {
+		return tengo.BulkDropOptions{}, err
+	}
+	return tengo.BulkDropOptions{
+		MaxConcurrency: ts.concurrency,
+		SkipBinlog:     ts.skipBinlog,
+	}, nil
+}
+
+// tempSchemaName returns the name of the temporary schema to use, which is
+// opts.SchemaName unless opts.SchemaNameFunc is set. An error is returned if
+// the name is not a legal schema name.
+func tempSchemaName(opts Options) (string, error) {
+	name := opts.SchemaName
+	if opts.SchemaNameFunc != nil {
+		name = opts.SchemaNameFunc(name)
+	}
+	if name == "" {
+		return "", errors.New("Temporary schema name cannot be blank")
+	} else if utf8.RuneCountInString(name) > 64 {
+		return "", fmt.Errorf("Temporary schema name %s exceeds the maximum length of 64 characters", name)
+	} else if strings.HasSuffix(name, " ") {
+		return "", fmt.Errorf("Temporary schema name %q cannot end with a space", name)
+	} else if strings.ContainsRune(name, 0) || !utf8.ValidString(name) {
+		return "", fmt.Errorf("Temporary schema name %q contains invalid characters", name)
+	}
+	return name, nil
+}
+
 // ConnectionPool returns a connection pool (*sqlx.DB) to the temporary
 // workspace schema, using the supplied connection params (which may be blank).
 func (ts *TempSchema) ConnectionPool(params string) (*sqlx.DB, error) {
 	return ts.inst.Connect(ts.schemaName, params)
 }
 
-// IntrospectSchema introspects and returns the temporary workspace schema.
+// IntrospectSchema introspects and returns the temporary workspace schema. The
+// schema's marker table is omitted.
 func (ts *TempSchema) IntrospectSchema() (*tengo.Schema, error) {
-	return ts.inst.Schema(ts.schemaName)
+	schema, err := ts.inst.Schema(ts.schemaName)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]*tengo.Table, 0, len(schema.Tables))
+	for _, table := range schema.Tables {
+		if table.Name != markerTableName {
+			tables = append(tables, table)
+		}
+	}
+	schema.Tables = tables
+	return schema, nil
 }
 
 // Cleanup either drops the temporary schema (if not using reuse-temp-schema)
 // or just drops all tables in the schema (if using reuse-temp-schema). If any
-// tables have any rows in the temp schema, the cleanup aborts and an error is
-// returned.
+// tables in the temp schema have more rows than permitted by Options.MaxRows
+// (default 0), the cleanup aborts and an error is returned.
 func (ts *TempSchema) Cleanup() error {
-	if ts.releaseLock == nil {
+	if !untrackWorkspace(ts) {
 		return errors.New("Cleanup() called multiple times on same TempSchema")
 	}
 	defer func() {
 		ts.releaseLock()
 		ts.releaseLock = nil
+		if !ts.skipLock {
+			logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Released workspace lock")
+		}
 	}()
 
-	dropOpts := tengo.BulkDropOptions{
-		MaxConcurrency: ts.concurrency,
-		OnlyIfEmpty:    true,
-		SkipBinlog:     ts.skipBinlog,
+	// The marker table always has a row, so it must be dropped before checking
+	// whether the other tables are empty
+	if err := dropMarker(ts.inst, ts.schemaName, ts.skipBinlog); err != nil {
+		return fmt.Errorf("Cannot clean up temporary schema on %s: %s", ts.inst, err)
+	}
+	dropOpts, err := bulkDropOptions(ts.inst, ts.schemaName, ts.maxRows, ts.concurrency, ts.skipBinlog)
+	if err != nil {
+		return fmt.Errorf("Cannot clean up temporary schema on %s: %s", ts.inst, err)
 	}
 	if ts.keepSchema {
 		if err := ts.inst.DropTablesInSchema(ts.schemaName, dropOpts); err != nil {
@@ -116,8 +232,171 @@ func (ts *TempSchema) Cleanup() error {
 		if err := ts.inst.DropRoutinesInSchema(ts.schemaName, dropOpts); err != nil {
 			return fmt.Errorf("Cannot drop routines in temporary schema on %s: %s", ts.inst, err)
 		}
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Cleaned up workspace schema contents")
 	} else if err := ts.inst.DropSchema(ts.schemaName, dropOpts); err != nil {
 		return fmt.Errorf("Cannot drop temporary schema on %s: %s", ts.inst, err)
+	} else {
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Dropped workspace schema")
 	}
 	return nil
 }
+
+// OrphanedTempSchemas returns the names of any schemas on inst that match the
+// naming pattern of temporary workspace schemas: either exactly baseName, or
+// baseName followed by an underscore and suffix. Such schemas may have been
+// left behind by a previous Skeema process that crashed or was killed before
+// it could clean up its workspace.
+func OrphanedTempSchemas(inst *tengo.Instance, baseName string) ([]string, error) {
+	if baseName == "" {
+		return nil, errors.New("No temp-schema name supplied")
+	}
+	schemaNames, err := inst.SchemaNames()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, name := range schemaNames {
+		if name == baseName || strings.HasPrefix(name, baseName+"_") {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// DropOrphanedTempSchema drops a leftover temporary workspace schema on inst.
+// The schema's workspace lock is obtained first, to ensure no other Skeema
+// process is actively using the schema; if the lock cannot be obtained within
+// opts.LockWaitTimeout, an error is returned. An error is also returned if any
+// table in the schema has one or more rows, in which case nothing is dropped,
+// unless the schema has a marker table showing it was created by Skeema.
+// Only opts.LockWaitTimeout, opts.Concurrency, and opts.SkipBinlog are used.
+func DropOrphanedTempSchema(inst *tengo.Instance, schemaName string, opts Options) error {
+	lockName := fmt.Sprintf("skeema.%s", schemaName)
+	releaseLock, err := getLock(inst, lockName, opts.LockWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("Unable to lock temporary schema %s on %s; it may be in use by another process: %s", schemaName, inst, err)
+	}
+	defer releaseLock()
+	logEvent(TypeTempSchema, inst, schemaName, "Obtained workspace lock")
+
+	// A schema with a marker table was created by Skeema, so any rows in its
+	// tables were inserted by the interrupted process
+	marker, err := readMarker(inst, schemaName)
+	if err != nil {
+		return fmt.Errorf("Cannot check temporary schema %s on %s: %s", schemaName, inst, err)
+	} else if marker != nil {
+		log.Debugf("Temporary schema %s on %s was left behind by %s", schemaName, inst, marker)
+	}
+	dropOpts := tengo.BulkDropOptions{
+		MaxConcurrency: opts.Concurrency,
+		OnlyIfEmpty:    marker == nil,
+		SkipBinlog:     opts.SkipBinlog,
+	}
+	if err := inst.DropSchema(schemaName, dropOpts); err != nil {
+		return fmt.Errorf("Cannot drop temporary schema %s on %s: %s", schemaName, inst, err)
+	}
+	logEvent(TypeTempSchema, inst, schemaName, "Dropped orphaned workspace schema")
+	return nil
+}
+
+// markerTableName is the name of a table created in each temporary schema,
+// recording which process created the schema. If the process is interrupted
+// before it can clean up, the marker shows that the schema's contents were
+// created by Skeema, and may safely be dropped by a later process.
+const markerTableName = "_skeema_workspace"
+
+// workspaceMarker describes the process which created a temporary schema, as
+// recorded in its marker table.
+type workspaceMarker struct {
+	Hostname  string `db:"hostname"`
+	PID       int    `db:"pid"`
+	CreatedAt string `db:"created_at"` // UTC, in the server's DATETIME format
+}
+
+// String returns a human-readable description of the process.
+func (wm *workspaceMarker) String() string {
+	return fmt.Sprintf("process %d on %s at %s UTC", wm.PID, wm.Hostname, wm.CreatedAt)
+}
+
+// inUse returns true if the marker was created by another process which is
+// still running. This can only be determined for processes on the same host as
+// this one; for other hosts, false is returned.
+func (wm *workspaceMarker) inUse() bool {
+	hostname, err := os.Hostname()
+	return err == nil && wm.Hostname == hostname && wm.PID != os.Getpid() && processRunning(wm.PID)
+}
+
+// processRunning returns true if a process with the supplied pid exists.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) != syscall.ESRCH
+}
+
+// markerParams returns connection params for creating or dropping a marker
+// table.
+func markerParams(skipBinlog bool) string {
+	if skipBinlog {
+		return "sql_log_bin=0"
+	}
+	return ""
+}
+
+// writeMarker creates a marker table in the temporary schema, describing the
+// current process. Failure to do so is logged but otherwise ignored, since the
+// marker is only needed to clean up after an interrupted process.
+func (ts *TempSchema) writeMarker() {
+	db, err := ts.inst.Connect(ts.schemaName, markerParams(ts.skipBinlog))
+	if err == nil {
+		create := "CREATE TABLE " + tengo.EscapeIdentifier(markerTableName) + ` (
+			id tinyint unsigned NOT NULL,
+			hostname varchar(255) NOT NULL,
+			pid int unsigned NOT NULL,
+			created_at datetime NOT NULL,
+			PRIMARY KEY (id)
+		)`
+		_, err = db.Exec(create)
+	}
+	if err == nil {
+		hostname, _ := os.Hostname()
+		insert := "INSERT INTO " + tengo.EscapeIdentifier(markerTableName) + " (id, hostname, pid, created_at) VALUES (1, ?, ?, UTC_TIMESTAMP())"
+		_, err = db.Exec(insert, hostname, os.Getpid())
+	}
+	if err != nil {
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Unable to create workspace marker table: "+err.Error())
+	} else {
+		logEvent(TypeTempSchema, ts.inst, ts.schemaName, "Created workspace marker table")
+	}
+}
+
+// readMarker returns the marker recorded in a temporary schema, or nil if the
+// schema has no marker table, or the table has no row.
+func readMarker(inst *tengo.Instance, schemaName string) (*workspaceMarker, error) {
+	db, err := inst.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	var marker workspaceMarker
+	query := "SELECT hostname, pid, created_at FROM " + tengo.EscapeIdentifier(markerTableName)
+	if err := db.Get(&marker, query); err == sql.ErrNoRows || tengo.IsDatabaseError(err, mysqlerr.ER_NO_SUCH_TABLE) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+// dropMarker drops the marker table from a temporary schema, if present.
+func dropMarker(inst *tengo.Instance, schemaName string, skipBinlog bool) error {
+	db, err := inst.Connect(schemaName, markerParams(skipBinlog))
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DROP TABLE IF EXISTS " + tengo.EscapeIdentifier(markerTableName))
+	return err
+}
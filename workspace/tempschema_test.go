@@ -1,8 +1,14 @@
 package workspace
 
 import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/skeema/tengo"
 )
 
 func (s WorkspaceIntegrationSuite) TestTempSchema(t *testing.T) {
@@ -106,6 +112,307 @@ func (s WorkspaceIntegrationSuite) TestTempSchemaCleanupDrop(t *testing.T) {
 	}
 }
 
+func (s WorkspaceIntegrationSuite) TestTempSchemaMismatch(t *testing.T) {
+	opts := Options{
+		Type:                TypeTempSchema,
+		CleanupAction:       CleanupActionNone,
+		Instance:            s.d.Instance,
+		SchemaName:          "_skeema_tmp",
+		DefaultCharacterSet: "latin1",
+		DefaultCollation:    "latin1_swedish_ci",
+		LockWaitTimeout:     100 * time.Millisecond,
+		Concurrency:         5,
+	}
+
+	// An event is used to determine whether the kept schema is reused or
+	// recreated, since events are not removed when emptying the schema
+	reuse := func(charSet, collation string, recreate bool) (eventExists bool) {
+		t.Helper()
+		opts.DefaultCharacterSet, opts.DefaultCollation, opts.RecreateOnMismatch = charSet, collation, recreate
+		ts, err := NewTempSchema(opts)
+		if err != nil {
+			t.Fatalf("Unexpected error from NewTempSchema: %s", err)
+		}
+		if schema, err := ts.inst.Schema(opts.SchemaName); err != nil {
+			t.Fatalf("Unexpectedly unable to obtain schema: %v", err)
+		} else if schema.CharSet != charSet || schema.Collation != collation {
+			t.Errorf("Expected temp schema to have defaults %s / %s, instead found %s / %s", charSet, collation, schema.CharSet, schema.Collation)
+		}
+		db, err := ts.inst.Connect("", "")
+		if err != nil {
+			t.Fatalf("Unable to connect: %s", err)
+		}
+		var eventCount int
+		if err := db.QueryRow("SELECT COUNT(*) FROM information_schema.events WHERE event_schema = ?", opts.SchemaName).Scan(&eventCount); err != nil {
+			t.Fatalf("Unable to query events: %s", err)
+		}
+		if eventCount == 0 {
+			db.MustExec("CREATE EVENT _skeema_tmp.marker ON SCHEDULE EVERY 1 DAY DISABLE DO SELECT 1")
+		}
+		if err := ts.Cleanup(); err != nil {
+			t.Fatalf("Unexpected error from cleanup: %s", err)
+		}
+		return eventCount > 0
+	}
+
+	if reuse("latin1", "latin1_swedish_ci", true) {
+		t.Error("Expected newly-created temp schema to lack marker event")
+	}
+	if !reuse("latin1", "latin1_swedish_ci", true) {
+		t.Error("Expected temp schema with matching defaults to be kept, but it was recreated")
+	}
+	if !reuse("utf8mb4", "utf8mb4_unicode_ci", false) {
+		t.Error("Expected mismatched temp schema to be altered in-place, but it was recreated")
+	}
+	if reuse("latin1", "latin1_swedish_ci", true) {
+		t.Error("Expected mismatched temp schema to be recreated, but it was kept")
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestTempSchemaSkipLock(t *testing.T) {
+	opts := Options{
+		Type:                TypeTempSchema,
+		CleanupAction:       CleanupActionDrop,
+		Instance:            s.d.Instance,
+		SchemaName:          "_skeema_tmp",
+		DefaultCharacterSet: "latin1",
+		DefaultCollation:    "latin1_swedish_ci",
+		LockWaitTimeout:     100 * time.Millisecond,
+		Concurrency:         5,
+	}
+
+	// A SkipLock TempSchema should work even while another TempSchema holds the
+	// lock on the same schema name
+	locked, err := NewTempSchema(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema: %s", err)
+	}
+	opts.SkipLock = true
+	opts.CleanupAction = CleanupActionNone
+	ts, err := NewTempSchema(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema with SkipLock despite held lock: %s", err)
+	}
+	if err := ts.Cleanup(); err != nil {
+		t.Errorf("Unexpected error from cleanup: %s", err)
+	}
+	if err := ts.Cleanup(); err == nil {
+		t.Error("Expected repeated calls to Cleanup() to error, but err was nil")
+	}
+
+	// Cleaning up the SkipLock TempSchema must not release the other lock
+	opts.SkipLock = false
+	if _, err := NewTempSchema(opts); err == nil {
+		t.Error("Expected error from already-locked NewTempSchema, instead err is nil")
+	}
+	if err := locked.Cleanup(); err != nil {
+		t.Errorf("Unexpected error from cleanup: %s", err)
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestTempSchemaMaxRows(t *testing.T) {
+	opts := Options{
+		Type:                TypeTempSchema,
+		CleanupAction:       CleanupActionDrop,
+		Instance:            s.d.Instance,
+		SchemaName:          "_skeema_tmp",
+		DefaultCharacterSet: "latin1",
+		DefaultCollation:    "latin1_swedish_ci",
+		LockWaitTimeout:     100 * time.Millisecond,
+		Concurrency:         5,
+		MaxRows:             2,
+	}
+
+	// testdata/tempschema1.sql inserts 3 rows, which exceeds MaxRows=2
+	ts, err := NewTempSchema(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema: %s", err)
+	}
+	if _, err := s.d.SourceSQL("testdata/tempschema1.sql"); err != nil {
+		t.Fatalf("Unexpected SourceSQL error: %s", err)
+	}
+	if err := ts.Cleanup(); err == nil {
+		t.Error("Expected cleanup error since a table exceeded MaxRows, but err was nil")
+	} else if has, _ := s.d.HasSchema(opts.SchemaName); !has {
+		t.Fatal("Expected schema to persist after failed cleanup, but it does not exist")
+	}
+
+	// At the threshold, existing schema should be re-usable, and cleanup should
+	// succeed
+	opts.MaxRows = 3
+	if ts, err = NewTempSchema(opts); err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema with MaxRows at threshold: %s", err)
+	}
+	if _, err := s.d.SourceSQL("testdata/tempschema1.sql"); err != nil {
+		t.Fatalf("Unexpected SourceSQL error: %s", err)
+	}
+	if err := ts.Cleanup(); err != nil {
+		t.Errorf("Unexpected error from cleanup with MaxRows at threshold: %s", err)
+	}
+	if has, err := s.d.HasSchema(opts.SchemaName); has || err != nil {
+		t.Errorf("Schema persisted despite successful cleanup: has=%t err=%s", has, err)
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestOrphanedTempSchemas(t *testing.T) {
+	for _, name := range []string{"_skeema_tmp", "_skeema_tmp_abc", "_skeema_tmpfoo"} {
+		if _, err := s.d.CreateSchema(name, tengo.SchemaCreationOptions{}); err != nil {
+			t.Fatalf("Unexpected error from CreateSchema: %s", err)
+		}
+	}
+	if _, err := s.d.SourceSQL("testdata/tempschema1.sql"); err != nil {
+		t.Fatalf("Unexpected SourceSQL error: %s", err)
+	}
+	names, err := OrphanedTempSchemas(s.d.Instance, "_skeema_tmp")
+	if err != nil {
+		t.Fatalf("Unexpected error from OrphanedTempSchemas: %s", err)
+	}
+	sort.Strings(names)
+	if expected := []string{"_skeema_tmp", "_skeema_tmp_abc"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected OrphanedTempSchemas to return %v, instead found %v", expected, names)
+	}
+	if _, err := OrphanedTempSchemas(s.d.Instance, ""); err == nil {
+		t.Error("Expected error from OrphanedTempSchemas with blank baseName, but err was nil")
+	}
+
+	opts := Options{
+		LockWaitTimeout: 100 * time.Millisecond,
+		Concurrency:     5,
+	}
+
+	// Drop should fail if a table has rows: _skeema_tmp.bar has a row
+	if err := DropOrphanedTempSchema(s.d.Instance, "_skeema_tmp", opts); err == nil {
+		t.Error("Expected error dropping temp schema with non-empty table, but err was nil")
+	} else if has, _ := s.d.HasSchema("_skeema_tmp"); !has {
+		t.Error("Expected schema with non-empty table to be retained, but it was dropped")
+	}
+
+	// Drop should fail if another process holds the lock
+	release, err := getLock(s.d.Instance, "skeema._skeema_tmp_abc", opts.LockWaitTimeout)
+	if err != nil {
+		t.Fatalf("Unexpected error from getLock: %s", err)
+	}
+	if err := DropOrphanedTempSchema(s.d.Instance, "_skeema_tmp_abc", opts); err == nil {
+		t.Error("Expected error dropping locked temp schema, but err was nil")
+	} else if has, _ := s.d.HasSchema("_skeema_tmp_abc"); !has {
+		t.Error("Expected locked schema to be retained, but it was dropped")
+	}
+
+	// With no lock wait timeout, getLock should fail immediately with
+	// ErrLockHeld, rather than a connection error
+	start := time.Now()
+	if _, err := getLock(s.d.Instance, "skeema._skeema_tmp_abc", 0); err != ErrLockHeld {
+		t.Errorf("Expected getLock to return ErrLockHeld, instead found %v", err)
+	} else if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected getLock with no wait to fail immediately, instead took %s", elapsed)
+	}
+	release()
+
+	// Drop should succeed once the lock is released. Brief sleep is needed to
+	// ensure the lock-holding goroutine has actually released.
+	time.Sleep(time.Second)
+	if err := DropOrphanedTempSchema(s.d.Instance, "_skeema_tmp_abc", opts); err != nil {
+		t.Errorf("Unexpected error from DropOrphanedTempSchema: %s", err)
+	} else if has, _ := s.d.HasSchema("_skeema_tmp_abc"); has {
+		t.Error("Expected schema to be dropped, but it still exists")
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestTempSchemaMarker(t *testing.T) {
+	opts := Options{
+		Type:                TypeTempSchema,
+		CleanupAction:       CleanupActionDrop,
+		Instance:            s.d.Instance,
+		SchemaName:          "_skeema_tmp",
+		DefaultCharacterSet: "latin1",
+		DefaultCollation:    "latin1_swedish_ci",
+		LockWaitTimeout:     100 * time.Millisecond,
+		Concurrency:         5,
+	}
+	ts, err := NewTempSchema(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema: %s", err)
+	}
+	if marker, err := readMarker(s.d.Instance, opts.SchemaName); err != nil || marker == nil {
+		t.Fatalf("Unexpected return from readMarker: %+v, %v", marker, err)
+	} else if marker.PID != os.Getpid() || marker.inUse() {
+		t.Errorf("Unexpected marker contents: %+v", marker)
+	}
+	if schema, err := ts.IntrospectSchema(); err != nil {
+		t.Fatalf("Unexpected error from IntrospectSchema: %s", err)
+	} else if schema.HasTable(markerTableName) {
+		t.Error("Expected IntrospectSchema to omit the marker table, but it did not")
+	}
+
+	// Simulate an interrupted process, which released its lock upon exit but did
+	// not clean up, leaving a table with rows. A new TempSchema should drop the
+	// leftover contents regardless, since the marker shows they came from Skeema.
+	if _, err := s.d.SourceSQL("testdata/tempschema1.sql"); err != nil {
+		t.Fatalf("Unexpected SourceSQL error: %s", err)
+	}
+	ts.releaseLock()
+	untrackWorkspace(ts)
+	time.Sleep(time.Second)
+	if ts, err = NewTempSchema(opts); err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema with leftover marker: %s", err)
+	}
+	if schema, err := ts.IntrospectSchema(); err != nil {
+		t.Fatalf("Unexpected error from IntrospectSchema: %s", err)
+	} else if len(schema.Tables) > 0 {
+		t.Errorf("Expected leftover tables to be dropped, instead found %d tables", len(schema.Tables))
+	}
+	if err := ts.Cleanup(); err != nil {
+		t.Errorf("Unexpected error from cleanup: %s", err)
+	}
+
+	// Same situation, but handled by DropOrphanedTempSchema
+	if ts, err = NewTempSchema(opts); err != nil {
+		t.Fatalf("Unexpected error from NewTempSchema: %s", err)
+	}
+	if _, err := s.d.SourceSQL("testdata/tempschema1.sql"); err != nil {
+		t.Fatalf("Unexpected SourceSQL error: %s", err)
+	}
+	ts.releaseLock()
+	untrackWorkspace(ts)
+	time.Sleep(time.Second)
+	if err := DropOrphanedTempSchema(s.d.Instance, opts.SchemaName, opts); err != nil {
+		t.Errorf("Unexpected error from DropOrphanedTempSchema: %s", err)
+	} else if has, _ := s.d.HasSchema(opts.SchemaName); has {
+		t.Error("Expected schema to be dropped, but it still exists")
+	}
+}
+
+func TestWorkspaceMarkerInUse(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("Unable to obtain hostname: %s", err)
+	}
+	if !processRunning(os.Getpid()) {
+		t.Error("Expected processRunning to return true for current process")
+	}
+	if processRunning(0) || processRunning(-1) {
+		t.Error("Expected processRunning to return false for invalid pids")
+	}
+	marker := &workspaceMarker{Hostname: hostname, PID: os.Getpid(), CreatedAt: "2020-01-02 03:04:05"}
+	if marker.inUse() {
+		t.Error("Expected marker from current process to not be considered in use by another process")
+	}
+	if parent := os.Getppid(); parent > 1 {
+		marker.PID = parent
+		if !marker.inUse() {
+			t.Error("Expected marker from running parent process to be considered in use")
+		}
+		marker.Hostname = hostname + ".example"
+		if marker.inUse() {
+			t.Error("Expected marker from another host to not be considered in use")
+		}
+	}
+	marker = &workspaceMarker{Hostname: "foo", PID: 123, CreatedAt: "2020-01-02 03:04:05"}
+	if actual, expected := marker.String(), "process 123 on foo at 2020-01-02 03:04:05 UTC"; actual != expected {
+		t.Errorf("Expected marker String() to return %q, instead found %q", expected, actual)
+	}
+}
+
 func TestTempSchemaNilInstance(t *testing.T) {
 	opts := Options{
 		Type:                TypeTempSchema,
@@ -121,3 +428,41 @@ func TestTempSchemaNilInstance(t *testing.T) {
 		t.Fatal("Expected non-nil error from NewTempSchema, but return was nil")
 	}
 }
+
+func TestGetLockConnectError(t *testing.T) {
+	// Connection failures must be distinguishable from a lock held by another
+	// process, even with no lock wait timeout
+	inst, err := tengo.NewInstance("mysql", "root:fakepw@tcp(127.0.0.1:1)/?timeout=1s")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	for _, maxWait := range []time.Duration{0, 100 * time.Millisecond} {
+		if _, err := getLock(inst, "skeema._skeema_tmp", maxWait); err == nil || err == ErrLockHeld {
+			t.Errorf("Expected connection error from getLock with maxWait=%s, instead found %v", maxWait, err)
+		}
+	}
+}
+
+func TestTempSchemaName(t *testing.T) {
+	opts := Options{SchemaName: "_skeema_tmp"}
+	if name, err := tempSchemaName(opts); name != "_skeema_tmp" || err != nil {
+		t.Errorf("Unexpected return from tempSchemaName: %q, %v", name, err)
+	}
+
+	opts.SchemaNameFunc = func(base string) string {
+		return base + "_build1234"
+	}
+	if name, err := tempSchemaName(opts); name != "_skeema_tmp_build1234" || err != nil {
+		t.Errorf("Unexpected return from tempSchemaName: %q, %v", name, err)
+	}
+
+	badNames := []string{"", "ends with space ", strings.Repeat("x", 65), "nul\x00byte", "bad\xffutf8"}
+	for _, badName := range badNames {
+		opts.SchemaNameFunc = func(string) string {
+			return badName
+		}
+		if _, err := tempSchemaName(opts); err == nil {
+			t.Errorf("Expected tempSchemaName to return an error for name %q, but it did not", badName)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// Trigger represents a trigger. tengo does not support triggers, so they are
+// introspected directly from information_schema and SHOW CREATE TRIGGER.
+type Trigger struct {
+	Name            string `db:"trigger_name"`
+	Table           string `db:"event_object_table"`
+	Timing          string `db:"action_timing"`      // "BEFORE" or "AFTER"
+	Event           string `db:"event_manipulation"` // "INSERT", "UPDATE", or "DELETE"
+	Body            string `db:"action_statement"`
+	Definer         string `db:"definer"`
+	SQLMode         string `db:"sql_mode"`
+	CreateStatement string `db:"-"`
+}
+
+// IntrospectTriggers returns the triggers in the default database of db,
+// sorted by name.
+func IntrospectTriggers(db *sqlx.DB) ([]*Trigger, error) {
+	var triggers []*Trigger
+	query := `
+		SELECT   trigger_name AS trigger_name,
+		         event_object_table AS event_object_table,
+		         action_timing AS action_timing,
+		         event_manipulation AS event_manipulation,
+		         action_statement AS action_statement, definer AS definer,
+		         sql_mode AS sql_mode
+		FROM     information_schema.triggers
+		WHERE    trigger_schema = DATABASE()
+		ORDER BY trigger_name`
+	if err := db.Select(&triggers, query); err != nil {
+		return nil, err
+	}
+	for _, trigger := range triggers {
+		// The number of columns returned by SHOW CREATE TRIGGER varies by server
+		// version, but the original statement is always the third
+		query := fmt.Sprintf("SHOW CREATE TRIGGER %s", tengo.EscapeIdentifier(trigger.Name))
+		values, err := db.QueryRowx(query).SliceScan()
+		if err != nil {
+			return nil, err
+		} else if len(values) < 3 {
+			return nil, fmt.Errorf("Unexpected result from %s", query)
+		}
+		trigger.CreateStatement = fmt.Sprintf("%s", values[2])
+	}
+	return triggers, nil
+}
+
+// Matches returns true if live is functionally equivalent to the receiver. The
+// creation-time sql_mode of the triggers is only compared if compareMetadata
+// is true.
+func (trig *Trigger) Matches(live *Trigger, compareMetadata bool) bool {
+	if trig.Name != live.Name || !strings.EqualFold(trig.Table, live.Table) || trig.Timing != live.Timing || trig.Event != live.Event || trig.Body != live.Body || trig.Definer != live.Definer {
+		return false
+	}
+	return !compareMetadata || trig.SQLMode == live.SQLMode
+}
+
+// introspectTriggers is used by ExecLogicalSchema to introspect the triggers
+// created in a workspace from the supplied CREATE TRIGGER statements, which
+// must be in the order returned by execWithRetries. The returned triggers are
+// in the same order, and use the original statements as their CreateStatement.
+// Unlike events and views, triggers do not need to be dropped from the
+// workspace afterwards, since workspace cleanup drops all tables, which also
+// drops their triggers.
+func introspectTriggers(ws Workspace, statements []*fs.Statement) ([]*Trigger, error) {
+	db, err := ws.ConnectionPool("")
+	if err != nil {
+		return nil, err
+	}
+	introspected, err := IntrospectTriggers(db)
+	if err != nil {
+		return nil, err
+	}
+	triggersByName := make(map[string]*Trigger, len(introspected))
+	for _, trigger := range introspected {
+		triggersByName[strings.ToLower(trigger.Name)] = trigger
+	}
+	triggers := make([]*Trigger, 0, len(statements))
+	for _, stmt := range statements {
+		if trigger := triggersByName[strings.ToLower(stmt.ObjectName)]; trigger != nil {
+			trigger.CreateStatement = stmt.Body()
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers, nil
+}
@@ -0,0 +1,79 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+)
+
+func TestTriggerMatches(t *testing.T) {
+	live := &Trigger{
+		Name:    "trg1",
+		Table:   "users",
+		Timing:  "BEFORE",
+		Event:   "INSERT",
+		Body:    "SET NEW.name = UPPER(NEW.name)",
+		Definer: "root@%",
+		SQLMode: "STRICT_TRANS_TABLES",
+	}
+	desired := *live
+	desired.CreateStatement = "CREATE TRIGGER trg1 BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name)"
+	desired.SQLMode = "ANSI_QUOTES"
+	if !desired.Matches(live, false) {
+		t.Error("Expected triggers differing only in CreateStatement and sql_mode to match without compareMetadata")
+	}
+	if desired.Matches(live, true) {
+		t.Error("Expected triggers differing in sql_mode to not match with compareMetadata")
+	}
+	for _, modify := range []func(trig *Trigger){
+		func(trig *Trigger) { trig.Table = "posts" },
+		func(trig *Trigger) { trig.Timing = "AFTER" },
+		func(trig *Trigger) { trig.Event = "UPDATE" },
+		func(trig *Trigger) { trig.Body = "SET NEW.name = LOWER(NEW.name)" },
+		func(trig *Trigger) { trig.Definer = "app@%" },
+	} {
+		desired := *live
+		modify(&desired)
+		if desired.Matches(live, false) {
+			t.Errorf("Expected triggers to not match: %+v vs %+v", desired, *live)
+		}
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaTriggers(t *testing.T) {
+	dirPath := "../testdata/golden/init/mydb/product"
+	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {
+		dirPath = strings.Replace(dirPath, "golden", "golden-mysql55", 1)
+	}
+	dir := s.getParsedDir(t, dirPath, "")
+	opts, err := OptionsForDir(dir, s.d.Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	opts.LockWaitTimeout = 100 * time.Millisecond
+
+	// trg_broken refers to a nonexistent table, so it can never be created
+	for _, stmt := range []*fs.Statement{
+		{ObjectName: "trg_users", Text: "CREATE TRIGGER trg_users BEFORE INSERT ON users FOR EACH ROW SET NEW.name = UPPER(NEW.name)"},
+		{ObjectName: "trg_broken", Text: "CREATE TRIGGER trg_broken BEFORE INSERT ON nonexistent FOR EACH ROW SET NEW.id = 1"},
+	} {
+		stmt.Type, stmt.ObjectType = fs.StatementTypeCreate, fs.ObjectTypeTrigger
+		dir.LogicalSchemas[0].AddStatement(stmt)
+	}
+	wsSchema, err := ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	if len(wsSchema.Failures) != 1 || wsSchema.Failures[0].Statement.ObjectName != "trg_broken" {
+		t.Errorf("Expected only trg_broken to fail, instead found failures %v", wsSchema.Failures)
+	}
+	if len(wsSchema.Triggers) != 1 {
+		t.Fatalf("Unexpected triggers returned by ExecLogicalSchema: %+v", wsSchema.Triggers)
+	}
+	trig := wsSchema.Triggers[0]
+	if trig.Name != "trg_users" || trig.Table != "users" || trig.Timing != "BEFORE" || trig.Event != "INSERT" || !strings.HasPrefix(trig.CreateStatement, "CREATE TRIGGER trg_users") {
+		t.Errorf("Unexpected trigger introspected: %+v", *trig)
+	}
+}
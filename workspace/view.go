@@ -0,0 +1,127 @@
+package workspace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// View represents a view. tengo does not support views, so they are
+// introspected directly from information_schema and SHOW CREATE VIEW. The
+// Definition and CreateStatement of an introspected view omit any schema name
+// qualifiers referring to the view's own schema, so that views may be compared
+// across schemas with different names, such as a workspace.
+type View struct {
+	Name            string `db:"table_name"`
+	Definer         string `db:"definer"`
+	Security        string `db:"security_type"` // "DEFINER" or "INVOKER"
+	CheckOption     string `db:"check_option"`  // "NONE", "CASCADED", or "LOCAL"
+	Definition      string `db:"view_definition"`
+	Algorithm       string `db:"-"` // "UNDEFINED", "MERGE", or "TEMPTABLE"
+	CreateStatement string `db:"-"`
+}
+
+// reViewAlgorithm matches the ALGORITHM clause of a view, as formatted by SHOW
+// CREATE VIEW.
+var reViewAlgorithm = regexp.MustCompile(`(?i)^CREATE\s+ALGORITHM=(\w+)\s`)
+
+// IntrospectViews returns the views in the default database of db, sorted by
+// name.
+func IntrospectViews(db *sqlx.DB) ([]*View, error) {
+	var schemaName string
+	if err := db.QueryRow("SELECT DATABASE()").Scan(&schemaName); err != nil {
+		return nil, err
+	}
+	var views []*View
+	query := `
+		SELECT   table_name AS table_name, definer AS definer,
+		         security_type AS security_type, check_option AS check_option,
+		         view_definition AS view_definition
+		FROM     information_schema.views
+		WHERE    table_schema = DATABASE()
+		ORDER BY table_name`
+	if err := db.Select(&views, query); err != nil {
+		return nil, err
+	}
+	for _, view := range views {
+		var name, charSetClient, collationConnection string
+		query := fmt.Sprintf("SHOW CREATE VIEW %s", tengo.EscapeIdentifier(view.Name))
+		if err := db.QueryRow(query).Scan(&name, &view.CreateStatement, &charSetClient, &collationConnection); err != nil {
+			return nil, err
+		}
+		m := reViewAlgorithm.FindStringSubmatch(view.CreateStatement)
+		if m == nil {
+			return nil, fmt.Errorf("Unable to parse algorithm of view %s from SHOW CREATE VIEW", tengo.EscapeIdentifier(view.Name))
+		}
+		view.Algorithm = strings.ToUpper(m[1])
+		view.Definition = unqualifyViewReferences(view.Definition, schemaName)
+		view.CreateStatement = unqualifyViewReferences(view.CreateStatement, schemaName)
+	}
+	return views, nil
+}
+
+// unqualifyViewReferences returns a copy of statement with all schema name
+// qualifiers referring to schemaName removed. The server always backtick-quotes
+// identifiers in view definitions, so only quoted qualifiers are considered.
+func unqualifyViewReferences(statement, schemaName string) string {
+	return strings.Replace(statement, tengo.EscapeIdentifier(schemaName)+".", "", -1)
+}
+
+// Matches returns true if live is functionally equivalent to the receiver. This
+// includes comparing the algorithm, definer, and SQL security characteristics
+// of the views, in addition to their definitions.
+func (v *View) Matches(live *View) bool {
+	return v.Name == live.Name && v.Definition == live.Definition && v.Algorithm == live.Algorithm && v.Definer == live.Definer && v.Security == live.Security && v.CheckOption == live.CheckOption
+}
+
+// ReplaceStatement returns a CREATE OR REPLACE VIEW statement which modifies an
+// existing view to match the receiver, which must be a desired view obtained by
+// ExecLogicalSchema. Any clause omitted from the receiver's CreateStatement
+// reverts to its default, just like with ALTER VIEW.
+func (v *View) ReplaceStatement() string {
+	stmt := reCreateViewHead.ReplaceAllString(v.CreateStatement, "${1}CREATE OR REPLACE ")
+	return reViewIfNotExists.ReplaceAllString(stmt, "${1}")
+}
+
+var (
+	reCreateViewHead  = regexp.MustCompile(`^(\s*)(?i:CREATE)\s+(?i:OR\s+REPLACE\s+)?`)
+	reViewIfNotExists = regexp.MustCompile(`(?i)(\bVIEW\s+)IF\s+NOT\s+EXISTS\s+`)
+)
+
+// introspectViews is used by ExecLogicalSchema to introspect the views created
+// in a workspace from the supplied CREATE VIEW statements, which must be in the
+// order returned by execWithRetries. The returned views are in the same order,
+// and use the original statements as their CreateStatement. Afterwards, the
+// views are dropped from the workspace, since workspace cleanup does not
+// otherwise handle views.
+func introspectViews(ws Workspace, statements []*fs.Statement) ([]*View, error) {
+	db, err := ws.ConnectionPool("")
+	if err != nil {
+		return nil, err
+	}
+	introspected, err := IntrospectViews(db)
+	if err != nil {
+		return nil, err
+	}
+	viewsByName := make(map[string]*View, len(introspected))
+	for _, view := range introspected {
+		viewsByName[strings.ToLower(view.Name)] = view
+	}
+	views := make([]*View, 0, len(statements))
+	for _, stmt := range statements {
+		if view := viewsByName[strings.ToLower(stmt.ObjectName)]; view != nil {
+			view.CreateStatement = stmt.Body()
+			views = append(views, view)
+		}
+	}
+	for _, view := range introspected {
+		if _, err := db.Exec("DROP VIEW IF EXISTS " + tengo.EscapeIdentifier(view.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return views, nil
+}
@@ -0,0 +1,107 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+)
+
+func TestUnqualifyViewReferences(t *testing.T) {
+	cases := map[string]string{
+		"select `product`.`users`.`id` AS `id` from `product`.`users`":         "select `users`.`id` AS `id` from `users`",
+		"select `other`.`users`.`id` AS `id` from `other`.`users`":             "select `other`.`users`.`id` AS `id` from `other`.`users`",
+		"select `u`.`id` AS `id` from (`product`.`users` `u` join `x`.`y`)":    "select `u`.`id` AS `id` from (`users` `u` join `x`.`y`)",
+		"select `not_product`.`users`.`id` AS `id` from `not_product`.`users`": "select `not_product`.`users`.`id` AS `id` from `not_product`.`users`",
+	}
+	for input, expected := range cases {
+		if actual := unqualifyViewReferences(input, "product"); actual != expected {
+			t.Errorf("Unexpected result from unqualifyViewReferences(%q): %q", input, actual)
+		}
+	}
+}
+
+func TestViewMatches(t *testing.T) {
+	live := &View{
+		Name:        "v1",
+		Definer:     "root@%",
+		Security:    "DEFINER",
+		CheckOption: "NONE",
+		Definition:  "select `users`.`id` AS `id` from `users`",
+		Algorithm:   "UNDEFINED",
+	}
+	desired := *live
+	desired.CreateStatement = "CREATE VIEW v1 AS SELECT id FROM users"
+	if !desired.Matches(live) {
+		t.Error("Expected views differing only in CreateStatement to match")
+	}
+	for _, modify := range []func(v *View){
+		func(v *View) { v.Definer = "app@%" },
+		func(v *View) { v.Security = "INVOKER" },
+		func(v *View) { v.CheckOption = "CASCADED" },
+		func(v *View) { v.Algorithm = "MERGE" },
+		func(v *View) { v.Definition = "select `users`.`name` AS `name` from `users`" },
+	} {
+		desired := *live
+		modify(&desired)
+		if desired.Matches(live) {
+			t.Errorf("Expected views to not match: %+v vs %+v", desired, *live)
+		}
+	}
+}
+
+func TestViewReplaceStatement(t *testing.T) {
+	cases := map[string]string{
+		"CREATE VIEW v1 AS SELECT id FROM users":                                                         "CREATE OR REPLACE VIEW v1 AS SELECT id FROM users",
+		"create or replace algorithm=merge view v1 as select id from users":                              "CREATE OR REPLACE algorithm=merge view v1 as select id from users",
+		"CREATE DEFINER=`root`@`%` SQL SECURITY INVOKER VIEW IF NOT EXISTS `v1` AS SELECT id FROM users": "CREATE OR REPLACE DEFINER=`root`@`%` SQL SECURITY INVOKER VIEW `v1` AS SELECT id FROM users",
+	}
+	for input, expected := range cases {
+		v := &View{Name: "v1", CreateStatement: input}
+		if actual := v.ReplaceStatement(); actual != expected {
+			t.Errorf("Unexpected result from ReplaceStatement on %q:\nexpected %q\nfound    %q", input, expected, actual)
+		}
+	}
+}
+
+func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaViews(t *testing.T) {
+	dirPath := "../testdata/golden/init/mydb/product"
+	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {
+		dirPath = strings.Replace(dirPath, "golden", "golden-mysql55", 1)
+	}
+	dir := s.getParsedDir(t, dirPath, "")
+	opts, err := OptionsForDir(dir, s.d.Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	opts.LockWaitTimeout = 100 * time.Millisecond
+
+	// a_names depends on b_users, so it can only be created on the second pass,
+	// and c_broken refers to a nonexistent table, so it can never be created
+	for _, stmt := range []*fs.Statement{
+		{ObjectName: "a_names", Text: "CREATE VIEW a_names AS SELECT name FROM b_users"},
+		{ObjectName: "b_users", Text: "CREATE ALGORITHM=MERGE SQL SECURITY INVOKER VIEW b_users AS SELECT id, name FROM users WITH CHECK OPTION"},
+		{ObjectName: "c_broken", Text: "CREATE VIEW c_broken AS SELECT id FROM nonexistent"},
+	} {
+		stmt.Type, stmt.ObjectType = fs.StatementTypeCreate, fs.ObjectTypeView
+		dir.LogicalSchemas[0].AddStatement(stmt)
+	}
+	wsSchema, err := ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	if len(wsSchema.Failures) != 1 || wsSchema.Failures[0].Statement.ObjectName != "c_broken" {
+		t.Errorf("Expected only c_broken to fail, instead found failures %v", wsSchema.Failures)
+	}
+	if len(wsSchema.Views) != 2 || wsSchema.Views[0].Name != "b_users" || wsSchema.Views[1].Name != "a_names" {
+		t.Fatalf("Unexpected views returned by ExecLogicalSchema: %+v", wsSchema.Views)
+	}
+	v := wsSchema.Views[0]
+	if v.Algorithm != "MERGE" || v.Security != "INVOKER" || v.CheckOption != "CASCADED" || strings.Contains(v.Definition, "_skeema_tmp") || !strings.HasPrefix(v.CreateStatement, "CREATE ALGORITHM=MERGE") {
+		t.Errorf("Unexpected view introspected: %+v", *v)
+	}
+	if wsSchema.Table("b_users") != nil {
+		t.Error("Expected views to not be introspected as tables")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -48,6 +49,18 @@ const (
 	TypePrefab                  // A pre-supplied Workspace, possibly from another package
 )
 
+func (t Type) String() string {
+	switch t {
+	case TypeTempSchema:
+		return "temp-schema"
+	case TypeLocalDocker:
+		return "docker"
+	case TypePrefab:
+		return "prefab"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
 // CleanupAction represents how to clean up a workspace.
 type CleanupAction int
 
@@ -81,12 +94,20 @@ type Options struct {
 	SchemaName          string
 	DefaultCharacterSet string
 	DefaultCollation    string
-	DefaultConnParams   string    // only TypeLocalDocker
-	RootPassword        string    // only TypeLocalDocker
-	PrefabWorkspace     Workspace // only TypePrefab
-	LockWaitTimeout     time.Duration
+	DefaultConnParams   string        // only TypeLocalDocker
+	RootPassword        string        // only TypeLocalDocker
+	PrefabWorkspace     Workspace     // only TypePrefab
+	LockWaitTimeout     time.Duration // if 0, fail immediately if another process holds the workspace lock
 	Concurrency         int
 	SkipBinlog          bool
+	MaxRows             int             // max rows permitted in any table upon cleanup
+	SkipLock            bool            // if true, don't obtain a workspace lock; only safe on isolated instances
+	RecreateOnMismatch  bool            // if true, drop and recreate a pre-existing schema with different defaults; only TypeTempSchema
+	DefaultTableOptions fs.TableOptions // added to any CREATE TABLE which does not specify them
+
+	// SchemaNameFunc optionally derives the actual workspace schema name from
+	// SchemaName, for example to include a build ID. Only TypeTempSchema.
+	SchemaNameFunc func(base string) string
 }
 
 // New returns a pointer to a ready-to-use Workspace, using the configuration
@@ -108,7 +129,9 @@ func New(opts Options) (Workspace, error) {
 // workspace won't be temp-schema based.
 // This method relies on option definitions from util.AddGlobalOptions(),
 // including "workspace", "temp-schema", "flavor", "docker-cleanup",
-// "reuse-temp-schema", "temp-schema-threads", "temp-schema-binlog"
+// "reuse-temp-schema", "temp-schema-mismatch", "temp-schema-threads",
+// "temp-schema-binlog", "max-rows", "no-lock", "lock-wait-timeout",
+// "default-table-options"
 func OptionsForDir(dir *fs.Dir, instance *tengo.Instance) (Options, error) {
 	requestedType, err := dir.Config.GetEnum("workspace", "temp-schema", "docker")
 	if err != nil {
@@ -120,6 +143,24 @@ func OptionsForDir(dir *fs.Dir, instance *tengo.Instance) (Options, error) {
 		LockWaitTimeout: 30 * time.Second,
 		Concurrency:     10,
 	}
+	if opts.DefaultTableOptions, err = dir.DefaultTableOptions(); err != nil {
+		return Options{}, err
+	}
+	opts.SkipLock = dir.Config.GetBool("no-lock")
+	if lockWait, err := dir.Config.GetInt("lock-wait-timeout"); err != nil {
+		return Options{}, err
+	} else if lockWait < 0 {
+		return Options{}, errors.New("lock-wait-timeout cannot be negative")
+	} else {
+		opts.LockWaitTimeout = time.Duration(lockWait) * time.Second
+	}
+	maxRows, err := dir.Config.GetInt("max-rows")
+	if err != nil {
+		return Options{}, err
+	} else if maxRows < 0 {
+		return Options{}, errors.New("max-rows cannot be negative")
+	}
+	opts.MaxRows = maxRows
 	if requestedType == "docker" {
 		opts.Type = TypeLocalDocker
 		opts.Flavor = tengo.NewFlavor(dir.Config.Get("flavor"))
@@ -144,6 +185,11 @@ func OptionsForDir(dir *fs.Dir, instance *tengo.Instance) (Options, error) {
 		if !dir.Config.GetBool("reuse-temp-schema") {
 			opts.CleanupAction = CleanupActionDrop
 		}
+		mismatch, err := dir.Config.GetEnum("temp-schema-mismatch", "alter", "recreate")
+		if err != nil {
+			return Options{}, err
+		}
+		opts.RecreateOnMismatch = (mismatch == "recreate")
 		if concurrency, err := dir.Config.GetInt("temp-schema-threads"); err != nil {
 			return Options{}, err
 		} else if concurrency < 1 {
@@ -202,6 +248,54 @@ func RegisterShutdownFunc(f ShutdownFunc) {
 	shutdownFuncs = append(shutdownFuncs, f)
 }
 
+var activeWorkspaces struct {
+	sync.Mutex
+	m map[Workspace]bool
+}
+
+// trackWorkspace records that ws has been created and not yet cleaned up, so
+// that CleanupAll may clean it up if the program is interrupted.
+func trackWorkspace(ws Workspace) {
+	activeWorkspaces.Lock()
+	defer activeWorkspaces.Unlock()
+	if activeWorkspaces.m == nil {
+		activeWorkspaces.m = make(map[Workspace]bool)
+	}
+	activeWorkspaces.m[ws] = true
+}
+
+// untrackWorkspace removes ws from the set of workspaces which have not yet
+// been cleaned up. It returns false if ws was not in the set, for example
+// because its cleanup has already begun elsewhere.
+func untrackWorkspace(ws Workspace) bool {
+	activeWorkspaces.Lock()
+	defer activeWorkspaces.Unlock()
+	if !activeWorkspaces.m[ws] {
+		return false
+	}
+	delete(activeWorkspaces.m, ws)
+	return true
+}
+
+// CleanupAll cleans up all workspaces which have been created but not yet
+// cleaned up. It is intended for use when the program is interrupted, such as
+// by a signal, so that temporary schemas and their locks are not left behind.
+// Errors are logged rather than returned, so that every workspace is attempted.
+// Programs should still call Shutdown afterwards.
+func CleanupAll() {
+	activeWorkspaces.Lock()
+	workspaces := make([]Workspace, 0, len(activeWorkspaces.m))
+	for ws := range activeWorkspaces.m {
+		workspaces = append(workspaces, ws)
+	}
+	activeWorkspaces.Unlock()
+	for _, ws := range workspaces {
+		if err := ws.Cleanup(); err != nil {
+			log.Warn(err.Error())
+		}
+	}
+}
+
 // StatementError represents a problem that occurred when executing a specific
 // fs.Statement in a Workspace.
 type StatementError struct {
@@ -230,6 +324,11 @@ type Schema struct {
 	*tengo.Schema
 	LogicalSchema *fs.LogicalSchema
 	Failures      []*StatementError
+	Events        []*Event
+	Sequences     []*Sequence
+	Views         []*View
+	Triggers      []*Trigger
+	Data          []*TableData // only for tables listed in manage-data
 }
 
 // FailedKeys returns a slice of tengo.ObjectKey values corresponding to
@@ -266,16 +365,41 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 		}
 	}()
 
-	// Run CREATEs in parallel
-	th := throttler.New(opts.Concurrency, len(logicalSchema.Creates))
+	// Run CREATE SEQUENCEs first, since column defaults may call NEXTVAL on
+	// them, and then run all other CREATEs in parallel, except for CREATE VIEWs
+	// and CREATE TRIGGERs
+	var sequenceStatements, viewStatements, triggerStatements, otherCreates []*fs.Statement
+	sequenceFailures := []*StatementError{}
 	for _, stmt := range logicalSchema.Creates {
+		if stmt.ObjectType == fs.ObjectTypeSequence {
+			sequenceStatements = append(sequenceStatements, stmt)
+		} else if stmt.ObjectType == fs.ObjectTypeView {
+			viewStatements = append(viewStatements, stmt)
+		} else if stmt.ObjectType == fs.ObjectTypeTrigger {
+			triggerStatements = append(triggerStatements, stmt)
+		} else {
+			otherCreates = append(otherCreates, stmt)
+		}
+	}
+	for _, stmt := range sequenceStatements {
+		db, err := ws.ConnectionPool(paramsForStatement(stmt, opts))
+		if err != nil {
+			fatalErr = fmt.Errorf("Cannot connect to workspace: %s", err)
+			return
+		}
+		if _, err := db.Exec(statementBody(stmt, opts)); err != nil {
+			sequenceFailures = append(sequenceFailures, wrapFailure(stmt, err))
+		}
+	}
+	th := throttler.New(opts.Concurrency, len(otherCreates))
+	for _, stmt := range otherCreates {
 		db, err := ws.ConnectionPool(paramsForStatement(stmt, opts))
 		if err != nil {
 			fatalErr = fmt.Errorf("Cannot connect to workspace: %s", err)
 			return
 		}
 		go func(db *sqlx.DB, statement *fs.Statement) {
-			_, err := db.Exec(statement.Body())
+			_, err := db.Exec(statementBody(statement, opts))
 			if err != nil {
 				err = wrapFailure(statement, err)
 			}
@@ -289,7 +413,7 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 	// expected from concurrent CREATEs in MySQL 8+ if FKs are present.
 	wsSchema = &Schema{
 		LogicalSchema: logicalSchema,
-		Failures:      []*StatementError{},
+		Failures:      sequenceFailures,
 	}
 	sequentialStatements := []*fs.Statement{}
 	for _, err := range th.Errs() {
@@ -302,8 +426,10 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 	}
 
 	// Run ALTERs sequentially, since foreign key manipulations don't play
-	// nice with concurrency.
+	// nice with concurrency. INSERTs for tables listed in manage-data run after
+	// all tables are in their final state, but before any triggers exist.
 	sequentialStatements = append(sequentialStatements, logicalSchema.Alters...)
+	sequentialStatements = append(sequentialStatements, logicalSchema.Inserts...)
 
 	for _, statement := range sequentialStatements {
 		db, connErr := ws.ConnectionPool(paramsForStatement(statement, opts))
@@ -311,15 +437,88 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 			fatalErr = fmt.Errorf("Cannot connect to workspace: %s", connErr)
 			return
 		}
-		if _, err := db.Exec(statement.Body()); err != nil {
+		if _, err := db.Exec(statementBody(statement, opts)); err != nil {
 			wsSchema.Failures = append(wsSchema.Failures, wrapFailure(statement, err))
 		}
 	}
 
+	// Run CREATE TRIGGERs once their tables exist, since a trigger may be
+	// ordered relative to another trigger using FOLLOWS or PRECEDES. Then run
+	// CREATE VIEWs last, since views may refer to any other object.
+	var triggerFailures, viewFailures []*StatementError
+	if triggerStatements, triggerFailures, fatalErr = execWithRetries(ws, triggerStatements, opts); fatalErr != nil {
+		fatalErr = fmt.Errorf("Cannot connect to workspace: %s", fatalErr)
+		return
+	}
+	if viewStatements, viewFailures, fatalErr = execWithRetries(ws, viewStatements, opts); fatalErr != nil {
+		fatalErr = fmt.Errorf("Cannot connect to workspace: %s", fatalErr)
+		return
+	}
+	wsSchema.Failures = append(wsSchema.Failures, triggerFailures...)
+	wsSchema.Failures = append(wsSchema.Failures, viewFailures...)
+
 	wsSchema.Schema, fatalErr = ws.IntrospectSchema()
+	if fatalErr != nil {
+		return
+	}
+
+	// tengo does not support events, sequences, views, or triggers, so they are
+	// introspected separately
+	failed := make(map[*fs.Statement]bool, len(wsSchema.Failures))
+	for _, stmtErr := range wsSchema.Failures {
+		failed[stmtErr.Statement] = true
+	}
+	var eventStatements []*fs.Statement
+	for _, stmt := range logicalSchema.Creates {
+		if stmt.ObjectType == fs.ObjectTypeEvent && !failed[stmt] {
+			eventStatements = append(eventStatements, stmt)
+		}
+	}
+	if len(eventStatements) > 0 {
+		var eventFailures []*StatementError
+		if wsSchema.Events, eventFailures, fatalErr = introspectEvents(ws, eventStatements); fatalErr != nil {
+			fatalErr = fmt.Errorf("Cannot introspect events in workspace: %s", fatalErr)
+		}
+		wsSchema.Failures = append(wsSchema.Failures, eventFailures...)
+	}
+	if len(sequenceStatements) > 0 && fatalErr == nil {
+		if wsSchema.Sequences, fatalErr = introspectSequences(ws, sequenceStatements); fatalErr != nil {
+			fatalErr = fmt.Errorf("Cannot introspect sequences in workspace: %s", fatalErr)
+		}
+	}
+	if len(viewStatements) > 0 && fatalErr == nil {
+		if wsSchema.Views, fatalErr = introspectViews(ws, viewStatements); fatalErr != nil {
+			fatalErr = fmt.Errorf("Cannot introspect views in workspace: %s", fatalErr)
+		}
+	}
+	if len(triggerStatements) > 0 && fatalErr == nil {
+		if wsSchema.Triggers, fatalErr = introspectTriggers(ws, triggerStatements); fatalErr != nil {
+			fatalErr = fmt.Errorf("Cannot introspect triggers in workspace: %s", fatalErr)
+		}
+	}
+	if len(logicalSchema.Inserts) > 0 && fatalErr == nil {
+		var dataFailures []*StatementError
+		if wsSchema.Data, dataFailures, fatalErr = introspectTableData(ws, wsSchema.Schema, logicalSchema.Inserts, opts); fatalErr != nil {
+			fatalErr = fmt.Errorf("Cannot introspect table data in workspace: %s", fatalErr)
+		}
+		wsSchema.Failures = append(wsSchema.Failures, dataFailures...)
+	}
 	return
 }
 
+// statementBody returns the SQL to execute in a workspace for the supplied
+// statement. CREATE TABLE statements have any missing default table options
+// added. CREATE EVENT statements are modified to create the event disabled,
+// so that it cannot execute in the workspace.
+func statementBody(statement *fs.Statement, opts Options) string {
+	if statement.Type == fs.StatementTypeCreate && statement.ObjectType == tengo.ObjectTypeTable {
+		return opts.DefaultTableOptions.ApplyToCreate(statement.Body())
+	} else if statement.Type == fs.StatementTypeCreate && statement.ObjectType == fs.ObjectTypeEvent {
+		return disabledEventCreate(statement.Body())
+	}
+	return statement.Body()
+}
+
 // paramsForStatement returns the session settings for executing the supplied
 // statement in a workspace.
 func paramsForStatement(statement *fs.Statement, opts Options) string {
@@ -343,8 +542,8 @@ func paramsForStatement(statement *fs.Statement, opts Options) string {
 		rememberSQLMode := map[tengo.ObjectType]bool{
 			tengo.ObjectTypeFunc: true,
 			tengo.ObjectTypeProc: true,
-			//tengo.ObjectTypeEvent: true,   // not implemented yet
-			//tengo.ObjectTypeTrigger: true, // not implemented yet
+			fs.ObjectTypeEvent:   true,
+			fs.ObjectTypeTrigger: true,
 		}
 		if rememberSQLMode[statement.ObjectType] {
 			params = append(params, "sql_mode=@@GLOBAL.sql_mode")
@@ -354,6 +553,43 @@ func paramsForStatement(statement *fs.Statement, opts Options) string {
 	return strings.Join(params, "&")
 }
 
+// execWithRetries runs the supplied statements in a workspace sequentially, in
+// order by object name. This is used for object types whose statements may
+// depend on other statements of the same type, such as a view referring to
+// another view. Failed statements are retried for as long as each pass manages
+// to successfully execute at least one more statement. The successfully
+// executed statements are returned in the order they ran, which is therefore a
+// valid creation order, along with the errors from the final attempt of any
+// remaining statements.
+func execWithRetries(ws Workspace, statements []*fs.Statement, opts Options) (executed []*fs.Statement, failures []*StatementError, err error) {
+	pending := make([]*fs.Statement, len(statements))
+	copy(pending, statements)
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ObjectName < pending[j].ObjectName
+	})
+	for len(pending) > 0 {
+		var remaining []*fs.Statement
+		failures = nil
+		for _, stmt := range pending {
+			db, err := ws.ConnectionPool(paramsForStatement(stmt, opts))
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := db.Exec(statementBody(stmt, opts)); err != nil {
+				remaining = append(remaining, stmt)
+				failures = append(failures, wrapFailure(stmt, err))
+			} else {
+				executed = append(executed, stmt)
+			}
+		}
+		if len(remaining) == len(pending) {
+			break
+		}
+		pending = remaining
+	}
+	return executed, failures, nil
+}
+
 func wrapFailure(statement *fs.Statement, err error) *StatementError {
 	stmtErr := &StatementError{
 		Statement: statement,
@@ -368,17 +604,81 @@ func wrapFailure(statement *fs.Statement, err error) *StatementError {
 	return stmtErr
 }
 
+// ErrLockHeld is returned when a workspace lock could not be obtained because
+// another process held it for the entire wait period. Other failures, such as
+// being unable to connect, are returned as different errors.
+var ErrLockHeld = errors.New("Lock is held by another process")
+
 // releaseFunc is a function to release a lock obtained by getLock
 type releaseFunc func()
 
+// skippedLock is a releaseFunc used in place of a real lock when
+// Options.SkipLock is true. There is nothing to release in this case.
+func skippedLock() {}
+
+// bulkDropOptions returns options for dropping all objects in a workspace
+// schema. If maxRows is 0, the returned options will cause the drop to fail if
+// any table has rows. Otherwise, the tables are checked here, and an error is
+// returned if any table has more than maxRows rows.
+func bulkDropOptions(instance *tengo.Instance, schemaName string, maxRows, concurrency int, skipBinlog bool) (tengo.BulkDropOptions, error) {
+	dropOpts := tengo.BulkDropOptions{
+		MaxConcurrency: concurrency,
+		OnlyIfEmpty:    true,
+		SkipBinlog:     skipBinlog,
+	}
+	if maxRows <= 0 {
+		return dropOpts, nil
+	}
+	db, err := instance.Connect(schemaName, "")
+	if err != nil {
+		return dropOpts, err
+	}
+	var tableNames []string
+	query := `
+		SELECT table_name
+		FROM   information_schema.tables
+		WHERE  table_schema = ? AND table_type = 'BASE TABLE'`
+	if err := db.Select(&tableNames, query, schemaName); err != nil {
+		return dropOpts, err
+	}
+	for _, name := range tableNames {
+		var count int
+		// Use a LIMIT in a subquery to avoid scanning large tables entirely
+		query := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s LIMIT %d) r", tengo.EscapeIdentifier(name), maxRows+1)
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			return dropOpts, err
+		} else if count > maxRows {
+			return dropOpts, fmt.Errorf("table %s has more than max-rows=%d rows", tengo.EscapeIdentifier(name), maxRows)
+		}
+	}
+	dropOpts.OnlyIfEmpty = false
+	return dropOpts, nil
+}
+
+// logEvent emits a debug-level structured log entry describing a workspace
+// operation. Only non-sensitive identifying information is included; in
+// particular, connection credentials must never be passed here.
+func logEvent(wsType Type, instance fmt.Stringer, schemaName, event string) {
+	log.WithFields(log.Fields{
+		"workspace": wsType.String(),
+		"instance":  instance.String(),
+		"schema":    schemaName,
+	}).Debug(event)
+}
+
+// getLock obtains the named advisory lock on instance, waiting up to maxWait
+// for it to become available. If maxWait is 0, only a single attempt is made,
+// failing immediately if another process holds the lock. ErrLockHeld is
+// returned if the lock could not be obtained due to another process holding
+// it; any other error indicates a problem communicating with the instance.
 func getLock(instance *tengo.Instance, lockName string, maxWait time.Duration) (releaseFunc, error) {
 	db, err := instance.Connect("", "")
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Unable to connect to obtain lock: %s", err)
 	}
 	lockConn, err := db.Conn(context.Background())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Unable to connect to obtain lock: %s", err)
 	}
 
 	done := make(chan struct{})
@@ -406,18 +706,30 @@ func getLock(instance *tengo.Instance, lockName string, maxWait time.Duration) (
 		}
 	}
 
+	// Only using a timeout of 1 sec on each query to avoid potential issues with
+	// query killers, spurious slow query logging, etc. With no wait at all, a
+	// timeout of 0 makes GET_LOCK return immediately.
+	query := "SELECT GET_LOCK(?, 1)"
+	if maxWait <= 0 {
+		query = "SELECT GET_LOCK(?, 0)"
+	}
 	var getLockResult int
 	start := time.Now()
-	for time.Since(start) < maxWait {
-		// Only using a timeout of 1 sec on each query to avoid potential issues with
-		// query killers, spurious slow query logging, etc
-		err := lockConn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, 1)", lockName).Scan(&getLockResult)
+	for {
+		err = lockConn.QueryRowContext(context.Background(), query, lockName).Scan(&getLockResult)
 		if err == nil && getLockResult == 1 {
 			// Launch a goroutine to keep the connection active, and release the lock
 			// once the ReleaseFunc is called
 			go connMaintainer()
 			return release, nil
 		}
+		if time.Since(start) >= maxWait {
+			break
+		}
+	}
+	lockConn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to acquire lock: %s", err)
 	}
-	return nil, errors.New("Unable to acquire lock")
+	return nil, ErrLockHeld
 }
@@ -1,12 +1,14 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/util"
@@ -152,6 +154,51 @@ func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaErrors(t *testing.T) {
 // concurrent table creation involving cross-referencing foreign keys. This
 // situation, if not specially handled, is known to cause random deadlock
 // errors with MySQL 8.0's new data dictionary.
+func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaDefaultTableOptions(t *testing.T) {
+	dirPath := "../testdata/golden/init/mydb/product"
+	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {
+		dirPath = strings.Replace(dirPath, "golden", "golden-mysql55", 1)
+	}
+	dir := s.getParsedDir(t, dirPath, "--default-table-options='ROW_FORMAT=COMPACT DEFAULT CHARSET=utf8 COMMENT=\"standard\"'")
+	opts, err := OptionsForDir(dir, s.d.Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	opts.LockWaitTimeout = 100 * time.Millisecond
+	dir.LogicalSchemas[0].AddStatement(&fs.Statement{
+		Type:       fs.StatementTypeCreate,
+		ObjectType: tengo.ObjectTypeTable,
+		ObjectName: "overrides",
+		Text:       "CREATE TABLE overrides (id int) ROW_FORMAT=REDUNDANT COMMENT='custom'",
+	})
+	wsSchema, err := ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	if len(wsSchema.Failures) > 0 {
+		t.Fatalf("Expected no StatementErrors, instead found %d: %v", len(wsSchema.Failures), wsSchema.Failures)
+	}
+
+	// Tables which specify a charset in their files keep it, but receive the
+	// other default options
+	for _, table := range wsSchema.Tables {
+		create := table.CreateStatement
+		if table.Name == "overrides" {
+			if !strings.Contains(create, "ROW_FORMAT=REDUNDANT") || !strings.Contains(create, "COMMENT='custom'") || table.CharSet != "utf8" {
+				t.Errorf("Expected table overrides to retain its explicit options and receive default charset, instead found: %s", create)
+			}
+		} else if !strings.Contains(create, "ROW_FORMAT=COMPACT") || !strings.Contains(create, "COMMENT='standard'") || table.CharSet != "latin1" {
+			t.Errorf("Expected table %s to receive default options other than charset, instead found: %s", table.Name, create)
+		}
+	}
+
+	// Invalid option values should be rejected by OptionsForDir
+	dir = s.getParsedDir(t, dirPath, "--default-table-options='AUTO_INCREMENT=100'")
+	if _, err := OptionsForDir(dir, s.d.Instance); err == nil {
+		t.Error("Expected error from OptionsForDir with invalid default-table-options, but it was nil")
+	}
+}
+
 func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaFK(t *testing.T) {
 	if !s.d.Flavor().HasDataDictionary() {
 		t.Skip("Test only relevant for flavors that have the new data dictionary")
@@ -204,15 +251,25 @@ func (s WorkspaceIntegrationSuite) TestOptionsForDir(t *testing.T) {
 	assertOptsError("--workspace=temp-schema --temp-schema-threads=-20")
 	assertOptsError("--workspace=temp-schema --temp-schema-threads=banana")
 	assertOptsError("--workspace=temp-schema --temp-schema-binlog=potato")
+	assertOptsError("--workspace=temp-schema --temp-schema-mismatch=ignore")
+	assertOptsError("--max-rows=-1")
+	assertOptsError("--max-rows=banana")
+	assertOptsError("--lock-wait-timeout=-1")
+	assertOptsError("--lock-wait-timeout=banana")
 
 	// Test default configuration, which should use temp-schema with drop cleanup
-	if opts := getOpts(""); opts.Type != TypeTempSchema || opts.CleanupAction != CleanupActionDrop {
-		t.Errorf("Unexpected type %v returned", opts.Type)
+	if opts := getOpts(""); opts.Type != TypeTempSchema || opts.CleanupAction != CleanupActionDrop || opts.RecreateOnMismatch || opts.LockWaitTimeout != 30*time.Second {
+		t.Errorf("Unexpected return from OptionsForDir: %+v", opts)
+	}
+
+	// Test fail-fast lock behavior
+	if opts := getOpts("--lock-wait-timeout=0"); opts.LockWaitTimeout != 0 {
+		t.Errorf("Unexpected return from OptionsForDir: %+v", opts)
 	}
 
 	// Test temp-schema with some non-default options
-	opts := getOpts("--workspace=temp-schema --temp-schema=override --reuse-temp-schema")
-	if opts.Type != TypeTempSchema || opts.CleanupAction != CleanupActionNone || opts.SchemaName != "override" {
+	opts := getOpts("--workspace=temp-schema --temp-schema=override --reuse-temp-schema --max-rows=5 --no-lock --temp-schema-mismatch=recreate")
+	if opts.Type != TypeTempSchema || opts.CleanupAction != CleanupActionNone || opts.SchemaName != "override" || opts.MaxRows != 5 || !opts.SkipLock || !opts.RecreateOnMismatch {
 		t.Errorf("Unexpected return from OptionsForDir: %+v", opts)
 	}
 
@@ -316,3 +373,41 @@ func (s *WorkspaceIntegrationSuite) getParsedDir(t *testing.T, dirPath, cliFlags
 	}
 	return dir
 }
+
+type cleanupCounter struct {
+	cleanups int
+}
+
+func (cc *cleanupCounter) ConnectionPool(params string) (*sqlx.DB, error) {
+	return nil, errors.New("not supported")
+}
+
+func (cc *cleanupCounter) IntrospectSchema() (*tengo.Schema, error) {
+	return nil, errors.New("not supported")
+}
+
+func (cc *cleanupCounter) Cleanup() error {
+	if !untrackWorkspace(cc) {
+		return errors.New("Cleanup() called multiple times")
+	}
+	cc.cleanups++
+	return nil
+}
+
+func TestCleanupAll(t *testing.T) {
+	a, b, c := &cleanupCounter{}, &cleanupCounter{}, &cleanupCounter{}
+	trackWorkspace(a)
+	trackWorkspace(b)
+	trackWorkspace(c)
+	if err := b.Cleanup(); err != nil {
+		t.Fatalf("Unexpected error from Cleanup: %s", err)
+	}
+	CleanupAll()
+	if a.cleanups != 1 || b.cleanups != 1 || c.cleanups != 1 {
+		t.Errorf("Expected each workspace to be cleaned up exactly once, instead found %d, %d, %d", a.cleanups, b.cleanups, c.cleanups)
+	}
+	CleanupAll()
+	if a.cleanups != 1 || b.cleanups != 1 || c.cleanups != 1 {
+		t.Errorf("Expected repeated CleanupAll to have no effect, instead found %d, %d, %d", a.cleanups, b.cleanups, c.cleanups)
+	}
+}